@@ -45,6 +45,14 @@ func TestAIGatewayRouteRule_GetStreamIdleTimeout(t *testing.T) {
 	}
 }
 
+func TestAIGatewayRouteRule_GetHedgeDelay(t *testing.T) {
+	require.Zero(t, (*AIGatewayRouteRule)(nil).GetHedgeDelay())
+	require.Zero(t, (&AIGatewayRouteRule{}).GetHedgeDelay())
+	require.Equal(t, 500*time.Millisecond,
+		(&AIGatewayRouteRule{HedgePolicy: &AIGatewayRouteRuleHedgePolicy{Delay: "500ms"}}).GetHedgeDelay())
+	require.Zero(t, (&AIGatewayRouteRule{HedgePolicy: &AIGatewayRouteRuleHedgePolicy{Delay: "nope"}}).GetHedgeDelay())
+}
+
 func TestAIGatewayRouteRule_GetTimeoutsWithDefaults(t *testing.T) {
 	tests := []struct {
 		name     string
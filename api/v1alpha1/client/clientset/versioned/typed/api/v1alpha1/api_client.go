@@ -22,6 +22,7 @@ type AigatewayV1alpha1Interface interface {
 	BackendSecurityPoliciesGetter
 	GatewayConfigsGetter
 	MCPRoutesGetter
+	ModelAliasesGetter
 	QuotaPoliciesGetter
 }
 
@@ -50,6 +51,10 @@ func (c *AigatewayV1alpha1Client) MCPRoutes(namespace string) MCPRouteInterface
 	return newMCPRoutes(c, namespace)
 }
 
+func (c *AigatewayV1alpha1Client) ModelAliases(namespace string) ModelAliasInterface {
+	return newModelAliases(c, namespace)
+}
+
 func (c *AigatewayV1alpha1Client) QuotaPolicies(namespace string) QuotaPolicyInterface {
 	return newQuotaPolicies(c, namespace)
 }
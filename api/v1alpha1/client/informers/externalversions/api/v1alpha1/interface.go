@@ -23,6 +23,8 @@ type Interface interface {
 	GatewayConfigs() GatewayConfigInformer
 	// MCPRoutes returns a MCPRouteInformer.
 	MCPRoutes() MCPRouteInformer
+	// ModelAliases returns a ModelAliasInformer.
+	ModelAliases() ModelAliasInformer
 	// QuotaPolicies returns a QuotaPolicyInformer.
 	QuotaPolicies() QuotaPolicyInformer
 }
@@ -63,6 +65,11 @@ func (v *version) MCPRoutes() MCPRouteInformer {
 	return &mCPRouteInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// ModelAliases returns a ModelAliasInformer.
+func (v *version) ModelAliases() ModelAliasInformer {
+	return &modelAliasInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // QuotaPolicies returns a QuotaPolicyInformer.
 func (v *version) QuotaPolicies() QuotaPolicyInformer {
 	return &quotaPolicyInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -52,6 +52,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Aigateway().V1alpha1().GatewayConfigs().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("mcproutes"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Aigateway().V1alpha1().MCPRoutes().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("modelaliases"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Aigateway().V1alpha1().ModelAliases().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("quotapolicies"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Aigateway().V1alpha1().QuotaPolicies().Informer()}, nil
 
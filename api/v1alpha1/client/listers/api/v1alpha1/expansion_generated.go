@@ -47,6 +47,14 @@ type MCPRouteListerExpansion interface{}
 // MCPRouteNamespaceLister.
 type MCPRouteNamespaceListerExpansion interface{}
 
+// ModelAliasListerExpansion allows custom methods to be added to
+// ModelAliasLister.
+type ModelAliasListerExpansion interface{}
+
+// ModelAliasNamespaceListerExpansion allows custom methods to be added to
+// ModelAliasNamespaceLister.
+type ModelAliasNamespaceListerExpansion interface{}
+
 // QuotaPolicyListerExpansion allows custom methods to be added to
 // QuotaPolicyLister.
 type QuotaPolicyListerExpansion interface{}
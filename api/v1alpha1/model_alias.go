@@ -0,0 +1,109 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ModelAlias maps a public model name, which the clients send in the requests, to weighted concrete models.
+//
+// The AI Gateway resolves the alias in a request to one of its concrete models before matching the AIGatewayRoute
+// rules, so the rules route the request based on the concrete model, which can be served by any backend. The alias
+// applies to the Gateways of the AIGatewayRoutes in the same namespace, and is listed by the "/v1/models" endpoint
+// alongside the models of the AIGatewayRoutes.
+//
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Alias",type=string,JSONPath=`.spec.alias`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.conditions[-1:].type`
+type ModelAlias struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ModelAliasSpec `json:"spec,omitempty"`
+	// Status defines the effective mapping and the status details of the ModelAlias.
+	Status ModelAliasStatus `json:"status,omitempty"`
+}
+
+// ModelAliasSpec details the mapping of a ModelAlias.
+type ModelAliasSpec struct {
+	// Alias is the public model name that the clients send in the requests, for example "chat-default".
+	//
+	// When multiple ModelAliases of a Gateway have the same alias, the one whose namespace/name is alphabetically
+	// first takes precedence.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Alias string `json:"alias"`
+	// Models is the list of the concrete models that the alias is resolved to, in proportion to their weights.
+	//
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=16
+	// +listType=map
+	// +listMapKey=name
+	Models []ModelAliasModel `json:"models"`
+	// Rollout gradually moves the requests for the alias to a new concrete model. The percentage of the rollout is
+	// resolved to its model, and the rest of the requests are resolved to the Models in proportion to their weights.
+	//
+	// +optional
+	Rollout *ModelAliasRollout `json:"rollout,omitempty"`
+	// StickyHeader is the name of a request header, such as one identifying the user, that the concrete model is
+	// assigned by. The requests with the same value of the header are resolved to the same model as long as the
+	// mapping does not change. Increasing the percentage of the rollout only moves the requests of the values newly
+	// assigned to the rollout, while the others keep their model.
+	//
+	// When not set, or when a request does not have the header, the model is picked at random.
+	//
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	StickyHeader *string `json:"stickyHeader,omitempty"`
+}
+
+// ModelAliasModel is a concrete model that a ModelAlias is resolved to.
+type ModelAliasModel struct {
+	// Name is the name of the concrete model, which is matched by the AIGatewayRoute rules with the
+	// "x-ai-eg-model" header.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Weight is the proportion of the requests for the alias resolved to the model, relative to the other models.
+	// Zero means that the model is not used, while keeping it in the list. At least one model must have a positive
+	// weight unless the rollout is complete.
+	//
+	// Defaults to 1.
+	//
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1000000
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+// ModelAliasRollout is the rollout of a new concrete model for a ModelAlias.
+type ModelAliasRollout struct {
+	// Model is the name of the concrete model being rolled out. This can also be one of the Models, in which case it
+	// receives its share of the rest of the requests as well.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Model string `json:"model"`
+	// Percent is the percentage of the requests for the alias resolved to the model. Increase it gradually to roll
+	// out the model, and set it to 100 to complete the rollout.
+	//
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percent int32 `json:"percent"`
+}
+
+// ModelAliasList contains a list of ModelAlias.
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+type ModelAliasList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ModelAlias `json:"items"`
+}
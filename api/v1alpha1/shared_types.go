@@ -15,14 +15,16 @@ package v1alpha1
 type VersionedAPISchema struct {
 	// Name is the name of the API schema of the AIGatewayRoute or AIServiceBackend.
 	//
-	// +kubebuilder:validation:Enum=OpenAI;Cohere;AWSBedrock;AzureOpenAI;GCPVertexAI;GCPAnthropic;Anthropic;AWSAnthropic
+	// +kubebuilder:validation:Enum=OpenAI;Cohere;AWSBedrock;AzureOpenAI;GCPVertexAI;GCPAnthropic;Anthropic;AWSAnthropic;Ollama;AzureAIInference
 	Name APISchema `json:"name"`
 
 	// Version is the version of the API schema.
 	//
 	// When the name is set to AzureOpenAI, this version maps to "API Version" in the
 	// Azure OpenAI API documentation (https://learn.microsoft.com/en-us/azure/ai-services/openai/reference#rest-api-versioning).
-	// This field is ignored for OpenAI, AWSBedrock, GCPVertexAI, and Anthropic.
+	// When the name is set to AzureAIInference, this version maps to the "api-version" query parameter of the
+	// Azure AI Model Inference API, defaulting to "2024-05-01-preview" when unset.
+	// This field is ignored for OpenAI, AWSBedrock, GCPVertexAI, Anthropic, and Ollama.
 	// For OpenAI and Anthropic, use prefix to configure custom request paths.
 	//
 	// See https://aigateway.envoyproxy.io/docs/capabilities/llm-integrations/supported-providers for details.
@@ -33,6 +35,8 @@ type VersionedAPISchema struct {
 	//
 	// When the name is set to "OpenAI", "chat completions" API endpoint will be "${this_field}/chat/completions".
 	// When the name is set to "Anthropic", the "messages" API endpoint will be "${this_field}/messages".
+	// When the name is set to "AzureAIInference", the "chat completions" API endpoint will be "${this_field}/chat/completions".
+	// For example, Azure AI Foundry resource endpoints, i.e. "*.services.ai.azure.com", use the "/models" prefix.
 	// It can be with or without a leading slash ("/").
 	// This field is ignored for AWSAnthropic and GCPAnthropic.
 	//
@@ -89,6 +93,19 @@ const (
 	// https://aws.amazon.com/bedrock/anthropic/
 	// https://docs.claude.com/en/api/claude-on-amazon-bedrock
 	APISchemaAWSAnthropic APISchema = "AWSAnthropic"
+	// APISchemaOllama is the native Ollama API schema. Chat completions use the /api/chat endpoint
+	// and embeddings use the /api/embed endpoint.
+	//
+	// https://github.com/ollama/ollama/blob/main/docs/api.md
+	APISchemaOllama APISchema = "Ollama"
+	// APISchemaAzureAIInference is the Azure AI Model Inference API schema, used by the models deployed in
+	// Azure AI Foundry such as Mistral, Llama, DeepSeek and Phi. Unlike AzureOpenAI, the request paths do not
+	// contain the deployment name; the model is selected by the model field of the request body.
+	// Authentication is configured with the AzureAPIKey, AzureCredentials or APIKey BackendSecurityPolicy, and the
+	// backends with the other types of the BackendSecurityPolicy are not accepted.
+	//
+	// https://learn.microsoft.com/en-us/azure/ai-foundry/model-inference/reference/reference-model-inference-api
+	APISchemaAzureAIInference APISchema = "AzureAIInference"
 )
 
 const (
@@ -160,3 +177,224 @@ const (
 	// AIGatewayFilterMetadataNamespace is the namespace for the ai-gateway filter metadata.
 	AIGatewayFilterMetadataNamespace = "io.envoy.ai_gateway"
 )
+
+// PromptPolicy configures the system and developer messages added by the AI Gateway to the requests before they are
+// sent to the backends. Unlike HTTPBodyMutation, the messages are added according to the API schema of the request:
+//
+//   - Chat completions: the messages in the "messages" array.
+//   - Messages: the text of the "system" field. The developer messages are added as the system prompt.
+//   - Responses: the text of the "instructions" field. The developer messages are added as the instructions.
+//
+// The messages are translated along with the rest of the request for the backends of the other API schemas. In
+// particular, the Gemini API has no endpoint of its own, so the "systemInstruction" of the GCPVertexAI backends is
+// translated from the messages added to the chat completions. The requests of the other endpoints are not changed.
+//
+// +kubebuilder:validation:XValidation:rule="(has(self.mode) && self.mode == 'Lock') || (has(self.messages) && size(self.messages) > 0)", message="messages must be set unless the mode is Lock"
+type PromptPolicy struct {
+	// Mode decides how the system and developer messages provided by the client are treated:
+	//
+	//   - Merge: keep them along with the Messages.
+	//   - Lock: reject the requests with them with the 400 status, so that only the Messages are followed.
+	//
+	// Default is Merge.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=Merge;Lock
+	// +kubebuilder:default=Merge
+	Mode PromptPolicyMode `json:"mode,omitempty"`
+
+	// Messages are the messages added to the requests in their order.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=16
+	Messages []PromptPolicyMessage `json:"messages,omitempty"`
+}
+
+// PromptPolicyMode is how the system and developer messages provided by the client are treated.
+type PromptPolicyMode string
+
+const (
+	// PromptPolicyModeMerge keeps the messages of the client along with the ones of the policy.
+	PromptPolicyModeMerge PromptPolicyMode = "Merge"
+	// PromptPolicyModeLock rejects the requests with the messages of the client.
+	PromptPolicyModeLock PromptPolicyMode = "Lock"
+)
+
+// PromptPolicyMessage is a message added to the requests by the PromptPolicy.
+type PromptPolicyMessage struct {
+	// Role is the role of the message, i.e., System or Developer. Default is System.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=System;Developer
+	// +kubebuilder:default=System
+	Role PromptPolicyRole `json:"role,omitempty"`
+
+	// Position is where the message is added relative to the system and developer messages of the client:
+	//
+	//   - Prepend: before them, i.e., at the beginning of the prompt.
+	//   - Append: after them, i.e., right before the rest of the conversation.
+	//
+	// Default is Prepend.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=Prepend;Append
+	// +kubebuilder:default=Prepend
+	Position PromptPolicyPosition `json:"position,omitempty"`
+
+	// Template is the content of the message, which is a Go text/template rendered for each request with the
+	// following data:
+	//
+	//   - .Headers: the map of the request headers by their lower-cased names, which are looked up with the index
+	//     function, e.g., index .Headers "x-tenant-id". The missing headers are empty strings.
+	//   - .Model: the model of the request sent by the client.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=32768
+	Template string `json:"template"`
+}
+
+// PromptPolicyRole is the role of a message added by the PromptPolicy.
+type PromptPolicyRole string
+
+const (
+	// PromptPolicyRoleSystem is the system message.
+	PromptPolicyRoleSystem PromptPolicyRole = "System"
+	// PromptPolicyRoleDeveloper is the developer message, which is the system message on the endpoints without the
+	// developer role.
+	PromptPolicyRoleDeveloper PromptPolicyRole = "Developer"
+)
+
+// PromptPolicyPosition is where a message is added by the PromptPolicy.
+type PromptPolicyPosition string
+
+const (
+	// PromptPolicyPositionPrepend adds the message before the system and developer messages of the client.
+	PromptPolicyPositionPrepend PromptPolicyPosition = "Prepend"
+	// PromptPolicyPositionAppend adds the message after the system and developer messages of the client.
+	PromptPolicyPositionAppend PromptPolicyPosition = "Append"
+)
+
+// ParameterPolicy configures the rules applied by the AI Gateway to the generation parameters of the requests before
+// they are sent to the backends. The parameters are identified in the API schema of the request:
+//
+//   - MaxTokens: the "max_completion_tokens" or the "max_tokens" of the chat completions, the "max_tokens" of the
+//     messages, and the "max_output_tokens" of the responses.
+//   - Temperature and TopP: the "temperature" and the "top_p" of all of them.
+//   - N: the "n" of the chat completions.
+//   - ThinkingBudgetTokens: the "thinking.budget_tokens" of the chat completions and the messages with the thinking
+//     enabled.
+//   - ReasoningEffort: the "reasoning_effort" of the chat completions and the "reasoning.effort" of the responses.
+//
+// The parameters not supported by the endpoint of a request are ignored, and the requests of the other endpoints,
+// e.g., the embeddings, are not changed.
+//
+// Since the providers require the ThinkingBudgetTokens to be lower than the MaxTokens, the budget is lowered to
+// one below the MaxTokens when the rules change a request so that it is not, and the request is rejected with the
+// 400 status if the budget is then below the minimum of 1024 tokens.
+//
+// +kubebuilder:validation:XValidation:rule="has(self.rules) || has(self.blockedFields)", message="at least one of rules or blockedFields must be set"
+type ParameterPolicy struct {
+	// Rules are the rules applied to the requests in their order.
+	//
+	// +optional
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=32
+	Rules []ParameterPolicyRule `json:"rules,omitempty"`
+
+	// BlockedFields are the paths of the fields of the request bodies in the dot notation, e.g., "logit_bias" or
+	// "metadata.user_id", in the API schema of the client. The requests setting any of them are rejected with the
+	// 400 status before the rules are applied.
+	//
+	// +optional
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=32
+	// +kubebuilder:validation:items:MaxLength=128
+	// +kubebuilder:validation:items:Pattern=`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`
+	BlockedFields []string `json:"blockedFields,omitempty"`
+}
+
+// ParameterPolicyRule is a rule of the ParameterPolicy applied to a generation parameter.
+//
+// The values of the numeric parameters are decimal numbers, e.g., "0.7", which are integers for MaxTokens, N and
+// ThinkingBudgetTokens. The values of ReasoningEffort are the levels "none", "minimal", "low", "medium", "high",
+// "xhigh" and "max" in this order.
+//
+// +kubebuilder:validation:XValidation:rule="!has(self.action) || self.action != 'Remove' || (!has(self.default) && !has(self.min) && !has(self.max))", message="default, min and max cannot be set with the Remove action"
+// +kubebuilder:validation:XValidation:rule="(has(self.action) && self.action != 'Clamp') || has(self.default) || has(self.min) || has(self.max)", message="at least one of default, min or max must be set with the Clamp action"
+type ParameterPolicyRule struct {
+	// Name is the name of the rule, which is reported in the errors rejecting the requests, the logs and the
+	// metrics, e.g., "cap-max-tokens".
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=64
+	Name string `json:"name"`
+
+	// Parameter is the generation parameter to which the rule applies.
+	//
+	// +kubebuilder:validation:Enum=MaxTokens;Temperature;TopP;N;ThinkingBudgetTokens;ReasoningEffort
+	Parameter ParameterPolicyParameter `json:"parameter"`
+
+	// Action is the action taken on the parameter:
+	//
+	//   - Clamp: replace the values below Min with Min and the ones above Max with Max.
+	//   - Reject: reject the requests with the values out of the range of Min and Max with the 400 status. Without
+	//     Min and Max, the requests setting the parameter are rejected, i.e., the parameter is blocked.
+	//   - Remove: remove the parameter from the requests, so that the default of the backend applies.
+	//
+	// Default is Clamp.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=Clamp;Reject;Remove
+	// +kubebuilder:default=Clamp
+	Action ParameterPolicyAction `json:"action,omitempty"`
+
+	// Default is the value set on the requests not setting the parameter, e.g., "1024". The ThinkingBudgetTokens
+	// is only set on the requests with the thinking enabled.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxLength=32
+	Default *string `json:"default,omitempty"`
+
+	// Min is the minimum value of the parameter, inclusive.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxLength=32
+	Min *string `json:"min,omitempty"`
+
+	// Max is the maximum value of the parameter, inclusive, e.g., "4096" for MaxTokens or "medium" for
+	// ReasoningEffort.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxLength=32
+	Max *string `json:"max,omitempty"`
+}
+
+// ParameterPolicyParameter is a generation parameter governed by the ParameterPolicy.
+type ParameterPolicyParameter string
+
+const (
+	// ParameterPolicyParameterMaxTokens is the maximum number of the output tokens.
+	ParameterPolicyParameterMaxTokens ParameterPolicyParameter = "MaxTokens"
+	// ParameterPolicyParameterTemperature is the sampling temperature.
+	ParameterPolicyParameterTemperature ParameterPolicyParameter = "Temperature"
+	// ParameterPolicyParameterTopP is the probability mass of the nucleus sampling.
+	ParameterPolicyParameterTopP ParameterPolicyParameter = "TopP"
+	// ParameterPolicyParameterN is the number of the choices generated.
+	ParameterPolicyParameterN ParameterPolicyParameter = "N"
+	// ParameterPolicyParameterThinkingBudgetTokens is the maximum number of the tokens of the extended thinking.
+	ParameterPolicyParameterThinkingBudgetTokens ParameterPolicyParameter = "ThinkingBudgetTokens"
+	// ParameterPolicyParameterReasoningEffort is the effort of the reasoning models.
+	ParameterPolicyParameterReasoningEffort ParameterPolicyParameter = "ReasoningEffort"
+)
+
+// ParameterPolicyAction is the action taken on a parameter by a ParameterPolicyRule.
+type ParameterPolicyAction string
+
+const (
+	// ParameterPolicyActionClamp replaces the values out of the range with its bounds.
+	ParameterPolicyActionClamp ParameterPolicyAction = "Clamp"
+	// ParameterPolicyActionReject rejects the requests with the values out of the range.
+	ParameterPolicyActionReject ParameterPolicyAction = "Reject"
+	// ParameterPolicyActionRemove removes the parameter from the requests.
+	ParameterPolicyActionRemove ParameterPolicyAction = "Remove"
+)
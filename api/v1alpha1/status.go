@@ -60,3 +60,26 @@ type QuotaPolicyStatus struct {
 	// Known .status.conditions.type are: "Accepted", "NotAccepted".
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
+
+// ModelAliasStatus contains the effective mapping of the alias and the conditions by the reconciliation result.
+type ModelAliasStatus struct {
+	// Models is the effective mapping of the alias, i.e., the share of the requests for the alias resolved to each
+	// concrete model once the rollout is applied. The models without share are omitted.
+	//
+	// +optional
+	Models []ModelAliasModelStatus `json:"models,omitempty"`
+	// Conditions is the list of conditions by the reconciliation result.
+	// Currently, at most one condition is set.
+	//
+	// Known .status.conditions.type are: "Accepted", "NotAccepted".
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ModelAliasModelStatus is the share of the requests for a ModelAlias resolved to a concrete model.
+type ModelAliasModelStatus struct {
+	// Name is the name of the concrete model.
+	Name string `json:"name"`
+	// Percent is the percentage of the requests for the alias resolved to the model, rounded to two decimal places,
+	// for example "33.33".
+	Percent string `json:"percent"`
+}
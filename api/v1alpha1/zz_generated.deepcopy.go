@@ -1,8 +1,3 @@
-// Copyright Envoy AI Gateway Authors
-// SPDX-License-Identifier: Apache-2.0
-// The full text of the Apache license is available in the LICENSE file at
-// the root of the repo.
-
 //go:build !ignore_autogenerated
 
 // Code generated by controller-gen. DO NOT EDIT.
@@ -108,6 +103,63 @@ func (in *AIGatewayRouteRule) DeepCopyInto(out *AIGatewayRouteRule) {
 		*out = new(v1.Duration)
 		**out = **in
 	}
+	if in.FallbackPolicy != nil {
+		in, out := &in.FallbackPolicy, &out.FallbackPolicy
+		*out = new(AIGatewayRouteRuleFallbackPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HedgePolicy != nil {
+		in, out := &in.HedgePolicy, &out.HedgePolicy
+		*out = new(AIGatewayRouteRuleHedgePolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BackendSelection != nil {
+		in, out := &in.BackendSelection, &out.BackendSelection
+		*out = new(AIGatewayRouteRuleBackendSelection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(AIGatewayRouteRuleAffinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Shadows != nil {
+		in, out := &in.Shadows, &out.Shadows
+		*out = make([]AIGatewayRouteRuleShadow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StreamFailover != nil {
+		in, out := &in.StreamFailover, &out.StreamFailover
+		*out = new(AIGatewayRouteRuleStreamFailover)
+		**out = **in
+	}
+	if in.ResponseCache != nil {
+		in, out := &in.ResponseCache, &out.ResponseCache
+		*out = new(AIGatewayRouteRuleResponseCache)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Guardrails != nil {
+		in, out := &in.Guardrails, &out.Guardrails
+		*out = new(AIGatewayRouteRuleGuardrails)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PromptPolicy != nil {
+		in, out := &in.PromptPolicy, &out.PromptPolicy
+		*out = new(PromptPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ToolPolicy != nil {
+		in, out := &in.ToolPolicy, &out.ToolPolicy
+		*out = new(AIGatewayRouteRuleToolPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ParameterPolicy != nil {
+		in, out := &in.ParameterPolicy, &out.ParameterPolicy
+		*out = new(ParameterPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ModelsOwnedBy != nil {
 		in, out := &in.ModelsOwnedBy, &out.ModelsOwnedBy
 		*out = new(string)
@@ -129,6 +181,31 @@ func (in *AIGatewayRouteRule) DeepCopy() *AIGatewayRouteRule {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleAffinity) DeepCopyInto(out *AIGatewayRouteRuleAffinity) {
+	*out = *in
+	if in.Messages != nil {
+		in, out := &in.Messages, &out.Messages
+		*out = new(int32)
+		**out = **in
+	}
+	if in.HeaderName != nil {
+		in, out := &in.HeaderName, &out.HeaderName
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleAffinity.
+func (in *AIGatewayRouteRuleAffinity) DeepCopy() *AIGatewayRouteRuleAffinity {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleAffinity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AIGatewayRouteRuleBackendRef) DeepCopyInto(out *AIGatewayRouteRuleBackendRef) {
 	*out = *in
@@ -167,6 +244,16 @@ func (in *AIGatewayRouteRuleBackendRef) DeepCopyInto(out *AIGatewayRouteRuleBack
 		*out = new(uint32)
 		**out = **in
 	}
+	if in.ContextWindow != nil {
+		in, out := &in.ContextWindow, &out.ContextWindow
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Price != nil {
+		in, out := &in.Price, &out.Price
+		*out = new(BackendPrice)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleBackendRef.
@@ -179,6 +266,186 @@ func (in *AIGatewayRouteRuleBackendRef) DeepCopy() *AIGatewayRouteRuleBackendRef
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleBackendSelection) DeepCopyInto(out *AIGatewayRouteRuleBackendSelection) {
+	*out = *in
+	if in.LatencyWeight != nil {
+		in, out := &in.LatencyWeight, &out.LatencyWeight
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CostWeight != nil {
+		in, out := &in.CostWeight, &out.CostWeight
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleBackendSelection.
+func (in *AIGatewayRouteRuleBackendSelection) DeepCopy() *AIGatewayRouteRuleBackendSelection {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleBackendSelection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleBodyMatch) DeepCopyInto(out *AIGatewayRouteRuleBodyMatch) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleBodyMatch.
+func (in *AIGatewayRouteRuleBodyMatch) DeepCopy() *AIGatewayRouteRuleBodyMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleBodyMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleExternalGuardrail) DeepCopyInto(out *AIGatewayRouteRuleExternalGuardrail) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Response != nil {
+		in, out := &in.Response, &out.Response
+		*out = new(AIGatewayRouteRuleExternalGuardrailResponse)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleExternalGuardrail.
+func (in *AIGatewayRouteRuleExternalGuardrail) DeepCopy() *AIGatewayRouteRuleExternalGuardrail {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleExternalGuardrail)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleExternalGuardrailResponse) DeepCopyInto(out *AIGatewayRouteRuleExternalGuardrailResponse) {
+	*out = *in
+	if in.WindowSize != nil {
+		in, out := &in.WindowSize, &out.WindowSize
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleExternalGuardrailResponse.
+func (in *AIGatewayRouteRuleExternalGuardrailResponse) DeepCopy() *AIGatewayRouteRuleExternalGuardrailResponse {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleExternalGuardrailResponse)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleFallbackAction) DeepCopyInto(out *AIGatewayRouteRuleFallbackAction) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleFallbackAction.
+func (in *AIGatewayRouteRuleFallbackAction) DeepCopy() *AIGatewayRouteRuleFallbackAction {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleFallbackAction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleFallbackPolicy) DeepCopyInto(out *AIGatewayRouteRuleFallbackPolicy) {
+	*out = *in
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]AIGatewayRouteRuleFallbackAction, len(*in))
+		copy(*out, *in)
+	}
+	if in.NumRetries != nil {
+		in, out := &in.NumRetries, &out.NumRetries
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleFallbackPolicy.
+func (in *AIGatewayRouteRuleFallbackPolicy) DeepCopy() *AIGatewayRouteRuleFallbackPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleFallbackPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleGuardrails) DeepCopyInto(out *AIGatewayRouteRuleGuardrails) {
+	*out = *in
+	if in.PII != nil {
+		in, out := &in.PII, &out.PII
+		*out = new(AIGatewayRouteRulePIIGuardrail)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.External != nil {
+		in, out := &in.External, &out.External
+		*out = new(AIGatewayRouteRuleExternalGuardrail)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PromptInjection != nil {
+		in, out := &in.PromptInjection, &out.PromptInjection
+		*out = new(AIGatewayRouteRulePromptInjectionGuardrail)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Output != nil {
+		in, out := &in.Output, &out.Output
+		*out = new(AIGatewayRouteRuleOutputGuardrail)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleGuardrails.
+func (in *AIGatewayRouteRuleGuardrails) DeepCopy() *AIGatewayRouteRuleGuardrails {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleGuardrails)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleHedgePolicy) DeepCopyInto(out *AIGatewayRouteRuleHedgePolicy) {
+	*out = *in
+	if in.MaxHedgedRequests != nil {
+		in, out := &in.MaxHedgedRequests, &out.MaxHedgedRequests
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleHedgePolicy.
+func (in *AIGatewayRouteRuleHedgePolicy) DeepCopy() *AIGatewayRouteRuleHedgePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleHedgePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AIGatewayRouteRuleMatch) DeepCopyInto(out *AIGatewayRouteRuleMatch) {
 	*out = *in
@@ -189,14 +456,288 @@ func (in *AIGatewayRouteRuleMatch) DeepCopyInto(out *AIGatewayRouteRuleMatch) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Body != nil {
+		in, out := &in.Body, &out.Body
+		*out = new(AIGatewayRouteRuleBodyMatch)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleMatch.
+func (in *AIGatewayRouteRuleMatch) DeepCopy() *AIGatewayRouteRuleMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleOutputFilterRule) DeepCopyInto(out *AIGatewayRouteRuleOutputFilterRule) {
+	*out = *in
+	if in.Regex != nil {
+		in, out := &in.Regex, &out.Regex
+		*out = new(string)
+		**out = **in
+	}
+	if in.Keywords != nil {
+		in, out := &in.Keywords, &out.Keywords
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleOutputFilterRule.
+func (in *AIGatewayRouteRuleOutputFilterRule) DeepCopy() *AIGatewayRouteRuleOutputFilterRule {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleOutputFilterRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleOutputGuardrail) DeepCopyInto(out *AIGatewayRouteRuleOutputGuardrail) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]AIGatewayRouteRuleOutputFilterRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WindowSize != nil {
+		in, out := &in.WindowSize, &out.WindowSize
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleOutputGuardrail.
+func (in *AIGatewayRouteRuleOutputGuardrail) DeepCopy() *AIGatewayRouteRuleOutputGuardrail {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleOutputGuardrail)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRulePIIGuardrail) DeepCopyInto(out *AIGatewayRouteRulePIIGuardrail) {
+	*out = *in
+	if in.Entities != nil {
+		in, out := &in.Entities, &out.Entities
+		*out = make([]PIIEntity, len(*in))
+		copy(*out, *in)
+	}
+	if in.Patterns != nil {
+		in, out := &in.Patterns, &out.Patterns
+		*out = make([]AIGatewayRouteRulePIIPattern, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRulePIIGuardrail.
+func (in *AIGatewayRouteRulePIIGuardrail) DeepCopy() *AIGatewayRouteRulePIIGuardrail {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRulePIIGuardrail)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRulePIIPattern) DeepCopyInto(out *AIGatewayRouteRulePIIPattern) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRulePIIPattern.
+func (in *AIGatewayRouteRulePIIPattern) DeepCopy() *AIGatewayRouteRulePIIPattern {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRulePIIPattern)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRulePromptInjectionGuardrail) DeepCopyInto(out *AIGatewayRouteRulePromptInjectionGuardrail) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]PromptInjectionRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.Patterns != nil {
+		in, out := &in.Patterns, &out.Patterns
+		*out = make([]AIGatewayRouteRulePromptInjectionPattern, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Threshold != nil {
+		in, out := &in.Threshold, &out.Threshold
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRulePromptInjectionGuardrail.
+func (in *AIGatewayRouteRulePromptInjectionGuardrail) DeepCopy() *AIGatewayRouteRulePromptInjectionGuardrail {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRulePromptInjectionGuardrail)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRulePromptInjectionPattern) DeepCopyInto(out *AIGatewayRouteRulePromptInjectionPattern) {
+	*out = *in
+	if in.Score != nil {
+		in, out := &in.Score, &out.Score
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRulePromptInjectionPattern.
+func (in *AIGatewayRouteRulePromptInjectionPattern) DeepCopy() *AIGatewayRouteRulePromptInjectionPattern {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRulePromptInjectionPattern)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleResponseCache) DeepCopyInto(out *AIGatewayRouteRuleResponseCache) {
+	*out = *in
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Semantic != nil {
+		in, out := &in.Semantic, &out.Semantic
+		*out = new(AIGatewayRouteRuleSemanticCache)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleResponseCache.
+func (in *AIGatewayRouteRuleResponseCache) DeepCopy() *AIGatewayRouteRuleResponseCache {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleResponseCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleSemanticCache) DeepCopyInto(out *AIGatewayRouteRuleSemanticCache) {
+	*out = *in
+	if in.SimilarityThreshold != nil {
+		in, out := &in.SimilarityThreshold, &out.SimilarityThreshold
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleSemanticCache.
+func (in *AIGatewayRouteRuleSemanticCache) DeepCopy() *AIGatewayRouteRuleSemanticCache {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleSemanticCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleShadow) DeepCopyInto(out *AIGatewayRouteRuleShadow) {
+	*out = *in
+	if in.Percent != nil {
+		in, out := &in.Percent, &out.Percent
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleShadow.
+func (in *AIGatewayRouteRuleShadow) DeepCopy() *AIGatewayRouteRuleShadow {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleShadow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleStreamFailover) DeepCopyInto(out *AIGatewayRouteRuleStreamFailover) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleStreamFailover.
+func (in *AIGatewayRouteRuleStreamFailover) DeepCopy() *AIGatewayRouteRuleStreamFailover {
+	if in == nil {
+		return nil
+	}
+	out := new(AIGatewayRouteRuleStreamFailover)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AIGatewayRouteRuleToolPolicy) DeepCopyInto(out *AIGatewayRouteRuleToolPolicy) {
+	*out = *in
+	if in.Allow != nil {
+		in, out := &in.Allow, &out.Allow
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowRegex != nil {
+		in, out := &in.AllowRegex, &out.AllowRegex
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Deny != nil {
+		in, out := &in.Deny, &out.Deny
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DenyRegex != nil {
+		in, out := &in.DenyRegex, &out.DenyRegex
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxTools != nil {
+		in, out := &in.MaxTools, &out.MaxTools
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxSchemaBytes != nil {
+		in, out := &in.MaxSchemaBytes, &out.MaxSchemaBytes
+		*out = new(int32)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleMatch.
-func (in *AIGatewayRouteRuleMatch) DeepCopy() *AIGatewayRouteRuleMatch {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIGatewayRouteRuleToolPolicy.
+func (in *AIGatewayRouteRuleToolPolicy) DeepCopy() *AIGatewayRouteRuleToolPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(AIGatewayRouteRuleMatch)
+	out := new(AIGatewayRouteRuleToolPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -338,6 +879,26 @@ func (in *AIServiceBackendSpec) DeepCopyInto(out *AIServiceBackendSpec) {
 		*out = new(HTTPBodyMutation)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.PromptCaching != nil {
+		in, out := &in.PromptCaching, &out.PromptCaching
+		*out = new(PromptCaching)
+		**out = **in
+	}
+	if in.CircuitBreaker != nil {
+		in, out := &in.CircuitBreaker, &out.CircuitBreaker
+		*out = new(CircuitBreaker)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PromptPolicy != nil {
+		in, out := &in.PromptPolicy, &out.PromptPolicy
+		*out = new(PromptPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ParameterPolicy != nil {
+		in, out := &in.ParameterPolicy, &out.ParameterPolicy
+		*out = new(ParameterPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIServiceBackendSpec.
@@ -424,6 +985,21 @@ func (in *AzureOIDCExchangeToken) DeepCopy() *AzureOIDCExchangeToken {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackendPrice) DeepCopyInto(out *BackendPrice) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackendPrice.
+func (in *BackendPrice) DeepCopy() *BackendPrice {
+	if in == nil {
+		return nil
+	}
+	out := new(BackendPrice)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BackendSecurityPolicy) DeepCopyInto(out *BackendSecurityPolicy) {
 	*out = *in
@@ -706,6 +1282,36 @@ func (in *BackendSecurityPolicyStatus) DeepCopy() *BackendSecurityPolicyStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CircuitBreaker) DeepCopyInto(out *CircuitBreaker) {
+	*out = *in
+	if in.ConsecutiveFailures != nil {
+		in, out := &in.ConsecutiveFailures, &out.ConsecutiveFailures
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TimeToFirstToken != nil {
+		in, out := &in.TimeToFirstToken, &out.TimeToFirstToken
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.OpenDuration != nil {
+		in, out := &in.OpenDuration, &out.OpenDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CircuitBreaker.
+func (in *CircuitBreaker) DeepCopy() *CircuitBreaker {
+	if in == nil {
+		return nil
+	}
+	out := new(CircuitBreaker)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GCPCredentialsFile) DeepCopyInto(out *GCPCredentialsFile) {
 	*out = *in
@@ -1498,6 +2104,231 @@ func (in *MCPToolFilter) DeepCopy() *MCPToolFilter {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelAlias) DeepCopyInto(out *ModelAlias) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelAlias.
+func (in *ModelAlias) DeepCopy() *ModelAlias {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelAlias)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ModelAlias) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelAliasList) DeepCopyInto(out *ModelAliasList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ModelAlias, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelAliasList.
+func (in *ModelAliasList) DeepCopy() *ModelAliasList {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelAliasList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ModelAliasList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelAliasModel) DeepCopyInto(out *ModelAliasModel) {
+	*out = *in
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelAliasModel.
+func (in *ModelAliasModel) DeepCopy() *ModelAliasModel {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelAliasModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelAliasModelStatus) DeepCopyInto(out *ModelAliasModelStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelAliasModelStatus.
+func (in *ModelAliasModelStatus) DeepCopy() *ModelAliasModelStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelAliasModelStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelAliasRollout) DeepCopyInto(out *ModelAliasRollout) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelAliasRollout.
+func (in *ModelAliasRollout) DeepCopy() *ModelAliasRollout {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelAliasRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelAliasSpec) DeepCopyInto(out *ModelAliasSpec) {
+	*out = *in
+	if in.Models != nil {
+		in, out := &in.Models, &out.Models
+		*out = make([]ModelAliasModel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(ModelAliasRollout)
+		**out = **in
+	}
+	if in.StickyHeader != nil {
+		in, out := &in.StickyHeader, &out.StickyHeader
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelAliasSpec.
+func (in *ModelAliasSpec) DeepCopy() *ModelAliasSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelAliasSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelAliasStatus) DeepCopyInto(out *ModelAliasStatus) {
+	*out = *in
+	if in.Models != nil {
+		in, out := &in.Models, &out.Models
+		*out = make([]ModelAliasModelStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelAliasStatus.
+func (in *ModelAliasStatus) DeepCopy() *ModelAliasStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelAliasStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ParameterPolicy) DeepCopyInto(out *ParameterPolicy) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]ParameterPolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BlockedFields != nil {
+		in, out := &in.BlockedFields, &out.BlockedFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParameterPolicy.
+func (in *ParameterPolicy) DeepCopy() *ParameterPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ParameterPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ParameterPolicyRule) DeepCopyInto(out *ParameterPolicyRule) {
+	*out = *in
+	if in.Default != nil {
+		in, out := &in.Default, &out.Default
+		*out = new(string)
+		**out = **in
+	}
+	if in.Min != nil {
+		in, out := &in.Min, &out.Min
+		*out = new(string)
+		**out = **in
+	}
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParameterPolicyRule.
+func (in *ParameterPolicyRule) DeepCopy() *ParameterPolicyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ParameterPolicyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PerModelQuota) DeepCopyInto(out *PerModelQuota) {
 	*out = *in
@@ -1519,6 +2350,56 @@ func (in *PerModelQuota) DeepCopy() *PerModelQuota {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PromptCaching) DeepCopyInto(out *PromptCaching) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PromptCaching.
+func (in *PromptCaching) DeepCopy() *PromptCaching {
+	if in == nil {
+		return nil
+	}
+	out := new(PromptCaching)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PromptPolicy) DeepCopyInto(out *PromptPolicy) {
+	*out = *in
+	if in.Messages != nil {
+		in, out := &in.Messages, &out.Messages
+		*out = make([]PromptPolicyMessage, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PromptPolicy.
+func (in *PromptPolicy) DeepCopy() *PromptPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PromptPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PromptPolicyMessage) DeepCopyInto(out *PromptPolicyMessage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PromptPolicyMessage.
+func (in *PromptPolicyMessage) DeepCopy() *PromptPolicyMessage {
+	if in == nil {
+		return nil
+	}
+	out := new(PromptPolicyMessage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProtectedResourceMetadata) DeepCopyInto(out *ProtectedResourceMetadata) {
 	*out = *in
@@ -92,9 +92,14 @@ type AIGatewayRouteSpec struct {
 	// HTTPRoute.spec.rules (one slot is reserved for a controller-injected catch-all rule). To
 	// configure more rules on the same Gateway, split them across multiple AIGatewayRoute resources.
 	//
+	// The HTTPRoute rules generated for the features of the rules count toward the same limit of 16:
+	// a rule with a backendSelection generates one per backend ref, and each of its shadows, its
+	// streamFailover and its semantic responseCache generate one each.
+	//
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:MaxItems=15
 	// +kubebuilder:validation:XValidation:rule="self.all(r1, !has(r1.name) || self.exists_one(r2, has(r2.name) && r1.name == r2.name))", message="rule name must be unique within the route"
+	// +kubebuilder:validation:XValidation:rule="size(self) + 1 + self.map(r, (has(r.backendSelection) && has(r.backendRefs) ? size(r.backendRefs) : 0) + (has(r.shadows) ? size(r.shadows) : 0) + (has(r.streamFailover) ? 1 : 0) + (has(r.responseCache) && has(r.responseCache.semantic) ? 1 : 0)).sum() <= 16", message="the rules generate more than 16 HTTPRoute rules including the ones of the backend selections, shadows, stream failovers and semantic caches; split the rules across multiple AIGatewayRoute resources"
 	Rules []AIGatewayRouteRule `json:"rules"`
 
 	// LLMRequestCosts specifies how to capture the cost of the LLM-related request, notably the token usage.
@@ -213,6 +218,12 @@ type AIGatewayRouteSpec struct {
 // +kubebuilder:validation:XValidation:rule="!has(self.name) || self.name != 'route-not-found'", message="rule name route-not-found is reserved"
 // +kubebuilder:validation:XValidation:rule="!has(self.backendRefs) || size(self.backendRefs) == 0 || (self.backendRefs.all(ref, !has(ref.group) && !has(ref.kind)) || self.backendRefs.all(ref, has(ref.group) && has(ref.kind)))", message="cannot mix InferencePool and AIServiceBackend references in the same rule"
 // +kubebuilder:validation:XValidation:rule="!has(self.backendRefs) || size(self.backendRefs) == 0 || !self.backendRefs.exists(ref, has(ref.group) && has(ref.kind)) || size(self.backendRefs) == 1", message="only one InferencePool backend is allowed per rule"
+// +kubebuilder:validation:XValidation:rule="!has(self.backendSelection) || !has(self.backendRefs) || self.backendRefs.all(ref, !has(ref.group))", message="backendSelection cannot be used with InferencePool backends"
+// +kubebuilder:validation:XValidation:rule="!has(self.backendSelection) || self.backendSelection.objective == 'Fastest' || !has(self.backendRefs) || self.backendRefs.all(ref, has(ref.price))", message="all backends must have a price when the backendSelection objective is Cheapest or Weighted"
+// +kubebuilder:validation:XValidation:rule="!has(self.affinity) || !has(self.backendSelection)", message="affinity and backendSelection cannot be used together"
+// +kubebuilder:validation:XValidation:rule="!has(self.hedgePolicy) || !has(self.backendRefs) || self.backendRefs.all(ref, !has(ref.group))", message="hedgePolicy cannot be used with InferencePool backends"
+// +kubebuilder:validation:XValidation:rule="!has(self.shadows) || !has(self.backendRefs) || self.backendRefs.all(ref, !has(ref.group))", message="shadows cannot be used with InferencePool backends"
+// +kubebuilder:validation:XValidation:rule="!has(self.streamFailover) || !has(self.backendRefs) || self.backendRefs.all(ref, !has(ref.group))", message="streamFailover cannot be used with InferencePool backends"
 type AIGatewayRouteRule struct {
 	// Name is the name of the route rule. This name must be unique within the route.
 	// When specified, it is copied to the generated HTTPRoute rule name.
@@ -280,6 +291,136 @@ type AIGatewayRouteRule struct {
 	// +optional
 	StreamIdleTimeout *gwapiv1.Duration `json:"streamIdleTimeout,omitempty"`
 
+	// FallbackPolicy configures how the error responses of the backends of this rule are handled depending on
+	// the class of the error, e.g., rate limit or context length exceeded, instead of the status code alone.
+	//
+	// The AI Gateway classifies each error response of a backend after translating it, so the same policy
+	// applies across backends of heterogeneous providers, and tells Envoy whether to retry the request on the
+	// same priority, to fail over to the next priority, or to return the error to the client immediately.
+	// The class of the final error is recorded in the metrics and the tracing spans as "error.type".
+	//
+	// When this is set, the retry policy of the generated routes only retries on the decision of the AI Gateway
+	// and on connection failures, and the status based retry conditions are not used.
+	//
+	// +optional
+	FallbackPolicy *AIGatewayRouteRuleFallbackPolicy `json:"fallbackPolicy,omitempty"`
+
+	// HedgePolicy configures the AI Gateway to send another copy of a streaming request to another backend of this
+	// rule when the backend has not started responding within a delay, e.g., for the latency critical interactive
+	// chat. The response that starts first is returned to the client, and the other requests are cancelled. The
+	// non-streaming requests are not hedged.
+	//
+	// Only the returned response counts toward the LLMRequestCosts and the quota, and the hedged requests are
+	// recorded as the events of the tracing span of the request.
+	//
+	// This cannot be used with InferencePool backends.
+	//
+	// +optional
+	HedgePolicy *AIGatewayRouteRuleHedgePolicy `json:"hedgePolicy,omitempty"`
+
+	// BackendSelection configures the AI Gateway to pick the preferred backend of this rule for each request
+	// based on the observed latency and the price of the backends, instead of the weighted load balancing alone.
+	//
+	// The preferred backend is chosen among the backends of the lowest priority, and the request is sent to it
+	// first. The other backends are kept as the fallback in their priority order, so that the retries and the
+	// fallback policy work as usual. When no backend can be preferred, e.g., before any latency is observed with
+	// the Fastest objective, the request is load balanced as if this was not set.
+	//
+	// This cannot be used with InferencePool backends.
+	//
+	// +optional
+	BackendSelection *AIGatewayRouteRuleBackendSelection `json:"backendSelection,omitempty"`
+
+	// Affinity configures the requests sharing the same key, e.g., the same system prompt or the same session, to be
+	// sent to the same backend of this rule, so that the prefix caches of the providers and the model servers are hit
+	// more often. The key is hashed onto a consistent hash ring across the backends of each priority, so that only a
+	// small part of the keys move to another backend when a backend is added or removed. When a cluster is generated
+	// per backend, e.g., when the backends need different settings, the backend is picked with the hash of the key
+	// weighted by the weights of the backends instead, so most of the keys move when a backend is added or removed.
+	//
+	// For InferencePool backends, the endpoint picker selects the endpoint of each request, and the key is not hashed
+	// by Envoy. With the Prompt type, the key is still set in the request header, which the endpoint picker of the
+	// Gateway API Inference Extension does not read, but a custom endpoint picker can.
+	//
+	// +optional
+	Affinity *AIGatewayRouteRuleAffinity `json:"affinity,omitempty"`
+
+	// Shadows is the list of the backends to which a sampled copy of the requests of this rule is mirrored, e.g., to
+	// evaluate a new model on the production traffic without affecting the clients.
+	//
+	// Each copy is translated to the API schema of the shadow backend and authenticated with its
+	// BackendSecurityPolicy like the requests to the backends of this rule, and sent asynchronously after the request
+	// is sent to its backend. The response of the shadow backend is discarded, and its latency and token usage are
+	// recorded in the metrics with the "shadow" attribute set to the name of the shadow backend. The copies do not
+	// count toward the LLMRequestCosts and the quota.
+	//
+	// This cannot be used with InferencePool backends.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=4
+	Shadows []AIGatewayRouteRuleShadow `json:"shadows,omitempty"`
+
+	// StreamFailover configures the AI Gateway to continue a streaming response of this rule on another backend when
+	// the stream of the backend terminates before it completes, e.g., when the provider drops the connection or sends
+	// an error event halfway through the response.
+	//
+	// The partial output returned to the client so far is buffered, and on the premature termination the request is
+	// re-issued to the failover backend with the partial output appended as the assistant message to continue from.
+	// The continuation is stitched onto the same stream returned to the client, and the token usage of both backends
+	// is merged and reported once, counting toward the LLMRequestCosts and the quota.
+	//
+	// Only the streaming requests of the chat completions and the messages endpoints whose partial output is text
+	// are continued. The ones with tool calls, reasoning or multiple choices are returned as is.
+	//
+	// This cannot be used with InferencePool backends.
+	//
+	// +optional
+	StreamFailover *AIGatewayRouteRuleStreamFailover `json:"streamFailover,omitempty"`
+
+	// ResponseCache enables the cache of the responses of this rule, so that the repeated identical requests, e.g.,
+	// the deterministic requests of CI and evaluation workloads, are served without being sent to the backend.
+	//
+	// The responses are keyed on the hash of the canonicalized request body sent to the backend, i.e., after the
+	// translation to the API schema of the backend, together with the backend and the path. Only the successful
+	// responses are cached, and the streaming responses are replayed as a single server-sent event stream. The
+	// requests with the "Cache-Control: no-cache" header are not served from the cache but refresh it, and the ones
+	// with the "Cache-Control: no-store" header bypass the cache.
+	//
+	// The cache hits are recorded in the metrics with the "cache" attribute set to "hit" and zero token usage, and do
+	// not count toward the LLMRequestCosts and the quota. The store of the cache is configured on the external
+	// processor, which defaults to an in-memory LRU cache.
+	//
+	// +optional
+	ResponseCache *AIGatewayRouteRuleResponseCache `json:"responseCache,omitempty"`
+
+	// Guardrails are the checks applied by the AI Gateway to the requests of this rule before they are sent to the
+	// backends and to their responses, e.g., to mask the personally identifiable information in the prompts.
+	//
+	// +optional
+	Guardrails *AIGatewayRouteRuleGuardrails `json:"guardrails,omitempty"`
+
+	// PromptPolicy adds the system and developer messages enforced by the AI Gateway to the requests of this rule,
+	// e.g., the safety instructions of the organization, and optionally rejects the requests with their own system
+	// prompts. The messages of the PromptPolicy of the AIServiceBackend, if any, are added after the ones of this rule.
+	//
+	// +optional
+	PromptPolicy *PromptPolicy `json:"promptPolicy,omitempty"`
+
+	// ToolPolicy controls the tools the clients may declare in the requests of this rule and the tool calls the models
+	// may return in their responses, e.g., to only allow the functions reviewed by the organization. The requests and
+	// the responses are inspected in the API schema of the client, so the policy applies to any backend of the rule.
+	//
+	// +optional
+	ToolPolicy *AIGatewayRouteRuleToolPolicy `json:"toolPolicy,omitempty"`
+
+	// ParameterPolicy sets the defaults of the generation parameters of the requests of this rule, and clamps or
+	// rejects their values out of the allowed ranges, e.g., to cap the maximum number of the output tokens of the
+	// expensive models. The rules of the ParameterPolicy of the AIServiceBackend, if any, are applied after the ones
+	// of this rule.
+	//
+	// +optional
+	ParameterPolicy *ParameterPolicy `json:"parameterPolicy,omitempty"`
+
 	// ModelsOwnedBy represents the owner of the running models serving by the backends,
 	// which will be exported as the field of "OwnedBy" in openai-compatible API "/models".
 	//
@@ -308,6 +449,792 @@ type AIGatewayRouteRule struct {
 	ModelsCreatedAt *metav1.Time `json:"modelsCreatedAt,omitempty"`
 }
 
+// AIGatewayRouteRuleBackendSelection configures how the preferred backend of a rule is chosen.
+//
+// The latency of a backend is the time to first token plus the inter-token latency times 256 output tokens, where
+// both are the exponentially weighted moving averages observed on its responses by each instance of the AI Gateway,
+// divided by its recent success rate so that the failing backends are avoided. The cost of a request on a backend is
+// computed from its price, the estimated number of the prompt tokens of the request and 256 output tokens.
+//
+// A backend without any observed latency is selected for a small fraction of the requests with the Fastest and
+// Weighted objectives so that its latency gets measured.
+//
+// +kubebuilder:validation:XValidation:rule="self.objective == 'Weighted' || (!has(self.latencyWeight) && !has(self.costWeight))", message="latencyWeight and costWeight can only be set with the Weighted objective"
+type AIGatewayRouteRuleBackendSelection struct {
+	// Objective is the objective of the selection:
+	//
+	//   - Fastest: prefer the backend with the lowest latency.
+	//   - Cheapest: prefer the backend with the lowest cost of the request. This requires the price of all the backends.
+	//   - Weighted: prefer the backend with the lowest weighted sum of the latency and the cost, each relative to the
+	//     highest one among the backends. This requires the price of all the backends.
+	//
+	// +kubebuilder:validation:Enum=Fastest;Cheapest;Weighted
+	Objective BackendSelectionObjective `json:"objective"`
+
+	// LatencyWeight is the weight of the latency with the Weighted objective.
+	//
+	// Default is 1.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	LatencyWeight *int32 `json:"latencyWeight,omitempty"`
+
+	// CostWeight is the weight of the cost with the Weighted objective.
+	//
+	// Default is 1.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	CostWeight *int32 `json:"costWeight,omitempty"`
+}
+
+// BackendSelectionObjective is the objective of the backend selection of a rule.
+type BackendSelectionObjective string
+
+const (
+	// BackendSelectionObjectiveFastest prefers the backend with the lowest latency.
+	BackendSelectionObjectiveFastest BackendSelectionObjective = "Fastest"
+	// BackendSelectionObjectiveCheapest prefers the backend with the lowest cost of the request.
+	BackendSelectionObjectiveCheapest BackendSelectionObjective = "Cheapest"
+	// BackendSelectionObjectiveWeighted prefers the backend with the lowest weighted sum of the latency and the cost.
+	BackendSelectionObjectiveWeighted BackendSelectionObjective = "Weighted"
+)
+
+// AIGatewayRouteRuleAffinity configures the key of the requests sent to the same backend of a rule.
+//
+// +kubebuilder:validation:XValidation:rule="self.type == 'Header' ? has(self.headerName) : !has(self.headerName)", message="headerName must be set if and only if the type is Header"
+// +kubebuilder:validation:XValidation:rule="self.type == 'Prompt' || !has(self.messages)", message="messages can only be set with the Prompt type"
+type AIGatewayRouteRuleAffinity struct {
+	// Type is the type of the key:
+	//
+	//   - Prompt: the hash of the system prompt and the first messages of the request, computed by the AI Gateway.
+	//     The key is set in the request header "x-ai-eg-affinity-prompt-<messages>", e.g.,
+	//     "x-ai-eg-affinity-prompt-1".
+	//   - Header: the value of the request header named HeaderName, e.g., a session or a user ID. The requests
+	//     without the header are load balanced randomly.
+	//
+	// +kubebuilder:validation:Enum=Prompt;Header
+	Type AffinityType `json:"type"`
+
+	// Messages is the number of the messages following the system prompt that are part of the key with the Prompt
+	// type. The system prompt consists of the system field of the request, e.g. "system" or "instructions", and the
+	// leading system and developer messages.
+	//
+	// Default is 1.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=32
+	Messages *int32 `json:"messages,omitempty"`
+
+	// HeaderName is the name of the request header whose value is the key with the Header type.
+	//
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	HeaderName *string `json:"headerName,omitempty"`
+}
+
+// AffinityType is the type of the key of an affinity.
+type AffinityType string
+
+const (
+	// AffinityTypePrompt uses the hash of the system prompt and the first messages of the request as the key.
+	AffinityTypePrompt AffinityType = "Prompt"
+	// AffinityTypeHeader uses the value of a request header as the key.
+	AffinityTypeHeader AffinityType = "Header"
+)
+
+// AIGatewayRouteRuleShadow is a backend to which the requests of a rule are mirrored.
+type AIGatewayRouteRuleShadow struct {
+	// Name is the name of the AIServiceBackend in the same namespace as the AIGatewayRoute.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// ModelNameOverride is the name of the model in the shadow backend. If provided this will override the name
+	// provided in the request.
+	//
+	// +optional
+	ModelNameOverride string `json:"modelNameOverride,omitempty"`
+
+	// Percent is the percentage of the requests of the rule that are mirrored to the shadow backend.
+	//
+	// Default is 100.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=100
+	Percent *int32 `json:"percent,omitempty"`
+
+	// CompareResponses enables the comparison of the response of the shadow backend with the response returned to
+	// the client. The similarity of the texts of the two responses, from 0 to 1, is recorded in the
+	// "aigw.shadow.similarity" metric. Only the non-streaming responses are compared.
+	//
+	// +optional
+	CompareResponses bool `json:"compareResponses,omitempty"`
+}
+
+// AIGatewayRouteRuleStreamFailover is the backend on which the truncated streaming responses of a rule are continued.
+type AIGatewayRouteRuleStreamFailover struct {
+	// Name is the name of the AIServiceBackend in the same namespace as the AIGatewayRoute.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// ModelNameOverride is the name of the model in the failover backend. If provided this will override the name
+	// provided in the request.
+	//
+	// +optional
+	ModelNameOverride string `json:"modelNameOverride,omitempty"`
+}
+
+// AIGatewayRouteRuleResponseCache configures the cache of the responses of a rule.
+type AIGatewayRouteRuleResponseCache struct {
+	// TTL is the time for which a cached response is served. Default is 1h.
+	//
+	// +optional
+	// +kubebuilder:default="1h"
+	TTL *gwapiv1.Duration `json:"ttl,omitempty"`
+
+	// Semantic enables the semantic mode of the cache, in which a request whose last user message is similar enough
+	// to the one of a cached request is served the cached response as well, e.g., the same question phrased
+	// differently.
+	//
+	// The last user message is embedded through the embeddings backend, and compared with the ones of the cached
+	// requests that are otherwise identical, i.e., sent to the same backend with the same model, parameters and earlier
+	// messages. The exact match is always looked up first. Only the chat completions and the messages endpoints are
+	// supported in the semantic mode.
+	//
+	// +optional
+	Semantic *AIGatewayRouteRuleSemanticCache `json:"semantic,omitempty"`
+}
+
+// AIGatewayRouteRuleSemanticCache configures the semantic mode of the response cache of a rule.
+type AIGatewayRouteRuleSemanticCache struct {
+	// EmbeddingsBackend is the name of the AIServiceBackend in the same namespace as the AIGatewayRoute, to which the
+	// embeddings requests are sent. The requests are translated to the API schema of the backend and authenticated with
+	// its BackendSecurityPolicy like the requests to the backends of the rule.
+	//
+	// +kubebuilder:validation:MinLength=1
+	EmbeddingsBackend string `json:"embeddingsBackend"`
+
+	// EmbeddingsModel is the name of the embedding model of the embeddings backend.
+	//
+	// +kubebuilder:validation:MinLength=1
+	EmbeddingsModel string `json:"embeddingsModel"`
+
+	// SimilarityThreshold is the minimum cosine similarity in percent of the last user messages, at or above which the
+	// cached response is served. Default is 95.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=95
+	SimilarityThreshold *int32 `json:"similarityThreshold,omitempty"`
+}
+
+// AIGatewayRouteRuleGuardrails configures the guardrails of a rule.
+type AIGatewayRouteRuleGuardrails struct {
+	// PII detects the personally identifiable information in the inputs of the chat completions, the messages and the
+	// responses requests, i.e., the text of the messages, the system prompt and the instructions, and masks, blocks or
+	// tokenizes it before the request is sent to the backend. The requests of the other endpoints are not inspected.
+	//
+	// The requests are inspected before the translation to the API schema of the backend, so the masked or tokenized
+	// request is also the one sent to the shadow backends and used as the key of the response cache. The detections
+	// are recorded as the span attributes and in the "aigw.guardrail.pii.detections" metric.
+	//
+	// +optional
+	PII *AIGatewayRouteRulePIIGuardrail `json:"pii,omitempty"`
+
+	// External calls an external guardrail service, e.g., an in-house safety classifier, with the normalized view of
+	// the chat completions, the messages and the responses requests, i.e., the model, the text of the messages and the
+	// tools, which is the same regardless of the API schema of the request. The service allows, denies or rewrites the
+	// request before it is sent to the backend, and optionally checks the output of the backend.
+	//
+	// The service is called after the PII guardrail, so it inspects the request as it is sent to the backend. The
+	// checks are recorded as the span events and in the "aigw.guardrail.external.checks" metric.
+	//
+	// +optional
+	External *AIGatewayRouteRuleExternalGuardrail `json:"external,omitempty"`
+
+	// PromptInjection detects the common prompt injection and jailbreak patterns, e.g., "ignore previous
+	// instructions", in the inputs of the end users and in the results of the tools fed back to the model, and blocks
+	// the requests with them or only logs them. The system prompts and the assistant messages are not inspected, nor
+	// are the requests of the endpoints whose texts are not followed by the model, e.g., the embeddings.
+	//
+	// The requests are inspected before the other guardrails. The detections are recorded as the span attributes and
+	// in the "aigw.guardrail.prompt_injection.detections" metric.
+	//
+	// +optional
+	PromptInjection *AIGatewayRouteRulePromptInjectionGuardrail `json:"promptInjection,omitempty"`
+
+	// Output filters the text output of the chat completions, the messages and the responses endpoints with the
+	// regular expressions and the keywords, and terminates the streaming responses or replaces the non-streaming ones
+	// matching them with the finish reason of the content filter of the API schema of the endpoint.
+	//
+	// The output is filtered before the external guardrail and the restoration of the tokens of the PII guardrail.
+	// The matches are logged, recorded as the span events and in the "aigw.guardrail.output.matches" metric.
+	//
+	// +optional
+	Output *AIGatewayRouteRuleOutputGuardrail `json:"output,omitempty"`
+}
+
+// AIGatewayRouteRuleExternalGuardrail configures the external guardrail service of a rule.
+//
+// The service receives the JSON object with the following fields, either as the body of a POST request for the HTTP
+// protocol, or as the google.protobuf.Struct request of the "envoy.ai_gateway.guardrail.v1.ExternalGuardrail/Check"
+// method for the GRPC protocol:
+//
+//   - phase: "request" or "response".
+//   - model: the model of the request sent to the backend.
+//   - messages: the texts of the request in their order as the objects with the "role", i.e., "system", "user",
+//     "assistant" or "tool", and the "content".
+//   - tools: the tools of the request as the objects with the "name" and the "description".
+//   - output: the text output of the backend so far on the response checks.
+//   - complete: true if the output is the whole text output of the backend.
+//
+// The service returns the object with the "decision", i.e., "allow", "deny" or "rewrite", the optional "reason"
+// returned to the client on denials, and, on rewrites, the "messages" replacing the content of the ones of the request
+// one to one. The denied requests are rejected with the 400 status.
+type AIGatewayRouteRuleExternalGuardrail struct {
+	// Protocol is the protocol of the service, i.e., HTTP or GRPC. Default is HTTP.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=HTTP;GRPC
+	// +kubebuilder:default=HTTP
+	Protocol ExternalGuardrailProtocol `json:"protocol,omitempty"`
+
+	// Endpoint is the URL of the service for the HTTP protocol, e.g., "http://classifier.safety:8080/check", or its
+	// target for the GRPC protocol, e.g., "classifier.safety:9090". The service is called by the external processor
+	// directly, and the GRPC protocol uses the plaintext connections.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Endpoint string `json:"endpoint"`
+
+	// Timeout is the maximum time of each call to the service, after which the check fails. Default is 1s.
+	//
+	// +optional
+	// +kubebuilder:default="1s"
+	Timeout *gwapiv1.Duration `json:"timeout,omitempty"`
+
+	// FailureMode is the behavior when the service cannot be called or returns an invalid response:
+	//
+	//   - FailClosed: reject the request with the 503 status, or terminate the response.
+	//   - FailOpen: let the request or the response through.
+	//
+	// Default is FailClosed.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=FailOpen;FailClosed
+	// +kubebuilder:default=FailClosed
+	FailureMode ExternalGuardrailFailureMode `json:"failureMode,omitempty"`
+
+	// Response enables the checks of the output of the backend. The responses are not checked if this is not set.
+	//
+	// +optional
+	Response *AIGatewayRouteRuleExternalGuardrailResponse `json:"response,omitempty"`
+}
+
+// ExternalGuardrailProtocol is the protocol of the external guardrail service.
+type ExternalGuardrailProtocol string
+
+const (
+	// ExternalGuardrailProtocolHTTP is the JSON POST requests.
+	ExternalGuardrailProtocolHTTP ExternalGuardrailProtocol = "HTTP"
+	// ExternalGuardrailProtocolGRPC is the gRPC requests with the google.protobuf.Struct messages.
+	ExternalGuardrailProtocolGRPC ExternalGuardrailProtocol = "GRPC"
+)
+
+// ExternalGuardrailFailureMode is the behavior when the external guardrail service fails.
+type ExternalGuardrailFailureMode string
+
+const (
+	// ExternalGuardrailFailureModeFailOpen lets the request or the response through.
+	ExternalGuardrailFailureModeFailOpen ExternalGuardrailFailureMode = "FailOpen"
+	// ExternalGuardrailFailureModeFailClosed rejects the request or terminates the response.
+	ExternalGuardrailFailureModeFailClosed ExternalGuardrailFailureMode = "FailClosed"
+)
+
+// AIGatewayRouteRuleExternalGuardrailResponse configures the checks of the output of the backend by the external
+// guardrail service.
+//
+// The non-streaming responses are checked once they complete, and the denied ones are replaced with the 400 error
+// response. The streaming responses are checked each time the text output held back reaches the WindowSize and at
+// the end of the stream, with the whole text output so far, and the events held back are returned to the client once
+// they are allowed. The denied streams are terminated with an error event in the schema of the endpoint.
+type AIGatewayRouteRuleExternalGuardrailResponse struct {
+	// WindowSize is the number of the characters of the text output of a streaming response held back between the
+	// checks. The larger windows call the service less often at the cost of the latency of the stream. Default is 500.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100000
+	// +kubebuilder:default=500
+	WindowSize *int32 `json:"windowSize,omitempty"`
+}
+
+// AIGatewayRouteRulePIIGuardrail configures the detection of the personally identifiable information of a rule.
+//
+// +kubebuilder:validation:XValidation:rule="!has(self.entities) || size(self.entities) > 0 || (has(self.patterns) && size(self.patterns) > 0)", message="at least one of entities or patterns must be set"
+type AIGatewayRouteRulePIIGuardrail struct {
+	// Action is the action taken on the detected information:
+	//
+	//   - Mask: replace each detection with the placeholder of its entity, e.g., "[EMAIL]".
+	//   - Block: reject the request with the 400 status.
+	//   - Tokenize: replace each detection with a token unique to the value within the request, e.g., "[EMAIL_1]",
+	//     and restore the original values in the response returned to the client, including the streaming ones.
+	//
+	// Default is Mask.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=Mask;Block;Tokenize
+	// +kubebuilder:default=Mask
+	Action PIIGuardrailAction `json:"action,omitempty"`
+
+	// Entities are the built-in types of the information to detect:
+	//
+	//   - Email: email addresses.
+	//   - PhoneNumber: phone numbers in the formats of the United States, e.g., "+1 415-555-2671" or
+	//     "(415) 555-2671", and in the E.164 format, e.g., "+14155552671".
+	//   - CreditCard: credit card numbers of 13 to 19 digits passing the Luhn check.
+	//   - NationalID: national identification numbers in the format of the US social security numbers, e.g.,
+	//     "123-45-6789".
+	//
+	// PhoneNumber and NationalID only cover the United States. To detect the formats of other countries, add a
+	// pattern of the same name to the Patterns.
+	//
+	// Default is all of them. An empty list only detects the Patterns.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=4
+	// +kubebuilder:validation:items:Enum=Email;PhoneNumber;CreditCard;NationalID
+	Entities []PIIEntity `json:"entities,omitempty"`
+
+	// Patterns are the custom types of the information to detect, e.g., the employee IDs.
+	//
+	// A pattern named after one of the Entities, e.g., "NationalID", replaces the built-in detection of the entity
+	// and keeps its placeholder, e.g., "[NATIONAL_ID]", whether or not the entity is listed in the Entities.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=32
+	Patterns []AIGatewayRouteRulePIIPattern `json:"patterns,omitempty"`
+}
+
+// PIIGuardrailAction is the action taken on the personally identifiable information detected in a request.
+type PIIGuardrailAction string
+
+const (
+	// PIIGuardrailActionMask replaces the detected information with the placeholder of its entity.
+	PIIGuardrailActionMask PIIGuardrailAction = "Mask"
+	// PIIGuardrailActionBlock rejects the request with the detected information.
+	PIIGuardrailActionBlock PIIGuardrailAction = "Block"
+	// PIIGuardrailActionTokenize replaces the detected information with tokens restored in the response.
+	PIIGuardrailActionTokenize PIIGuardrailAction = "Tokenize"
+)
+
+// PIIEntity is a built-in type of the personally identifiable information.
+type PIIEntity string
+
+const (
+	// PIIEntityEmail is the email addresses.
+	PIIEntityEmail PIIEntity = "Email"
+	// PIIEntityPhoneNumber is the phone numbers in the formats of the United States and the E.164 format.
+	PIIEntityPhoneNumber PIIEntity = "PhoneNumber"
+	// PIIEntityCreditCard is the credit card numbers.
+	PIIEntityCreditCard PIIEntity = "CreditCard"
+	// PIIEntityNationalID is the national identification numbers in the format of the US social security numbers.
+	PIIEntityNationalID PIIEntity = "NationalID"
+)
+
+// AIGatewayRouteRulePIIPattern is a custom type of the personally identifiable information.
+type AIGatewayRouteRulePIIPattern struct {
+	// Name is the name of the type, which is used in the placeholders and the tokens in upper case, e.g., "[EMPLOYEE_ID]"
+	// for "employee_id", as well as in the metrics.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=64
+	// +kubebuilder:validation:Pattern=`^[A-Za-z][A-Za-z0-9_]*$`
+	Name string `json:"name"`
+
+	// Regex is the RE2 regular expression matching the information, e.g., "EMP-[0-9]{6}".
+	//
+	// +kubebuilder:validation:MinLength=1
+	Regex string `json:"regex"`
+}
+
+// AIGatewayRouteRulePromptInjectionGuardrail configures the detection of the prompt injections of a rule.
+//
+// Each text of the request is scored with the sum of the scores of the rules matching it, and the injection is
+// detected in the request if any of its texts scores at or above the Threshold. The rules detect the signals that
+// are suspicious on their own to a varying degree, so the threshold can be tuned to the false positives tolerated,
+// e.g., in the Shadow mode before blocking the requests.
+//
+// +kubebuilder:validation:XValidation:rule="!has(self.rules) || size(self.rules) > 0 || (has(self.patterns) && size(self.patterns) > 0)", message="at least one of rules or patterns must be set"
+type AIGatewayRouteRulePromptInjectionGuardrail struct {
+	// Mode is the action taken on the requests with the detected injection:
+	//
+	//   - Block: reject the request with the 400 status.
+	//   - Shadow: only log and record the detection, and send the request to the backend.
+	//
+	// Default is Block.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=Block;Shadow
+	// +kubebuilder:default=Block
+	Mode PromptInjectionGuardrailMode `json:"mode,omitempty"`
+
+	// Rules are the built-in rules of the detection with their scores:
+	//
+	//   - IgnoreInstructions (60): the requests to ignore the previous instructions, e.g., "ignore all previous
+	//     instructions" or "disregard the rules above".
+	//   - RoleOverride (40): the attempts to assign an unrestricted role to the model, e.g., "you are now DAN" or
+	//     "developer mode".
+	//   - SystemPromptExtraction (40): the requests to reveal the system prompt, e.g., "print your system prompt".
+	//   - DelimiterInjection (30): the role markers of the chat templates, e.g., "<|im_start|>system" or "[INST]".
+	//   - HiddenUnicode (50): the invisible characters, i.e., the zero width characters, the bidirectional controls
+	//     and the Unicode tags.
+	//   - EncodedPayload (30): the base64 payloads of at least 32 characters decoding to a text, which are also
+	//     matched with the other rules.
+	//
+	// Default is all of them. An empty list only applies the Patterns.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=6
+	// +kubebuilder:validation:items:Enum=IgnoreInstructions;RoleOverride;SystemPromptExtraction;DelimiterInjection;HiddenUnicode;EncodedPayload
+	Rules []PromptInjectionRule `json:"rules,omitempty"`
+
+	// Patterns are the custom rules of the detection, e.g., the phrases of the attacks seen on the application.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=32
+	Patterns []AIGatewayRouteRulePromptInjectionPattern `json:"patterns,omitempty"`
+
+	// Threshold is the score of a text at or above which the injection is detected. With the default, a single rule
+	// scoring 50 or more is enough, while the rules of the lower scores need to match together. Default is 50.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1000
+	// +kubebuilder:default=50
+	Threshold *int32 `json:"threshold,omitempty"`
+}
+
+// PromptInjectionGuardrailMode is the action taken on the requests with the detected prompt injection.
+type PromptInjectionGuardrailMode string
+
+const (
+	// PromptInjectionGuardrailModeBlock rejects the requests with the detected injection.
+	PromptInjectionGuardrailModeBlock PromptInjectionGuardrailMode = "Block"
+	// PromptInjectionGuardrailModeShadow only logs and records the detected injection.
+	PromptInjectionGuardrailModeShadow PromptInjectionGuardrailMode = "Shadow"
+)
+
+// PromptInjectionRule is a built-in rule of the prompt injection detection.
+type PromptInjectionRule string
+
+const (
+	// PromptInjectionRuleIgnoreInstructions is the requests to ignore the previous instructions.
+	PromptInjectionRuleIgnoreInstructions PromptInjectionRule = "IgnoreInstructions"
+	// PromptInjectionRuleRoleOverride is the attempts to assign an unrestricted role to the model.
+	PromptInjectionRuleRoleOverride PromptInjectionRule = "RoleOverride"
+	// PromptInjectionRuleSystemPromptExtraction is the requests to reveal the system prompt.
+	PromptInjectionRuleSystemPromptExtraction PromptInjectionRule = "SystemPromptExtraction"
+	// PromptInjectionRuleDelimiterInjection is the role markers of the chat templates.
+	PromptInjectionRuleDelimiterInjection PromptInjectionRule = "DelimiterInjection"
+	// PromptInjectionRuleHiddenUnicode is the invisible characters.
+	PromptInjectionRuleHiddenUnicode PromptInjectionRule = "HiddenUnicode"
+	// PromptInjectionRuleEncodedPayload is the base64 payloads decoding to a text.
+	PromptInjectionRuleEncodedPayload PromptInjectionRule = "EncodedPayload"
+)
+
+// AIGatewayRouteRulePromptInjectionPattern is a custom rule of the prompt injection detection.
+type AIGatewayRouteRulePromptInjectionPattern struct {
+	// Name is the name of the rule, which is reported on the detections, e.g., in the metrics.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=64
+	// +kubebuilder:validation:Pattern=`^[A-Za-z][A-Za-z0-9_]*$`
+	Name string `json:"name"`
+
+	// Regex is the RE2 regular expression matching the injection, e.g., "(?i)send .* to https?://".
+	//
+	// +kubebuilder:validation:MinLength=1
+	Regex string `json:"regex"`
+
+	// Score is added to the score of the text matching the rule. Default is 50.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1000
+	// +kubebuilder:default=50
+	Score *int32 `json:"score,omitempty"`
+}
+
+// AIGatewayRouteRuleOutputGuardrail configures the filter of the text output of a rule.
+//
+// The non-streaming responses are filtered once they complete. The matching ones are returned with the text output
+// removed and the finish reason of the content filter, i.e., the "content_filter" finish_reason of the chat
+// completions, the "refusal" stop_reason of the messages and the incomplete status with the "content_filter" reason
+// of the responses.
+//
+// The streaming responses are filtered as they are generated, with each text delta matched together with the last
+// WindowSize characters of the text before it, so that the matches spanning several deltas are found. The events are
+// not held back, so the text before the match, including its beginning in the earlier events, reaches the client.
+// The matching streams are terminated with the events of the same finish reasons, after which the rest of the
+// response of the backend is dropped.
+type AIGatewayRouteRuleOutputGuardrail struct {
+	// Mode is the action taken on the output matching a rule:
+	//
+	//   - Block: terminate or replace the response as described above.
+	//   - Shadow: only log and record the match, and return the response as is.
+	//
+	// Default is Block.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=Block;Shadow
+	// +kubebuilder:default=Block
+	Mode OutputGuardrailMode `json:"mode,omitempty"`
+
+	// Rules are the rules of the filter, applied in their order. The first rule matching the output is reported.
+	//
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=32
+	Rules []AIGatewayRouteRuleOutputFilterRule `json:"rules"`
+
+	// WindowSize is the number of the characters of the text of a streaming response before each delta matched
+	// together with it, which bounds the length of the matches spanning several deltas. Default is 256.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=8192
+	// +kubebuilder:default=256
+	WindowSize *int32 `json:"windowSize,omitempty"`
+}
+
+// OutputGuardrailMode is the action taken on the output matching a rule of the output guardrail.
+type OutputGuardrailMode string
+
+const (
+	// OutputGuardrailModeBlock terminates or replaces the matching output.
+	OutputGuardrailModeBlock OutputGuardrailMode = "Block"
+	// OutputGuardrailModeShadow only logs and records the match.
+	OutputGuardrailModeShadow OutputGuardrailMode = "Shadow"
+)
+
+// AIGatewayRouteRuleOutputFilterRule is a rule of the output guardrail, which matches either a regular expression
+// or any of the keywords.
+//
+// +kubebuilder:validation:XValidation:rule="has(self.regex) != (has(self.keywords) && size(self.keywords) > 0)", message="exactly one of regex or keywords must be set"
+type AIGatewayRouteRuleOutputFilterRule struct {
+	// Name is the name of the rule, which is reported on the matches, e.g., in the metrics.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=64
+	// +kubebuilder:validation:Pattern=`^[A-Za-z][A-Za-z0-9_]*$`
+	Name string `json:"name"`
+
+	// Regex is the RE2 regular expression matching the output, e.g., "(?i)internal use only".
+	//
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	Regex *string `json:"regex,omitempty"`
+
+	// Keywords are the keywords matching the output anywhere in the text regardless of the case, e.g., "Project
+	// Orion".
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=256
+	// +kubebuilder:validation:items:MinLength=1
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// AIGatewayRouteRuleToolPolicy configures the tools allowed in the requests of a rule and in the responses.
+//
+// The tools are identified by their names, e.g., the name of the function, while the built-in tools of the providers
+// without a name are identified by their type, e.g., "google_search". A tool is allowed if it matches none of the
+// Deny and DenyRegex rules, and either matches one of the Allow and AllowRegex rules or none of them are set.
+//
+// +kubebuilder:validation:XValidation:rule="has(self.allow) || has(self.allowRegex) || has(self.deny) || has(self.denyRegex) || has(self.maxTools) || has(self.maxSchemaBytes) || (has(self.removeBuiltInTools) && self.removeBuiltInTools)", message="at least one of allow, allowRegex, deny, denyRegex, maxTools, maxSchemaBytes or removeBuiltInTools must be set"
+type AIGatewayRouteRuleToolPolicy struct {
+	// Action is the action taken on the disallowed tools:
+	//
+	//   - Block: reject the requests declaring them with the 400 status, and replace the responses calling them with
+	//     an error, or terminate the streaming responses with an error event.
+	//   - Strip: remove them from the requests, and remove the calls of them from the responses.
+	//
+	// Default is Block.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=Block;Strip
+	// +kubebuilder:default=Block
+	Action ToolPolicyAction `json:"action,omitempty"`
+
+	// Allow is the list of the names of the allowed tools.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=64
+	Allow []string `json:"allow,omitempty"`
+
+	// AllowRegex is the list of the RE2 regular expressions matching the names of the allowed tools, e.g., "^crm_".
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=32
+	AllowRegex []string `json:"allowRegex,omitempty"`
+
+	// Deny is the list of the names of the disallowed tools. This takes precedence over Allow and AllowRegex.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=64
+	Deny []string `json:"deny,omitempty"`
+
+	// DenyRegex is the list of the RE2 regular expressions matching the names of the disallowed tools, e.g.,
+	// "(?i)delete". This takes precedence over Allow and AllowRegex.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=32
+	DenyRegex []string `json:"denyRegex,omitempty"`
+
+	// MaxTools is the maximum number of the tools declared in a request after the disallowed ones are stripped. The
+	// requests declaring more tools are rejected with the 400 status.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxTools *int32 `json:"maxTools,omitempty"`
+
+	// MaxSchemaBytes is the maximum size in bytes of the JSON schema of the parameters of each tool declared in a
+	// request. The requests declaring a tool with a larger schema are rejected with the 400 status.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxSchemaBytes *int32 `json:"maxSchemaBytes,omitempty"`
+
+	// RemoveBuiltInTools removes the built-in tools of the providers from the requests regardless of the Action, e.g.,
+	// the web search and the code execution tools, so that only the tools executed by the client are declared. These
+	// are the tools of a type other than "function" and "custom", as well as the "web_search_options" of the chat
+	// completions.
+	//
+	// +optional
+	RemoveBuiltInTools bool `json:"removeBuiltInTools,omitempty"`
+}
+
+// ToolPolicyAction is the action taken on the disallowed tools.
+type ToolPolicyAction string
+
+const (
+	// ToolPolicyActionBlock rejects the requests and the responses with the disallowed tools.
+	ToolPolicyActionBlock ToolPolicyAction = "Block"
+	// ToolPolicyActionStrip removes the disallowed tools from the requests and the responses.
+	ToolPolicyActionStrip ToolPolicyAction = "Strip"
+)
+
+// AIGatewayRouteRuleFallbackPolicy configures the action taken for each class of the error responses of the backends.
+//
+// The error classes that are not listed, as well as the errors that cannot be classified, are returned to the
+// client immediately.
+//
+// When the policy has both Retry and Failover actions, the request is tried at most twice on each priority: an
+// error of a class with the Retry action is retried once on the same priority before the next error moves the
+// request to the next priority, and an error of a class with the Failover action moves it to the next priority
+// right away.
+type AIGatewayRouteRuleFallbackPolicy struct {
+	// Actions is the list of the actions taken per error class.
+	//
+	// +listType=map
+	// +listMapKey=errorClass
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=5
+	Actions []AIGatewayRouteRuleFallbackAction `json:"actions"`
+
+	// NumRetries is the maximum number of retries of a request, including the failovers. When the policy has both
+	// Retry and Failover actions, a failover right after the first attempt on a priority counts as two retries.
+	//
+	// Default is 2.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:default=2
+	NumRetries *int32 `json:"numRetries,omitempty"`
+}
+
+// AIGatewayRouteRuleHedgePolicy configures when a request is hedged.
+//
+// A streaming request is hedged when no backend has started responding, i.e., returned the response headers, within
+// Delay since the last request was sent. Most providers return the headers of a streaming response together with its
+// first token, so the delay bounds the time to first token of the request. The non-streaming requests are not hedged,
+// since their response headers are only returned once the whole response is generated.
+//
+// The hedged requests are sent to the backends that have not been tried for the request yet, and they count
+// toward the retries of the route, including the ones of the FallbackPolicy.
+type AIGatewayRouteRuleHedgePolicy struct {
+	// Delay is the time after which another copy of the request is sent if no backend has started responding.
+	Delay gwapiv1.Duration `json:"delay"`
+
+	// MaxHedgedRequests is the maximum number of the additional copies of a request.
+	//
+	// Default is 1.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=3
+	// +kubebuilder:default=1
+	MaxHedgedRequests *int32 `json:"maxHedgedRequests,omitempty"`
+}
+
+// AIGatewayRouteRuleFallbackAction is the action taken when a backend returns an error of the class.
+type AIGatewayRouteRuleFallbackAction struct {
+	// ErrorClass is the class of the error response:
+	//
+	//   - RateLimit: the backend rate limited the request, e.g., a 429 response.
+	//   - Overloaded: the backend is temporarily unable to serve the request, e.g., a 503 or 529 response.
+	//   - ContextLength: the prompt exceeds the context window of the model.
+	//   - ContentFilter: the prompt was rejected by the content filter of the provider.
+	//   - Auth: the backend rejected the credentials, e.g., a 401 or 403 response.
+	//
+	// +kubebuilder:validation:Enum=RateLimit;Overloaded;ContextLength;ContentFilter;Auth
+	ErrorClass ErrorClass `json:"errorClass"`
+
+	// Action is the action taken for the error class:
+	//
+	//   - Retry: retry the request on a backend of the same priority.
+	//   - Failover: retry the request on a backend of the next priority.
+	//   - Return: return the error to the client immediately.
+	//
+	// +kubebuilder:validation:Enum=Retry;Failover;Return
+	Action FallbackActionType `json:"action"`
+}
+
+// ErrorClass is the class of an error response of a backend.
+type ErrorClass string
+
+const (
+	// ErrorClassRateLimit is the class of the rate limited requests.
+	ErrorClassRateLimit ErrorClass = "RateLimit"
+	// ErrorClassOverloaded is the class of the requests rejected by an overloaded backend.
+	ErrorClassOverloaded ErrorClass = "Overloaded"
+	// ErrorClassContextLength is the class of the requests exceeding the context window of the model.
+	ErrorClassContextLength ErrorClass = "ContextLength"
+	// ErrorClassContentFilter is the class of the requests rejected by the content filter of the provider.
+	ErrorClassContentFilter ErrorClass = "ContentFilter"
+	// ErrorClassAuth is the class of the requests rejected due to the credentials.
+	ErrorClassAuth ErrorClass = "Auth"
+)
+
+// FallbackActionType is the action taken for an error class.
+type FallbackActionType string
+
+const (
+	// FallbackActionTypeRetry retries the request on a backend of the same priority.
+	FallbackActionTypeRetry FallbackActionType = "Retry"
+	// FallbackActionTypeFailover retries the request on a backend of the next priority.
+	FallbackActionTypeFailover FallbackActionType = "Failover"
+	// FallbackActionTypeReturn returns the error to the client immediately.
+	FallbackActionTypeReturn FallbackActionType = "Return"
+)
+
 // AIGatewayRouteRuleBackendRef is a reference to a backend with a weight.
 // It can reference either an AIServiceBackend or an InferencePool resource.
 //
@@ -395,6 +1322,40 @@ type AIGatewayRouteRuleBackendRef struct {
 	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:default=0
 	Priority *uint32 `json:"priority,omitempty"`
+
+	// ContextWindow is the maximum number of tokens that the model served by this backend accepts.
+	// When set, the AI Gateway estimates the number of prompt tokens of each request, and retries a request
+	// that does not fit on the other backends of the rule instead of sending it to this backend. The request
+	// is rejected with a 400 error when none of them can fit it.
+	//
+	// When all the backends of a rule have a context window, the rule only matches the requests fitting in
+	// the largest of them, so that larger requests fall through to the next matching rule, e.g., one routing
+	// to a model with a larger context window. When no rule that can match the requested model can fit the
+	// request, it is rejected early.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ContextWindow *int32 `json:"contextWindow,omitempty"`
+
+	// Price is the price of the model served by this backend, used by the backend selection of the rule.
+	// This field is ignored when referencing InferencePool resources.
+	//
+	// +optional
+	Price *BackendPrice `json:"price,omitempty"`
+}
+
+// BackendPrice is the price of the tokens of a backend per million tokens. The unit is arbitrary, e.g. US dollars,
+// as long as it is the same across the backends of the rule.
+type BackendPrice struct {
+	// InputTokens is the price of a million input tokens as a decimal number, e.g. "0.15".
+	//
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	InputTokens string `json:"inputTokens"`
+
+	// OutputTokens is the price of a million output tokens as a decimal number, e.g. "0.6".
+	//
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	OutputTokens string `json:"outputTokens"`
 }
 
 type AIGatewayRouteRuleMatch struct {
@@ -406,6 +1367,39 @@ type AIGatewayRouteRuleMatch struct {
 	// +optional
 	// +kubebuilder:validation:MaxItems=16
 	Headers []gwapiv1.HTTPHeaderMatch `json:"headers,omitempty"`
+
+	// Body specifies a match on the request body, which is evaluated by the AI Gateway filter after parsing the
+	// request body. When both Headers and Body are specified, the request must satisfy all of them.
+	//
+	// +optional
+	Body *AIGatewayRouteRuleBodyMatch `json:"body,omitempty"`
+}
+
+// AIGatewayRouteRuleBodyMatch matches the request based on its body with a CEL expression.
+//
+// The AI Gateway filter evaluates the expression after parsing the request body, and sets the result to
+// an internal request header that the generated HTTPRoute matches on.
+// The expression must evaluate to a bool, and the following variables are available:
+//
+//   - model (string): the model name in the request body.
+//   - stream (bool): whether the request is a streaming request.
+//   - has_tools (bool): whether the request declares any tools.
+//   - has_images (bool): whether any of the input messages contains an image.
+//   - estimated_prompt_tokens (uint): the estimated number of prompt tokens, based on four characters per token.
+//   - request (map): the request body as a JSON object.
+//
+// For example:
+//
+//   - "has_images" matches multimodal requests.
+//   - "estimated_prompt_tokens > 32000u" matches long-context requests.
+//   - "has(request.reasoning_effort) && request.reasoning_effort == 'high'" matches requests with a high reasoning effort.
+//
+// When the expression fails to evaluate, e.g., when accessing a field missing from the request, the request does not match.
+type AIGatewayRouteRuleBodyMatch struct {
+	// CEL is the CEL expression that must evaluate to true for the request to match.
+	//
+	// +kubebuilder:validation:MinLength=1
+	CEL string `json:"cel"`
 }
 
 // HTTPBodyMutation defines the mutation of HTTP request body JSON fields that will be applied to the request
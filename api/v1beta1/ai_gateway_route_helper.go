@@ -34,6 +34,18 @@ func (r *AIGatewayRouteRule) GetStreamIdleTimeout() time.Duration {
 	return d
 }
 
+// GetHedgeDelay returns the delay of the hedge policy of this rule, or zero when not configured.
+func (r *AIGatewayRouteRule) GetHedgeDelay() time.Duration {
+	if r == nil || r.HedgePolicy == nil {
+		return 0
+	}
+	d, err := time.ParseDuration(string(r.HedgePolicy.Delay))
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
 // GetTimeoutsOrDefault returns the timeouts with default values applied when not specified.
 // This ensures that AI Gateway routes have appropriate timeout defaults for AI workloads.
 func (r *AIGatewayRouteRule) GetTimeoutsOrDefault() *gwapiv1.HTTPRouteTimeouts {
@@ -76,6 +76,103 @@ type AIServiceBackendSpec struct {
 	// +optional
 	BodyMutation *HTTPBodyMutation `json:"bodyMutation,omitempty"`
 
+	// PromptCaching configures the automatic placement of prompt cache breakpoints in the requests
+	// sent to this backend. This allows clients that cannot set provider-specific cache controls,
+	// such as OpenAI SDK clients, to benefit from prompt caching on long system prompts and tool lists.
+	//
+	// Currently, this is only applied to OpenAI chat completion requests translated to the
+	// GCPAnthropic and AWSAnthropic schemas. Cache read and creation tokens are reported in the
+	// token usage as usual, so they can be used in LLMRequestCosts.
+	//
+	// +optional
+	PromptCaching *PromptCaching `json:"promptCaching,omitempty"`
+
+	// CircuitBreaker ejects this backend when it is overloaded, i.e., when it keeps returning rate limit or
+	// overloaded errors, or when its streaming responses keep exceeding the time to first token objective.
+	// Envoy's outlier detection cannot do this since it knows neither the class of the error nor the time
+	// to first token.
+	//
+	// While the circuit breaker is open, the requests routed to this backend are rejected by the AI Gateway
+	// without being sent, and retried on the other backends of the AIGatewayRoute rule that have not been tried
+	// yet. The client receives a 503 response only when none of them can serve the request. The state is tracked
+	// per AI Gateway instance and per AIGatewayRoute rule referencing this backend.
+	//
+	// +optional
+	CircuitBreaker *CircuitBreaker `json:"circuitBreaker,omitempty"`
+
+	// PromptPolicy adds the system and developer messages enforced by the AI Gateway to the requests sent to this
+	// backend, e.g., the formatting instructions of its model. The messages are added after the ones of the
+	// PromptPolicy of the AIGatewayRoute rule, if any.
+	//
+	// +optional
+	PromptPolicy *PromptPolicy `json:"promptPolicy,omitempty"`
+
+	// ParameterPolicy sets the defaults of the generation parameters of the requests sent to this backend, and
+	// clamps or rejects their values out of the ranges supported by its model. The rules are applied after the ones
+	// of the ParameterPolicy of the AIGatewayRoute rule, if any.
+	//
+	// +optional
+	ParameterPolicy *ParameterPolicy `json:"parameterPolicy,omitempty"`
+
 	// TODO: maybe add backend-level LLMRequestCost configuration that overrides the AIGatewayRoute-level LLMRequestCost.
 	// 	That may be useful for the backend that has a different cost calculation logic.
 }
+
+// CircuitBreaker defines when a backend is ejected based on the AI specific signals.
+//
+// A failure is either an error response classified as rate limit or overloaded, or a streaming response whose
+// time to first token exceeds TimeToFirstToken. Any other response is a success. The circuit breaker opens after
+// ConsecutiveFailures failures in a row. After OpenDuration, it lets a single probe request through (half-open),
+// and then closes if the probe succeeds, or opens again otherwise.
+type CircuitBreaker struct {
+	// ConsecutiveFailures is the number of failures in a row after which the circuit breaker opens.
+	//
+	// Default is 5.
+	//
+	// +optional
+	// +kubebuilder:default=5
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1000
+	ConsecutiveFailures *int32 `json:"consecutiveFailures,omitempty"`
+	// TimeToFirstToken is the objective of the time to first token of the streaming responses. A streaming response
+	// whose first token arrives later than this is counted as a failure.
+	//
+	// If this field is not set, the time to first token is not taken into account.
+	//
+	// +optional
+	TimeToFirstToken *gwapiv1.Duration `json:"timeToFirstToken,omitempty"`
+	// OpenDuration is the time during which the circuit breaker stays open before letting a probe request through.
+	//
+	// Default is 30s.
+	//
+	// +optional
+	// +kubebuilder:default="30s"
+	OpenDuration *gwapiv1.Duration `json:"openDuration,omitempty"`
+}
+
+// PromptCaching defines where ephemeral prompt cache breakpoints are automatically placed.
+//
+// Anthropic allows at most four cache breakpoints per request, including the ones already set by the client.
+// Breakpoints are placed on the tools, the system prompt and then the conversation turns from the most recent one
+// until the limit is reached. Existing breakpoints set by the client are never modified.
+type PromptCaching struct {
+	// System places a cache breakpoint at the end of the system prompt.
+	//
+	// +optional
+	System bool `json:"system,omitempty"`
+	// Tools places a cache breakpoint at the end of the tool definitions.
+	//
+	// +optional
+	Tools bool `json:"tools,omitempty"`
+	// LastTurns is the number of the most recent conversation messages on which a cache breakpoint is placed.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=4
+	LastTurns int32 `json:"lastTurns,omitempty"`
+	// TTL is the time-to-live of the placed cache breakpoints. When unset, the provider default (5m) is used.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum="5m";"1h"
+	TTL string `json:"ttl,omitempty"`
+}
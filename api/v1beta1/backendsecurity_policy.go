@@ -79,12 +79,13 @@ type BackendSecurityPolicySpec struct {
 	// +optional
 	AWSCredentials *BackendSecurityPolicyAWSCredentials `json:"awsCredentials,omitempty"`
 
-	// AzureAPIKey is a mechanism to access Azure OpenAI backend(s). The API key will be injected into the api-key header.
+	// AzureAPIKey is a mechanism to access Azure OpenAI or Azure AI Inference backend(s). The API key will be injected into the api-key header.
 	//
 	// +optional
 	AzureAPIKey *BackendSecurityPolicyAzureAPIKey `json:"azureAPIKey,omitempty"`
 
-	// AzureCredentials is a mechanism to access a backend(s). Azure OpenAI specific logic will be applied.
+	// AzureCredentials is a mechanism to access Azure OpenAI or Azure AI Inference backend(s) with the Microsoft Entra ID
+	// access tokens. Azure OpenAI specific logic will be applied.
 	//
 	// +optional
 	AzureCredentials *BackendSecurityPolicyAzureCredentials `json:"azureCredentials,omitempty"`
@@ -19,8 +19,8 @@ import (
 )
 
 // readConfig returns the configuration as a string from the given path,
-// substituting environment variables. If OPENAI_API_KEY or AZURE_OPENAI_API_KEY
-// is set, it generates the config from environment variables. Otherwise, it returns an error.
+// substituting environment variables. If OPENAI_API_KEY, AZURE_OPENAI_API_KEY, ANTHROPIC_API_KEY
+// or OLLAMA_HOST is set, it generates the config from environment variables. Otherwise, it returns an error.
 func readConfig(path string, mcpServers *autoconfig.MCPServers, debug bool) (string, error) {
 	// If a file path is provided, prefer it.
 	if path != "" {
@@ -52,11 +52,16 @@ func readConfig(path string, mcpServers *autoconfig.MCPServers, debug bool) (str
 		if err := autoconfig.PopulateAnthropicEnvConfig(&data); err != nil {
 			return "", err
 		}
+	} else if os.Getenv("OLLAMA_HOST") != "" {
+		// Add Ollama config from ENV if available (only when neither OpenAI nor Anthropic is configured)
+		if err := autoconfig.PopulateOllamaEnvConfig(&data); err != nil {
+			return "", err
+		}
 	}
 
 	// If we've found no config data, return an error.
 	if reflect.DeepEqual(data, autoconfig.ConfigData{Debug: debug, EnvoyVersion: os.Getenv("ENVOY_VERSION")}) {
-		return "", errors.New("you must supply at least OPENAI_API_KEY, AZURE_OPENAI_API_KEY, ANTHROPIC_API_KEY, or a config file path")
+		return "", errors.New("you must supply at least OPENAI_API_KEY, AZURE_OPENAI_API_KEY, ANTHROPIC_API_KEY, OLLAMA_HOST, or a config file path")
 	}
 
 	// Otel access logging is handled by Envoy directly where supported.
@@ -78,6 +78,14 @@ func TestReadConfig(t *testing.T) {
 			expectHostnames: []string{"api.anthropic.com"},
 			expectPort:      "443",
 		},
+		{
+			name: "generates config from Ollama env vars",
+			envVars: map[string]string{
+				"OLLAMA_HOST": "localhost:11434",
+			},
+			expectHostnames: []string{"localhost"},
+			expectPort:      "11434",
+		},
 		{
 			name: "OpenAI takes precedence when both are set",
 			envVars: map[string]string{
@@ -121,7 +129,7 @@ func TestReadConfig(t *testing.T) {
 	t.Run("error when file and no OPENAI_API_KEY", func(t *testing.T) {
 		_, err := readConfig("", nil, false)
 		require.Error(t, err)
-		require.EqualError(t, err, "you must supply at least OPENAI_API_KEY, AZURE_OPENAI_API_KEY, ANTHROPIC_API_KEY, or a config file path")
+		require.EqualError(t, err, "you must supply at least OPENAI_API_KEY, AZURE_OPENAI_API_KEY, ANTHROPIC_API_KEY, OLLAMA_HOST, or a config file path")
 	})
 
 	t.Run("error when file does not exist", func(t *testing.T) {
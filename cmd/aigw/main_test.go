@@ -121,8 +121,8 @@ Run the AI Gateway locally for given configuration.
 Arguments:
   [<path>]    Path to the AI Gateway configuration yaml file. Defaults to
               $AIGW_CONFIG_HOME/config.yaml if exists, otherwise optional when
-              at least OPENAI_API_KEY, AZURE_OPENAI_API_KEY or ANTHROPIC_API_KEY
-              is set.
+              at least OPENAI_API_KEY, AZURE_OPENAI_API_KEY, ANTHROPIC_API_KEY
+              or OLLAMA_HOST is set.
 
 Flags:
   -h, --help                  Show context-sensitive help.
@@ -406,7 +406,7 @@ func TestCmdRun_Validate(t *testing.T) {
 			name:          "no config and no env vars",
 			path:          "",
 			envVars:       map[string]string{},
-			expectedError: "you must supply at least OPENAI_API_KEY, AZURE_OPENAI_API_KEY, ANTHROPIC_API_KEY, or a config file path",
+			expectedError: "you must supply at least OPENAI_API_KEY, AZURE_OPENAI_API_KEY, ANTHROPIC_API_KEY, OLLAMA_HOST, or a config file path",
 		},
 		{
 			name:    "config path provided",
@@ -442,6 +442,13 @@ func TestCmdRun_Validate(t *testing.T) {
 				"ANTHROPIC_API_KEY": "sk-ant-test",
 			},
 		},
+		{
+			name: "OLLAMA_HOST set",
+			path: "",
+			envVars: map[string]string{
+				"OLLAMA_HOST": "localhost:11434",
+			},
+		},
 		{
 			name: "config path and OPENAI_API_KEY both set",
 			path: "/path/to/config.yaml",
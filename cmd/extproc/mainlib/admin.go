@@ -36,12 +36,13 @@ func newGrpcClient(addr net.Addr, opts ...grpc.DialOption) (*grpc.ClientConn, er
 }
 
 // startAdminServer starts an HTTP admin server on the provided listener for
-// serving Prometheus metrics and health checks. It exposes two endpoints:
+// serving Prometheus metrics and health checks. It exposes three endpoints:
 //   - /metrics: Serves Prometheus metrics using the provided registry.
 //   - /health: Same check Envoy uses: this ExternalProcessorServer.
+//   - /circuit_breakers: Serves the states of the circuit breakers of the backends as JSON.
 //
 // The server returned is running in a goroutine.
-func startAdminServer(lis net.Listener, logger *slog.Logger, registry prometheus.Gatherer, extprocHealth grpc_health_v1.HealthClient) *http.Server {
+func startAdminServer(lis net.Listener, logger *slog.Logger, registry prometheus.Gatherer, extprocHealth grpc_health_v1.HealthClient, circuitBreakers http.Handler) *http.Server {
 	mux := http.NewServeMux()
 
 	mux.Handle("/metrics", promhttp.HandlerFor(
@@ -66,6 +67,8 @@ func startAdminServer(lis net.Listener, logger *slog.Logger, registry prometheus
 		_, _ = w.Write([]byte("OK\n"))
 	})
 
+	mux.Handle("/circuit_breakers", circuitBreakers)
+
 	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
 
 	go func() {
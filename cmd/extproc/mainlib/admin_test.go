@@ -15,12 +15,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	prometheusmodel "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/protobuf/proto"
+
+	"github.com/envoyproxy/ai-gateway/internal/circuitbreaker"
 )
 
 func TestStartAdminServer_Metrics(t *testing.T) {
@@ -136,7 +139,7 @@ gen_ai_server_request_duration_seconds_count{gen_ai_operation_name="chat",gen_ai
 			}
 			mockRegistry := &mockPrometheusGatherer{metricFamilies: tt.metricFamilies}
 
-			s := startAdminServer(lis, slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})), mockRegistry, mockHealthClient)
+			s := startAdminServer(lis, slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})), mockRegistry, mockHealthClient, circuitbreaker.NewRegistry())
 			defer s.Shutdown(context.Background()) //nolint:errcheck
 
 			rr := httptest.NewRecorder()
@@ -189,7 +192,7 @@ func TestStartAdminServer_Health(t *testing.T) {
 			defer lis.Close() //nolint:errcheck
 
 			mockRegistry := &mockPrometheusGatherer{metricFamilies: []*prometheusmodel.MetricFamily{}}
-			s := startAdminServer(lis, slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})), mockRegistry, tt.healthClient)
+			s := startAdminServer(lis, slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})), mockRegistry, tt.healthClient, circuitbreaker.NewRegistry())
 			defer s.Shutdown(context.Background()) //nolint:errcheck
 
 			rr := httptest.NewRecorder()
@@ -202,6 +205,28 @@ func TestStartAdminServer_Health(t *testing.T) {
 	}
 }
 
+func TestStartAdminServer_CircuitBreakers(t *testing.T) {
+	lis, err := listen(t.Context(), t.Name(), "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close() //nolint:errcheck
+
+	circuitBreakers := circuitbreaker.NewRegistry()
+	circuitBreakers.Update(map[string]circuitbreaker.Config{"ns/route/rule/0/ref/0": {ConsecutiveFailures: 5, OpenDuration: time.Second}})
+	mockRegistry := &mockPrometheusGatherer{metricFamilies: []*prometheusmodel.MetricFamily{}}
+	mockHealthClient := &mockHealthClient{
+		checkResp: &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING},
+	}
+	s := startAdminServer(lis, slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})), mockRegistry, mockHealthClient, circuitBreakers)
+	defer s.Shutdown(context.Background()) //nolint:errcheck
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/circuit_breakers", nil)
+	s.Handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `[{"backend":"ns/route/rule/0/ref/0","state":"closed","consecutiveFailures":0}]`, rr.Body.String())
+}
+
 type mockPrometheusGatherer struct {
 	metricFamilies []*prometheusmodel.MetricFamily
 }
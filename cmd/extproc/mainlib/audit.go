@@ -0,0 +1,31 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package mainlib
+
+import (
+	"context"
+
+	"github.com/envoyproxy/ai-gateway/internal/audit"
+)
+
+// AuditSink writes the audit records of the requests. The records are written one at a time by a single goroutine.
+//
+// This allows the users building their own external processor to ship the audit records to their own storage, e.g.,
+// an object storage or a message queue.
+type AuditSink interface {
+	// Write writes the line of a record, which is a JSON object without the trailing newline.
+	Write(ctx context.Context, line []byte) error
+	// Close flushes the records written so far and releases the resources of the sink.
+	Close(ctx context.Context) error
+}
+
+// RegisterAuditSink registers the factory of the audit sink with the given name, which can then be selected with the
+// -auditSink flag. The factory is given the value of the -auditSinkConfig flag. This must be called before Main.
+func RegisterAuditSink(name string, newSink func(ctx context.Context, config string) (AuditSink, error)) {
+	audit.RegisterSink(name, func(ctx context.Context, config string) (audit.Sink, error) {
+		return newSink(ctx, config)
+	})
+}
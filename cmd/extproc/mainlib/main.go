@@ -6,6 +6,7 @@
 package mainlib
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"flag"
@@ -26,6 +27,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health/grpc_health_v1"
 
+	"github.com/envoyproxy/ai-gateway/internal/audit"
 	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
 	"github.com/envoyproxy/ai-gateway/internal/extproc"
 	"github.com/envoyproxy/ai-gateway/internal/filterapi"
@@ -33,6 +35,7 @@ import (
 	"github.com/envoyproxy/ai-gateway/internal/mcpproxy"
 	"github.com/envoyproxy/ai-gateway/internal/metrics"
 	"github.com/envoyproxy/ai-gateway/internal/requestheaderattrs"
+	"github.com/envoyproxy/ai-gateway/internal/responsecache"
 	"github.com/envoyproxy/ai-gateway/internal/tracing"
 	"github.com/envoyproxy/ai-gateway/internal/version"
 )
@@ -61,6 +64,26 @@ type extProcFlags struct {
 	maxRecvMsgSize int
 	// endpointPrefixes is the comma-separated key-value pairs for endpoint prefixes.
 	endpointPrefixes string
+	// responseCacheStore is the name of the store of the response cache.
+	responseCacheStore string
+	// responseCacheStoreConfig is the configuration of the store of the response cache, whose format depends on the store.
+	responseCacheStoreConfig string
+	// responseCacheVectorStore is the name of the vector store of the semantic mode of the response cache.
+	responseCacheVectorStore string
+	// responseCacheVectorStoreConfig is the configuration of the vector store of the semantic mode of the response cache.
+	responseCacheVectorStoreConfig string
+	// auditSink is the name of the sink of the audit records, or empty if the requests are not audited.
+	auditSink string
+	// auditSinkConfig is the configuration of the sink of the audit records, whose format depends on the sink.
+	auditSinkConfig string
+	// auditBodies is the policy of the request and the response bodies of the audit records.
+	auditBodies audit.BodyPolicy
+	// auditTenantHeaders is the comma-separated names of the request headers recorded as the tenant of the audit records.
+	auditTenantHeaders string
+	// auditBufferSize is the number of the audit records buffered before they are written to the sink.
+	auditBufferSize int
+	// auditKeyFile is the path of the file containing the key of the HMAC chaining the audit records.
+	auditKeyFile string
 }
 
 func setOptionalString(dst **string) func(string) error {
@@ -101,7 +124,7 @@ func parseAndValidateFlags(args []string) (extProcFlags, error) {
 	)
 	fs.BoolVar(&flags.enableRedaction, "enableRedaction", false,
 		"Enable redaction of sensitive information in debug logs.")
-	fs.IntVar(&flags.adminPort, "adminPort", 1064, "HTTP port for the admin server (serves /metrics and /health endpoints).")
+	fs.IntVar(&flags.adminPort, "adminPort", 1064, "HTTP port for the admin server (serves /metrics, /health and /circuit_breakers endpoints).")
 	fs.Func("requestHeaderAttributes",
 		"Comma-separated key-value pairs for mapping HTTP request headers to otel attributes shared across metrics, spans, and access logs. Format: x-tenant-id:tenant.id.",
 		setOptionalString(&flags.requestHeaderAttributes),
@@ -144,6 +167,35 @@ func parseAndValidateFlags(args []string) (extProcFlags, error) {
 		"Number of iterations used in the fallback PBKDF2 key derivation for MCP session encryption.")
 	fs.DurationVar(&flags.mcpWriteTimeout, "mcpWriteTimeout", 120*time.Second,
 		"The maximum duration before timing out writes of the MCP response")
+	fs.StringVar(&flags.responseCacheStore, "responseCacheStore", "memory",
+		"The store of the response cache of the AIGatewayRoute rules. One of 'memory', 'disk', or the name of a store "+
+			"registered with RegisterResponseCacheStore.")
+	fs.StringVar(&flags.responseCacheStoreConfig, "responseCacheStoreConfig", "",
+		"The configuration of the store of the response cache: the maximum number of the entries for 'memory' "+
+			"(default 10000), and the directory optionally followed by ',maxEntries=<n>' (default 10000) and "+
+			"',maxSize=<bytes>' (default 1GiB) for 'disk'.")
+	fs.StringVar(&flags.responseCacheVectorStore, "responseCacheVectorStore", "memory",
+		"The vector store of the semantic mode of the response cache. One of 'memory', or the name of a vector store "+
+			"registered with RegisterResponseCacheVectorStore.")
+	fs.StringVar(&flags.responseCacheVectorStoreConfig, "responseCacheVectorStoreConfig", "",
+		"The configuration of the vector store of the semantic mode of the response cache: the maximum number of the "+
+			"entries for 'memory' (default 10000).")
+	fs.StringVar(&flags.auditSink, "auditSink", "",
+		"The sink of the audit records of the requests. One of 'stdout', 'file', 'otlp', or the name of a sink "+
+			"registered with RegisterAuditSink. The requests are not audited if empty.")
+	fs.StringVar(&flags.auditSinkConfig, "auditSinkConfig", "",
+		"The configuration of the sink of the audit records: for 'file', the path of the file optionally followed by the "+
+			"maximum size in megabytes (default 100) and the maximum number of the rotated files (default 10), separated "+
+			"by commas. 'otlp' is configured by the OTEL_* environment variables.")
+	auditBodiesPtr := fs.String("auditBodies", string(audit.BodiesNone),
+		"The policy of the request and the response bodies of the audit records. One of 'none', 'redacted', or 'full'.")
+	fs.StringVar(&flags.auditTenantHeaders, "auditTenantHeaders", "",
+		"The comma-separated names of the request headers recorded as the tenant of the audit records, e.g., 'x-tenant-id'.")
+	fs.IntVar(&flags.auditBufferSize, "auditBufferSize", audit.DefaultBufferSize,
+		"The number of the audit records buffered before they are written to the sink, after which the new records are dropped.")
+	fs.StringVar(&flags.auditKeyFile, "auditKeyFile", "",
+		"The path of the file containing the key of the HMAC-SHA256 chaining the audit records, e.g., mounted from a "+
+			"Secret. The records are chained by their SHA-256 without a key if empty, which does not detect forged records.")
 
 	if err := fs.Parse(args); err != nil {
 		return extProcFlags{}, fmt.Errorf("failed to parse extProcFlags: %w", err)
@@ -180,6 +232,13 @@ func parseAndValidateFlags(args []string) (extProcFlags, error) {
 			errs = append(errs, fmt.Errorf("failed to parse endpoint prefixes: %w", err))
 		}
 	}
+	var err error
+	if flags.auditBodies, err = audit.ParseBodyPolicy(*auditBodiesPtr); err != nil {
+		errs = append(errs, err)
+	}
+	if flags.auditBufferSize <= 0 {
+		errs = append(errs, fmt.Errorf("invalid audit buffer size %d", flags.auditBufferSize))
+	}
 
 	return flags, errors.Join(errs...)
 }
@@ -302,6 +361,51 @@ func Main(ctx context.Context, args []string, stderr io.Writer) (err error) {
 	if err != nil {
 		return fmt.Errorf("failed to create external processor server: %w", err)
 	}
+	responseCacheStore, err := responsecache.NewStore(flags.responseCacheStore, flags.responseCacheStoreConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create response cache store: %w", err)
+	}
+	server.SetResponseCacheStore(responseCacheStore)
+	responseCacheVectorStore, err := responsecache.NewVectorStore(flags.responseCacheVectorStore, flags.responseCacheVectorStoreConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create response cache vector store: %w", err)
+	}
+	server.SetResponseCacheVectorStore(responseCacheVectorStore)
+	var auditLogger *audit.Logger
+	if flags.auditSink != "" {
+		auditSink, err := audit.NewSink(ctx, flags.auditSink, flags.auditSinkConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create audit sink: %w", err)
+		}
+		var tenantHeaders []string
+		for h := range strings.SplitSeq(flags.auditTenantHeaders, ",") {
+			if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+				tenantHeaders = append(tenantHeaders, h)
+			}
+		}
+		var auditKey []byte
+		if flags.auditKeyFile != "" {
+			if auditKey, err = os.ReadFile(flags.auditKeyFile); err != nil {
+				return fmt.Errorf("failed to read audit key file: %w", err)
+			}
+			if auditKey = bytes.TrimSpace(auditKey); len(auditKey) == 0 {
+				return fmt.Errorf("audit key file %s is empty", flags.auditKeyFile)
+			}
+		} else {
+			l.Warn("audit records are chained without a key, so forged records are not detected")
+		}
+		auditLogger = audit.NewLogger(auditSink, audit.Options{
+			BufferSize:    flags.auditBufferSize,
+			Bodies:        flags.auditBodies,
+			TenantHeaders: tenantHeaders,
+			Key:           auditKey,
+			Logger:        l,
+		})
+		server.SetAuditLogger(auditLogger)
+	}
+	if err = metrics.RegisterCircuitBreakerState(meter, server.CircuitBreakers().Statuses); err != nil {
+		return fmt.Errorf("failed to register circuit breaker metrics: %w", err)
+	}
 	server.Register(path.Join(flags.rootPrefix, endpointPrefixes.OpenAI, "/v1/chat/completions"), extproc.NewFactory(
 		chatCompletionMetricsFactory, tracing.ChatCompletionTracer(), endpointspec.ChatCompletionsEndpointSpec{}))
 	server.Register(path.Join(flags.rootPrefix, endpointPrefixes.OpenAI, "/v1/completions"), extproc.NewFactory(
@@ -383,7 +487,7 @@ func Main(ctx context.Context, args []string, stderr io.Writer) (err error) {
 	healthClient := grpc_health_v1.NewHealthClient(healthCheckConn)
 
 	// Start HTTP admin server for metrics and health checks.
-	adminServer := startAdminServer(adminLis, l, promRegistry, healthClient)
+	adminServer := startAdminServer(adminLis, l, promRegistry, healthClient, server.CircuitBreakers())
 
 	go func() {
 		<-ctx.Done()
@@ -403,6 +507,11 @@ func Main(ctx context.Context, args []string, stderr io.Writer) (err error) {
 		if err := metricsShutdown(shutdownCtx); err != nil {
 			l.Error("Failed to shutdown metrics gracefully", "error", err)
 		}
+		if auditLogger != nil {
+			if err := auditLogger.Close(shutdownCtx); err != nil {
+				l.Error("Failed to close audit logger gracefully", "error", err)
+			}
+		}
 		if mcpServer != nil {
 			if err := mcpServer.Shutdown(shutdownCtx); err != nil {
 				l.Error("Failed to shutdown mcp proxy server gracefully", "error", err)
@@ -18,6 +18,9 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+
+	"github.com/envoyproxy/ai-gateway/internal/audit"
+	"github.com/envoyproxy/ai-gateway/internal/responsecache"
 )
 
 func Test_parseAndValidateFlags(t *testing.T) {
@@ -221,6 +224,98 @@ func Test_parseAndValidateFlags(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("response cache store", func(t *testing.T) {
+		flags, err := parseAndValidateFlags([]string{"-configPath", "/path/to/config.yaml"})
+		require.NoError(t, err)
+		require.Equal(t, "memory", flags.responseCacheStore)
+		require.Empty(t, flags.responseCacheStoreConfig)
+		require.Equal(t, "memory", flags.responseCacheVectorStore)
+		require.Empty(t, flags.responseCacheVectorStoreConfig)
+
+		flags, err = parseAndValidateFlags([]string{
+			"-configPath", "/path/to/config.yaml",
+			"-responseCacheStore", "disk", "-responseCacheStoreConfig", "/var/cache/aigw",
+			"-responseCacheVectorStore", "memory", "-responseCacheVectorStoreConfig", "500",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "disk", flags.responseCacheStore)
+		require.Equal(t, "/var/cache/aigw", flags.responseCacheStoreConfig)
+		require.Equal(t, "memory", flags.responseCacheVectorStore)
+		require.Equal(t, "500", flags.responseCacheVectorStoreConfig)
+	})
+
+	t.Run("audit", func(t *testing.T) {
+		flags, err := parseAndValidateFlags([]string{"-configPath", "/path/to/config.yaml"})
+		require.NoError(t, err)
+		require.Empty(t, flags.auditSink)
+		require.Equal(t, audit.BodiesNone, flags.auditBodies)
+		require.Equal(t, audit.DefaultBufferSize, flags.auditBufferSize)
+
+		flags, err = parseAndValidateFlags([]string{
+			"-configPath", "/path/to/config.yaml",
+			"-auditSink", "file", "-auditSinkConfig", "/var/log/aigw/audit.jsonl,50,3",
+			"-auditBodies", "redacted", "-auditTenantHeaders", "x-tenant-id,x-team", "-auditBufferSize", "100",
+			"-auditKeyFile", "/etc/aigw/audit-key",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "file", flags.auditSink)
+		require.Equal(t, "/var/log/aigw/audit.jsonl,50,3", flags.auditSinkConfig)
+		require.Equal(t, audit.BodiesRedacted, flags.auditBodies)
+		require.Equal(t, "x-tenant-id,x-team", flags.auditTenantHeaders)
+		require.Equal(t, 100, flags.auditBufferSize)
+		require.Equal(t, "/etc/aigw/audit-key", flags.auditKeyFile)
+
+		_, err = parseAndValidateFlags([]string{
+			"-configPath", "/path/to/config.yaml", "-auditBodies", "partial", "-auditBufferSize", "0",
+		})
+		require.EqualError(t, err, "invalid audit body policy \"partial\"\ninvalid audit buffer size 0")
+	})
+}
+
+// sharedResponseCacheStore is a ResponseCacheStore registered in the tests.
+type sharedResponseCacheStore struct {
+	ResponseCacheStore
+	config string
+}
+
+func TestRegisterResponseCacheStore(t *testing.T) {
+	RegisterResponseCacheStore("test-shared", func(config string) (ResponseCacheStore, error) {
+		return &sharedResponseCacheStore{config: config}, nil
+	})
+	store, err := responsecache.NewStore("test-shared", "redis://localhost:6379")
+	require.NoError(t, err)
+	require.Equal(t, "redis://localhost:6379", store.(*sharedResponseCacheStore).config)
+}
+
+// sharedResponseCacheVectorStore is a ResponseCacheVectorStore registered in the tests.
+type sharedResponseCacheVectorStore struct {
+	ResponseCacheVectorStore
+	config string
+}
+
+func TestRegisterResponseCacheVectorStore(t *testing.T) {
+	RegisterResponseCacheVectorStore("test-vectordb", func(config string) (ResponseCacheVectorStore, error) {
+		return &sharedResponseCacheVectorStore{config: config}, nil
+	})
+	store, err := responsecache.NewVectorStore("test-vectordb", "http://localhost:6333")
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:6333", store.(*sharedResponseCacheVectorStore).config)
+}
+
+// bufferedAuditSink is an AuditSink registered in the tests.
+type bufferedAuditSink struct {
+	AuditSink
+	config string
+}
+
+func TestRegisterAuditSink(t *testing.T) {
+	RegisterAuditSink("test-queue", func(_ context.Context, config string) (AuditSink, error) {
+		return &bufferedAuditSink{config: config}, nil
+	})
+	sink, err := audit.NewSink(t.Context(), "test-queue", "kafka://localhost:9092")
+	require.NoError(t, err)
+	require.Equal(t, "kafka://localhost:9092", sink.(*bufferedAuditSink).config)
 }
 
 func TestListenAddress(t *testing.T) {
@@ -0,0 +1,56 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package mainlib
+
+import (
+	"context"
+	"time"
+
+	"github.com/envoyproxy/ai-gateway/internal/responsecache"
+)
+
+// ResponseCacheStore stores the cached responses of the AIGatewayRoute rules with the response cache.
+// Implementations must be safe for concurrent use.
+//
+// This allows the users building their own external processor to share the response cache across the replicas
+// of the external processor, e.g., by storing the responses in Redis.
+type ResponseCacheStore interface {
+	// Get returns the value stored under the key, or false if there is none or it has expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value under the key for the given TTL.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// RegisterResponseCacheStore registers the factory of the response cache store with the given name, which can then
+// be selected with the -responseCacheStore flag. The factory is given the value of the -responseCacheStoreConfig
+// flag. This must be called before Main.
+func RegisterResponseCacheStore(name string, newStore func(config string) (ResponseCacheStore, error)) {
+	responsecache.RegisterStore(name, func(config string) (responsecache.Store, error) {
+		return newStore(config)
+	})
+}
+
+// ResponseCacheVectorStore stores the embeddings of the cached requests for the semantic mode of the response cache.
+// Implementations must be safe for concurrent use.
+//
+// This allows the users building their own external processor to look up the similar requests in a vector database.
+// The embeddings are partitioned into scopes, and only the embeddings of the same scope are compared.
+type ResponseCacheVectorStore interface {
+	// Search returns the key of the entry of the scope whose embedding is the most similar to the given one, together
+	// with their cosine similarity, or false if the scope has no entry.
+	Search(ctx context.Context, scope string, embedding []float32) (key string, similarity float64, ok bool, err error)
+	// Add adds the embedding of the entry stored under the key to the scope for the given TTL.
+	Add(ctx context.Context, scope string, embedding []float32, key string, ttl time.Duration) error
+}
+
+// RegisterResponseCacheVectorStore registers the factory of the response cache vector store with the given name,
+// which can then be selected with the -responseCacheVectorStore flag. The factory is given the value of the
+// -responseCacheVectorStoreConfig flag. This must be called before Main.
+func RegisterResponseCacheVectorStore(name string, newStore func(config string) (ResponseCacheVectorStore, error)) {
+	responsecache.RegisterVectorStore(name, func(config string) (responsecache.VectorStore, error) {
+		return newStore(config)
+	})
+}
@@ -0,0 +1,119 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+// Package affinity computes the affinity keys of the requests, which Envoy hashes onto a consistent hash ring across
+// the backends of the AIGatewayRoute rules with an affinity so that the requests sharing a prompt prefix are sent to
+// the same backend.
+package affinity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"slices"
+	"strconv"
+)
+
+// systemKeys are the top-level request body fields holding the system prompt across the supported APIs, e.g.,
+// "system" for the Anthropic Messages API and "instructions" for the OpenAI Responses API.
+var systemKeys = []string{"system", "instructions"}
+
+// messagesKeys are the top-level request body fields holding the messages across the supported APIs, e.g.,
+// "messages" for the OpenAI Chat Completions and Anthropic Messages APIs, "input" for the OpenAI Responses API,
+// and "prompt" for the OpenAI Completions API. A string is regarded as a single message.
+var messagesKeys = []string{"messages", "input", "prompt"}
+
+// systemRoles are the roles of the leading messages that are part of the system prompt.
+var systemRoles = []string{"system", "developer"}
+
+// PromptKey returns the affinity key of the request body computed from its system prompt and the given number of
+// the messages following it, or false if the request has none of them.
+func PromptKey(request map[string]any, messages int) (string, bool) {
+	h := sha256.New()
+	var found bool
+	for _, key := range systemKeys {
+		if v, ok := request[key]; ok {
+			found = true
+			writeValue(h, v)
+		}
+	}
+	for _, key := range messagesKeys {
+		v, ok := request[key]
+		if !ok {
+			continue
+		}
+		list, ok := v.([]any)
+		if !ok {
+			list = []any{v}
+		}
+		i := 0
+		for ; i < len(list) && isSystemMessage(list[i]); i++ {
+			writeValue(h, list[i])
+		}
+		for end := min(i+messages, len(list)); i < end; i++ {
+			writeValue(h, list[i])
+		}
+		found = found || i > 0
+		break
+	}
+	if !found {
+		return "", false
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16]), true
+}
+
+// isSystemMessage returns true if the message has one of the systemRoles.
+func isSystemMessage(message any) bool {
+	m, ok := message.(map[string]any)
+	if !ok {
+		return false
+	}
+	role, _ := m["role"].(string)
+	return slices.Contains(systemRoles, role)
+}
+
+// writeValue writes the unambiguous encoding of the JSON value v to h. Unlike the JSON encoding, the keys of the
+// objects are sorted so that the result does not depend on their order.
+func writeValue(h hash.Hash, v any) {
+	switch v := v.(type) {
+	case nil:
+		_, _ = h.Write([]byte{'n'})
+	case bool:
+		_, _ = h.Write([]byte{'b', boolByte(v)})
+	case float64:
+		_, _ = h.Write([]byte("d" + strconv.FormatFloat(v, 'g', -1, 64) + ";"))
+	case string:
+		writeString(h, v)
+	case []any:
+		_, _ = h.Write([]byte("a" + strconv.Itoa(len(v)) + ";"))
+		for _, e := range v {
+			writeValue(h, e)
+		}
+	case map[string]any:
+		_, _ = h.Write([]byte("o" + strconv.Itoa(len(v)) + ";"))
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			writeString(h, k)
+			writeValue(h, v[k])
+		}
+	}
+}
+
+// writeString writes the length-prefixed string s to h.
+func writeString(h hash.Hash, s string) {
+	_, _ = h.Write([]byte("s" + strconv.Itoa(len(s)) + ";"))
+	_, _ = h.Write([]byte(s))
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return '1'
+	}
+	return '0'
+}
@@ -0,0 +1,76 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package affinity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+func TestPromptKey(t *testing.T) {
+	key := func(t *testing.T, body string, messages int) (string, bool) {
+		var request map[string]any
+		require.NoError(t, json.Unmarshal([]byte(body), &request))
+		return PromptKey(request, messages)
+	}
+
+	t.Run("no prompt", func(t *testing.T) {
+		_, ok := key(t, `{"model":"gpt-4o"}`, 1)
+		require.False(t, ok)
+		_, ok = key(t, `{"model":"gpt-4o","messages":[]}`, 1)
+		require.False(t, ok)
+	})
+
+	t.Run("chat completions", func(t *testing.T) {
+		base, ok := key(t, `{"model":"a","messages":[{"role":"system","content":"be nice"},{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}]}`, 1)
+		require.True(t, ok)
+		require.Len(t, base, 32)
+
+		// The following messages and the other fields are not part of the key.
+		k, ok := key(t, `{"model":"b","temperature":0.5,"messages":[{"content":"be nice","role":"system"},{"role":"user","content":"hi"},{"role":"assistant","content":"bye"}]}`, 1)
+		require.True(t, ok)
+		require.Equal(t, base, k)
+
+		// A different first message changes the key.
+		k, ok = key(t, `{"model":"a","messages":[{"role":"system","content":"be nice"},{"role":"user","content":"hey"}]}`, 1)
+		require.True(t, ok)
+		require.NotEqual(t, base, k)
+
+		// A different system prompt changes the key even with zero messages.
+		zero, ok := key(t, `{"model":"a","messages":[{"role":"system","content":"be nice"},{"role":"user","content":"hey"}]}`, 0)
+		require.True(t, ok)
+		k, ok = key(t, `{"model":"a","messages":[{"role":"developer","content":"be nice"},{"role":"user","content":"hey"}]}`, 0)
+		require.True(t, ok)
+		require.NotEqual(t, zero, k)
+
+		// The number of messages is part of the key.
+		k, ok = key(t, `{"model":"a","messages":[{"role":"system","content":"be nice"},{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}]}`, 2)
+		require.True(t, ok)
+		require.NotEqual(t, base, k)
+	})
+
+	t.Run("anthropic messages", func(t *testing.T) {
+		base, ok := key(t, `{"system":"be nice","messages":[{"role":"user","content":"hi"}]}`, 0)
+		require.True(t, ok)
+		k, ok := key(t, `{"system":"be mean","messages":[{"role":"user","content":"hi"}]}`, 0)
+		require.True(t, ok)
+		require.NotEqual(t, base, k)
+	})
+
+	t.Run("responses", func(t *testing.T) {
+		base, ok := key(t, `{"instructions":"be nice","input":"hi"}`, 1)
+		require.True(t, ok)
+		k, ok := key(t, `{"instructions":"be nice","input":[{"role":"user","content":"hi"}]}`, 1)
+		require.True(t, ok)
+		require.NotEqual(t, base, k)
+		k, ok = key(t, `{"instructions":"be nice","input":"hi"}`, 1)
+		require.True(t, ok)
+		require.Equal(t, base, k)
+	})
+}
@@ -638,6 +638,89 @@ type TitanEmbeddingResponse struct {
 	InputTextTokenCount int `json:"inputTextTokenCount"`
 }
 
+// TitanMultimodalEmbeddingRequest is the request body for the Amazon Titan Multimodal Embeddings model
+// (amazon.titan-embed-image-v1). At least one of InputText and InputImage is required, and when both are set,
+// a single embedding combining them is returned. The response is a TitanEmbeddingResponse.
+//
+// See https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-titan-embed-mm.html
+type TitanMultimodalEmbeddingRequest struct {
+	// InputText is the text to embed.
+	InputText string `json:"inputText,omitempty"`
+
+	// InputImage is the base64-encoded image to embed.
+	InputImage string `json:"inputImage,omitempty"`
+
+	// EmbeddingConfig configures the output embedding.
+	EmbeddingConfig *TitanMultimodalEmbeddingConfig `json:"embeddingConfig,omitempty"`
+}
+
+// TitanMultimodalEmbeddingConfig is the embedding configuration of a TitanMultimodalEmbeddingRequest.
+type TitanMultimodalEmbeddingConfig struct {
+	// OutputEmbeddingLength is the number of dimensions for the output embedding.
+	// Accepted values: 256, 384, 1024 (default).
+	OutputEmbeddingLength int `json:"outputEmbeddingLength"`
+}
+
+// CohereEmbedRequest is the request body for the Cohere Embed models via the AWS Bedrock InvokeModel API.
+// Exactly one of Texts, Images and Inputs must be set. Inputs and OutputDimension are only supported by
+// Cohere Embed v4 (cohere.embed-v4:0).
+//
+// See https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-embed-v4.html
+type CohereEmbedRequest struct {
+	// InputType is the type of the input, such as search_document or search_query. Required.
+	InputType string `json:"input_type"`
+
+	// Texts are the texts to embed, one embedding per text.
+	Texts []string `json:"texts,omitempty"`
+
+	// Images are the data URLs of the images to embed, one embedding per image.
+	Images []string `json:"images,omitempty"`
+
+	// Inputs are the interleaved text and image inputs, one embedding per input.
+	Inputs []CohereEmbedInput `json:"inputs,omitempty"`
+
+	// EmbeddingTypes specifies the output embedding types, such as float or int8.
+	EmbeddingTypes []string `json:"embedding_types,omitempty"`
+
+	// OutputDimension is the number of dimensions for the output embeddings.
+	// Accepted values: 256, 512, 1024, 1536 (default).
+	OutputDimension *int `json:"output_dimension,omitempty"`
+}
+
+// CohereEmbedInput is a single input of a CohereEmbedRequest, which can mix text and image content.
+type CohereEmbedInput struct {
+	Content []CohereEmbedContent `json:"content"`
+}
+
+// CohereEmbedContent is a single text or image content of a CohereEmbedInput.
+type CohereEmbedContent struct {
+	// Type is either "text" or "image_url".
+	Type string `json:"type"`
+
+	// Text is set when Type is "text".
+	Text string `json:"text,omitempty"`
+
+	// ImageURL is the data URL of the image, set when Type is "image_url".
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+// CohereEmbedResponse is the response body returned by the Cohere Embed models when EmbeddingTypes is set.
+// The number of input tokens is only returned in the X-Amzn-Bedrock-Input-Token-Count response header.
+//
+// See https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-embed-v4.html
+type CohereEmbedResponse struct {
+	// ID is the ID of the response.
+	ID string `json:"id"`
+
+	// Embeddings are the embeddings by type, in the order of the inputs.
+	Embeddings struct {
+		Float [][]float64 `json:"float,omitempty"`
+	} `json:"embeddings"`
+
+	// ResponseType is "embeddings_by_type" when EmbeddingTypes is set.
+	ResponseType string `json:"response_type"`
+}
+
 // CountTokensInvokeModelRequest is the request structure for the Bedrock CountTokens API using InvokeModel-style input.
 // The body is a base64-encoded model-specific request body (e.g., Anthropic Messages format).
 // https://docs.aws.amazon.com/bedrock/latest/APIReference/API_runtime_CountTokens.html
@@ -108,6 +108,51 @@ type PredictResponse struct {
 	Predictions []*Prediction `json:"predictions"`
 }
 
+// MultimodalPredictRequest is the predict request body for the multimodalembedding models.
+// https://docs.cloud.google.com/vertex-ai/generative-ai/docs/model-reference/multimodal-embeddings-api#request_body
+type MultimodalPredictRequest struct {
+	Instances []*MultimodalInstance `json:"instances"`
+
+	Parameters *MultimodalParameters `json:"parameters,omitempty"`
+}
+
+// MultimodalInstance is a single input of the multimodalembedding models. When both Text and Image are set,
+// the response contains a separate embedding for each of them.
+type MultimodalInstance struct {
+	// The text to generate the embedding for.
+	Text string `json:"text,omitempty"`
+
+	// The image to generate the embedding for.
+	Image *MultimodalImage `json:"image,omitempty"`
+}
+
+// MultimodalImage is an image of a MultimodalInstance.
+type MultimodalImage struct {
+	// The base64-encoded image bytes.
+	BytesBase64Encoded string `json:"bytesBase64Encoded,omitempty"`
+
+	// The media type of the image, such as image/png or image/jpeg.
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// MultimodalParameters are the parameters of a MultimodalPredictRequest.
+type MultimodalParameters struct {
+	// The dimension of the embeddings. Accepted values: 128, 256, 512 or 1408 (default).
+	Dimension int `json:"dimension,omitempty"`
+}
+
+// MultimodalPrediction is a single prediction of the multimodalembedding models.
+// https://docs.cloud.google.com/vertex-ai/generative-ai/docs/model-reference/multimodal-embeddings-api#response-body
+type MultimodalPrediction struct {
+	TextEmbedding  []float64 `json:"textEmbedding,omitempty"`
+	ImageEmbedding []float64 `json:"imageEmbedding,omitempty"`
+}
+
+// MultimodalPredictResponse is the predict response body for the multimodalembedding models.
+type MultimodalPredictResponse struct {
+	Predictions []*MultimodalPrediction `json:"predictions"`
+}
+
 // EmbedContentRequest is the request body for the embedContent endpoint used by newer embedding models
 // (e.g. gemini-embedding-2-*).
 // All input texts are packed as parts in a single Content object and we drop deprecated top-level fields
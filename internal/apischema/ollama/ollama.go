@@ -0,0 +1,172 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+// Package ollama contains the Ollama native API schema definitions.
+//
+// https://github.com/ollama/ollama/blob/main/docs/api.md
+package ollama
+
+import "github.com/envoyproxy/ai-gateway/internal/json"
+
+// ChatRequest represents the request body for the /api/chat endpoint.
+// Docs: https://github.com/ollama/ollama/blob/main/docs/api.md#generate-a-chat-completion
+type ChatRequest struct {
+	// Model is the name of the model to use, e.g. "llama3.2".
+	Model string `json:"model"`
+	// Messages is the conversation history.
+	Messages []Message `json:"messages"`
+	// Tools is the list of tools the model may call.
+	Tools []Tool `json:"tools,omitempty"`
+	// Format is either the string "json" or a JSON schema object that constrains the output.
+	Format json.RawMessage `json:"format,omitempty"`
+	// Options are the model parameters such as temperature.
+	Options *Options `json:"options,omitempty"`
+	// Stream controls whether the response is streamed as NDJSON. Ollama defaults to true when unset,
+	// so this is always sent explicitly.
+	Stream bool `json:"stream"`
+	// Think enables the thinking output of reasoning models. This is either a bool or one of "low", "medium", "high".
+	Think any `json:"think,omitempty"`
+	// KeepAlive controls how long the model stays loaded in memory after the request.
+	KeepAlive string `json:"keep_alive,omitempty"`
+}
+
+// Message is a single message of the chat conversation.
+type Message struct {
+	// Role is one of "system", "user", "assistant" or "tool".
+	Role string `json:"role"`
+	// Content is the text content of the message.
+	Content string `json:"content"`
+	// Thinking is the thinking output of the model. Only set on assistant messages.
+	Thinking string `json:"thinking,omitempty"`
+	// Images is a list of base64-encoded images without the data URI prefix.
+	Images []string `json:"images,omitempty"`
+	// ToolCalls is the list of tool calls made by the assistant.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolName is the name of the tool whose result this message carries. Only set on tool messages.
+	ToolName string `json:"tool_name,omitempty"`
+}
+
+// ToolCall is a tool call made by the model.
+type ToolCall struct {
+	// ID is the identifier of the tool call. Only returned by recent Ollama versions.
+	ID string `json:"id,omitempty"`
+	// Function is the function to be called.
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction is the function called by a ToolCall.
+type ToolCallFunction struct {
+	// Index is the position of the tool call in the response.
+	Index int `json:"index,omitempty"`
+	// Name is the name of the function.
+	Name string `json:"name"`
+	// Arguments is the arguments of the function as a JSON object.
+	Arguments map[string]any `json:"arguments"`
+}
+
+// Tool is a tool definition available to the model.
+type Tool struct {
+	// Type is always "function".
+	Type string `json:"type"`
+	// Function is the function definition.
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction is the function definition of a Tool.
+type ToolFunction struct {
+	// Name is the name of the function.
+	Name string `json:"name"`
+	// Description is the description of the function.
+	Description string `json:"description,omitempty"`
+	// Parameters is the JSON schema of the function parameters.
+	Parameters any `json:"parameters,omitempty"`
+}
+
+// Options are the model parameters.
+// Docs: https://github.com/ollama/ollama/blob/main/docs/modelfile.md#valid-parameters-and-values
+type Options struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	NumPredict       *int64   `json:"num_predict,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	FrequencyPenalty *float32 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float32 `json:"presence_penalty,omitempty"`
+}
+
+// ChatResponse represents the response body for the /api/chat endpoint.
+//
+// When streaming, each line of the NDJSON response is a ChatResponse carrying a partial message,
+// and the final line has Done set to true along with the token counts.
+type ChatResponse struct {
+	// Model is the name of the model that generated the response.
+	Model string `json:"model"`
+	// CreatedAt is the RFC 3339 timestamp of the response.
+	CreatedAt string `json:"created_at"`
+	// Message is the generated message, or a partial message when streaming.
+	Message Message `json:"message"`
+	// Done is true on the final response.
+	Done bool `json:"done"`
+	// DoneReason is the reason the generation stopped, e.g. "stop" or "length".
+	DoneReason string `json:"done_reason,omitempty"`
+	// PromptEvalCount is the number of tokens in the prompt.
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	// EvalCount is the number of tokens in the response.
+	EvalCount int `json:"eval_count,omitempty"`
+	// TotalDuration is the time spent generating the response in nanoseconds.
+	TotalDuration int64 `json:"total_duration,omitempty"`
+}
+
+// EmbedRequest represents the request body for the /api/embed endpoint.
+// Docs: https://github.com/ollama/ollama/blob/main/docs/api.md#generate-embeddings
+type EmbedRequest struct {
+	// Model is the name of the model to use.
+	Model string `json:"model"`
+	// Input is either a string or a list of strings to embed.
+	Input any `json:"input"`
+	// Truncate truncates the end of each input to fit within the context length. Defaults to true.
+	Truncate *bool `json:"truncate,omitempty"`
+	// Dimensions is the number of dimensions of the resulting embeddings.
+	Dimensions *int `json:"dimensions,omitempty"`
+	// KeepAlive controls how long the model stays loaded in memory after the request.
+	KeepAlive string `json:"keep_alive,omitempty"`
+}
+
+// EmbedResponse represents the response body for the /api/embed endpoint.
+type EmbedResponse struct {
+	// Model is the name of the model that generated the embeddings.
+	Model string `json:"model"`
+	// Embeddings is the list of embeddings in the same order as the input.
+	Embeddings [][]float64 `json:"embeddings"`
+	// PromptEvalCount is the number of tokens in the input.
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+}
+
+// ListModelsResponse represents the response body for the /api/tags endpoint.
+// Docs: https://github.com/ollama/ollama/blob/main/docs/api.md#list-local-models
+type ListModelsResponse struct {
+	// Models is the list of models available locally.
+	Models []ModelInfo `json:"models"`
+}
+
+// ModelInfo describes a locally available model.
+type ModelInfo struct {
+	// Name is the name of the model including the tag, e.g. "llama3.2:latest".
+	Name string `json:"name"`
+	// Model is the model identifier, usually the same as Name.
+	Model string `json:"model"`
+	// ModifiedAt is the RFC 3339 timestamp of the last modification of the model.
+	ModifiedAt string `json:"modified_at"`
+	// Size is the size of the model in bytes.
+	Size int64 `json:"size"`
+	// Digest is the digest of the model.
+	Digest string `json:"digest"`
+}
+
+// Error represents the error response body of the Ollama API.
+type Error struct {
+	// Error is the error message.
+	Error string `json:"error"`
+}
@@ -410,6 +410,10 @@ type EmbeddingInputItem struct {
 	Content  EmbeddingContent  `json:"content"`             // The actual text content (string or []string)
 	TaskType EmbeddingTaskType `json:"task_type,omitempty"` // Optional task type
 	Title    string            `json:"title,omitempty"`     // Optional title
+	// Image is the image to embed, either as a data URL (data:image/png;base64,...) or as base64-encoded bytes.
+	// This is only supported by the multimodal embedding models, such as GCP Vertex AI multimodalembedding,
+	// and AWS Bedrock Titan Multimodal Embeddings and Cohere Embed v4.
+	Image string `json:"image,omitempty"`
 }
 
 // EmbeddingRequestInput is the EmbeddingRequest.Input type.
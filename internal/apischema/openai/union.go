@@ -85,8 +85,8 @@ func unmarshalJSONEmbeddingInput(typ string, data []byte) (interface{}, error) {
 		if err != nil {
 			return nil, fmt.Errorf("cannot unmarshal %s as EmbeddingInputItem: %w", typ, err)
 		}
-		// Validate that the content field is not empty
-		if item.Content.IsEmpty() {
+		// Validate that the item has either content or an image to embed.
+		if item.Content.IsEmpty() && item.Image == "" {
 			return nil, fmt.Errorf("invalid %s type (must be string, object, or array)", typ)
 		}
 		return item, nil
@@ -119,9 +119,9 @@ func unmarshalJSONEmbeddingInput(typ string, data []byte) (interface{}, error) {
 			if err := json.Unmarshal(data, &items); err != nil {
 				return nil, fmt.Errorf("cannot unmarshal %s as []EmbeddingInputItem: %w", typ, err)
 			}
-			// Validate that all items have non-empty content
+			// Validate that all items have either content or an image to embed.
 			for _, item := range items {
-				if item.Content.IsEmpty() {
+				if item.Content.IsEmpty() && item.Image == "" {
 					return nil, fmt.Errorf("invalid %s array element", typ)
 				}
 			}
@@ -224,6 +224,14 @@ func TestUnmarshalJSONEmbeddingInput(t *testing.T) {
 				TaskType: "RETRIEVAL_QUERY",
 			},
 		},
+		{
+			name: "array of EmbeddingInputItem objects with images",
+			data: []byte(`[{"image":"data:image/png;base64,aGVsbG8="},{"content":"a cat","image":"aGVsbG8="}]`),
+			expected: []EmbeddingInputItem{
+				{Image: "data:image/png;base64,aGVsbG8="},
+				{Content: EmbeddingContent{Value: "a cat"}, Image: "aGVsbG8="},
+			},
+		},
 	}
 
 	for _, tc := range successCases {
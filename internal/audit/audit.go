@@ -0,0 +1,326 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+// Package audit implements the audit log of the external processor, which records each request completed by a
+// backend in a structured record. The records are chained by their keyed hashes so that a removed, modified or
+// forged record is detected, and they are written to a pluggable sink asynchronously so that the audit log never blocks the requests.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+// DefaultBufferSize is the default number of the records buffered before they are written to the sink.
+const DefaultBufferSize = 1024
+
+// DefaultMaxBodySize is the default maximum size of each of the request and the response bodies of a record.
+const DefaultMaxBodySize = 1 << 20
+
+// Record is the audit record of a request.
+type Record struct {
+	// Seq is the sequence number of the record, which starts at 1 when the external processor starts. This is set by
+	// the Logger.
+	Seq uint64 `json:"seq"`
+	// Time is the time when the response completed.
+	Time time.Time `json:"time"`
+	// RequestID is the value of the x-request-id header of the request.
+	RequestID string `json:"requestId,omitempty"`
+	// Tenant are the values of the tenant headers of the request by their names.
+	Tenant map[string]string `json:"tenant,omitempty"`
+	// Route is the name of the AIGatewayRoute of the request.
+	Route string `json:"route,omitempty"`
+	// Backend is the name of the backend serving the request.
+	Backend string `json:"backend,omitempty"`
+	// Endpoint is the path of the request sent by the client.
+	Endpoint string `json:"endpoint,omitempty"`
+	// OriginalModel is the model of the request sent by the client, RequestModel is the one sent to the backend after
+	// the model name override, and ResponseModel is the one reported by the backend.
+	OriginalModel string `json:"originalModel,omitempty"`
+	RequestModel  string `json:"requestModel,omitempty"`
+	ResponseModel string `json:"responseModel,omitempty"`
+	// Stream is true if the response is streamed.
+	Stream bool `json:"stream"`
+	// StatusCode is the status code of the response returned to the client.
+	StatusCode int `json:"statusCode,omitempty"`
+	// Error is the error of the processing of the response, if any.
+	Error string `json:"error,omitempty"`
+	// Usage is the token usage of the request.
+	Usage Usage `json:"usage"`
+	// Costs are the request costs of the route by their metadata keys.
+	Costs map[string]uint64 `json:"costs,omitempty"`
+	// RequestBody and ResponseBody are the bodies of the request and the response returned to the client, which are
+	// recorded, redacted or omitted according to the BodyPolicy of the Logger. BodiesTruncated is true if either is
+	// cut at the maximum body size.
+	RequestBody     string `json:"requestBody,omitempty"`
+	ResponseBody    string `json:"responseBody,omitempty"`
+	BodiesTruncated bool   `json:"bodiesTruncated,omitempty"`
+	// PrevHash is the hash of the previous record, which is empty for the first record. This is set by the Logger.
+	PrevHash string `json:"prevHash"`
+
+	// requestBody and responseBody are the raw bodies set by SetBodies, which are processed by the writer of the Logger.
+	requestBody, responseBody []byte
+}
+
+// Usage is the token usage of a request. The zero fields are the ones not reported by the backend.
+type Usage struct {
+	InputTokens              uint32 `json:"inputTokens,omitempty"`
+	OutputTokens             uint32 `json:"outputTokens,omitempty"`
+	TotalTokens              uint32 `json:"totalTokens,omitempty"`
+	CachedInputTokens        uint32 `json:"cachedInputTokens,omitempty"`
+	CacheCreationInputTokens uint32 `json:"cacheCreationInputTokens,omitempty"`
+	ReasoningTokens          uint32 `json:"reasoningTokens,omitempty"`
+}
+
+// SetBodies sets the raw request and response bodies of the record, which are recorded according to the BodyPolicy
+// of the Logger. truncated is true if the response body has been cut at the maximum body size. The bodies must not be
+// modified after this.
+func (r *Record) SetBodies(request, response []byte, truncated bool) {
+	r.requestBody, r.responseBody, r.BodiesTruncated = request, response, truncated
+}
+
+// Options are the options of the Logger.
+type Options struct {
+	// BufferSize is the number of the records buffered before they are written to the sink, after which the new
+	// records are dropped. Defaults to DefaultBufferSize.
+	BufferSize int
+	// Bodies is the policy of the request and the response bodies. Defaults to BodiesNone.
+	Bodies BodyPolicy
+	// MaxBodySize is the maximum size of each of the bodies of a record. Defaults to DefaultMaxBodySize.
+	MaxBodySize int
+	// TenantHeaders are the names of the request headers identifying the tenant of the request.
+	TenantHeaders []string
+	// Key is the key of the HMAC-SHA256 chaining the records, so that the records cannot be forged or spliced from
+	// another log without the key. The records are chained by their plain SHA-256 if empty, which only detects the
+	// accidental modifications.
+	Key []byte
+	// Logger is the logger of the errors of the sink and of the dropped records.
+	Logger *slog.Logger
+}
+
+// Logger writes the audit records to the sink. The records are buffered and written by a single goroutine, which
+// also assigns their sequence numbers and their hashes, so that Log never blocks the caller.
+type Logger struct {
+	sink    Sink
+	opts    Options
+	logger  *slog.Logger
+	records chan *Record
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	// dropped is the number of the records dropped because the buffer is full, and reported is the number already
+	// logged, which is only accessed by the writer.
+	dropped  atomic.Uint64
+	reported uint64
+	// seq and prevHash are the sequence number and the hash of the last record, which are only accessed by the writer.
+	seq      uint64
+	prevHash string
+}
+
+// NewLogger creates a new Logger writing the records to the sink, and starts its writer.
+func NewLogger(sink Sink, opts Options) *Logger {
+	opts.BufferSize = cmp.Or(opts.BufferSize, DefaultBufferSize)
+	opts.Bodies = cmp.Or(opts.Bodies, BodiesNone)
+	opts.MaxBodySize = cmp.Or(opts.MaxBodySize, DefaultMaxBodySize)
+	l := &Logger{
+		sink:    sink,
+		opts:    opts,
+		logger:  cmp.Or(opts.Logger, slog.Default()),
+		records: make(chan *Record, opts.BufferSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// TenantHeaders returns the names of the request headers identifying the tenant of the request.
+func (l *Logger) TenantHeaders() []string {
+	return l.opts.TenantHeaders
+}
+
+// RecordsBodies returns true if the bodies of the requests and the responses are recorded, redacted or not.
+func (l *Logger) RecordsBodies() bool {
+	return l.opts.Bodies != BodiesNone
+}
+
+// MaxBodySize returns the maximum size of each of the bodies of a record.
+func (l *Logger) MaxBodySize() int {
+	return l.opts.MaxBodySize
+}
+
+// Log queues the record to be written to the sink, and returns false if it is dropped because the buffer is full.
+// This never blocks, and the record must not be modified after this.
+func (l *Logger) Log(r *Record) bool {
+	select {
+	case l.records <- r:
+		return true
+	default:
+		l.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped returns the number of the records dropped because the buffer is full.
+func (l *Logger) Dropped() uint64 {
+	return l.dropped.Load()
+}
+
+// Close writes the buffered records and closes the sink. The records logged after this are not written.
+func (l *Logger) Close(ctx context.Context) error {
+	l.once.Do(func() { close(l.stop) })
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to write the buffered audit records: %w", ctx.Err())
+	}
+	return l.sink.Close(ctx)
+}
+
+// run writes the records until the Logger is closed, and then the records buffered so far.
+func (l *Logger) run() {
+	defer close(l.done)
+	defer func() {
+		// The head of the chain is logged outside of the audit log, so that the records removed at the end of the
+		// chain are detected by comparing it with the last record.
+		if l.seq > 0 {
+			l.logger.Info("audit chain closed", slog.Uint64("seq", l.seq), slog.String("hash", l.prevHash))
+		}
+	}()
+	for {
+		select {
+		case r := <-l.records:
+			l.write(r)
+		case <-l.stop:
+			for {
+				select {
+				case r := <-l.records:
+					l.write(r)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write chains the record to the previous one and writes it to the sink.
+func (l *Logger) write(r *Record) {
+	if n := l.dropped.Load(); n > l.reported {
+		l.logger.Warn("audit records dropped because the buffer is full", slog.Uint64("dropped", n-l.reported))
+		l.reported = n
+	}
+	l.applyBodyPolicy(r)
+	l.seq++
+	r.Seq, r.PrevHash = l.seq, l.prevHash
+	line, hash, err := seal(r, l.opts.Key)
+	if err != nil {
+		l.logger.Error("failed to marshal the audit record", slog.String("error", err.Error()))
+		l.seq--
+		return
+	}
+	l.prevHash = hash
+	// The record is chained even if the sink fails to write it, so that the gap is detected.
+	if err = l.sink.Write(context.Background(), line); err != nil {
+		l.logger.Error("failed to write the audit record", slog.Uint64("seq", r.Seq), slog.String("error", err.Error()))
+	}
+}
+
+// hashSuffixLen is the length of the hash field appended to the serialized record by seal.
+var hashSuffixLen = len(`,"hash":""}`) + sha256.Size*2
+
+// seal serializes the record with its hash appended as the last field, and returns the line and the hash. The hash is
+// the HMAC-SHA256 with the key, or the SHA-256 if the key is empty, of the serialized record without the hash field,
+// which includes the hash of the previous record, so that the records form a chain. The hash is computed over the
+// exact bytes of the line, so that it does not depend on the order of the fields of the serialization.
+func seal(r *Record, key []byte) (line []byte, hash string, err error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, "", err
+	}
+	hash = hashOf(b, key)
+	line = append(b[:len(b)-1], `,"hash":"`...)
+	line = append(line, hash...)
+	return append(line, `"}`...), hash, nil
+}
+
+// hashOf returns the hex-encoded HMAC-SHA256 of the content with the key, or its SHA-256 if the key is empty.
+func hashOf(content, key []byte) string {
+	if len(key) == 0 {
+		sum := sha256.Sum256(content)
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(content)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Chain is a run of the chained records in a log verified by Verify.
+type Chain struct {
+	// Line is the line number of the first record of the chain in the log.
+	Line int
+	// FirstSeq and LastSeq are the sequence numbers of the first and the last records of the chain. FirstSeq is
+	// greater than 1 if the log starts in the middle of the chain, e.g., after a rotation or if the records at the
+	// beginning of the log are removed.
+	FirstSeq, LastSeq uint64
+	// LastHash is the hash of the last record of the chain, which is compared with the head of the chain logged by
+	// the external processor to detect the records removed at the end of the chain.
+	LastHash string
+}
+
+// Verify reads the JSON lines of the records and verifies their hash chain with the key of the Logger, i.e., that
+// each record has the hash of its content and follows the previous one, and returns the chains of the log in order.
+//
+// Only the first record of the log may start in the middle of a chain, which is reported by the FirstSeq of the first
+// chain. Any other record starts a new chain only if it has the sequence number 1 and no previous hash, which is the
+// case for the first record after the external processor restarts, or the records of another chain spliced into the
+// log. Each restart is reported as another chain, so the log is a single complete chain only if Verify returns one
+// chain starting at 1.
+func Verify(r io.Reader, key []byte) ([]Chain, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	var chains []Chain
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Bytes()
+		if len(line) < hashSuffixLen || !bytes.HasPrefix(line[len(line)-hashSuffixLen:], []byte(`,"hash":"`)) {
+			return chains, fmt.Errorf("line %d: missing hash", n)
+		}
+		content := append(bytes.Clone(line[:len(line)-hashSuffixLen]), '}')
+		hash := string(line[len(line)-hashSuffixLen+len(`,"hash":"`) : len(line)-2])
+		if !hmac.Equal([]byte(hashOf(content, key)), []byte(hash)) {
+			return chains, fmt.Errorf("line %d: hash mismatch", n)
+		}
+		s, prev := gjson.GetBytes(content, "seq").Uint(), gjson.GetBytes(content, "prevHash").String()
+		switch {
+		case len(chains) == 0 || (s == 1 && prev == ""):
+			chains = append(chains, Chain{Line: n, FirstSeq: s})
+		case s != chains[len(chains)-1].LastSeq+1:
+			return chains, fmt.Errorf("line %d: sequence number %d does not follow %d", n, s, chains[len(chains)-1].LastSeq)
+		case prev != chains[len(chains)-1].LastHash:
+			return chains, fmt.Errorf("line %d: previous hash does not match the record %d", n, chains[len(chains)-1].LastSeq)
+		}
+		c := &chains[len(chains)-1]
+		c.LastSeq, c.LastHash = s, hash
+	}
+	if err := scanner.Err(); err != nil {
+		return chains, fmt.Errorf("failed to read the audit records: %w", err)
+	}
+	return chains, nil
+}
@@ -0,0 +1,226 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+// memorySink is the Sink keeping the lines in memory, whose writes can be blocked to fill the buffer of the Logger.
+type memorySink struct {
+	mu     sync.Mutex
+	lines  []string
+	closed bool
+	// started is closed once the first write starts, which then waits until block is closed.
+	started   chan struct{}
+	startOnce sync.Once
+	block     chan struct{}
+}
+
+func (s *memorySink) Write(_ context.Context, line []byte) error {
+	if s.started != nil {
+		s.startOnce.Do(func() { close(s.started) })
+	}
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines = append(s.lines, string(line))
+	return nil
+}
+
+func (s *memorySink) Close(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func TestLogger(t *testing.T) {
+	sink := &memorySink{}
+	l := NewLogger(sink, Options{TenantHeaders: []string{"x-tenant"}})
+	require.Equal(t, []string{"x-tenant"}, l.TenantHeaders())
+	require.False(t, l.RecordsBodies())
+	require.Equal(t, DefaultMaxBodySize, l.MaxBodySize())
+
+	for _, backend := range []string{"a", "b", "c"} {
+		r := &Record{Time: time.Unix(0, 0).UTC(), Backend: backend, Tenant: map[string]string{"x-tenant": "acme"}}
+		r.SetBodies([]byte(`{"secret":"x"}`), []byte(`{}`), false)
+		require.True(t, l.Log(r))
+	}
+	require.NoError(t, l.Close(t.Context()))
+	require.True(t, sink.closed)
+
+	require.Len(t, sink.lines, 3)
+	var prevHash string
+	for i, line := range sink.lines {
+		require.Equal(t, int64(i+1), gjson.Get(line, "seq").Int())
+		require.Equal(t, prevHash, gjson.Get(line, "prevHash").String())
+		require.Equal(t, "acme", gjson.Get(line, "tenant.x-tenant").String())
+		// The bodies are omitted by default.
+		require.False(t, gjson.Get(line, "requestBody").Exists())
+		prevHash = gjson.Get(line, "hash").String()
+		require.Len(t, prevHash, 64)
+	}
+	chains, err := Verify(strings.NewReader(strings.Join(sink.lines, "\n")), nil)
+	require.NoError(t, err)
+	require.Equal(t, []Chain{{Line: 1, FirstSeq: 1, LastSeq: 3, LastHash: prevHash}}, chains)
+}
+
+func TestLogger_Dropped(t *testing.T) {
+	sink := &memorySink{started: make(chan struct{}), block: make(chan struct{})}
+	l := NewLogger(sink, Options{BufferSize: 1})
+
+	require.True(t, l.Log(&Record{}))
+	// The first record is being written, which blocks the writer.
+	<-sink.started
+	require.True(t, l.Log(&Record{}))
+	// The buffer is full, and Log does not block.
+	require.False(t, l.Log(&Record{}))
+	require.Equal(t, uint64(1), l.Dropped())
+
+	close(sink.block)
+	require.NoError(t, l.Close(t.Context()))
+	require.Len(t, sink.lines, 2)
+}
+
+func TestLogger_CloseTimeout(t *testing.T) {
+	sink := &memorySink{block: make(chan struct{})}
+	l := NewLogger(sink, Options{})
+	require.True(t, l.Log(&Record{}))
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	require.ErrorContains(t, l.Close(ctx), "failed to write the buffered audit records")
+	close(sink.block)
+	require.NoError(t, l.Close(t.Context()))
+}
+
+func TestVerify(t *testing.T) {
+	key := []byte("audit-key")
+	newLog := func(t *testing.T, routes ...string) (lines []string, head string) {
+		var buf, logs bytes.Buffer
+		l := NewLogger(NewWriterSink(&buf), Options{Key: key, Logger: slog.New(slog.NewJSONHandler(&logs, nil))})
+		for _, route := range routes {
+			l.Log(&Record{Route: route})
+		}
+		require.NoError(t, l.Close(t.Context()))
+		// The head of the chain is logged once the Logger is closed.
+		require.Equal(t, int64(len(routes)), gjson.Get(logs.String(), "seq").Int())
+		return strings.Split(strings.TrimSpace(buf.String()), "\n"), gjson.Get(logs.String(), "hash").String()
+	}
+	lines, head := newLog(t, "route", "route", "route")
+	require.Len(t, lines, 3)
+	require.Equal(t, gjson.Get(lines[2], "hash").String(), head)
+	other, _ := newLog(t, "a", "b")
+	hashOfLine := func(i int) string { return gjson.Get(lines[i], "hash").String() }
+
+	for _, tc := range []struct {
+		name      string
+		lines     []string
+		key       []byte
+		expChains []Chain
+		expErr    string
+	}{
+		{name: "valid", lines: lines, expChains: []Chain{{Line: 1, FirstSeq: 1, LastSeq: 3, LastHash: head}}},
+		{
+			// The records removed at the beginning of the log are reported by the first sequence number.
+			name: "truncated beginning", lines: lines[1:],
+			expChains: []Chain{{Line: 1, FirstSeq: 2, LastSeq: 3, LastHash: head}},
+		},
+		{
+			// The records removed at the end of the log are reported by the last hash not matching the head.
+			name: "truncated end", lines: lines[:2],
+			expChains: []Chain{{Line: 1, FirstSeq: 1, LastSeq: 2, LastHash: hashOfLine(1)}},
+		},
+		{
+			name: "restart", lines: append(append([]string{}, lines...), lines[0]),
+			expChains: []Chain{
+				{Line: 1, FirstSeq: 1, LastSeq: 3, LastHash: head},
+				{Line: 4, FirstSeq: 1, LastSeq: 1, LastHash: hashOfLine(0)},
+			},
+		},
+		{
+			// A chain spliced into the log in place of the end of the chain is reported as a restart.
+			name: "spliced chain", lines: []string{lines[0], other[0], other[1]},
+			expChains: []Chain{
+				{Line: 1, FirstSeq: 1, LastSeq: 1, LastHash: hashOfLine(0)},
+				{Line: 2, FirstSeq: 1, LastSeq: 2, LastHash: gjson.Get(other[1], "hash").String()},
+			},
+		},
+		{
+			name: "spliced record", lines: []string{lines[0], other[1], lines[2]},
+			expChains: []Chain{{Line: 1, FirstSeq: 1, LastSeq: 1, LastHash: hashOfLine(0)}},
+			expErr:    "line 2: previous hash does not match the record 1",
+		},
+		{
+			name: "removed", lines: []string{lines[0], lines[2]},
+			expChains: []Chain{{Line: 1, FirstSeq: 1, LastSeq: 1, LastHash: hashOfLine(0)}},
+			expErr:    "line 2: sequence number 3 does not follow 1",
+		},
+		{
+			name:      "modified",
+			lines:     []string{lines[0], strings.Replace(lines[1], `"route"`, `"other"`, 1), lines[2]},
+			expChains: []Chain{{Line: 1, FirstSeq: 1, LastSeq: 1, LastHash: hashOfLine(0)}},
+			expErr:    "line 2: hash mismatch",
+		},
+		{
+			// The record modified and rehashed without the key is detected.
+			name:   "forged",
+			lines:  []string{forge(t, strings.Replace(lines[0], `"route"`, `"other"`, 1))},
+			expErr: "line 1: hash mismatch",
+		},
+		{name: "wrong key", lines: lines, key: []byte("other-key"), expErr: "line 1: hash mismatch"},
+		{name: "missing hash", lines: []string{`{"seq":1}`}, expErr: "line 1: missing hash"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			k := tc.key
+			if k == nil {
+				k = key
+			}
+			chains, err := Verify(strings.NewReader(strings.Join(tc.lines, "\n")), k)
+			require.Equal(t, tc.expChains, chains)
+			if tc.expErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expErr)
+			}
+		})
+	}
+
+	t.Run("without key", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := NewLogger(NewWriterSink(&buf), Options{})
+		l.Log(&Record{Route: "route"})
+		require.NoError(t, l.Close(t.Context()))
+		chains, err := Verify(strings.NewReader(buf.String()), nil)
+		require.NoError(t, err)
+		require.Len(t, chains, 1)
+		// The chain with a key is not verified without it.
+		_, err = Verify(strings.NewReader(strings.Join(lines, "\n")), nil)
+		require.EqualError(t, err, "line 1: hash mismatch")
+	})
+}
+
+// forge replaces the hash of the line with the SHA-256 of its content, as if the record were rehashed without the key.
+func forge(t *testing.T, line string) string {
+	content := line[:len(line)-hashSuffixLen] + "}"
+	sum := sha256.Sum256([]byte(content))
+	forged := line[:len(line)-hashSuffixLen] + `,"hash":"` + hex.EncodeToString(sum[:]) + `"}`
+	require.Len(t, forged, len(line))
+	return forged
+}
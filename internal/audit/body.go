@@ -0,0 +1,149 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package audit
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/redaction"
+)
+
+// BodyPolicy is the policy of the request and the response bodies of the audit records.
+type BodyPolicy string
+
+const (
+	// BodiesNone omits the bodies.
+	BodiesNone BodyPolicy = "none"
+	// BodiesRedacted records the bodies with their string values redacted, except for the structural ones such as
+	// the models and the roles, so that the structure of the requests is recorded without their content.
+	BodiesRedacted BodyPolicy = "redacted"
+	// BodiesFull records the bodies as they are.
+	BodiesFull BodyPolicy = "full"
+)
+
+// ParseBodyPolicy parses the name of a BodyPolicy.
+func ParseBodyPolicy(s string) (BodyPolicy, error) {
+	switch p := BodyPolicy(s); p {
+	case BodiesNone, BodiesRedacted, BodiesFull:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid audit body policy %q", s)
+}
+
+// unredactedKeys are the keys of the string values of the JSON bodies kept by BodiesRedacted, which describe the
+// structure of the requests and the responses rather than their content.
+var unredactedKeys = map[string]struct{}{
+	"model": {}, "role": {}, "type": {}, "object": {}, "finish_reason": {}, "stop_reason": {}, "status": {},
+}
+
+// AppendBody appends the chunk of a body to the body so far up to the limit, and returns true if the chunk is cut.
+func AppendBody(body, chunk []byte, limit int) ([]byte, bool) {
+	room := limit - len(body)
+	if room >= len(chunk) {
+		return append(body, chunk...), false
+	}
+	return append(body, chunk[:max(room, 0)]...), true
+}
+
+// applyBodyPolicy sets the bodies of the record from its raw bodies according to the body policy of the Logger.
+func (l *Logger) applyBodyPolicy(r *Record) {
+	request, response := r.requestBody, r.responseBody
+	r.requestBody, r.responseBody = nil, nil
+	var truncated bool
+	switch l.opts.Bodies {
+	case BodiesFull:
+		r.RequestBody, truncated = truncateBody(string(request), l.opts.MaxBodySize)
+		r.BodiesTruncated = r.BodiesTruncated || truncated
+		r.ResponseBody, truncated = truncateBody(string(response), l.opts.MaxBodySize)
+	case BodiesRedacted:
+		r.RequestBody, truncated = truncateBody(redactBody(request), l.opts.MaxBodySize)
+		r.BodiesTruncated = r.BodiesTruncated || truncated
+		r.ResponseBody, truncated = truncateBody(redactBody(response), l.opts.MaxBodySize)
+	default:
+		r.RequestBody, r.ResponseBody, r.BodiesTruncated = "", "", false
+	}
+	r.BodiesTruncated = r.BodiesTruncated || truncated
+}
+
+// truncateBody cuts the body at the limit on a character boundary, and replaces the invalid UTF-8 sequences, e.g., of
+// the binary bodies, so that the body is a valid JSON string.
+func truncateBody(body string, limit int) (string, bool) {
+	truncated := len(body) > limit
+	if truncated {
+		body = body[:limit]
+		for len(body) > 0 && !utf8.ValidString(body[max(len(body)-utf8.UTFMax, 0):]) {
+			body = body[:len(body)-1]
+		}
+	}
+	return strings.ToValidUTF8(body, "�"), truncated
+}
+
+// redactBody redacts the string values of the JSON body, or of each of the JSON events of the server-sent events
+// body, keeping the structure of the body. Any other body is redacted as a whole.
+func redactBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err == nil {
+		return marshalRedacted(v)
+	}
+	lines := strings.Split(string(body), "\n")
+	sse := false
+	for i, line := range lines {
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		sse = true
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			continue
+		}
+		v = nil
+		if err := json.Unmarshal([]byte(data), &v); err != nil {
+			lines[i] = "data: " + redaction.RedactString(data)
+			continue
+		}
+		lines[i] = "data: " + marshalRedacted(v)
+	}
+	if !sse {
+		return redaction.RedactString(string(body))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// marshalRedacted marshals the JSON value with its string values redacted.
+func marshalRedacted(v any) string {
+	b, err := json.Marshal(redactValue("", v))
+	if err != nil {
+		return redaction.RedactString(fmt.Sprint(v))
+	}
+	return string(b)
+}
+
+// redactValue redacts the string values of the JSON value, except for the ones of the unredacted keys.
+func redactValue(key string, v any) any {
+	switch v := v.(type) {
+	case string:
+		if _, ok := unredactedKeys[key]; ok {
+			return v
+		}
+		return redaction.RedactString(v)
+	case map[string]any:
+		for k, e := range v {
+			v[k] = redactValue(k, e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = redactValue(key, e)
+		}
+	}
+	return v
+}
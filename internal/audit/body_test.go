@@ -0,0 +1,94 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package audit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/envoyproxy/ai-gateway/internal/redaction"
+)
+
+func TestParseBodyPolicy(t *testing.T) {
+	for _, s := range []string{"none", "redacted", "full"} {
+		p, err := ParseBodyPolicy(s)
+		require.NoError(t, err)
+		require.Equal(t, BodyPolicy(s), p)
+	}
+	_, err := ParseBodyPolicy("partial")
+	require.EqualError(t, err, `invalid audit body policy "partial"`)
+}
+
+func TestAppendBody(t *testing.T) {
+	body, truncated := AppendBody(nil, []byte("abc"), 5)
+	require.False(t, truncated)
+	require.Equal(t, "abc", string(body))
+	body, truncated = AppendBody(body, []byte("def"), 5)
+	require.True(t, truncated)
+	require.Equal(t, "abcde", string(body))
+	body, truncated = AppendBody(body, []byte("g"), 5)
+	require.True(t, truncated)
+	require.Equal(t, "abcde", string(body))
+}
+
+func TestApplyBodyPolicy(t *testing.T) {
+	request := []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"my secret"}],"max_tokens":10}`)
+	response := []byte("data: {\"object\":\"chat.completion.chunk\",\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n\n")
+
+	t.Run("none", func(t *testing.T) {
+		r := &Record{}
+		r.SetBodies(request, response, true)
+		(&Logger{opts: Options{Bodies: BodiesNone, MaxBodySize: 1024}}).applyBodyPolicy(r)
+		require.Empty(t, r.RequestBody)
+		require.Empty(t, r.ResponseBody)
+		require.False(t, r.BodiesTruncated)
+	})
+
+	t.Run("full", func(t *testing.T) {
+		r := &Record{}
+		r.SetBodies(request, response, false)
+		(&Logger{opts: Options{Bodies: BodiesFull, MaxBodySize: 1024}}).applyBodyPolicy(r)
+		require.Equal(t, string(request), r.RequestBody)
+		require.Equal(t, string(response), r.ResponseBody)
+		require.False(t, r.BodiesTruncated)
+
+		r.SetBodies(request, response, false)
+		(&Logger{opts: Options{Bodies: BodiesFull, MaxBodySize: 16}}).applyBodyPolicy(r)
+		require.Equal(t, string(request[:16]), r.RequestBody)
+		require.True(t, r.BodiesTruncated)
+	})
+
+	t.Run("redacted", func(t *testing.T) {
+		r := &Record{}
+		r.SetBodies(request, response, false)
+		(&Logger{opts: Options{Bodies: BodiesRedacted, MaxBodySize: 1024}}).applyBodyPolicy(r)
+		require.JSONEq(t, `{"model":"gpt-4o","messages":[{"role":"user","content":"`+redaction.RedactString("my secret")+`"}],"max_tokens":10}`,
+			r.RequestBody)
+		// The keys of the JSON events are marshaled in any order.
+		lines := strings.Split(r.ResponseBody, "\n")
+		require.Equal(t, []string{"", "data: [DONE]", "", ""}, lines[1:])
+		data, ok := strings.CutPrefix(lines[0], "data: ")
+		require.True(t, ok)
+		require.JSONEq(t, `{"object":"chat.completion.chunk","choices":[{"delta":{"content":"`+redaction.RedactString("hi")+`"}}]}`, data)
+	})
+}
+
+func TestRedactBody(t *testing.T) {
+	require.Empty(t, redactBody(nil))
+	require.Equal(t, redaction.RedactString("plain text"), redactBody([]byte("plain text")))
+	require.Equal(t, "event: x\ndata: "+redaction.RedactString("not json")+"\n\n", redactBody([]byte("event: x\ndata: not json\n\n")))
+}
+
+func TestTruncateBody(t *testing.T) {
+	s, truncated := truncateBody("日本語", 4)
+	require.True(t, truncated)
+	require.Equal(t, "日", s)
+	s, truncated = truncateBody("a\xffb", 10)
+	require.False(t, truncated)
+	require.Equal(t, "a�b", s)
+}
@@ -0,0 +1,263 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/exporters/autoexport"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Sink writes the audit records. The records are written by the single writer of the Logger, so the implementations
+// need not be safe for concurrent use. This can be plugged in with RegisterSink by the users building their own
+// external processor.
+type Sink interface {
+	// Write writes the line of a record, which is a JSON object without the trailing newline.
+	Write(ctx context.Context, line []byte) error
+	// Close flushes the records written so far and releases the resources of the sink.
+	Close(ctx context.Context) error
+}
+
+// SinkFactory creates a Sink from its configuration, which is the value of the -auditSinkConfig flag of the
+// external processor.
+type SinkFactory func(ctx context.Context, config string) (Sink, error)
+
+var (
+	sinkFactoriesMu sync.RWMutex
+	sinkFactories   = map[string]SinkFactory{
+		"stdout": func(context.Context, string) (Sink, error) {
+			return NewWriterSink(os.Stdout), nil
+		},
+		"file": func(_ context.Context, config string) (Sink, error) {
+			return newFileSinkFromConfig(config)
+		},
+		"otlp": func(ctx context.Context, _ string) (Sink, error) {
+			return NewOTLPSink(ctx)
+		},
+	}
+)
+
+// RegisterSink registers the factory of the sink with the given name, which can then be selected with the -auditSink
+// flag of the external processor. This replaces the factory already registered with the name.
+func RegisterSink(name string, f SinkFactory) {
+	sinkFactoriesMu.Lock()
+	defer sinkFactoriesMu.Unlock()
+	sinkFactories[name] = f
+}
+
+// NewSink creates the sink registered with the given name from its configuration.
+func NewSink(ctx context.Context, name, config string) (Sink, error) {
+	sinkFactoriesMu.RLock()
+	f, ok := sinkFactories[name]
+	sinkFactoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown audit sink %q", name)
+	}
+	return f(ctx, config)
+}
+
+// writerSink is the Sink writing each record as a line of the writer.
+type writerSink struct {
+	w io.Writer
+}
+
+// NewWriterSink creates a new Sink writing each record as a line of the writer, e.g., os.Stdout.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+// Write implements [Sink.Write].
+func (s *writerSink) Write(_ context.Context, line []byte) error {
+	_, err := s.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close implements [Sink.Close].
+func (s *writerSink) Close(context.Context) error { return nil }
+
+const (
+	// DefaultFileMaxSizeMB is the default size of the file of the file sink in megabytes at which it is rotated.
+	DefaultFileMaxSizeMB = 100
+	// DefaultFileMaxBackups is the default number of the rotated files kept by the file sink.
+	DefaultFileMaxBackups = 10
+)
+
+// fileSink is the Sink writing the records as the JSON lines of a file, which is rotated once it reaches the maximum
+// size. The rotated files are suffixed with their index, starting at 1 for the most recent one, and the ones beyond
+// the maximum number of the backups are removed.
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	f          *os.File
+	size       int64
+}
+
+// newFileSinkFromConfig creates a new file sink from its configuration, which is the path of the file optionally
+// followed by the maximum size in megabytes and the maximum number of the backups, separated by commas.
+func newFileSinkFromConfig(config string) (Sink, error) {
+	parts := strings.Split(config, ",")
+	if parts[0] == "" {
+		return nil, errors.New("the path of the audit file must be set")
+	}
+	maxSizeMB, maxBackups := DefaultFileMaxSizeMB, DefaultFileMaxBackups
+	var err error
+	if len(parts) > 1 {
+		if maxSizeMB, err = strconv.Atoi(parts[1]); err != nil || maxSizeMB <= 0 {
+			return nil, fmt.Errorf("invalid maximum size of the audit file %q", parts[1])
+		}
+	}
+	if len(parts) > 2 {
+		if maxBackups, err = strconv.Atoi(parts[2]); err != nil || maxBackups < 0 {
+			return nil, fmt.Errorf("invalid maximum number of the audit file backups %q", parts[2])
+		}
+	}
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid audit file configuration %q", config)
+	}
+	return NewFileSink(parts[0], int64(maxSizeMB)<<20, maxBackups)
+}
+
+// NewFileSink creates a new Sink writing the records as the JSON lines of the file at the path, which is rotated
+// once it reaches maxSize bytes, keeping maxBackups rotated files. The records are appended to the existing file.
+func NewFileSink(path string, maxSize int64, maxBackups int) (Sink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create the directory of the audit file: %w", err)
+	}
+	s := &fileSink{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// open opens the file for appending.
+func (s *fileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open the audit file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to stat the audit file: %w", err)
+	}
+	s.f, s.size = f, info.Size()
+	return nil
+}
+
+// Write implements [Sink.Write].
+func (s *fileSink) Write(_ context.Context, line []byte) error {
+	n := int64(len(line)) + 1
+	if s.size > 0 && s.size+n > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write the audit file: %w", err)
+	}
+	s.size += n
+	return nil
+}
+
+// rotate renames the file to the first backup, shifting the existing backups, and opens a new file.
+func (s *fileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return fmt.Errorf("failed to close the audit file: %w", err)
+	}
+	if s.maxBackups == 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove the audit file: %w", err)
+		}
+		return s.open()
+	}
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(s.backup(i), s.backup(i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rotate the audit file: %w", err)
+		}
+	}
+	if err := os.Rename(s.path, s.backup(1)); err != nil {
+		return fmt.Errorf("failed to rotate the audit file: %w", err)
+	}
+	return s.open()
+}
+
+// backup returns the path of the i-th backup.
+func (s *fileSink) backup(i int) string {
+	return s.path + "." + strconv.Itoa(i)
+}
+
+// Close implements [Sink.Close].
+func (s *fileSink) Close(context.Context) error {
+	if err := s.f.Sync(); err != nil {
+		_ = s.f.Close()
+		return fmt.Errorf("failed to sync the audit file: %w", err)
+	}
+	return s.f.Close()
+}
+
+// otlpSink is the Sink emitting the records as the OpenTelemetry log records, whose body is the JSON line.
+type otlpSink struct {
+	provider *sdklog.LoggerProvider
+	logger   otellog.Logger
+}
+
+// NewOTLPSink creates a new Sink exporting the records as the OpenTelemetry log records, configured by the standard
+// environment variables such as OTEL_EXPORTER_OTLP_LOGS_ENDPOINT and OTEL_LOGS_EXPORTER.
+func NewOTLPSink(ctx context.Context) (Sink, error) {
+	exporter, err := autoexport.NewLogExporter(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the audit log exporter: %w", err)
+	}
+	envRes, err := resource.New(ctx, resource.WithFromEnv(), resource.WithTelemetrySDK())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource from env: %w", err)
+	}
+	// The service name of the environment takes precedence over the default one.
+	res, err := resource.Merge(resource.NewSchemaless(attribute.String("service.name", "ai-gateway")), envRes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge env resource: %w", err)
+	}
+	return newOTLPSink(sdklog.NewBatchProcessor(exporter), res), nil
+}
+
+// newOTLPSink creates a new otlpSink emitting the records to the processor.
+func newOTLPSink(processor sdklog.Processor, res *resource.Resource) *otlpSink {
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(processor), sdklog.WithResource(res))
+	return &otlpSink{provider: provider, logger: provider.Logger("github.com/envoyproxy/ai-gateway/internal/audit")}
+}
+
+// Write implements [Sink.Write].
+func (s *otlpSink) Write(ctx context.Context, line []byte) error {
+	var r otellog.Record
+	r.SetTimestamp(time.Now())
+	r.SetEventName("aigw.audit")
+	r.SetSeverity(otellog.SeverityInfo)
+	r.SetBody(otellog.StringValue(string(line)))
+	s.logger.Emit(ctx, r)
+	return nil
+}
+
+// Close implements [Sink.Close].
+func (s *otlpSink) Close(ctx context.Context) error {
+	return s.provider.Shutdown(ctx)
+}
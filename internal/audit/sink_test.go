@@ -0,0 +1,132 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func TestNewSink(t *testing.T) {
+	_, err := NewSink(t.Context(), "unknown", "")
+	require.EqualError(t, err, `unknown audit sink "unknown"`)
+
+	s, err := NewSink(t.Context(), "stdout", "")
+	require.NoError(t, err)
+	require.IsType(t, &writerSink{}, s)
+
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		config string
+		expErr string
+	}{
+		{config: "", expErr: "the path of the audit file must be set"},
+		{config: filepath.Join(dir, "a.jsonl") + ",0", expErr: `invalid maximum size of the audit file "0"`},
+		{config: filepath.Join(dir, "a.jsonl") + ",1,-1", expErr: `invalid maximum number of the audit file backups "-1"`},
+		{config: filepath.Join(dir, "a.jsonl") + ",1,1,1", expErr: "invalid audit file configuration"},
+		{config: filepath.Join(dir, "a.jsonl") + ",1,2"},
+	} {
+		t.Run(tc.config, func(t *testing.T) {
+			s, err := NewSink(t.Context(), "file", tc.config)
+			if tc.expErr != "" {
+				require.ErrorContains(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, int64(1<<20), s.(*fileSink).maxSize)
+			require.Equal(t, 2, s.(*fileSink).maxBackups)
+			require.NoError(t, s.Close(t.Context()))
+		})
+	}
+
+	RegisterSink("custom", func(context.Context, string) (Sink, error) { return &memorySink{}, nil })
+	s, err = NewSink(t.Context(), "custom", "")
+	require.NoError(t, err)
+	require.IsType(t, &memorySink{}, s)
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewWriterSink(&buf)
+	require.NoError(t, s.Write(t.Context(), []byte(`{"seq":1}`)))
+	require.NoError(t, s.Write(t.Context(), []byte(`{"seq":2}`)))
+	require.NoError(t, s.Close(t.Context()))
+	require.Equal(t, "{\"seq\":1}\n{\"seq\":2}\n", buf.String())
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	read := func(p string) string {
+		b, err := os.ReadFile(p)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	s, err := NewFileSink(path, 20, 2)
+	require.NoError(t, err)
+	for _, line := range []string{"0123456789", "abcdefgh", "ABCDEFGH", "0123456789ab"} {
+		require.NoError(t, s.Write(t.Context(), []byte(line)))
+	}
+	require.NoError(t, s.Close(t.Context()))
+	// Each file holds the lines up to the maximum size, and the oldest ones are rotated out.
+	require.Equal(t, "0123456789ab\n", read(path))
+	require.Equal(t, "ABCDEFGH\n", read(path+".1"))
+	require.Equal(t, "0123456789\nabcdefgh\n", read(path+".2"))
+
+	// The records are appended to the existing file.
+	s, err = NewFileSink(path, 20, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Write(t.Context(), []byte("uvw")))
+	require.Equal(t, "0123456789ab\nuvw\n", read(path))
+	require.NoError(t, s.Write(t.Context(), []byte("0123456789abcdef")))
+	require.NoError(t, s.Close(t.Context()))
+	// Without backups, the file is truncated on the rotation.
+	require.Equal(t, "0123456789abcdef\n", read(path))
+}
+
+// memoryLogExporter is the OpenTelemetry log exporter keeping the bodies of the exported records in memory.
+type memoryLogExporter struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (e *memoryLogExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range records {
+		require.Equal(nil, "aigw.audit", r.EventName())
+		e.bodies = append(e.bodies, r.Body().AsString())
+	}
+	return nil
+}
+
+func (e *memoryLogExporter) Shutdown(context.Context) error   { return nil }
+func (e *memoryLogExporter) ForceFlush(context.Context) error { return nil }
+
+func TestOTLPSink(t *testing.T) {
+	exporter := &memoryLogExporter{}
+	s := newOTLPSink(sdklog.NewBatchProcessor(exporter), resource.Empty())
+	require.NoError(t, s.Write(t.Context(), []byte(`{"seq":1}`)))
+	require.NoError(t, s.Write(t.Context(), []byte(`{"seq":2}`)))
+	// The batched records are exported on the close.
+	require.NoError(t, s.Close(t.Context()))
+	require.Equal(t, []string{`{"seq":1}`, `{"seq":2}`}, exporter.bodies)
+}
+
+func TestNewOTLPSink(t *testing.T) {
+	t.Setenv("OTEL_LOGS_EXPORTER", "console")
+	s, err := NewOTLPSink(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, s.Close(t.Context()))
+}
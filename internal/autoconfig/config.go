@@ -48,6 +48,14 @@ type AnthropicConfig struct {
 	Version     string // API version (Anthropic path prefix)
 }
 
+// OllamaConfig holds Ollama-specific configuration for generating AIServiceBackend resources.
+// This is nil when no Ollama configuration is present.
+type OllamaConfig struct {
+	BackendName string   // References a Backend.Name (typically "ollama")
+	SchemaName  string   // Schema name: "Ollama"
+	Models      []string // Models discovered from the Ollama server, declared so they are listed by /v1/models
+}
+
 // MCPBackendRef references a backend with MCP-specific routing configuration.
 // Used to generate MCPRoute backendRefs with path, tool filtering, and authentication.
 type MCPBackendRef struct {
@@ -59,11 +67,12 @@ type MCPBackendRef struct {
 }
 
 // ConfigData holds all template data for generating the AI Gateway configuration.
-// It supports OpenAI-only, Anthropic-only, MCP-only, or combined configurations.
+// It supports OpenAI-only, Anthropic-only, Ollama-only, MCP-only, or combined configurations.
 type ConfigData struct {
-	Backends       []Backend        // All backend endpoints (e.g. OpenAI, Anthropic, MCP, and OTEL)
+	Backends       []Backend        // All backend endpoints (e.g. OpenAI, Anthropic, Ollama, MCP, and OTEL)
 	OpenAI         *OpenAIConfig    // OpenAI-specific configuration (nil when not present)
 	Anthropic      *AnthropicConfig // Anthropic-specific configuration (nil when not present)
+	Ollama         *OllamaConfig    // Ollama-specific configuration (nil when not present)
 	MCPBackendRefs []MCPBackendRef  // MCP routing configuration (nil/empty for OpenAI-only or Anthropic-only mode)
 	Debug          bool             // Enable debug logging for Envoy (includes component-level logging for ext_proc, http, connection)
 	EnvoyVersion   string           // Explicitly configure the version of Envoy to use.
@@ -70,6 +70,9 @@ var (
 	//go:embed testdata/anthropic.yaml
 	anthropicYAML string
 
+	//go:embed testdata/ollama.yaml
+	ollamaYAML string
+
 	//go:embed testdata/openai-otel.yaml
 	openaiOTELYAML string
 
@@ -456,6 +459,25 @@ func TestWriteConfig(t *testing.T) {
 			},
 			expected: anthropicYAML,
 		},
+		{
+			name: "Ollama native API with discovered models",
+			input: ConfigData{
+				Backends: []Backend{
+					{
+						Name: "ollama",
+						IP:   "127.0.0.1",
+						Port: 11434,
+					},
+				},
+				Ollama: &OllamaConfig{
+					BackendName: "ollama",
+					SchemaName:  "Ollama",
+					Models:      []string{"llama3.2:latest", "nomic-embed-text:latest"},
+				},
+				OTELLog: &otelLogConfig{Exporter: "console"},
+			},
+			expected: ollamaYAML,
+		},
 	}
 
 	for _, tt := range tests {
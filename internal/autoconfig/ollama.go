@@ -0,0 +1,128 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package autoconfig
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/envoyproxy/ai-gateway/internal/translator"
+)
+
+const (
+	// ollamaDefaultPort is the port Ollama listens on when OLLAMA_HOST does not specify one.
+	ollamaDefaultPort = "11434"
+	// ollamaDiscoveryTimeout bounds the best-effort model discovery at startup.
+	ollamaDiscoveryTimeout = 2 * time.Second
+	// ollamaMaxDeclaredModels keeps the generated route rule within the maximum number of matches,
+	// leaving room for the catch-all match.
+	ollamaMaxDeclaredModels = 127
+)
+
+// PopulateOllamaEnvConfig populates ConfigData with Ollama backend configuration
+// from the OLLAMA_HOST environment variable, the same one used by the Ollama CLI.
+//
+// This errs if OLLAMA_HOST is not set.
+//
+// The models available in Ollama are discovered via its /api/tags endpoint, so that they
+// are listed by /v1/models. Discovery is best-effort: when Ollama is not reachable, every
+// model name is still routed to it, but none are declared.
+//
+// See https://github.com/ollama/ollama/blob/main/docs/faq.md#how-do-i-configure-ollama-server
+func PopulateOllamaEnvConfig(data *ConfigData) error {
+	if data == nil {
+		return fmt.Errorf("ConfigData cannot be nil")
+	}
+
+	ollamaHost := os.Getenv("OLLAMA_HOST")
+	if ollamaHost == "" {
+		return fmt.Errorf("OLLAMA_HOST environment variable is required")
+	}
+	baseURL, err := ollamaBaseURL(ollamaHost)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := parseURL(baseURL)
+	if err != nil {
+		return err
+	}
+
+	data.Backends = append(data.Backends, Backend{
+		Name:     "ollama",
+		Hostname: parsed.hostname,
+		IP:       parsed.ip,
+		Port:     parsed.port,
+		NeedsTLS: parsed.needsTLS,
+	})
+	data.Ollama = &OllamaConfig{
+		BackendName: "ollama",
+		SchemaName:  "Ollama",
+		Models:      discoverOllamaModels(baseURL),
+	}
+	return nil
+}
+
+// ollamaBaseURL normalizes OLLAMA_HOST, which may omit the scheme and the port, to a base URL.
+// The unspecified address Ollama is commonly bound to, e.g. "0.0.0.0", is mapped to the loopback address.
+func ollamaBaseURL(ollamaHost string) (string, error) {
+	if !strings.Contains(ollamaHost, "://") {
+		ollamaHost = "http://" + ollamaHost
+	}
+	u, err := url.Parse(ollamaHost)
+	if err != nil {
+		return "", fmt.Errorf("invalid OLLAMA_HOST: %w", err)
+	}
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = ollamaDefaultPort
+	}
+	switch host {
+	case "", "0.0.0.0":
+		host = "127.0.0.1"
+	case "::":
+		host = "::1"
+	}
+	u.Host = net.JoinHostPort(host, port)
+	u.Path = ""
+	return u.String(), nil
+}
+
+// discoverOllamaModels returns the IDs of the models available in Ollama, or nil if they cannot be listed.
+func discoverOllamaModels(baseURL string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), ollamaDiscoveryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	modelList, err := translator.OllamaModelListToOpenAI(resp.Body, "Ollama")
+	if err != nil {
+		return nil
+	}
+	var models []string
+	for _, m := range modelList.Data {
+		if len(models) == ollamaMaxDeclaredModels {
+			break
+		}
+		models = append(models, m.ID)
+	}
+	return models
+}
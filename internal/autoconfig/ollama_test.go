@@ -0,0 +1,87 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package autoconfig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	internaltesting "github.com/envoyproxy/ai-gateway/internal/testing"
+)
+
+func TestPopulateOllamaEnvConfig(t *testing.T) {
+	internaltesting.ClearTestEnv(t)
+
+	t.Run("missing OLLAMA_HOST", func(t *testing.T) {
+		err := PopulateOllamaEnvConfig(&ConfigData{})
+		require.EqualError(t, err, "OLLAMA_HOST environment variable is required")
+	})
+
+	t.Run("nil ConfigData", func(t *testing.T) {
+		require.EqualError(t, PopulateOllamaEnvConfig(nil), "ConfigData cannot be nil")
+	})
+
+	t.Run("discovers models", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/api/tags", r.URL.Path)
+			_, _ = w.Write([]byte(`{"models":[{"name":"llama3.2:latest","model":"llama3.2:latest"},{"name":"qwen3:8b","model":"qwen3:8b"}]}`))
+		}))
+		t.Cleanup(server.Close)
+		t.Setenv("OLLAMA_HOST", server.URL)
+
+		data := &ConfigData{}
+		require.NoError(t, PopulateOllamaEnvConfig(data))
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+		require.Len(t, data.Backends, 1)
+		require.Equal(t, "ollama", data.Backends[0].Name)
+		require.Equal(t, "127.0.0.1", data.Backends[0].IP)
+		require.Equal(t, u.Port(), strconv.Itoa(data.Backends[0].Port))
+		require.Equal(t, &OllamaConfig{
+			BackendName: "ollama",
+			SchemaName:  "Ollama",
+			Models:      []string{"llama3.2:latest", "qwen3:8b"},
+		}, data.Ollama)
+	})
+
+	t.Run("unreachable server declares no models", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		t.Cleanup(server.Close)
+		t.Setenv("OLLAMA_HOST", server.URL)
+
+		data := &ConfigData{}
+		require.NoError(t, PopulateOllamaEnvConfig(data))
+		require.Nil(t, data.Ollama.Models)
+	})
+}
+
+func TestOllamaBaseURL(t *testing.T) {
+	tests := []struct {
+		ollamaHost string
+		expected   string
+	}{
+		{ollamaHost: "localhost", expected: "http://localhost:11434"},
+		{ollamaHost: "0.0.0.0", expected: "http://127.0.0.1:11434"},
+		{ollamaHost: "0.0.0.0:8080", expected: "http://127.0.0.1:8080"},
+		{ollamaHost: "[::]:11434", expected: "http://[::1]:11434"},
+		{ollamaHost: "https://ollama.example.com", expected: "https://ollama.example.com:11434"},
+		{ollamaHost: "http://ollama.local:11434/", expected: "http://ollama.local:11434"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ollamaHost, func(t *testing.T) {
+			actual, err := ollamaBaseURL(tt.ollamaHost)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
@@ -25,8 +25,8 @@ func newAzureAPIKeyHandler(auth *filterapi.AzureAPIKeyAuth) (filterapi.BackendAu
 	return &azureAPIKeyHandler{apiKey: strings.TrimSpace(auth.Key)}, nil
 }
 
-// Do sets the api-key header for Azure OpenAI authentication.
-// Azure OpenAI uses "api-key" header instead of "Authorization: Bearer".
+// Do sets the api-key header for Azure OpenAI and Azure AI Inference authentication.
+// Both use "api-key" header instead of "Authorization: Bearer".
 func (a *azureAPIKeyHandler) Do(_ context.Context, requestHeaders map[string]string, _ []byte) ([]internalapi.Header, error) {
 	requestHeaders["api-key"] = a.apiKey
 	return []internalapi.Header{{"api-key", a.apiKey}}, nil
@@ -0,0 +1,197 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+// Package backendselection implements the selection of the preferred backend of the AIGatewayRoute rules with a
+// backend selection, based on the latency observed on the responses of the backends, their failures and their price.
+package backendselection
+
+import (
+	"slices"
+	"sync"
+)
+
+// ExpectedOutputTokens is the number of the output tokens assumed for the latency and the cost of a request, since
+// it is not known before the response.
+const ExpectedOutputTokens = 256
+
+// ProbeProbability is the probability of selecting a candidate without any observed latency so that its latency
+// gets measured, instead of the preferred one among the observed candidates.
+const ProbeProbability = 0.05
+
+// ewmaWeight is the weight of a new sample in the exponentially weighted moving averages of the latency and the
+// failure rate.
+const ewmaWeight = 0.2
+
+// maxFailureRate caps the failure rate applied to the expected latency, so that the expected latency of a failing
+// backend stays finite.
+const maxFailureRate = 0.9
+
+// Latency holds the exponentially weighted moving averages of the latency and the failure rate of a backend. This is
+// safe for concurrent use.
+type Latency struct {
+	mu                  sync.Mutex
+	observed            bool
+	timeToFirstTokenMs  float64
+	interTokenLatencyMs float64
+	failureRate         float64
+}
+
+// Record records the time to first token and the inter-token latency of a streaming response in milliseconds.
+func (l *Latency) Record(timeToFirstTokenMs, interTokenLatencyMs float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.record(timeToFirstTokenMs, interTokenLatencyMs)
+}
+
+// RecordResponse records the latency of a non-streaming response in milliseconds with the number of its output
+// tokens. The time to first token of the response is not known, so it is estimated from the inter-token latency
+// observed so far, or the latency is taken as the inter-token latency if none has been observed yet.
+func (l *Latency) RecordResponse(latencyMs float64, outputTokens uint32) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tokens := float64(max(outputTokens, 1))
+	if !l.observed {
+		l.record(0, latencyMs/tokens)
+		return
+	}
+	l.record(max(latencyMs-l.interTokenLatencyMs*tokens, 0), l.interTokenLatencyMs)
+}
+
+// RecordFailure records a failed response of the backend, which raises its expected latency.
+func (l *Latency) RecordFailure() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failureRate += ewmaWeight * (1 - l.failureRate)
+}
+
+// record records a successful response. This must be called with the lock held.
+func (l *Latency) record(timeToFirstTokenMs, interTokenLatencyMs float64) {
+	l.failureRate -= ewmaWeight * l.failureRate
+	if !l.observed {
+		l.observed = true
+		l.timeToFirstTokenMs, l.interTokenLatencyMs = timeToFirstTokenMs, interTokenLatencyMs
+		return
+	}
+	l.timeToFirstTokenMs += ewmaWeight * (timeToFirstTokenMs - l.timeToFirstTokenMs)
+	l.interTokenLatencyMs += ewmaWeight * (interTokenLatencyMs - l.interTokenLatencyMs)
+}
+
+// Expected returns the expected latency in milliseconds of a response of ExpectedOutputTokens, or false if no
+// latency has been observed yet. The latency is divided by the success rate of the backend, i.e., it is the expected
+// latency including the retries of the failed requests. This is nil safe.
+func (l *Latency) Expected() (float64, bool) {
+	if l == nil {
+		return 0, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.observed {
+		return 0, false
+	}
+	latency := l.timeToFirstTokenMs + l.interTokenLatencyMs*ExpectedOutputTokens
+	return latency / (1 - min(l.failureRate, maxFailureRate)), true
+}
+
+// Registry holds the latencies of the backends across the configuration updates. This is safe for concurrent use.
+type Registry struct {
+	mu        sync.Mutex
+	latencies map[string]*Latency
+}
+
+// NewRegistry creates a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{latencies: make(map[string]*Latency)}
+}
+
+// Update replaces the set of the tracked backends with the given backend names, and returns their latencies by the
+// backend name. The latency of a backend that was already tracked is kept.
+func (r *Registry) Update(names []string) map[string]*Latency {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	latencies := make(map[string]*Latency, len(names))
+	for _, name := range names {
+		l, ok := r.latencies[name]
+		if !ok {
+			l = &Latency{}
+		}
+		latencies[name] = l
+	}
+	r.latencies = latencies
+	return latencies
+}
+
+// Candidate is a backend that can be preferred by the selection.
+type Candidate struct {
+	// Latency is the latency of the backend. Nil means no latency has been observed.
+	Latency *Latency
+	// InputTokenPrice is the price of a million input tokens.
+	InputTokenPrice float64
+	// OutputTokenPrice is the price of a million output tokens.
+	OutputTokenPrice float64
+}
+
+// Select returns the index of the preferred candidate for a request with the given number of prompt tokens, or
+// false if none can be preferred. probe is a random number in [0, 1) deciding whether a candidate without any
+// observed latency is probed.
+//
+// The preferred candidate has the lowest weighted sum of its latency and the cost of the request, each divided by
+// the highest one among the candidates. When the latency is weighted, a candidate without any observed latency is
+// selected with ProbeProbability so that its latency gets measured, and is not preferred otherwise. The latency is
+// ignored when none of them has been observed.
+func Select(latencyWeight, costWeight float64, candidates []Candidate, promptTokens uint64, probe float64) (int, bool) {
+	if len(candidates) == 0 {
+		return 0, false
+	}
+	latencies := make([]float64, len(candidates))
+	var maxLatency float64
+	var unobserved []int
+	if latencyWeight > 0 {
+		for i := range candidates {
+			l, ok := candidates[i].Latency.Expected()
+			if !ok {
+				unobserved = append(unobserved, i)
+				continue
+			}
+			latencies[i] = l
+			maxLatency = max(maxLatency, l)
+		}
+		switch {
+		case len(unobserved) == len(candidates):
+			latencyWeight = 0
+			unobserved = nil
+		case len(unobserved) > 0 && probe < ProbeProbability:
+			return unobserved[int(probe/ProbeProbability*float64(len(unobserved)))], true
+		}
+	}
+	if latencyWeight <= 0 && costWeight <= 0 {
+		return 0, false
+	}
+
+	costs := make([]float64, len(candidates))
+	var maxCost float64
+	for i := range candidates {
+		c := &candidates[i]
+		costs[i] = (c.InputTokenPrice*float64(promptTokens) + c.OutputTokenPrice*ExpectedOutputTokens) / 1e6
+		maxCost = max(maxCost, costs[i])
+	}
+
+	best, bestScore := -1, 0.0
+	for i := range candidates {
+		if slices.Contains(unobserved, i) {
+			continue
+		}
+		var score float64
+		if latencyWeight > 0 && maxLatency > 0 {
+			score += latencyWeight * latencies[i] / maxLatency
+		}
+		if costWeight > 0 && maxCost > 0 {
+			score += costWeight * costs[i] / maxCost
+		}
+		if best < 0 || score < bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best, true
+}
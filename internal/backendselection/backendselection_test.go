@@ -0,0 +1,232 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package backendselection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatency(t *testing.T) {
+	var nilLatency *Latency
+	_, ok := nilLatency.Expected()
+	require.False(t, ok)
+
+	l := &Latency{}
+	_, ok = l.Expected()
+	require.False(t, ok)
+
+	// The first sample is taken as is.
+	l.Record(100, 10)
+	expected, ok := l.Expected()
+	require.True(t, ok)
+	require.InDelta(t, 100+10*ExpectedOutputTokens, expected, 1e-9)
+
+	// The following samples are averaged.
+	l.Record(200, 20)
+	expected, ok = l.Expected()
+	require.True(t, ok)
+	require.InDelta(t, 120+12*ExpectedOutputTokens, expected, 1e-9)
+}
+
+func TestLatency_RecordResponse(t *testing.T) {
+	l := &Latency{}
+	// Without any observed inter-token latency, the latency is spread over the output tokens.
+	l.RecordResponse(1000, 100)
+	expected, ok := l.Expected()
+	require.True(t, ok)
+	require.InDelta(t, 10*ExpectedOutputTokens, expected, 1e-9)
+
+	// Then the time to first token is estimated from the inter-token latency.
+	l.RecordResponse(1500, 100)
+	expected, ok = l.Expected()
+	require.True(t, ok)
+	require.InDelta(t, 100+10*ExpectedOutputTokens, expected, 1e-9)
+
+	// The response without any output token is counted as one token.
+	l = &Latency{}
+	l.RecordResponse(50, 0)
+	expected, _ = l.Expected()
+	require.InDelta(t, 50*ExpectedOutputTokens, expected, 1e-9)
+}
+
+func TestLatency_RecordFailure(t *testing.T) {
+	l := &Latency{}
+	// The failures alone do not make the latency observed.
+	l.RecordFailure()
+	_, ok := l.Expected()
+	require.False(t, ok)
+
+	l = &Latency{}
+	l.Record(100, 10)
+	base, _ := l.Expected()
+	l.RecordFailure()
+	expected, _ := l.Expected()
+	require.InDelta(t, base/0.8, expected, 1e-9)
+
+	// The failure rate is capped.
+	for range 100 {
+		l.RecordFailure()
+	}
+	expected, _ = l.Expected()
+	require.InDelta(t, base/(1-maxFailureRate), expected, 1e-6)
+
+	// The successes decay the failure rate.
+	for range 100 {
+		l.Record(100, 10)
+	}
+	expected, _ = l.Expected()
+	require.InDelta(t, base, expected, 1e-6)
+}
+
+func TestRegistry_Update(t *testing.T) {
+	r := NewRegistry()
+	latencies := r.Update([]string{"a", "b"})
+	require.Len(t, latencies, 2)
+	latencies["a"].Record(100, 10)
+
+	updated := r.Update([]string{"a", "c"})
+	require.Len(t, updated, 2)
+	require.Same(t, latencies["a"], updated["a"])
+	_, ok := updated["c"].Expected()
+	require.False(t, ok)
+
+	// The removed backends are dropped.
+	require.NotSame(t, latencies["b"], r.Update([]string{"b"})["b"])
+}
+
+func TestSelect(t *testing.T) {
+	observed := func(ttftMs, itlMs float64) *Latency {
+		l := &Latency{}
+		l.Record(ttftMs, itlMs)
+		return l
+	}
+	fast, slow := observed(100, 10), observed(1000, 50)
+	// failing is as fast as fast but fails most of the time, which makes it slower than slower.
+	failing, slower := observed(100, 10), observed(500, 20)
+	for range 10 {
+		failing.RecordFailure()
+	}
+
+	for _, tc := range []struct {
+		name                      string
+		latencyWeight, costWeight float64
+		candidates                []Candidate
+		promptTokens              uint64
+		probe                     float64
+		exp                       int
+		expOK                     bool
+	}{
+		{name: "no candidates", latencyWeight: 1},
+		{
+			name:          "fastest",
+			latencyWeight: 1,
+			candidates:    []Candidate{{Latency: slow}, {Latency: fast}},
+			exp:           1,
+			expOK:         true,
+		},
+		{
+			name:          "fastest probes unobserved",
+			latencyWeight: 1,
+			candidates:    []Candidate{{Latency: fast}, {}, {Latency: slow}, {}},
+			probe:         ProbeProbability * 0.75,
+			exp:           3,
+			expOK:         true,
+		},
+		{
+			name:          "fastest ignores unobserved without probe",
+			latencyWeight: 1,
+			candidates:    []Candidate{{}, {Latency: slow}, {Latency: fast}},
+			probe:         ProbeProbability,
+			exp:           2,
+			expOK:         true,
+		},
+		{
+			name:          "fastest counts failures",
+			latencyWeight: 1,
+			candidates:    []Candidate{{Latency: failing}, {Latency: slower}},
+			exp:           1,
+			expOK:         true,
+		},
+		{
+			name:          "fastest without any observed latency",
+			latencyWeight: 1,
+			candidates:    []Candidate{{}, {}},
+		},
+		{
+			name:       "cheapest",
+			costWeight: 1,
+			candidates: []Candidate{
+				{Latency: fast, InputTokenPrice: 3, OutputTokenPrice: 15},
+				{Latency: slow, InputTokenPrice: 0.15, OutputTokenPrice: 0.6},
+			},
+			promptTokens: 1000,
+			exp:          1,
+			expOK:        true,
+		},
+		{
+			name:       "cheapest depends on prompt tokens",
+			costWeight: 1,
+			candidates: []Candidate{
+				{InputTokenPrice: 1, OutputTokenPrice: 1},
+				{InputTokenPrice: 0.1, OutputTokenPrice: 10},
+			},
+			promptTokens: 100000,
+			exp:          1,
+			expOK:        true,
+		},
+		{
+			name:          "weighted towards latency",
+			latencyWeight: 3,
+			costWeight:    1,
+			candidates: []Candidate{
+				{Latency: fast, InputTokenPrice: 1, OutputTokenPrice: 2},
+				{Latency: slow, InputTokenPrice: 0.5, OutputTokenPrice: 1},
+			},
+			promptTokens: 1000,
+			exp:          0,
+			expOK:        true,
+		},
+		{
+			name:          "weighted towards cost",
+			latencyWeight: 1,
+			costWeight:    3,
+			candidates: []Candidate{
+				{Latency: fast, InputTokenPrice: 1, OutputTokenPrice: 2},
+				{Latency: slow, InputTokenPrice: 0.5, OutputTokenPrice: 1},
+			},
+			promptTokens: 1000,
+			exp:          1,
+			expOK:        true,
+		},
+		{
+			name:          "weighted without any observed latency uses cost",
+			latencyWeight: 1,
+			costWeight:    1,
+			candidates: []Candidate{
+				{InputTokenPrice: 1, OutputTokenPrice: 2},
+				{InputTokenPrice: 0.5, OutputTokenPrice: 1},
+			},
+			promptTokens: 1000,
+			exp:          1,
+			expOK:        true,
+		},
+		{
+			name:       "tie keeps the first",
+			costWeight: 1,
+			candidates: []Candidate{{}, {}},
+			exp:        0,
+			expOK:      true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, ok := Select(tc.latencyWeight, tc.costWeight, tc.candidates, tc.promptTokens, tc.probe)
+			require.Equal(t, tc.expOK, ok)
+			require.Equal(t, tc.exp, actual)
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+// Package circuitbreaker implements the per-backend circuit breakers driven by the AI specific signals, i.e., the
+// rate limit and overloaded error responses and the time to first token of the streaming responses, which Envoy's
+// outlier detection does not know about.
+package circuitbreaker
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+// State is the state of a circuit breaker.
+type State string
+
+const (
+	// StateClosed is the state where the requests are let through.
+	StateClosed State = "closed"
+	// StateOpen is the state where the requests are rejected.
+	StateOpen State = "open"
+	// StateHalfOpen is the state where a single probe request is let through to decide whether to close again.
+	StateHalfOpen State = "half_open"
+)
+
+// Config is the configuration of a circuit breaker.
+type Config struct {
+	// ConsecutiveFailures is the number of failures in a row after which the circuit breaker opens.
+	ConsecutiveFailures int
+	// TimeToFirstToken is the objective of the time to first token of the streaming responses. Zero means disabled.
+	TimeToFirstToken time.Duration
+	// OpenDuration is the time during which the circuit breaker stays open before letting a probe request through.
+	OpenDuration time.Duration
+}
+
+// Breaker is the circuit breaker of a single backend. This is safe for concurrent use.
+type Breaker struct {
+	name string
+	now  func() time.Time
+
+	mu     sync.Mutex
+	config Config
+	state  State
+	// failures is the number of failures in a row in the closed state.
+	failures int
+	// openedAt is the time when the circuit breaker last opened.
+	openedAt time.Time
+	// probeStartedAt is the time when the probe request was let through in the half-open state. This is zero when
+	// no probe is in flight.
+	probeStartedAt time.Time
+}
+
+// Config returns the configuration of the circuit breaker.
+func (b *Breaker) Config() Config {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.config
+}
+
+// Allow returns true if a request can be sent to the backend. In the half-open state, this returns true only for
+// the probe request, and for another one if the probe does not record its result within the open duration, e.g.
+// because the client went away.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	switch b.state {
+	case StateOpen:
+		if now.Sub(b.openedAt) < b.config.OpenDuration {
+			return false
+		}
+		b.state = StateHalfOpen
+		b.probeStartedAt = now
+		return true
+	case StateHalfOpen:
+		if !b.probeStartedAt.IsZero() && now.Sub(b.probeStartedAt) < b.config.OpenDuration {
+			return false
+		}
+		b.probeStartedAt = now
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess records a response which is neither a rate limit nor an overloaded error and meets the time to
+// first token objective. This closes the circuit breaker in the half-open state.
+func (b *Breaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	if b.state == StateHalfOpen {
+		b.state = StateClosed
+		b.probeStartedAt = time.Time{}
+	}
+}
+
+// RecordFailure records a rate limit or an overloaded error response, or a streaming response exceeding the time
+// to first token objective. This opens the circuit breaker after the configured number of failures in a row, or
+// immediately in the half-open state.
+func (b *Breaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case StateOpen:
+		// The failures of the requests sent before the circuit breaker opened do not extend the open duration.
+	case StateHalfOpen:
+		b.open()
+	default:
+		b.failures++
+		if b.failures >= b.config.ConsecutiveFailures {
+			b.open()
+		}
+	}
+}
+
+func (b *Breaker) open() {
+	b.state = StateOpen
+	b.openedAt = b.now()
+	b.failures = 0
+	b.probeStartedAt = time.Time{}
+}
+
+// Status is the snapshot of the state of a circuit breaker.
+type Status struct {
+	// Backend is the name of the backend.
+	Backend string `json:"backend"`
+	// State is the current state of the circuit breaker.
+	State State `json:"state"`
+	// ConsecutiveFailures is the number of failures in a row in the closed state.
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+	// OpenedAt is the time when the circuit breaker last opened, if any.
+	OpenedAt *time.Time `json:"openedAt,omitempty"`
+}
+
+// Status returns the snapshot of the state of the circuit breaker. An open circuit breaker whose open duration
+// has elapsed is reported as half-open, since the next request is let through as the probe.
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := Status{Backend: b.name, State: b.state, ConsecutiveFailures: b.failures}
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.config.OpenDuration {
+		s.State = StateHalfOpen
+	}
+	if !b.openedAt.IsZero() {
+		openedAt := b.openedAt
+		s.OpenedAt = &openedAt
+	}
+	return s
+}
+
+// Registry holds the circuit breakers of the backends across the configuration updates, so that their state is
+// kept as long as the backends and their configuration are. This is safe for concurrent use.
+type Registry struct {
+	now func() time.Time
+
+	mu       sync.RWMutex
+	breakers map[string]*Breaker
+}
+
+// NewRegistry creates a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{now: time.Now, breakers: make(map[string]*Breaker)}
+}
+
+// Update replaces the set of the circuit breakers with the given configurations by the backend name, and returns
+// the resulting circuit breakers by the backend name. The state of an existing circuit breaker is kept unless its
+// configuration has changed.
+func (r *Registry) Update(configs map[string]Config) map[string]*Breaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	breakers := make(map[string]*Breaker, len(configs))
+	for name, config := range configs {
+		if b, ok := r.breakers[name]; ok && b.Config() == config {
+			breakers[name] = b
+			continue
+		}
+		breakers[name] = &Breaker{name: name, now: r.now, config: config, state: StateClosed}
+	}
+	r.breakers = breakers
+	return breakers
+}
+
+// Statuses returns the snapshots of the states of all the circuit breakers sorted by the backend name.
+func (r *Registry) Statuses() []Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	statuses := make([]Status, 0, len(r.breakers))
+	for _, b := range r.breakers {
+		statuses = append(statuses, b.Status())
+	}
+	slices.SortFunc(statuses, func(a, b Status) int { return strings.Compare(a.Backend, b.Backend) })
+	return statuses
+}
+
+// ServeHTTP implements [http.Handler] to serve the states of the circuit breakers as JSON on the admin server.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(r.Statuses())
+}
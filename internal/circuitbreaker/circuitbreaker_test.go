@@ -0,0 +1,113 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package circuitbreaker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRegistry(now *time.Time) *Registry {
+	r := NewRegistry()
+	r.now = func() time.Time { return *now }
+	return r
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	r := newTestRegistry(&now)
+	b := r.Update(map[string]Config{"backend": {ConsecutiveFailures: 2, OpenDuration: 10 * time.Second}})["backend"]
+	require.NotNil(t, b)
+
+	// A success resets the consecutive failures.
+	require.True(t, b.Allow())
+	b.RecordFailure()
+	b.RecordSuccess()
+	b.RecordFailure()
+	require.Equal(t, StateClosed, b.Status().State)
+	require.Equal(t, 1, b.Status().ConsecutiveFailures)
+
+	// Opens after the consecutive failures.
+	b.RecordFailure()
+	require.Equal(t, StateOpen, b.Status().State)
+	require.False(t, b.Allow())
+	// The failures of the requests in flight do not extend the open duration.
+	now = now.Add(5 * time.Second)
+	b.RecordFailure()
+	require.False(t, b.Allow())
+
+	// Lets a single probe through after the open duration.
+	now = now.Add(5 * time.Second)
+	require.Equal(t, StateHalfOpen, b.Status().State)
+	require.True(t, b.Allow())
+	require.False(t, b.Allow())
+	// Opens again when the probe fails.
+	b.RecordFailure()
+	require.Equal(t, StateOpen, b.Status().State)
+	require.Equal(t, now, *b.Status().OpenedAt)
+	require.False(t, b.Allow())
+
+	// Lets another probe through when the previous one does not record its result within the open duration.
+	now = now.Add(10 * time.Second)
+	require.True(t, b.Allow())
+	now = now.Add(9 * time.Second)
+	require.False(t, b.Allow())
+	now = now.Add(time.Second)
+	require.True(t, b.Allow())
+
+	// Closes when the probe succeeds.
+	b.RecordSuccess()
+	require.Equal(t, StateClosed, b.Status().State)
+	require.True(t, b.Allow())
+	require.True(t, b.Allow())
+}
+
+func TestRegistry_Update(t *testing.T) {
+	now := time.Unix(1000, 0)
+	r := newTestRegistry(&now)
+	config := Config{ConsecutiveFailures: 1, OpenDuration: time.Minute}
+	breakers := r.Update(map[string]Config{"a": config, "b": config})
+	require.Len(t, breakers, 2)
+	breakers["a"].RecordFailure()
+	breakers["b"].RecordFailure()
+
+	// The state is kept for the unchanged configuration, and reset for the changed one.
+	updated := r.Update(map[string]Config{"a": config, "b": {ConsecutiveFailures: 2, OpenDuration: time.Minute}})
+	require.Same(t, breakers["a"], updated["a"])
+	require.NotSame(t, breakers["b"], updated["b"])
+	require.Equal(t, []Status{
+		{Backend: "a", State: StateOpen, OpenedAt: &now},
+		{Backend: "b", State: StateClosed},
+	}, r.Statuses())
+
+	// The removed backends are dropped.
+	require.Empty(t, r.Update(nil))
+	require.Empty(t, r.Statuses())
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := newTestRegistry(&now)
+	breakers := r.Update(map[string]Config{
+		"a": {ConsecutiveFailures: 1, OpenDuration: time.Minute},
+		"b": {ConsecutiveFailures: 3, OpenDuration: time.Minute},
+	})
+	breakers["a"].RecordFailure()
+	breakers["b"].RecordFailure()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/circuit_breakers", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, `[
+  {"backend":"a","state":"open","consecutiveFailures":0,"openedAt":"2025-01-02T03:04:05Z"},
+  {"backend":"b","state":"closed","consecutiveFailures":1}
+]`, rec.Body.String())
+}
@@ -8,6 +8,8 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 
 	egv1a1 "github.com/envoyproxy/gateway/api/v1alpha1"
@@ -314,11 +316,25 @@ func (c *AIGatewayRouteController) newHTTPRoute(ctx context.Context, dst *gwapiv
 		}
 		var matches []gwapiv1.HTTPRouteMatch
 		for j := range rule.Matches {
+			headers := rule.Matches[j].Headers
+			if body := rule.Matches[j].Body; body != nil {
+				headers = append(slices.Clone(headers), bodyMatchHeader(body.CEL))
+			}
 			matches = append(matches, gwapiv1.HTTPRouteMatch{
-				Headers: rule.Matches[j].Headers,
+				Headers: headers,
 				Path:    &gwapiv1.HTTPPathMatch{Value: &c.rootPrefix},
 			})
 		}
+		if cel := ruleContextWindowCEL(rule); cel != "" {
+			// Restrict the rule to the requests fitting in the context window of its backends, so that
+			// larger ones fall through to the next matching rule.
+			if len(matches) == 0 {
+				matches = append(matches, gwapiv1.HTTPRouteMatch{Path: &gwapiv1.HTTPPathMatch{Value: &c.rootPrefix}})
+			}
+			for j := range matches {
+				matches[j].Headers = append(slices.Clone(matches[j].Headers), bodyMatchHeader(cel))
+			}
+		}
 		rules = append(rules, gwapiv1.HTTPRouteRule{
 			Name:        rule.Name,
 			BackendRefs: backendRefs,
@@ -340,6 +356,18 @@ func (c *AIGatewayRouteController) newHTTPRoute(ctx context.Context, dst *gwapiv
 			},
 		}},
 	})
+	// The rules preferring each backend are appended after the others so that the indexes of the rules generated
+	// above match the ones of the AIGatewayRoute rules.
+	rules = append(rules, backendSelectionRules(aiGatewayRoute, rules, c.rootPrefix)...)
+	shadows, err := c.shadowRules(ctx, aiGatewayRoute, rewriteFilters)
+	if err != nil {
+		return err
+	}
+	rules = append(rules, shadows...)
+	if len(rules) > maxHTTPRouteRules {
+		return fmt.Errorf("the AIGatewayRoute generates %d HTTPRoute rules including the ones of the backend selections, shadows, stream failovers and semantic caches, "+
+			"which exceeds the limit of %d; split the rules across multiple AIGatewayRoute resources", len(rules), maxHTTPRouteRules)
+	}
 
 	dst.Spec.Rules = rules
 
@@ -371,6 +399,155 @@ func (c *AIGatewayRouteController) newHTTPRoute(ctx context.Context, dst *gwapiv
 	return nil
 }
 
+// maxHTTPRouteRules is the maximum number of the rules of an HTTPRoute in the Gateway API.
+const maxHTTPRouteRules = 16
+
+// backendSelectionRules returns the HTTPRoute rules of the backend selections of the AIGatewayRoute rules, where
+// rules are the HTTPRoute rules generated from the AIGatewayRoute rules at the same indexes.
+//
+// For each AIGatewayRoute rule with a backend selection, one HTTPRoute rule per backend ref is generated in order,
+// which matches the requests for which the router filter has set the header of the selection to the index of the
+// backend ref. Since it has one more header match than the rule, it takes precedence over the rule. The extension
+// server maps these back to the AIGatewayRoute rule, and gives the preferred backend ref the highest priority in
+// the generated clusters.
+func backendSelectionRules(aiGatewayRoute *aigv1b1.AIGatewayRoute, rules []gwapiv1.HTTPRouteRule, rootPrefix string) []gwapiv1.HTTPRouteRule {
+	var ret []gwapiv1.HTTPRouteRule
+	for i := range aiGatewayRoute.Spec.Rules {
+		rule := &aiGatewayRoute.Spec.Rules[i]
+		if rule.BackendSelection == nil || len(rule.BackendRefs) == 0 || rule.BackendRefs[0].IsInferencePool() {
+			continue
+		}
+		matches := rules[i].Matches
+		if len(matches) == 0 {
+			matches = []gwapiv1.HTTPRouteMatch{{Path: &gwapiv1.HTTPPathMatch{Value: &rootPrefix}}}
+		}
+		headerName := gwapiv1.HTTPHeaderName(internalapi.BackendSelectionHeaderName(aiGatewayRoute.Namespace, aiGatewayRoute.Name, i))
+		for j := range rule.BackendRefs {
+			preferredMatches := make([]gwapiv1.HTTPRouteMatch, len(matches))
+			for k := range matches {
+				preferredMatches[k] = matches[k]
+				preferredMatches[k].Headers = append(slices.Clone(matches[k].Headers), gwapiv1.HTTPHeaderMatch{
+					Type:  ptr.To(gwapiv1.HeaderMatchExact),
+					Name:  headerName,
+					Value: strconv.Itoa(j),
+				})
+			}
+			ret = append(ret, gwapiv1.HTTPRouteRule{
+				BackendRefs: rules[i].BackendRefs,
+				Matches:     preferredMatches,
+				Filters:     rules[i].Filters,
+				Timeouts:    rules[i].Timeouts,
+			})
+		}
+	}
+	return ret
+}
+
+// shadowRules returns the HTTPRoute rules of the shadows, the stream failovers and the embeddings backends of the
+// semantic response caches of the AIGatewayRoute rules, which are appended after the ones of the backend selections.
+//
+// One HTTPRoute rule per such backend is generated, which matches the requests sent by the AI Gateway filter with the
+// shadow backend header set to the name of the backend. The extension server moves the routes of these rules from the
+// Gateway listeners to the internal shadow listener, so that they cannot be matched by clients.
+func (c *AIGatewayRouteController) shadowRules(ctx context.Context, aiGatewayRoute *aigv1b1.AIGatewayRoute, filters []gwapiv1.HTTPRouteFilter) ([]gwapiv1.HTTPRouteRule, error) {
+	var ret []gwapiv1.HTTPRouteRule
+	for i := range aiGatewayRoute.Spec.Rules {
+		rule := &aiGatewayRoute.Spec.Rules[i]
+		for j := range rule.Shadows {
+			shadow := &rule.Shadows[j]
+			backend, err := c.backend(ctx, aiGatewayRoute.Namespace, shadow.Name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get AIServiceBackend %s.%s of shadow: %w", shadow.Name, aiGatewayRoute.Namespace, err)
+			}
+			name := internalapi.PerRouteRuleShadowBackendName(aiGatewayRoute.Namespace, shadow.Name, aiGatewayRoute.Name, i, j)
+			ret = append(ret, shadowRule(backend, name, rule, filters))
+		}
+		if f := rule.StreamFailover; f != nil {
+			backend, err := c.backend(ctx, aiGatewayRoute.Namespace, f.Name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get AIServiceBackend %s.%s of stream failover: %w", f.Name, aiGatewayRoute.Namespace, err)
+			}
+			name := internalapi.PerRouteRuleStreamFailoverBackendName(aiGatewayRoute.Namespace, f.Name, aiGatewayRoute.Name, i)
+			ret = append(ret, shadowRule(backend, name, rule, filters))
+		}
+		if rc := rule.ResponseCache; rc != nil && rc.Semantic != nil {
+			sc := rc.Semantic
+			backend, err := c.backend(ctx, aiGatewayRoute.Namespace, sc.EmbeddingsBackend)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get AIServiceBackend %s.%s of semantic cache: %w", sc.EmbeddingsBackend, aiGatewayRoute.Namespace, err)
+			}
+			name := internalapi.PerRouteRuleSemanticCacheBackendName(aiGatewayRoute.Namespace, sc.EmbeddingsBackend, aiGatewayRoute.Name, i)
+			ret = append(ret, shadowRule(backend, name, rule, filters))
+		}
+	}
+	return ret, nil
+}
+
+// shadowRule returns the HTTPRoute rule matching the requests sent to the given backend through the shadow listener.
+func shadowRule(backend *aigv1b1.AIServiceBackend, name string, rule *aigv1b1.AIGatewayRouteRule, filters []gwapiv1.HTTPRouteFilter) gwapiv1.HTTPRouteRule {
+	return gwapiv1.HTTPRouteRule{
+		BackendRefs: []gwapiv1.HTTPBackendRef{{BackendRef: gwapiv1.BackendRef{BackendObjectReference: backend.Spec.BackendRef}}},
+		Matches: []gwapiv1.HTTPRouteMatch{{
+			Headers: []gwapiv1.HTTPHeaderMatch{{
+				Type:  ptr.To(gwapiv1.HeaderMatchExact),
+				Name:  internalapi.ShadowBackendHeader,
+				Value: name,
+			}},
+			// The requests are sent with the path of the backend, so they are matched regardless of the root prefix.
+			Path: &gwapiv1.HTTPPathMatch{Value: ptr.To("/")},
+		}},
+		Filters:  filters,
+		Timeouts: rule.GetTimeoutsOrDefault(),
+	}
+}
+
+// bodyMatchHeader returns the header match of the given request body match CEL expression. The AI Gateway filter
+// sets the result of the expression to this header after parsing the body.
+func bodyMatchHeader(cel string) gwapiv1.HTTPHeaderMatch {
+	return gwapiv1.HTTPHeaderMatch{
+		Type:  ptr.To(gwapiv1.HeaderMatchExact),
+		Name:  gwapiv1.HTTPHeaderName(internalapi.BodyMatchHeaderName(cel)),
+		Value: "true",
+	}
+}
+
+// ruleContextWindow returns the largest context window of the backends of the rule, or zero when any of them
+// has no context window since the rule can then serve requests of any size.
+func ruleContextWindow(rule *aigv1b1.AIGatewayRouteRule) (window int32) {
+	for i := range rule.BackendRefs {
+		cw := rule.BackendRefs[i].ContextWindow
+		if cw == nil {
+			return 0
+		}
+		window = max(window, *cw)
+	}
+	return
+}
+
+// widenContextWindow returns the largest context window of the rules matching a model given the one so far and the
+// one of another rule matching it, where zero means unbounded and -1 means no rule.
+func widenContextWindow(current, window int32) int32 {
+	switch {
+	case window < 0:
+		return current
+	case current < 0:
+		return window
+	case current == 0 || window == 0:
+		return 0
+	}
+	return max(current, window)
+}
+
+// ruleContextWindowCEL returns the request body match CEL expression matching the requests fitting in the
+// context window of the rule, or an empty string when the rule has no context window.
+func ruleContextWindowCEL(rule *aigv1b1.AIGatewayRouteRule) string {
+	window := ruleContextWindow(rule)
+	if window == 0 {
+		return ""
+	}
+	return fmt.Sprintf("estimated_prompt_tokens <= %du", window)
+}
+
 // syncGateways synchronizes the gateways referenced by the AIGatewayRoute by sending events to the gateway controller.
 func (c *AIGatewayRouteController) syncGateways(ctx context.Context, aiGatewayRoute *aigv1b1.AIGatewayRoute) error {
 	for _, p := range aiGatewayRoute.Spec.ParentRefs {
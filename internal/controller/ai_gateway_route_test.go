@@ -8,6 +8,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"testing"
 
 	egv1a1 "github.com/envoyproxy/gateway/api/v1alpha1"
@@ -28,6 +29,7 @@ import (
 	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 
 	aigv1b1 "github.com/envoyproxy/ai-gateway/api/v1beta1"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
 	internaltesting "github.com/envoyproxy/ai-gateway/internal/testing"
 )
 
@@ -105,6 +107,45 @@ func TestAIGatewayRouteController_Reconcile_SyncError(t *testing.T) {
 	require.Equal(t, aigv1b1.ConditionTypeNotAccepted, updatedRoute.Status.Conditions[0].Type)
 }
 
+func TestAIGatewayRouteController_Reconcile_TooManyHTTPRouteRules(t *testing.T) {
+	fakeClient := requireNewFakeClientWithIndexes(t)
+	eventCh := internaltesting.NewControllerEventChan[*gwapiv1.Gateway]()
+	c := NewAIGatewayRouteController(fakeClient, fake2.NewClientset(), ctrl.Log, eventCh.Ch, "/v1")
+
+	var backendRefs []aigv1b1.AIGatewayRouteRuleBackendRef
+	for _, name := range []string{"backend-a", "backend-b", "backend-c"} {
+		require.NoError(t, fakeClient.Create(t.Context(), &aigv1b1.AIServiceBackend{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: aigv1b1.AIServiceBackendSpec{
+				BackendRef: gwapiv1.BackendObjectReference{Name: gwapiv1.ObjectName(name)},
+			},
+		}))
+		backendRefs = append(backendRefs, aigv1b1.AIGatewayRouteRuleBackendRef{Name: name})
+	}
+	// The 13 rules, the route-not-found rule and the 3 rules of the backend selection exceed the limit.
+	rules := []aigv1b1.AIGatewayRouteRule{{
+		BackendRefs:      backendRefs,
+		BackendSelection: &aigv1b1.AIGatewayRouteRuleBackendSelection{Objective: aigv1b1.BackendSelectionObjectiveFastest},
+	}}
+	for range 12 {
+		rules = append(rules, aigv1b1.AIGatewayRouteRule{BackendRefs: backendRefs[:1]})
+	}
+	route := &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "toomanyrules", Namespace: "default"},
+		Spec:       aigv1b1.AIGatewayRouteSpec{Rules: rules},
+	}
+	require.NoError(t, fakeClient.Create(t.Context(), route))
+
+	_, err := c.Reconcile(t.Context(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "toomanyrules"}})
+	require.ErrorContains(t, err, "generates 17 HTTPRoute rules")
+
+	var updatedRoute aigv1b1.AIGatewayRoute
+	require.NoError(t, fakeClient.Get(t.Context(), types.NamespacedName{Namespace: "default", Name: "toomanyrules"}, &updatedRoute))
+	require.Len(t, updatedRoute.Status.Conditions, 1)
+	require.Equal(t, aigv1b1.ConditionTypeNotAccepted, updatedRoute.Status.Conditions[0].Type)
+	require.Contains(t, updatedRoute.Status.Conditions[0].Message, "exceeds the limit of 16")
+}
+
 func requireNewFakeClientWithIndexes(t *testing.T) client.Client {
 	builder := fake.NewClientBuilder().WithScheme(Scheme).
 		WithStatusSubresource(&aigv1b1.AIGatewayRoute{}).
@@ -1122,3 +1163,309 @@ func TestAIGatewayRouteController_SameNamespaceBackend_NoReferenceGrantNeeded(t
 	require.Len(t, updatedRoute.Status.Conditions, 1)
 	require.Equal(t, aigv1b1.ConditionTypeAccepted, updatedRoute.Status.Conditions[0].Type)
 }
+
+func Test_newHTTPRoute_BodyMatch(t *testing.T) {
+	c := requireNewFakeClientWithIndexes(t)
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIServiceBackend{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backend", Namespace: "test-ns"},
+		Spec: aigv1b1.AIServiceBackendSpec{
+			BackendRef: gwapiv1.BackendObjectReference{Name: "some-backend", Namespace: ptr.To(gwapiv1.Namespace("test-ns"))},
+		},
+	}))
+
+	modelHeaders := []gwapiv1.HTTPHeaderMatch{{Name: internalapi.ModelNameHeaderKeyDefault, Value: "auto"}}
+	aiGatewayRoute := &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-route", Namespace: "test-ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				{
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "test-backend"}},
+					Matches: []aigv1b1.AIGatewayRouteRuleMatch{
+						{Headers: modelHeaders, Body: &aigv1b1.AIGatewayRouteRuleBodyMatch{CEL: "has_images"}},
+						{Body: &aigv1b1.AIGatewayRouteRuleBodyMatch{CEL: "estimated_prompt_tokens > 32000u"}},
+					},
+				},
+			},
+		},
+	}
+
+	controller := &AIGatewayRouteController{client: c}
+	httpRoute := &gwapiv1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Name: "test-route", Namespace: "test-ns"}}
+	require.NoError(t, controller.newHTTPRoute(t.Context(), httpRoute, aiGatewayRoute))
+
+	matches := httpRoute.Spec.Rules[0].Matches
+	require.Len(t, matches, 2)
+	require.Equal(t, []gwapiv1.HTTPHeaderMatch{
+		{Name: internalapi.ModelNameHeaderKeyDefault, Value: "auto"},
+		{Type: ptr.To(gwapiv1.HeaderMatchExact), Name: gwapiv1.HTTPHeaderName(internalapi.BodyMatchHeaderName("has_images")), Value: "true"},
+	}, matches[0].Headers)
+	require.Equal(t, []gwapiv1.HTTPHeaderMatch{
+		{Type: ptr.To(gwapiv1.HeaderMatchExact), Name: gwapiv1.HTTPHeaderName(internalapi.BodyMatchHeaderName("estimated_prompt_tokens > 32000u")), Value: "true"},
+	}, matches[1].Headers)
+	// The headers of the AIGatewayRoute must not be modified.
+	require.Len(t, aiGatewayRoute.Spec.Rules[0].Matches[0].Headers, 1)
+}
+
+func Test_newHTTPRoute_ContextWindow(t *testing.T) {
+	c := requireNewFakeClientWithIndexes(t)
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIServiceBackend{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backend", Namespace: "test-ns"},
+		Spec: aigv1b1.AIServiceBackendSpec{
+			BackendRef: gwapiv1.BackendObjectReference{Name: "some-backend", Namespace: ptr.To(gwapiv1.Namespace("test-ns"))},
+		},
+	}))
+
+	modelHeaders := []gwapiv1.HTTPHeaderMatch{{Name: internalapi.ModelNameHeaderKeyDefault, Value: "auto"}}
+	aiGatewayRoute := &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-route", Namespace: "test-ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				{
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{
+						{Name: "test-backend", ContextWindow: ptr.To[int32](8000)},
+						{Name: "test-backend", ContextWindow: ptr.To[int32](32000)},
+					},
+					Matches: []aigv1b1.AIGatewayRouteRuleMatch{{Headers: modelHeaders}},
+				},
+				{
+					// Not all the backends have a context window.
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{
+						{Name: "test-backend", ContextWindow: ptr.To[int32](128000)},
+						{Name: "test-backend"},
+					},
+					Matches: []aigv1b1.AIGatewayRouteRuleMatch{{Headers: modelHeaders}},
+				},
+				{
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "test-backend", ContextWindow: ptr.To[int32](1000000)}},
+				},
+			},
+		},
+	}
+
+	controller := &AIGatewayRouteController{client: c}
+	httpRoute := &gwapiv1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Name: "test-route", Namespace: "test-ns"}}
+	require.NoError(t, controller.newHTTPRoute(t.Context(), httpRoute, aiGatewayRoute))
+
+	rules := httpRoute.Spec.Rules
+	require.Len(t, rules, 4) // Including the route-not-found rule.
+	require.Equal(t, []gwapiv1.HTTPHeaderMatch{
+		{Name: internalapi.ModelNameHeaderKeyDefault, Value: "auto"},
+		{Type: ptr.To(gwapiv1.HeaderMatchExact), Name: gwapiv1.HTTPHeaderName(internalapi.BodyMatchHeaderName("estimated_prompt_tokens <= 32000u")), Value: "true"},
+	}, rules[0].Matches[0].Headers)
+	require.Equal(t, modelHeaders, rules[1].Matches[0].Headers)
+	require.Len(t, rules[2].Matches, 1)
+	require.Equal(t, []gwapiv1.HTTPHeaderMatch{
+		{Type: ptr.To(gwapiv1.HeaderMatchExact), Name: gwapiv1.HTTPHeaderName(internalapi.BodyMatchHeaderName("estimated_prompt_tokens <= 1000000u")), Value: "true"},
+	}, rules[2].Matches[0].Headers)
+	// The headers of the AIGatewayRoute must not be modified.
+	require.Len(t, aiGatewayRoute.Spec.Rules[0].Matches[0].Headers, 1)
+}
+
+func Test_newHTTPRoute_BackendSelection(t *testing.T) {
+	c := requireNewFakeClientWithIndexes(t)
+	for _, name := range []string{"backend-a", "backend-b"} {
+		require.NoError(t, c.Create(t.Context(), &aigv1b1.AIServiceBackend{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec: aigv1b1.AIServiceBackendSpec{
+				BackendRef: gwapiv1.BackendObjectReference{Name: gwapiv1.ObjectName(name), Namespace: ptr.To(gwapiv1.Namespace("test-ns"))},
+			},
+		}))
+	}
+
+	modelHeaders := []gwapiv1.HTTPHeaderMatch{{Name: internalapi.ModelNameHeaderKeyDefault, Value: "auto"}}
+	backendRefs := []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "backend-a"}, {Name: "backend-b"}}
+	aiGatewayRoute := &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-route", Namespace: "test-ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				{BackendRefs: backendRefs, Matches: []aigv1b1.AIGatewayRouteRuleMatch{{Headers: modelHeaders}}},
+				{
+					BackendRefs:      backendRefs,
+					Matches:          []aigv1b1.AIGatewayRouteRuleMatch{{Headers: modelHeaders}},
+					BackendSelection: &aigv1b1.AIGatewayRouteRuleBackendSelection{Objective: aigv1b1.BackendSelectionObjectiveFastest},
+				},
+				{
+					BackendRefs:      backendRefs[:1],
+					BackendSelection: &aigv1b1.AIGatewayRouteRuleBackendSelection{Objective: aigv1b1.BackendSelectionObjectiveFastest},
+				},
+			},
+		},
+	}
+
+	controller := &AIGatewayRouteController{client: c, rootPrefix: "/"}
+	httpRoute := &gwapiv1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Name: "test-route", Namespace: "test-ns"}}
+	require.NoError(t, controller.newHTTPRoute(t.Context(), httpRoute, aiGatewayRoute))
+
+	rules := httpRoute.Spec.Rules
+	// The rules of the AIGatewayRoute and the route-not-found rule are followed by one rule per backend ref of the
+	// rules with a backend selection.
+	require.Len(t, rules, 7)
+	require.Equal(t, "route-not-found", string(*rules[3].Name))
+	header1 := gwapiv1.HTTPHeaderName(internalapi.BackendSelectionHeaderName("test-ns", "test-route", 1))
+	for i, ref := range []int{0, 1} {
+		rule := rules[4+i]
+		require.Nil(t, rule.Name)
+		require.Equal(t, rules[1].BackendRefs, rule.BackendRefs)
+		require.Equal(t, rules[1].Timeouts, rule.Timeouts)
+		require.Equal(t, []gwapiv1.HTTPHeaderMatch{
+			{Name: internalapi.ModelNameHeaderKeyDefault, Value: "auto"},
+			{Type: ptr.To(gwapiv1.HeaderMatchExact), Name: header1, Value: strconv.Itoa(ref)},
+		}, rule.Matches[0].Headers)
+	}
+	// The rule without matches matches the root prefix.
+	require.Equal(t, "/", *rules[6].Matches[0].Path.Value)
+	require.Equal(t, []gwapiv1.HTTPHeaderMatch{
+		{Type: ptr.To(gwapiv1.HeaderMatchExact), Name: gwapiv1.HTTPHeaderName(internalapi.BackendSelectionHeaderName("test-ns", "test-route", 2)), Value: "0"},
+	}, rules[6].Matches[0].Headers)
+	// The headers of the AIGatewayRoute must not be modified.
+	require.Len(t, aiGatewayRoute.Spec.Rules[1].Matches[0].Headers, 1)
+	require.Len(t, rules[1].Matches[0].Headers, 1)
+
+	t.Run("too many rules", func(t *testing.T) {
+		for range 12 {
+			aiGatewayRoute.Spec.Rules = append(aiGatewayRoute.Spec.Rules, aigv1b1.AIGatewayRouteRule{BackendRefs: backendRefs[:1]})
+		}
+		err := controller.newHTTPRoute(t.Context(), httpRoute, aiGatewayRoute)
+		require.ErrorContains(t, err, "generates 19 HTTPRoute rules")
+	})
+}
+
+func Test_newHTTPRoute_Shadows(t *testing.T) {
+	c := requireNewFakeClientWithIndexes(t)
+	for _, name := range []string{"primary", "shadow"} {
+		require.NoError(t, c.Create(t.Context(), &aigv1b1.AIServiceBackend{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec: aigv1b1.AIServiceBackendSpec{
+				BackendRef: gwapiv1.BackendObjectReference{Name: gwapiv1.ObjectName(name + "-backend")},
+			},
+		}))
+	}
+	aiGatewayRoute := &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-route", Namespace: "test-ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{{
+				BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "primary"}},
+				Shadows:     []aigv1b1.AIGatewayRouteRuleShadow{{Name: "shadow"}},
+			}},
+		},
+	}
+
+	controller := &AIGatewayRouteController{client: c, rootPrefix: "/ai"}
+	httpRoute := &gwapiv1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Name: "test-route", Namespace: "test-ns"}}
+	require.NoError(t, controller.newHTTPRoute(t.Context(), httpRoute, aiGatewayRoute))
+
+	rules := httpRoute.Spec.Rules
+	// The rule of the AIGatewayRoute and the route-not-found rule are followed by the rule of the shadow.
+	require.Len(t, rules, 3)
+	shadow := rules[2]
+	require.Nil(t, shadow.Name)
+	require.Equal(t, []gwapiv1.HTTPBackendRef{{BackendRef: gwapiv1.BackendRef{
+		BackendObjectReference: gwapiv1.BackendObjectReference{Name: "shadow-backend"},
+	}}}, shadow.BackendRefs)
+	require.Equal(t, []gwapiv1.HTTPRouteMatch{{
+		Headers: []gwapiv1.HTTPHeaderMatch{{
+			Type:  ptr.To(gwapiv1.HeaderMatchExact),
+			Name:  internalapi.ShadowBackendHeader,
+			Value: "test-ns/shadow/route/test-route/rule/0/shadow/0",
+		}},
+		Path: &gwapiv1.HTTPPathMatch{Value: ptr.To("/")},
+	}}, shadow.Matches)
+	require.Equal(t, rules[0].Filters, shadow.Filters)
+	require.Equal(t, rules[0].Timeouts, shadow.Timeouts)
+
+	t.Run("missing shadow backend", func(t *testing.T) {
+		aiGatewayRoute.Spec.Rules[0].Shadows = []aigv1b1.AIGatewayRouteRuleShadow{{Name: "nonexistent"}}
+		err := controller.newHTTPRoute(t.Context(), httpRoute, aiGatewayRoute)
+		require.ErrorContains(t, err, "failed to get AIServiceBackend nonexistent.test-ns of shadow")
+	})
+}
+
+func Test_newHTTPRoute_StreamFailover(t *testing.T) {
+	c := requireNewFakeClientWithIndexes(t)
+	for _, name := range []string{"primary", "failover"} {
+		require.NoError(t, c.Create(t.Context(), &aigv1b1.AIServiceBackend{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec: aigv1b1.AIServiceBackendSpec{
+				BackendRef: gwapiv1.BackendObjectReference{Name: gwapiv1.ObjectName(name + "-backend")},
+			},
+		}))
+	}
+	aiGatewayRoute := &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-route", Namespace: "test-ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{{
+				BackendRefs:    []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "primary"}},
+				StreamFailover: &aigv1b1.AIGatewayRouteRuleStreamFailover{Name: "failover"},
+			}},
+		},
+	}
+
+	controller := &AIGatewayRouteController{client: c, rootPrefix: "/ai"}
+	httpRoute := &gwapiv1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Name: "test-route", Namespace: "test-ns"}}
+	require.NoError(t, controller.newHTTPRoute(t.Context(), httpRoute, aiGatewayRoute))
+
+	rules := httpRoute.Spec.Rules
+	// The stream failover is routed through the shadow listener like the shadows.
+	require.Len(t, rules, 3)
+	failover := rules[2]
+	require.Equal(t, []gwapiv1.HTTPBackendRef{{BackendRef: gwapiv1.BackendRef{
+		BackendObjectReference: gwapiv1.BackendObjectReference{Name: "failover-backend"},
+	}}}, failover.BackendRefs)
+	require.Equal(t, []gwapiv1.HTTPHeaderMatch{{
+		Type:  ptr.To(gwapiv1.HeaderMatchExact),
+		Name:  internalapi.ShadowBackendHeader,
+		Value: "test-ns/failover/route/test-route/rule/0/stream-failover",
+	}}, failover.Matches[0].Headers)
+
+	t.Run("missing stream failover backend", func(t *testing.T) {
+		aiGatewayRoute.Spec.Rules[0].StreamFailover.Name = "nonexistent"
+		err := controller.newHTTPRoute(t.Context(), httpRoute, aiGatewayRoute)
+		require.ErrorContains(t, err, "failed to get AIServiceBackend nonexistent.test-ns of stream failover")
+	})
+}
+
+func Test_newHTTPRoute_SemanticCache(t *testing.T) {
+	c := requireNewFakeClientWithIndexes(t)
+	for _, name := range []string{"primary", "embeddings"} {
+		require.NoError(t, c.Create(t.Context(), &aigv1b1.AIServiceBackend{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec: aigv1b1.AIServiceBackendSpec{
+				BackendRef: gwapiv1.BackendObjectReference{Name: gwapiv1.ObjectName(name + "-backend")},
+			},
+		}))
+	}
+	aiGatewayRoute := &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-route", Namespace: "test-ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{{
+				BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "primary"}},
+				ResponseCache: &aigv1b1.AIGatewayRouteRuleResponseCache{
+					Semantic: &aigv1b1.AIGatewayRouteRuleSemanticCache{EmbeddingsBackend: "embeddings", EmbeddingsModel: "text-embedding-3-small"},
+				},
+			}},
+		},
+	}
+
+	controller := &AIGatewayRouteController{client: c, rootPrefix: "/ai"}
+	httpRoute := &gwapiv1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Name: "test-route", Namespace: "test-ns"}}
+	require.NoError(t, controller.newHTTPRoute(t.Context(), httpRoute, aiGatewayRoute))
+
+	rules := httpRoute.Spec.Rules
+	// The embeddings requests are routed through the shadow listener like the shadows.
+	require.Len(t, rules, 3)
+	embeddings := rules[2]
+	require.Equal(t, []gwapiv1.HTTPBackendRef{{BackendRef: gwapiv1.BackendRef{
+		BackendObjectReference: gwapiv1.BackendObjectReference{Name: "embeddings-backend"},
+	}}}, embeddings.BackendRefs)
+	require.Equal(t, []gwapiv1.HTTPHeaderMatch{{
+		Type:  ptr.To(gwapiv1.HeaderMatchExact),
+		Name:  internalapi.ShadowBackendHeader,
+		Value: "test-ns/embeddings/route/test-route/rule/0/semantic-cache",
+	}}, embeddings.Matches[0].Headers)
+
+	t.Run("missing embeddings backend", func(t *testing.T) {
+		aiGatewayRoute.Spec.Rules[0].ResponseCache.Semantic.EmbeddingsBackend = "nonexistent"
+		err := controller.newHTTPRoute(t.Context(), httpRoute, aiGatewayRoute)
+		require.ErrorContains(t, err, "failed to get AIServiceBackend nonexistent.test-ns of semantic cache")
+	})
+}
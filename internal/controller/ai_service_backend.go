@@ -8,6 +8,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/go-logr/logr"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -80,6 +81,12 @@ func (c *AIBackendController) syncAIServiceBackend(ctx context.Context, aiBacken
 		return fmt.Errorf("multiple BackendSecurityPolicies found for AIServiceBackend %s: %v",
 			aiBackend.Name, names)
 	}
+	if len(backendSecurityPolicyList.Items) == 1 {
+		bsp := &backendSecurityPolicyList.Items[0]
+		if err := validateBackendSecurityPolicyType(aiBackend.Spec.APISchema.Name, bsp.Spec.Type); err != nil {
+			return fmt.Errorf("invalid BackendSecurityPolicy %s for AIServiceBackend %s: %w", bsp.Name, aiBackend.Name, err)
+		}
+	}
 
 	// Propagate the bsp events all the way up to relevant Gateways regardless of being deleted or not.
 	_ = handleFinalizer(ctx, c.client, c.logger, aiBackend, nil)
@@ -100,6 +107,25 @@ func (c *AIBackendController) syncAIServiceBackend(ctx context.Context, aiBacken
 	return nil
 }
 
+// azureAIInferenceBackendSecurityPolicyTypes are the types of the BackendSecurityPolicy supported by the
+// AzureAIInference schema. The Azure AI Model Inference API accepts the key of the resource in the api-key header
+// (AzureAPIKey) or as the bearer token (APIKey), and the Microsoft Entra ID access tokens (AzureCredentials).
+var azureAIInferenceBackendSecurityPolicyTypes = []aigv1b1.BackendSecurityPolicyType{
+	aigv1b1.BackendSecurityPolicyTypeAzureAPIKey,
+	aigv1b1.BackendSecurityPolicyTypeAzureCredentials,
+	aigv1b1.BackendSecurityPolicyTypeAPIKey,
+}
+
+// validateBackendSecurityPolicyType returns an error if the type of the BackendSecurityPolicy is not supported by the
+// API schema of the backend.
+func validateBackendSecurityPolicyType(schema aigv1b1.APISchema, bspType aigv1b1.BackendSecurityPolicyType) error {
+	if schema != aigv1b1.APISchemaAzureAIInference || slices.Contains(azureAIInferenceBackendSecurityPolicyTypes, bspType) {
+		return nil
+	}
+	return fmt.Errorf("the type %s is not supported by the %s schema, which supports %v",
+		bspType, schema, azureAIInferenceBackendSecurityPolicyTypes)
+}
+
 // updateAIServiceBackendStatus updates the status of the AIServiceBackend.
 func (c *AIBackendController) updateAIServiceBackendStatus(ctx context.Context, backend *aigv1b1.AIServiceBackend, conditionType string, message string) {
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -7,6 +7,7 @@ package controller
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -112,3 +113,52 @@ func TestAIServiceBackendController_Reconcile_error_with_multiple_bsps(t *testin
 	_, err = c.Reconcile(t.Context(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: backendName}})
 	require.ErrorContains(t, err, `multiple BackendSecurityPolicies found for AIServiceBackend mybackend: [bsp-0 bsp-1 bsp-2 bsp-3 bsp-4]`)
 }
+
+func TestAIServiceBackendController_Reconcile_AzureAIInference(t *testing.T) {
+	fakeClient := requireNewFakeClientWithIndexes(t)
+	eventChan := internaltesting.NewControllerEventChan[*aigv1b1.AIGatewayRoute]()
+	c := NewAIServiceBackendController(fakeClient, fake2.NewClientset(), ctrl.Log, eventChan.Ch)
+
+	const namespace = "default"
+	for _, tc := range []struct {
+		bspType aigv1b1.BackendSecurityPolicyType
+		expErr  string
+	}{
+		{bspType: aigv1b1.BackendSecurityPolicyTypeAzureAPIKey},
+		{bspType: aigv1b1.BackendSecurityPolicyTypeAzureCredentials},
+		{bspType: aigv1b1.BackendSecurityPolicyTypeAPIKey},
+		{
+			bspType: aigv1b1.BackendSecurityPolicyTypeAWSCredentials,
+			expErr: "invalid BackendSecurityPolicy foundry-awscredentials for AIServiceBackend foundry-awscredentials: " +
+				"the type AWSCredentials is not supported by the AzureAIInference schema, which supports [AzureAPIKey AzureCredentials APIKey]",
+		},
+	} {
+		t.Run(string(tc.bspType), func(t *testing.T) {
+			name := "foundry-" + strings.ToLower(string(tc.bspType))
+			require.NoError(t, fakeClient.Create(t.Context(), &aigv1b1.BackendSecurityPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Spec: aigv1b1.BackendSecurityPolicySpec{
+					Type:       tc.bspType,
+					TargetRefs: []gwapiv1a2.LocalPolicyTargetReference{{Name: gwapiv1.ObjectName(name)}},
+				},
+			}))
+			require.NoError(t, fakeClient.Create(t.Context(), &aigv1b1.AIServiceBackend{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Spec:       aigv1b1.AIServiceBackendSpec{APISchema: aigv1b1.VersionedAPISchema{Name: aigv1b1.APISchemaAzureAIInference}},
+			}))
+			_, err := c.Reconcile(t.Context(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}})
+
+			var backend aigv1b1.AIServiceBackend
+			require.NoError(t, fakeClient.Get(t.Context(), types.NamespacedName{Namespace: namespace, Name: name}, &backend))
+			require.Len(t, backend.Status.Conditions, 1)
+			if tc.expErr != "" {
+				require.EqualError(t, err, tc.expErr)
+				require.Equal(t, aigv1b1.ConditionTypeNotAccepted, backend.Status.Conditions[0].Type)
+				require.Equal(t, tc.expErr, backend.Status.Conditions[0].Message)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, aigv1b1.ConditionTypeAccepted, backend.Status.Conditions[0].Type)
+			}
+		})
+	}
+}
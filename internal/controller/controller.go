@@ -250,6 +250,13 @@ func StartControllers(ctx context.Context, mgr manager.Manager, config *rest.Con
 		}
 	}
 
+	// ModelAlias controller for the model aliases resolved by the Gateways.
+	modelAliasC := NewModelAliasController(c, logger.WithName("model-alias"), aiGatewayRouteEventChan)
+	if err = TypedControllerBuilderForCRD(mgr, &aigv1a1.ModelAlias{}).
+		Complete(modelAliasC); err != nil {
+		return fmt.Errorf("failed to create controller for ModelAlias: %w", err)
+	}
+
 	// ReferenceGrant controller for cross-namespace access validation
 	referenceGrantC := NewReferenceGrantController(c, logger.WithName("reference-grant"), aiGatewayRouteEventChan)
 	if err = TypedControllerBuilderForCRD(mgr, &gwapiv1b1.ReferenceGrant{}).
@@ -475,6 +482,18 @@ func aiGatewayRouteIndexFunc(o client.Object) []string {
 			key := fmt.Sprintf("%s.%s", backend.Name, backendNamespace)
 			ret = append(ret, key)
 		}
+		for _, shadow := range rule.Shadows {
+			// The shadow backends are always in the route's namespace.
+			ret = append(ret, fmt.Sprintf("%s.%s", shadow.Name, aiGatewayRoute.Namespace))
+		}
+		if f := rule.StreamFailover; f != nil {
+			// The stream failover backend is always in the route's namespace as well.
+			ret = append(ret, fmt.Sprintf("%s.%s", f.Name, aiGatewayRoute.Namespace))
+		}
+		if rc := rule.ResponseCache; rc != nil && rc.Semantic != nil {
+			// So is the embeddings backend of the semantic cache.
+			ret = append(ret, fmt.Sprintf("%s.%s", rc.Semantic.EmbeddingsBackend, aiGatewayRoute.Namespace))
+		}
 	}
 	return ret
 }
@@ -50,6 +50,11 @@ func Test_aiGatewayRouteIndexFunc(t *testing.T) {
 						{Name: "backend1", Weight: ptr.To[int32](1)},
 						{Name: "backend2", Weight: ptr.To[int32](1)},
 					},
+					Shadows:        []aigv1b1.AIGatewayRouteRuleShadow{{Name: "shadow1"}},
+					StreamFailover: &aigv1b1.AIGatewayRouteRuleStreamFailover{Name: "failover1"},
+					ResponseCache: &aigv1b1.AIGatewayRouteRuleResponseCache{
+						Semantic: &aigv1b1.AIGatewayRouteRuleSemanticCache{EmbeddingsBackend: "embeddings1"},
+					},
 				},
 			},
 		},
@@ -68,6 +73,23 @@ func Test_aiGatewayRouteIndexFunc(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, aiGatewayRoutes.Items, 1)
 	require.Equal(t, aiGatewayRoute.Name, aiGatewayRoutes.Items[0].Name)
+
+	err = c.List(t.Context(), &aiGatewayRoutes,
+		client.MatchingFields{k8sClientIndexBackendToReferencingAIGatewayRoute: "shadow1.default"})
+	require.NoError(t, err)
+	require.Len(t, aiGatewayRoutes.Items, 1)
+	require.Equal(t, aiGatewayRoute.Name, aiGatewayRoutes.Items[0].Name)
+
+	err = c.List(t.Context(), &aiGatewayRoutes,
+		client.MatchingFields{k8sClientIndexBackendToReferencingAIGatewayRoute: "failover1.default"})
+	require.NoError(t, err)
+	require.Len(t, aiGatewayRoutes.Items, 1)
+	require.Equal(t, aiGatewayRoute.Name, aiGatewayRoutes.Items[0].Name)
+
+	err = c.List(t.Context(), &aiGatewayRoutes,
+		client.MatchingFields{k8sClientIndexBackendToReferencingAIGatewayRoute: "embeddings1.default"})
+	require.NoError(t, err)
+	require.Len(t, aiGatewayRoutes.Items, 1)
 }
 
 func Test_backendSecurityPolicyIndexFunc(t *testing.T) {
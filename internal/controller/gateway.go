@@ -9,7 +9,12 @@ import (
 	"cmp"
 	"context"
 	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,9 +36,14 @@ import (
 	aigv1a1 "github.com/envoyproxy/ai-gateway/api/v1alpha1"
 	aigv1b1 "github.com/envoyproxy/ai-gateway/api/v1beta1"
 	"github.com/envoyproxy/ai-gateway/internal/controller/rotators"
+	"github.com/envoyproxy/ai-gateway/internal/errorclass"
 	"github.com/envoyproxy/ai-gateway/internal/filterapi"
 	"github.com/envoyproxy/ai-gateway/internal/internalapi"
 	"github.com/envoyproxy/ai-gateway/internal/llmcostcel"
+	"github.com/envoyproxy/ai-gateway/internal/parameterpolicy"
+	"github.com/envoyproxy/ai-gateway/internal/promptpolicy"
+	"github.com/envoyproxy/ai-gateway/internal/requestcel"
+	"github.com/envoyproxy/ai-gateway/internal/toolpolicy"
 	"github.com/envoyproxy/ai-gateway/internal/version"
 )
 
@@ -250,6 +260,358 @@ func aigwLLMRequestCostToFilterAPI(cost aigv1b1.LLMRequestCost, routeName string
 	return out, nil
 }
 
+// promptCachingToFilterAPI converts an aigv1b1.PromptCaching to filterapi.PromptCaching.
+func promptCachingToFilterAPI(p *aigv1b1.PromptCaching) *filterapi.PromptCaching {
+	if p == nil {
+		return nil
+	}
+	return &filterapi.PromptCaching{
+		System:    p.System,
+		Tools:     p.Tools,
+		LastTurns: int(p.LastTurns),
+		TTL:       p.TTL,
+	}
+}
+
+// circuitBreakerToFilterAPI converts an aigv1b1.CircuitBreaker to filterapi.CircuitBreaker, applying the defaults
+// of the API in case they are not set, e.g., in the objects created before the field was added.
+func circuitBreakerToFilterAPI(cb *aigv1b1.CircuitBreaker) *filterapi.CircuitBreaker {
+	if cb == nil {
+		return nil
+	}
+	ret := &filterapi.CircuitBreaker{
+		ConsecutiveFailures: int(ptr.Deref(cb.ConsecutiveFailures, 5)),
+		OpenDuration:        30 * time.Second,
+	}
+	if cb.TimeToFirstToken != nil {
+		if d, err := time.ParseDuration(string(*cb.TimeToFirstToken)); err == nil && d > 0 {
+			ret.TimeToFirstToken = d
+		}
+	}
+	if cb.OpenDuration != nil {
+		if d, err := time.ParseDuration(string(*cb.OpenDuration)); err == nil && d > 0 {
+			ret.OpenDuration = d
+		}
+	}
+	return ret
+}
+
+// responseCacheToFilterAPI converts the response cache of the rule to filterapi.ResponseCache, applying the defaults
+// of the API in case they are not set, or returns nil if the rule has none.
+func responseCacheToFilterAPI(route *aigv1b1.AIGatewayRoute, ruleIndex int) *filterapi.ResponseCache {
+	rc := route.Spec.Rules[ruleIndex].ResponseCache
+	if rc == nil {
+		return nil
+	}
+	ret := &filterapi.ResponseCache{TTL: time.Hour}
+	if rc.TTL != nil {
+		if d, err := time.ParseDuration(string(*rc.TTL)); err == nil && d > 0 {
+			ret.TTL = d
+		}
+	}
+	if sc := rc.Semantic; sc != nil {
+		ret.Semantic = &filterapi.SemanticCache{
+			EmbeddingsBackend:   internalapi.PerRouteRuleSemanticCacheBackendName(route.Namespace, sc.EmbeddingsBackend, route.Name, ruleIndex),
+			SimilarityThreshold: float64(ptr.Deref(sc.SimilarityThreshold, 95)) / 100,
+		}
+	}
+	return ret
+}
+
+// guardrailsToFilterAPI converts the guardrails of the rule to filterapi.Guardrails, applying the defaults of the API
+// in case they are not set, or returns nil if the rule has none. This returns an error if a custom pattern of the PII
+// guardrail, of the prompt injection guardrail or of the output guardrail is not a valid regular expression or the URL
+// of the HTTP external guardrail is invalid, in which case the external processor would reject the configuration.
+func guardrailsToFilterAPI(route *aigv1b1.AIGatewayRoute, ruleIndex int) (*filterapi.Guardrails, error) {
+	g := route.Spec.Rules[ruleIndex].Guardrails
+	if g == nil || (g.PII == nil && g.External == nil && g.PromptInjection == nil && g.Output == nil) {
+		return nil, nil
+	}
+	ret := &filterapi.Guardrails{}
+	if g.PII != nil {
+		pii := &filterapi.PIIGuardrail{Action: filterapi.PIIGuardrailAction(g.PII.Action)}
+		if pii.Action == "" {
+			pii.Action = filterapi.PIIGuardrailActionMask
+		}
+		if g.PII.Entities == nil {
+			pii.Entities = []string{
+				string(aigv1b1.PIIEntityEmail), string(aigv1b1.PIIEntityPhoneNumber),
+				string(aigv1b1.PIIEntityCreditCard), string(aigv1b1.PIIEntityNationalID),
+			}
+		} else {
+			for _, e := range g.PII.Entities {
+				pii.Entities = append(pii.Entities, string(e))
+			}
+		}
+		for _, p := range g.PII.Patterns {
+			if _, err := regexp.Compile(p.Regex); err != nil {
+				return nil, fmt.Errorf("invalid regex of the PII pattern %q: %w", p.Name, err)
+			}
+			pii.Patterns = append(pii.Patterns, filterapi.PIIPattern{Name: p.Name, Regex: p.Regex})
+		}
+		ret.PII = pii
+	}
+	if e := g.External; e != nil {
+		ext := &filterapi.ExternalGuardrail{
+			Protocol: cmp.Or(string(e.Protocol), string(aigv1b1.ExternalGuardrailProtocolHTTP)),
+			Endpoint: e.Endpoint,
+			Timeout:  time.Second,
+			FailOpen: e.FailureMode == aigv1b1.ExternalGuardrailFailureModeFailOpen,
+		}
+		if ext.Protocol == string(aigv1b1.ExternalGuardrailProtocolHTTP) {
+			if u, err := url.Parse(e.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return nil, fmt.Errorf("invalid URL of the external guardrail %q", e.Endpoint)
+			}
+		}
+		if e.Timeout != nil {
+			if d, err := time.ParseDuration(string(*e.Timeout)); err == nil && d > 0 {
+				ext.Timeout = d
+			}
+		}
+		if e.Response != nil {
+			ext.ResponseWindowSize = int(ptr.Deref(e.Response.WindowSize, 500))
+		}
+		ret.External = ext
+	}
+	if pi := g.PromptInjection; pi != nil {
+		ret.PromptInjection = &filterapi.PromptInjectionGuardrail{
+			Shadow:    pi.Mode == aigv1b1.PromptInjectionGuardrailModeShadow,
+			Threshold: int(ptr.Deref(pi.Threshold, 50)),
+		}
+		if pi.Rules == nil {
+			ret.PromptInjection.Rules = []string{
+				string(aigv1b1.PromptInjectionRuleIgnoreInstructions), string(aigv1b1.PromptInjectionRuleRoleOverride),
+				string(aigv1b1.PromptInjectionRuleSystemPromptExtraction), string(aigv1b1.PromptInjectionRuleDelimiterInjection),
+				string(aigv1b1.PromptInjectionRuleHiddenUnicode), string(aigv1b1.PromptInjectionRuleEncodedPayload),
+			}
+		} else {
+			for _, r := range pi.Rules {
+				ret.PromptInjection.Rules = append(ret.PromptInjection.Rules, string(r))
+			}
+		}
+		for _, p := range pi.Patterns {
+			if _, err := regexp.Compile(p.Regex); err != nil {
+				return nil, fmt.Errorf("invalid regex of the prompt injection pattern %q: %w", p.Name, err)
+			}
+			ret.PromptInjection.Patterns = append(ret.PromptInjection.Patterns, filterapi.PromptInjectionPattern{
+				Name: p.Name, Regex: p.Regex, Score: int(ptr.Deref(p.Score, 50)),
+			})
+		}
+	}
+	if out := g.Output; out != nil {
+		ret.Output = &filterapi.OutputGuardrail{
+			Shadow:     out.Mode == aigv1b1.OutputGuardrailModeShadow,
+			WindowSize: int(ptr.Deref(out.WindowSize, 256)),
+		}
+		for _, r := range out.Rules {
+			regex := ptr.Deref(r.Regex, "")
+			if _, err := regexp.Compile(regex); err != nil {
+				return nil, fmt.Errorf("invalid regex of the output filter rule %q: %w", r.Name, err)
+			}
+			ret.Output.Rules = append(ret.Output.Rules, filterapi.OutputFilterRule{Name: r.Name, Regex: regex, Keywords: r.Keywords})
+		}
+	}
+	return ret, nil
+}
+
+// toolPolicyToFilterAPI converts the tool policy of the rule to filterapi.ToolPolicy, or returns nil if the rule has
+// none. This returns an error if a regular expression of the policy is invalid, in which case the external processor
+// would reject the configuration.
+func toolPolicyToFilterAPI(route *aigv1b1.AIGatewayRoute, ruleIndex int) (*filterapi.ToolPolicy, error) {
+	tp := route.Spec.Rules[ruleIndex].ToolPolicy
+	if tp == nil {
+		return nil, nil
+	}
+	if _, err := toolpolicy.NewMatcher(tp.Allow, tp.AllowRegex, tp.Deny, tp.DenyRegex); err != nil {
+		return nil, fmt.Errorf("invalid tool policy: %w", err)
+	}
+	return &filterapi.ToolPolicy{
+		Strip:              tp.Action == aigv1b1.ToolPolicyActionStrip,
+		Allow:              tp.Allow,
+		AllowRegex:         tp.AllowRegex,
+		Deny:               tp.Deny,
+		DenyRegex:          tp.DenyRegex,
+		MaxTools:           int(ptr.Deref(tp.MaxTools, 0)),
+		MaxSchemaBytes:     int(ptr.Deref(tp.MaxSchemaBytes, 0)),
+		RemoveBuiltInTools: tp.RemoveBuiltInTools,
+	}, nil
+}
+
+// promptPolicyToFilterAPI merges the prompt policies of the route rule and the backend into filterapi.PromptPolicy,
+// or returns nil if neither has one. The messages of the backend are added after the ones of the route rule, and the
+// client messages are rejected if either locks them.
+func promptPolicyToFilterAPI(routeLevel, backendLevel *aigv1b1.PromptPolicy) (*filterapi.PromptPolicy, error) {
+	if routeLevel == nil && backendLevel == nil {
+		return nil, nil
+	}
+	ret := &filterapi.PromptPolicy{}
+	for _, p := range []*aigv1b1.PromptPolicy{routeLevel, backendLevel} {
+		if p == nil {
+			continue
+		}
+		ret.Lock = ret.Lock || p.Mode == aigv1b1.PromptPolicyModeLock
+		for i, m := range p.Messages {
+			if _, err := promptpolicy.NewTemplate(m.Template); err != nil {
+				return nil, fmt.Errorf("invalid template of the prompt policy message %d: %w", i, err)
+			}
+			ret.Messages = append(ret.Messages, filterapi.PromptPolicyMessage{
+				Developer: m.Role == aigv1b1.PromptPolicyRoleDeveloper,
+				Append:    m.Position == aigv1b1.PromptPolicyPositionAppend,
+				Template:  m.Template,
+			})
+		}
+	}
+	return ret, nil
+}
+
+// parameterPolicyToFilterAPI merges the parameter policies of the route rule and the backend into
+// filterapi.ParameterPolicy, or returns nil if neither has one. The rules of the backend are applied after the ones of
+// the route rule, and the blocked fields of both are merged. This returns an error if a value of a rule is invalid,
+// in which case the external processor would reject the configuration.
+func parameterPolicyToFilterAPI(routeLevel, backendLevel *aigv1b1.ParameterPolicy) (*filterapi.ParameterPolicy, error) {
+	if routeLevel == nil && backendLevel == nil {
+		return nil, nil
+	}
+	ret := &filterapi.ParameterPolicy{}
+	for _, p := range []*aigv1b1.ParameterPolicy{routeLevel, backendLevel} {
+		if p == nil {
+			continue
+		}
+		for _, r := range p.Rules {
+			action := r.Action
+			if action == "" {
+				action = aigv1b1.ParameterPolicyActionClamp
+			}
+			rule := filterapi.ParameterPolicyRule{
+				Name:      r.Name,
+				Parameter: string(r.Parameter),
+				Action:    string(action),
+				Default:   ptr.Deref(r.Default, ""),
+				Min:       ptr.Deref(r.Min, ""),
+				Max:       ptr.Deref(r.Max, ""),
+			}
+			if _, err := parameterpolicy.NewRule(rule.Name, parameterpolicy.Parameter(rule.Parameter),
+				parameterpolicy.Action(rule.Action), rule.Default, rule.Min, rule.Max); err != nil {
+				return nil, fmt.Errorf("invalid parameter policy rule %q: %w", r.Name, err)
+			}
+			ret.Rules = append(ret.Rules, rule)
+		}
+		for _, f := range p.BlockedFields {
+			if !slices.Contains(ret.BlockedFields, f) {
+				ret.BlockedFields = append(ret.BlockedFields, f)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// backendSelectionToFilterAPI converts the backend selection of the rule to filterapi.BackendSelection, or returns
+// nil if the rule has none. The candidates are the enabled backends of the lowest priority, so that the backends of
+// the higher priorities are only used for the failover.
+func backendSelectionToFilterAPI(route *aigv1b1.AIGatewayRoute, ruleIndex int) *filterapi.BackendSelection {
+	rule := &route.Spec.Rules[ruleIndex]
+	if rule.BackendSelection == nil || len(rule.BackendRefs) == 0 || rule.BackendRefs[0].IsInferencePool() {
+		return nil
+	}
+	ret := &filterapi.BackendSelection{HeaderName: internalapi.BackendSelectionHeaderName(route.Namespace, route.Name, ruleIndex)}
+	switch rule.BackendSelection.Objective {
+	case aigv1b1.BackendSelectionObjectiveFastest:
+		ret.LatencyWeight = 1
+	case aigv1b1.BackendSelectionObjectiveCheapest:
+		ret.CostWeight = 1
+	default:
+		ret.LatencyWeight = float64(ptr.Deref(rule.BackendSelection.LatencyWeight, 1))
+		ret.CostWeight = float64(ptr.Deref(rule.BackendSelection.CostWeight, 1))
+	}
+
+	lowestPriority := uint32(math.MaxUint32)
+	for i := range rule.BackendRefs {
+		if br := &rule.BackendRefs[i]; ptr.Deref(br.Weight, 1) != 0 {
+			lowestPriority = min(lowestPriority, ptr.Deref(br.Priority, 0))
+		}
+	}
+	for i := range rule.BackendRefs {
+		br := &rule.BackendRefs[i]
+		if ptr.Deref(br.Weight, 1) == 0 || ptr.Deref(br.Priority, 0) != lowestPriority {
+			continue
+		}
+		candidate := filterapi.BackendSelectionCandidate{
+			Backend:  internalapi.PerRouteRuleRefBackendName(route.Namespace, br.Name, route.Name, ruleIndex, i),
+			RefIndex: i,
+		}
+		if br.Price != nil {
+			// The prices are validated by the API, so the errors are ignored.
+			candidate.InputTokenPrice, _ = strconv.ParseFloat(br.Price.InputTokens, 64)
+			candidate.OutputTokenPrice, _ = strconv.ParseFloat(br.Price.OutputTokens, 64)
+		}
+		ret.Candidates = append(ret.Candidates, candidate)
+	}
+	return ret
+}
+
+// fallbackErrorClasses maps the error classes of the API to the ones used by the filter.
+var fallbackErrorClasses = map[aigv1b1.ErrorClass]errorclass.Class{
+	aigv1b1.ErrorClassRateLimit:     errorclass.RateLimit,
+	aigv1b1.ErrorClassOverloaded:    errorclass.Overloaded,
+	aigv1b1.ErrorClassContextLength: errorclass.ContextLength,
+	aigv1b1.ErrorClassContentFilter: errorclass.ContentFilter,
+	aigv1b1.ErrorClassAuth:          errorclass.Auth,
+}
+
+// retriableErrorClassesToFilterAPI returns the error classes retried by the fallback policy, i.e., the ones whose
+// action is either Retry or Failover.
+func retriableErrorClassesToFilterAPI(p *aigv1b1.AIGatewayRouteRuleFallbackPolicy) []string {
+	if p == nil {
+		return nil
+	}
+	var classes []string
+	for _, a := range p.Actions {
+		if a.Action == aigv1b1.FallbackActionTypeReturn {
+			continue
+		}
+		if c, ok := fallbackErrorClasses[a.ErrorClass]; ok {
+			classes = append(classes, string(c))
+		}
+	}
+	return classes
+}
+
+// failoverErrorClassesToFilterAPI returns the error classes on which the fallback policy fails over to the next
+// priority when it also retries the other classes on the same priority, or nil if it does not mix the two actions.
+func failoverErrorClassesToFilterAPI(p *aigv1b1.AIGatewayRouteRuleFallbackPolicy) []string {
+	if p == nil || !slices.ContainsFunc(p.Actions, func(a aigv1b1.AIGatewayRouteRuleFallbackAction) bool {
+		return a.Action == aigv1b1.FallbackActionTypeRetry
+	}) {
+		return nil
+	}
+	var classes []string
+	for _, a := range p.Actions {
+		if a.Action != aigv1b1.FallbackActionTypeFailover {
+			continue
+		}
+		if c, ok := fallbackErrorClasses[a.ErrorClass]; ok {
+			classes = append(classes, string(c))
+		}
+	}
+	return classes
+}
+
+// shadowsToFilterAPI converts the shadows of the rule to filterapi.Shadow, applying the defaults of the API in case
+// they are not set.
+func shadowsToFilterAPI(route *aigv1b1.AIGatewayRoute, ruleIndex int) []filterapi.Shadow {
+	rule := &route.Spec.Rules[ruleIndex]
+	var shadows []filterapi.Shadow
+	for i := range rule.Shadows {
+		shadow := &rule.Shadows[i]
+		shadows = append(shadows, filterapi.Shadow{
+			Backend:          internalapi.PerRouteRuleShadowBackendName(route.Namespace, shadow.Name, route.Name, ruleIndex, i),
+			Percent:          int(ptr.Deref(shadow.Percent, 100)),
+			CompareResponses: shadow.CompareResponses,
+		})
+	}
+	return shadows
+}
+
 // mergeBodyMutations merges route-level and backend-level BodyMutation with route-level taking precedence.
 // Returns the merged BodyMutation where route-level operations override backend-level operations for conflicting body fields.
 func mergeBodyMutations(routeLevel, backendLevel *aigv1b1.HTTPBodyMutation) *aigv1b1.HTTPBodyMutation {
@@ -375,6 +737,29 @@ func (c *GatewayController) reconcileFilterConfigSecret(
 	// ec.UnscopedModels (and merge them into ec.ModelsByHost) when at least one route
 	// IS hostname-scoped; otherwise the existing ec.Models list already covers them.
 	var unscopedModels []filterapi.Model
+	// Header names of the body matches already added, so that identical expressions are evaluated only once.
+	bodyMatchHeaders := map[string]struct{}{}
+	addBodyMatch := func(cel string) {
+		headerName := internalapi.BodyMatchHeaderName(cel)
+		if _, ok := bodyMatchHeaders[headerName]; !ok {
+			bodyMatchHeaders[headerName] = struct{}{}
+			ec.RequestBodyMatches = append(ec.RequestBodyMatches, filterapi.RequestBodyMatch{HeaderName: headerName, CEL: cel})
+		}
+	}
+	// Header names of the prompt affinities already added, so that the key is computed only once per number of messages.
+	promptAffinityHeaders := map[string]struct{}{}
+	addPromptAffinity := func(messages int) {
+		headerName := internalapi.PromptAffinityHeaderName(messages)
+		if _, ok := promptAffinityHeaders[headerName]; !ok {
+			promptAffinityHeaders[headerName] = struct{}{}
+			ec.PromptAffinities = append(ec.PromptAffinities, filterapi.PromptAffinity{HeaderName: headerName, Messages: messages})
+		}
+	}
+	// The largest context window of the rules matching each model, where zero means that a rule without
+	// a context window matches the model. anyModelContextWindow is the one of the rules that can match any
+	// model, e.g., without a match on the model header, or -1 if there is none.
+	modelContextWindows := map[string]int32{}
+	anyModelContextWindow := int32(-1)
 
 	for i := range aiGatewayRoutes {
 		aiGatewayRoute := &aiGatewayRoutes[i]
@@ -391,7 +776,21 @@ func (c *GatewayController) reconcileFilterConfigSecret(
 		injectedQuotaCosts := make(map[string]struct{})
 		for ruleIndex := range spec.Rules {
 			rule := &spec.Rules[ruleIndex]
+			contextWindow := ruleContextWindow(rule)
+			if contextWindow > 0 {
+				addBodyMatch(ruleContextWindowCEL(rule))
+			}
+			if len(rule.Matches) == 0 {
+				anyModelContextWindow = widenContextWindow(anyModelContextWindow, contextWindow)
+			}
 			for _, m := range rule.Matches {
+				if m.Body != nil {
+					if _, err = requestcel.NewProgram(m.Body.CEL); err != nil {
+						return false, fmt.Errorf("invalid body match CEL expression in route %s: %w", aiGatewayRoute.Name, err)
+					}
+					addBodyMatch(m.Body.CEL)
+				}
+				modelMatched := false
 				for _, h := range m.Headers {
 					// If explicitly set to something that is not an exact match, skip.
 					// If not set, we assume it's an exact match.
@@ -400,6 +799,12 @@ func (c *GatewayController) reconcileFilterConfigSecret(
 					if (h.Type != nil && *h.Type != gwapiv1.HeaderMatchExact) || string(h.Name) != internalapi.ModelNameHeaderKeyDefault {
 						continue
 					}
+					modelMatched = true
+					w, ok := modelContextWindows[h.Value]
+					if !ok {
+						w = -1
+					}
+					modelContextWindows[h.Value] = widenContextWindow(w, contextWindow)
 					model := filterapi.Model{
 						Name:      h.Value,
 						CreatedAt: ptr.Deref[metav1.Time](rule.ModelsCreatedAt, aiGatewayRoute.CreationTimestamp).UTC(),
@@ -420,14 +825,44 @@ func (c *GatewayController) reconcileFilterConfigSecret(
 						unscopedModels = append(unscopedModels, model)
 					}
 				}
+				if !modelMatched {
+					// The match does not restrict the model, e.g., a catch-all or a body match.
+					anyModelContextWindow = widenContextWindow(anyModelContextWindow, contextWindow)
+				}
 			}
 			for backendRefIndex := range rule.BackendRefs {
 				backendRef := &rule.BackendRefs[backendRefIndex]
 				b := filterapi.Backend{}
 				b.Name = internalapi.PerRouteRuleRefBackendName(aiGatewayRoute.Namespace, backendRef.Name, aiGatewayRoute.Name, ruleIndex, backendRefIndex)
 				b.ModelNameOverride = backendRef.ModelNameOverride
+				b.ContextWindow = ptr.Deref(backendRef.ContextWindow, 0)
+				b.RetriableErrorClasses = retriableErrorClassesToFilterAPI(rule.FallbackPolicy)
+				b.FailoverErrorClasses = failoverErrorClassesToFilterAPI(rule.FallbackPolicy)
+				b.Hedging = rule.HedgePolicy != nil
+				b.Shadows = shadowsToFilterAPI(aiGatewayRoute, ruleIndex)
+				if f := rule.StreamFailover; f != nil {
+					b.StreamFailoverBackend = internalapi.PerRouteRuleStreamFailoverBackendName(aiGatewayRoute.Namespace, f.Name, aiGatewayRoute.Name, ruleIndex)
+				}
+				b.ResponseCache = responseCacheToFilterAPI(aiGatewayRoute, ruleIndex)
+				b.Guardrails, err = guardrailsToFilterAPI(aiGatewayRoute, ruleIndex)
+				if err != nil {
+					// The backend is skipped rather than the guardrail, so that the requests are never sent unchecked.
+					c.logger.Error(err, "failed to convert the guardrails. Skipping this backend.",
+						"backend_name", backendRef.Name, "aigatewayroute", aiGatewayRoute.Name,
+						"namespace", aiGatewayRoute.Namespace)
+					continue
+				}
+				b.ToolPolicy, err = toolPolicyToFilterAPI(aiGatewayRoute, ruleIndex)
+				if err != nil {
+					c.logger.Error(err, "failed to convert the tool policy. Skipping this backend.",
+						"backend_name", backendRef.Name, "aigatewayroute", aiGatewayRoute.Name,
+						"namespace", aiGatewayRoute.Namespace)
+					continue
+				}
 
 				var bsp *aigv1b1.BackendSecurityPolicy
+				var backendPromptPolicy *aigv1b1.PromptPolicy
+				var backendParameterPolicy *aigv1b1.ParameterPolicy
 				backendNamespace := backendRef.GetNamespace(aiGatewayRoute.Namespace)
 
 				if backendRef.IsInferencePool() {
@@ -470,11 +905,37 @@ func (c *GatewayController) reconcileFilterConfigSecret(
 					// Merge with route-level taking precedence over backend-level
 					mergedBodyMutation := mergeBodyMutations(routeBodyMutation, backendBodyMutation)
 					b.BodyMutation = bodyMutationToFilterAPI(mergedBodyMutation)
+					b.PromptCaching = promptCachingToFilterAPI(backendObj.Spec.PromptCaching)
+					b.CircuitBreaker = circuitBreakerToFilterAPI(backendObj.Spec.CircuitBreaker)
+					backendPromptPolicy = backendObj.Spec.PromptPolicy
+					backendParameterPolicy = backendObj.Spec.ParameterPolicy
 
 					b.Schema = schemaToFilterAPI(backendObj.Spec.APISchema)
 				}
 
+				b.PromptPolicy, err = promptPolicyToFilterAPI(rule.PromptPolicy, backendPromptPolicy)
+				if err != nil {
+					// The backend is skipped rather than the policy, so that the requests are never sent without it.
+					c.logger.Error(err, "failed to convert the prompt policy. Skipping this backend.",
+						"backend_name", backendRef.Name, "aigatewayroute", aiGatewayRoute.Name,
+						"namespace", aiGatewayRoute.Namespace)
+					continue
+				}
+				b.ParameterPolicy, err = parameterPolicyToFilterAPI(rule.ParameterPolicy, backendParameterPolicy)
+				if err != nil {
+					c.logger.Error(err, "failed to convert the parameter policy. Skipping this backend.",
+						"backend_name", backendRef.Name, "aigatewayroute", aiGatewayRoute.Name,
+						"namespace", aiGatewayRoute.Namespace)
+					continue
+				}
+
 				if bsp != nil {
+					if err = validateBackendSecurityPolicyType(aigv1b1.APISchema(b.Schema.Name), bsp.Spec.Type); err != nil {
+						c.logger.Error(err, "unsupported backend security policy. Skipping this backend.",
+							"backend_name", backendRef.Name, "backend_security_policy", bsp.Name,
+							"aigatewayroute", aiGatewayRoute.Name, "namespace", aiGatewayRoute.Namespace)
+						continue
+					}
 					b.Auth, err = c.bspToFilterAPIBackendAuth(ctx, bsp)
 					if err != nil {
 						c.logger.Error(err, "failed to get backend auth from backend security policy. Skipping this backend.",
@@ -500,6 +961,55 @@ func (c *GatewayController) reconcileFilterConfigSecret(
 					routeBackendNames = append(routeBackendNames, b.Name)
 				}
 			}
+			for shadowIndex := range rule.Shadows {
+				// The shadow backends are not added to the route backend names since they do not count toward
+				// the request costs.
+				shadow := &rule.Shadows[shadowIndex]
+				b, shadowErr := c.shadowBackend(ctx, aiGatewayRoute.Namespace, shadow.Name,
+					internalapi.PerRouteRuleShadowBackendName(aiGatewayRoute.Namespace, shadow.Name, aiGatewayRoute.Name, ruleIndex, shadowIndex),
+					shadow.ModelNameOverride)
+				if shadowErr != nil {
+					c.logger.Error(shadowErr, "failed to get shadow backend. Skipping this shadow.",
+						"backend_name", shadow.Name, "aigatewayroute", aiGatewayRoute.Name,
+						"namespace", aiGatewayRoute.Namespace)
+					continue
+				}
+				ec.Backends = append(ec.Backends, *b)
+			}
+			if f := rule.StreamFailover; f != nil {
+				// The usage of the continuation is merged into the one of the backend ref whose stream is continued,
+				// so the failover backend is not added to the route backend names either.
+				b, failoverErr := c.shadowBackend(ctx, aiGatewayRoute.Namespace, f.Name,
+					internalapi.PerRouteRuleStreamFailoverBackendName(aiGatewayRoute.Namespace, f.Name, aiGatewayRoute.Name, ruleIndex),
+					f.ModelNameOverride)
+				if failoverErr != nil {
+					c.logger.Error(failoverErr, "failed to get stream failover backend. Skipping the stream failover.",
+						"backend_name", f.Name, "aigatewayroute", aiGatewayRoute.Name,
+						"namespace", aiGatewayRoute.Namespace)
+				} else {
+					ec.Backends = append(ec.Backends, *b)
+				}
+			}
+			if rc := rule.ResponseCache; rc != nil && rc.Semantic != nil {
+				// The embeddings requests do not count toward the request costs either.
+				sc := rc.Semantic
+				b, embeddingsErr := c.shadowBackend(ctx, aiGatewayRoute.Namespace, sc.EmbeddingsBackend,
+					internalapi.PerRouteRuleSemanticCacheBackendName(aiGatewayRoute.Namespace, sc.EmbeddingsBackend, aiGatewayRoute.Name, ruleIndex),
+					sc.EmbeddingsModel)
+				if embeddingsErr != nil {
+					c.logger.Error(embeddingsErr, "failed to get semantic cache embeddings backend. Skipping the semantic cache.",
+						"backend_name", sc.EmbeddingsBackend, "aigatewayroute", aiGatewayRoute.Name,
+						"namespace", aiGatewayRoute.Namespace)
+				} else {
+					ec.Backends = append(ec.Backends, *b)
+				}
+			}
+			if selection := backendSelectionToFilterAPI(aiGatewayRoute, ruleIndex); selection != nil {
+				ec.BackendSelections = append(ec.BackendSelections, *selection)
+			}
+			if a := rule.Affinity; a != nil && a.Type == aigv1b1.AffinityTypePrompt {
+				addPromptAffinity(int(ptr.Deref(a.Messages, 1)))
+			}
 		}
 		if len(routeBackendNames) > 0 {
 			// Dedup per (metadataKey, routeName): last definition wins.
@@ -522,6 +1032,33 @@ func (c *GatewayController) reconcileFilterConfigSecret(
 		}
 	}
 
+	// The model aliases are not scoped to the hostnames of the routes, so they are listed on every host.
+	modelAliases, err := c.modelAliasesForRoutes(ctx, aiGatewayRoutes)
+	if err != nil {
+		return false, err
+	}
+	for i := range modelAliases {
+		modelAlias := &modelAliases[i]
+		ec.ModelAliases = append(ec.ModelAliases, modelAliasToFilterAPI(modelAlias))
+		model := filterapi.Model{
+			Name:      modelAlias.Spec.Alias,
+			CreatedAt: modelAlias.CreationTimestamp.UTC(),
+			OwnedBy:   defaultOwnedBy,
+		}
+		ec.Models = append(ec.Models, model)
+		unscopedModels = append(unscopedModels, model)
+	}
+
+	for model, window := range modelContextWindows {
+		// The requests are only rejected before the routing when every rule that can match the model is bounded.
+		if window = widenContextWindow(window, anyModelContextWindow); window > 0 {
+			if ec.ModelContextWindows == nil {
+				ec.ModelContextWindows = make(map[string]int32)
+			}
+			ec.ModelContextWindows[model] = window
+		}
+	}
+
 	// If at least one route is hostname-scoped, promote the unscoped models to ec.UnscopedModels
 	// so the runtime can fall back to them on unmatched hosts, and merge them into every per-host
 	// list so a host-matched request still sees the models from routes that didn't declare hostnames.
@@ -979,12 +1516,85 @@ func (c *GatewayController) injectQuotaPolicyCostExpressions(
 	}
 }
 
+// modelAliasesForRoutes returns the valid ModelAliases in the namespaces of the given AIGatewayRoutes, sorted by
+// namespace/name. When multiple ModelAliases have the same alias, only the first one is returned.
+func (c *GatewayController) modelAliasesForRoutes(ctx context.Context, aiGatewayRoutes []aigv1b1.AIGatewayRoute) ([]aigv1a1.ModelAlias, error) {
+	namespaces := map[string]struct{}{}
+	var ret []aigv1a1.ModelAlias
+	for i := range aiGatewayRoutes {
+		namespace := aiGatewayRoutes[i].Namespace
+		if _, ok := namespaces[namespace]; ok || !aiGatewayRoutes[i].GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		namespaces[namespace] = struct{}{}
+		var modelAliases aigv1a1.ModelAliasList
+		if err := c.client.List(ctx, &modelAliases, client.InNamespace(namespace)); err != nil {
+			return nil, fmt.Errorf("failed to list ModelAliases in namespace %s: %w", namespace, err)
+		}
+		for j := range modelAliases.Items {
+			modelAlias := &modelAliases.Items[j]
+			if !modelAlias.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+			if _, err := modelAliasShares(&modelAlias.Spec); err != nil {
+				c.logger.Info("skipping invalid ModelAlias", "namespace", modelAlias.Namespace, "name", modelAlias.Name, "error", err.Error())
+				continue
+			}
+			ret = append(ret, *modelAlias)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Namespace != ret[j].Namespace {
+			return ret[i].Namespace < ret[j].Namespace
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	aliases := map[string]struct{}{}
+	deduped := ret[:0]
+	for i := range ret {
+		if _, ok := aliases[ret[i].Spec.Alias]; ok {
+			c.logger.Info("skipping ModelAlias with a duplicate alias", "namespace", ret[i].Namespace, "name", ret[i].Name, "alias", ret[i].Spec.Alias)
+			continue
+		}
+		aliases[ret[i].Spec.Alias] = struct{}{}
+		deduped = append(deduped, ret[i])
+	}
+	return deduped, nil
+}
+
 // QuotaCostMetadataKey is the dynamic metadata key used to store a
 // QuotaPolicy's computed cost. A single key suffices because only one model
 // is active per request, and ext_proc filters cost entries by Model before
 // writing to this key.
 const QuotaCostMetadataKey = "quota_cost"
 
+// shadowBackend returns the filter backend with the given name of the AIServiceBackend of a shadow or a stream
+// failover, to which the requests are sent through the shadow listener. Since the AIServiceBackend is not a backend
+// ref of the rule, only its own configuration applies.
+func (c *GatewayController) shadowBackend(ctx context.Context, namespace, backendName, name, modelNameOverride string) (*filterapi.Backend, error) {
+	backendObj, bsp, err := c.backendWithMaybeBSP(ctx, namespace, backendName)
+	if err != nil {
+		return nil, err
+	}
+	b := &filterapi.Backend{
+		Name:              name,
+		ModelNameOverride: modelNameOverride,
+		Schema:            schemaToFilterAPI(backendObj.Spec.APISchema),
+		HeaderMutation:    headerMutationToFilterAPI(backendObj.Spec.HeaderMutation),
+		BodyMutation:      bodyMutationToFilterAPI(backendObj.Spec.BodyMutation),
+		PromptCaching:     promptCachingToFilterAPI(backendObj.Spec.PromptCaching),
+	}
+	if bsp != nil {
+		if err = validateBackendSecurityPolicyType(backendObj.Spec.APISchema.Name, bsp.Spec.Type); err != nil {
+			return nil, fmt.Errorf("unsupported backend security policy %s: %w", bsp.Name, err)
+		}
+		if b.Auth, err = c.bspToFilterAPIBackendAuth(ctx, bsp); err != nil {
+			return nil, fmt.Errorf("failed to get backend auth from backend security policy %s: %w", bsp.Name, err)
+		}
+	}
+	return b, nil
+}
+
 // backendWithMaybeBSP retrieves the AIServiceBackend and its associated BackendSecurityPolicy if it exists.
 func (c *GatewayController) backendWithMaybeBSP(ctx context.Context, namespace, name string) (backend *aigv1b1.AIServiceBackend, bsp *aigv1b1.BackendSecurityPolicy, err error) {
 	backend = &aigv1b1.AIServiceBackend{}
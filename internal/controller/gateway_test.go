@@ -31,6 +31,7 @@ import (
 	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 	gwapiv1a2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
 
+	aigv1a1 "github.com/envoyproxy/ai-gateway/api/v1alpha1"
 	aigv1b1 "github.com/envoyproxy/ai-gateway/api/v1beta1"
 	"github.com/envoyproxy/ai-gateway/internal/controller/rotators"
 	"github.com/envoyproxy/ai-gateway/internal/filterapi"
@@ -711,6 +712,512 @@ func TestGatewayController_reconcileFilterConfigSecret_InvalidCELExpression(t *t
 	require.Contains(t, err.Error(), "invalid CEL expression")
 }
 
+func TestGatewayController_reconcileFilterConfigSecret_RequestBodyMatches(t *testing.T) {
+	fakeClient := requireNewFakeClientWithIndexes(t)
+	kube := fake2.NewClientset()
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&zap.Options{Development: true, Level: zapcore.DebugLevel})))
+	c := NewGatewayController(fakeClient, kube, ctrl.Log, "envoy-gateway-system",
+		"docker.io/envoyproxy/ai-gateway-extproc:latest", "info", false, nil, true)
+
+	const gwNamespace = "ns"
+	require.NoError(t, fakeClient.Create(t.Context(), &aigv1b1.AIServiceBackend{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backend", Namespace: gwNamespace},
+		Spec: aigv1b1.AIServiceBackendSpec{
+			BackendRef: gwapiv1.BackendObjectReference{Name: "some-backend", Namespace: ptr.To[gwapiv1.Namespace](gwNamespace)},
+		},
+	}))
+	newRoute := func(name string, exprs ...string) aigv1b1.AIGatewayRoute {
+		var matches []aigv1b1.AIGatewayRouteRuleMatch
+		for _, expr := range exprs {
+			matches = append(matches, aigv1b1.AIGatewayRouteRuleMatch{Body: &aigv1b1.AIGatewayRouteRuleBodyMatch{CEL: expr}})
+		}
+		return aigv1b1.AIGatewayRoute{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: gwNamespace},
+			Spec: aigv1b1.AIGatewayRouteSpec{
+				Rules: []aigv1b1.AIGatewayRouteRule{
+					{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "test-backend"}}, Matches: matches},
+				},
+			},
+		}
+	}
+
+	const someNamespace = "some-namespace"
+	t.Run("deduplicated", func(t *testing.T) {
+		routes := []aigv1b1.AIGatewayRoute{newRoute("route1", "has_images", "stream"), newRoute("route2", "has_images")}
+		_, err := c.reconcileFilterConfigSecret(t.Context(), "gw", gwNamespace, someNamespace, routes, nil, "foouuid", nil)
+		require.NoError(t, err)
+
+		fc := requireFilterConfigFromBundle(t, kube, someNamespace, "gw", gwNamespace)
+		require.Equal(t, []filterapi.RequestBodyMatch{
+			{HeaderName: internalapi.BodyMatchHeaderName("has_images"), CEL: "has_images"},
+			{HeaderName: internalapi.BodyMatchHeaderName("stream"), CEL: "stream"},
+		}, fc.RequestBodyMatches)
+	})
+
+	t.Run("invalid CEL expression", func(t *testing.T) {
+		routes := []aigv1b1.AIGatewayRoute{newRoute("route1", "estimated_prompt_tokens")}
+		_, err := c.reconcileFilterConfigSecret(t.Context(), "gw", gwNamespace, someNamespace, routes, nil, "foouuid", nil)
+		require.ErrorContains(t, err, "invalid body match CEL expression in route route1")
+	})
+}
+
+func TestGatewayController_reconcileFilterConfigSecret_ContextWindows(t *testing.T) {
+	fakeClient := requireNewFakeClientWithIndexes(t)
+	kube := fake2.NewClientset()
+	c := NewGatewayController(fakeClient, kube, ctrl.Log, "envoy-gateway-system",
+		"docker.io/envoyproxy/ai-gateway-extproc:latest", "info", false, nil, true)
+
+	const gwNamespace = "ns"
+	require.NoError(t, fakeClient.Create(t.Context(), &aigv1b1.AIServiceBackend{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backend", Namespace: gwNamespace},
+		Spec: aigv1b1.AIServiceBackendSpec{
+			BackendRef: gwapiv1.BackendObjectReference{Name: "some-backend", Namespace: ptr.To[gwapiv1.Namespace](gwNamespace)},
+		},
+	}))
+	newRule := func(model string, contextWindow *int32) aigv1b1.AIGatewayRouteRule {
+		return aigv1b1.AIGatewayRouteRule{
+			BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "test-backend", ContextWindow: contextWindow}},
+			Matches: []aigv1b1.AIGatewayRouteRuleMatch{{Headers: []gwapiv1.HTTPHeaderMatch{
+				{Name: internalapi.ModelNameHeaderKeyDefault, Value: model},
+			}}},
+		}
+	}
+	routes := []aigv1b1.AIGatewayRoute{{
+		ObjectMeta: metav1.ObjectMeta{Name: "route1", Namespace: gwNamespace},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				newRule("auto", ptr.To[int32](8000)),
+				newRule("auto", ptr.To[int32](128000)),
+				newRule("small", ptr.To[int32](8000)),
+				// A rule without a context window can serve requests of any size.
+				newRule("small", nil),
+			},
+		},
+	}}
+
+	const someNamespace = "some-namespace"
+	_, err := c.reconcileFilterConfigSecret(t.Context(), "gw", gwNamespace, someNamespace, routes, nil, "foouuid", nil)
+	require.NoError(t, err)
+
+	fc := requireFilterConfigFromBundle(t, kube, someNamespace, "gw", gwNamespace)
+	require.Equal(t, map[string]int32{"auto": 128000}, fc.ModelContextWindows)
+	require.Equal(t, []filterapi.RequestBodyMatch{
+		{HeaderName: internalapi.BodyMatchHeaderName("estimated_prompt_tokens <= 8000u"), CEL: "estimated_prompt_tokens <= 8000u"},
+		{HeaderName: internalapi.BodyMatchHeaderName("estimated_prompt_tokens <= 128000u"), CEL: "estimated_prompt_tokens <= 128000u"},
+	}, fc.RequestBodyMatches)
+	require.Len(t, fc.Backends, 4)
+	require.Equal(t, int32(8000), fc.Backends[0].ContextWindow)
+	require.Zero(t, fc.Backends[3].ContextWindow)
+
+	for _, tc := range []struct {
+		name    string
+		anyRule aigv1b1.AIGatewayRouteRule
+		exp     map[string]int32
+	}{
+		{
+			name: "bounded catch-all rule",
+			anyRule: aigv1b1.AIGatewayRouteRule{
+				BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "test-backend", ContextWindow: ptr.To[int32](200000)}},
+			},
+			exp: map[string]int32{"auto": 200000},
+		},
+		{
+			name: "unbounded catch-all rule",
+			anyRule: aigv1b1.AIGatewayRouteRule{
+				BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "test-backend"}},
+			},
+		},
+		{
+			name: "unbounded body match rule",
+			anyRule: aigv1b1.AIGatewayRouteRule{
+				BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "test-backend"}},
+				Matches: []aigv1b1.AIGatewayRouteRuleMatch{
+					{Body: &aigv1b1.AIGatewayRouteRuleBodyMatch{CEL: "has_images"}},
+				},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			withAnyRule := []aigv1b1.AIGatewayRoute{*routes[0].DeepCopy()}
+			withAnyRule[0].Spec.Rules = append(withAnyRule[0].Spec.Rules, tc.anyRule)
+			_, err := c.reconcileFilterConfigSecret(t.Context(), "gw", gwNamespace, someNamespace, withAnyRule, nil, "foouuid", nil)
+			require.NoError(t, err)
+			fc := requireFilterConfigFromBundle(t, kube, someNamespace, "gw", gwNamespace)
+			require.Equal(t, tc.exp, fc.ModelContextWindows)
+		})
+	}
+}
+
+func TestGatewayController_reconcileFilterConfigSecret_PromptAffinities(t *testing.T) {
+	fakeClient := requireNewFakeClientWithIndexes(t)
+	kube := fake2.NewClientset()
+	c := NewGatewayController(fakeClient, kube, ctrl.Log, "envoy-gateway-system",
+		"docker.io/envoyproxy/ai-gateway-extproc:latest", "info", false, nil, true)
+
+	const gwNamespace = "ns"
+	require.NoError(t, fakeClient.Create(t.Context(), &aigv1b1.AIServiceBackend{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backend", Namespace: gwNamespace},
+		Spec: aigv1b1.AIServiceBackendSpec{
+			BackendRef: gwapiv1.BackendObjectReference{Name: "some-backend", Namespace: ptr.To[gwapiv1.Namespace](gwNamespace)},
+		},
+	}))
+	newRule := func(affinity *aigv1b1.AIGatewayRouteRuleAffinity) aigv1b1.AIGatewayRouteRule {
+		return aigv1b1.AIGatewayRouteRule{
+			BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "test-backend"}},
+			Affinity:    affinity,
+		}
+	}
+	routes := []aigv1b1.AIGatewayRoute{{
+		ObjectMeta: metav1.ObjectMeta{Name: "route1", Namespace: gwNamespace},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				newRule(&aigv1b1.AIGatewayRouteRuleAffinity{Type: aigv1b1.AffinityTypePrompt}),
+				newRule(&aigv1b1.AIGatewayRouteRuleAffinity{Type: aigv1b1.AffinityTypePrompt, Messages: ptr.To[int32](1)}),
+				newRule(&aigv1b1.AIGatewayRouteRuleAffinity{Type: aigv1b1.AffinityTypePrompt, Messages: ptr.To[int32](0)}),
+				// The header affinity is hashed by Envoy directly.
+				newRule(&aigv1b1.AIGatewayRouteRuleAffinity{Type: aigv1b1.AffinityTypeHeader, HeaderName: ptr.To("x-session-id")}),
+				newRule(nil),
+			},
+		},
+	}}
+
+	const someNamespace = "some-namespace"
+	_, err := c.reconcileFilterConfigSecret(t.Context(), "gw", gwNamespace, someNamespace, routes, nil, "foouuid", nil)
+	require.NoError(t, err)
+
+	fc := requireFilterConfigFromBundle(t, kube, someNamespace, "gw", gwNamespace)
+	require.Equal(t, []filterapi.PromptAffinity{
+		{HeaderName: "x-ai-eg-affinity-prompt-1", Messages: 1},
+		{HeaderName: "x-ai-eg-affinity-prompt-0", Messages: 0},
+	}, fc.PromptAffinities)
+}
+
+func TestGatewayController_reconcileFilterConfigSecret_FallbackPolicy(t *testing.T) {
+	fakeClient := requireNewFakeClientWithIndexes(t)
+	kube := fake2.NewClientset()
+	c := NewGatewayController(fakeClient, kube, ctrl.Log, "envoy-gateway-system",
+		"docker.io/envoyproxy/ai-gateway-extproc:latest", "info", false, nil, true)
+
+	const gwNamespace = "ns"
+	require.NoError(t, fakeClient.Create(t.Context(), &aigv1b1.AIServiceBackend{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backend", Namespace: gwNamespace},
+		Spec: aigv1b1.AIServiceBackendSpec{
+			BackendRef: gwapiv1.BackendObjectReference{Name: "some-backend", Namespace: ptr.To[gwapiv1.Namespace](gwNamespace)},
+		},
+	}))
+	routes := []aigv1b1.AIGatewayRoute{{
+		ObjectMeta: metav1.ObjectMeta{Name: "route1", Namespace: gwNamespace},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				{
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "test-backend"}, {Name: "test-backend", Priority: ptr.To[uint32](1)}},
+					FallbackPolicy: &aigv1b1.AIGatewayRouteRuleFallbackPolicy{
+						Actions: []aigv1b1.AIGatewayRouteRuleFallbackAction{
+							{ErrorClass: aigv1b1.ErrorClassRateLimit, Action: aigv1b1.FallbackActionTypeFailover},
+							{ErrorClass: aigv1b1.ErrorClassContentFilter, Action: aigv1b1.FallbackActionTypeReturn},
+							{ErrorClass: aigv1b1.ErrorClassContextLength, Action: aigv1b1.FallbackActionTypeFailover},
+						},
+					},
+				},
+				{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "test-backend"}}},
+				{
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "test-backend"}, {Name: "test-backend", Priority: ptr.To[uint32](1)}},
+					FallbackPolicy: &aigv1b1.AIGatewayRouteRuleFallbackPolicy{
+						Actions: []aigv1b1.AIGatewayRouteRuleFallbackAction{
+							{ErrorClass: aigv1b1.ErrorClassOverloaded, Action: aigv1b1.FallbackActionTypeRetry},
+							{ErrorClass: aigv1b1.ErrorClassRateLimit, Action: aigv1b1.FallbackActionTypeFailover},
+						},
+					},
+				},
+			},
+		},
+	}}
+
+	const someNamespace = "some-namespace"
+	_, err := c.reconcileFilterConfigSecret(t.Context(), "gw", gwNamespace, someNamespace, routes, nil, "foouuid", nil)
+	require.NoError(t, err)
+
+	fc := requireFilterConfigFromBundle(t, kube, someNamespace, "gw", gwNamespace)
+	require.Len(t, fc.Backends, 5)
+	require.Equal(t, []string{"rate_limit", "context_length_exceeded"}, fc.Backends[0].RetriableErrorClasses)
+	require.Equal(t, []string{"rate_limit", "context_length_exceeded"}, fc.Backends[1].RetriableErrorClasses)
+	require.Nil(t, fc.Backends[0].FailoverErrorClasses)
+	require.Nil(t, fc.Backends[2].RetriableErrorClasses)
+	// The failover classes are only set when the policy also retries on the same priority.
+	for _, b := range fc.Backends[3:] {
+		require.Equal(t, []string{"overloaded", "rate_limit"}, b.RetriableErrorClasses)
+		require.Equal(t, []string{"rate_limit"}, b.FailoverErrorClasses)
+	}
+}
+
+func TestGatewayController_reconcileFilterConfigSecret_HedgePolicy(t *testing.T) {
+	fakeClient := requireNewFakeClientWithIndexes(t)
+	kube := fake2.NewClientset()
+	c := NewGatewayController(fakeClient, kube, ctrl.Log, "envoy-gateway-system",
+		"docker.io/envoyproxy/ai-gateway-extproc:latest", "info", false, nil, true)
+
+	const gwNamespace = "ns"
+	require.NoError(t, fakeClient.Create(t.Context(), &aigv1b1.AIServiceBackend{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backend", Namespace: gwNamespace},
+		Spec: aigv1b1.AIServiceBackendSpec{
+			BackendRef: gwapiv1.BackendObjectReference{Name: "some-backend", Namespace: ptr.To[gwapiv1.Namespace](gwNamespace)},
+		},
+	}))
+	routes := []aigv1b1.AIGatewayRoute{{
+		ObjectMeta: metav1.ObjectMeta{Name: "route1", Namespace: gwNamespace},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				{
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "test-backend"}, {Name: "test-backend"}},
+					HedgePolicy: &aigv1b1.AIGatewayRouteRuleHedgePolicy{Delay: "500ms"},
+				},
+				{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "test-backend"}}},
+			},
+		},
+	}}
+
+	const someNamespace = "some-namespace"
+	_, err := c.reconcileFilterConfigSecret(t.Context(), "gw", gwNamespace, someNamespace, routes, nil, "foouuid", nil)
+	require.NoError(t, err)
+
+	fc := requireFilterConfigFromBundle(t, kube, someNamespace, "gw", gwNamespace)
+	require.Len(t, fc.Backends, 3)
+	require.True(t, fc.Backends[0].Hedging)
+	require.True(t, fc.Backends[1].Hedging)
+	require.False(t, fc.Backends[2].Hedging)
+}
+
+func TestGatewayController_reconcileFilterConfigSecret_Shadows(t *testing.T) {
+	fakeClient := requireNewFakeClientWithIndexes(t)
+	kube := fake2.NewClientset()
+	c := NewGatewayController(fakeClient, kube, ctrl.Log, "envoy-gateway-system",
+		"docker.io/envoyproxy/ai-gateway-extproc:latest", "info", false, nil, true)
+
+	const gwNamespace = "ns"
+	for _, name := range []string{"primary", "shadow"} {
+		require.NoError(t, fakeClient.Create(t.Context(), &aigv1b1.AIServiceBackend{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: gwNamespace},
+			Spec: aigv1b1.AIServiceBackendSpec{
+				BackendRef: gwapiv1.BackendObjectReference{Name: "some-backend", Namespace: ptr.To[gwapiv1.Namespace](gwNamespace)},
+			},
+		}))
+	}
+	routes := []aigv1b1.AIGatewayRoute{{
+		ObjectMeta: metav1.ObjectMeta{Name: "route1", Namespace: gwNamespace},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{{
+				BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "primary"}},
+				Shadows: []aigv1b1.AIGatewayRouteRuleShadow{
+					{Name: "shadow", ModelNameOverride: "shadow-model", CompareResponses: true},
+					{Name: "shadow", Percent: ptr.To[int32](10)},
+				},
+			}},
+		},
+	}}
+
+	const someNamespace = "some-namespace"
+	_, err := c.reconcileFilterConfigSecret(t.Context(), "gw", gwNamespace, someNamespace, routes, nil, "foouuid", nil)
+	require.NoError(t, err)
+
+	fc := requireFilterConfigFromBundle(t, kube, someNamespace, "gw", gwNamespace)
+	require.Len(t, fc.Backends, 3)
+	require.Equal(t, []filterapi.Shadow{
+		{Backend: "ns/shadow/route/route1/rule/0/shadow/0", Percent: 100, CompareResponses: true},
+		{Backend: "ns/shadow/route/route1/rule/0/shadow/1", Percent: 10},
+	}, fc.Backends[0].Shadows)
+	require.Equal(t, "ns/shadow/route/route1/rule/0/shadow/0", fc.Backends[1].Name)
+	require.Equal(t, "shadow-model", fc.Backends[1].ModelNameOverride)
+	require.Equal(t, "ns/shadow/route/route1/rule/0/shadow/1", fc.Backends[2].Name)
+	require.Empty(t, fc.Backends[2].ModelNameOverride)
+}
+
+func TestGatewayController_reconcileFilterConfigSecret_StreamFailover(t *testing.T) {
+	fakeClient := requireNewFakeClientWithIndexes(t)
+	kube := fake2.NewClientset()
+	c := NewGatewayController(fakeClient, kube, ctrl.Log, "envoy-gateway-system",
+		"docker.io/envoyproxy/ai-gateway-extproc:latest", "info", false, nil, true)
+
+	const gwNamespace = "ns"
+	for _, name := range []string{"primary", "failover"} {
+		require.NoError(t, fakeClient.Create(t.Context(), &aigv1b1.AIServiceBackend{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: gwNamespace},
+			Spec: aigv1b1.AIServiceBackendSpec{
+				BackendRef: gwapiv1.BackendObjectReference{Name: "some-backend", Namespace: ptr.To[gwapiv1.Namespace](gwNamespace)},
+			},
+		}))
+	}
+	routes := []aigv1b1.AIGatewayRoute{{
+		ObjectMeta: metav1.ObjectMeta{Name: "route1", Namespace: gwNamespace},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{{
+				BackendRefs:    []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "primary"}},
+				StreamFailover: &aigv1b1.AIGatewayRouteRuleStreamFailover{Name: "failover", ModelNameOverride: "failover-model"},
+			}},
+		},
+	}}
+
+	const someNamespace = "some-namespace"
+	_, err := c.reconcileFilterConfigSecret(t.Context(), "gw", gwNamespace, someNamespace, routes, nil, "foouuid", nil)
+	require.NoError(t, err)
+
+	fc := requireFilterConfigFromBundle(t, kube, someNamespace, "gw", gwNamespace)
+	require.Len(t, fc.Backends, 2)
+	require.Equal(t, "ns/failover/route/route1/rule/0/stream-failover", fc.Backends[0].StreamFailoverBackend)
+	require.Equal(t, "ns/failover/route/route1/rule/0/stream-failover", fc.Backends[1].Name)
+	require.Equal(t, "failover-model", fc.Backends[1].ModelNameOverride)
+}
+
+func TestGatewayController_reconcileFilterConfigSecret_AzureAIInference(t *testing.T) {
+	fakeClient := requireNewFakeClientWithIndexes(t)
+	kube := fake2.NewClientset()
+	c := NewGatewayController(fakeClient, kube, ctrl.Log, "envoy-gateway-system",
+		"docker.io/envoyproxy/ai-gateway-extproc:latest", "info", false, nil, true)
+
+	const gwNamespace = "ns"
+	bsps := []aigv1b1.BackendSecurityPolicySpec{
+		{
+			Type:        aigv1b1.BackendSecurityPolicyTypeAzureAPIKey,
+			AzureAPIKey: &aigv1b1.BackendSecurityPolicyAzureAPIKey{SecretRef: &gwapiv1.SecretObjectReference{Name: "api-key-secret"}},
+		},
+		{
+			Type:             aigv1b1.BackendSecurityPolicyTypeAzureCredentials,
+			AzureCredentials: &aigv1b1.BackendSecurityPolicyAzureCredentials{},
+		},
+		{
+			Type:           aigv1b1.BackendSecurityPolicyTypeAWSCredentials,
+			AWSCredentials: &aigv1b1.BackendSecurityPolicyAWSCredentials{Region: "us-east-1"},
+		},
+	}
+	var backendRefs []aigv1b1.AIGatewayRouteRuleBackendRef
+	for i, spec := range bsps {
+		name := fmt.Sprintf("foundry-%d", i)
+		require.NoError(t, fakeClient.Create(t.Context(), &aigv1b1.AIServiceBackend{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: gwNamespace},
+			Spec: aigv1b1.AIServiceBackendSpec{
+				APISchema:  aigv1b1.VersionedAPISchema{Name: aigv1b1.APISchemaAzureAIInference, Version: ptr.To("2024-05-01-preview")},
+				BackendRef: gwapiv1.BackendObjectReference{Name: "some-backend", Namespace: ptr.To[gwapiv1.Namespace](gwNamespace)},
+			},
+		}))
+		spec.TargetRefs = []gwapiv1a2.LocalPolicyTargetReference{
+			{Group: aiServiceBackendGroup, Kind: aiServiceBackendKind, Name: gwapiv1.ObjectName(name)},
+		}
+		require.NoError(t, fakeClient.Create(t.Context(), &aigv1b1.BackendSecurityPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: gwNamespace},
+			Spec:       spec,
+		}))
+		backendRefs = append(backendRefs, aigv1b1.AIGatewayRouteRuleBackendRef{Name: name})
+	}
+	for _, s := range []*corev1.Secret{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-key-secret", Namespace: gwNamespace},
+			StringData: map[string]string{apiKeyInSecret: "thisisapikey"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: rotators.GetBSPSecretName("foundry-1"), Namespace: gwNamespace},
+			StringData: map[string]string{rotators.AzureAccessTokenKey: "thisisazurecredentials"},
+		},
+	} {
+		_, err := kube.CoreV1().Secrets(gwNamespace).Create(t.Context(), s, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	routes := []aigv1b1.AIGatewayRoute{{
+		ObjectMeta: metav1.ObjectMeta{Name: "route1", Namespace: gwNamespace},
+		Spec:       aigv1b1.AIGatewayRouteSpec{Rules: []aigv1b1.AIGatewayRouteRule{{BackendRefs: backendRefs}}},
+	}}
+
+	const someNamespace = "some-namespace"
+	_, err := c.reconcileFilterConfigSecret(t.Context(), "gw", gwNamespace, someNamespace, routes, nil, "foouuid", nil)
+	require.NoError(t, err)
+
+	// The API key is sent in the api-key header and the Entra ID token as the bearer token, while the backend with the
+	// AWS credentials, which the schema does not support, is skipped.
+	fc := requireFilterConfigFromBundle(t, kube, someNamespace, "gw", gwNamespace)
+	require.Len(t, fc.Backends, 2)
+	require.Equal(t, "ns/foundry-0/route/route1/rule/0/ref/0", fc.Backends[0].Name)
+	require.Equal(t, filterapi.APISchemaAzureAIInference, fc.Backends[0].Schema.Name)
+	require.Equal(t, &filterapi.BackendAuth{AzureAPIKey: &filterapi.AzureAPIKeyAuth{Key: "thisisapikey"}}, fc.Backends[0].Auth)
+	require.Equal(t, "ns/foundry-1/route/route1/rule/0/ref/1", fc.Backends[1].Name)
+	require.Equal(t, &filterapi.BackendAuth{AzureAuth: &filterapi.AzureAuth{AccessToken: "thisisazurecredentials"}}, fc.Backends[1].Auth)
+}
+
+func TestGatewayController_reconcileFilterConfigSecret_ModelAliases(t *testing.T) {
+	fakeClient := requireNewFakeClientWithIndexes(t)
+	kube := fake2.NewClientset()
+	c := NewGatewayController(fakeClient, kube, ctrl.Log, "envoy-gateway-system",
+		"docker.io/envoyproxy/ai-gateway-extproc:latest", "info", false, nil, true)
+
+	const gwNamespace = "ns"
+	for _, modelAlias := range []*aigv1a1.ModelAlias{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: gwNamespace},
+			Spec: aigv1a1.ModelAliasSpec{
+				Alias:        "chat-default",
+				Models:       []aigv1a1.ModelAliasModel{{Name: "gpt-4o"}, {Name: "unused", Weight: ptr.To[int32](0)}},
+				Rollout:      &aigv1a1.ModelAliasRollout{Model: "gpt-5", Percent: 10},
+				StickyHeader: ptr.To("X-User-ID"),
+			},
+		},
+		// The first ModelAlias by name takes precedence.
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: gwNamespace},
+			Spec:       aigv1a1.ModelAliasSpec{Alias: "chat-default", Models: []aigv1a1.ModelAliasModel{{Name: "gpt-4o-mini"}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: gwNamespace},
+			Spec:       aigv1a1.ModelAliasSpec{Alias: "chat-fast", Models: []aigv1a1.ModelAliasModel{{Name: "gpt-4o-mini"}}},
+		},
+		// Invalid ModelAliases are skipped.
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "d", Namespace: gwNamespace},
+			Spec:       aigv1a1.ModelAliasSpec{Alias: "invalid", Models: []aigv1a1.ModelAliasModel{{Name: "gpt-4o", Weight: ptr.To[int32](0)}}},
+		},
+		// ModelAliases in the other namespaces are not included.
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "e", Namespace: "other"},
+			Spec:       aigv1a1.ModelAliasSpec{Alias: "other", Models: []aigv1a1.ModelAliasModel{{Name: "gpt-4o"}}},
+		},
+	} {
+		require.NoError(t, fakeClient.Create(t.Context(), modelAlias))
+	}
+	routes := []aigv1b1.AIGatewayRoute{{
+		ObjectMeta: metav1.ObjectMeta{Name: "route1", Namespace: gwNamespace},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{{
+				Matches: []aigv1b1.AIGatewayRouteRuleMatch{{Headers: []gwapiv1.HTTPHeaderMatch{
+					{Name: internalapi.ModelNameHeaderKeyDefault, Value: "gpt-4o"},
+				}}},
+			}},
+		},
+	}}
+
+	const someNamespace = "some-namespace"
+	_, err := c.reconcileFilterConfigSecret(t.Context(), "gw", gwNamespace, someNamespace, routes, nil, "foouuid", nil)
+	require.NoError(t, err)
+
+	fc := requireFilterConfigFromBundle(t, kube, someNamespace, "gw", gwNamespace)
+	require.Equal(t, []filterapi.ModelAlias{
+		{Name: "chat-default", Models: []filterapi.ModelAliasModel{{Name: "gpt-4o-mini", Weight: 1}}},
+		{Name: "chat-fast", Models: []filterapi.ModelAliasModel{{Name: "gpt-4o-mini", Weight: 1}}},
+	}, fc.ModelAliases)
+	var models []string
+	for _, m := range fc.Models {
+		models = append(models, m.Name)
+	}
+	require.Equal(t, []string{"gpt-4o", "chat-default", "chat-fast"}, models)
+
+	// The rollout and the sticky header are converted once the precedent ModelAlias is deleted.
+	require.NoError(t, fakeClient.Delete(t.Context(), &aigv1a1.ModelAlias{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: gwNamespace}}))
+	_, err = c.reconcileFilterConfigSecret(t.Context(), "gw", gwNamespace, someNamespace, routes, nil, "baruuid", nil)
+	require.NoError(t, err)
+	fc = requireFilterConfigFromBundle(t, kube, someNamespace, "gw", gwNamespace)
+	require.Equal(t, filterapi.ModelAlias{
+		Name:         "chat-default",
+		Models:       []filterapi.ModelAliasModel{{Name: "gpt-4o", Weight: 1}},
+		Rollout:      &filterapi.ModelAliasRollout{Model: "gpt-5", Percent: 10},
+		StickyHeader: "x-user-id",
+	}, fc.ModelAliases[0])
+}
+
 func TestGatewayController_reconcileFilterConfigSecret_SkipsDeletedRoutes(t *testing.T) {
 	fakeClient := requireNewFakeClientWithIndexes(t)
 	kube := fake2.NewClientset()
@@ -2796,6 +3303,342 @@ func Test_bodyMutationToFilterAPI(t *testing.T) {
 	}
 }
 
+func Test_promptCachingToFilterAPI(t *testing.T) {
+	require.Nil(t, promptCachingToFilterAPI(nil))
+	require.Equal(t, &filterapi.PromptCaching{System: true, Tools: true, LastTurns: 2, TTL: "1h"},
+		promptCachingToFilterAPI(&aigv1b1.PromptCaching{System: true, Tools: true, LastTurns: 2, TTL: "1h"}))
+}
+
+func Test_circuitBreakerToFilterAPI(t *testing.T) {
+	require.Nil(t, circuitBreakerToFilterAPI(nil))
+	require.Equal(t, &filterapi.CircuitBreaker{ConsecutiveFailures: 5, OpenDuration: 30 * time.Second},
+		circuitBreakerToFilterAPI(&aigv1b1.CircuitBreaker{}))
+	require.Equal(t, &filterapi.CircuitBreaker{ConsecutiveFailures: 3, TimeToFirstToken: 2 * time.Second, OpenDuration: time.Minute},
+		circuitBreakerToFilterAPI(&aigv1b1.CircuitBreaker{
+			ConsecutiveFailures: ptr.To[int32](3),
+			TimeToFirstToken:    ptr.To[gwapiv1.Duration]("2s"),
+			OpenDuration:        ptr.To[gwapiv1.Duration]("1m"),
+		}))
+}
+
+func Test_responseCacheToFilterAPI(t *testing.T) {
+	route := &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "route", Namespace: "ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{Rules: []aigv1b1.AIGatewayRouteRule{
+			{},
+			{ResponseCache: &aigv1b1.AIGatewayRouteRuleResponseCache{}},
+			{ResponseCache: &aigv1b1.AIGatewayRouteRuleResponseCache{TTL: ptr.To[gwapiv1.Duration]("10m")}},
+			{ResponseCache: &aigv1b1.AIGatewayRouteRuleResponseCache{
+				Semantic: &aigv1b1.AIGatewayRouteRuleSemanticCache{EmbeddingsBackend: "embeddings", EmbeddingsModel: "text-embedding-3-small"},
+			}},
+			{ResponseCache: &aigv1b1.AIGatewayRouteRuleResponseCache{
+				Semantic: &aigv1b1.AIGatewayRouteRuleSemanticCache{EmbeddingsBackend: "embeddings", SimilarityThreshold: ptr.To[int32](90)},
+			}},
+		}},
+	}
+	require.Nil(t, responseCacheToFilterAPI(route, 0))
+	require.Equal(t, &filterapi.ResponseCache{TTL: time.Hour}, responseCacheToFilterAPI(route, 1))
+	require.Equal(t, &filterapi.ResponseCache{TTL: 10 * time.Minute}, responseCacheToFilterAPI(route, 2))
+	require.Equal(t, &filterapi.ResponseCache{TTL: time.Hour, Semantic: &filterapi.SemanticCache{
+		EmbeddingsBackend:   "ns/embeddings/route/route/rule/3/semantic-cache",
+		SimilarityThreshold: 0.95,
+	}}, responseCacheToFilterAPI(route, 3))
+	require.Equal(t, 0.9, responseCacheToFilterAPI(route, 4).Semantic.SimilarityThreshold)
+}
+
+func Test_guardrailsToFilterAPI(t *testing.T) {
+	route := &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "route", Namespace: "ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{Rules: []aigv1b1.AIGatewayRouteRule{
+			{},
+			{Guardrails: &aigv1b1.AIGatewayRouteRuleGuardrails{PII: &aigv1b1.AIGatewayRouteRulePIIGuardrail{}}},
+			{Guardrails: &aigv1b1.AIGatewayRouteRuleGuardrails{PII: &aigv1b1.AIGatewayRouteRulePIIGuardrail{
+				Action:   aigv1b1.PIIGuardrailActionTokenize,
+				Entities: []aigv1b1.PIIEntity{aigv1b1.PIIEntityEmail},
+				Patterns: []aigv1b1.AIGatewayRouteRulePIIPattern{{Name: "employee_id", Regex: `EMP-\d{6}`}},
+			}}},
+			{Guardrails: &aigv1b1.AIGatewayRouteRuleGuardrails{PII: &aigv1b1.AIGatewayRouteRulePIIGuardrail{
+				Patterns: []aigv1b1.AIGatewayRouteRulePIIPattern{{Name: "bad", Regex: "("}},
+			}}},
+			{Guardrails: &aigv1b1.AIGatewayRouteRuleGuardrails{External: &aigv1b1.AIGatewayRouteRuleExternalGuardrail{
+				Endpoint: "http://classifier.safety:8080/check",
+			}}},
+			{Guardrails: &aigv1b1.AIGatewayRouteRuleGuardrails{External: &aigv1b1.AIGatewayRouteRuleExternalGuardrail{
+				Protocol:    aigv1b1.ExternalGuardrailProtocolGRPC,
+				Endpoint:    "classifier.safety:9090",
+				Timeout:     ptr.To[gwapiv1.Duration]("250ms"),
+				FailureMode: aigv1b1.ExternalGuardrailFailureModeFailOpen,
+				Response:    &aigv1b1.AIGatewayRouteRuleExternalGuardrailResponse{},
+			}}},
+			{Guardrails: &aigv1b1.AIGatewayRouteRuleGuardrails{External: &aigv1b1.AIGatewayRouteRuleExternalGuardrail{
+				Endpoint: "classifier.safety:8080",
+			}}},
+			{Guardrails: &aigv1b1.AIGatewayRouteRuleGuardrails{PromptInjection: &aigv1b1.AIGatewayRouteRulePromptInjectionGuardrail{}}},
+			{Guardrails: &aigv1b1.AIGatewayRouteRuleGuardrails{PromptInjection: &aigv1b1.AIGatewayRouteRulePromptInjectionGuardrail{
+				Mode:      aigv1b1.PromptInjectionGuardrailModeShadow,
+				Rules:     []aigv1b1.PromptInjectionRule{aigv1b1.PromptInjectionRuleHiddenUnicode},
+				Threshold: ptr.To[int32](80),
+				Patterns: []aigv1b1.AIGatewayRouteRulePromptInjectionPattern{
+					{Name: "exfiltration", Regex: `send .* to https?://`},
+					{Name: "secrets", Regex: `(?i)print the api keys`, Score: ptr.To[int32](90)},
+				},
+			}}},
+			{Guardrails: &aigv1b1.AIGatewayRouteRuleGuardrails{PromptInjection: &aigv1b1.AIGatewayRouteRulePromptInjectionGuardrail{
+				Patterns: []aigv1b1.AIGatewayRouteRulePromptInjectionPattern{{Name: "bad", Regex: "("}},
+			}}},
+			{Guardrails: &aigv1b1.AIGatewayRouteRuleGuardrails{Output: &aigv1b1.AIGatewayRouteRuleOutputGuardrail{
+				Rules: []aigv1b1.AIGatewayRouteRuleOutputFilterRule{{Name: "codename", Keywords: []string{"Project Orion"}}},
+			}}},
+			{Guardrails: &aigv1b1.AIGatewayRouteRuleGuardrails{Output: &aigv1b1.AIGatewayRouteRuleOutputGuardrail{
+				Mode:       aigv1b1.OutputGuardrailModeShadow,
+				WindowSize: ptr.To[int32](64),
+				Rules:      []aigv1b1.AIGatewayRouteRuleOutputFilterRule{{Name: "card", Regex: ptr.To(`\d{16}`)}},
+			}}},
+			{Guardrails: &aigv1b1.AIGatewayRouteRuleGuardrails{Output: &aigv1b1.AIGatewayRouteRuleOutputGuardrail{
+				Rules: []aigv1b1.AIGatewayRouteRuleOutputFilterRule{{Name: "bad", Regex: ptr.To("(")}},
+			}}},
+		}},
+	}
+	g, err := guardrailsToFilterAPI(route, 0)
+	require.NoError(t, err)
+	require.Nil(t, g)
+
+	g, err = guardrailsToFilterAPI(route, 1)
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.Guardrails{PII: &filterapi.PIIGuardrail{
+		Action:   filterapi.PIIGuardrailActionMask,
+		Entities: []string{"Email", "PhoneNumber", "CreditCard", "NationalID"},
+	}}, g)
+
+	g, err = guardrailsToFilterAPI(route, 2)
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.Guardrails{PII: &filterapi.PIIGuardrail{
+		Action:   filterapi.PIIGuardrailActionTokenize,
+		Entities: []string{"Email"},
+		Patterns: []filterapi.PIIPattern{{Name: "employee_id", Regex: `EMP-\d{6}`}},
+	}}, g)
+
+	_, err = guardrailsToFilterAPI(route, 3)
+	require.ErrorContains(t, err, `invalid regex of the PII pattern "bad"`)
+
+	g, err = guardrailsToFilterAPI(route, 4)
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.Guardrails{External: &filterapi.ExternalGuardrail{
+		Protocol: "HTTP", Endpoint: "http://classifier.safety:8080/check", Timeout: time.Second,
+	}}, g)
+
+	g, err = guardrailsToFilterAPI(route, 5)
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.Guardrails{External: &filterapi.ExternalGuardrail{
+		Protocol: "GRPC", Endpoint: "classifier.safety:9090", Timeout: 250 * time.Millisecond, FailOpen: true,
+		ResponseWindowSize: 500,
+	}}, g)
+
+	_, err = guardrailsToFilterAPI(route, 6)
+	require.ErrorContains(t, err, `invalid URL of the external guardrail "classifier.safety:8080"`)
+
+	g, err = guardrailsToFilterAPI(route, 7)
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.Guardrails{PromptInjection: &filterapi.PromptInjectionGuardrail{
+		Rules: []string{
+			"IgnoreInstructions", "RoleOverride", "SystemPromptExtraction", "DelimiterInjection", "HiddenUnicode", "EncodedPayload",
+		},
+		Threshold: 50,
+	}}, g)
+
+	g, err = guardrailsToFilterAPI(route, 8)
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.Guardrails{PromptInjection: &filterapi.PromptInjectionGuardrail{
+		Shadow: true, Rules: []string{"HiddenUnicode"}, Threshold: 80,
+		Patterns: []filterapi.PromptInjectionPattern{
+			{Name: "exfiltration", Regex: `send .* to https?://`, Score: 50},
+			{Name: "secrets", Regex: `(?i)print the api keys`, Score: 90},
+		},
+	}}, g)
+
+	_, err = guardrailsToFilterAPI(route, 9)
+	require.ErrorContains(t, err, `invalid regex of the prompt injection pattern "bad"`)
+
+	g, err = guardrailsToFilterAPI(route, 10)
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.Guardrails{Output: &filterapi.OutputGuardrail{
+		Rules: []filterapi.OutputFilterRule{{Name: "codename", Keywords: []string{"Project Orion"}}}, WindowSize: 256,
+	}}, g)
+
+	g, err = guardrailsToFilterAPI(route, 11)
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.Guardrails{Output: &filterapi.OutputGuardrail{
+		Shadow: true, Rules: []filterapi.OutputFilterRule{{Name: "card", Regex: `\d{16}`}}, WindowSize: 64,
+	}}, g)
+
+	_, err = guardrailsToFilterAPI(route, 12)
+	require.ErrorContains(t, err, `invalid regex of the output filter rule "bad"`)
+}
+
+func Test_toolPolicyToFilterAPI(t *testing.T) {
+	route := &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "route", Namespace: "ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{Rules: []aigv1b1.AIGatewayRouteRule{
+			{},
+			{ToolPolicy: &aigv1b1.AIGatewayRouteRuleToolPolicy{Deny: []string{"delete_user"}}},
+			{ToolPolicy: &aigv1b1.AIGatewayRouteRuleToolPolicy{
+				Action:             aigv1b1.ToolPolicyActionStrip,
+				Allow:              []string{"get_weather"},
+				AllowRegex:         []string{"^crm_"},
+				DenyRegex:          []string{"(?i)delete"},
+				MaxTools:           ptr.To[int32](8),
+				MaxSchemaBytes:     ptr.To[int32](4096),
+				RemoveBuiltInTools: true,
+			}},
+			{ToolPolicy: &aigv1b1.AIGatewayRouteRuleToolPolicy{DenyRegex: []string{"("}}},
+		}},
+	}
+
+	tp, err := toolPolicyToFilterAPI(route, 0)
+	require.NoError(t, err)
+	require.Nil(t, tp)
+
+	tp, err = toolPolicyToFilterAPI(route, 1)
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.ToolPolicy{Deny: []string{"delete_user"}}, tp)
+
+	tp, err = toolPolicyToFilterAPI(route, 2)
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.ToolPolicy{
+		Strip: true, Allow: []string{"get_weather"}, AllowRegex: []string{"^crm_"}, DenyRegex: []string{"(?i)delete"},
+		MaxTools: 8, MaxSchemaBytes: 4096, RemoveBuiltInTools: true,
+	}, tp)
+
+	_, err = toolPolicyToFilterAPI(route, 3)
+	require.ErrorContains(t, err, `invalid tool policy: invalid regex "("`)
+}
+
+func Test_promptPolicyToFilterAPI(t *testing.T) {
+	route := &aigv1b1.PromptPolicy{Messages: []aigv1b1.PromptPolicyMessage{
+		{Template: "Follow the policies of Acme."},
+		{Role: aigv1b1.PromptPolicyRoleDeveloper, Position: aigv1b1.PromptPolicyPositionAppend, Template: `Tenant: {{ index .Headers "x-tenant-id" }}`},
+	}}
+	backend := &aigv1b1.PromptPolicy{
+		Mode:     aigv1b1.PromptPolicyModeLock,
+		Messages: []aigv1b1.PromptPolicyMessage{{Position: aigv1b1.PromptPolicyPositionPrepend, Template: "Answer in Markdown."}},
+	}
+
+	p, err := promptPolicyToFilterAPI(nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, p)
+
+	p, err = promptPolicyToFilterAPI(route, nil)
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.PromptPolicy{Messages: []filterapi.PromptPolicyMessage{
+		{Template: "Follow the policies of Acme."},
+		{Developer: true, Append: true, Template: `Tenant: {{ index .Headers "x-tenant-id" }}`},
+	}}, p)
+
+	// The messages of the backend follow the ones of the route rule, and the backend locks the client messages.
+	p, err = promptPolicyToFilterAPI(route, backend)
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.PromptPolicy{Lock: true, Messages: []filterapi.PromptPolicyMessage{
+		{Template: "Follow the policies of Acme."},
+		{Developer: true, Append: true, Template: `Tenant: {{ index .Headers "x-tenant-id" }}`},
+		{Template: "Answer in Markdown."},
+	}}, p)
+
+	p, err = promptPolicyToFilterAPI(nil, &aigv1b1.PromptPolicy{Mode: aigv1b1.PromptPolicyModeLock})
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.PromptPolicy{Lock: true}, p)
+
+	_, err = promptPolicyToFilterAPI(&aigv1b1.PromptPolicy{Messages: []aigv1b1.PromptPolicyMessage{{Template: "{{ .Headers"}}}, nil)
+	require.ErrorContains(t, err, "invalid template of the prompt policy message 0")
+}
+
+func Test_parameterPolicyToFilterAPI(t *testing.T) {
+	route := &aigv1b1.ParameterPolicy{Rules: []aigv1b1.ParameterPolicyRule{
+		{Name: "cap-max-tokens", Parameter: aigv1b1.ParameterPolicyParameterMaxTokens, Default: ptr.To("1024"), Max: ptr.To("8192")},
+		{Name: "block-n", Parameter: aigv1b1.ParameterPolicyParameterN, Action: aigv1b1.ParameterPolicyActionReject},
+	}, BlockedFields: []string{"logit_bias"}}
+	backend := &aigv1b1.ParameterPolicy{Rules: []aigv1b1.ParameterPolicyRule{
+		{Name: "cap-effort", Parameter: aigv1b1.ParameterPolicyParameterReasoningEffort, Action: aigv1b1.ParameterPolicyActionClamp, Max: ptr.To("medium")},
+	}, BlockedFields: []string{"metadata.user_id", "logit_bias"}}
+
+	p, err := parameterPolicyToFilterAPI(nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, p)
+
+	// The rules of the backend follow the ones of the route rule, and the blocked fields are merged.
+	p, err = parameterPolicyToFilterAPI(route, backend)
+	require.NoError(t, err)
+	require.Equal(t, &filterapi.ParameterPolicy{Rules: []filterapi.ParameterPolicyRule{
+		{Name: "cap-max-tokens", Parameter: "MaxTokens", Action: "Clamp", Default: "1024", Max: "8192"},
+		{Name: "block-n", Parameter: "N", Action: "Reject"},
+		{Name: "cap-effort", Parameter: "ReasoningEffort", Action: "Clamp", Max: "medium"},
+	}, BlockedFields: []string{"logit_bias", "metadata.user_id"}}, p)
+
+	_, err = parameterPolicyToFilterAPI(nil, &aigv1b1.ParameterPolicy{Rules: []aigv1b1.ParameterPolicyRule{
+		{Name: "bad", Parameter: aigv1b1.ParameterPolicyParameterMaxTokens, Max: ptr.To("1.5")},
+	}})
+	require.ErrorContains(t, err, `invalid parameter policy rule "bad"`)
+}
+
+func Test_backendSelectionToFilterAPI(t *testing.T) {
+	route := &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "route", Namespace: "ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{Rules: []aigv1b1.AIGatewayRouteRule{
+			{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "a"}}},
+			{
+				BackendSelection: &aigv1b1.AIGatewayRouteRuleBackendSelection{Objective: aigv1b1.BackendSelectionObjectiveFastest},
+				BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{
+					{Name: "a"},
+					// Only the backends of the lowest priority are the candidates.
+					{Name: "b", Priority: ptr.To[uint32](1)},
+					// The disabled backends are not the candidates.
+					{Name: "c", Weight: ptr.To[int32](0)},
+					{Name: "d", Priority: ptr.To[uint32](0)},
+				},
+			},
+			{
+				BackendSelection: &aigv1b1.AIGatewayRouteRuleBackendSelection{Objective: aigv1b1.BackendSelectionObjectiveCheapest},
+				BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{
+					{Name: "a", Price: &aigv1b1.BackendPrice{InputTokens: "0.15", OutputTokens: "0.6"}},
+				},
+			},
+			{
+				BackendSelection: &aigv1b1.AIGatewayRouteRuleBackendSelection{
+					Objective:     aigv1b1.BackendSelectionObjectiveWeighted,
+					LatencyWeight: ptr.To[int32](3),
+				},
+				BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{
+					{Name: "a", Price: &aigv1b1.BackendPrice{InputTokens: "3", OutputTokens: "15"}},
+				},
+			},
+		}},
+	}
+
+	require.Nil(t, backendSelectionToFilterAPI(route, 0))
+	require.Equal(t, &filterapi.BackendSelection{
+		HeaderName:    internalapi.BackendSelectionHeaderName("ns", "route", 1),
+		LatencyWeight: 1,
+		Candidates: []filterapi.BackendSelectionCandidate{
+			{Backend: internalapi.PerRouteRuleRefBackendName("ns", "a", "route", 1, 0), RefIndex: 0},
+			{Backend: internalapi.PerRouteRuleRefBackendName("ns", "d", "route", 1, 3), RefIndex: 3},
+		},
+	}, backendSelectionToFilterAPI(route, 1))
+	require.Equal(t, &filterapi.BackendSelection{
+		HeaderName: internalapi.BackendSelectionHeaderName("ns", "route", 2),
+		CostWeight: 1,
+		Candidates: []filterapi.BackendSelectionCandidate{
+			{Backend: internalapi.PerRouteRuleRefBackendName("ns", "a", "route", 2, 0), InputTokenPrice: 0.15, OutputTokenPrice: 0.6},
+		},
+	}, backendSelectionToFilterAPI(route, 2))
+	require.Equal(t, &filterapi.BackendSelection{
+		HeaderName:    internalapi.BackendSelectionHeaderName("ns", "route", 3),
+		LatencyWeight: 3,
+		CostWeight:    1,
+		Candidates: []filterapi.BackendSelectionCandidate{
+			{Backend: internalapi.PerRouteRuleRefBackendName("ns", "a", "route", 3, 0), InputTokenPrice: 3, OutputTokenPrice: 15},
+		},
+	}, backendSelectionToFilterAPI(route, 3))
+}
+
 // TestGatewayController_reconcileFilterConfigSecret_GlobalDefaults tests that
 // global LLM request costs from GatewayConfig are properly included in the filter config
 // when no routes override them.
@@ -0,0 +1,176 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aigv1a1 "github.com/envoyproxy/ai-gateway/api/v1alpha1"
+	aigv1b1 "github.com/envoyproxy/ai-gateway/api/v1beta1"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+)
+
+// ModelAliasController implements [reconcile.TypedReconciler] for [aigv1a1.ModelAlias].
+//
+// This validates the ModelAlias, reports its effective mapping in the status, and notifies the AIGatewayRoutes in
+// the same namespace of changes, so that the filter configuration of their Gateways is updated.
+//
+// Exported for testing purposes.
+type ModelAliasController struct {
+	client             client.Client
+	logger             logr.Logger
+	aiGatewayRouteChan chan event.GenericEvent
+}
+
+// NewModelAliasController creates a new reconcile.TypedReconciler[reconcile.Request] for the ModelAlias resource.
+func NewModelAliasController(
+	client client.Client,
+	logger logr.Logger,
+	aiGatewayRouteChan chan event.GenericEvent,
+) *ModelAliasController {
+	return &ModelAliasController{
+		client:             client,
+		logger:             logger,
+		aiGatewayRouteChan: aiGatewayRouteChan,
+	}
+}
+
+// Reconcile implements [reconcile.TypedReconciler] for [aigv1a1.ModelAlias].
+func (c *ModelAliasController) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	var modelAlias aigv1a1.ModelAlias
+	if err := c.client.Get(ctx, req.NamespacedName, &modelAlias); err != nil {
+		if apierrors.IsNotFound(err) {
+			c.logger.Info("Deleting ModelAlias", "namespace", req.Namespace, "name", req.Name)
+			c.notifyAIGatewayRoutes(ctx, req.Namespace)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	c.logger.Info("Reconciling ModelAlias", "namespace", req.Namespace, "name", req.Name)
+
+	// The routes are notified in both cases, since an invalid ModelAlias is removed from the filter configuration.
+	defer c.notifyAIGatewayRoutes(ctx, req.Namespace)
+	shares, err := modelAliasShares(&modelAlias.Spec)
+	if err != nil {
+		c.logger.Error(err, "invalid ModelAlias", "namespace", req.Namespace, "name", req.Name)
+		c.updateModelAliasStatus(ctx, &modelAlias, nil, aigv1a1.ConditionTypeNotAccepted, err.Error())
+		// Retrying does not help until the ModelAlias is updated, which triggers a new reconciliation.
+		return ctrl.Result{}, nil
+	}
+	c.updateModelAliasStatus(ctx, &modelAlias, shares, aigv1a1.ConditionTypeAccepted, "ModelAlias reconciled successfully")
+	return ctrl.Result{}, nil
+}
+
+// notifyAIGatewayRoutes sends events for all AIGatewayRoutes in the given namespace, whose Gateways include the
+// ModelAliases of the namespace in their filter configuration.
+func (c *ModelAliasController) notifyAIGatewayRoutes(ctx context.Context, namespace string) {
+	var aiGatewayRoutes aigv1b1.AIGatewayRouteList
+	if err := c.client.List(ctx, &aiGatewayRoutes, client.InNamespace(namespace)); err != nil {
+		c.logger.Error(err, "failed to list AIGatewayRoutes in namespace", "namespace", namespace)
+		return
+	}
+	for i := range aiGatewayRoutes.Items {
+		route := &aiGatewayRoutes.Items[i]
+		c.logger.Info("notifying AIGatewayRoute of ModelAlias change", "route", route.Name, "namespace", route.Namespace)
+		c.aiGatewayRouteChan <- event.GenericEvent{Object: route}
+	}
+}
+
+// updateModelAliasStatus updates the status of the ModelAlias.
+func (c *ModelAliasController) updateModelAliasStatus(ctx context.Context, modelAlias *aigv1a1.ModelAlias,
+	shares []aigv1a1.ModelAliasModelStatus, conditionType string, message string,
+) {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := c.client.Get(ctx, client.ObjectKey{Name: modelAlias.Name, Namespace: modelAlias.Namespace}, modelAlias); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		modelAlias.Status.Models = shares
+		modelAlias.Status.Conditions = newConditions(conditionType, message)
+		return c.client.Status().Update(ctx, modelAlias)
+	})
+	if err != nil {
+		c.logger.Error(err, "failed to update ModelAlias status", "namespace", modelAlias.Namespace, "name", modelAlias.Name)
+	}
+}
+
+// modelAliasShares validates the ModelAlias, and returns the percentage of the requests for the alias resolved to
+// each concrete model in the order of the models followed by the rollout. The models without share are omitted.
+func modelAliasShares(spec *aigv1a1.ModelAliasSpec) ([]aigv1a1.ModelAliasModelStatus, error) {
+	var totalWeight int64
+	for _, m := range spec.Models {
+		totalWeight += int64(ptr.Deref(m.Weight, 1))
+	}
+	var rolloutPercent float64
+	if spec.Rollout != nil {
+		rolloutPercent = float64(spec.Rollout.Percent)
+	}
+	if totalWeight == 0 && rolloutPercent < 100 {
+		return nil, errors.New("at least one model must have a positive weight unless the rollout is complete")
+	}
+
+	shares := map[string]float64{}
+	var names []string
+	addShare := func(name string, percent float64) {
+		if percent <= 0 {
+			return
+		}
+		if _, ok := shares[name]; !ok {
+			names = append(names, name)
+		}
+		shares[name] += percent
+	}
+	for _, m := range spec.Models {
+		if totalWeight > 0 {
+			addShare(m.Name, (100-rolloutPercent)*float64(ptr.Deref(m.Weight, 1))/float64(totalWeight))
+		}
+	}
+	if spec.Rollout != nil {
+		addShare(spec.Rollout.Model, rolloutPercent)
+	}
+
+	ret := make([]aigv1a1.ModelAliasModelStatus, 0, len(names))
+	for _, name := range names {
+		ret = append(ret, aigv1a1.ModelAliasModelStatus{
+			Name:    name,
+			Percent: strconv.FormatFloat(math.Round(shares[name]*100)/100, 'f', -1, 64),
+		})
+	}
+	return ret, nil
+}
+
+// modelAliasToFilterAPI converts a valid ModelAlias to the filter configuration.
+func modelAliasToFilterAPI(modelAlias *aigv1a1.ModelAlias) filterapi.ModelAlias {
+	spec := &modelAlias.Spec
+	ret := filterapi.ModelAlias{
+		Name:         spec.Alias,
+		StickyHeader: strings.ToLower(ptr.Deref(spec.StickyHeader, "")),
+	}
+	for _, m := range spec.Models {
+		if w := ptr.Deref(m.Weight, 1); w > 0 {
+			ret.Models = append(ret.Models, filterapi.ModelAliasModel{Name: m.Name, Weight: w})
+		}
+	}
+	if spec.Rollout != nil && spec.Rollout.Percent > 0 {
+		ret.Rollout = &filterapi.ModelAliasRollout{Model: spec.Rollout.Model, Percent: spec.Rollout.Percent}
+	}
+	return ret
+}
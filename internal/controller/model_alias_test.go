@@ -0,0 +1,126 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aigv1a1 "github.com/envoyproxy/ai-gateway/api/v1alpha1"
+	aigv1b1 "github.com/envoyproxy/ai-gateway/api/v1beta1"
+	internaltesting "github.com/envoyproxy/ai-gateway/internal/testing"
+)
+
+func TestModelAliasController_Reconcile(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().WithScheme(Scheme).WithStatusSubresource(&aigv1a1.ModelAlias{}).Build()
+	eventCh := internaltesting.NewControllerEventChan[*aigv1b1.AIGatewayRoute]()
+	c := NewModelAliasController(fakeClient, ctrl.Log, eventCh.Ch)
+
+	const namespace = "default"
+	for _, route := range []*aigv1b1.AIGatewayRoute{
+		{ObjectMeta: metav1.ObjectMeta{Name: "route1", Namespace: namespace}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "route2", Namespace: "other"}},
+	} {
+		require.NoError(t, fakeClient.Create(t.Context(), route))
+	}
+	modelAlias := &aigv1a1.ModelAlias{
+		ObjectMeta: metav1.ObjectMeta{Name: "chat-default", Namespace: namespace},
+		Spec: aigv1a1.ModelAliasSpec{
+			Alias:   "chat-default",
+			Models:  []aigv1a1.ModelAliasModel{{Name: "gpt-4o", Weight: ptr.To[int32](2)}, {Name: "claude", Weight: ptr.To[int32](1)}},
+			Rollout: &aigv1a1.ModelAliasRollout{Model: "gpt-5", Percent: 10},
+		},
+	}
+	require.NoError(t, fakeClient.Create(t.Context(), modelAlias))
+
+	key := types.NamespacedName{Namespace: namespace, Name: "chat-default"}
+	res, err := c.Reconcile(t.Context(), reconcile.Request{NamespacedName: key})
+	require.NoError(t, err)
+	require.Equal(t, ctrl.Result{}, res)
+	var updated aigv1a1.ModelAlias
+	require.NoError(t, fakeClient.Get(t.Context(), key, &updated))
+	require.Equal(t, []aigv1a1.ModelAliasModelStatus{
+		{Name: "gpt-4o", Percent: "60"},
+		{Name: "claude", Percent: "30"},
+		{Name: "gpt-5", Percent: "10"},
+	}, updated.Status.Models)
+	require.Len(t, updated.Status.Conditions, 1)
+	require.Equal(t, aigv1a1.ConditionTypeAccepted, updated.Status.Conditions[0].Type)
+	// Only the AIGatewayRoutes in the same namespace are notified.
+	routes := eventCh.RequireItemsEventually(t, 1)
+	require.Equal(t, "route1", routes[0].Name)
+
+	// An invalid ModelAlias is not accepted, and its routes are notified so that it is removed from the Gateways.
+	updated.Spec.Models = []aigv1a1.ModelAliasModel{{Name: "gpt-4o", Weight: ptr.To[int32](0)}}
+	require.NoError(t, fakeClient.Update(t.Context(), &updated))
+	_, err = c.Reconcile(t.Context(), reconcile.Request{NamespacedName: key})
+	require.NoError(t, err)
+	require.NoError(t, fakeClient.Get(t.Context(), key, &updated))
+	require.Empty(t, updated.Status.Models)
+	require.Equal(t, aigv1a1.ConditionTypeNotAccepted, updated.Status.Conditions[0].Type)
+	require.Equal(t, "at least one model must have a positive weight unless the rollout is complete",
+		updated.Status.Conditions[0].Message)
+	eventCh.RequireItemsEventually(t, 1)
+
+	// The routes are notified when the ModelAlias is deleted.
+	require.NoError(t, fakeClient.Delete(t.Context(), &updated))
+	_, err = c.Reconcile(t.Context(), reconcile.Request{NamespacedName: key})
+	require.NoError(t, err)
+	eventCh.RequireItemsEventually(t, 1)
+}
+
+func Test_modelAliasShares(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		spec   aigv1a1.ModelAliasSpec
+		exp    []aigv1a1.ModelAliasModelStatus
+		expErr string
+	}{
+		{
+			name: "default weights",
+			spec: aigv1a1.ModelAliasSpec{Models: []aigv1a1.ModelAliasModel{{Name: "a"}, {Name: "b"}, {Name: "c"}}},
+			exp:  []aigv1a1.ModelAliasModelStatus{{Name: "a", Percent: "33.33"}, {Name: "b", Percent: "33.33"}, {Name: "c", Percent: "33.33"}},
+		},
+		{
+			name: "rollout of one of the models",
+			spec: aigv1a1.ModelAliasSpec{
+				Models:  []aigv1a1.ModelAliasModel{{Name: "a"}, {Name: "b"}},
+				Rollout: &aigv1a1.ModelAliasRollout{Model: "b", Percent: 20},
+			},
+			exp: []aigv1a1.ModelAliasModelStatus{{Name: "a", Percent: "40"}, {Name: "b", Percent: "60"}},
+		},
+		{
+			name: "complete rollout",
+			spec: aigv1a1.ModelAliasSpec{
+				Models:  []aigv1a1.ModelAliasModel{{Name: "a", Weight: ptr.To[int32](0)}},
+				Rollout: &aigv1a1.ModelAliasRollout{Model: "b", Percent: 100},
+			},
+			exp: []aigv1a1.ModelAliasModelStatus{{Name: "b", Percent: "100"}},
+		},
+		{
+			name:   "no weight",
+			spec:   aigv1a1.ModelAliasSpec{Models: []aigv1a1.ModelAliasModel{{Name: "a", Weight: ptr.To[int32](0)}}},
+			expErr: "at least one model must have a positive weight unless the rollout is complete",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			shares, err := modelAliasShares(&tc.spec)
+			if tc.expErr != "" {
+				require.EqualError(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, shares)
+		})
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/envoyproxy/ai-gateway/internal/filterapi"
 	"github.com/envoyproxy/ai-gateway/internal/internalapi"
 	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/parameterpolicy"
 	"github.com/envoyproxy/ai-gateway/internal/redaction"
 	"github.com/envoyproxy/ai-gateway/internal/tracing/tracingapi"
 	"github.com/envoyproxy/ai-gateway/internal/translator"
@@ -83,6 +84,25 @@ type (
 		// * redactedReq: A copy with sensitive fields replaced by [REDACTED LENGTH=n HASH=xxxx] placeholders.
 		// * err: An error if redaction fails (implementation-specific).
 		RedactSensitiveInfoFromRequest(req *ReqT) (redactedReq *ReqT, err error)
+		// UntrustedTexts returns the texts of the request that are not written by the application itself, i.e., the
+		// inputs of the end users and the results of the tools fed back to the model, which are inspected by the
+		// prompt injection guardrail. The system prompts and the assistant messages are not included.
+		//
+		// Parameters:
+		// * req: The parsed request.
+		//
+		// Returns:
+		// * texts: The untrusted texts in the order in which they appear in the request, or nil if none.
+		UntrustedTexts(req *ReqT) (texts []UntrustedText)
+		// GenerationParameters returns the generation parameters of the request governed by the parameter policies,
+		// e.g., the maximum number of the output tokens, including the ones not set by the request.
+		//
+		// Parameters:
+		// * req: The parsed request.
+		//
+		// Returns:
+		// * params: The parameters supported by the endpoint, or nil if none.
+		GenerationParameters(req *ReqT) (params []GenerationParameter)
 		// ParseMultipartBody parses a multipart/form-data request body.
 		// Endpoints that don't support multipart should return an error.
 		//
@@ -94,6 +114,25 @@ type (
 		// Returns the same tuple as ParseBody.
 		ParseMultipartBody(body []byte, contentType string, costConfigured bool) (originalModel internalapi.OriginalModel, req *ReqT, stream bool, mutatedBody []byte, err error)
 	}
+	// UntrustedText is a text of the request returned by [Spec.UntrustedTexts].
+	UntrustedText struct {
+		// Source is where the text comes from.
+		Source UntrustedTextSource
+		// Text is the text.
+		Text string
+	}
+	// UntrustedTextSource is the source of an UntrustedText.
+	UntrustedTextSource string
+	// GenerationParameter is a generation parameter of the request returned by [Spec.GenerationParameters].
+	GenerationParameter struct {
+		// Name is the parameter.
+		Name parameterpolicy.Parameter
+		// Path is the gjson path of the parameter in the request body, e.g., "reasoning.effort".
+		Path string
+		// Value is the value of the parameter, which is a float64, a string for the reasoning effort, or nil if
+		// the request does not set it.
+		Value any
+	}
 	// ChatCompletionsEndpointSpec implements EndpointSpec for /v1/chat/completions.
 	ChatCompletionsEndpointSpec struct{}
 	// CompletionsEndpointSpec implements EndpointSpec for /v1/completions.
@@ -118,6 +157,13 @@ type (
 	TokenizeEndpointSpec struct{}
 )
 
+const (
+	// UntrustedTextSourceUser is the input of the end user, e.g., the content of a user message.
+	UntrustedTextSourceUser UntrustedTextSource = "user"
+	// UntrustedTextSourceTool is the result of a tool fed back to the model, e.g., the content of a tool message.
+	UntrustedTextSourceTool UntrustedTextSource = "tool"
+)
+
 var errMultipartNotSupported = fmt.Errorf("%w: multipart body not supported for this endpoint", internalapi.ErrMalformedRequest)
 
 // ParseBody implements [EndpointSpec.ParseBody].
@@ -170,6 +216,10 @@ func (ChatCompletionsEndpointSpec) GetTranslator(schema filterapi.VersionedAPISc
 		return translator.NewChatCompletionOpenAIToGCPVertexAITranslator(modelNameOverride), nil
 	case filterapi.APISchemaGCPAnthropic:
 		return translator.NewChatCompletionOpenAIToGCPAnthropicTranslator(schema.Version, modelNameOverride), nil
+	case filterapi.APISchemaOllama:
+		return translator.NewChatCompletionOpenAIToOllamaTranslator(modelNameOverride), nil
+	case filterapi.APISchemaAzureAIInference:
+		return translator.NewChatCompletionOpenAIToAzureAIInferenceTranslator(schema.Version, schema.Prefix, modelNameOverride), nil
 	default:
 		return nil, fmt.Errorf("unsupported API schema: backend=%s", schema)
 	}
@@ -200,6 +250,58 @@ func (ChatCompletionsEndpointSpec) RedactSensitiveInfoFromRequest(req *openai.Ch
 	return &redacted, nil
 }
 
+// UntrustedTexts implements [Spec.UntrustedTexts].
+func (ChatCompletionsEndpointSpec) UntrustedTexts(req *openai.ChatCompletionRequest) (texts []UntrustedText) {
+	for _, msg := range req.Messages {
+		switch {
+		case msg.OfUser != nil:
+			switch v := msg.OfUser.Content.Value.(type) {
+			case string:
+				texts = appendUntrustedText(texts, UntrustedTextSourceUser, v)
+			case []openai.ChatCompletionContentPartUserUnionParam:
+				for _, part := range v {
+					if part.OfText != nil {
+						texts = appendUntrustedText(texts, UntrustedTextSourceUser, part.OfText.Text)
+					}
+				}
+			}
+		case msg.OfTool != nil:
+			switch v := msg.OfTool.Content.Value.(type) {
+			case string:
+				texts = appendUntrustedText(texts, UntrustedTextSourceTool, v)
+			case []openai.ChatCompletionContentPartTextParam:
+				for _, part := range v {
+					texts = appendUntrustedText(texts, UntrustedTextSourceTool, part.Text)
+				}
+			}
+		}
+	}
+	return texts
+}
+
+// GenerationParameters implements [Spec.GenerationParameters].
+func (ChatCompletionsEndpointSpec) GenerationParameters(req *openai.ChatCompletionRequest) (params []GenerationParameter) {
+	// Both of the maximum numbers of the tokens are reported when set, so that neither can bypass the rules.
+	if req.MaxCompletionTokens != nil || req.MaxTokens == nil {
+		params = append(params, GenerationParameter{Name: parameterpolicy.ParameterMaxTokens, Path: "max_completion_tokens", Value: optionalNumber(req.MaxCompletionTokens)})
+	}
+	if req.MaxTokens != nil {
+		params = append(params, GenerationParameter{Name: parameterpolicy.ParameterMaxTokens, Path: "max_tokens", Value: optionalNumber(req.MaxTokens)})
+	}
+	params = append(params,
+		GenerationParameter{Name: parameterpolicy.ParameterTemperature, Path: "temperature", Value: optionalNumber(req.Temperature)},
+		GenerationParameter{Name: parameterpolicy.ParameterTopP, Path: "top_p", Value: optionalNumber(req.TopP)},
+		GenerationParameter{Name: parameterpolicy.ParameterN, Path: "n", Value: optionalNumber(req.N)},
+		GenerationParameter{Name: parameterpolicy.ParameterReasoningEffort, Path: "reasoning_effort", Value: optionalString(string(req.ReasoningEffort))},
+	)
+	if req.Thinking != nil && req.Thinking.OfEnabled != nil {
+		params = append(params, GenerationParameter{
+			Name: parameterpolicy.ParameterThinkingBudgetTokens, Path: "thinking.budget_tokens", Value: optionalNumber(&req.Thinking.OfEnabled.BudgetTokens),
+		})
+	}
+	return
+}
+
 // ParseBody implements [EndpointSpec.ParseBody].
 func (CompletionsEndpointSpec) ParseBody(
 	body []byte,
@@ -233,6 +335,29 @@ func (CompletionsEndpointSpec) RedactSensitiveInfoFromRequest(req *openai.Comple
 	return req, nil
 }
 
+// UntrustedTexts implements [Spec.UntrustedTexts].
+func (CompletionsEndpointSpec) UntrustedTexts(req *openai.CompletionRequest) (texts []UntrustedText) {
+	switch v := req.Prompt.Value.(type) {
+	case string:
+		texts = appendUntrustedText(texts, UntrustedTextSourceUser, v)
+	case []string:
+		for _, prompt := range v {
+			texts = appendUntrustedText(texts, UntrustedTextSourceUser, prompt)
+		}
+	}
+	return texts
+}
+
+// GenerationParameters implements [Spec.GenerationParameters].
+func (CompletionsEndpointSpec) GenerationParameters(req *openai.CompletionRequest) []GenerationParameter {
+	return []GenerationParameter{
+		{Name: parameterpolicy.ParameterMaxTokens, Path: "max_tokens", Value: optionalNumber(req.MaxTokens)},
+		{Name: parameterpolicy.ParameterTemperature, Path: "temperature", Value: optionalNumber(req.Temperature)},
+		{Name: parameterpolicy.ParameterTopP, Path: "top_p", Value: optionalNumber(req.TopP)},
+		{Name: parameterpolicy.ParameterN, Path: "n", Value: optionalNumber(req.N)},
+	}
+}
+
 // ParseBody implements [EndpointSpec.ParseBody].
 func (EmbeddingsEndpointSpec) ParseBody(
 	body []byte,
@@ -261,6 +386,10 @@ func (EmbeddingsEndpointSpec) GetTranslator(schema filterapi.VersionedAPISchema,
 		return translator.NewEmbeddingOpenAIToGCPVertexAITranslator("", modelNameOverride), nil
 	case filterapi.APISchemaAWSBedrock:
 		return translator.NewEmbeddingOpenAIToAWSBedrockTranslator(modelNameOverride), nil
+	case filterapi.APISchemaOllama:
+		return translator.NewEmbeddingOpenAIToOllamaTranslator(modelNameOverride), nil
+	case filterapi.APISchemaAzureAIInference:
+		return translator.NewEmbeddingOpenAIToAzureAIInferenceTranslator(schema.Version, schema.Prefix, modelNameOverride), nil
 	default:
 		return nil, fmt.Errorf("unsupported API schema: backend=%s", schema)
 	}
@@ -272,6 +401,17 @@ func (EmbeddingsEndpointSpec) RedactSensitiveInfoFromRequest(req *openai.Embeddi
 	return req, nil
 }
 
+// UntrustedTexts implements [Spec.UntrustedTexts].
+func (EmbeddingsEndpointSpec) UntrustedTexts(*openai.EmbeddingRequest) []UntrustedText {
+	// The inputs are embedded rather than followed by the model.
+	return nil
+}
+
+// GenerationParameters implements [Spec.GenerationParameters].
+func (EmbeddingsEndpointSpec) GenerationParameters(*openai.EmbeddingRequest) []GenerationParameter {
+	return nil
+}
+
 func (ImageGenerationEndpointSpec) ParseBody(
 	body []byte,
 	_ bool,
@@ -304,6 +444,16 @@ func (ImageGenerationEndpointSpec) RedactSensitiveInfoFromRequest(req *openai.Im
 	return req, nil
 }
 
+// UntrustedTexts implements [Spec.UntrustedTexts].
+func (ImageGenerationEndpointSpec) UntrustedTexts(req *openai.ImageGenerationRequest) []UntrustedText {
+	return appendUntrustedText(nil, UntrustedTextSourceUser, req.Prompt)
+}
+
+// GenerationParameters implements [Spec.GenerationParameters].
+func (ImageGenerationEndpointSpec) GenerationParameters(*openai.ImageGenerationRequest) []GenerationParameter {
+	return nil
+}
+
 // ParseBody implements [EndpointSpec.ParseBody].
 func (ResponsesEndpointSpec) ParseBody(
 	body []byte,
@@ -339,6 +489,62 @@ func (ResponsesEndpointSpec) RedactSensitiveInfoFromRequest(req *openai.Response
 	return req, nil
 }
 
+// UntrustedTexts implements [Spec.UntrustedTexts].
+func (ResponsesEndpointSpec) UntrustedTexts(req *openai.ResponseRequest) (texts []UntrustedText) {
+	if req.Input.OfString != nil {
+		texts = appendUntrustedText(texts, UntrustedTextSourceUser, *req.Input.OfString)
+	}
+	appendInputTexts := func(content []openai.ResponseInputContentUnionParam) {
+		for _, part := range content {
+			if part.OfInputText != nil {
+				texts = appendUntrustedText(texts, UntrustedTextSourceUser, part.OfInputText.Text)
+			}
+		}
+	}
+	for _, item := range req.Input.OfInputItemList {
+		switch {
+		case item.OfMessage != nil && item.OfMessage.Role == "user":
+			if item.OfMessage.Content.OfString != nil {
+				texts = appendUntrustedText(texts, UntrustedTextSourceUser, *item.OfMessage.Content.OfString)
+			}
+			appendInputTexts(item.OfMessage.Content.OfInputItemContentList)
+		case item.OfInputMessage != nil && item.OfInputMessage.Role == "user":
+			appendInputTexts(item.OfInputMessage.Content)
+		case item.OfFunctionCallOutput != nil:
+			output := item.OfFunctionCallOutput.Output
+			if output.OfString != nil {
+				texts = appendUntrustedText(texts, UntrustedTextSourceTool, *output.OfString)
+			}
+			for _, part := range output.OfResponseFunctionCallOutputItemArray {
+				if part.OfInputText != nil {
+					texts = appendUntrustedText(texts, UntrustedTextSourceTool, part.OfInputText.Text)
+				}
+			}
+		case item.OfCustomToolCallOutput != nil:
+			output := item.OfCustomToolCallOutput.Output
+			if output.OfString != nil {
+				texts = appendUntrustedText(texts, UntrustedTextSourceTool, *output.OfString)
+			}
+			for _, part := range output.OfOutputContentList {
+				if part.OfInputText != nil {
+					texts = appendUntrustedText(texts, UntrustedTextSourceTool, part.OfInputText.Text)
+				}
+			}
+		}
+	}
+	return texts
+}
+
+// GenerationParameters implements [Spec.GenerationParameters].
+func (ResponsesEndpointSpec) GenerationParameters(req *openai.ResponseRequest) []GenerationParameter {
+	return []GenerationParameter{
+		{Name: parameterpolicy.ParameterMaxTokens, Path: "max_output_tokens", Value: optionalNumber(req.MaxOutputTokens)},
+		{Name: parameterpolicy.ParameterTemperature, Path: "temperature", Value: optionalNumber(req.Temperature)},
+		{Name: parameterpolicy.ParameterTopP, Path: "top_p", Value: optionalNumber(req.TopP)},
+		{Name: parameterpolicy.ParameterReasoningEffort, Path: "reasoning.effort", Value: optionalString(req.Reasoning.Effort)},
+	}
+}
+
 // ParseBody implements [EndpointSpec.ParseBody].
 func (MessagesEndpointSpec) ParseBody(
 	body []byte,
@@ -388,6 +594,54 @@ func (MessagesEndpointSpec) RedactSensitiveInfoFromRequest(req *anthropic.Messag
 	return req, nil
 }
 
+// UntrustedTexts implements [Spec.UntrustedTexts].
+func (MessagesEndpointSpec) UntrustedTexts(req *anthropic.MessagesRequest) (texts []UntrustedText) {
+	for _, msg := range req.Messages {
+		if msg.Role != anthropic.MessageRoleUser {
+			continue
+		}
+		texts = appendUntrustedText(texts, UntrustedTextSourceUser, msg.Content.Text)
+		for _, block := range msg.Content.Array {
+			switch {
+			case block.Text != nil:
+				texts = appendUntrustedText(texts, UntrustedTextSourceUser, block.Text.Text)
+			case block.ToolResult != nil && block.ToolResult.Content != nil:
+				texts = appendUntrustedText(texts, UntrustedTextSourceTool, block.ToolResult.Content.Text)
+				for _, item := range block.ToolResult.Content.Array {
+					if item.Text != nil {
+						texts = appendUntrustedText(texts, UntrustedTextSourceTool, item.Text.Text)
+					}
+				}
+			case block.SearchResult != nil:
+				// The search results are retrieved by the application, e.g., from the web, like the results of the tools.
+				for _, text := range block.SearchResult.Content {
+					texts = appendUntrustedText(texts, UntrustedTextSourceTool, text.Text)
+				}
+			}
+		}
+	}
+	return texts
+}
+
+// GenerationParameters implements [Spec.GenerationParameters].
+func (MessagesEndpointSpec) GenerationParameters(req *anthropic.MessagesRequest) (params []GenerationParameter) {
+	var maxTokens any
+	if req.MaxTokens != 0 {
+		maxTokens = req.MaxTokens
+	}
+	params = []GenerationParameter{
+		{Name: parameterpolicy.ParameterMaxTokens, Path: "max_tokens", Value: maxTokens},
+		{Name: parameterpolicy.ParameterTemperature, Path: "temperature", Value: optionalNumber(req.Temperature)},
+		{Name: parameterpolicy.ParameterTopP, Path: "top_p", Value: optionalNumber(req.TopP)},
+	}
+	if req.Thinking != nil && req.Thinking.Enabled != nil {
+		params = append(params, GenerationParameter{
+			Name: parameterpolicy.ParameterThinkingBudgetTokens, Path: "thinking.budget_tokens", Value: optionalNumber(&req.Thinking.Enabled.BudgetTokens),
+		})
+	}
+	return
+}
+
 // ParseBody implements [EndpointSpec.ParseBody].
 func (RerankEndpointSpec) ParseBody(
 	body []byte,
@@ -421,6 +675,17 @@ func (RerankEndpointSpec) RedactSensitiveInfoFromRequest(req *cohereschema.Reran
 	return req, nil
 }
 
+// UntrustedTexts implements [Spec.UntrustedTexts].
+func (RerankEndpointSpec) UntrustedTexts(*cohereschema.RerankV2Request) []UntrustedText {
+	// The query and the documents are scored rather than followed by the model.
+	return nil
+}
+
+// GenerationParameters implements [Spec.GenerationParameters].
+func (RerankEndpointSpec) GenerationParameters(*cohereschema.RerankV2Request) []GenerationParameter {
+	return nil
+}
+
 // ParseBody implements [EndpointSpec.ParseBody].
 func (TokenizeEndpointSpec) ParseBody(
 	body []byte,
@@ -470,6 +735,17 @@ func (TokenizeEndpointSpec) RedactSensitiveInfoFromRequest(req *tokenize.Request
 	return req, nil
 }
 
+// UntrustedTexts implements [Spec.UntrustedTexts].
+func (TokenizeEndpointSpec) UntrustedTexts(*tokenize.RequestUnion) []UntrustedText {
+	// The texts are only tokenized.
+	return nil
+}
+
+// GenerationParameters implements [Spec.GenerationParameters].
+func (TokenizeEndpointSpec) GenerationParameters(*tokenize.RequestUnion) []GenerationParameter {
+	return nil
+}
+
 // ParseMultipartBody implements [Spec.ParseMultipartBody].
 func (TokenizeEndpointSpec) ParseMultipartBody([]byte, string, bool) (internalapi.OriginalModel, *tokenize.RequestUnion, bool, []byte, error) {
 	return "", nil, false, nil, errMultipartNotSupported
@@ -701,6 +977,17 @@ func (SpeechEndpointSpec) RedactSensitiveInfoFromRequest(req *openai.SpeechReque
 	return &redacted, nil
 }
 
+// UntrustedTexts implements [Spec.UntrustedTexts].
+func (SpeechEndpointSpec) UntrustedTexts(*openai.SpeechRequest) []UntrustedText {
+	// The input is synthesized rather than followed by the model.
+	return nil
+}
+
+// GenerationParameters implements [Spec.GenerationParameters].
+func (SpeechEndpointSpec) GenerationParameters(*openai.SpeechRequest) []GenerationParameter {
+	return nil
+}
+
 // ParseBody implements [Spec.ParseBody]. Transcription uses multipart, so JSON body is not expected.
 func (TranscriptionEndpointSpec) ParseBody(
 	_ []byte, _ bool,
@@ -822,6 +1109,17 @@ func (TranscriptionEndpointSpec) RedactSensitiveInfoFromRequest(req *openai.Tran
 	return &redacted, nil
 }
 
+// UntrustedTexts implements [Spec.UntrustedTexts].
+func (TranscriptionEndpointSpec) UntrustedTexts(*openai.TranscriptionRequest) []UntrustedText {
+	// The prompt only guides the style of the transcription.
+	return nil
+}
+
+// GenerationParameters implements [Spec.GenerationParameters].
+func (TranscriptionEndpointSpec) GenerationParameters(*openai.TranscriptionRequest) []GenerationParameter {
+	return nil
+}
+
 // ParseBody implements [Spec.ParseBody]. Translation uses multipart, so JSON body is not expected.
 func (TranslationEndpointSpec) ParseBody(
 	_ []byte, _ bool,
@@ -927,6 +1225,41 @@ func (TranslationEndpointSpec) RedactSensitiveInfoFromRequest(req *openai.Transl
 	return &redacted, nil
 }
 
+// UntrustedTexts implements [Spec.UntrustedTexts].
+func (TranslationEndpointSpec) UntrustedTexts(*openai.TranslationRequest) []UntrustedText {
+	// The prompt only guides the style of the translation.
+	return nil
+}
+
+// GenerationParameters implements [Spec.GenerationParameters].
+func (TranslationEndpointSpec) GenerationParameters(*openai.TranslationRequest) []GenerationParameter {
+	return nil
+}
+
+// appendUntrustedText appends the text of the source to the texts unless it is empty.
+// optionalNumber returns the number as a float64, or nil if it is not set.
+func optionalNumber[T int | int64 | float64](v *T) any {
+	if v == nil {
+		return nil
+	}
+	return float64(*v)
+}
+
+// optionalString returns the string, or nil if it is empty.
+func optionalString(v string) any {
+	if v == "" {
+		return nil
+	}
+	return v
+}
+
+func appendUntrustedText(texts []UntrustedText, source UntrustedTextSource, text string) []UntrustedText {
+	if text == "" {
+		return texts
+	}
+	return append(texts, UntrustedText{Source: source, Text: text})
+}
+
 // readFormField reads the entire value of a multipart form field as a string.
 func readFormField(part *multipart.Part) (string, error) {
 	data, err := io.ReadAll(part)
@@ -19,6 +19,7 @@ import (
 	"github.com/envoyproxy/ai-gateway/internal/filterapi"
 	"github.com/envoyproxy/ai-gateway/internal/internalapi"
 	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/parameterpolicy"
 	"github.com/envoyproxy/ai-gateway/internal/redaction"
 )
 
@@ -89,6 +90,8 @@ func TestChatCompletionsEndpointSpec_GetTranslator(t *testing.T) {
 		{Name: filterapi.APISchemaAzureOpenAI, Version: "2024-02-01"},
 		{Name: filterapi.APISchemaGCPVertexAI},
 		{Name: filterapi.APISchemaGCPAnthropic, Version: "2024-05-01"},
+		{Name: filterapi.APISchemaOllama},
+		{Name: filterapi.APISchemaAzureAIInference, Version: "2024-05-01-preview"},
 	}
 
 	for _, schema := range supported {
@@ -186,6 +189,8 @@ func TestEmbeddingsEndpointSpec_GetTranslator(t *testing.T) {
 		{Name: filterapi.APISchemaAzureOpenAI},
 		{Name: filterapi.APISchemaGCPVertexAI},
 		{Name: filterapi.APISchemaAWSBedrock},
+		{Name: filterapi.APISchemaOllama},
+		{Name: filterapi.APISchemaAzureAIInference, Version: "2024-05-01-preview"},
 	}
 	for _, schema := range supported {
 		s := schema
@@ -970,6 +975,146 @@ func TestChatCompletionsEndpointSpec_RedactSensitiveInfoFromRequest(t *testing.T
 	})
 }
 
+func TestUntrustedTexts(t *testing.T) {
+	user := func(text string) UntrustedText { return UntrustedText{Source: UntrustedTextSourceUser, Text: text} }
+	tool := func(text string) UntrustedText { return UntrustedText{Source: UntrustedTextSourceTool, Text: text} }
+
+	t.Run("chat completions", func(t *testing.T) {
+		_, req, _, _, err := ChatCompletionsEndpointSpec{}.ParseBody([]byte(`{"model":"gpt-4o","messages":[
+			{"role":"system","content":"Be nice."},
+			{"role":"developer","content":"Be brief."},
+			{"role":"user","content":"Hi"},
+			{"role":"assistant","content":"Hello"},
+			{"role":"user","content":[{"type":"text","text":"Look:"},{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]},
+			{"role":"tool","tool_call_id":"1","content":"Sunny"},
+			{"role":"tool","tool_call_id":"2","content":[{"type":"text","text":"Rainy"}]}
+		]}`), false)
+		require.NoError(t, err)
+		require.Equal(t, []UntrustedText{user("Hi"), user("Look:"), tool("Sunny"), tool("Rainy")},
+			ChatCompletionsEndpointSpec{}.UntrustedTexts(req))
+	})
+
+	t.Run("completions", func(t *testing.T) {
+		_, req, _, _, err := CompletionsEndpointSpec{}.ParseBody([]byte(`{"model":"m","prompt":["a","b"]}`), false)
+		require.NoError(t, err)
+		require.Equal(t, []UntrustedText{user("a"), user("b")}, CompletionsEndpointSpec{}.UntrustedTexts(req))
+	})
+
+	t.Run("responses", func(t *testing.T) {
+		_, req, _, _, err := ResponsesEndpointSpec{}.ParseBody([]byte(`{"model":"gpt-4o","instructions":"Be nice.","input":[
+			{"role":"system","content":"Be brief."},
+			{"role":"user","content":"Hi"},
+			{"type":"message","role":"user","content":[{"type":"input_text","text":"Look:"}]},
+			{"type":"function_call_output","call_id":"1","output":"Sunny"}
+		]}`), false)
+		require.NoError(t, err)
+		require.Equal(t, []UntrustedText{user("Hi"), user("Look:"), tool("Sunny")}, ResponsesEndpointSpec{}.UntrustedTexts(req))
+
+		_, req, _, _, err = ResponsesEndpointSpec{}.ParseBody([]byte(`{"model":"gpt-4o","input":"Hi"}`), false)
+		require.NoError(t, err)
+		require.Equal(t, []UntrustedText{user("Hi")}, ResponsesEndpointSpec{}.UntrustedTexts(req))
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		_, req, _, _, err := MessagesEndpointSpec{}.ParseBody([]byte(`{"model":"claude","max_tokens":10,"system":"Be nice.","messages":[
+			{"role":"user","content":"Hi"},
+			{"role":"assistant","content":[{"type":"text","text":"Hello"}]},
+			{"role":"user","content":[
+				{"type":"tool_result","tool_use_id":"1","content":"Sunny"},
+				{"type":"tool_result","tool_use_id":"2","content":[{"type":"text","text":"Rainy"}]},
+				{"type":"search_result","source":"https://example.com","title":"Example","content":[{"type":"text","text":"Found"}]},
+				{"type":"text","text":"Thanks"}
+			]}
+		]}`), false)
+		require.NoError(t, err)
+		require.Equal(t, []UntrustedText{user("Hi"), tool("Sunny"), tool("Rainy"), tool("Found"), user("Thanks")},
+			MessagesEndpointSpec{}.UntrustedTexts(req))
+	})
+
+	t.Run("image generation", func(t *testing.T) {
+		require.Equal(t, []UntrustedText{user("A cat")},
+			ImageGenerationEndpointSpec{}.UntrustedTexts(&openai.ImageGenerationRequest{Prompt: "A cat"}))
+	})
+
+	t.Run("not inspected", func(t *testing.T) {
+		require.Nil(t, EmbeddingsEndpointSpec{}.UntrustedTexts(&openai.EmbeddingRequest{}))
+		require.Nil(t, RerankEndpointSpec{}.UntrustedTexts(&cohereschema.RerankV2Request{Query: "q", Documents: []string{"d"}}))
+		require.Nil(t, SpeechEndpointSpec{}.UntrustedTexts(&openai.SpeechRequest{Input: "Hi"}))
+		require.Nil(t, TokenizeEndpointSpec{}.UntrustedTexts(&tokenize.RequestUnion{}))
+		require.Nil(t, TranscriptionEndpointSpec{}.UntrustedTexts(&openai.TranscriptionRequest{Prompt: "Hi"}))
+		require.Nil(t, TranslationEndpointSpec{}.UntrustedTexts(&openai.TranslationRequest{Prompt: "Hi"}))
+	})
+}
+
+func TestGenerationParameters(t *testing.T) {
+	param := func(name parameterpolicy.Parameter, path string, value any) GenerationParameter {
+		return GenerationParameter{Name: name, Path: path, Value: value}
+	}
+
+	t.Run("chat completions", func(t *testing.T) {
+		_, req, _, _, err := ChatCompletionsEndpointSpec{}.ParseBody([]byte(`{"model":"gpt-4o","messages":[],
+			"max_tokens":100000,"temperature":0.5,"n":2,"reasoning_effort":"high","thinking":{"type":"enabled","budget_tokens":2048}}`), false)
+		require.NoError(t, err)
+		require.Equal(t, []GenerationParameter{
+			param(parameterpolicy.ParameterMaxTokens, "max_tokens", 100000.0),
+			param(parameterpolicy.ParameterTemperature, "temperature", 0.5),
+			param(parameterpolicy.ParameterTopP, "top_p", nil),
+			param(parameterpolicy.ParameterN, "n", 2.0),
+			param(parameterpolicy.ParameterReasoningEffort, "reasoning_effort", "high"),
+			param(parameterpolicy.ParameterThinkingBudgetTokens, "thinking.budget_tokens", 2048.0),
+		}, ChatCompletionsEndpointSpec{}.GenerationParameters(req))
+
+		_, req, _, _, err = ChatCompletionsEndpointSpec{}.ParseBody([]byte(`{"model":"gpt-4o","messages":[],"max_completion_tokens":10}`), false)
+		require.NoError(t, err)
+		require.Equal(t, param(parameterpolicy.ParameterMaxTokens, "max_completion_tokens", 10.0),
+			ChatCompletionsEndpointSpec{}.GenerationParameters(req)[0])
+	})
+
+	t.Run("completions", func(t *testing.T) {
+		_, req, _, _, err := CompletionsEndpointSpec{}.ParseBody([]byte(`{"model":"m","prompt":"a","max_tokens":16}`), false)
+		require.NoError(t, err)
+		require.Equal(t, []GenerationParameter{
+			param(parameterpolicy.ParameterMaxTokens, "max_tokens", 16.0),
+			param(parameterpolicy.ParameterTemperature, "temperature", nil),
+			param(parameterpolicy.ParameterTopP, "top_p", nil),
+			param(parameterpolicy.ParameterN, "n", nil),
+		}, CompletionsEndpointSpec{}.GenerationParameters(req))
+	})
+
+	t.Run("responses", func(t *testing.T) {
+		_, req, _, _, err := ResponsesEndpointSpec{}.ParseBody([]byte(`{"model":"gpt-5","input":"Hi","max_output_tokens":512,"top_p":0.9,"reasoning":{"effort":"low"}}`), false)
+		require.NoError(t, err)
+		require.Equal(t, []GenerationParameter{
+			param(parameterpolicy.ParameterMaxTokens, "max_output_tokens", 512.0),
+			param(parameterpolicy.ParameterTemperature, "temperature", nil),
+			param(parameterpolicy.ParameterTopP, "top_p", 0.9),
+			param(parameterpolicy.ParameterReasoningEffort, "reasoning.effort", "low"),
+		}, ResponsesEndpointSpec{}.GenerationParameters(req))
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		_, req, _, _, err := MessagesEndpointSpec{}.ParseBody([]byte(`{"model":"claude","max_tokens":4096,"temperature":1,"messages":[],
+			"thinking":{"type":"enabled","budget_tokens":1024}}`), false)
+		require.NoError(t, err)
+		require.Equal(t, []GenerationParameter{
+			param(parameterpolicy.ParameterMaxTokens, "max_tokens", 4096.0),
+			param(parameterpolicy.ParameterTemperature, "temperature", 1.0),
+			param(parameterpolicy.ParameterTopP, "top_p", nil),
+			param(parameterpolicy.ParameterThinkingBudgetTokens, "thinking.budget_tokens", 1024.0),
+		}, MessagesEndpointSpec{}.GenerationParameters(req))
+	})
+
+	t.Run("not governed", func(t *testing.T) {
+		require.Nil(t, EmbeddingsEndpointSpec{}.GenerationParameters(&openai.EmbeddingRequest{}))
+		require.Nil(t, ImageGenerationEndpointSpec{}.GenerationParameters(&openai.ImageGenerationRequest{}))
+		require.Nil(t, RerankEndpointSpec{}.GenerationParameters(&cohereschema.RerankV2Request{}))
+		require.Nil(t, SpeechEndpointSpec{}.GenerationParameters(&openai.SpeechRequest{}))
+		require.Nil(t, TokenizeEndpointSpec{}.GenerationParameters(&tokenize.RequestUnion{}))
+		require.Nil(t, TranscriptionEndpointSpec{}.GenerationParameters(&openai.TranscriptionRequest{}))
+		require.Nil(t, TranslationEndpointSpec{}.GenerationParameters(&openai.TranslationRequest{}))
+	})
+}
+
 func TestRedactString(t *testing.T) {
 	t.Run("redact_non_empty_string", func(t *testing.T) {
 		result := redaction.RedactString("sensitive data")
@@ -0,0 +1,117 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+// Package errorclass classifies the error responses of the AI providers into the classes used by the fallback
+// policy of the AIGatewayRoute rules, as well as the "error.type" attribute of the metrics and the tracing spans.
+package errorclass
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+// Class is the class of an error response.
+type Class string
+
+const (
+	// RateLimit is the class of the rate limited requests.
+	RateLimit Class = "rate_limit"
+	// Overloaded is the class of the requests rejected by an overloaded backend.
+	Overloaded Class = "overloaded"
+	// ContextLength is the class of the requests exceeding the context window of the model.
+	ContextLength Class = "context_length_exceeded"
+	// ContentFilter is the class of the requests rejected by the content filter of the provider.
+	ContentFilter Class = "content_filter"
+	// Auth is the class of the requests rejected due to the credentials.
+	Auth Class = "auth"
+	// Other is the class of the errors that cannot be classified. This is the fallback value of the
+	// "error.type" attribute defined by the OpenTelemetry semantic conventions.
+	Other Class = "_OTHER"
+)
+
+// contextLengthMarkers are the lowercase substrings of the error codes and messages indicating that the prompt
+// exceeds the context window of the model across the supported providers.
+var contextLengthMarkers = []string{
+	"context_length_exceeded",              // OpenAI, Azure OpenAI.
+	"context length exceeded",              // AI Gateway, see internalapi.ErrContextLengthExceeded.
+	"maximum context length",               // OpenAI, vLLM.
+	"context window",                       // Vertex AI, Ollama.
+	"prompt is too long",                   // Anthropic.
+	"input is too long",                    // AWS Bedrock.
+	"too many input tokens",                // AWS Bedrock.
+	"exceeds the maximum number of tokens", // Gemini.
+	"request_too_large",                    // Anthropic.
+}
+
+// contentFilterMarkers are the lowercase substrings of the error codes and messages indicating that the request
+// was rejected by the content filter of the provider.
+var contentFilterMarkers = []string{
+	"content_filter",            // Azure OpenAI.
+	"content_policy_violation",  // OpenAI.
+	"content management policy", // Azure OpenAI.
+	"content filtering",
+	"responsible ai practices", // Vertex AI.
+	"guardrail",                // AWS Bedrock.
+}
+
+// Classify returns the class of the error response with the status code and the body, which is expected to be
+// already translated to the format of the client facing API, e.g., by translator.Translator.ResponseError.
+func Classify(statusCode int, body []byte) Class {
+	errType, code, message := parse(body)
+	details := strings.ToLower(errType + " " + code + " " + message)
+	switch {
+	case statusCode == http.StatusTooManyRequests || errType == "rate_limit_error":
+		return RateLimit
+	case statusCode == http.StatusServiceUnavailable || statusCode == 529 || errType == "overloaded_error":
+		return Overloaded
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return Auth
+	case containsAny(details, contextLengthMarkers):
+		return ContextLength
+	case containsAny(details, contentFilterMarkers):
+		return ContentFilter
+	case statusCode == http.StatusRequestEntityTooLarge:
+		return ContextLength
+	}
+	return Other
+}
+
+// parse extracts the type, the code and the message of the error from the body in any of the following shapes:
+//
+//   - OpenAI: {"error":{"type":"...","code":"...","message":"..."}}
+//   - Anthropic: {"type":"error","error":{"type":"...","message":"..."}}
+//   - AWS Bedrock: {"message":"..."}
+//   - Ollama: {"error":"..."}
+//
+// When the body is not JSON, it is used as the message as is.
+func parse(body []byte) (errType, code, message string) {
+	var raw map[string]any
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return "", "", string(body)
+	}
+	if msg, ok := raw["error"].(string); ok {
+		// Ollama and some OpenAI compatible servers use a plain string.
+		return "", "", msg
+	}
+	fields := raw
+	if inner, ok := raw["error"].(map[string]any); ok {
+		fields = inner
+	}
+	errType, _ = fields["type"].(string)
+	code, _ = fields["code"].(string)
+	message, _ = fields["message"].(string)
+	return
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package errorclass
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		statusCode int
+		body       string
+		exp        Class
+	}{
+		{
+			name:       "openai rate limit",
+			statusCode: 429,
+			body:       `{"error":{"type":"requests","code":"rate_limit_exceeded","message":"Rate limit reached"}}`,
+			exp:        RateLimit,
+		},
+		{
+			name:       "anthropic rate limit type",
+			statusCode: 400,
+			body:       `{"type":"error","error":{"type":"rate_limit_error","message":"Number of request tokens has exceeded your rate limit"}}`,
+			exp:        RateLimit,
+		},
+		{
+			name:       "service unavailable",
+			statusCode: 503,
+			body:       `upstream connect error`,
+			exp:        Overloaded,
+		},
+		{
+			name:       "anthropic overloaded",
+			statusCode: 529,
+			body:       `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			exp:        Overloaded,
+		},
+		{
+			name:       "unauthorized",
+			statusCode: 401,
+			body:       `{"error":{"type":"invalid_request_error","code":"invalid_api_key","message":"Incorrect API key provided"}}`,
+			exp:        Auth,
+		},
+		{
+			name:       "forbidden",
+			statusCode: 403,
+			body:       `{"message":"The security token included in the request is invalid."}`,
+			exp:        Auth,
+		},
+		{
+			name:       "openai context length",
+			statusCode: 400,
+			body:       `{"error":{"type":"invalid_request_error","code":"context_length_exceeded","message":"This model's maximum context length is 128000 tokens."}}`,
+			exp:        ContextLength,
+		},
+		{
+			name:       "anthropic context length",
+			statusCode: 400,
+			body:       `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`,
+			exp:        ContextLength,
+		},
+		{
+			name:       "bedrock context length",
+			statusCode: 400,
+			body:       `{"message":"Input is too long for requested model."}`,
+			exp:        ContextLength,
+		},
+		{
+			name:       "ollama context length",
+			statusCode: 400,
+			body:       `{"error":"the input length exceeds the context window"}`,
+			exp:        ContextLength,
+		},
+		{
+			name:       "request entity too large",
+			statusCode: 413,
+			body:       `request too large`,
+			exp:        ContextLength,
+		},
+		{
+			name:       "azure content filter",
+			statusCode: 400,
+			body:       `{"error":{"type":null,"code":"content_filter","message":"The response was filtered due to the prompt triggering Azure OpenAI's content management policy."}}`,
+			exp:        ContentFilter,
+		},
+		{
+			name:       "openai content policy",
+			statusCode: 400,
+			body:       `{"error":{"type":"invalid_request_error","code":"content_policy_violation","message":"Your request was rejected."}}`,
+			exp:        ContentFilter,
+		},
+		{
+			name:       "other bad request",
+			statusCode: 400,
+			body:       `{"error":{"type":"invalid_request_error","code":"invalid_value","message":"temperature must be below 2"}}`,
+			exp:        Other,
+		},
+		{
+			name:       "internal server error",
+			statusCode: 500,
+			body:       ``,
+			exp:        Other,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, Classify(tc.statusCode, []byte(tc.body)))
+		})
+	}
+}
@@ -26,7 +26,9 @@ import (
 	htomv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/header_to_metadata/v3"
 	upstream_codecv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/upstream_codec/v3"
 	httpconnectionmanagerv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	previous_prioritiesv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/retry/priority/previous_priorities/v3"
 	httpv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/require"
@@ -513,6 +515,76 @@ func TestMaybeModifyClusterPerBackendClusterName(t *testing.T) {
 	})
 }
 
+func Test_aiGatewayRouteRuleIndexOf(t *testing.T) {
+	selection := &aigv1b1.AIGatewayRouteRuleBackendSelection{Objective: aigv1b1.BackendSelectionObjectiveFastest}
+	route := &aigv1b1.AIGatewayRoute{Spec: aigv1b1.AIGatewayRouteSpec{Rules: []aigv1b1.AIGatewayRouteRule{
+		{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "a"}}, BackendSelection: selection},
+		{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "a"}, {Name: "b"}}},
+		{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "a"}, {Name: "b"}}, BackendSelection: selection},
+	}}}
+	for _, tc := range []struct {
+		httpRouteRuleIndex, expRuleIndex, expPreferredRefIndex int
+	}{
+		{httpRouteRuleIndex: 0, expRuleIndex: 0, expPreferredRefIndex: -1},
+		{httpRouteRuleIndex: 2, expRuleIndex: 2, expPreferredRefIndex: -1},
+		// The route-not-found rule.
+		{httpRouteRuleIndex: 3, expRuleIndex: 3, expPreferredRefIndex: -1},
+		{httpRouteRuleIndex: 4, expRuleIndex: 0, expPreferredRefIndex: 0},
+		{httpRouteRuleIndex: 5, expRuleIndex: 2, expPreferredRefIndex: 0},
+		{httpRouteRuleIndex: 6, expRuleIndex: 2, expPreferredRefIndex: 1},
+		// The rules of the shadows.
+		{httpRouteRuleIndex: 7, expRuleIndex: 3, expPreferredRefIndex: -1},
+	} {
+		ruleIndex, preferredRefIndex := aiGatewayRouteRuleIndexOf(route, tc.httpRouteRuleIndex)
+		require.Equal(t, tc.expRuleIndex, ruleIndex, "HTTPRoute rule index %d", tc.httpRouteRuleIndex)
+		require.Equal(t, tc.expPreferredRefIndex, preferredRefIndex, "HTTPRoute rule index %d", tc.httpRouteRuleIndex)
+	}
+}
+
+func TestMaybeModifyClusterBackendSelection(t *testing.T) {
+	c := newFakeClient()
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "myroute", Namespace: "ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{Rules: []aigv1b1.AIGatewayRouteRule{{
+			BackendSelection: &aigv1b1.AIGatewayRouteRuleBackendSelection{Objective: aigv1b1.BackendSelectionObjectiveFastest},
+			BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{
+				{Name: "aaa"},
+				{Name: "bbb"},
+				{Name: "ccc", Priority: ptr.To[uint32](1)},
+			},
+		}}},
+	}))
+	s, err := New(c, logr.Discard(), udsPath, false, nil, nil, "envoy-ai-gateway-ratelimit.envoy-gateway-system", 5, false)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name          string
+		cluster       string
+		expPriorities []uint32
+	}{
+		{name: "rule", cluster: "httproute/ns/myroute/rule/0", expPriorities: []uint32{0, 0, 1}},
+		{name: "preferring the first backend", cluster: "httproute/ns/myroute/rule/2", expPriorities: []uint32{0, 1, 2}},
+		{name: "preferring the second backend", cluster: "httproute/ns/myroute/rule/3", expPriorities: []uint32{1, 0, 2}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &clusterv3.Cluster{Name: tc.cluster, LoadAssignment: &endpointv3.ClusterLoadAssignment{}}
+			for range 3 {
+				cluster.LoadAssignment.Endpoints = append(cluster.LoadAssignment.Endpoints,
+					&endpointv3.LocalityLbEndpoints{LbEndpoints: []*endpointv3.LbEndpoint{{}}})
+			}
+			require.NoError(t, s.maybeModifyCluster(t.Context(), cluster))
+			for i, name := range []string{"aaa", "bbb", "ccc"} {
+				endpoints := cluster.LoadAssignment.Endpoints[i]
+				require.Equal(t, tc.expPriorities[i], endpoints.Priority)
+				// The backend names are the ones of the AIGatewayRoute rule.
+				require.Equal(t, internalapi.PerRouteRuleRefBackendName("ns", name, "myroute", 0, i),
+					endpoints.LbEndpoints[0].Metadata.FilterMetadata[internalapi.InternalEndpointMetadataNamespace].
+						Fields[internalapi.InternalMetadataBackendNameKey].GetStringValue())
+			}
+		})
+	}
+}
+
 // Helper function to create an InferencePool ExtensionResource.
 func createInferencePoolExtensionResource(name, namespace string) *egextension.ExtensionResource {
 	unstructuredObj := &unstructured.Unstructured{
@@ -1647,9 +1719,9 @@ func TestMaybeSetStreamIdleTimeout(t *testing.T) {
 	})
 }
 
-// TestApplyStreamIdleTimeouts tests that the per-try idle timeout is applied while walking
+// TestApplyRoutePolicies tests that the per-try idle timeout is applied while walking
 // the route configurations, and that unrelated routes are left untouched.
-func TestApplyStreamIdleTimeouts(t *testing.T) {
+func TestApplyRoutePolicies(t *testing.T) {
 	c := newFakeClient()
 	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIGatewayRoute{
 		ObjectMeta: metav1.ObjectMeta{Name: "ttft-route", Namespace: "default"},
@@ -1669,7 +1741,7 @@ func TestApplyStreamIdleTimeouts(t *testing.T) {
 		VirtualHosts: []*routev3.VirtualHost{{Routes: []*routev3.Route{configured, other}}},
 	}}
 
-	require.NoError(t, s.applyStreamIdleTimeouts(context.Background(), routeConfigs))
+	require.NoError(t, s.applyRoutePolicies(context.Background(), routeConfigs))
 	require.Equal(t, durationpb.New(7*time.Second), configured.GetRoute().RetryPolicy.GetPerTryIdleTimeout())
 	require.Nil(t, other.GetRoute().RetryPolicy)
 
@@ -1683,13 +1755,603 @@ func TestApplyStreamIdleTimeouts(t *testing.T) {
 			}).Build(),
 		logr.Discard(), udsPath, false, nil, nil, "envoy-ai-gateway-ratelimit.envoy-gateway-system", 5, false)
 	require.NoError(t, err)
-	err = failing.applyStreamIdleTimeouts(context.Background(),
+	err = failing.applyRoutePolicies(context.Background(),
 		[]*routev3.RouteConfiguration{{VirtualHosts: []*routev3.VirtualHost{{Routes: []*routev3.Route{
 			forwarding("httproute/default/ttft-route/rule/0/match/0"),
 		}}}}})
 	require.ErrorContains(t, err, "boom")
 }
 
+// TestMaybeSetFallbackPolicy tests that the route's retry policy is configured from the AIGatewayRoute
+// rule's FallbackPolicy.
+func TestMaybeSetFallbackPolicy(t *testing.T) {
+	c := newFakeClient()
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "fallback-route", Namespace: "default"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				{FallbackPolicy: &aigv1b1.AIGatewayRouteRuleFallbackPolicy{
+					Actions: []aigv1b1.AIGatewayRouteRuleFallbackAction{
+						{ErrorClass: aigv1b1.ErrorClassRateLimit, Action: aigv1b1.FallbackActionTypeFailover},
+						{ErrorClass: aigv1b1.ErrorClassAuth, Action: aigv1b1.FallbackActionTypeReturn},
+					},
+				}},
+				{FallbackPolicy: &aigv1b1.AIGatewayRouteRuleFallbackPolicy{
+					Actions: []aigv1b1.AIGatewayRouteRuleFallbackAction{
+						{ErrorClass: aigv1b1.ErrorClassOverloaded, Action: aigv1b1.FallbackActionTypeRetry},
+					},
+					NumRetries: ptr.To[int32](5),
+				}},
+				{}, // No FallbackPolicy.
+				{FallbackPolicy: &aigv1b1.AIGatewayRouteRuleFallbackPolicy{
+					Actions: []aigv1b1.AIGatewayRouteRuleFallbackAction{
+						{ErrorClass: aigv1b1.ErrorClassOverloaded, Action: aigv1b1.FallbackActionTypeRetry},
+						{ErrorClass: aigv1b1.ErrorClassRateLimit, Action: aigv1b1.FallbackActionTypeFailover},
+					},
+					NumRetries: ptr.To[int32](4),
+				}},
+			},
+		},
+	}))
+	s, err := New(c, logr.Discard(), udsPath, false, nil, nil, "envoy-ai-gateway-ratelimit.envoy-gateway-system", 5, false)
+	require.NoError(t, err)
+
+	forwardingRoute := func(name string) *routev3.Route {
+		return &routev3.Route{Name: name, Action: &routev3.Route_Route{Route: &routev3.RouteAction{}}}
+	}
+	call := func(t *testing.T, route *routev3.Route) {
+		cache := make(map[client.ObjectKey]*aigv1b1.AIGatewayRoute)
+		require.NoError(t, s.maybeSetFallbackPolicy(context.Background(), route, cache))
+	}
+	requireRetriableHeaders := func(t *testing.T, rp *routev3.RetryPolicy) {
+		require.Len(t, rp.RetriableHeaders, 1)
+		require.True(t, proto.Equal(&routev3.HeaderMatcher{
+			Name:                 internalapi.FallbackErrorClassHeader,
+			HeaderMatchSpecifier: &routev3.HeaderMatcher_PresentMatch{PresentMatch: true},
+		}, rp.RetriableHeaders[0]))
+	}
+
+	t.Run("failover", func(t *testing.T) {
+		route := forwardingRoute("httproute/default/fallback-route/rule/0/match/0")
+		route.GetRoute().RetryPolicy = &routev3.RetryPolicy{
+			RetryOn:              "5xx,retriable-status-codes",
+			RetriableStatusCodes: []uint32{429},
+			PerTryIdleTimeout:    durationpb.New(time.Second),
+		}
+		call(t, route)
+		rp := route.GetRoute().RetryPolicy
+		require.Equal(t, "retriable-headers,reset,connect-failure,refused-stream", rp.RetryOn)
+		require.Nil(t, rp.RetriableStatusCodes)
+		require.Equal(t, durationpb.New(time.Second), rp.PerTryIdleTimeout)
+		requireRetriableHeaders(t, rp)
+		require.Equal(t, uint32(2), rp.NumRetries.GetValue())
+		require.Equal(t, "envoy.retry_priorities.previous_priorities", rp.RetryPriority.GetName())
+		var cfg previous_prioritiesv3.PreviousPrioritiesConfig
+		require.NoError(t, rp.RetryPriority.GetTypedConfig().UnmarshalTo(&cfg))
+		require.Equal(t, int32(1), cfg.UpdateFrequency)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		route := forwardingRoute("httproute/default/fallback-route/rule/1/match/0")
+		call(t, route)
+		rp := route.GetRoute().RetryPolicy
+		require.Equal(t, "retriable-headers,reset,connect-failure,refused-stream", rp.RetryOn)
+		requireRetriableHeaders(t, rp)
+		require.Equal(t, uint32(5), rp.NumRetries.GetValue())
+		require.Nil(t, rp.RetryPriority)
+	})
+
+	t.Run("retry and failover", func(t *testing.T) {
+		route := forwardingRoute("httproute/default/fallback-route/rule/3/match/0")
+		call(t, route)
+		rp := route.GetRoute().RetryPolicy
+		requireRetriableHeaders(t, rp)
+		require.Equal(t, uint32(4), rp.NumRetries.GetValue())
+		require.Equal(t, "envoy.retry_priorities.previous_priorities", rp.RetryPriority.GetName())
+		var cfg previous_prioritiesv3.PreviousPrioritiesConfig
+		require.NoError(t, rp.RetryPriority.GetTypedConfig().UnmarshalTo(&cfg))
+		require.Equal(t, int32(2), cfg.UpdateFrequency)
+	})
+
+	t.Run("no policy", func(t *testing.T) {
+		route := forwardingRoute("httproute/default/fallback-route/rule/2/match/0")
+		call(t, route)
+		require.Nil(t, route.GetRoute().RetryPolicy)
+	})
+
+	t.Run("ignores non-forwarding route", func(t *testing.T) {
+		route := &routev3.Route{
+			Name:   "httproute/default/fallback-route/rule/0/match/0",
+			Action: &routev3.Route_DirectResponse{DirectResponse: &routev3.DirectResponseAction{Status: 403}},
+		}
+		call(t, route)
+		require.Nil(t, route.GetRoute())
+	})
+}
+
+func TestMaybeSetContextWindowRetryPolicy(t *testing.T) {
+	c := newFakeClient()
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "context-window-route", Namespace: "default"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{
+					{Name: "small", ContextWindow: ptr.To[int32](8000)},
+					{Name: "large", ContextWindow: ptr.To[int32](128000)},
+					{Name: "unbounded"},
+				}},
+				{
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{
+						{Name: "small", ContextWindow: ptr.To[int32](8000)},
+						{Name: "unbounded"},
+					},
+					FallbackPolicy: &aigv1b1.AIGatewayRouteRuleFallbackPolicy{
+						Actions: []aigv1b1.AIGatewayRouteRuleFallbackAction{
+							{ErrorClass: aigv1b1.ErrorClassRateLimit, Action: aigv1b1.FallbackActionTypeRetry},
+						},
+						NumRetries: ptr.To[int32](3),
+					},
+				},
+				{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{
+					{Name: "a", ContextWindow: ptr.To[int32](8000)},
+					{Name: "b", ContextWindow: ptr.To[int32](8000)},
+				}},
+			},
+		},
+	}))
+	s, err := New(c, logr.Discard(), udsPath, false, nil, nil, "envoy-ai-gateway-ratelimit.envoy-gateway-system", 5, false)
+	require.NoError(t, err)
+
+	apply := func(t *testing.T, ruleIndex int) *routev3.RetryPolicy {
+		route := &routev3.Route{
+			Name:   fmt.Sprintf("httproute/default/context-window-route/rule/%d/match/0", ruleIndex),
+			Action: &routev3.Route_Route{Route: &routev3.RouteAction{}},
+		}
+		require.NoError(t, s.applyRoutePolicies(t.Context(), []*routev3.RouteConfiguration{{
+			VirtualHosts: []*routev3.VirtualHost{{Routes: []*routev3.Route{route}}},
+		}}))
+		return route.GetRoute().RetryPolicy
+	}
+
+	t.Run("different context windows", func(t *testing.T) {
+		rp := apply(t, 0)
+		require.Equal(t, "retriable-headers", rp.RetryOn)
+		require.Len(t, rp.RetriableHeaders, 1)
+		require.True(t, proto.Equal(&routev3.HeaderMatcher{
+			Name: internalapi.FallbackErrorClassHeader,
+			HeaderMatchSpecifier: &routev3.HeaderMatcher_StringMatch{
+				StringMatch: &matcherv3.StringMatcher{MatchPattern: &matcherv3.StringMatcher_Exact{Exact: "context_length_exceeded"}},
+			},
+		}, rp.RetriableHeaders[0]))
+		// Every other backend is tried once.
+		require.Equal(t, uint32(2), rp.NumRetries.GetValue())
+		require.Len(t, rp.RetryHostPredicate, 1)
+		require.Equal(t, "envoy.retry_host_predicates.previous_hosts", rp.RetryHostPredicate[0].Name)
+	})
+
+	t.Run("with fallback policy", func(t *testing.T) {
+		rp := apply(t, 1)
+		// The retry conditions of the fallback policy are kept, which retry any class set by the upstream filter.
+		require.Equal(t, "retriable-headers,reset,connect-failure,refused-stream", rp.RetryOn)
+		require.Len(t, rp.RetriableHeaders, 1)
+		require.True(t, rp.RetriableHeaders[0].GetPresentMatch())
+		require.Equal(t, uint32(3), rp.NumRetries.GetValue())
+		require.Len(t, rp.RetryHostPredicate, 1)
+	})
+
+	t.Run("same context windows", func(t *testing.T) {
+		require.Nil(t, apply(t, 2))
+	})
+}
+
+func TestMaybeSetCircuitBreakerRetryPolicy(t *testing.T) {
+	c := newFakeClient()
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIServiceBackend{
+		ObjectMeta: metav1.ObjectMeta{Name: "guarded", Namespace: "default"},
+		Spec:       aigv1b1.AIServiceBackendSpec{CircuitBreaker: &aigv1b1.CircuitBreaker{}},
+	}))
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIServiceBackend{
+		ObjectMeta: metav1.ObjectMeta{Name: "plain", Namespace: "default"},
+	}))
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "breaker-route", Namespace: "default"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "plain"}, {Name: "guarded"}, {Name: "missing"}}},
+				{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "plain"}, {Name: "missing"}}},
+				{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "guarded"}}},
+				{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{
+					{Name: "guarded", ContextWindow: ptr.To[int32](8000)},
+					{Name: "plain"},
+				}},
+			},
+		},
+	}))
+	s, err := New(c, logr.Discard(), udsPath, false, nil, nil, "envoy-ai-gateway-ratelimit.envoy-gateway-system", 5, false)
+	require.NoError(t, err)
+
+	apply := func(t *testing.T, ruleIndex int) *routev3.RetryPolicy {
+		route := &routev3.Route{
+			Name:   fmt.Sprintf("httproute/default/breaker-route/rule/%d/match/0", ruleIndex),
+			Action: &routev3.Route_Route{Route: &routev3.RouteAction{}},
+		}
+		require.NoError(t, s.applyRoutePolicies(t.Context(), []*routev3.RouteConfiguration{{
+			VirtualHosts: []*routev3.VirtualHost{{Routes: []*routev3.Route{route}}},
+		}}))
+		return route.GetRoute().RetryPolicy
+	}
+	exact := func(class string) *routev3.HeaderMatcher {
+		return &routev3.HeaderMatcher{
+			Name: internalapi.FallbackErrorClassHeader,
+			HeaderMatchSpecifier: &routev3.HeaderMatcher_StringMatch{
+				StringMatch: &matcherv3.StringMatcher{MatchPattern: &matcherv3.StringMatcher_Exact{Exact: class}},
+			},
+		}
+	}
+
+	t.Run("backend with circuit breaker", func(t *testing.T) {
+		rp := apply(t, 0)
+		require.Equal(t, "retriable-headers", rp.RetryOn)
+		require.Len(t, rp.RetriableHeaders, 1)
+		require.True(t, proto.Equal(exact("overloaded"), rp.RetriableHeaders[0]))
+		// Every other backend is tried once.
+		require.Equal(t, uint32(2), rp.NumRetries.GetValue())
+		require.Len(t, rp.RetryHostPredicate, 1)
+	})
+	t.Run("no circuit breaker", func(t *testing.T) {
+		require.Nil(t, apply(t, 1))
+	})
+	t.Run("single backend", func(t *testing.T) {
+		require.Nil(t, apply(t, 2))
+	})
+	t.Run("with different context windows", func(t *testing.T) {
+		rp := apply(t, 3)
+		require.Len(t, rp.RetriableHeaders, 2)
+		require.True(t, proto.Equal(exact("context_length_exceeded"), rp.RetriableHeaders[0]))
+		require.True(t, proto.Equal(exact("overloaded"), rp.RetriableHeaders[1]))
+		require.Equal(t, uint32(1), rp.NumRetries.GetValue())
+	})
+}
+
+// TestMaybeModifyClusterFallbackPolicy tests that the upstream filter receives the response headers only for
+// the clusters of the rules with a FallbackPolicy.
+func TestMaybeModifyClusterFallbackPolicy(t *testing.T) {
+	c := newFakeClient()
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "fallback-route", Namespace: "ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				{
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "aaa"}},
+					FallbackPolicy: &aigv1b1.AIGatewayRouteRuleFallbackPolicy{
+						Actions: []aigv1b1.AIGatewayRouteRuleFallbackAction{
+							{ErrorClass: aigv1b1.ErrorClassRateLimit, Action: aigv1b1.FallbackActionTypeFailover},
+						},
+					},
+				},
+				{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "bbb"}}},
+				{
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{
+						{Name: "ccc", Priority: ptr.To[uint32](0)},
+						{Name: "ddd", Priority: ptr.To[uint32](1)},
+					},
+					FallbackPolicy: &aigv1b1.AIGatewayRouteRuleFallbackPolicy{
+						Actions: []aigv1b1.AIGatewayRouteRuleFallbackAction{
+							{ErrorClass: aigv1b1.ErrorClassRateLimit, Action: aigv1b1.FallbackActionTypeFailover},
+							{ErrorClass: aigv1b1.ErrorClassOverloaded, Action: aigv1b1.FallbackActionTypeRetry},
+						},
+					},
+				},
+			},
+		},
+	}))
+	s, err := New(c, logr.Discard(), udsPath, false, nil, nil, "envoy-ai-gateway-ratelimit.envoy-gateway-system", 5, false)
+	require.NoError(t, err)
+
+	modify := func(t *testing.T, clusterName string, endpoints int) (*clusterv3.Cluster, *extprocv3.ExternalProcessor) {
+		cluster := &clusterv3.Cluster{Name: clusterName, LoadAssignment: &endpointv3.ClusterLoadAssignment{}}
+		for range endpoints {
+			cluster.LoadAssignment.Endpoints = append(cluster.LoadAssignment.Endpoints,
+				&endpointv3.LocalityLbEndpoints{LbEndpoints: []*endpointv3.LbEndpoint{{}}})
+		}
+		require.NoError(t, s.maybeModifyCluster(t.Context(), cluster))
+		var po httpv3.HttpProtocolOptions
+		require.NoError(t, cluster.TypedExtensionProtocolOptions["envoy.extensions.upstreams.http.v3.HttpProtocolOptions"].UnmarshalTo(&po))
+		for _, f := range po.HttpFilters {
+			if f.Name == aiGatewayExtProcName {
+				var ep extprocv3.ExternalProcessor
+				require.NoError(t, f.GetTypedConfig().UnmarshalTo(&ep))
+				return cluster, &ep
+			}
+		}
+		t.Fatal("ext_proc filter not found")
+		return nil, nil
+	}
+	endpointPriority := func(cluster *clusterv3.Cluster, i int) *structpb.Value {
+		return cluster.LoadAssignment.Endpoints[i].LbEndpoints[0].Metadata.
+			FilterMetadata[internalapi.InternalEndpointMetadataNamespace].Fields[internalapi.InternalMetadataPriorityKey]
+	}
+
+	cluster, ep := modify(t, "httproute/ns/fallback-route/rule/0", 1)
+	require.Equal(t, extprocv3.ProcessingMode_SEND, ep.ProcessingMode.ResponseHeaderMode)
+	// The priorities are only needed by the upstream filter when the policy both retries and fails over.
+	require.NotContains(t, ep.RequestAttributes, internalapi.XDSUpstreamHostMetadataPriorityPath)
+	require.Nil(t, endpointPriority(cluster, 0))
+
+	_, ep = modify(t, "httproute/ns/fallback-route/rule/1", 1)
+	require.Equal(t, extprocv3.ProcessingMode_SKIP, ep.ProcessingMode.ResponseHeaderMode)
+
+	cluster, ep = modify(t, "httproute/ns/fallback-route/rule/2", 2)
+	require.Equal(t, extprocv3.ProcessingMode_SEND, ep.ProcessingMode.ResponseHeaderMode)
+	require.Contains(t, ep.RequestAttributes, internalapi.XDSUpstreamHostMetadataPriorityPath)
+	for i := range 2 {
+		require.Equal(t, float64(i), endpointPriority(cluster, i).GetNumberValue())
+	}
+}
+
+func TestMaybeModifyClusterStreamFailover(t *testing.T) {
+	c := newFakeClient()
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "sf-route", Namespace: "ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				{
+					BackendRefs:    []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "aaa"}},
+					StreamFailover: &aigv1b1.AIGatewayRouteRuleStreamFailover{Name: "bbb"},
+				},
+				{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "bbb"}}},
+			},
+		},
+	}))
+	s, err := New(c, logr.Discard(), udsPath, false, nil, nil, "envoy-ai-gateway-ratelimit.envoy-gateway-system", 5, false)
+	require.NoError(t, err)
+
+	filterNames := func(t *testing.T, clusterName string) []string {
+		cluster := &clusterv3.Cluster{Name: clusterName}
+		require.NoError(t, s.maybeModifyCluster(t.Context(), cluster))
+		var po httpv3.HttpProtocolOptions
+		require.NoError(t, cluster.TypedExtensionProtocolOptions["envoy.extensions.upstreams.http.v3.HttpProtocolOptions"].UnmarshalTo(&po))
+		var names []string
+		for _, f := range po.HttpFilters {
+			names = append(names, f.Name)
+			if f.Name == streamFailoverExtProcName {
+				var ep extprocv3.ExternalProcessor
+				require.NoError(t, f.GetTypedConfig().UnmarshalTo(&ep))
+				require.Equal(t, extprocv3.ProcessingMode_FULL_DUPLEX_STREAMED, ep.ProcessingMode.ResponseBodyMode)
+				require.Equal(t, extprocv3.ProcessingMode_SEND, ep.ProcessingMode.ResponseTrailerMode)
+				require.Equal(t, internalapi.StreamFailoverFilterMetadataKey, ep.GrpcService.InitialMetadata[0].Key)
+			}
+		}
+		return names
+	}
+	// The stream failover filter follows the AI Gateway upstream filter so that it sees the response first.
+	require.Equal(t, []string{aiGatewayExtProcName, streamFailoverExtProcName, "envoy.filters.http.header_mutation", "envoy.filters.http.upstream_codec"},
+		filterNames(t, "httproute/ns/sf-route/rule/0"))
+	require.NotContains(t, filterNames(t, "httproute/ns/sf-route/rule/1"), streamFailoverExtProcName)
+}
+
+func TestMaybeSetHedgePolicy(t *testing.T) {
+	c := newFakeClient()
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "hedge-route", Namespace: "default"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				{HedgePolicy: &aigv1b1.AIGatewayRouteRuleHedgePolicy{Delay: "500ms"}},
+				{
+					FallbackPolicy: &aigv1b1.AIGatewayRouteRuleFallbackPolicy{
+						Actions: []aigv1b1.AIGatewayRouteRuleFallbackAction{
+							{ErrorClass: aigv1b1.ErrorClassRateLimit, Action: aigv1b1.FallbackActionTypeRetry},
+						},
+					},
+					HedgePolicy: &aigv1b1.AIGatewayRouteRuleHedgePolicy{Delay: "1s", MaxHedgedRequests: ptr.To[int32](3)},
+				},
+				{}, // No HedgePolicy.
+			},
+		},
+	}))
+	s, err := New(c, logr.Discard(), udsPath, false, nil, nil, "envoy-ai-gateway-ratelimit.envoy-gateway-system", 5, false)
+	require.NoError(t, err)
+
+	apply := func(t *testing.T, ruleIndex int) []*routev3.Route {
+		route := &routev3.Route{
+			Name:   fmt.Sprintf("httproute/default/hedge-route/rule/%d/match/0", ruleIndex),
+			Match:  &routev3.RouteMatch{PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: "/"}},
+			Action: &routev3.Route_Route{Route: &routev3.RouteAction{}},
+		}
+		vh := &routev3.VirtualHost{Routes: []*routev3.Route{route}}
+		require.NoError(t, s.applyRoutePolicies(t.Context(), []*routev3.RouteConfiguration{{
+			VirtualHosts: []*routev3.VirtualHost{vh},
+		}}))
+		return vh.Routes
+	}
+	// requireHedged checks that the hedged route precedes the original one, and only matches the streaming requests.
+	requireHedged := func(t *testing.T, routes []*routev3.Route) *routev3.RouteAction {
+		require.Len(t, routes, 2)
+		hedged, original := routes[0], routes[1]
+		require.Equal(t, original.Name+"/hedge", hedged.Name)
+		require.Equal(t, "/", hedged.Match.GetPrefix())
+		require.Len(t, hedged.Match.Headers, 1)
+		require.Equal(t, internalapi.StreamHeader, hedged.Match.Headers[0].Name)
+		require.Equal(t, "true", hedged.Match.Headers[0].GetStringMatch().GetExact())
+		require.Empty(t, original.Match.Headers)
+		require.Nil(t, original.GetRoute().HedgePolicy)
+		return hedged.GetRoute()
+	}
+
+	t.Run("hedge only", func(t *testing.T) {
+		routes := apply(t, 0)
+		action := requireHedged(t, routes)
+		require.True(t, action.HedgePolicy.HedgeOnPerTryTimeout)
+		rp := action.RetryPolicy
+		require.Equal(t, "reset,connect-failure,refused-stream", rp.RetryOn)
+		require.Equal(t, durationpb.New(500*time.Millisecond), rp.PerTryTimeout)
+		require.Equal(t, uint32(1), rp.NumRetries.GetValue())
+		require.Len(t, rp.RetryHostPredicate, 1)
+		require.Equal(t, "envoy.retry_host_predicates.previous_hosts", rp.RetryHostPredicate[0].Name)
+		require.Equal(t, int64(5), rp.HostSelectionRetryMaxAttempts)
+		// The non-streaming requests are not hedged.
+		require.Nil(t, routes[1].GetRoute().RetryPolicy)
+	})
+
+	t.Run("with fallback policy", func(t *testing.T) {
+		routes := apply(t, 1)
+		action := requireHedged(t, routes)
+		require.True(t, action.HedgePolicy.HedgeOnPerTryTimeout)
+		rp := action.RetryPolicy
+		// The retry conditions of the fallback policy are kept.
+		require.Equal(t, "retriable-headers,reset,connect-failure,refused-stream", rp.RetryOn)
+		require.Equal(t, durationpb.New(time.Second), rp.PerTryTimeout)
+		require.Equal(t, uint32(3), rp.NumRetries.GetValue())
+		// The retry policy of the original route is the one of the fallback policy.
+		original := routes[1].GetRoute().RetryPolicy
+		require.Equal(t, "retriable-headers,reset,connect-failure,refused-stream", original.RetryOn)
+		require.Nil(t, original.PerTryTimeout)
+		require.Empty(t, original.RetryHostPredicate)
+	})
+
+	t.Run("no policy", func(t *testing.T) {
+		routes := apply(t, 2)
+		require.Len(t, routes, 1)
+		action := routes[0].GetRoute()
+		require.Nil(t, action.HedgePolicy)
+		require.Nil(t, action.RetryPolicy)
+	})
+}
+
+// TestMaybeModifyClusterHedgePolicy tests that the upstream filter receives the response headers for the clusters
+// of the rules with a HedgePolicy.
+func TestMaybeModifyClusterHedgePolicy(t *testing.T) {
+	c := newFakeClient()
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "hedge-route", Namespace: "ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{{
+				BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "aaa"}, {Name: "bbb"}},
+				HedgePolicy: &aigv1b1.AIGatewayRouteRuleHedgePolicy{Delay: "500ms"},
+			}},
+		},
+	}))
+	s, err := New(c, logr.Discard(), udsPath, false, nil, nil, "envoy-ai-gateway-ratelimit.envoy-gateway-system", 5, false)
+	require.NoError(t, err)
+
+	cluster := &clusterv3.Cluster{Name: "httproute/ns/hedge-route/rule/0"}
+	require.NoError(t, s.maybeModifyCluster(t.Context(), cluster))
+	var po httpv3.HttpProtocolOptions
+	require.NoError(t, cluster.TypedExtensionProtocolOptions["envoy.extensions.upstreams.http.v3.HttpProtocolOptions"].UnmarshalTo(&po))
+	var ep extprocv3.ExternalProcessor
+	for _, f := range po.HttpFilters {
+		if f.Name == aiGatewayExtProcName {
+			require.NoError(t, f.GetTypedConfig().UnmarshalTo(&ep))
+		}
+	}
+	require.Equal(t, extprocv3.ProcessingMode_SEND, ep.GetProcessingMode().GetResponseHeaderMode())
+}
+
+func TestMaybeSetAffinityHashPolicy(t *testing.T) {
+	c := newFakeClient()
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "affinity-route", Namespace: "default"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				{
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "aaa"}},
+					Affinity:    &aigv1b1.AIGatewayRouteRuleAffinity{Type: aigv1b1.AffinityTypePrompt},
+				},
+				{
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "aaa"}},
+					Affinity: &aigv1b1.AIGatewayRouteRuleAffinity{
+						Type:       aigv1b1.AffinityTypeHeader,
+						HeaderName: ptr.To("x-session-id"),
+					},
+				},
+				{
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{
+						Name:  "pool",
+						Group: ptr.To("inference.networking.k8s.io"),
+						Kind:  ptr.To("InferencePool"),
+					}},
+					Affinity: &aigv1b1.AIGatewayRouteRuleAffinity{Type: aigv1b1.AffinityTypePrompt},
+				},
+				{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "aaa"}}}, // No Affinity.
+			},
+		},
+	}))
+	s, err := New(c, logr.Discard(), udsPath, false, nil, nil, "envoy-ai-gateway-ratelimit.envoy-gateway-system", 5, false)
+	require.NoError(t, err)
+
+	hashPolicy := func(t *testing.T, ruleIndex int) []*routev3.RouteAction_HashPolicy {
+		route := &routev3.Route{
+			Name:   fmt.Sprintf("httproute/default/affinity-route/rule/%d/match/0", ruleIndex),
+			Action: &routev3.Route_Route{Route: &routev3.RouteAction{}},
+		}
+		cache := make(map[client.ObjectKey]*aigv1b1.AIGatewayRoute)
+		require.NoError(t, s.maybeSetAffinityHashPolicy(t.Context(), route, cache))
+		return route.GetRoute().HashPolicy
+	}
+	headerOf := func(t *testing.T, hp []*routev3.RouteAction_HashPolicy) string {
+		require.Len(t, hp, 1)
+		return hp[0].GetHeader().GetHeaderName()
+	}
+
+	t.Run("prompt", func(t *testing.T) {
+		require.Equal(t, "x-ai-eg-affinity-prompt-1", headerOf(t, hashPolicy(t, 0)))
+	})
+	t.Run("header", func(t *testing.T) {
+		require.Equal(t, "x-session-id", headerOf(t, hashPolicy(t, 1)))
+	})
+	t.Run("inference pool", func(t *testing.T) {
+		require.Nil(t, hashPolicy(t, 2))
+	})
+	t.Run("no affinity", func(t *testing.T) {
+		require.Nil(t, hashPolicy(t, 3))
+	})
+	t.Run("cluster per backend ref", func(t *testing.T) {
+		route := &routev3.Route{
+			Name: "httproute/default/affinity-route/rule/0/match/0",
+			Action: &routev3.Route_Route{Route: &routev3.RouteAction{ClusterSpecifier: &routev3.RouteAction_WeightedClusters{
+				WeightedClusters: &routev3.WeightedCluster{Clusters: []*routev3.WeightedCluster_ClusterWeight{
+					{Name: "httproute/default/affinity-route/rule/0/backend/0"},
+					{Name: "httproute/default/affinity-route/rule/0/backend/1"},
+				}},
+			}}},
+		}
+		require.NoError(t, s.maybeSetAffinityHashPolicy(t.Context(), route, make(map[client.ObjectKey]*aigv1b1.AIGatewayRoute)))
+		// The cluster is picked with the hash of the key as well, so that the key sticks to a backend.
+		require.True(t, route.GetRoute().GetWeightedClusters().GetUseHashPolicy().GetValue())
+		require.Equal(t, "x-ai-eg-affinity-prompt-1", headerOf(t, route.GetRoute().HashPolicy))
+	})
+}
+
+// TestMaybeModifyClusterAffinity tests that the clusters of the rules with an Affinity use the ring hash load balancer.
+func TestMaybeModifyClusterAffinity(t *testing.T) {
+	c := newFakeClient()
+	require.NoError(t, c.Create(t.Context(), &aigv1b1.AIGatewayRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "affinity-route", Namespace: "ns"},
+		Spec: aigv1b1.AIGatewayRouteSpec{
+			Rules: []aigv1b1.AIGatewayRouteRule{
+				{
+					BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "aaa"}},
+					Affinity:    &aigv1b1.AIGatewayRouteRuleAffinity{Type: aigv1b1.AffinityTypePrompt},
+				},
+				{BackendRefs: []aigv1b1.AIGatewayRouteRuleBackendRef{{Name: "bbb"}}},
+			},
+		},
+	}))
+	s, err := New(c, logr.Discard(), udsPath, false, nil, nil, "envoy-ai-gateway-ratelimit.envoy-gateway-system", 5, false)
+	require.NoError(t, err)
+
+	cluster := &clusterv3.Cluster{Name: "httproute/ns/affinity-route/rule/0"}
+	require.NoError(t, s.maybeModifyCluster(t.Context(), cluster))
+	require.Equal(t, clusterv3.Cluster_RING_HASH, cluster.LbPolicy)
+	require.NotNil(t, cluster.GetRingHashLbConfig())
+	require.Nil(t, cluster.LoadBalancingPolicy)
+
+	cluster = &clusterv3.Cluster{Name: "httproute/ns/affinity-route/rule/1"}
+	require.NoError(t, s.maybeModifyCluster(t.Context(), cluster))
+	require.Equal(t, clusterv3.Cluster_ROUND_ROBIN, cluster.LbPolicy)
+	require.Nil(t, cluster.GetRingHashLbConfig())
+}
+
 // TestConstructInferencePoolsFrom tests the constructInferencePoolsFrom method.
 func TestConstructInferencePoolsFrom(t *testing.T) {
 	logger := logr.Discard()
@@ -9,6 +9,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -25,23 +26,32 @@ import (
 	header_mutationv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/header_mutation/v3"
 	upstream_codecv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/upstream_codec/v3"
 	httpconnectionmanagerv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	previous_hostsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/retry/host/previous_hosts/v3"
+	previous_prioritiesv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/retry/priority/previous_priorities/v3"
 	httpv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	gwaiev1 "sigs.k8s.io/gateway-api-inference-extension/api/v1"
 
 	aigv1b1 "github.com/envoyproxy/ai-gateway/api/v1beta1"
+	"github.com/envoyproxy/ai-gateway/internal/errorclass"
 	"github.com/envoyproxy/ai-gateway/internal/internalapi"
 )
 
 const (
 	extProcUDSClusterName = "ai-gateway-extproc-uds"
 	aiGatewayExtProcName  = "envoy.filters.http.ext_proc/aigateway"
-	noBackendRefIndex     = -1
+	// streamFailoverExtProcName is the name of the upstream filter continuing the truncated streaming responses of
+	// the rules with StreamFailover. See buildStreamFailoverExtProcFilter.
+	streamFailoverExtProcName = "envoy.filters.http.ext_proc/aigateway-stream-failover"
+	noBackendRefIndex         = -1
 )
 
 type aiGatewayClusterName struct {
@@ -118,9 +128,9 @@ func (s *Server) PostTranslateModify(ctx context.Context, req *egextension.PostT
 		return nil, fmt.Errorf("failed to modify listeners and routes for InferencePool support: %w", err)
 	}
 
-	// Apply the per-rule stream idle timeout to the generated routes.
-	if err = s.applyStreamIdleTimeouts(ctx, req.Routes); err != nil {
-		return nil, fmt.Errorf("failed to apply stream idle timeouts: %w", err)
+	// Apply the per-rule stream idle timeout, fallback policy and affinity to the generated routes.
+	if err = s.applyRoutePolicies(ctx, req.Routes); err != nil {
+		return nil, fmt.Errorf("failed to apply route policies: %w", err)
 	}
 
 	// Ensure the AI Gateway external processor UDS cluster exists.
@@ -195,22 +205,53 @@ func (s *Server) PostTranslateModify(ctx context.Context, req *egextension.PostT
 		return nil, fmt.Errorf("failed to inject quota rate limiting: %w", err)
 	}
 
+	// Move the routes of the shadow backends to the internal shadow listener. This is done last so that none of the
+	// filters of the Gateway listeners applies to the copies of the requests.
+	if err = s.maybeGenerateResourcesForShadows(req); err != nil {
+		return nil, fmt.Errorf("failed to generate resources for shadows: %w", err)
+	}
+
 	response := &egextension.PostTranslateModifyResponse{Clusters: req.Clusters, Secrets: req.Secrets, Listeners: req.Listeners, Routes: req.Routes}
 	return response, nil
 }
 
-// applyStreamIdleTimeouts walks the generated route configurations and sets the per-try idle
-// timeout on every AIGatewayRoute route whose rule configures StreamIdleTimeout.
+// applyRoutePolicies walks the generated route configurations and updates the retry policy of every
+// AIGatewayRoute route whose rule configures StreamIdleTimeout or FallbackPolicy or whose backends have different
+// context windows or a circuit breaker, and the hash policy of every
+// AIGatewayRoute route whose rule configures Affinity. The route of a rule with HedgePolicy is preceded by its copy
+// hedging the streaming requests.
 // Lookups are cached to avoid hitting the API server more than once per route.
-func (s *Server) applyStreamIdleTimeouts(ctx context.Context, routeConfigs []*routev3.RouteConfiguration) error {
+func (s *Server) applyRoutePolicies(ctx context.Context, routeConfigs []*routev3.RouteConfiguration) error {
 	cache := make(map[client.ObjectKey]*aigv1b1.AIGatewayRoute)
 	for _, rc := range routeConfigs {
 		for _, vh := range rc.VirtualHosts {
+			routes := make([]*routev3.Route, 0, len(vh.Routes))
 			for _, route := range vh.Routes {
 				if err := s.maybeSetStreamIdleTimeout(ctx, route, cache); err != nil {
 					return err
 				}
+				if err := s.maybeSetFallbackPolicy(ctx, route, cache); err != nil {
+					return err
+				}
+				if err := s.maybeSetContextWindowRetryPolicy(ctx, route, cache); err != nil {
+					return err
+				}
+				if err := s.maybeSetCircuitBreakerRetryPolicy(ctx, route, cache); err != nil {
+					return err
+				}
+				if err := s.maybeSetAffinityHashPolicy(ctx, route, cache); err != nil {
+					return err
+				}
+				hedged, err := s.maybeHedgedRoute(ctx, route, cache)
+				if err != nil {
+					return err
+				}
+				if hedged != nil {
+					routes = append(routes, hedged)
+				}
+				routes = append(routes, route)
 			}
+			vh.Routes = routes
 		}
 	}
 	return nil
@@ -221,47 +262,366 @@ func (s *Server) applyStreamIdleTimeouts(ctx context.Context, routeConfigs []*ro
 // duration, so a retry policy covering `reset` falls over to the next backend before any
 // response reaches the downstream client.
 func (s *Server) maybeSetStreamIdleTimeout(ctx context.Context, route *routev3.Route, cache map[client.ObjectKey]*aigv1b1.AIGatewayRoute) error {
+	rule, err := s.aiGatewayRouteRuleOf(ctx, route, cache)
+	if err != nil || rule == nil {
+		return err
+	}
+
+	timeout := rule.GetStreamIdleTimeout()
+	if timeout <= 0 {
+		return nil
+	}
+
 	action := route.GetRoute()
-	if action == nil {
-		// Not a forwarding route (e.g. DirectResponse, Redirect).
+	if action.RetryPolicy == nil {
+		action.RetryPolicy = &routev3.RetryPolicy{}
+	}
+	action.RetryPolicy.PerTryIdleTimeout = durationpb.New(timeout)
+	return nil
+}
+
+// maybeSetFallbackPolicy configures route.retry_policy from the rule's FallbackPolicy. The upstream filter sets
+// the fallback header on the error responses whose class is to be retried, so the retry policy retries on the
+// presence of the header as well as on the connection failures, instead of on the status codes.
+//
+// When the policy fails over on some classes, the retries are sent to the next priority. If it also retries the
+// other classes on the same priority, the priority is only updated every two attempts so that the first retry on
+// each priority stays on it, and the upstream filter rejects the attempts on a priority without calling the backend
+// once an attempt on it failed with a class to fail over on. The upstream filter knows the priority of each attempt
+// from the metadata of the endpoint selected by Envoy, so that the attempts rejected by the upstream filter itself,
+// e.g., by an open circuit breaker, do not shift it.
+func (s *Server) maybeSetFallbackPolicy(ctx context.Context, route *routev3.Route, cache map[client.ObjectKey]*aigv1b1.AIGatewayRoute) error {
+	rule, err := s.aiGatewayRouteRuleOf(ctx, route, cache)
+	if err != nil || rule == nil || rule.FallbackPolicy == nil {
+		return err
+	}
+
+	action := route.GetRoute()
+	if action.RetryPolicy == nil {
+		action.RetryPolicy = &routev3.RetryPolicy{}
+	}
+	rp := action.RetryPolicy
+	rp.RetryOn = "retriable-headers,reset,connect-failure,refused-stream"
+	rp.RetriableStatusCodes = nil
+	rp.RetriableHeaders = []*routev3.HeaderMatcher{{
+		Name:                 internalapi.FallbackErrorClassHeader,
+		HeaderMatchSpecifier: &routev3.HeaderMatcher_PresentMatch{PresentMatch: true},
+	}}
+	rp.NumRetries = wrapperspb.UInt32(uint32(ptr.Deref(rule.FallbackPolicy.NumRetries, 2))) // #nosec G115
+	rp.RetryPriority = nil
+	hasAction := func(t aigv1b1.FallbackActionType) bool {
+		return slices.ContainsFunc(rule.FallbackPolicy.Actions, func(a aigv1b1.AIGatewayRouteRuleFallbackAction) bool {
+			return a.Action == t
+		})
+	}
+	if !hasAction(aigv1b1.FallbackActionTypeFailover) {
+		return nil
+	}
+	updateFrequency := int32(1)
+	if hasAction(aigv1b1.FallbackActionTypeRetry) {
+		updateFrequency = 2
+	}
+	priorityAny, err := toAny(&previous_prioritiesv3.PreviousPrioritiesConfig{UpdateFrequency: updateFrequency})
+	if err != nil {
+		return fmt.Errorf("failed to marshal PreviousPrioritiesConfig to Any: %w", err)
+	}
+	rp.RetryPriority = &routev3.RetryPolicy_RetryPriority{
+		Name:       "envoy.retry_priorities.previous_priorities",
+		ConfigType: &routev3.RetryPolicy_RetryPriority_TypedConfig{TypedConfig: priorityAny},
+	}
+	return nil
+}
+
+// maybeSetContextWindowRetryPolicy configures route.retry_policy to retry the requests rejected by a backend whose
+// context window is too small on the other backends of the rule, when their context windows differ. The upstream
+// filter rejects such a request with the ContextLength class in the fallback error class header, and the retry is
+// sent to a host that has not been tried yet, so that every backend is tried at most once.
+//
+// This runs after maybeSetFallbackPolicy so that the retry conditions of the fallback policy are kept.
+func (s *Server) maybeSetContextWindowRetryPolicy(ctx context.Context, route *routev3.Route, cache map[client.ObjectKey]*aigv1b1.AIGatewayRoute) error {
+	rule, err := s.aiGatewayRouteRuleOf(ctx, route, cache)
+	if err != nil || rule == nil || !hasDifferentContextWindows(rule) {
+		return err
+	}
+
+	return retryOnOtherBackends(route.GetRoute(), len(rule.BackendRefs), errorclass.ContextLength)
+}
+
+// maybeSetCircuitBreakerRetryPolicy configures route.retry_policy to retry the requests rejected by a backend whose
+// circuit breaker is open on the other backends of the rule, so that an open circuit breaker does not fail the request
+// while another backend can serve it. The upstream filter rejects such a request with the Overloaded class in the
+// fallback error class header.
+//
+// This runs after maybeSetFallbackPolicy so that the retry conditions of the fallback policy are kept.
+func (s *Server) maybeSetCircuitBreakerRetryPolicy(ctx context.Context, route *routev3.Route, cache map[client.ObjectKey]*aigv1b1.AIGatewayRoute) error {
+	rule, err := s.aiGatewayRouteRuleOf(ctx, route, cache)
+	if err != nil || rule == nil || len(rule.BackendRefs) < 2 {
+		return err
+	}
+	namespace := strings.Split(route.Name, "/")[1]
+	hasCircuitBreaker := false
+	for i := range rule.BackendRefs {
+		ref := &rule.BackendRefs[i]
+		if ref.IsInferencePool() {
+			continue
+		}
+		var backend aigv1b1.AIServiceBackend
+		if err = s.k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, &backend); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get AIServiceBackend %s/%s: %w", namespace, ref.Name, err)
+		}
+		if backend.Spec.CircuitBreaker != nil {
+			hasCircuitBreaker = true
+			break
+		}
+	}
+	if !hasCircuitBreaker {
 		return nil
 	}
+	return retryOnOtherBackends(route.GetRoute(), len(rule.BackendRefs), errorclass.Overloaded)
+}
+
+// retryOnOtherBackends configures the retry policy of the route to retry the requests rejected by the upstream filter
+// with the given class in the fallback error class header on the hosts that have not been tried yet, so that every
+// backend is tried at most once. The retry conditions already configured are kept.
+func retryOnOtherBackends(action *routev3.RouteAction, backends int, class errorclass.Class) error {
+	if action.RetryPolicy == nil {
+		action.RetryPolicy = &routev3.RetryPolicy{}
+	}
+	rp := action.RetryPolicy
+	if rp.RetryOn == "" {
+		rp.RetryOn = "retriable-headers"
+	} else if !slices.Contains(strings.Split(rp.RetryOn, ","), "retriable-headers") {
+		rp.RetryOn += ",retriable-headers"
+	}
+	if !retriesOnErrorClass(rp, class) {
+		rp.RetriableHeaders = append(rp.RetriableHeaders, &routev3.HeaderMatcher{
+			Name: internalapi.FallbackErrorClassHeader,
+			HeaderMatchSpecifier: &routev3.HeaderMatcher_StringMatch{
+				StringMatch: &matcherv3.StringMatcher{MatchPattern: &matcherv3.StringMatcher_Exact{Exact: string(class)}},
+			},
+		})
+	}
+	if others := uint32(backends - 1); rp.NumRetries.GetValue() < others { // #nosec G115
+		rp.NumRetries = wrapperspb.UInt32(others)
+	}
+	if len(rp.RetryHostPredicate) == 0 {
+		predicateAny, err := toAny(&previous_hostsv3.PreviousHostsPredicate{})
+		if err != nil {
+			return fmt.Errorf("failed to marshal PreviousHostsPredicate to Any: %w", err)
+		}
+		rp.RetryHostPredicate = []*routev3.RetryPolicy_RetryHostPredicate{{
+			Name:       "envoy.retry_host_predicates.previous_hosts",
+			ConfigType: &routev3.RetryPolicy_RetryHostPredicate_TypedConfig{TypedConfig: predicateAny},
+		}}
+		rp.HostSelectionRetryMaxAttempts = 5
+	}
+	return nil
+}
+
+// retriesOnErrorClass returns true if the retriable headers of the retry policy match the fallback error class header
+// with the given class, i.e., either its presence or the class itself.
+func retriesOnErrorClass(rp *routev3.RetryPolicy, class errorclass.Class) bool {
+	for _, h := range rp.RetriableHeaders {
+		if h.Name != internalapi.FallbackErrorClassHeader {
+			continue
+		}
+		if h.GetPresentMatch() || h.GetStringMatch().GetExact() == string(class) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDifferentContextWindows returns true if a backend of the rule has a context window smaller than another backend,
+// where a backend without a context window can serve the requests of any size.
+func hasDifferentContextWindows(rule *aigv1b1.AIGatewayRouteRule) bool {
+	for i := range rule.BackendRefs {
+		a := rule.BackendRefs[i].ContextWindow
+		for j := range rule.BackendRefs {
+			b := rule.BackendRefs[j].ContextWindow
+			if a != nil && (b == nil || *b > *a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maybeHedgedRoute returns the copy of the route hedging the streaming requests from the rule's HedgePolicy, or nil if
+// the rule has no HedgePolicy. The copy matches the stream header set by the router filter in addition to the matches
+// of the route, and precedes it, so that the non-streaming requests are never hedged: their response headers are
+// only returned once the whole response is generated, so the per-try timeout would fire on most of them.
+//
+// The copy configures route.hedge_policy and route.retry_policy. Envoy sends another request when the per-try timeout
+// fires without cancelling the ones in flight, and returns the response that starts first. The hedged requests are
+// retries of the route, so they are sent to the hosts that have not been tried yet, and the number of retries is at
+// least the number of the hedged requests.
+//
+// This runs after the other policies are applied to the route so that the copy keeps them, e.g., the retry conditions
+// of the fallback policy.
+func (s *Server) maybeHedgedRoute(ctx context.Context, route *routev3.Route, cache map[client.ObjectKey]*aigv1b1.AIGatewayRoute) (*routev3.Route, error) {
+	rule, err := s.aiGatewayRouteRuleOf(ctx, route, cache)
+	if err != nil || rule == nil {
+		return nil, err
+	}
+	delay := rule.GetHedgeDelay()
+	if delay <= 0 {
+		return nil, nil
+	}
+
+	hedged := proto.Clone(route).(*routev3.Route)
+	hedged.Name = route.Name + "/hedge"
+	if hedged.Match == nil {
+		hedged.Match = &routev3.RouteMatch{}
+	}
+	hedged.Match.Headers = append(hedged.Match.Headers, &routev3.HeaderMatcher{
+		Name: internalapi.StreamHeader,
+		HeaderMatchSpecifier: &routev3.HeaderMatcher_StringMatch{
+			StringMatch: &matcherv3.StringMatcher{MatchPattern: &matcherv3.StringMatcher_Exact{Exact: "true"}},
+		},
+	})
+	action := hedged.GetRoute()
+	action.HedgePolicy = &routev3.HedgePolicy{HedgeOnPerTryTimeout: true}
+	if action.RetryPolicy == nil {
+		action.RetryPolicy = &routev3.RetryPolicy{}
+	}
+	rp := action.RetryPolicy
+	if rp.RetryOn == "" {
+		// Envoy only hedges the requests of the routes with a retry policy, which requires a retry condition.
+		rp.RetryOn = "reset,connect-failure,refused-stream"
+	}
+	rp.PerTryTimeout = durationpb.New(delay)
+	hedges := uint32(ptr.Deref(rule.HedgePolicy.MaxHedgedRequests, 1)) // #nosec G115
+	if rp.NumRetries.GetValue() < hedges {
+		rp.NumRetries = wrapperspb.UInt32(hedges)
+	}
+	predicateAny, err := toAny(&previous_hostsv3.PreviousHostsPredicate{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal PreviousHostsPredicate to Any: %w", err)
+	}
+	rp.RetryHostPredicate = []*routev3.RetryPolicy_RetryHostPredicate{{
+		Name:       "envoy.retry_host_predicates.previous_hosts",
+		ConfigType: &routev3.RetryPolicy_RetryHostPredicate_TypedConfig{TypedConfig: predicateAny},
+	}}
+	rp.HostSelectionRetryMaxAttempts = 5
+	return hedged, nil
+}
+
+// maybeSetAffinityHashPolicy configures route.hash_policy from the rule's Affinity so that the ring hash load
+// balancer of the cluster sends the requests with the same key to the same backend. The key is either the header
+// set by the router filter to the hash of the prompt prefix, or the header configured in the rule.
+//
+// When Envoy Gateway generates a cluster per backend ref, the route splits the requests across the clusters with
+// weighted clusters, which pick a cluster at random unless they are told to use the hash policy as well. The ring
+// hash of each cluster then only spreads the requests across the endpoints of a single backend.
+//
+// For InferencePool backends, the endpoint picker selects the endpoint instead, so the route is left untouched.
+func (s *Server) maybeSetAffinityHashPolicy(ctx context.Context, route *routev3.Route, cache map[client.ObjectKey]*aigv1b1.AIGatewayRoute) error {
+	rule, err := s.aiGatewayRouteRuleOf(ctx, route, cache)
+	if err != nil || rule == nil || rule.Affinity == nil {
+		return err
+	}
+	if len(rule.BackendRefs) > 0 && rule.BackendRefs[0].IsInferencePool() {
+		return nil
+	}
+	route.GetRoute().HashPolicy = []*routev3.RouteAction_HashPolicy{{
+		PolicySpecifier: &routev3.RouteAction_HashPolicy_Header_{
+			Header: &routev3.RouteAction_HashPolicy_Header{HeaderName: affinityHeaderName(rule.Affinity)},
+		},
+	}}
+	if wc := route.GetRoute().GetWeightedClusters(); wc != nil {
+		wc.RandomValueSpecifier = &routev3.WeightedCluster_UseHashPolicy{UseHashPolicy: wrapperspb.Bool(true)}
+	}
+	return nil
+}
+
+// affinityHeaderName returns the name of the request header carrying the affinity key.
+func affinityHeaderName(affinity *aigv1b1.AIGatewayRouteRuleAffinity) string {
+	if affinity.Type == aigv1b1.AffinityTypeHeader {
+		return ptr.Deref(affinity.HeaderName, "")
+	}
+	return internalapi.PromptAffinityHeaderName(int(ptr.Deref(affinity.Messages, 1)))
+}
+
+// aiGatewayRouteRuleOf returns the AIGatewayRoute rule from which the forwarding route was generated, or nil if
+// the route is not generated from an AIGatewayRoute.
+func (s *Server) aiGatewayRouteRuleOf(ctx context.Context, route *routev3.Route, cache map[client.ObjectKey]*aigv1b1.AIGatewayRoute) (*aigv1b1.AIGatewayRouteRule, error) {
+	if route.GetRoute() == nil {
+		// Not a forwarding route (e.g. DirectResponse, Redirect).
+		return nil, nil
+	}
 
 	// Route name format: "httproute/<namespace>/<name>/rule/<index>/match/<...>".
 	parts := strings.Split(route.Name, "/")
 	if len(parts) < 5 || parts[0] != "httproute" || parts[3] != "rule" || parts[1] == "" || parts[2] == "" {
-		return nil
+		return nil, nil
 	}
 	ruleIndex, err := strconv.Atoi(parts[4])
 	if err != nil {
-		return nil
+		return nil, nil
 	}
 
 	aigwRoute, err := s.retrieveAndCacheAIGatewayRoute(ctx, cache, client.ObjectKey{Namespace: parts[1], Name: parts[2]})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if aigwRoute == nil {
 		// Not an AIGatewayRoute-owned route, or it was deleted during translation.
-		return nil
+		return nil, nil
 	}
 
 	// The list of rules in the AIGatewayRoute may have changed since this route was generated,
-	// so we check the rule index is still valid before applying the timeout.
+	// so we check the rule index is still valid.
+	ruleIndex, _ = aiGatewayRouteRuleIndexOf(aigwRoute, ruleIndex)
 	if ruleIndex >= len(aigwRoute.Spec.Rules) {
-		return nil
+		return nil, nil
 	}
+	return &aigwRoute.Spec.Rules[ruleIndex], nil
+}
 
-	timeout := aigwRoute.Spec.Rules[ruleIndex].GetStreamIdleTimeout()
-	if timeout <= 0 {
-		return nil
+// aiGatewayRouteRuleIndexOf returns the index of the AIGatewayRoute rule from which the HTTPRoute rule at the given
+// index was generated, and the index of the backend ref preferred by the HTTPRoute rule, or -1 if it prefers none.
+//
+// The controller generates the HTTPRoute rules in the order of the AIGatewayRoute rules followed by the route-not-found
+// rule, then one rule per backend ref of each rule with a backend selection, and then one rule per shadow. The
+// returned rule index is out of range when the HTTPRoute rule is not generated from any of the AIGatewayRoute rules,
+// which is the case for the rules of the shadows since their requests are processed by the router filter only.
+func aiGatewayRouteRuleIndexOf(aigwRoute *aigv1b1.AIGatewayRoute, httpRouteRuleIndex int) (ruleIndex, preferredRefIndex int) {
+	rules := aigwRoute.Spec.Rules
+	if httpRouteRuleIndex <= len(rules) {
+		return httpRouteRuleIndex, -1
+	}
+	offset := httpRouteRuleIndex - len(rules) - 1
+	for i := range rules {
+		rule := &rules[i]
+		if rule.BackendSelection == nil || len(rule.BackendRefs) == 0 || rule.BackendRefs[0].IsInferencePool() {
+			continue
+		}
+		if offset < len(rule.BackendRefs) {
+			return i, offset
+		}
+		offset -= len(rule.BackendRefs)
 	}
+	return len(rules), -1
+}
 
-	if action.RetryPolicy == nil {
-		action.RetryPolicy = &routev3.RetryPolicy{}
+// setEndpointsPriority sets the priority of the endpoints of the backend ref at the given index if configured. When
+// one of the backend refs is preferred, it gets the highest priority, and the others are moved to the next one so
+// that they are kept as the fallback in the same order.
+func setEndpointsPriority(endpoints *endpointv3.LocalityLbEndpoints, backendRef *aigv1b1.AIGatewayRouteRuleBackendRef, refIndex, preferredRefIndex int) {
+	switch {
+	case preferredRefIndex < 0:
+		if backendRef.Priority != nil {
+			endpoints.Priority = *backendRef.Priority
+		}
+	case refIndex == preferredRefIndex:
+		endpoints.Priority = 0
+	default:
+		endpoints.Priority = ptr.Deref(backendRef.Priority, 0) + 1
 	}
-	action.RetryPolicy.PerTryIdleTimeout = durationpb.New(timeout)
-	return nil
 }
 
 // retrieveAndCacheAIGatewayRoute returns the AIGatewayRoute for the key and saves the result.
@@ -325,6 +685,7 @@ func (s *Server) maybeModifyCluster(ctx context.Context, cluster *clusterv3.Clus
 	}
 
 	// Get the backend from the HTTPRoute object.
+	httpRouteRuleIndex, preferredRefIndex := aiGatewayRouteRuleIndexOf(&aigwRoute, httpRouteRuleIndex)
 	if httpRouteRuleIndex >= len(aigwRoute.Spec.Rules) {
 		s.log.Info("HTTPRoute rule index out of range",
 			"cluster_name", cluster.Name, "rule_index", httpRouteRuleIndex)
@@ -356,11 +717,12 @@ func (s *Server) maybeModifyCluster(ctx context.Context, cluster *clusterv3.Clus
 		case clusterName.backendRefIndex != noBackendRefIndex:
 			backendRef := httpRouteRule.BackendRefs[clusterName.backendRefIndex]
 			for _, endpoints := range cluster.LoadAssignment.Endpoints {
-				if backendRef.Priority != nil {
-					endpoints.Priority = *backendRef.Priority
-				}
+				setEndpointsPriority(endpoints, &backendRef, clusterName.backendRefIndex, preferredRefIndex)
 				for _, endpoint := range endpoints.LbEndpoints {
 					setEndpointMetadataBackendName(endpoint, aigwRoute.Namespace, backendRef.Name, aigwRoute.Name, httpRouteRuleIndex, clusterName.backendRefIndex)
+					if retriesAndFailsOver(httpRouteRule.FallbackPolicy) {
+						setEndpointMetadataPriority(endpoint, endpoints.Priority)
+					}
 				}
 			}
 		default:
@@ -376,14 +738,22 @@ func (s *Server) maybeModifyCluster(ctx context.Context, cluster *clusterv3.Clus
 				lbEndpointIndex++
 				name := backendRef.Name
 				namespace := aigwRoute.Namespace
-				if backendRef.Priority != nil {
-					endpoints.Priority = *backendRef.Priority
-				}
+				setEndpointsPriority(endpoints, &backendRef, i, preferredRefIndex)
 				for _, endpoint := range endpoints.LbEndpoints {
 					setEndpointMetadataBackendName(endpoint, namespace, name, aigwRoute.Name, httpRouteRuleIndex, i)
+					if retriesAndFailsOver(httpRouteRule.FallbackPolicy) {
+						setEndpointMetadataPriority(endpoint, endpoints.Priority)
+					}
 				}
 			}
 		}
+		if httpRouteRule.Affinity != nil {
+			// The endpoints are selected on a consistent hash ring with the hash policy of the route.
+			// See maybeSetAffinityHashPolicy.
+			cluster.LbPolicy = clusterv3.Cluster_RING_HASH
+			cluster.LbConfig = &clusterv3.Cluster_RingHashLbConfig_{RingHashLbConfig: &clusterv3.Cluster_RingHashLbConfig{}}
+			cluster.LoadBalancingPolicy = nil
+		}
 	} else {
 		// we can only specify one backend in a rule for InferencePool.
 		backendRefIndex := 0
@@ -431,6 +801,10 @@ func (s *Server) maybeModifyCluster(ctx context.Context, cluster *clusterv3.Clus
 		internalapi.XDSClusterMetadataBackendNamePath,
 		internalapi.XDSRouteMetadataRouteNamePath,
 	}
+	if retriesAndFailsOver(httpRouteRule.FallbackPolicy) {
+		// The upstream filter rejects the attempts on the priorities already failed over. See maybeSetFallbackPolicy.
+		extProcConfig.RequestAttributes = append(extProcConfig.RequestAttributes, internalapi.XDSUpstreamHostMetadataPriorityPath)
+	}
 	extProcConfig.ProcessingMode = &extprocv3.ProcessingMode{
 		RequestHeaderMode: extprocv3.ProcessingMode_SEND,
 		// At the upstream filter, it can access the original body in its memory, so it can perform the translation
@@ -440,6 +814,13 @@ func (s *Server) maybeModifyCluster(ctx context.Context, cluster *clusterv3.Clus
 		ResponseHeaderMode: extprocv3.ProcessingMode_SKIP,
 		ResponseBodyMode:   extprocv3.ProcessingMode_NONE,
 	}
+	if httpRouteRule.FallbackPolicy != nil || httpRouteRule.HedgePolicy != nil {
+		// The upstream filter classifies the error response of each attempt so that the retry policy of the route
+		// can decide whether to retry it, and tags the response of each attempt of a hedged request so that the
+		// router filter can tell which attempt won. The successful responses are still handled at the router
+		// filter level.
+		extProcConfig.ProcessingMode.ResponseHeaderMode = extprocv3.ProcessingMode_SEND
+	}
 	extProcConfig.MessageTimeout = durationpb.New(10 * time.Second)
 	extProcConfig.GrpcService = &corev3.GrpcService{
 		TargetSpecifier: &corev3.GrpcService_EnvoyGrpc_{
@@ -485,13 +866,25 @@ func (s *Server) maybeModifyCluster(ctx context.Context, cluster *clusterv3.Clus
 		ConfigType: &httpconnectionmanagerv3.HttpFilter_TypedConfig{TypedConfig: hmAny},
 	}
 
+	filters := []*httpconnectionmanagerv3.HttpFilter{extProcFilter}
+	if httpRouteRule.StreamFailover != nil && pool == nil {
+		var sfFilter *httpconnectionmanagerv3.HttpFilter
+		if sfFilter, err = buildStreamFailoverExtProcFilter(); err != nil {
+			s.log.Error(err, "failed to build the stream failover filter", "cluster_name", cluster.Name)
+			return err
+		}
+		filters = append(filters, sfFilter)
+	}
+	filters = append(filters, headerMutFilter)
+
 	if len(po.HttpFilters) > 0 {
 		// Insert the ext_proc filter before the last filter since the last one is always the upstream codec filter.
 		last := po.HttpFilters[len(po.HttpFilters)-1]
 		po.HttpFilters = po.HttpFilters[:len(po.HttpFilters)-1]
-		po.HttpFilters = append(po.HttpFilters, extProcFilter, headerMutFilter, last)
+		po.HttpFilters = append(po.HttpFilters, filters...)
+		po.HttpFilters = append(po.HttpFilters, last)
 	} else {
-		po.HttpFilters = append(po.HttpFilters, extProcFilter, headerMutFilter)
+		po.HttpFilters = append(po.HttpFilters, filters...)
 		// We always need the upstream_code filter as a last filter.
 		upstreamCodec := &httpconnectionmanagerv3.HttpFilter{}
 		upstreamCodec.Name = "envoy.filters.http.upstream_codec"
@@ -515,6 +908,41 @@ func (s *Server) maybeModifyCluster(ctx context.Context, cluster *clusterv3.Clus
 	return nil
 }
 
+// buildStreamFailoverExtProcFilter builds the upstream filter continuing the truncated streaming responses of the
+// rules with StreamFailover. Unlike the AI Gateway upstream filter, it processes the response body in the
+// FULL_DUPLEX_STREAMED mode, so that the external processor can stream the continuation on the stream failover
+// backend before the end of the response. The response passes through it before the AI Gateway upstream filter, and
+// it is told apart by the external processor with the gRPC metadata.
+func buildStreamFailoverExtProcFilter() (*httpconnectionmanagerv3.HttpFilter, error) {
+	ecAny, err := toAny(&extprocv3.ExternalProcessor{
+		GrpcService: &corev3.GrpcService{
+			TargetSpecifier: &corev3.GrpcService_EnvoyGrpc_{
+				EnvoyGrpc: &corev3.GrpcService_EnvoyGrpc{
+					ClusterName: extProcUDSClusterName,
+				},
+			},
+			InitialMetadata: []*corev3.HeaderValue{{Key: internalapi.StreamFailoverFilterMetadataKey, Value: "true"}},
+		},
+		ProcessingMode: &extprocv3.ProcessingMode{
+			// The request headers carry the internal request ID of the router filter.
+			RequestHeaderMode:   extprocv3.ProcessingMode_SEND,
+			RequestBodyMode:     extprocv3.ProcessingMode_NONE,
+			RequestTrailerMode:  extprocv3.ProcessingMode_SKIP,
+			ResponseHeaderMode:  extprocv3.ProcessingMode_SEND,
+			ResponseBodyMode:    extprocv3.ProcessingMode_FULL_DUPLEX_STREAMED,
+			ResponseTrailerMode: extprocv3.ProcessingMode_SEND,
+		},
+		MessageTimeout: durationpb.New(10 * time.Second),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ExternalProcessor to Any: %w", err)
+	}
+	return &httpconnectionmanagerv3.HttpFilter{
+		Name:       streamFailoverExtProcName,
+		ConfigType: &httpconnectionmanagerv3.HttpFilter_TypedConfig{TypedConfig: ecAny},
+	}, nil
+}
+
 // maybeModifyListenerAndRoutes modifies listeners and routes to support InferencePool backends.
 // This function performs the following operations:
 // 1. Identifies listeners and routes that use InferencePool backends
@@ -970,6 +1398,25 @@ func setClusterMetadataBackendName(cluster *clusterv3.Cluster, namespace, name,
 	)
 }
 
+// retriesAndFailsOver returns true if the fallback policy both retries some error classes on the same priority and
+// fails over to the next priority on others, in which case the upstream filter needs the priority of each attempt.
+func retriesAndFailsOver(p *aigv1b1.AIGatewayRouteRuleFallbackPolicy) bool {
+	if p == nil {
+		return false
+	}
+	hasAction := func(t aigv1b1.FallbackActionType) bool {
+		return slices.ContainsFunc(p.Actions, func(a aigv1b1.AIGatewayRouteRuleFallbackAction) bool { return a.Action == t })
+	}
+	return hasAction(aigv1b1.FallbackActionTypeRetry) && hasAction(aigv1b1.FallbackActionTypeFailover)
+}
+
+// setEndpointMetadataPriority sets the priority of the endpoint in its metadata, which is passed to the upstream
+// filter for each attempt.
+func setEndpointMetadataPriority(endpoint *endpointv3.LbEndpoint, priority uint32) {
+	endpoint.Metadata.FilterMetadata[internalapi.InternalEndpointMetadataNamespace].Fields[internalapi.InternalMetadataPriorityKey] =
+		structpb.NewNumberValue(float64(priority))
+}
+
 func setEndpointMetadataBackendName(endpoint *endpointv3.LbEndpoint, namespace, name, routeName string, routeRuleIndex, refIndex int) {
 	if endpoint.Metadata == nil {
 		endpoint.Metadata = &corev3.Metadata{}
@@ -371,6 +371,7 @@ func (s *Server) resolveClusterRule(ctx context.Context, clusterName string) *cl
 		return nil
 	}
 
+	ruleIndex, _ = aiGatewayRouteRuleIndexOf(&aigwRoute, ruleIndex)
 	if ruleIndex >= len(aigwRoute.Spec.Rules) {
 		return nil
 	}
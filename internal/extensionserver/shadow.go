@@ -0,0 +1,149 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extensionserver
+
+import (
+	"fmt"
+
+	egextension "github.com/envoyproxy/gateway/proto/extension"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	routerv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	httpconnectionmanagerv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+)
+
+const shadowListenerName = "aigateway-shadow-listener"
+
+// maybeGenerateResourcesForShadows moves the routes of the shadows of the AIGatewayRoute rules from the Gateway
+// listeners to the internal shadow listener, which the AI Gateway filter sends the copies of the requests to.
+//
+// The copies are already translated and authenticated by the AI Gateway filter, so the shadow listener only routes
+// them to the shadow backends without any other filter.
+func (s *Server) maybeGenerateResourcesForShadows(req *egextension.PostTranslateModifyRequest) error {
+	shadowRoutes := s.moveShadowRoutes(req.Routes)
+	if shadowRoutes == nil {
+		return nil
+	}
+	l, err := createShadowListener()
+	if err != nil {
+		return fmt.Errorf("failed to create shadow listener: %w", err)
+	}
+	req.Listeners = append(req.Listeners, l)
+	req.Routes = append(req.Routes, shadowRoutes)
+	return nil
+}
+
+// moveShadowRoutes removes the routes of the shadows from the given route configurations, and returns the route
+// configuration of the shadow listener with them.
+//
+// Returns nil if no shadow routes are found.
+func (s *Server) moveShadowRoutes(routes []*routev3.RouteConfiguration) *routev3.RouteConfiguration {
+	var shadowRoutes []*routev3.Route
+	for _, routeConfig := range routes {
+		for _, vh := range routeConfig.VirtualHosts {
+			var originalRoutes []*routev3.Route
+			for _, route := range vh.Routes {
+				if !isShadowRoute(route) {
+					originalRoutes = append(originalRoutes, route)
+					continue
+				}
+				// The filters of the Gateway listener are not on the shadow listener.
+				route.TypedPerFilterConfig = nil
+				route.RequestHeadersToRemove = append(route.RequestHeadersToRemove, internalapi.ShadowBackendHeader)
+				shadowRoutes = append(shadowRoutes, route)
+			}
+			vh.Routes = originalRoutes
+		}
+	}
+	if len(shadowRoutes) == 0 {
+		return nil
+	}
+
+	s.log.Info("created routes for shadow listener", "numRoutes", len(shadowRoutes))
+	return &routev3.RouteConfiguration{
+		Name: fmt.Sprintf("%s-route-config", shadowListenerName),
+		VirtualHosts: []*routev3.VirtualHost{
+			{
+				Name:    fmt.Sprintf("%s-wildcard", shadowListenerName),
+				Domains: []string{"*"},
+				Routes:  shadowRoutes,
+			},
+		},
+	}
+}
+
+// isShadowRoute returns true if the route is generated from the HTTPRoute rule of a shadow, which matches the shadow
+// backend header.
+func isShadowRoute(route *routev3.Route) bool {
+	if route.GetRoute() == nil {
+		return false
+	}
+	for _, h := range route.GetMatch().GetHeaders() {
+		if h.Name == internalapi.ShadowBackendHeader {
+			return true
+		}
+	}
+	return false
+}
+
+// createShadowListener creates the internal listener to which the AI Gateway filter sends the copies of the requests
+// to the shadow backends.
+func createShadowListener() (*listenerv3.Listener, error) {
+	router, err := toAny(&routerv3.Router{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal router filter config: %w", err)
+	}
+	httpConManager := &httpconnectionmanagerv3.HttpConnectionManager{
+		StatPrefix: fmt.Sprintf("%s-http", shadowListenerName),
+		// Match the :scheme pseudo-header to the upstream transport protocol.
+		SchemeHeaderTransformation: &corev3.SchemeHeaderTransformation{
+			MatchUpstream: true,
+		},
+		RouteSpecifier: &httpconnectionmanagerv3.HttpConnectionManager_Rds{
+			Rds: &httpconnectionmanagerv3.Rds{
+				RouteConfigName: fmt.Sprintf("%s-route-config", shadowListenerName),
+				ConfigSource: &corev3.ConfigSource{
+					ConfigSourceSpecifier: &corev3.ConfigSource_Ads{
+						Ads: &corev3.AggregatedConfigSource{},
+					},
+					ResourceApiVersion: corev3.ApiVersion_V3,
+				},
+			},
+		},
+		HttpFilters: []*httpconnectionmanagerv3.HttpFilter{{
+			Name:       wellknown.Router,
+			ConfigType: &httpconnectionmanagerv3.HttpFilter_TypedConfig{TypedConfig: router},
+		}},
+	}
+	a, err := toAny(httpConManager)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal HTTP Connection Manager for shadow listener: %w", err)
+	}
+	return &listenerv3.Listener{
+		Name: shadowListenerName,
+		Address: &corev3.Address{
+			Address: &corev3.Address_SocketAddress{
+				SocketAddress: &corev3.SocketAddress{
+					Protocol: corev3.SocketAddress_TCP,
+					Address:  "127.0.0.1",
+					PortSpecifier: &corev3.SocketAddress_PortValue{
+						PortValue: internalapi.ShadowListenerPort,
+					},
+				},
+			},
+		},
+		FilterChains: []*listenerv3.FilterChain{{
+			Filters: []*listenerv3.Filter{{
+				Name:       wellknown.HTTPConnectionManager,
+				ConfigType: &listenerv3.Filter_TypedConfig{TypedConfig: a},
+			}},
+		}},
+	}, nil
+}
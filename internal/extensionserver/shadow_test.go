@@ -0,0 +1,86 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extensionserver
+
+import (
+	"testing"
+
+	egextension "github.com/envoyproxy/gateway/proto/extension"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	httpconnectionmanagerv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+)
+
+func TestServer_maybeGenerateResourcesForShadows(t *testing.T) {
+	newShadowRoute := func() *routev3.Route {
+		return &routev3.Route{
+			Name: "httproute/ns/route/rule/3/match/0/*",
+			Match: &routev3.RouteMatch{Headers: []*routev3.HeaderMatcher{
+				{Name: internalapi.ShadowBackendHeader},
+			}},
+			Action:               &routev3.Route_Route{Route: &routev3.RouteAction{}},
+			TypedPerFilterConfig: map[string]*anypb.Any{"envoy.filters.http.ext_proc": {}},
+		}
+	}
+	normal := &routev3.Route{Name: "httproute/ns/route/rule/0/match/0/*", Action: &routev3.Route_Route{Route: &routev3.RouteAction{}}}
+	// A direct response route matching the header is not moved.
+	directResponse := &routev3.Route{
+		Name:   "direct",
+		Match:  &routev3.RouteMatch{Headers: []*routev3.HeaderMatcher{{Name: internalapi.ShadowBackendHeader}}},
+		Action: &routev3.Route_DirectResponse{},
+	}
+
+	t.Run("no shadows", func(t *testing.T) {
+		req := &egextension.PostTranslateModifyRequest{Routes: []*routev3.RouteConfiguration{{
+			VirtualHosts: []*routev3.VirtualHost{{Routes: []*routev3.Route{normal, directResponse}}},
+		}}}
+		require.NoError(t, (&Server{log: testr.New(t)}).maybeGenerateResourcesForShadows(req))
+		require.Empty(t, req.Listeners)
+		require.Len(t, req.Routes, 1)
+		require.Len(t, req.Routes[0].VirtualHosts[0].Routes, 2)
+	})
+
+	t.Run("shadows", func(t *testing.T) {
+		shadow1, shadow2 := newShadowRoute(), newShadowRoute()
+		req := &egextension.PostTranslateModifyRequest{Routes: []*routev3.RouteConfiguration{
+			{VirtualHosts: []*routev3.VirtualHost{{Routes: []*routev3.Route{normal, shadow1, directResponse}}}},
+			{VirtualHosts: []*routev3.VirtualHost{{Routes: []*routev3.Route{shadow2}}}},
+		}}
+		require.NoError(t, (&Server{log: testr.New(t)}).maybeGenerateResourcesForShadows(req))
+
+		require.Len(t, req.Routes, 3)
+		require.Equal(t, []*routev3.Route{normal, directResponse}, req.Routes[0].VirtualHosts[0].Routes)
+		require.Empty(t, req.Routes[1].VirtualHosts[0].Routes)
+		shadowRouteConfig := req.Routes[2]
+		require.Equal(t, "aigateway-shadow-listener-route-config", shadowRouteConfig.Name)
+		require.Len(t, shadowRouteConfig.VirtualHosts, 1)
+		vh := shadowRouteConfig.VirtualHosts[0]
+		require.Equal(t, []string{"*"}, vh.Domains)
+		require.Equal(t, []*routev3.Route{shadow1, shadow2}, vh.Routes)
+		for _, r := range vh.Routes {
+			require.Nil(t, r.TypedPerFilterConfig)
+			require.Equal(t, []string{internalapi.ShadowBackendHeader}, r.RequestHeadersToRemove)
+		}
+
+		require.Len(t, req.Listeners, 1)
+		l := req.Listeners[0]
+		require.Equal(t, shadowListenerName, l.Name)
+		require.Equal(t, "127.0.0.1", l.Address.GetSocketAddress().Address)
+		require.Equal(t, uint32(internalapi.ShadowListenerPort), l.Address.GetSocketAddress().GetPortValue())
+		require.Len(t, l.FilterChains, 1)
+		require.Len(t, l.FilterChains[0].Filters, 1)
+		hcm := &httpconnectionmanagerv3.HttpConnectionManager{}
+		require.NoError(t, l.FilterChains[0].Filters[0].GetTypedConfig().UnmarshalTo(hcm))
+		require.Equal(t, "aigateway-shadow-listener-route-config", hcm.GetRds().RouteConfigName)
+		require.Len(t, hcm.HttpFilters, 1)
+		require.Equal(t, wellknown.Router, hcm.HttpFilters[0].Name)
+	})
+}
@@ -0,0 +1,113 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"cmp"
+	"log/slog"
+	"strconv"
+	"time"
+
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+
+	"github.com/envoyproxy/ai-gateway/internal/audit"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+)
+
+// auditLogger returns the logger of the audit records of the requests, or nil if the requests are not audited.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) auditLogger() *audit.Logger {
+	if u.parent == nil || u.parent.config == nil {
+		return nil
+	}
+	return u.parent.config.AuditLogger
+}
+
+// recordsAuditBodies returns true if the response body returned to the client is recorded in the audit record.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) recordsAuditBodies() bool {
+	l := u.auditLogger()
+	return l != nil && l.RecordsBodies()
+}
+
+// bufferAuditResponse appends the chunk of the response body returned to the client to the one of the audit record,
+// and keeps the status code returned to the client if it is changed by the processing of the response.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) bufferAuditResponse(headerMutation *extprocv3.HeaderMutation, chunk []byte) {
+	for _, h := range headerMutation.GetSetHeaders() {
+		if h.GetHeader().GetKey() == ":status" {
+			u.auditStatus = string(h.GetHeader().GetRawValue())
+		}
+	}
+	if l := u.auditLogger(); l != nil && l.RecordsBodies() {
+		var truncated bool
+		u.auditResponseBody, truncated = audit.AppendBody(u.auditResponseBody, chunk, l.MaxBodySize())
+		u.auditResponseTruncated = u.auditResponseTruncated || truncated
+	}
+}
+
+// recordImmediateAudit logs the audit record of the request answered by the immediate response without calling the
+// backend, e.g., rejected by a guardrail or served from the response cache, or failed to be processed.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) recordImmediateAudit(l *audit.Logger, resp *extprocv3.ImmediateResponse, processingErr error) {
+	if resp != nil {
+		u.auditStatus = strconv.Itoa(int(resp.GetStatus().GetCode()))
+		if l.RecordsBodies() {
+			u.auditResponseBody, u.auditResponseTruncated = audit.AppendBody(nil, resp.GetBody(), l.MaxBodySize())
+		}
+	}
+	u.recordAudit(l, processingErr)
+}
+
+// recordAudit logs the audit record of the request once its response is complete or fails to be processed, unless
+// it has already been logged. This never blocks, since the record is dropped if the buffer of the audit logger is
+// full.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) recordAudit(l *audit.Logger, processingErr error) {
+	if u.audited {
+		return
+	}
+	u.audited = true
+	r := &audit.Record{
+		Time:          time.Now(),
+		RequestID:     u.requestHeaders["x-request-id"],
+		Route:         u.routeName,
+		Backend:       u.backendName,
+		Endpoint:      cmp.Or(u.requestHeaders[originalPathHeader], u.requestHeaders[":path"]),
+		OriginalModel: u.parent.originalModel,
+		RequestModel:  u.requestHeaders[internalapi.ModelNameHeaderKeyDefault],
+		ResponseModel: u.responseModel,
+		Stream:        u.parent.stream,
+	}
+	r.StatusCode, _ = strconv.Atoi(cmp.Or(u.auditStatus, u.responseHeaders[":status"]))
+	if processingErr != nil {
+		r.Error = processingErr.Error()
+	}
+	for _, h := range l.TenantHeaders() {
+		if v, ok := u.requestHeaders[h]; ok {
+			if r.Tenant == nil {
+				r.Tenant = make(map[string]string, len(l.TenantHeaders()))
+			}
+			r.Tenant[h] = v
+		}
+	}
+	r.Usage.InputTokens, _ = u.costs.InputTokens()
+	r.Usage.OutputTokens, _ = u.costs.OutputTokens()
+	r.Usage.TotalTokens, _ = u.costs.TotalTokens()
+	r.Usage.CachedInputTokens, _ = u.costs.CachedInputTokens()
+	r.Usage.CacheCreationInputTokens, _ = u.costs.CacheCreationInputTokens()
+	r.Usage.ReasoningTokens, _ = u.costs.ReasoningTokens()
+	if config := u.parent.config; len(config.GlobalRequestCosts) > 0 || len(config.RequestCosts) > 0 {
+		costs, err := evalRequestCosts(config.GlobalRequestCosts, config.RequestCosts, &u.costs, u.requestHeaders, u.backendName, u.routeName)
+		if err != nil {
+			u.logger.Warn("failed to evaluate the request costs of the audit record", slog.String("error", err.Error()))
+		}
+		r.Costs = costs
+	}
+	if l.RecordsBodies() {
+		// The request body is the one sent to the backend, e.g., with the personally identifiable information masked.
+		r.SetBodies(u.requestBodyRaw, u.auditResponseBody, u.auditResponseTruncated)
+		// The body is owned by the record from now on.
+		u.auditResponseBody = nil
+	}
+	// The dropped records are reported by the audit logger.
+	l.Log(r)
+}
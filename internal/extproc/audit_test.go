@@ -0,0 +1,248 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/audit"
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/responsecache"
+)
+
+func TestUpstreamProcessor_recordAudit(t *testing.T) {
+	newUpstream := func(t *testing.T, l *audit.Logger, status string) *chatCompletionProcessorUpstreamFilter {
+		body := openai.ChatCompletionRequest{Model: "gpt-4o"}
+		mt := &mockTranslator{t: t, expRequestBody: &body, expHeaders: map[string]string{":status": status}, retResponseModel: "gpt-4o-2024-08-06"}
+		mt.retUsedToken.SetInputTokens(10)
+		mt.retUsedToken.SetOutputTokens(20)
+		mt.retUsedToken.SetTotalTokens(30)
+		raw := []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"hello"}]}`)
+		u := &chatCompletionProcessorUpstreamFilter{
+			requestHeaders: map[string]string{
+				":path": "/v1/chat/completions", originalPathHeader: "/v1/chat/completions", "x-request-id": "req-1",
+				"x-tenant": "acme", internalapi.ModelNameHeaderKeyDefault: "gpt-4o",
+			},
+			metrics:        &mockMetrics{},
+			logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+			translator:     mt,
+			backendName:    "default/openai/route/r/rule/0/ref/0",
+			routeName:      "default/r",
+			requestBodyRaw: raw,
+			parent: &chatCompletionProcessorRouterFilter{
+				originalRequestBody:    &body,
+				originalRequestBodyRaw: raw,
+				config:                 &filterapi.RuntimeConfig{AuditLogger: l},
+				originalModel:          "gpt-4o",
+			},
+		}
+		_, err := u.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{Headers: []*corev3.HeaderValue{{Key: ":status", Value: status}}})
+		require.NoError(t, err)
+		return u
+	}
+
+	t.Run("response", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := audit.NewLogger(audit.NewWriterSink(&buf), audit.Options{Bodies: audit.BodiesFull, TenantHeaders: []string{"x-tenant", "x-team"}})
+		u := newUpstream(t, l, "200")
+		_, err := u.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{Body: []byte(`{"choices":[`)})
+		require.NoError(t, err)
+		// The record is logged once the response completes.
+		_, err = u.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{Body: []byte(`]}`), EndOfStream: true})
+		require.NoError(t, err)
+		require.NoError(t, l.Close(t.Context()))
+
+		line := strings.TrimSpace(buf.String())
+		_, err = audit.Verify(strings.NewReader(line), nil)
+		require.NoError(t, err)
+		for path, exp := range map[string]string{
+			"seq":           "1",
+			"requestId":     "req-1",
+			"tenant":        `{"x-tenant":"acme"}`,
+			"route":         "default/r",
+			"backend":       "default/openai/route/r/rule/0/ref/0",
+			"endpoint":      "/v1/chat/completions",
+			"originalModel": "gpt-4o",
+			"requestModel":  "gpt-4o",
+			"responseModel": "gpt-4o-2024-08-06",
+			"statusCode":    "200",
+			"usage":         `{"inputTokens":10,"outputTokens":20,"totalTokens":30}`,
+			"requestBody":   `{"model":"gpt-4o","messages":[{"role":"user","content":"hello"}]}`,
+			"responseBody":  `{"choices":[]}`,
+		} {
+			require.Equal(t, exp, gjson.Get(line, path).String(), path)
+		}
+		require.False(t, gjson.Get(line, "costs").Exists())
+		require.False(t, gjson.Get(line, "error").Exists())
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := audit.NewLogger(audit.NewWriterSink(&buf), audit.Options{})
+		u := newUpstream(t, l, "503")
+		_, err := u.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{Body: []byte(`{"error":"overloaded"}`), EndOfStream: true})
+		require.NoError(t, err)
+		require.NoError(t, l.Close(t.Context()))
+
+		line := strings.TrimSpace(buf.String())
+		require.Equal(t, int64(503), gjson.Get(line, "statusCode").Int())
+		// The bodies are not recorded by default.
+		require.False(t, gjson.Get(line, "responseBody").Exists())
+		require.False(t, gjson.Get(line, "requestBody").Exists())
+	})
+
+	t.Run("not audited", func(t *testing.T) {
+		u := newUpstream(t, nil, "200")
+		require.Nil(t, u.auditLogger())
+		require.False(t, u.recordsAuditBodies())
+		_, err := u.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{Body: []byte(`{}`), EndOfStream: true})
+		require.NoError(t, err)
+		require.Nil(t, u.auditResponseBody)
+	})
+}
+
+func TestUpstreamProcessor_recordAudit_immediateResponse(t *testing.T) {
+	const requestBody = `{"model":"gpt-4o","messages":[{"role":"user","content":"Email jane@example.com."}]}`
+	newFilters := func(t *testing.T, l *audit.Logger, backend *filterapi.RuntimeBackend) (*chatCompletionProcessorRouterFilter, *chatCompletionProcessorUpstreamFilter) {
+		var parsed openai.ChatCompletionRequest
+		require.NoError(t, json.Unmarshal([]byte(requestBody), &parsed))
+		headers := map[string]string{
+			":path": "/v1/chat/completions", ":method": "POST", "content-type": "application/json", "x-request-id": "req-1",
+		}
+		r := &chatCompletionProcessorRouterFilter{
+			eh:                     endpointspec.ChatCompletionsEndpointSpec{},
+			config:                 &filterapi.RuntimeConfig{AuditLogger: l},
+			logger:                 slog.Default(),
+			requestHeaders:         headers,
+			originalRequestBodyRaw: []byte(requestBody),
+			originalRequestBody:    &parsed,
+			originalModel:          "gpt-4o",
+		}
+		u := &chatCompletionProcessorUpstreamFilter{requestHeaders: maps.Clone(headers), metrics: &mockGuardrailMetrics{}, logger: slog.Default()}
+		require.NoError(t, u.SetBackend(t.Context(), backend, "test-route", r))
+		return r, u
+	}
+	newPIIBackend := func(t *testing.T, action filterapi.PIIGuardrailAction) *filterapi.RuntimeBackend {
+		pii := &filterapi.PIIGuardrail{Action: action, Entities: []string{guardrail.PIIEntityEmail}}
+		detector, err := guardrail.NewPIIDetector(pii.Entities, nil)
+		require.NoError(t, err)
+		return &filterapi.RuntimeBackend{
+			Backend: &filterapi.Backend{
+				Name:       "openai",
+				Schema:     filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+				Guardrails: &filterapi.Guardrails{PII: pii},
+			},
+			PIIDetector: detector,
+		}
+	}
+	const maskedText = "Email [EMAIL]."
+
+	t.Run("cache hit", func(t *testing.T) {
+		const responseBody = `{"model":"gpt-4o-2024-08-06","choices":[{"message":{"role":"assistant","content":"hello"}}]}`
+		store := responsecache.NewMemoryStore(10)
+		backend := &filterapi.RuntimeBackend{
+			Backend: &filterapi.Backend{
+				Name:          "openai",
+				Schema:        filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+				ResponseCache: &filterapi.ResponseCache{TTL: time.Hour},
+			},
+			ResponseCache: store,
+		}
+		var buf bytes.Buffer
+		l := audit.NewLogger(audit.NewWriterSink(&buf), audit.Options{Bodies: audit.BodiesFull})
+
+		// The first request is audited once its response completes.
+		r, u := newFilters(t, l, backend)
+		_, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		_, err = r.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{Headers: []*corev3.HeaderValue{
+			{Key: ":status", Value: "200"}, {Key: "content-type", Value: "application/json"},
+		}})
+		require.NoError(t, err)
+		_, err = r.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{Body: []byte(responseBody), EndOfStream: true})
+		require.NoError(t, err)
+
+		// The second one is served from the cache, and audited on the cache hit.
+		r, u = newFilters(t, l, backend)
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		require.NotNil(t, resp.GetImmediateResponse())
+		// The record is not logged again if the response is processed.
+		_, err = r.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{EndOfStream: true})
+		require.NoError(t, err)
+		require.NoError(t, l.Close(t.Context()))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		_, err = audit.Verify(strings.NewReader(buf.String()), nil)
+		require.NoError(t, err)
+		for path, exp := range map[string]string{
+			"seq":           "2",
+			"requestId":     "req-1",
+			"statusCode":    "200",
+			"responseModel": "gpt-4o-2024-08-06",
+			"requestBody":   requestBody,
+			"responseBody":  responseBody,
+		} {
+			require.Equal(t, exp, gjson.Get(lines[1], path).String(), path)
+		}
+	})
+
+	t.Run("guardrail block", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := audit.NewLogger(audit.NewWriterSink(&buf), audit.Options{Bodies: audit.BodiesFull})
+		_, u := newFilters(t, l, newPIIBackend(t, filterapi.PIIGuardrailActionBlock))
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		ir := resp.GetImmediateResponse()
+		require.NotNil(t, ir)
+		require.NoError(t, l.Close(t.Context()))
+
+		line := strings.TrimSpace(buf.String())
+		require.Equal(t, int64(400), gjson.Get(line, "statusCode").Int())
+		require.Equal(t, string(ir.Body), gjson.Get(line, "responseBody").String())
+		// The rejected request is recorded with the information masked.
+		require.Equal(t, maskedText, gjson.Get(gjson.Get(line, "requestBody").String(), "messages.0.content").String())
+		require.NotContains(t, line, "jane@example.com")
+	})
+
+	t.Run("pii mask", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := audit.NewLogger(audit.NewWriterSink(&buf), audit.Options{Bodies: audit.BodiesFull})
+		r, u := newFilters(t, l, newPIIBackend(t, filterapi.PIIGuardrailActionMask))
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		require.Nil(t, resp.GetImmediateResponse())
+		_, err = r.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{Headers: []*corev3.HeaderValue{
+			{Key: ":status", Value: "200"}, {Key: "content-type", Value: "application/json"},
+		}})
+		require.NoError(t, err)
+		_, err = r.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{Body: []byte(`{"choices":[]}`), EndOfStream: true})
+		require.NoError(t, err)
+		require.NoError(t, l.Close(t.Context()))
+
+		// The request body is the one sent to the backend.
+		line := strings.TrimSpace(buf.String())
+		require.Equal(t, maskedText, gjson.Get(gjson.Get(line, "requestBody").String(), "messages.0.content").String())
+		require.NotContains(t, line, "jane@example.com")
+	})
+}
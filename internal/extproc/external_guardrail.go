@@ -0,0 +1,246 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+	"github.com/envoyproxy/ai-gateway/internal/tracing/tracingapi"
+)
+
+// externalGuardrailDecisionError is the decision recorded when the check of the external guardrail fails.
+const externalGuardrailDecisionError = "error"
+
+// applyExternalGuardrail checks the request with the external guardrail service, and rewrites the request body sent
+// to the backend if the service asks to. This returns the immediate response rejecting the request if the service
+// denies it or fails while the guardrail fails closed, or nil otherwise.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) applyExternalGuardrail(ctx context.Context) (*extprocv3.ProcessingResponse, error) {
+	rp := u.parent
+	switch any(rp.eh).(type) {
+	case endpointspec.ChatCompletionsEndpointSpec, endpointspec.MessagesEndpointSpec, endpointspec.ResponsesEndpointSpec:
+	default:
+		// The requests of the other endpoints are not inspected.
+		return nil, nil
+	}
+
+	raw := u.requestBodyRaw
+	texts := requestTexts(rp.eh, raw)
+	req := &guardrail.ExternalCheckRequest{
+		Phase: guardrail.ExternalPhaseRequest,
+		Model: cmp.Or(string(u.modelNameOverride), u.requestHeaders[internalapi.ModelNameHeaderKeyDefault], rp.originalModel),
+		Tools: requestTools(rp.eh, raw),
+	}
+	req.Messages = make([]guardrail.ExternalMessage, len(texts))
+	for i, t := range texts {
+		req.Messages[i] = guardrail.ExternalMessage{Role: t.role, Content: gjson.GetBytes(raw, t.path).String()}
+	}
+
+	resp := u.checkExternalGuardrail(ctx, req)
+	switch {
+	case resp == nil:
+		u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
+		return createUserFacingErrorResponse(503, "ServiceUnavailable", "guardrail service is unavailable"), nil
+	case resp.Decision == guardrail.ExternalDecisionDeny:
+		u.logger.Info("rejecting request denied by the external guardrail", slog.String("backend", u.backendName),
+			slog.String("reason", resp.Reason))
+		u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
+		return createUserFacingErrorResponse(400, "BadRequest", jsonEscaped(externalGuardrailDeniedMessage("request", resp.Reason))), nil
+	case resp.Decision == guardrail.ExternalDecisionRewrite:
+		for i, t := range texts {
+			content := resp.Messages[i].Content
+			if content == req.Messages[i].Content {
+				continue
+			}
+			var err error
+			if raw, err = sjson.SetBytes(raw, t.path, content); err != nil {
+				return nil, fmt.Errorf("failed to rewrite the request: %w", err)
+			}
+			req.Messages[i].Content = content
+		}
+		_, parsed, _, _, err := rp.eh.ParseBody(raw, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the rewritten request: %w", err)
+		}
+		u.requestBodyRaw, u.requestBody = raw, parsed
+		u.requestBodyMasked = true
+	}
+
+	if u.externalGuardrail.ResponseWindowSize > 0 {
+		u.externalRequest = req
+		if rp.stream {
+			u.externalStream = &externalGuardrailStream{format: streamFailoverFormatOf(rp.eh), windowSize: u.externalGuardrail.ResponseWindowSize}
+		}
+	}
+	return nil, nil
+}
+
+// checkExternalGuardrail calls the external guardrail service, and records the check. This returns nil if the check
+// fails and the guardrail fails closed, or the allow decision if it fails open.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) checkExternalGuardrail(ctx context.Context, req *guardrail.ExternalCheckRequest) *guardrail.ExternalCheckResponse {
+	resp, err := u.externalChecker.Check(ctx, req)
+	if err == nil && resp.Decision == guardrail.ExternalDecisionRewrite && req.Phase == guardrail.ExternalPhaseRequest &&
+		len(resp.Messages) != len(req.Messages) {
+		err = fmt.Errorf("rewrite has %d messages for the %d messages of the request", len(resp.Messages), len(req.Messages))
+	}
+
+	decision, reason := externalGuardrailDecisionError, ""
+	if err == nil {
+		decision, reason = string(resp.Decision), resp.Reason
+	}
+	if m, ok := u.metrics.(metrics.GuardrailMetrics); ok {
+		m.RecordExternalGuardrailCheck(ctx, req.Phase, decision, u.requestHeaders)
+	}
+	if recorder, ok := u.parent.span.(tracingapi.GuardrailRecorder); ok {
+		recorder.RecordExternalGuardrailCheck(req.Phase, decision, reason)
+	}
+	if err != nil {
+		u.logger.Info("failed to check with the external guardrail", slog.String("backend", u.backendName),
+			slog.String("phase", req.Phase), slog.Bool("fail_open", u.externalGuardrail.FailOpen), slog.String("error", err.Error()))
+		if u.externalGuardrail.FailOpen {
+			return &guardrail.ExternalCheckResponse{Decision: guardrail.ExternalDecisionAllow}
+		}
+		return nil
+	}
+	return resp
+}
+
+// checkExternalGuardrailResponse checks the text output of the non-streaming response with the external guardrail
+// service, and returns the response body returned to the client. If the service denies the response or fails while
+// the guardrail fails closed, the body is the error replacing the response, and statusCode is its status.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) checkExternalGuardrailResponse(ctx context.Context, body []byte) (out []byte, statusCode int) {
+	req := *u.externalRequest
+	req.Phase, req.Output, req.Complete = guardrail.ExternalPhaseResponse, responseOutputText(u.parent.eh, body), true
+	resp := u.checkExternalGuardrail(ctx, &req)
+	switch {
+	case resp == nil:
+		return formatUserFacingErrorJSON("ServiceUnavailable", 503, "guardrail service is unavailable"), 503
+	case resp.Decision == guardrail.ExternalDecisionDeny:
+		u.logger.Info("replacing response denied by the external guardrail", slog.String("backend", u.backendName),
+			slog.String("reason", resp.Reason))
+		return formatUserFacingErrorJSON("BadRequest", 400, jsonEscaped(externalGuardrailDeniedMessage("response", resp.Reason))), 400
+	}
+	return body, 0
+}
+
+// externalGuardrailStream holds back the events of the streaming response between the checks of the external
+// guardrail service, so that the output denied by the service is not returned to the client.
+type externalGuardrailStream struct {
+	format     streamFailoverFormat
+	windowSize int
+	// pending is the incomplete event at the end of the response so far.
+	pending []byte
+	// held are the complete events held back since the last check, and heldText is the number of the characters of
+	// their text output.
+	held     []byte
+	heldText int
+	// output is the text output of the response so far.
+	output strings.Builder
+	// denied is true once the response is terminated, after which the rest of the response is dropped.
+	denied bool
+}
+
+// checkExternalGuardrailStream checks the chunk of the streaming response each time the text output held back reaches
+// the window size and at the end of the stream, and returns the events allowed by the external guardrail service. This
+// returns the error event terminating the stream if the service denies the output or fails while the guardrail fails
+// closed, after which denied is true.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) checkExternalGuardrailStream(ctx context.Context, chunk []byte, endOfStream bool) (out []byte, denied bool) {
+	s := u.externalStream
+	if s.denied {
+		return nil, true
+	}
+	events, rest := splitSSEEvents(append(s.pending, chunk...))
+	s.pending = rest
+	for _, raw := range events {
+		s.held = append(s.held, raw...)
+		for _, d := range textDeltas(s.format, parseSSEEvent(raw)) {
+			text := gjson.GetBytes(d.event.data, d.path).String()
+			s.output.WriteString(text)
+			s.heldText += utf8.RuneCountInString(text)
+		}
+		if s.heldText >= s.windowSize {
+			if errEvent := u.checkExternalGuardrailOutput(ctx, false); errEvent != nil {
+				return append(out, errEvent...), true
+			}
+			out, s.held, s.heldText = append(out, s.held...), nil, 0
+		}
+	}
+	if endOfStream {
+		if s.output.Len() > 0 {
+			if errEvent := u.checkExternalGuardrailOutput(ctx, true); errEvent != nil {
+				return append(out, errEvent...), true
+			}
+		}
+		out = append(append(out, s.held...), s.pending...)
+		s.held, s.heldText, s.pending = nil, 0, nil
+	}
+	return out, false
+}
+
+// checkExternalGuardrailOutput checks the text output of the streaming response so far, and returns the error events
+// terminating the stream if it is not allowed, or nil otherwise.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) checkExternalGuardrailOutput(ctx context.Context, complete bool) []byte {
+	s := u.externalStream
+	req := *u.externalRequest
+	req.Phase, req.Output, req.Complete = guardrail.ExternalPhaseResponse, s.output.String(), complete
+	resp := u.checkExternalGuardrail(ctx, &req)
+	if resp != nil && resp.Decision != guardrail.ExternalDecisionDeny {
+		return nil
+	}
+	s.denied, s.held, s.pending = true, nil, nil
+	if resp == nil {
+		return externalGuardrailErrorEvents(s.format, 503, "ServiceUnavailable", "guardrail service is unavailable")
+	}
+	u.logger.Info("terminating stream denied by the external guardrail", slog.String("backend", u.backendName),
+		slog.String("reason", resp.Reason))
+	return externalGuardrailErrorEvents(s.format, 400, "BadRequest", externalGuardrailDeniedMessage("response", resp.Reason))
+}
+
+// externalGuardrailErrorEvents returns the events terminating the streaming response of the format with the error.
+func externalGuardrailErrorEvents(format streamFailoverFormat, statusCode int, errorType, message string) []byte {
+	data := formatUserFacingErrorJSON(errorType, statusCode, jsonEscaped(message))
+	switch format {
+	case streamFailoverChatCompletions:
+		return append(sseEvent{data: data}.bytes(), sseEvent{data: []byte("[DONE]")}.bytes()...)
+	case streamFailoverMessages:
+		return sseEvent{name: "error", data: data}.bytes()
+	default: // The events of the responses endpoint.
+		data = fmt.Appendf(nil, `{"type":"error","code":"%s","message":"%s","param":null}`, errorType, jsonEscaped(message))
+		return sseEvent{name: "error", data: data}.bytes()
+	}
+}
+
+// externalGuardrailDeniedMessage returns the message of the error returned to the client when the external guardrail
+// denies the request or the response, e.g., "request denied by the guardrail: violence".
+func externalGuardrailDeniedMessage(phase, reason string) string {
+	if reason == "" {
+		return phase + " denied by the guardrail"
+	}
+	return phase + " denied by the guardrail: " + reason
+}
+
+// jsonEscaped returns the string escaped as the content of a JSON string, since the reasons given by the external
+// guardrail services may contain any characters.
+func jsonEscaped(s string) string {
+	b, err := json.Marshal(s)
+	if err != nil || len(b) < 2 {
+		return ""
+	}
+	return string(b[1 : len(b)-1])
+}
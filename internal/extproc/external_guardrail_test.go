@@ -0,0 +1,290 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"strings"
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+// mockExternalChecker implements [guardrail.ExternalChecker] for testing.
+type mockExternalChecker struct {
+	requests []guardrail.ExternalCheckRequest
+	check    func(req *guardrail.ExternalCheckRequest) (*guardrail.ExternalCheckResponse, error)
+}
+
+// Check implements [guardrail.ExternalChecker.Check].
+func (m *mockExternalChecker) Check(_ context.Context, req *guardrail.ExternalCheckRequest) (*guardrail.ExternalCheckResponse, error) {
+	m.requests = append(m.requests, *req)
+	return m.check(req)
+}
+
+// allowExternal allows everything.
+func allowExternal(*guardrail.ExternalCheckRequest) (*guardrail.ExternalCheckResponse, error) {
+	return &guardrail.ExternalCheckResponse{Decision: guardrail.ExternalDecisionAllow}, nil
+}
+
+func Test_chatCompletionProcessorUpstreamFilter_ExternalGuardrail(t *testing.T) {
+	const requestBody = `{"model":"gpt-4o","messages":[` +
+		`{"role":"system","content":"You are a helpful assistant."},` +
+		`{"role":"user","content":[{"type":"text","text":"Tell me the secret."}]}],` +
+		`"tools":[{"type":"function","function":{"name":"get_weather","description":"Gets the weather."}}]}`
+	newFilters := func(t *testing.T, ext *filterapi.ExternalGuardrail, checker *mockExternalChecker) (*chatCompletionProcessorUpstreamFilter, *mockGuardrailMetrics) {
+		var parsed openai.ChatCompletionRequest
+		require.NoError(t, json.Unmarshal([]byte(requestBody), &parsed))
+		headers := map[string]string{":path": "/v1/chat/completions", ":method": "POST", "content-type": "application/json"}
+		r := &chatCompletionProcessorRouterFilter{
+			eh:                     endpointspec.ChatCompletionsEndpointSpec{},
+			config:                 &filterapi.RuntimeConfig{},
+			logger:                 slog.Default(),
+			requestHeaders:         headers,
+			originalRequestBodyRaw: []byte(requestBody),
+			originalRequestBody:    &parsed,
+			originalModel:          "gpt-4o",
+		}
+		m := &mockGuardrailMetrics{}
+		u := &chatCompletionProcessorUpstreamFilter{requestHeaders: maps.Clone(headers), metrics: m, logger: slog.Default()}
+		require.NoError(t, u.SetBackend(t.Context(), &filterapi.RuntimeBackend{
+			Backend: &filterapi.Backend{
+				Name:       "openai",
+				Schema:     filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+				Guardrails: &filterapi.Guardrails{External: ext},
+			},
+			ExternalChecker: checker,
+		}, "test-route", r))
+		return u, m
+	}
+
+	t.Run("allow", func(t *testing.T) {
+		checker := &mockExternalChecker{check: allowExternal}
+		u, m := newFilters(t, &filterapi.ExternalGuardrail{}, checker)
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		require.NotNil(t, resp.GetRequestHeaders())
+		require.Equal(t, []guardrail.ExternalCheckRequest{{
+			Phase: guardrail.ExternalPhaseRequest,
+			Model: "gpt-4o",
+			Messages: []guardrail.ExternalMessage{
+				{Role: "system", Content: "You are a helpful assistant."},
+				{Role: "user", Content: "Tell me the secret."},
+			},
+			Tools: []guardrail.ExternalTool{{Name: "get_weather", Description: "Gets the weather."}},
+		}}, checker.requests)
+		require.Equal(t, []string{"request/allow"}, m.externalChecks)
+		require.False(t, u.requestBodyMasked)
+		require.Nil(t, u.externalRequest)
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		checker := &mockExternalChecker{check: func(*guardrail.ExternalCheckRequest) (*guardrail.ExternalCheckResponse, error) {
+			return &guardrail.ExternalCheckResponse{Decision: guardrail.ExternalDecisionDeny, Reason: `asks for "secrets"`}, nil
+		}}
+		u, m := newFilters(t, &filterapi.ExternalGuardrail{}, checker)
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		ir := resp.GetImmediateResponse()
+		require.NotNil(t, ir)
+		require.Equal(t, typev3.StatusCode_BadRequest, ir.Status.Code)
+		require.Equal(t, `request denied by the guardrail: asks for "secrets"`, gjson.GetBytes(ir.Body, "error.message").String())
+		require.Equal(t, []string{"request/deny"}, m.externalChecks)
+		m.RequireRequestFailure(t)
+	})
+
+	t.Run("rewrite", func(t *testing.T) {
+		checker := &mockExternalChecker{check: func(req *guardrail.ExternalCheckRequest) (*guardrail.ExternalCheckResponse, error) {
+			resp := &guardrail.ExternalCheckResponse{Decision: guardrail.ExternalDecisionRewrite}
+			for _, msg := range req.Messages {
+				msg.Content = strings.ReplaceAll(msg.Content, "secret", "weather")
+				resp.Messages = append(resp.Messages, msg)
+			}
+			return resp, nil
+		}}
+		u, m := newFilters(t, &filterapi.ExternalGuardrail{}, checker)
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		body := resp.GetRequestHeaders().Response.BodyMutation.GetBody()
+		require.Equal(t, "Tell me the weather.", gjson.GetBytes(body, "messages.1.content.0.text").String())
+		require.Equal(t, "You are a helpful assistant.", gjson.GetBytes(body, "messages.0.content").String())
+		require.Equal(t, []string{"request/rewrite"}, m.externalChecks)
+	})
+
+	t.Run("invalid rewrite fails closed", func(t *testing.T) {
+		checker := &mockExternalChecker{check: func(*guardrail.ExternalCheckRequest) (*guardrail.ExternalCheckResponse, error) {
+			return &guardrail.ExternalCheckResponse{Decision: guardrail.ExternalDecisionRewrite}, nil
+		}}
+		u, m := newFilters(t, &filterapi.ExternalGuardrail{}, checker)
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		require.Equal(t, typev3.StatusCode_ServiceUnavailable, resp.GetImmediateResponse().Status.Code)
+		require.Equal(t, []string{"request/error"}, m.externalChecks)
+	})
+
+	t.Run("failure fails open", func(t *testing.T) {
+		checker := &mockExternalChecker{check: func(*guardrail.ExternalCheckRequest) (*guardrail.ExternalCheckResponse, error) {
+			return nil, errors.New("connection refused")
+		}}
+		u, m := newFilters(t, &filterapi.ExternalGuardrail{FailOpen: true}, checker)
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		require.NotNil(t, resp.GetRequestHeaders())
+		require.Equal(t, []string{"request/error"}, m.externalChecks)
+	})
+
+	t.Run("response", func(t *testing.T) {
+		checker := &mockExternalChecker{check: func(req *guardrail.ExternalCheckRequest) (*guardrail.ExternalCheckResponse, error) {
+			if strings.Contains(req.Output, "launch codes") {
+				return &guardrail.ExternalCheckResponse{Decision: guardrail.ExternalDecisionDeny, Reason: "leak"}, nil
+			}
+			return allowExternal(req)
+		}}
+		for _, tc := range []struct {
+			name, output, expStatus string
+			expChecks               []string
+		}{
+			{name: "allowed", output: "It is sunny.", expChecks: []string{"request/allow", "response/allow"}},
+			{name: "denied", output: "The launch codes are 0000.", expStatus: "400", expChecks: []string{"request/allow", "response/deny"}},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				checker.requests = nil
+				u, m := newFilters(t, &filterapi.ExternalGuardrail{ResponseWindowSize: 100}, checker)
+				_, err := u.ProcessRequestHeaders(t.Context(), nil)
+				require.NoError(t, err)
+				_, err = u.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{Headers: []*corev3.HeaderValue{
+					{Key: ":status", Value: "200"}, {Key: "content-type", Value: "application/json"},
+				}})
+				require.NoError(t, err)
+				respBody := `{"choices":[{"index":0,"message":{"role":"assistant","content":"` + tc.output + `"}}],` +
+					`"usage":{"prompt_tokens":10,"completion_tokens":8,"total_tokens":18}}`
+				res, err := u.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{EndOfStream: true, Body: []byte(respBody)})
+				require.NoError(t, err)
+				require.Equal(t, tc.expChecks, m.externalChecks)
+				require.Equal(t, tc.output, checker.requests[1].Output)
+				require.True(t, checker.requests[1].Complete)
+				require.Len(t, checker.requests[1].Messages, 2)
+
+				out := res.GetResponseBody().Response.BodyMutation.GetBody()
+				var status string
+				for _, h := range res.GetResponseBody().Response.HeaderMutation.GetSetHeaders() {
+					if h.Header.Key == ":status" {
+						status = string(h.Header.RawValue)
+					}
+				}
+				require.Equal(t, tc.expStatus, status)
+				if tc.expStatus == "" {
+					require.Equal(t, respBody, string(out))
+				} else {
+					require.Equal(t, "response denied by the guardrail: leak", gjson.GetBytes(out, "error.message").String())
+					m.RequireRequestFailure(t)
+				}
+				// The token usage is recorded regardless of the decision.
+				m.RequireTokensRecorded(t, 10, 0, 0, 8)
+			})
+		}
+	})
+}
+
+func TestExternalGuardrailStream(t *testing.T) {
+	newUpstream := func(format streamFailoverFormat, checker *mockExternalChecker) *chatCompletionProcessorUpstreamFilter {
+		return &chatCompletionProcessorUpstreamFilter{
+			parent:            &chatCompletionProcessorRouterFilter{eh: endpointspec.ChatCompletionsEndpointSpec{}},
+			metrics:           &mockGuardrailMetrics{},
+			logger:            slog.Default(),
+			externalChecker:   checker,
+			externalGuardrail: &filterapi.ExternalGuardrail{ResponseWindowSize: 10},
+			externalRequest:   &guardrail.ExternalCheckRequest{Phase: guardrail.ExternalPhaseRequest, Model: "gpt-4o"},
+			externalStream:    &externalGuardrailStream{format: format, windowSize: 10},
+		}
+	}
+	chatChunk := func(text string) string {
+		return `data: {"choices":[{"index":0,"delta":{"content":"` + text + `"}}]}` + "\n\n"
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		checker := &mockExternalChecker{check: allowExternal}
+		u := newUpstream(streamFailoverChatCompletions, checker)
+		// The events are held back until the text output reaches the window size.
+		out, denied := u.checkExternalGuardrailStream(t.Context(), []byte(chatChunk("Hello")+chatChunk(", wor")[:10]), false)
+		require.False(t, denied)
+		require.Empty(t, out)
+		require.Empty(t, checker.requests)
+
+		out, denied = u.checkExternalGuardrailStream(t.Context(), []byte(chatChunk(", wor")[10:]+chatChunk("ld")), false)
+		require.False(t, denied)
+		require.Equal(t, chatChunk("Hello")+chatChunk(", wor"), string(out))
+		require.Len(t, checker.requests, 1)
+		require.Equal(t, guardrail.ExternalCheckRequest{Phase: guardrail.ExternalPhaseResponse, Model: "gpt-4o", Output: "Hello, wor"}, checker.requests[0])
+
+		// The rest is checked at the end of the stream.
+		out, denied = u.checkExternalGuardrailStream(t.Context(), []byte("data: [DONE]\n\n"), true)
+		require.False(t, denied)
+		require.Equal(t, chatChunk("ld")+"data: [DONE]\n\n", string(out))
+		require.Len(t, checker.requests, 2)
+		require.Equal(t, "Hello, world", checker.requests[1].Output)
+		require.True(t, checker.requests[1].Complete)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		checker := &mockExternalChecker{check: func(req *guardrail.ExternalCheckRequest) (*guardrail.ExternalCheckResponse, error) {
+			if strings.Contains(req.Output, "launch") {
+				return &guardrail.ExternalCheckResponse{Decision: guardrail.ExternalDecisionDeny, Reason: "leak"}, nil
+			}
+			return allowExternal(req)
+		}}
+		u := newUpstream(streamFailoverChatCompletions, checker)
+		out, denied := u.checkExternalGuardrailStream(t.Context(), []byte(chatChunk("The codes:")+chatChunk(" launch it")), false)
+		require.True(t, denied)
+		require.Equal(t, chatChunk("The codes:")+
+			`data: {"type":"error","error":{"type":"BadRequest","code":"400","message":"response denied by the guardrail: leak"}}`+"\n\n"+
+			"data: [DONE]\n\n", string(out))
+
+		// The rest of the stream is dropped.
+		out, denied = u.checkExternalGuardrailStream(t.Context(), []byte(chatChunk("now")+"data: [DONE]\n\n"), true)
+		require.True(t, denied)
+		require.Empty(t, out)
+		require.Len(t, checker.requests, 2)
+	})
+
+	t.Run("messages failure fails closed", func(t *testing.T) {
+		checker := &mockExternalChecker{check: func(*guardrail.ExternalCheckRequest) (*guardrail.ExternalCheckResponse, error) {
+			return nil, errors.New("timeout")
+		}}
+		u := newUpstream(streamFailoverMessages, checker)
+		out, denied := u.checkExternalGuardrailStream(t.Context(), []byte("event: content_block_delta\n"+
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"0123456789"}}`+"\n\n"), false)
+		require.True(t, denied)
+		require.Equal(t, "event: error\n"+
+			`data: {"type":"error","error":{"type":"ServiceUnavailable","code":"503","message":"guardrail service is unavailable"}}`+"\n\n", string(out))
+	})
+
+	t.Run("responses", func(t *testing.T) {
+		checker := &mockExternalChecker{check: func(*guardrail.ExternalCheckRequest) (*guardrail.ExternalCheckResponse, error) {
+			return &guardrail.ExternalCheckResponse{Decision: guardrail.ExternalDecisionDeny}, nil
+		}}
+		u := newUpstream(0, checker)
+		out, denied := u.checkExternalGuardrailStream(t.Context(), []byte("event: response.output_text.delta\n"+
+			`data: {"type":"response.output_text.delta","output_index":0,"content_index":0,"delta":"Hi"}`+"\n\n"), true)
+		require.True(t, denied)
+		require.Equal(t, "event: error\n"+
+			`data: {"type":"error","code":"BadRequest","message":"response denied by the guardrail","param":null}`+"\n\n", string(out))
+		require.Equal(t, "Hi", checker.requests[0].Output)
+	})
+}
@@ -0,0 +1,183 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
+)
+
+// requestText is a text of the request inspected by the guardrails.
+type requestText struct {
+	// path is the path of the text in the request body.
+	path string
+	// role is the normalized role of the text, i.e., "system", "user", "assistant" or "tool".
+	role string
+}
+
+// requestTexts returns the texts of the request inspected by the guardrails, which are the text of the messages, the
+// system prompt and the instructions of the chat completions, the messages and the responses requests.
+func requestTexts(eh any, raw []byte) []requestText {
+	var texts []requestText
+	// addContent adds the content that is either a string or an array of the parts of the given types.
+	addContent := func(path, role string, content gjson.Result, partTypes ...string) {
+		if content.Type == gjson.String {
+			texts = append(texts, requestText{path: path, role: role})
+			return
+		}
+		for j, part := range content.Array() {
+			if slices.Contains(partTypes, part.Get("type").String()) && part.Get("text").Type == gjson.String {
+				texts = append(texts, requestText{path: path + "." + strconv.Itoa(j) + ".text", role: role})
+			}
+		}
+	}
+	switch eh.(type) {
+	case endpointspec.ChatCompletionsEndpointSpec:
+		for i, m := range gjson.GetBytes(raw, "messages").Array() {
+			addContent("messages."+strconv.Itoa(i)+".content", normalizedRole(m.Get("role").String()), m.Get("content"), "text")
+		}
+	case endpointspec.MessagesEndpointSpec:
+		addContent("system", "system", gjson.GetBytes(raw, "system"), "text")
+		for i, m := range gjson.GetBytes(raw, "messages").Array() {
+			path := "messages." + strconv.Itoa(i) + ".content"
+			content := m.Get("content")
+			addContent(path, normalizedRole(m.Get("role").String()), content, "text")
+			for j, block := range content.Array() {
+				if block.Get("type").String() == "tool_result" {
+					addContent(path+"."+strconv.Itoa(j)+".content", "tool", block.Get("content"), "text")
+				}
+			}
+		}
+	case endpointspec.ResponsesEndpointSpec:
+		if gjson.GetBytes(raw, "instructions").Type == gjson.String {
+			texts = append(texts, requestText{path: "instructions", role: "system"})
+		}
+		input := gjson.GetBytes(raw, "input")
+		if input.Type == gjson.String {
+			texts = append(texts, requestText{path: "input", role: "user"})
+		}
+		for i, item := range input.Array() {
+			path := "input." + strconv.Itoa(i)
+			if content := item.Get("content"); content.Exists() {
+				addContent(path+".content", normalizedRole(cmp.Or(item.Get("role").String(), "user")), content,
+					"input_text", "output_text")
+			}
+			if item.Get("output").Type == gjson.String {
+				texts = append(texts, requestText{path: path + ".output", role: "tool"})
+			}
+		}
+	}
+	return texts
+}
+
+// normalizedRole returns the role of the message in the normalized view of the request, where the developer messages
+// are the system ones.
+func normalizedRole(role string) string {
+	if role == "developer" {
+		return "system"
+	}
+	return role
+}
+
+// requestTools returns the tools of the chat completions, the messages and the responses requests in the normalized
+// view of the request. The built-in tools without a name are named after their type.
+func requestTools(eh any, raw []byte) []guardrail.ExternalTool {
+	var tools []guardrail.ExternalTool
+	for _, tool := range gjson.GetBytes(raw, "tools").Array() {
+		fn := tool
+		if _, ok := eh.(endpointspec.ChatCompletionsEndpointSpec); ok && tool.Get("function").Exists() {
+			fn = tool.Get("function")
+		}
+		tools = append(tools, guardrail.ExternalTool{
+			Name:        cmp.Or(fn.Get("name").String(), tool.Get("type").String()),
+			Description: fn.Get("description").String(),
+		})
+	}
+	return tools
+}
+
+// responseOutputText returns the text output of the non-streaming response of the chat completions, the messages and
+// the responses endpoints, with the texts of the choices and the content blocks separated by the newlines.
+func responseOutputText(eh any, body []byte) string {
+	var texts []string
+	switch eh.(type) {
+	case endpointspec.ChatCompletionsEndpointSpec:
+		for _, choice := range gjson.GetBytes(body, "choices").Array() {
+			if content := choice.Get("message.content"); content.Type == gjson.String {
+				texts = append(texts, content.String())
+			}
+		}
+	case endpointspec.MessagesEndpointSpec:
+		for _, block := range gjson.GetBytes(body, "content").Array() {
+			if block.Get("type").String() == "text" {
+				texts = append(texts, block.Get("text").String())
+			}
+		}
+	case endpointspec.ResponsesEndpointSpec:
+		for _, item := range gjson.GetBytes(body, "output").Array() {
+			for _, part := range item.Get("content").Array() {
+				if part.Get("type").String() == "output_text" {
+					texts = append(texts, part.Get("text").String())
+				}
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// textDelta is a text delta of a streaming response.
+type textDelta struct {
+	event sseEvent
+	// key identifies the text the delta belongs to, e.g., the index of the choice.
+	key  string
+	path string
+	// final is true if no more text follows the delta.
+	final bool
+}
+
+// textDeltas returns the text deltas in the event of the streaming response of the format, or nil if it is not a
+// text delta. The zero format is the one of the responses endpoint.
+func textDeltas(format streamFailoverFormat, e sseEvent) []textDelta {
+	switch format {
+	case streamFailoverChatCompletions:
+		var deltas []textDelta
+		for _, choice := range gjson.GetBytes(e.data, "choices").Array() {
+			if choice.Get("delta.content").Type != gjson.String {
+				continue
+			}
+			// The delta is returned as a chunk of its own choice, so that it can be modified for the choice.
+			data, err := sjson.SetRawBytes(e.data, "choices", []byte("["+choice.Raw+"]"))
+			if err != nil {
+				return nil
+			}
+			deltas = append(deltas, textDelta{
+				event: sseEvent{name: e.name, data: data},
+				key:   "choice:" + choice.Get("index").String(),
+				path:  "choices.0.delta.content",
+				final: choice.Get("finish_reason").Type == gjson.String,
+			})
+		}
+		return deltas
+	case streamFailoverMessages:
+		if gjson.GetBytes(e.data, "type").String() == "content_block_delta" && gjson.GetBytes(e.data, "delta.text").Type == gjson.String {
+			return []textDelta{{event: e, key: "block:" + gjson.GetBytes(e.data, "index").String(), path: "delta.text"}}
+		}
+	default: // The events of the responses endpoint.
+		if gjson.GetBytes(e.data, "type").String() == "response.output_text.delta" {
+			key := "output:" + gjson.GetBytes(e.data, "output_index").String() + ":" + gjson.GetBytes(e.data, "content_index").String()
+			return []textDelta{{event: e, key: key, path: "delta"}}
+		}
+	}
+	return nil
+}
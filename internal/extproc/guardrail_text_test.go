@@ -0,0 +1,139 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
+)
+
+func TestRequestTexts(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		eh   any
+		body string
+		exp  []requestText
+	}{
+		{
+			name: "chat completions",
+			eh:   endpointspec.ChatCompletionsEndpointSpec{},
+			body: `{"messages":[{"role":"developer","content":"a"},{"role":"user","content":[{"type":"image_url"},{"type":"text","text":"b"}]}]}`,
+			exp:  []requestText{{path: "messages.0.content", role: "system"}, {path: "messages.1.content.1.text", role: "user"}},
+		},
+		{
+			name: "messages",
+			eh:   endpointspec.MessagesEndpointSpec{},
+			body: `{"system":[{"type":"text","text":"s"}],"messages":[{"role":"user","content":"a"},` +
+				`{"role":"user","content":[{"type":"text","text":"b"},{"type":"tool_result","content":"c"}]}]}`,
+			exp: []requestText{
+				{path: "system.0.text", role: "system"},
+				{path: "messages.0.content", role: "user"},
+				{path: "messages.1.content.0.text", role: "user"},
+				{path: "messages.1.content.1.content", role: "tool"},
+			},
+		},
+		{
+			name: "responses",
+			eh:   endpointspec.ResponsesEndpointSpec{},
+			body: `{"instructions":"i","input":[{"role":"user","content":[{"type":"input_text","text":"a"}]},` +
+				`{"type":"function_call_output","output":"b"}]}`,
+			exp: []requestText{
+				{path: "instructions", role: "system"},
+				{path: "input.0.content.0.text", role: "user"},
+				{path: "input.1.output", role: "tool"},
+			},
+		},
+		{
+			name: "responses with string input",
+			eh:   endpointspec.ResponsesEndpointSpec{},
+			body: `{"input":"a"}`,
+			exp:  []requestText{{path: "input", role: "user"}},
+		},
+		{
+			name: "embeddings",
+			eh:   endpointspec.EmbeddingsEndpointSpec{},
+			body: `{"input":"a"}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, requestTexts(tc.eh, []byte(tc.body)))
+		})
+	}
+}
+
+func TestRequestTools(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		eh   any
+		body string
+		exp  []guardrail.ExternalTool
+	}{
+		{
+			name: "chat completions",
+			eh:   endpointspec.ChatCompletionsEndpointSpec{},
+			body: `{"tools":[{"type":"function","function":{"name":"get_weather","description":"Gets the weather."}}]}`,
+			exp:  []guardrail.ExternalTool{{Name: "get_weather", Description: "Gets the weather."}},
+		},
+		{
+			name: "messages",
+			eh:   endpointspec.MessagesEndpointSpec{},
+			body: `{"tools":[{"name":"get_weather","description":"Gets the weather.","input_schema":{}},` +
+				`{"type":"web_search_20250305","name":"web_search"}]}`,
+			exp: []guardrail.ExternalTool{{Name: "get_weather", Description: "Gets the weather."}, {Name: "web_search"}},
+		},
+		{
+			name: "responses",
+			eh:   endpointspec.ResponsesEndpointSpec{},
+			body: `{"tools":[{"type":"function","name":"get_weather"},{"type":"web_search_preview"}]}`,
+			exp:  []guardrail.ExternalTool{{Name: "get_weather"}, {Name: "web_search_preview"}},
+		},
+		{
+			name: "no tools",
+			eh:   endpointspec.ChatCompletionsEndpointSpec{},
+			body: `{}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, requestTools(tc.eh, []byte(tc.body)))
+		})
+	}
+}
+
+func TestResponseOutputText(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		eh   any
+		body string
+		exp  string
+	}{
+		{
+			name: "chat completions",
+			eh:   endpointspec.ChatCompletionsEndpointSpec{},
+			body: `{"choices":[{"message":{"content":"a"}},{"message":{"content":null,"tool_calls":[]}},{"message":{"content":"b"}}]}`,
+			exp:  "a\nb",
+		},
+		{
+			name: "messages",
+			eh:   endpointspec.MessagesEndpointSpec{},
+			body: `{"content":[{"type":"thinking","thinking":"t"},{"type":"text","text":"a"}]}`,
+			exp:  "a",
+		},
+		{
+			name: "responses",
+			eh:   endpointspec.ResponsesEndpointSpec{},
+			body: `{"output":[{"type":"reasoning"},{"type":"message","content":[{"type":"output_text","text":"a"}]}]}`,
+			exp:  "a",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, responseOutputText(tc.eh, []byte(tc.body)))
+		})
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 var (
 	_ Processor                                 = &mockProcessor{}
+	_ upstreamResponseProcessor                 = &mockUpstreamResponseProcessor{}
 	_ translator.OpenAIChatCompletionTranslator = &mockTranslator{}
 )
 
@@ -72,6 +73,24 @@ func (m mockProcessor) ProcessResponseBody(_ context.Context, body *extprocv3.Ht
 	return m.retProcessingResponse, m.retErr
 }
 
+// mockUpstreamResponseProcessor implements [upstreamResponseProcessor] for testing.
+type mockUpstreamResponseProcessor struct {
+	mockProcessor
+	retUpstreamProcessingResponse *extprocv3.ProcessingResponse
+}
+
+// ProcessUpstreamResponseHeaders implements [upstreamResponseProcessor.ProcessUpstreamResponseHeaders].
+func (m mockUpstreamResponseProcessor) ProcessUpstreamResponseHeaders(_ context.Context, headerMap *corev3.HeaderMap) (*extprocv3.ProcessingResponse, error) {
+	require.Equal(m.t, m.expHeaderMap, headerMap)
+	return m.retUpstreamProcessingResponse, m.retErr
+}
+
+// ProcessUpstreamResponseBody implements [upstreamResponseProcessor.ProcessUpstreamResponseBody].
+func (m mockUpstreamResponseProcessor) ProcessUpstreamResponseBody(_ context.Context, body *extprocv3.HttpBody) (*extprocv3.ProcessingResponse, error) {
+	require.Equal(m.t, m.expBody, body)
+	return m.retUpstreamProcessingResponse, m.retErr
+}
+
 // mockTranslator implements [translator.Translator] for testing.
 type mockTranslator struct {
 	t                           *testing.T
@@ -176,6 +195,7 @@ type mockMetrics struct {
 	requestModel                 string
 	responseModel                string
 	backend                      string
+	errorType                    string
 	requestSuccessCount          int
 	requestErrorCount            int
 	inputTokenCount              int
@@ -208,6 +228,9 @@ func (m *mockMetrics) SetResponseModel(responseModel internalapi.ResponseModel)
 	m.responseModel = responseModel
 }
 
+// SetErrorType implements [metrics.Metrics].
+func (m *mockMetrics) SetErrorType(errorType string) { m.errorType = errorType }
+
 // SetBackend implements [metrics.Metrics].
 func (m *mockMetrics) SetBackend(backend *filterapi.Backend) { m.backend = backend.Name }
 
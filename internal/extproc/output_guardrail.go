@@ -0,0 +1,220 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+	"github.com/envoyproxy/ai-gateway/internal/tracing/tracingapi"
+)
+
+// outputGuardrailSupported returns true if the text output of the responses of the endpoint is filtered by the
+// output guardrail.
+func outputGuardrailSupported(eh any) bool {
+	switch eh.(type) {
+	case endpointspec.ChatCompletionsEndpointSpec, endpointspec.MessagesEndpointSpec, endpointspec.ResponsesEndpointSpec:
+		return true
+	}
+	return false
+}
+
+// outputGuardrailStream tracks the text output of the streaming response filtered by the output guardrail.
+type outputGuardrailStream struct {
+	format streamFailoverFormat
+	// pending is the incomplete event at the end of the response so far.
+	pending []byte
+	// windows are the windows of the texts of the response, e.g., of each choice, by the key of their deltas.
+	windows map[string]*guardrail.OutputWindow
+	// response is the response object of the last lifecycle event of the responses endpoint, which is terminated as
+	// incomplete, and sequence is the sequence number of the last event.
+	response gjson.Result
+	sequence int64
+	// matched is true once a rule matches, after which the output is not filtered anymore, and blocked is true if the
+	// stream is terminated, after which the rest of the response is dropped.
+	matched, blocked bool
+}
+
+// checkOutputGuardrail filters the chunk of the response with the output guardrail, and returns the chunk returned to
+// the client. If a rule matches in the Block mode, the streaming response is terminated with the events of the finish
+// reason of the content filter, and the non-streaming response is replaced with the one of that finish reason, after
+// which blocked is true.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) checkOutputGuardrail(ctx context.Context, chunk []byte, endOfStream bool) (out []byte, blocked bool) {
+	if !u.parent.stream {
+		return u.checkOutputGuardrailResponse(ctx, chunk)
+	}
+	if u.outputStream == nil {
+		u.outputStream = &outputGuardrailStream{format: streamFailoverFormatOf(u.parent.eh), windows: make(map[string]*guardrail.OutputWindow)}
+	}
+	s := u.outputStream
+	if s.blocked {
+		return nil, true
+	}
+	if s.matched {
+		return chunk, false
+	}
+	events, rest := splitSSEEvents(append(s.pending, chunk...))
+	s.pending = rest
+	for i, raw := range events {
+		e := parseSSEEvent(raw)
+		s.observe(e)
+		for _, d := range textDeltas(s.format, e) {
+			w, ok := s.windows[d.key]
+			if !ok {
+				w = u.outputFilter.NewWindow()
+				s.windows[d.key] = w
+			}
+			rule := w.Append(gjson.GetBytes(d.event.data, d.path).String())
+			if rule == "" {
+				continue
+			}
+			s.matched = true
+			u.recordOutputGuardrailMatch(ctx, rule)
+			if u.outputGuardrail.Shadow {
+				// The rest of the response is returned as is.
+				out = append(append(append(out, raw...), joinEvents(events[i+1:])...), s.pending...)
+				s.pending = nil
+				return out, false
+			}
+			s.blocked, s.pending = true, nil
+			return append(out, u.outputGuardrailFinishEvents(d.event)...), true
+		}
+		out = append(out, raw...)
+	}
+	if endOfStream {
+		out = append(out, s.pending...)
+		s.pending = nil
+	}
+	return out, false
+}
+
+// observe records the state of the response from the event needed to terminate it.
+func (s *outputGuardrailStream) observe(e sseEvent) {
+	if s.format != 0 {
+		return
+	}
+	// The events of the responses endpoint.
+	if seq := gjson.GetBytes(e.data, "sequence_number"); seq.Exists() {
+		s.sequence = seq.Int()
+	}
+	if resp := gjson.GetBytes(e.data, "response"); resp.IsObject() {
+		s.response = resp
+	}
+}
+
+// joinEvents concatenates the raw events.
+func joinEvents(events [][]byte) []byte {
+	var b []byte
+	for _, e := range events {
+		b = append(b, e...)
+	}
+	return b
+}
+
+// outputGuardrailFinishEvents returns the events terminating the streaming response with the finish reason of the
+// content filter, following the text delta matching the rule.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) outputGuardrailFinishEvents(delta sseEvent) []byte {
+	switch s := u.outputStream; s.format {
+	case streamFailoverChatCompletions:
+		// The chunk of the delta is reused for its identifiers, e.g., the ID and the model.
+		choice := fmt.Appendf(nil, `[{"index":%d,"delta":{},"finish_reason":"content_filter"}]`,
+			gjson.GetBytes(delta.data, "choices.0.index").Int())
+		data, err := sjson.SetRawBytes(delta.data, "choices", choice)
+		if err != nil {
+			data = delta.data
+		}
+		return append(sseEvent{name: delta.name, data: data}.bytes(), sseEvent{data: []byte("[DONE]")}.bytes()...)
+	case streamFailoverMessages:
+		outputTokens, _ := u.costs.OutputTokens()
+		b := sseEvent{name: "content_block_stop", data: fmt.Appendf(nil, `{"type":"content_block_stop","index":%d}`,
+			gjson.GetBytes(delta.data, "index").Int())}.bytes()
+		b = append(b, sseEvent{name: "message_delta", data: fmt.Appendf(nil,
+			`{"type":"message_delta","delta":{"stop_reason":"refusal","stop_sequence":null},"usage":{"output_tokens":%d}}`,
+			outputTokens)}.bytes()...)
+		return append(b, sseEvent{name: "message_stop", data: []byte(`{"type":"message_stop"}`)}.bytes()...)
+	default: // The events of the responses endpoint.
+		resp := []byte(s.response.Raw)
+		if len(resp) == 0 {
+			resp = []byte(`{"object":"response"}`)
+		}
+		resp, _ = sjson.SetBytes(resp, "status", "incomplete")
+		resp, _ = sjson.SetRawBytes(resp, "incomplete_details", []byte(`{"reason":"content_filter"}`))
+		data := fmt.Appendf(nil, `{"type":"response.incomplete","sequence_number":%d}`, s.sequence+1)
+		data, _ = sjson.SetRawBytes(data, "response", resp)
+		return sseEvent{name: "response.incomplete", data: data}.bytes()
+	}
+}
+
+// checkOutputGuardrailResponse filters the text output of the non-streaming response, and returns the response body
+// returned to the client, which has the text output removed and the finish reason of the content filter if a rule
+// matches in the Block mode.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) checkOutputGuardrailResponse(ctx context.Context, body []byte) (out []byte, blocked bool) {
+	var rule string
+	out = body
+	switch any(u.parent.eh).(type) {
+	case endpointspec.ChatCompletionsEndpointSpec:
+		// The choices are filtered separately, so that only the matching ones are removed.
+		for i, choice := range gjson.GetBytes(body, "choices").Array() {
+			content := choice.Get("message.content")
+			if content.Type != gjson.String {
+				continue
+			}
+			r := u.outputFilter.Match(content.String())
+			if r == "" {
+				continue
+			}
+			rule = cmp.Or(rule, r)
+			if !u.outputGuardrail.Shadow {
+				path := "choices." + strconv.Itoa(i)
+				out, _ = sjson.SetBytes(out, path+".message.content", "")
+				out, _ = sjson.SetBytes(out, path+".finish_reason", "content_filter")
+			}
+		}
+	case endpointspec.MessagesEndpointSpec:
+		if rule = u.outputFilter.Match(responseOutputText(u.parent.eh, body)); rule != "" && !u.outputGuardrail.Shadow {
+			out, _ = sjson.SetRawBytes(out, "content", []byte("[]"))
+			out, _ = sjson.SetBytes(out, "stop_reason", "refusal")
+			out, _ = sjson.SetRawBytes(out, "stop_sequence", []byte("null"))
+		}
+	case endpointspec.ResponsesEndpointSpec:
+		if rule = u.outputFilter.Match(responseOutputText(u.parent.eh, body)); rule != "" && !u.outputGuardrail.Shadow {
+			out, _ = sjson.SetRawBytes(out, "output", []byte("[]"))
+			out, _ = sjson.SetBytes(out, "status", "incomplete")
+			out, _ = sjson.SetRawBytes(out, "incomplete_details", []byte(`{"reason":"content_filter"}`))
+		}
+	}
+	if rule == "" {
+		return body, false
+	}
+	u.recordOutputGuardrailMatch(ctx, rule)
+	return out, !u.outputGuardrail.Shadow
+}
+
+// recordOutputGuardrailMatch logs and records the match of the output by the rule, which is the audit event of the
+// output guardrail.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) recordOutputGuardrailMatch(ctx context.Context, rule string) {
+	action := "block"
+	if u.outputGuardrail.Shadow {
+		action = "shadow"
+	}
+	u.logger.Info("output matched by the output guardrail", slog.String("backend", u.backendName),
+		slog.String("rule", rule), slog.String("action", action), slog.Bool("stream", u.parent.stream))
+	if m, ok := u.metrics.(metrics.GuardrailMetrics); ok {
+		m.RecordOutputGuardrailMatch(ctx, rule, action, u.requestHeaders)
+	}
+	if recorder, ok := u.parent.span.(tracingapi.GuardrailRecorder); ok {
+		recorder.RecordOutputGuardrailMatch(action, rule)
+	}
+}
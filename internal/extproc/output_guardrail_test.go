@@ -0,0 +1,166 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
+)
+
+func newOutputFilter(t *testing.T) *guardrail.OutputFilter {
+	f, err := guardrail.NewOutputFilter([]guardrail.OutputRule{{Name: "codename", Keywords: []string{"Project Orion"}}}, 32)
+	require.NoError(t, err)
+	return f
+}
+
+func TestOutputGuardrailStream(t *testing.T) {
+	chatChunk := func(text string) string {
+		return `data: {"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"content":"` + text + `"}}]}` + "\n\n"
+	}
+	newChatUpstream := func(t *testing.T, shadow bool) (*chatCompletionProcessorUpstreamFilter, *mockGuardrailMetrics) {
+		m := &mockGuardrailMetrics{}
+		return &chatCompletionProcessorUpstreamFilter{
+			parent:          &chatCompletionProcessorRouterFilter{eh: endpointspec.ChatCompletionsEndpointSpec{}, stream: true},
+			metrics:         m,
+			logger:          slog.Default(),
+			outputFilter:    newOutputFilter(t),
+			outputGuardrail: &filterapi.OutputGuardrail{Shadow: shadow},
+		}, m
+	}
+
+	t.Run("blocked across deltas", func(t *testing.T) {
+		u, m := newChatUpstream(t, false)
+		out, blocked := u.checkOutputGuardrail(t.Context(), []byte(chatChunk("The Proj")+chatChunk("ect")[:20]), false)
+		require.False(t, blocked)
+		// The incomplete event is held until it completes.
+		require.Equal(t, chatChunk("The Proj"), string(out))
+
+		out, blocked = u.checkOutputGuardrail(t.Context(), []byte(chatChunk("ect")[20:]+chatChunk(" Orion launch")), false)
+		require.True(t, blocked)
+		require.Equal(t, chatChunk("ect")+
+			`data: {"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{},"finish_reason":"content_filter"}]}`+"\n\n"+
+			"data: [DONE]\n\n", string(out))
+		require.Equal(t, []string{"codename/block"}, m.outputMatches)
+
+		// The rest of the stream is dropped.
+		out, blocked = u.checkOutputGuardrail(t.Context(), []byte(chatChunk(" is next")+"data: [DONE]\n\n"), true)
+		require.True(t, blocked)
+		require.Empty(t, out)
+	})
+
+	t.Run("shadow", func(t *testing.T) {
+		u, m := newChatUpstream(t, true)
+		in := chatChunk("Project Orion") + chatChunk(" is") + "data: [DO"
+		out, blocked := u.checkOutputGuardrail(t.Context(), []byte(in), false)
+		require.False(t, blocked)
+		require.Equal(t, in, string(out))
+		out, blocked = u.checkOutputGuardrail(t.Context(), []byte("NE]\n\n"), true)
+		require.False(t, blocked)
+		require.Equal(t, "NE]\n\n", string(out))
+		require.Equal(t, []string{"codename/shadow"}, m.outputMatches)
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		u := &messagesProcessorUpstreamFilter{
+			parent:          &messagesProcessorRouterFilter{eh: endpointspec.MessagesEndpointSpec{}, stream: true},
+			metrics:         &mockGuardrailMetrics{},
+			logger:          slog.Default(),
+			outputFilter:    newOutputFilter(t),
+			outputGuardrail: &filterapi.OutputGuardrail{},
+		}
+		delta := "event: content_block_delta\n" +
+			`data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"about Project Orion"}}` + "\n\n"
+		out, blocked := u.checkOutputGuardrail(t.Context(), []byte(delta), false)
+		require.True(t, blocked)
+		require.Equal(t, "event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":1}\n\n"+
+			"event: message_delta\n"+
+			`data: {"type":"message_delta","delta":{"stop_reason":"refusal","stop_sequence":null},"usage":{"output_tokens":0}}`+"\n\n"+
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n", string(out))
+	})
+
+	t.Run("responses", func(t *testing.T) {
+		u := &chatCompletionProcessorUpstreamFilter{
+			parent:          &chatCompletionProcessorRouterFilter{stream: true},
+			metrics:         &mockGuardrailMetrics{},
+			logger:          slog.Default(),
+			outputFilter:    newOutputFilter(t),
+			outputGuardrail: &filterapi.OutputGuardrail{},
+			// The zero format is the one of the responses endpoint.
+			outputStream: &outputGuardrailStream{windows: make(map[string]*guardrail.OutputWindow)},
+		}
+		created := "event: response.created\n" +
+			`data: {"type":"response.created","sequence_number":0,"response":{"id":"resp_1","object":"response","status":"in_progress"}}` + "\n\n"
+		delta := "event: response.output_text.delta\n" +
+			`data: {"type":"response.output_text.delta","sequence_number":1,"output_index":0,"content_index":0,"delta":"Project Orion"}` + "\n\n"
+		out, blocked := u.checkOutputGuardrail(t.Context(), []byte(created+delta), false)
+		require.True(t, blocked)
+		require.Equal(t, created+"event: response.incomplete\n"+
+			`data: {"type":"response.incomplete","sequence_number":2,"response":{"id":"resp_1","object":"response","status":"incomplete","incomplete_details":{"reason":"content_filter"}}}`+"\n\n",
+			string(out))
+	})
+}
+
+func TestOutputGuardrailResponse(t *testing.T) {
+	t.Run("chat completions", func(t *testing.T) {
+		m := &mockGuardrailMetrics{}
+		u := &chatCompletionProcessorUpstreamFilter{
+			parent:          &chatCompletionProcessorRouterFilter{eh: endpointspec.ChatCompletionsEndpointSpec{}},
+			metrics:         m,
+			logger:          slog.Default(),
+			outputFilter:    newOutputFilter(t),
+			outputGuardrail: &filterapi.OutputGuardrail{},
+		}
+		body := `{"choices":[` +
+			`{"index":0,"message":{"role":"assistant","content":"Nothing to see."},"finish_reason":"stop"},` +
+			`{"index":1,"message":{"role":"assistant","content":"Project Orion is next."},"finish_reason":"stop"}]}`
+		out, blocked := u.checkOutputGuardrail(t.Context(), []byte(body), true)
+		require.True(t, blocked)
+		require.JSONEq(t, `{"choices":[`+
+			`{"index":0,"message":{"role":"assistant","content":"Nothing to see."},"finish_reason":"stop"},`+
+			`{"index":1,"message":{"role":"assistant","content":""},"finish_reason":"content_filter"}]}`, string(out))
+		require.Equal(t, []string{"codename/block"}, m.outputMatches)
+
+		out, blocked = u.checkOutputGuardrail(t.Context(), []byte(`{"choices":[{"index":0,"message":{"content":"Hi."}}]}`), true)
+		require.False(t, blocked)
+		require.JSONEq(t, `{"choices":[{"index":0,"message":{"content":"Hi."}}]}`, string(out))
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		u := &messagesProcessorUpstreamFilter{
+			parent:          &messagesProcessorRouterFilter{eh: endpointspec.MessagesEndpointSpec{}},
+			metrics:         &mockGuardrailMetrics{},
+			logger:          slog.Default(),
+			outputFilter:    newOutputFilter(t),
+			outputGuardrail: &filterapi.OutputGuardrail{},
+		}
+		out, blocked := u.checkOutputGuardrail(t.Context(),
+			[]byte(`{"type":"message","content":[{"type":"text","text":"Project Orion"}],"stop_reason":"end_turn"}`), true)
+		require.True(t, blocked)
+		require.JSONEq(t, `{"type":"message","content":[],"stop_reason":"refusal","stop_sequence":null}`, string(out))
+	})
+
+	t.Run("shadow", func(t *testing.T) {
+		m := &mockGuardrailMetrics{}
+		u := &messagesProcessorUpstreamFilter{
+			parent:          &messagesProcessorRouterFilter{eh: endpointspec.MessagesEndpointSpec{}},
+			metrics:         m,
+			logger:          slog.Default(),
+			outputFilter:    newOutputFilter(t),
+			outputGuardrail: &filterapi.OutputGuardrail{Shadow: true},
+		}
+		body := `{"type":"message","content":[{"type":"text","text":"Project Orion"}],"stop_reason":"end_turn"}`
+		out, blocked := u.checkOutputGuardrail(t.Context(), []byte(body), true)
+		require.False(t, blocked)
+		require.Equal(t, body, string(out))
+		require.Equal(t, []string{"codename/shadow"}, m.outputMatches)
+	})
+}
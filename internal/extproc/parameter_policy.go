@@ -0,0 +1,149 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+	"github.com/envoyproxy/ai-gateway/internal/parameterpolicy"
+)
+
+// applyParameterPolicy applies the rules of the parameter policy of the backend to the generation parameters of the
+// request body sent to the backend, which are identified by the endpoint spec of the request. This returns the
+// immediate response rejecting the request if it sets a blocked field or a rule rejects it, or nil otherwise.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) applyParameterPolicy(ctx context.Context) (*extprocv3.ProcessingResponse, error) {
+	rp := u.parent
+	raw := u.requestBodyRaw
+	for _, path := range u.blockedFields {
+		if !gjson.GetBytes(raw, path).Exists() {
+			continue
+		}
+		u.recordParameterPolicyRule(ctx, parameterpolicy.RuleBlockedFields, path, parameterpolicy.OutcomeReject)
+		u.logger.Info("rejecting request by the parameter policy", slog.String("backend", u.backendName),
+			slog.String("rule", parameterpolicy.RuleBlockedFields), slog.String("field", path))
+		u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
+		return createUserFacingErrorResponse(400, "BadRequest",
+			jsonEscaped(fmt.Sprintf("request field %q is blocked by the parameter policy", path))), nil
+	}
+
+	params := rp.eh.GenerationParameters(u.requestBody)
+	if len(params) == 0 {
+		return nil, nil
+	}
+
+	changed := false
+	for _, rule := range u.parameterRules {
+		for i := range params {
+			p := &params[i]
+			if p.Name != rule.Parameter {
+				continue
+			}
+			value, outcome := rule.Apply(p.Value)
+			if outcome == parameterpolicy.OutcomeNone {
+				continue
+			}
+			u.recordParameterPolicyRule(ctx, rule.Name, string(rule.Parameter), outcome)
+			if outcome == parameterpolicy.OutcomeReject {
+				u.logger.Info("rejecting request by the parameter policy", slog.String("backend", u.backendName),
+					slog.String("rule", rule.Name), slog.String("parameter", p.Path), slog.Any("value", p.Value))
+				u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
+				return createUserFacingErrorResponse(400, "BadRequest",
+					jsonEscaped(fmt.Sprintf("request parameter %q is not allowed by the parameter policy rule %q", p.Path, rule.Name))), nil
+			}
+			u.logger.Debug("applied parameter policy rule", slog.String("backend", u.backendName),
+				slog.String("rule", rule.Name), slog.String("outcome", string(outcome)), slog.String("parameter", p.Path),
+				slog.Any("from", p.Value), slog.Any("to", value))
+
+			var err error
+			if value == nil {
+				raw, err = sjson.DeleteBytes(raw, p.Path)
+			} else {
+				raw, err = sjson.SetBytes(raw, p.Path, value)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply the parameter policy rule %q: %w", rule.Name, err)
+			}
+			p.Value = value
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, nil
+	}
+	if res, err := u.fitThinkingBudget(ctx, params, &raw); res != nil || err != nil {
+		return res, err
+	}
+
+	_, parsed, _, _, err := rp.eh.ParseBody(raw, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the request with the parameter policy applied: %w", err)
+	}
+	u.requestBodyRaw, u.requestBody = raw, parsed
+	u.requestBodyMasked = true
+	return nil, nil
+}
+
+// fitThinkingBudget lowers the thinking budget of the request changed by the rules to one below the maximum number of
+// the output tokens when it is not below it, since the providers reject such requests. This returns the immediate
+// response rejecting the request if the budget would then be below the minimum accepted by the providers.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) fitThinkingBudget(ctx context.Context, params []endpointspec.GenerationParameter, raw *[]byte) (*extprocv3.ProcessingResponse, error) {
+	var budget *endpointspec.GenerationParameter
+	limit := math.Inf(1)
+	for i := range params {
+		p := &params[i]
+		v, ok := p.Value.(float64)
+		if !ok {
+			continue
+		}
+		switch p.Name {
+		case parameterpolicy.ParameterThinkingBudgetTokens:
+			budget = p
+		case parameterpolicy.ParameterMaxTokens:
+			limit = min(limit, v)
+		}
+	}
+	if budget == nil || budget.Value.(float64) < limit {
+		return nil, nil
+	}
+
+	lowered := limit - 1
+	if lowered < parameterpolicy.MinThinkingBudgetTokens {
+		u.recordParameterPolicyRule(ctx, parameterpolicy.RuleThinkingBudget, string(budget.Name), parameterpolicy.OutcomeReject)
+		u.logger.Info("rejecting request by the parameter policy", slog.String("backend", u.backendName),
+			slog.String("rule", parameterpolicy.RuleThinkingBudget), slog.String("parameter", budget.Path),
+			slog.Any("value", budget.Value), slog.Float64("max_tokens", limit))
+		u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
+		return createUserFacingErrorResponse(400, "BadRequest", jsonEscaped(fmt.Sprintf(
+			"request parameter %q must be at least %d and lower than the maximum number of the output tokens %d",
+			budget.Path, parameterpolicy.MinThinkingBudgetTokens, int64(limit)))), nil
+	}
+	u.recordParameterPolicyRule(ctx, parameterpolicy.RuleThinkingBudget, string(budget.Name), parameterpolicy.OutcomeClamp)
+	u.logger.Debug("applied parameter policy rule", slog.String("backend", u.backendName),
+		slog.String("rule", parameterpolicy.RuleThinkingBudget), slog.String("outcome", string(parameterpolicy.OutcomeClamp)),
+		slog.String("parameter", budget.Path), slog.Any("from", budget.Value), slog.Any("to", lowered))
+	var err error
+	if *raw, err = sjson.SetBytes(*raw, budget.Path, lowered); err != nil {
+		return nil, fmt.Errorf("failed to lower the thinking budget: %w", err)
+	}
+	budget.Value = lowered
+	return nil, nil
+}
+
+// recordParameterPolicyRule records the change made to the request by the rule, or its rejection.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) recordParameterPolicyRule(ctx context.Context, rule, parameter string, outcome parameterpolicy.Outcome) {
+	if m, ok := u.metrics.(metrics.ParameterPolicyMetrics); ok {
+		m.RecordParameterPolicyRule(ctx, rule, parameter, string(outcome), u.requestHeaders)
+	}
+}
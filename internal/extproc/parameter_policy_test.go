@@ -0,0 +1,215 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"testing"
+
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	anthropicschema "github.com/envoyproxy/ai-gateway/internal/apischema/anthropic"
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/parameterpolicy"
+)
+
+// mockParameterPolicyMetrics implements [metrics.ParameterPolicyMetrics] for testing.
+type mockParameterPolicyMetrics struct {
+	mockMetrics
+	rules []string
+}
+
+// RecordParameterPolicyRule implements [metrics.ParameterPolicyMetrics].
+func (m *mockParameterPolicyMetrics) RecordParameterPolicyRule(_ context.Context, rule, parameter, action string, _ map[string]string) {
+	m.rules = append(m.rules, rule+"/"+parameter+"/"+action)
+}
+
+func newParameterRules(t *testing.T, rules ...filterapi.ParameterPolicyRule) []*parameterpolicy.Rule {
+	var ret []*parameterpolicy.Rule
+	for _, r := range rules {
+		rule, err := parameterpolicy.NewRule(r.Name, parameterpolicy.Parameter(r.Parameter), parameterpolicy.Action(r.Action), r.Default, r.Min, r.Max)
+		require.NoError(t, err)
+		ret = append(ret, rule)
+	}
+	return ret
+}
+
+func Test_chatCompletionProcessorUpstreamFilter_ParameterPolicy(t *testing.T) {
+	newFilters := func(t *testing.T, requestBody string, blockedFields []string, rules ...filterapi.ParameterPolicyRule) (*chatCompletionProcessorUpstreamFilter, *mockParameterPolicyMetrics) {
+		var parsed openai.ChatCompletionRequest
+		require.NoError(t, json.Unmarshal([]byte(requestBody), &parsed))
+		headers := map[string]string{":path": "/v1/chat/completions", ":method": "POST", "content-type": "application/json"}
+		r := &chatCompletionProcessorRouterFilter{
+			eh:                     endpointspec.ChatCompletionsEndpointSpec{},
+			config:                 &filterapi.RuntimeConfig{},
+			logger:                 slog.Default(),
+			requestHeaders:         headers,
+			originalRequestBodyRaw: []byte(requestBody),
+			originalRequestBody:    &parsed,
+			originalModel:          "gpt-4o",
+		}
+		m := &mockParameterPolicyMetrics{}
+		u := &chatCompletionProcessorUpstreamFilter{requestHeaders: maps.Clone(headers), metrics: m, logger: slog.Default()}
+		require.NoError(t, u.SetBackend(t.Context(), &filterapi.RuntimeBackend{
+			Backend: &filterapi.Backend{
+				Name:            "openai",
+				Schema:          filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+				ParameterPolicy: &filterapi.ParameterPolicy{Rules: rules, BlockedFields: blockedFields},
+			},
+			ParameterRules: newParameterRules(t, rules...),
+		}, "test-route", r))
+		return u, m
+	}
+
+	t.Run("clamp and default", func(t *testing.T) {
+		u, m := newFilters(t, `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}],"max_tokens":100000,"reasoning_effort":"high"}`, nil,
+			filterapi.ParameterPolicyRule{Name: "cap-max-tokens", Parameter: "MaxTokens", Action: "Clamp", Max: "4096"},
+			filterapi.ParameterPolicyRule{Name: "cap-effort", Parameter: "ReasoningEffort", Action: "Clamp", Max: "medium"},
+			filterapi.ParameterPolicyRule{Name: "default-temperature", Parameter: "Temperature", Action: "Clamp", Default: "0.2"},
+		)
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		body := resp.GetRequestHeaders().Response.BodyMutation.GetBody()
+		require.Equal(t, int64(4096), gjson.GetBytes(body, "max_tokens").Int())
+		require.Equal(t, "medium", gjson.GetBytes(body, "reasoning_effort").String())
+		require.Equal(t, 0.2, gjson.GetBytes(body, "temperature").Float())
+		require.Equal(t, []string{
+			"cap-max-tokens/MaxTokens/clamp", "cap-effort/ReasoningEffort/clamp", "default-temperature/Temperature/default",
+		}, m.rules)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		u, m := newFilters(t, `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}],"top_p":0.1}`, nil,
+			filterapi.ParameterPolicyRule{Name: "no-top-p", Parameter: "TopP", Action: "Remove"})
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		body := resp.GetRequestHeaders().Response.BodyMutation.GetBody()
+		require.False(t, gjson.GetBytes(body, "top_p").Exists())
+		require.Equal(t, []string{"no-top-p/TopP/remove"}, m.rules)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		u, m := newFilters(t, `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}],"n":4}`, nil,
+			filterapi.ParameterPolicyRule{Name: "single-choice", Parameter: "N", Action: "Reject", Max: "1"})
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		ir := resp.GetImmediateResponse()
+		require.NotNil(t, ir)
+		require.Equal(t, typev3.StatusCode_BadRequest, ir.Status.Code)
+		require.Contains(t, string(ir.Body), `request parameter \"n\" is not allowed by the parameter policy rule \"single-choice\"`)
+		require.Equal(t, []string{"single-choice/N/reject"}, m.rules)
+		m.RequireRequestFailure(t)
+	})
+
+	t.Run("blocked field", func(t *testing.T) {
+		u, m := newFilters(t, `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}],"logit_bias":{"50256":-100}}`,
+			[]string{"metadata.user_id", "logit_bias"},
+			filterapi.ParameterPolicyRule{Name: "no-top-p", Parameter: "TopP", Action: "Remove"})
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		ir := resp.GetImmediateResponse()
+		require.NotNil(t, ir)
+		require.Equal(t, typev3.StatusCode_BadRequest, ir.Status.Code)
+		require.Contains(t, string(ir.Body), `request field \"logit_bias\" is blocked by the parameter policy`)
+		require.Equal(t, []string{"blocked-fields/logit_bias/reject"}, m.rules)
+		m.RequireRequestFailure(t)
+	})
+
+	t.Run("thinking budget lowered below max tokens", func(t *testing.T) {
+		u, m := newFilters(t, `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}],"max_tokens":64000,`+
+			`"thinking":{"type":"enabled","budget_tokens":32000}}`, nil,
+			filterapi.ParameterPolicyRule{Name: "cap-max-tokens", Parameter: "MaxTokens", Action: "Clamp", Max: "8192"})
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		body := resp.GetRequestHeaders().Response.BodyMutation.GetBody()
+		require.Equal(t, int64(8192), gjson.GetBytes(body, "max_tokens").Int())
+		require.Equal(t, int64(8191), gjson.GetBytes(body, "thinking.budget_tokens").Int())
+		require.Equal(t, []string{"cap-max-tokens/MaxTokens/clamp", "thinking-budget/ThinkingBudgetTokens/clamp"}, m.rules)
+	})
+
+	t.Run("thinking budget below minimum", func(t *testing.T) {
+		u, m := newFilters(t, `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}],"max_tokens":64000,`+
+			`"thinking":{"type":"enabled","budget_tokens":2048}}`, nil,
+			filterapi.ParameterPolicyRule{Name: "cap-max-tokens", Parameter: "MaxTokens", Action: "Clamp", Max: "1024"})
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		ir := resp.GetImmediateResponse()
+		require.NotNil(t, ir)
+		require.Equal(t, typev3.StatusCode_BadRequest, ir.Status.Code)
+		require.Contains(t, string(ir.Body), `request parameter \"thinking.budget_tokens\" must be at least 1024`)
+		require.Equal(t, []string{"cap-max-tokens/MaxTokens/clamp", "thinking-budget/ThinkingBudgetTokens/reject"}, m.rules)
+		m.RequireRequestFailure(t)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		u, m := newFilters(t, `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}],"max_completion_tokens":100}`, nil,
+			filterapi.ParameterPolicyRule{Name: "cap-max-tokens", Parameter: "MaxTokens", Action: "Clamp", Default: "1024", Max: "4096"})
+		_, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		require.False(t, u.requestBodyMasked)
+		require.Empty(t, m.rules)
+	})
+}
+
+func Test_messagesProcessorUpstreamFilter_ParameterPolicy(t *testing.T) {
+	const requestBody = `{"model":"claude-sonnet","max_tokens":64000,"messages":[{"role":"user","content":"Hi"}],` +
+		`"thinking":{"type":"enabled","budget_tokens":32000}}`
+	var parsed anthropicschema.MessagesRequest
+	require.NoError(t, json.Unmarshal([]byte(requestBody), &parsed))
+	headers := map[string]string{":path": "/v1/messages", ":method": "POST", "content-type": "application/json"}
+	r := &messagesProcessorRouterFilter{
+		eh:                     endpointspec.MessagesEndpointSpec{},
+		config:                 &filterapi.RuntimeConfig{},
+		logger:                 slog.Default(),
+		requestHeaders:         headers,
+		originalRequestBodyRaw: []byte(requestBody),
+		originalRequestBody:    &parsed,
+		originalModel:          "claude-sonnet",
+	}
+	for _, tc := range []struct {
+		name      string
+		rules     []filterapi.ParameterPolicyRule
+		expBudget int64
+	}{
+		{
+			name: "clamp",
+			rules: []filterapi.ParameterPolicyRule{
+				{Name: "cap-max-tokens", Parameter: "MaxTokens", Action: "Clamp", Max: "8192"},
+				{Name: "cap-thinking", Parameter: "ThinkingBudgetTokens", Action: "Clamp", Max: "4096"},
+			},
+			expBudget: 4096,
+		},
+		{
+			name:      "thinking budget lowered below max tokens",
+			rules:     []filterapi.ParameterPolicyRule{{Name: "cap-max-tokens", Parameter: "MaxTokens", Action: "Clamp", Max: "8192"}},
+			expBudget: 8191,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			u := &messagesProcessorUpstreamFilter{requestHeaders: maps.Clone(headers), metrics: &mockMetrics{}, logger: slog.Default()}
+			require.NoError(t, u.SetBackend(t.Context(), &filterapi.RuntimeBackend{
+				Backend: &filterapi.Backend{
+					Name:            "anthropic",
+					Schema:          filterapi.VersionedAPISchema{Name: filterapi.APISchemaAnthropic},
+					ParameterPolicy: &filterapi.ParameterPolicy{Rules: tc.rules},
+				},
+				ParameterRules: newParameterRules(t, tc.rules...),
+			}, "test-route", r))
+			resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+			require.NoError(t, err)
+			body := resp.GetRequestHeaders().Response.BodyMutation.GetBody()
+			require.Equal(t, int64(8192), gjson.GetBytes(body, "max_tokens").Int())
+			require.Equal(t, tc.expBudget, gjson.GetBytes(body, "thinking.budget_tokens").Int())
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+	"github.com/envoyproxy/ai-gateway/internal/tracing/tracingapi"
+)
+
+// applyPIIGuardrail detects the personally identifiable information in the text of the request, and masks or
+// tokenizes it in the request body sent to the backend. This returns the immediate response rejecting the request if
+// the action is Block and some information is detected, or nil otherwise.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) applyPIIGuardrail(ctx context.Context) (*extprocv3.ProcessingResponse, error) {
+	rp := u.parent
+	action := u.piiGuardrail.Action
+	var tokens *guardrail.PIITokens
+	if action == filterapi.PIIGuardrailActionTokenize {
+		tokens = guardrail.NewPIITokens()
+	}
+	detections := make(map[string]int)
+	replace := func(label, value string) string {
+		detections[strings.ToLower(label)]++
+		if tokens != nil {
+			return tokens.Token(label, value)
+		}
+		return guardrail.PIIPlaceholder(label)
+	}
+
+	raw := rp.originalRequestBodyRaw
+	for _, t := range requestTexts(rp.eh, raw) {
+		text := gjson.GetBytes(raw, t.path).String()
+		masked := u.piiDetector.Replace(text, replace)
+		if masked == text {
+			continue
+		}
+		var err error
+		if raw, err = sjson.SetBytes(raw, t.path, masked); err != nil {
+			return nil, fmt.Errorf("failed to mask the personally identifiable information: %w", err)
+		}
+	}
+	if len(detections) == 0 {
+		return nil, nil
+	}
+
+	actionLabel := strings.ToLower(string(action))
+	if m, ok := u.metrics.(metrics.GuardrailMetrics); ok {
+		for entity, count := range detections {
+			m.RecordPIIDetections(ctx, entity, actionLabel, count, u.requestHeaders)
+		}
+	}
+	if recorder, ok := rp.span.(tracingapi.GuardrailRecorder); ok {
+		recorder.RecordPIIDetections(actionLabel, detections)
+	}
+	entities := slices.Sorted(maps.Keys(detections))
+	if action == filterapi.PIIGuardrailActionBlock {
+		u.logger.Info("rejecting request with personally identifiable information", slog.String("backend", u.backendName),
+			slog.Any("entities", entities))
+		// The rejected request is audited with the information masked.
+		u.requestBodyRaw = raw
+		u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
+		return createUserFacingErrorResponse(400, "BadRequest",
+			"request contains personally identifiable information: "+strings.Join(entities, ", ")), nil
+	}
+
+	_, parsed, _, _, err := rp.eh.ParseBody(raw, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the masked request: %w", err)
+	}
+	u.requestBodyRaw, u.requestBody = raw, parsed
+	u.requestBodyMasked = true
+	if tokens != nil {
+		u.piiTokens = tokens
+		if rp.stream {
+			u.piiRestorer = &piiRestorer{tokens: tokens, format: streamFailoverFormatOf(rp.eh)}
+		}
+		// The semantic mode of the response cache would serve the response restored with the values of a request to
+		// the similar requests with the same tokens, so only the identical requests share the cached responses.
+		u.semanticCache = nil
+	}
+	return nil, nil
+}
+
+// restorePII restores the tokenized information in the response body returned to the client.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) restorePII(body []byte, endOfStream bool) []byte {
+	if u.piiRestorer != nil {
+		return u.piiRestorer.process(body, endOfStream)
+	}
+	return u.piiTokens.RestoreJSON(body)
+}
+
+// piiRestorer restores the tokenized information in the streaming response. The text deltas are restored as they
+// are returned, while the beginning of a token split across the deltas is held back until the rest of it arrives.
+type piiRestorer struct {
+	tokens *guardrail.PIITokens
+	format streamFailoverFormat
+	// pending is the incomplete event at the end of the response so far.
+	pending []byte
+	// held are the text deltas held back by the key of the text they belong to, e.g., the index of the choice.
+	held map[string]*piiHeldDelta
+	// heldKeys are the keys of held in the order in which they are first held back.
+	heldKeys []string
+}
+
+// piiHeldDelta is the text held back from a text delta of the streaming response.
+type piiHeldDelta struct {
+	// event is the last delta of the text, whose copy returns the held text.
+	event sseEvent
+	// path is the path of the text in the data of the event.
+	path string
+	text string
+}
+
+// process restores the chunk of the streaming response, and returns the complete events of the response so far.
+func (r *piiRestorer) process(chunk []byte, endOfStream bool) []byte {
+	events, rest := splitSSEEvents(append(r.pending, chunk...))
+	r.pending = rest
+	var out []byte
+	for _, raw := range events {
+		deltas := textDeltas(r.format, parseSSEEvent(raw))
+		if len(deltas) == 0 {
+			out = append(out, r.flush()...)
+			out = append(out, r.tokens.RestoreJSON(raw)...)
+			continue
+		}
+		for _, d := range deltas {
+			out = append(out, r.restoreDelta(d)...)
+		}
+	}
+	if endOfStream {
+		out = append(out, r.flush()...)
+		out = append(out, r.tokens.RestoreJSON(r.pending)...)
+		r.pending = nil
+	}
+	return out
+}
+
+// restoreDelta returns the text delta with the tokens restored, holding back the beginning of a token at its end.
+func (r *piiRestorer) restoreDelta(d textDelta) []byte {
+	text := gjson.GetBytes(d.event.data, d.path).String()
+	if h, ok := r.held[d.key]; ok {
+		text = h.text + text
+		delete(r.held, d.key)
+		r.heldKeys = slices.DeleteFunc(r.heldKeys, func(k string) bool { return k == d.key })
+	}
+	n := 0
+	if !d.final {
+		n = r.tokens.PartialSuffix(text)
+	}
+	if n > 0 {
+		if r.held == nil {
+			r.held = make(map[string]*piiHeldDelta)
+		}
+		r.held[d.key] = &piiHeldDelta{event: d.event, path: d.path, text: text[len(text)-n:]}
+		r.heldKeys = append(r.heldKeys, d.key)
+	}
+	data, err := sjson.SetBytes(d.event.data, d.path, r.tokens.Restore(text[:len(text)-n]))
+	if err != nil {
+		return d.event.bytes()
+	}
+	return sseEvent{name: d.event.name, data: data}.bytes()
+}
+
+// flush returns the text held back as the copies of the last delta of each text, which precede the next event that is
+// not a text delta or the end of the stream since the text does not continue any token then.
+func (r *piiRestorer) flush() []byte {
+	var out []byte
+	for _, key := range r.heldKeys {
+		h := r.held[key]
+		data, err := sjson.SetBytes(h.event.data, h.path, h.text)
+		if err != nil {
+			continue
+		}
+		out = append(out, sseEvent{name: h.event.name, data: data}.bytes()...)
+	}
+	r.held, r.heldKeys = nil, nil
+	return out
+}
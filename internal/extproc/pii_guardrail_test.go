@@ -0,0 +1,193 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+// mockGuardrailMetrics implements [metrics.GuardrailMetrics] for testing.
+type mockGuardrailMetrics struct {
+	mockMetrics
+	piiDetections             map[string]int
+	externalChecks            []string
+	promptInjectionDetections []string
+	outputMatches             []string
+}
+
+// RecordPIIDetections implements [metrics.GuardrailMetrics].
+func (m *mockGuardrailMetrics) RecordPIIDetections(_ context.Context, entity, action string, count int, _ map[string]string) {
+	if m.piiDetections == nil {
+		m.piiDetections = make(map[string]int)
+	}
+	m.piiDetections[entity+"/"+action] += count
+}
+
+// RecordExternalGuardrailCheck implements [metrics.GuardrailMetrics].
+func (m *mockGuardrailMetrics) RecordExternalGuardrailCheck(_ context.Context, phase, decision string, _ map[string]string) {
+	m.externalChecks = append(m.externalChecks, phase+"/"+decision)
+}
+
+// RecordPromptInjectionDetection implements [metrics.GuardrailMetrics].
+func (m *mockGuardrailMetrics) RecordPromptInjectionDetection(_ context.Context, rule, action string, _ map[string]string) {
+	m.promptInjectionDetections = append(m.promptInjectionDetections, rule+"/"+action)
+}
+
+// RecordOutputGuardrailMatch implements [metrics.GuardrailMetrics].
+func (m *mockGuardrailMetrics) RecordOutputGuardrailMatch(_ context.Context, rule, action string, _ map[string]string) {
+	m.outputMatches = append(m.outputMatches, rule+"/"+action)
+}
+
+func Test_chatCompletionProcessorUpstreamFilter_PIIGuardrail(t *testing.T) {
+	const requestBody = `{"model":"gpt-4o","messages":[` +
+		`{"role":"system","content":"You are a helpful assistant."},` +
+		`{"role":"user","content":[{"type":"text","text":"Email jane@example.com or call (415) 555-2671."}]}]}`
+	newFilters := func(t *testing.T, action filterapi.PIIGuardrailAction) (*chatCompletionProcessorUpstreamFilter, *mockGuardrailMetrics) {
+		var parsed openai.ChatCompletionRequest
+		require.NoError(t, json.Unmarshal([]byte(requestBody), &parsed))
+		headers := map[string]string{":path": "/v1/chat/completions", ":method": "POST", "content-type": "application/json"}
+		r := &chatCompletionProcessorRouterFilter{
+			eh:                     endpointspec.ChatCompletionsEndpointSpec{},
+			config:                 &filterapi.RuntimeConfig{},
+			logger:                 slog.Default(),
+			requestHeaders:         headers,
+			originalRequestBodyRaw: []byte(requestBody),
+			originalRequestBody:    &parsed,
+			originalModel:          "gpt-4o",
+		}
+		pii := &filterapi.PIIGuardrail{Action: action, Entities: []string{guardrail.PIIEntityEmail, guardrail.PIIEntityPhoneNumber}}
+		detector, err := guardrail.NewPIIDetector(pii.Entities, nil)
+		require.NoError(t, err)
+		m := &mockGuardrailMetrics{}
+		u := &chatCompletionProcessorUpstreamFilter{requestHeaders: maps.Clone(headers), metrics: m, logger: slog.Default()}
+		require.NoError(t, u.SetBackend(t.Context(), &filterapi.RuntimeBackend{
+			Backend: &filterapi.Backend{
+				Name:       "openai",
+				Schema:     filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+				Guardrails: &filterapi.Guardrails{PII: pii},
+			},
+			PIIDetector: detector,
+		}, "test-route", r))
+		return u, m
+	}
+
+	t.Run("mask", func(t *testing.T) {
+		u, m := newFilters(t, filterapi.PIIGuardrailActionMask)
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		body := resp.GetRequestHeaders().Response.BodyMutation.GetBody()
+		require.Equal(t, "Email [EMAIL] or call [PHONE_NUMBER].", gjson.GetBytes(body, "messages.1.content.0.text").String())
+		require.Equal(t, "You are a helpful assistant.", gjson.GetBytes(body, "messages.0.content").String())
+		require.Equal(t, map[string]int{"email/mask": 1, "phone_number/mask": 1}, m.piiDetections)
+		require.Nil(t, u.piiTokens)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		u, m := newFilters(t, filterapi.PIIGuardrailActionBlock)
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		ir := resp.GetImmediateResponse()
+		require.NotNil(t, ir)
+		require.Equal(t, typev3.StatusCode_BadRequest, ir.Status.Code)
+		require.Contains(t, string(ir.Body), "request contains personally identifiable information: email, phone_number")
+		require.Equal(t, map[string]int{"email/block": 1, "phone_number/block": 1}, m.piiDetections)
+		m.RequireRequestFailure(t)
+	})
+
+	t.Run("tokenize", func(t *testing.T) {
+		u, m := newFilters(t, filterapi.PIIGuardrailActionTokenize)
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		body := resp.GetRequestHeaders().Response.BodyMutation.GetBody()
+		require.Equal(t, "Email [EMAIL_1] or call [PHONE_NUMBER_1].", gjson.GetBytes(body, "messages.1.content.0.text").String())
+		require.Equal(t, map[string]int{"email/tokenize": 1, "phone_number/tokenize": 1}, m.piiDetections)
+
+		// The tokens are restored in the response.
+		_, err = u.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{Headers: []*corev3.HeaderValue{
+			{Key: ":status", Value: "200"}, {Key: "content-type", Value: "application/json"},
+		}})
+		require.NoError(t, err)
+		res, err := u.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{EndOfStream: true, Body: []byte(
+			`{"choices":[{"index":0,"message":{"role":"assistant","content":"I emailed [EMAIL_1] and called [PHONE_NUMBER_1]."}}],` +
+				`"usage":{"prompt_tokens":10,"completion_tokens":8,"total_tokens":18}}`,
+		)})
+		require.NoError(t, err)
+		out := res.GetResponseBody().Response.BodyMutation.GetBody()
+		require.Equal(t, "I emailed jane@example.com and called (415) 555-2671.", gjson.GetBytes(out, "choices.0.message.content").String())
+		m.RequireTokensRecorded(t, 10, 0, 0, 8)
+	})
+}
+
+func TestPIIRestorer(t *testing.T) {
+	newTokens := func() *guardrail.PIITokens {
+		tokens := guardrail.NewPIITokens()
+		tokens.Token("EMAIL", "jane@example.com")
+		return tokens
+	}
+
+	t.Run("chat completions", func(t *testing.T) {
+		r := &piiRestorer{tokens: newTokens(), format: streamFailoverChatCompletions}
+		// The token split across the deltas is held back until it completes.
+		out := r.process([]byte(`data: {"choices":[{"index":0,"delta":{"content":"Sent to [EM"}}]}`+"\n\n"+
+			`data: {"choices":[{"index":0,"delta":{"content":"AIL_1] and"}}]}`+"\n\n"+`data: {"choices":[{"ind`), false)
+		require.Equal(t, `data: {"choices":[{"index":0,"delta":{"content":"Sent to "}}]}`+"\n\n"+
+			`data: {"choices":[{"index":0,"delta":{"content":"jane@example.com and"}}]}`+"\n\n", string(out))
+		// The held text is returned before the event that is not a text delta.
+		out = r.process([]byte(`ex":0,"delta":{"content":" [EMA"}}]}`+"\n\n"+
+			`data: {"choices":[],"usage":{"total_tokens":3}}`+"\n\n"), false)
+		require.Equal(t, `data: {"choices":[{"index":0,"delta":{"content":" "}}]}`+"\n\n"+
+			`data: {"choices":[{"index":0,"delta":{"content":"[EMA"}}]}`+"\n\n"+
+			`data: {"choices":[],"usage":{"total_tokens":3}}`+"\n\n", string(out))
+		// Nothing is held back from the last delta of the choice.
+		out = r.process([]byte(`data: {"choices":[{"index":0,"delta":{"content":"[EM"},"finish_reason":"stop"}]}`+"\n\n"+
+			"data: [DONE]\n\n"), true)
+		require.Equal(t, `data: {"choices":[{"index":0,"delta":{"content":"[EM"},"finish_reason":"stop"}]}`+"\n\n"+
+			"data: [DONE]\n\n", string(out))
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		r := &piiRestorer{tokens: newTokens(), format: streamFailoverMessages}
+		out := r.process([]byte("event: content_block_delta\n"+
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"To [EMAIL"}}`+"\n\n"), false)
+		require.Equal(t, "event: content_block_delta\n"+
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"To "}}`+"\n\n", string(out))
+		// The held text is returned at the end of the stream.
+		out = r.process(nil, true)
+		require.Equal(t, "event: content_block_delta\n"+
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"[EMAIL"}}`+"\n\n", string(out))
+	})
+
+	t.Run("responses", func(t *testing.T) {
+		r := &piiRestorer{tokens: newTokens()}
+		out := r.process([]byte("event: response.output_text.delta\n"+
+			`data: {"type":"response.output_text.delta","output_index":0,"content_index":0,"delta":"To [EMAIL_"}`+"\n\n"+
+			"event: response.output_text.delta\n"+
+			`data: {"type":"response.output_text.delta","output_index":0,"content_index":0,"delta":"1]."}`+"\n\n"+
+			"event: response.output_text.done\n"+
+			`data: {"type":"response.output_text.done","output_index":0,"content_index":0,"text":"To [EMAIL_1]."}`+"\n\n"), true)
+		require.Equal(t, "event: response.output_text.delta\n"+
+			`data: {"type":"response.output_text.delta","output_index":0,"content_index":0,"delta":"To "}`+"\n\n"+
+			"event: response.output_text.delta\n"+
+			`data: {"type":"response.output_text.delta","output_index":0,"content_index":0,"delta":"jane@example.com."}`+"\n\n"+
+			"event: response.output_text.done\n"+
+			`data: {"type":"response.output_text.done","output_index":0,"content_index":0,"text":"To jane@example.com."}`+"\n\n", string(out))
+	})
+}
@@ -39,6 +39,32 @@ type Processor interface {
 	SetBackend(ctx context.Context, backend *filterapi.RuntimeBackend, routeName string, routerProcessor Processor) error
 }
 
+// upstreamResponseProcessor is optionally implemented by an upstream filter level processor to process the response
+// of each upstream attempt before Envoy decides whether to retry the request. The upstream filter only receives the
+// responses when the route rule has a fallback policy, and the final response is still processed at the router filter
+// level via [Processor.ProcessResponseHeaders] and [Processor.ProcessResponseBody].
+type upstreamResponseProcessor interface {
+	// ProcessUpstreamResponseHeaders processes the response headers message of an upstream attempt.
+	ProcessUpstreamResponseHeaders(context.Context, *corev3.HeaderMap) (*extprocv3.ProcessingResponse, error)
+	// ProcessUpstreamResponseBody processes the response body message of an upstream attempt.
+	ProcessUpstreamResponseBody(context.Context, *extprocv3.HttpBody) (*extprocv3.ProcessingResponse, error)
+}
+
+// endpointPrioritySetter is optionally implemented by an upstream filter level processor to receive the priority of
+// the endpoint selected by Envoy for the upstream attempt, which is passed in the metadata of the endpoint when the
+// fallback policy of the route rule fails over to the next priority. This is called before [Processor.SetBackend].
+type endpointPrioritySetter interface {
+	// SetEndpointPriority sets the priority of the endpoint of the upstream attempt.
+	SetEndpointPriority(priority uint32)
+}
+
+// responseTrailersProcessor is optionally implemented by a processor receiving the response trailers, i.e., the
+// processor of the stream failover filter whose response trailers are sent in the FULL_DUPLEX_STREAMED mode.
+type responseTrailersProcessor interface {
+	// ProcessResponseTrailers processes the response trailers message.
+	ProcessResponseTrailers(context.Context, *corev3.HeaderMap) (*extprocv3.ProcessingResponse, error)
+}
+
 // passThroughProcessor implements the Processor interface.
 type passThroughProcessor struct{}
 
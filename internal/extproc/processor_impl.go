@@ -9,12 +9,18 @@ import (
 	"bytes"
 	"cmp"
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math/rand/v2"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	extprocv3http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_proc/v3"
@@ -24,15 +30,25 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/types/known/structpb"
 
+	"github.com/envoyproxy/ai-gateway/internal/affinity"
 	"github.com/envoyproxy/ai-gateway/internal/backendauth"
+	"github.com/envoyproxy/ai-gateway/internal/backendselection"
 	"github.com/envoyproxy/ai-gateway/internal/bodymutator"
+	"github.com/envoyproxy/ai-gateway/internal/circuitbreaker"
 	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/errorclass"
 	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
 	"github.com/envoyproxy/ai-gateway/internal/headermutator"
 	"github.com/envoyproxy/ai-gateway/internal/internalapi"
 	"github.com/envoyproxy/ai-gateway/internal/json"
 	"github.com/envoyproxy/ai-gateway/internal/llmcostcel"
 	"github.com/envoyproxy/ai-gateway/internal/metrics"
+	"github.com/envoyproxy/ai-gateway/internal/parameterpolicy"
+	"github.com/envoyproxy/ai-gateway/internal/promptpolicy"
+	"github.com/envoyproxy/ai-gateway/internal/requestcel"
+	"github.com/envoyproxy/ai-gateway/internal/responsecache"
+	"github.com/envoyproxy/ai-gateway/internal/toolpolicy"
 	"github.com/envoyproxy/ai-gateway/internal/tracing/tracingapi"
 	"github.com/envoyproxy/ai-gateway/internal/translator"
 )
@@ -61,10 +77,11 @@ func NewFactory[ReqT any, RespT any, RespChunkT any, EndpointSpecT endpointspec.
 	tracer tracingapi.RequestTracer[ReqT, RespT, RespChunkT],
 	_ EndpointSpecT, // This is a type marker to bind EndpointSpecT without specifying ReqT, RespT, RespChunkT explicitly.
 ) ProcessorFactory {
+	sh := newShadower(f)
 	return func(config *filterapi.RuntimeConfig, requestHeaders map[string]string, logger *slog.Logger, isUpstreamFilter bool, enableRedaction bool) (Processor, error) {
 		logger = logger.With("isUpstreamFilter", fmt.Sprintf("%v", isUpstreamFilter))
 		if !isUpstreamFilter {
-			return newRouterProcessor[ReqT, RespT, RespChunkT, EndpointSpecT](config, requestHeaders, logger, tracer, enableRedaction), nil
+			return newRouterProcessor[ReqT, RespT, RespChunkT, EndpointSpecT](config, requestHeaders, logger, tracer, sh, enableRedaction), nil
 		}
 		return newUpstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT](requestHeaders, f.NewMetrics(), logger), nil
 	}
@@ -94,16 +111,52 @@ type (
 		originalRequestBodyRaw []byte
 		originalModel          internalapi.OriginalModel
 		forceBodyMutation      bool
+		// resolvedModel is the concrete model that the model alias of the request is resolved to, or empty if the
+		// original model is not an alias. This overrides the model of the request for the backends without
+		// ModelNameOverride.
+		resolvedModel string
+		// attributes are the attributes of the request used by the request body matches and the context window
+		// checks. This is computed lazily since it requires parsing the whole request body, and at most once since
+		// the upstream filters of the retried and the hedged requests may read it concurrently. See requestAttributes.
+		attributes     *requestcel.Attributes
+		attributesOnce sync.Once
 		// tracer is the tracer used for requests.
 		tracer tracingapi.RequestTracer[ReqT, RespT, RespChunkT]
 		// span is the tracing span for this request, created in ProcessRequestBody.
 		span tracingapi.Span[RespT, RespChunkT]
+		// mu guards the fields updated by the upstream filters, which run concurrently when the request is hedged.
+		mu sync.Mutex
+		// hedgeAttempts are the upstream filters of a hedged request in the order of the attempts. Since the attempts
+		// run concurrently, upstreamFilter is set to the one whose response is returned to the client once it is known.
+		hedgeAttempts []*upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]
+		// hedged is true once an attempt has been sent while an earlier one had not started responding.
+		hedged bool
+		// shadower sends the copies of the request to the shadow backends.
+		shadower *shadower
+		// shadowsStarted is true once the copies of the request have been sent to the shadow backends. This is guarded
+		// by mu.
+		shadowsStarted bool
+		// primaryResponse is the response body published for the shadows comparing their responses to it, or nil if
+		// none does. This is guarded by mu.
+		primaryResponse *primaryResponse
+		// responseStarted is true once the response headers are processed at the router filter, after which
+		// upstreamFilter is not updated anymore.
+		responseStarted bool
+		// responseBodySize is the size of the response body processed at the router filter so far, and
+		// responseBodyAwaited is closed once it reaches responseBodyAwaitedSize, which the stream failover filter
+		// waits for. These are guarded by mu. See continueStream.
+		responseBodySize        int
+		responseBodyAwaited     chan struct{}
+		responseBodyAwaitedSize int
 		// upstreamFilterCount is the number of upstream filters that have been processed.
 		// This is used to determine if the request is a retry request.
 		upstreamFilterCount int
-		stream              bool
-		debugLogEnabled     bool
-		enableRedaction     bool
+		// failedOverPriorities are the error classes of the attempts on which the fallback policy failed the request
+		// over to the next priority by the priorities of the attempts. This is guarded by mu.
+		failedOverPriorities map[uint32]errorclass.Class
+		stream               bool
+		debugLogEnabled      bool
+		enableRedaction      bool
 	}
 	// upstreamProcessor implements [Processor] for the upstream filter for the standard LLM endpoints.
 	//
@@ -111,6 +164,55 @@ type (
 	upstreamProcessor[ReqT, RespT, RespChunkT any, EndpointSpecT endpointspec.Spec[ReqT, RespT, RespChunkT]] struct {
 		parent *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]
 
+		// requestBody is the request body before the translation to the schema of the backend, which is the original
+		// request body of the parent with the guardrails of the backend applied.
+		requestBody    *ReqT
+		requestBodyRaw []byte
+		// requestBodyMasked is true if the guardrails or the prompt, tool or parameter policies have changed the request
+		// body.
+		requestBodyMasked bool
+		// promptPolicy and promptTemplates are the prompt policy of the route rule and the backend, or nil if not
+		// configured.
+		promptPolicy    *filterapi.PromptPolicy
+		promptTemplates []*promptpolicy.Template
+		// toolPolicy and toolMatcher are the tool policy of the route rule, or nil if not configured. toolMatcher is also
+		// nil if the policy has no allow or deny rules.
+		toolPolicy  *filterapi.ToolPolicy
+		toolMatcher *toolpolicy.Matcher
+		// toolCalls inspects the tool calls of the response, or nil if they are not inspected.
+		toolCalls *toolCallFilter
+		// parameterRules and blockedFields are the rules and the blocked fields of the parameter policy of the route
+		// rule and the backend, or nil if not configured.
+		parameterRules []*parameterpolicy.Rule
+		blockedFields  []string
+		// promptInjectionDetector and promptInjectionGuardrail are the prompt injection guardrail of the route rule, or
+		// nil if not configured.
+		promptInjectionDetector  *guardrail.PromptInjectionDetector
+		promptInjectionGuardrail *filterapi.PromptInjectionGuardrail
+		// piiDetector and piiGuardrail are the PII guardrail of the route rule, or nil if not configured.
+		piiDetector  *guardrail.PIIDetector
+		piiGuardrail *filterapi.PIIGuardrail
+		// piiTokens are the tokens of the information tokenized in the request, which are restored in the response, or
+		// nil if none.
+		piiTokens *guardrail.PIITokens
+		// piiRestorer restores the tokens in the streaming response, or nil if the response is not streamed.
+		piiRestorer *piiRestorer
+		// externalChecker and externalGuardrail are the external guardrail of the route rule, or nil if not configured.
+		externalChecker   guardrail.ExternalChecker
+		externalGuardrail *filterapi.ExternalGuardrail
+		// externalRequest is the normalized view of the request sent to the external guardrail, which is reused for
+		// the checks of the response, or nil if the response is not checked.
+		externalRequest *guardrail.ExternalCheckRequest
+		// externalStream holds back the streaming response between the checks of the external guardrail, or nil if the
+		// response is not streamed or not checked.
+		externalStream *externalGuardrailStream
+		// outputFilter and outputGuardrail are the output guardrail of the route rule, or nil if not configured or if
+		// the output of the endpoint is not filtered.
+		outputFilter    *guardrail.OutputFilter
+		outputGuardrail *filterapi.OutputGuardrail
+		// outputStream tracks the streaming response filtered by the output guardrail, or nil if not streamed.
+		outputStream *outputGuardrailStream
+
 		logger             *slog.Logger
 		requestHeaders     map[string]string
 		responseHeaders    map[string]string
@@ -119,11 +221,77 @@ type (
 		decompressedOffset int    // tracks decompressed bytes already returned
 		translator         translator.Translator[ReqT, tracingapi.Span[RespT, RespChunkT]]
 		modelNameOverride  internalapi.ModelNameOverride
-		headerMutator      *headermutator.HeaderMutator
-		bodyMutator        *bodymutator.BodyMutator
-		backendName        string
-		routeName          string
-		handler            filterapi.BackendAuthHandler
+		contextWindow      int32
+		// retriableErrorClasses are the error classes retried by the fallback policy of the route rule.
+		retriableErrorClasses []string
+		// failoverErrorClasses are the retriable error classes on which the fallback policy fails over to the next
+		// priority while retrying the other classes on the same priority.
+		failoverErrorClasses []string
+		// priority is the priority of the endpoint selected by Envoy for the attempt if hasPriority is true, which is
+		// only known when the fallback policy both retries on the same priority and fails over to the next one.
+		priority    uint32
+		hasPriority bool
+		// circuitBreaker is the circuit breaker of the backend, or nil if not configured.
+		circuitBreaker *circuitbreaker.Breaker
+		// circuitBreakerRecorded is true once the result of the response has been recorded on the circuit breaker.
+		circuitBreakerRecorded bool
+		// hedgeAttempt is the 1-based index of the upstream attempt if the request is hedged, or zero otherwise.
+		hedgeAttempt int
+		// responded is true once the response headers of the hedged attempt have been received. This is guarded by
+		// the mutex of the parent.
+		responded bool
+		// shadows are the shadow backends to which the copies of the request are sent.
+		shadows []filterapi.Shadow
+		// streamFailoverBackend is the backend on which the truncated streaming response is continued, if any.
+		streamFailoverBackend string
+		// failover tracks the streaming response returned to the client to continue it on the stream failover
+		// backend, or nil if the response is not continued.
+		failover *streamFailover
+		// continuation is the continuation of the truncated stream once the stream failover filter starts sending it,
+		// after which the chunks of the response body are the ones of the continuation. This is guarded by the mutex
+		// of the parent.
+		continuation *streamContinuation
+		// responseCache is the store of the response cache if the route rule has the response cache, or nil otherwise.
+		responseCache    responsecache.Store
+		responseCacheTTL time.Duration
+		// responseCacheKey is the key under which the response is stored once it completes, or empty if it is not
+		// stored, e.g., on a cache hit.
+		responseCacheKey string
+		// responseCacheBody is the response returned to the client so far, which is stored in the response cache.
+		responseCacheBody []byte
+		// responseCacheHit is true if the response is replayed from the response cache, in which case the response
+		// is not processed.
+		responseCacheHit bool
+		// semanticCache is the semantic mode of the response cache if enabled, in which case the embedding of the
+		// request is stored in responseCacheVectorStore together with its response.
+		semanticCache            *filterapi.SemanticCache
+		responseCacheVectorStore responsecache.VectorStore
+		// responseCacheScope and responseCacheEmbedding are the scope and the embedding of the request added to the
+		// vector store once the response is stored, or empty if it is not added.
+		responseCacheScope     string
+		responseCacheEmbedding []float32
+		// responseModel is the model reported by the backend in the response so far.
+		responseModel string
+		// auditResponseBody is the response returned to the client so far if the bodies are recorded in the audit
+		// record, and auditResponseTruncated is true if it exceeds the maximum body size of the audit records.
+		auditResponseBody      []byte
+		auditResponseTruncated bool
+		// auditStatus is the status code returned to the client if it is changed by the processing of the response.
+		auditStatus string
+		// audited is true once the audit record of the request is logged.
+		audited bool
+		// latency is the latency of the backend if it is a candidate of a backend selection, or nil otherwise.
+		// requestStart is the time when the request was sent to the backend, from which the latency of the
+		// non-streaming responses is measured, and latencyFailureRecorded is true once a failure is recorded.
+		latency                *backendselection.Latency
+		requestStart           time.Time
+		latencyFailureRecorded bool
+
+		headerMutator *headermutator.HeaderMutator
+		bodyMutator   *bodymutator.BodyMutator
+		backendName   string
+		routeName     string
+		handler       filterapi.BackendAuthHandler
 		// cost is the cost of the request that is accumulated during the processing of the response.
 		costs metrics.TokenUsage
 		// metrics tracking.
@@ -136,6 +304,7 @@ func newRouterProcessor[ReqT, RespT, RespChunkT any, EndpointSpecT endpointspec.
 	requestHeaders map[string]string,
 	logger *slog.Logger,
 	tracer tracingapi.RequestTracer[ReqT, RespT, RespChunkT],
+	shadower *shadower,
 	enableRedaction bool,
 ) *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT] {
 	debugLogEnabled := logger.Enabled(context.Background(), slog.LevelDebug)
@@ -144,6 +313,7 @@ func newRouterProcessor[ReqT, RespT, RespChunkT any, EndpointSpecT endpointspec.
 		requestHeaders:    requestHeaders,
 		logger:            logger,
 		tracer:            tracer,
+		shadower:          shadower,
 		forceBodyMutation: false,
 		debugLogEnabled:   debugLogEnabled,
 		enableRedaction:   enableRedaction,
@@ -163,20 +333,51 @@ func newUpstreamProcessor[ReqT, RespT, RespChunkT any, EndpointSpecT endpointspe
 
 // ProcessResponseHeaders implements [Processor.ProcessResponseHeaders].
 func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessResponseHeaders(ctx context.Context, headerMap *corev3.HeaderMap) (*extprocv3.ProcessingResponse, error) {
+	r.mu.Lock()
+	r.responseStarted = true
+	if len(r.hedgeAttempts) > 0 {
+		r.selectHedgeWinner(headerMap)
+	}
+	upstreamFilter := r.upstreamFilter
+	r.mu.Unlock()
 	// If the request failed to route and/or immediate response was returned before the upstream filter was set,
 	// r.upstreamFilter can be nil.
-	if r.upstreamFilter != nil { // See the comment on the "upstreamFilter" field.
-		return r.upstreamFilter.ProcessResponseHeaders(ctx, headerMap)
+	if upstreamFilter != nil { // See the comment on the "upstreamFilter" field.
+		return upstreamFilter.ProcessResponseHeaders(ctx, headerMap)
 	}
 	return r.passThroughProcessor.ProcessResponseHeaders(ctx, headerMap)
 }
 
+// selectHedgeWinner sets the upstream filter to the attempt of the hedged request whose response is returned to the
+// client, which is identified by the attempt index set on the response header by the upstream filter. The response
+// without the header, e.g., a local reply of Envoy, is processed by the last attempt as usual.
+//
+// This must be called with r.mu held.
+func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) selectHedgeWinner(headerMap *corev3.HeaderMap) {
+	attempt, err := strconv.Atoi(headersToMap(headerMap)[internalapi.HedgeAttemptHeader])
+	if err != nil || attempt < 1 || attempt > len(r.hedgeAttempts) {
+		return
+	}
+	winner := r.hedgeAttempts[attempt-1]
+	r.upstreamFilter = winner
+	if recorder, ok := r.span.(tracingapi.HedgeRecorder); ok && r.hedged {
+		recorder.RecordHedgeWinner(winner.backendName, attempt)
+	}
+}
+
 // ProcessResponseBody implements [Processor.ProcessResponseBody].
 func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessResponseBody(ctx context.Context, body *extprocv3.HttpBody) (resp *extprocv3.ProcessingResponse, err error) {
 	// If the request failed to route and/or immediate response was returned before the upstream filter was set,
 	// r.upstreamFilter can be nil.
 	if r.upstreamFilter != nil { // See the comment on the "upstreamFilter" field.
 		resp, err = r.upstreamFilter.ProcessResponseBody(ctx, body)
+		chunk, encoding := body.Body, r.upstreamFilter.responseEncoding
+		if mutated := resp.GetResponseBody().GetResponse().GetBodyMutation().GetBody(); mutated != nil {
+			chunk, encoding = mutated, ""
+		}
+		code, _ := strconv.Atoi(r.upstreamFilter.responseHeaders[":status"])
+		r.maybePublishPrimaryResponse(chunk, body.EndOfStream, encoding, err == nil && isGoodStatusCode(code))
+		r.responseBodyProcessed(len(body.Body))
 	} else {
 		resp, err = r.passThroughProcessor.ProcessResponseBody(ctx, body)
 	}
@@ -260,12 +461,29 @@ func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessRequest
 		r.originalRequestBodyRaw = rawBody.Body
 	}
 
-	r.requestHeaders[internalapi.ModelNameHeaderKeyDefault] = originalModel
+	r.originalModel = originalModel
+	r.stream = stream
+	// The alias is resolved before the routing, so the rules and the backends see the concrete model.
+	model := originalModel
+	if alias, ok := r.config.ModelAliases[originalModel]; ok {
+		r.resolvedModel = r.resolveModelAlias(alias)
+		model = r.resolvedModel
+	}
+	if window, ok := r.config.ModelContextWindows[model]; ok {
+		// None of the rules matching the model can serve the request, so reject it before routing.
+		if tokens := r.requestAttributes().EstimatedPromptTokens; tokens > uint64(window) { //nolint:gosec
+			err = contextLengthExceededError(tokens, window, model)
+			logger.Info("returning user-facing error for oversized request", slog.String("error", err.Error()))
+			return createUserFacingErrorResponse(400, "BadRequest", err.Error()), nil
+		}
+	}
+
+	r.requestHeaders[internalapi.ModelNameHeaderKeyDefault] = model
 
 	var additionalHeaders []*corev3.HeaderValueOption
 	additionalHeaders = append(additionalHeaders, &corev3.HeaderValueOption{
-		// Set the original model to the request header with the key `x-ai-eg-model`.
-		Header: &corev3.HeaderValue{Key: internalapi.ModelNameHeaderKeyDefault, RawValue: []byte(originalModel)},
+		// Set the original model, or the model its alias is resolved to, to the request header with the key `x-ai-eg-model`.
+		Header: &corev3.HeaderValue{Key: internalapi.ModelNameHeaderKeyDefault, RawValue: []byte(model)},
 	})
 	originalPath := r.requestHeaders[":path"]
 	r.requestHeaders[originalPathHeader] = originalPath
@@ -278,13 +496,34 @@ func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessRequest
 			Header: &corev3.HeaderValue{Key: internalapi.EnvoyOriginalPathHeader, RawValue: []byte(originalPath)},
 		})
 	}
-	r.originalModel = originalModel
+	var removedHeaders []string
+	if stream {
+		// The routes hedging the streaming requests match on the header, which is never taken from the client.
+		r.requestHeaders[internalapi.StreamHeader] = "true"
+		additionalHeaders = append(additionalHeaders, &corev3.HeaderValueOption{
+			Header: &corev3.HeaderValue{Key: internalapi.StreamHeader, RawValue: []byte("true")},
+		})
+	} else if _, ok := r.requestHeaders[internalapi.StreamHeader]; ok {
+		delete(r.requestHeaders, internalapi.StreamHeader)
+		removedHeaders = append(removedHeaders, internalapi.StreamHeader)
+	}
+	if len(r.config.RequestBodyMatches) > 0 {
+		additionalHeaders = r.evaluateRequestBodyMatches(logger, additionalHeaders)
+	}
+	if len(r.config.BackendSelections) > 0 {
+		additionalHeaders = r.selectBackends(logger, additionalHeaders)
+	}
+	if len(r.config.PromptAffinities) > 0 {
+		var removed []string
+		additionalHeaders, removed = r.setPromptAffinityKeys(additionalHeaders)
+		removedHeaders = append(removedHeaders, removed...)
+	}
 	r.originalRequestBody = body
-	r.stream = stream
 
 	// Tracing may need to inject headers, so create a header mutation here.
 	headerMutation := &extprocv3.HeaderMutation{
-		SetHeaders: additionalHeaders,
+		SetHeaders:    additionalHeaders,
+		RemoveHeaders: removedHeaders,
 	}
 	r.span = r.tracer.StartSpanAndInjectHeaders(
 		ctx,
@@ -306,7 +545,150 @@ func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessRequest
 	}, nil
 }
 
+// evaluateRequestBodyMatches evaluates the request body matches of the AIGatewayRoute rules, and sets each result
+// to its header so that the route can be selected based on it. The headers are always set, which overwrites any
+// value sent by the client. An expression that fails to evaluate does not match.
+func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) evaluateRequestBodyMatches(
+	logger *slog.Logger, headers []*corev3.HeaderValueOption,
+) []*corev3.HeaderValueOption {
+	attrs := r.requestAttributes()
+	for i := range r.config.RequestBodyMatches {
+		m := &r.config.RequestBodyMatches[i]
+		matched, err := requestcel.EvaluateProgram(m.CELProg, attrs)
+		if err != nil {
+			logger.Debug("request body match did not evaluate, treating as not matched",
+				slog.String("cel", m.CEL), slog.String("error", err.Error()))
+		}
+		value := strconv.FormatBool(matched)
+		r.requestHeaders[m.HeaderName] = value
+		headers = append(headers, &corev3.HeaderValueOption{
+			Header: &corev3.HeaderValue{Key: m.HeaderName, RawValue: []byte(value)},
+		})
+	}
+	return headers
+}
+
+// backendSelectionNone is the value of the backend selection header when no backend is preferred.
+const backendSelectionNone = "none"
+
+// selectBackends sets the index of the preferred backend ref of each backend selection to its header so that the
+// route preferring the backend can be selected. The headers are always set, which overwrites any value sent by the
+// client, to backendSelectionNone when no backend can be preferred so that the request is load balanced as usual.
+func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) selectBackends(
+	logger *slog.Logger, headers []*corev3.HeaderValueOption,
+) []*corev3.HeaderValueOption {
+	for i := range r.config.BackendSelections {
+		s := &r.config.BackendSelections[i]
+		candidates := make([]backendselection.Candidate, len(s.Candidates))
+		for j := range s.Candidates {
+			c := &s.Candidates[j]
+			candidates[j] = backendselection.Candidate{InputTokenPrice: c.InputTokenPrice, OutputTokenPrice: c.OutputTokenPrice}
+			if b, ok := r.config.Backends[c.Backend]; ok {
+				candidates[j].Latency = b.Latency
+			}
+		}
+		var promptTokens uint64
+		if s.CostWeight > 0 {
+			// Parsing the whole request body is only needed for the cost.
+			promptTokens = r.requestAttributes().EstimatedPromptTokens
+		}
+		value := backendSelectionNone
+		probe := rand.Float64() // #nosec G404
+		if j, ok := backendselection.Select(s.LatencyWeight, s.CostWeight, candidates, promptTokens, probe); ok {
+			value = strconv.Itoa(s.Candidates[j].RefIndex)
+			logger.Debug("selected preferred backend", slog.String("backend", s.Candidates[j].Backend))
+		}
+		r.requestHeaders[s.HeaderName] = value
+		headers = append(headers, &corev3.HeaderValueOption{
+			Header: &corev3.HeaderValue{Key: s.HeaderName, RawValue: []byte(value)},
+		})
+	}
+	return headers
+}
+
+// setPromptAffinityKeys sets the affinity key of the request computed for each prompt affinity to its header so
+// that the routes of the rules with the affinity hash on it. The headers sent by the client are overwritten, or
+// removed when the request has no prompt so that it is load balanced randomly.
+func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) setPromptAffinityKeys(
+	headers []*corev3.HeaderValueOption,
+) ([]*corev3.HeaderValueOption, []string) {
+	var removed []string
+	request := r.requestAttributes().Request
+	for i := range r.config.PromptAffinities {
+		a := &r.config.PromptAffinities[i]
+		key, ok := affinity.PromptKey(request, a.Messages)
+		if !ok {
+			delete(r.requestHeaders, a.HeaderName)
+			removed = append(removed, a.HeaderName)
+			continue
+		}
+		r.requestHeaders[a.HeaderName] = key
+		headers = append(headers, &corev3.HeaderValueOption{
+			Header: &corev3.HeaderValue{Key: a.HeaderName, RawValue: []byte(key)},
+		})
+	}
+	return headers, removed
+}
+
+// resolveModelAlias returns the concrete model that the given alias is resolved to for the request.
+//
+// The rollout and the weighted models are picked from two independent points in [0, 1), which are derived from the
+// value of the sticky header when present, so increasing the percentage of the rollout does not move the requests
+// between the other models.
+func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) resolveModelAlias(alias *filterapi.ModelAlias) string {
+	var rolloutPoint, modelPoint float64
+	if key := r.requestHeaders[alias.StickyHeader]; alias.StickyHeader != "" && key != "" {
+		rolloutPoint, modelPoint = modelAliasPoint(alias.Name, "rollout", key), modelAliasPoint(alias.Name, "model", key)
+	} else {
+		rolloutPoint, modelPoint = rand.Float64(), rand.Float64() // #nosec G404
+	}
+
+	var totalWeight int64
+	for _, m := range alias.Models {
+		totalWeight += int64(m.Weight)
+	}
+	if rollout := alias.Rollout; rollout != nil && (totalWeight == 0 || rolloutPoint*100 < float64(rollout.Percent)) {
+		return rollout.Model
+	}
+	point := modelPoint * float64(totalWeight)
+	for _, m := range alias.Models {
+		if point < float64(m.Weight) {
+			return m.Name
+		}
+		point -= float64(m.Weight)
+	}
+	if len(alias.Models) > 0 {
+		// Only reached due to the floating point rounding.
+		return alias.Models[len(alias.Models)-1].Name
+	}
+	return alias.Name
+}
+
+// modelAliasPoint returns the point in [0, 1) derived from the hash of the alias, the purpose and the key.
+func modelAliasPoint(alias, purpose, key string) float64 {
+	sum := sha256.Sum256([]byte(alias + "\x00" + purpose + "\x00" + key))
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+}
+
+// requestAttributes returns the attributes of the request, computing them on the first call. This is safe for
+// concurrent use once the request body has been processed by the router filter.
+func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) requestAttributes() *requestcel.Attributes {
+	r.attributesOnce.Do(func() {
+		r.attributes = requestcel.NewAttributes(cmp.Or(r.resolvedModel, r.originalModel), r.stream, r.originalRequestBodyRaw)
+	})
+	return r.attributes
+}
+
+// contextLengthExceededError returns the user-facing error of a request whose estimated prompt does not fit
+// in the given context window.
+func contextLengthExceededError(tokens uint64, window int32, model string) error {
+	return fmt.Errorf("%w: the request has about %d prompt tokens, which exceeds the context window of %d tokens of model %s",
+		internalapi.ErrContextLengthExceeded, tokens, window, model)
+}
+
 func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) onRetry() bool {
+	u.parent.mu.Lock()
+	defer u.parent.mu.Unlock()
 	return u.parent.upstreamFilterCount > 1
 }
 
@@ -321,22 +703,106 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessReque
 		if err != nil {
 			u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
 		}
+		if l := u.auditLogger(); l != nil && (err != nil || res.GetImmediateResponse() != nil) {
+			// The request is rejected or served from the response cache without calling the backend.
+			u.recordImmediateAudit(l, res.GetImmediateResponse(), err)
+		}
 	}()
 
+	u.requestBodyRaw, u.requestBody = u.parent.originalRequestBodyRaw, u.parent.originalRequestBody
 	// Start tracking metrics for this request.
 	u.metrics.StartRequest(u.requestHeaders)
+	u.requestStart = time.Now()
 	// Set the original model from the request body before any overrides
 	u.metrics.SetOriginalModel(u.parent.originalModel)
 	// Set the request model for metrics from the original model or override if applied.
 	reqModel := cmp.Or(u.requestHeaders[internalapi.ModelNameHeaderKeyDefault], u.parent.originalModel)
 	u.metrics.SetRequestModel(reqModel)
 
+	if u.contextWindow > 0 {
+		if tokens := u.parent.requestAttributes().EstimatedPromptTokens; tokens > uint64(u.contextWindow) { //nolint:gosec
+			userFacingErr := contextLengthExceededError(tokens, u.contextWindow, reqModel)
+			u.logger.Info("rejecting oversized request to backend", slog.String("backend", u.backendName),
+				slog.String("error", userFacingErr.Error()))
+			u.metrics.SetErrorType(string(errorclass.ContextLength))
+			u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
+			resp := createUserFacingErrorResponse(400, "BadRequest", userFacingErr.Error())
+			// Another backend of the rule may have a larger context window, so the route retries the request on the
+			// other backends. The error is returned to the client once none of them can serve the request.
+			setHeader(resp.GetImmediateResponse().Headers, internalapi.FallbackErrorClassHeader, string(errorclass.ContextLength))
+			return resp, nil
+		}
+	}
+
+	// The route retries the request twice on each priority when its fallback policy both retries on the same
+	// priority and fails over to the next one, so the attempts on a priority are rejected without calling the
+	// backend once an attempt on it failed with an error class to fail over on. The retry of the rejection is then
+	// sent to the next priority.
+	if failedOver := u.failedOverClass(); failedOver != "" {
+		u.logger.Debug("failing over to the next priority", slog.String("error_class", string(failedOver)),
+			slog.String("backend", u.backendName), slog.Uint64("priority", uint64(u.priority)))
+		// The rejection is not a response of the backend, so it is not recorded on the circuit breaker.
+		u.circuitBreaker = nil
+		u.metrics.SetErrorType(string(failedOver))
+		u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
+		resp := createUserFacingErrorResponse(503, "ServiceUnavailable",
+			fmt.Sprintf("backend is unavailable after a %s error", failedOver))
+		setHeader(resp.GetImmediateResponse().Headers, internalapi.FallbackErrorClassHeader, string(failedOver))
+		return resp, nil
+	}
+
+	if u.circuitBreaker != nil && !u.circuitBreaker.Allow() {
+		u.logger.Info("rejecting request to backend with open circuit breaker", slog.String("backend", u.backendName))
+		// The rejection is not a response of the backend, so it is not recorded on the circuit breaker.
+		u.circuitBreaker = nil
+		u.metrics.SetErrorType(circuitBreakerOpenErrorType)
+		u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
+		u.recordFailover(errorclass.Overloaded)
+		resp := createUserFacingErrorResponse(503, "ServiceUnavailable", "backend is temporarily unavailable")
+		// The route retries the request on the other backends of the rule regardless of its fallback policy. The
+		// error is returned to the client once none of them can serve the request.
+		setHeader(resp.GetImmediateResponse().Headers, internalapi.FallbackErrorClassHeader, string(errorclass.Overloaded))
+		return resp, nil
+	}
+
+	if u.promptInjectionDetector != nil {
+		if res = u.applyPromptInjectionGuardrail(ctx); res != nil {
+			return res, nil
+		}
+	}
+	if u.piiDetector != nil {
+		if res, err = u.applyPIIGuardrail(ctx); res != nil || err != nil {
+			return res, err
+		}
+	}
+	if u.externalChecker != nil {
+		if res, err = u.applyExternalGuardrail(ctx); res != nil || err != nil {
+			return res, err
+		}
+	}
+	if u.promptPolicy != nil {
+		if res, err = u.applyPromptPolicy(ctx); res != nil || err != nil {
+			return res, err
+		}
+	}
+	if u.toolPolicy != nil {
+		if res, err = u.applyToolPolicy(ctx); res != nil || err != nil {
+			return res, err
+		}
+	}
+	if len(u.parameterRules) > 0 || len(u.blockedFields) > 0 {
+		if res, err = u.applyParameterPolicy(ctx); res != nil || err != nil {
+			return res, err
+		}
+	}
+
 	// We force the body mutation in the following cases:
 	// * The request is a retry request because the body mutation might have happened the previous iteration.
 	// * The request is a streaming request, and the IncludeUsage option is set to false since we need to ensure that
 	//	the token usage is calculated correctly without being bypassed.
-	forceBodyMutation := u.onRetry() || u.parent.forceBodyMutation
-	newHeaders, newBody, err := u.translator.RequestBody(u.parent.originalRequestBodyRaw, u.parent.originalRequestBody, forceBodyMutation)
+	// * The guardrails or the prompt, tool or parameter policies have changed the request body.
+	forceBodyMutation := u.onRetry() || u.parent.forceBodyMutation || u.requestBodyMasked
+	newHeaders, newBody, err := u.translator.RequestBody(u.requestBodyRaw, u.requestBody, forceBodyMutation)
 	if err != nil {
 		if userFacingErr := internalapi.GetUserFacingError(err); userFacingErr != nil {
 			// return to user as 422 -  e.g., "invalid request body: tool_choice type not supported"
@@ -376,7 +842,7 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessReque
 
 	if wantBodyReplace {
 		// Apply body mutations from the route and also restore original body on retry.
-		bodyMutation = applyBodyMutation(u.bodyMutator, bodyMutation, u.parent.originalRequestBodyRaw, u.logger)
+		bodyMutation = applyBodyMutation(u.bodyMutator, bodyMutation, u.requestBodyRaw, u.logger)
 	}
 
 	// Ensure bodyMutation is not nil for subsequent processing
@@ -388,6 +854,21 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessReque
 		u.requestHeaders[h.Header.Key] = string(h.Header.RawValue)
 	}
 
+	if u.responseCache != nil {
+		body := u.requestBodyRaw
+		if wantBodyReplace {
+			body = bodyMutation.GetBody()
+		}
+		if u.piiTokens != nil {
+			// The responses are cached with the tokens restored, so they are only shared by the requests with the same
+			// values of the tokens.
+			body = u.piiTokens.RestoreJSON(body)
+		}
+		if resp := u.lookupResponseCache(ctx, body); resp != nil {
+			return resp, nil
+		}
+	}
+
 	if h := u.handler; h != nil {
 		var hdrs []internalapi.Header
 		hdrs, err = h.Do(ctx, u.requestHeaders, bodyMutation.GetBody())
@@ -406,6 +887,8 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessReque
 		}
 	}
 
+	u.maybeStartShadows(ctx)
+
 	if !wantBodyReplace {
 		// No body change -> no content-length restamp; emit CONTINUE so Envoy
 		// keeps whatever body the previous filter in the chain produced.
@@ -453,7 +936,17 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessRespo
 		}
 	}()
 
+	if u.responseCacheHit {
+		// The cached response is already in the schema of the client.
+		return &extprocv3.ProcessingResponse{Response: &extprocv3.ProcessingResponse_ResponseHeaders{
+			ResponseHeaders: &extprocv3.HeadersResponse{},
+		}}, nil
+	}
 	u.responseHeaders = headersToMap(headers)
+	if u.responseHeaders[":status"] != "200" {
+		// Only the successful responses are cached.
+		u.responseCacheKey = ""
+	}
 	if enc := u.responseHeaders["content-encoding"]; enc != "" {
 		u.responseEncoding = enc
 	}
@@ -465,11 +958,22 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessRespo
 		return nil, fmt.Errorf("failed to transform response headers: %w", err)
 	}
 	var mode *extprocv3http.ProcessingMode
+	u.failover = nil
 	if u.parent.stream && u.responseHeaders[":status"] == "200" {
 		// We only stream the response if the status code is 200 and the response is a stream.
 		mode = &extprocv3http.ProcessingMode{ResponseBodyMode: extprocv3http.ProcessingMode_STREAMED}
+		if format := streamFailoverFormatOf(u.parent.eh); format != 0 && u.streamFailoverBackend != "" {
+			u.failover = newStreamFailover(format)
+		}
 	}
 	headerMutation, _ := mutationsFromTranslationResult(newHeaders, nil)
+	for _, h := range []string{internalapi.FallbackErrorClassHeader, internalapi.HedgeAttemptHeader} {
+		if _, ok := u.responseHeaders[h]; ok {
+			// The header is set by the upstream filter for the route and the router filter, so it is not exposed to
+			// the client.
+			headerMutation.RemoveHeaders = append(headerMutation.RemoveHeaders, h)
+		}
+	}
 	return &extprocv3.ProcessingResponse{Response: &extprocv3.ProcessingResponse_ResponseHeaders{
 		ResponseHeaders: &extprocv3.HeadersResponse{
 			Response: &extprocv3.CommonResponse{HeaderMutation: headerMutation},
@@ -479,6 +983,18 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessRespo
 
 // ProcessResponseBody implements [Processor.ProcessResponseBody].
 func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessResponseBody(ctx context.Context, body *extprocv3.HttpBody) (res *extprocv3.ProcessingResponse, err error) {
+	defer func() {
+		// The record is logged only once, so the request served from the response cache is not audited again.
+		if l := u.auditLogger(); l != nil && (err != nil || body.EndOfStream) {
+			u.recordAudit(l, err)
+		}
+	}()
+	if u.responseCacheHit {
+		// The request has been recorded in the metrics on the cache hit.
+		return &extprocv3.ProcessingResponse{Response: &extprocv3.ProcessingResponse_ResponseBody{
+			ResponseBody: &extprocv3.BodyResponse{},
+		}}, nil
+	}
 	recordRequestCompletionErr := false
 	defer func() {
 		if err != nil || recordRequestCompletionErr {
@@ -490,6 +1006,25 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessRespo
 		}
 	}()
 
+	u.parent.mu.Lock()
+	continuation := u.continuation
+	u.parent.mu.Unlock()
+	if continuation != nil {
+		// The chunk is a part of the continuation sent by the stream failover filter, which is already stitched onto
+		// the response in the schema of the client.
+		if body.EndOfStream {
+			select {
+			case <-continuation.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			u.costs.Merge(continuation.costs)
+		}
+		res, recordRequestCompletionErr, err = u.processClientChunk(ctx, body.Body, body.EndOfStream, &extprocv3.HeaderMutation{},
+			&extprocv3.BodyMutation{Mutation: &extprocv3.BodyMutation_Body{Body: body.Body}}, false, u.responseModel)
+		return res, err
+	}
+
 	// Decompress the body if needed.
 	// For streaming responses with content-encoding, use stateful decompression
 	// that accumulates compressed bytes across chunks.
@@ -505,18 +1040,36 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessRespo
 
 	// Assume all responses have a valid status code header.
 	if code, _ := strconv.Atoi(u.responseHeaders[":status"]); !isGoodStatusCode(code) {
+		var decoded, newBody []byte
 		var newHeaders []internalapi.Header
-		var newBody []byte
-		newHeaders, newBody, err = u.translator.ResponseError(u.responseHeaders, decodingResult.reader)
+		decoded, err = io.ReadAll(decodingResult.reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response error: %w", err)
+		}
+		newHeaders, newBody, err = u.translator.ResponseError(u.responseHeaders, bytes.NewReader(decoded))
 		if err != nil {
 			return nil, fmt.Errorf("failed to transform response error: %w", err)
 		}
 		headerMutation, bodyMutation := mutationsFromTranslationResult(newHeaders, newBody)
+		if newBody == nil {
+			newBody = decoded
+		}
+		if u.auditLogger() != nil {
+			u.bufferAuditResponse(headerMutation, newBody)
+		}
+		class := errorclass.Classify(code, newBody)
+		u.recordCircuitBreakerResult(isOverloadErrorClass(class))
+		u.recordLatencyFailure(code, class)
+		errorType := string(class)
+		u.metrics.SetErrorType(errorType)
 		if u.parent.span != nil {
 			b := bodyMutation.GetBody()
 			if b == nil {
 				b = body.Body
 			}
+			if recorder, ok := u.parent.span.(tracingapi.ErrorTypeRecorder); ok {
+				recorder.RecordErrorType(errorType)
+			}
 			u.parent.span.EndSpanOnError(code, b)
 		}
 		// Mark so the deferred handler records failure.
@@ -533,16 +1086,115 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessRespo
 		}, nil
 	}
 
-	newHeaders, newBody, tokenUsage, responseModel, err := u.translator.ResponseBody(u.responseHeaders, decodingResult.reader, body.EndOfStream, u.parent.span)
+	reader := decodingResult.reader
+	var decoded bytes.Buffer
+	inspected := u.failover != nil || u.responseCacheKey != "" || u.piiTokens != nil || u.externalRequest != nil ||
+		u.toolCalls != nil || u.outputFilter != nil || u.recordsAuditBodies()
+	if inspected {
+		// The decoded body is what the client receives if the translator does not mutate it.
+		reader = io.TeeReader(reader, &decoded)
+	}
+	newHeaders, newBody, tokenUsage, responseModel, err := u.translator.ResponseBody(u.responseHeaders, reader, body.EndOfStream, u.parent.span)
 	if err != nil {
 		return nil, fmt.Errorf("failed to transform response: %w", err)
 	}
 	headerMutation, bodyMutation := mutationsFromTranslationResult(newHeaders, newBody)
 
+	// Translator reports the latest cumulative token usage which we use to override existing costs.
+	u.costs.Override(tokenUsage)
+
+	// chunk is the chunk sent to the client, which is rewritten by each of the stages below in turn.
+	var chunk []byte
+	if inspected {
+		chunk = clientBoundChunk(bodyMutation, reader, &decoded)
+	}
+
+	if f := u.failover; f != nil {
+		chunk = f.process(chunk)
+		if body.EndOfStream {
+			// The response is not continued by the stream failover filter, so it ends as it is.
+			chunk = append(chunk, f.finish()...)
+		}
+		bodyMutation = &extprocv3.BodyMutation{Mutation: &extprocv3.BodyMutation_Body{Body: chunk}}
+	}
+
+	res, recordRequestCompletionErr, err = u.processClientChunk(ctx, chunk, body.EndOfStream, headerMutation, bodyMutation,
+		decodingResult.isEncoded, responseModel)
+	return res, err
+}
+
+// processClientChunk processes the chunk of the response body sent to the client, which is either translated from
+// the response of the backend or a part of the continuation of the stream failover, through the stages inspecting
+// the output, and records the metrics. This also returns true if the request is to be recorded as failed.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) processClientChunk(ctx context.Context, chunk []byte, endOfStream bool,
+	headerMutation *extprocv3.HeaderMutation, bodyMutation *extprocv3.BodyMutation, isEncoded bool, responseModel string,
+) (resp *extprocv3.ProcessingResponse, recordRequestCompletionErr bool, err error) {
+	if u.toolCalls != nil {
+		// The tool calls are inspected before the external guardrail so that it only sees the ones returned to the client.
+		var statusCode int
+		var denied bool
+		chunk, statusCode, denied = u.checkToolResponse(chunk, endOfStream)
+		if statusCode != 0 {
+			setHeader(headerMutation, ":status", strconv.Itoa(statusCode))
+		}
+		if denied {
+			u.responseCacheKey = ""
+			recordRequestCompletionErr = statusCode != 0 || endOfStream
+		}
+		bodyMutation = &extprocv3.BodyMutation{Mutation: &extprocv3.BodyMutation_Body{Body: chunk}}
+	}
+
+	if u.outputFilter != nil && !recordRequestCompletionErr {
+		// The output is filtered before the external guardrail so that the service does not see the blocked output.
+		var blocked bool
+		if chunk, blocked = u.checkOutputGuardrail(ctx, chunk, endOfStream); blocked {
+			u.responseCacheKey = ""
+			// The blocked stream is recorded as failed once it ends.
+			recordRequestCompletionErr = !u.parent.stream || endOfStream
+		}
+		bodyMutation = &extprocv3.BodyMutation{Mutation: &extprocv3.BodyMutation_Body{Body: chunk}}
+	}
+
+	if u.externalRequest != nil && !recordRequestCompletionErr {
+		// The output is checked as it is generated by the backend, i.e., before the tokens of the PII guardrail are
+		// restored, while the continuation of the stream failover is checked as well.
+		var denied bool
+		if u.externalStream != nil {
+			chunk, denied = u.checkExternalGuardrailStream(ctx, chunk, endOfStream)
+			// The denied stream is recorded as failed once it ends.
+			recordRequestCompletionErr = denied && endOfStream
+		} else {
+			var statusCode int
+			if chunk, statusCode = u.checkExternalGuardrailResponse(ctx, chunk); statusCode != 0 {
+				setHeader(headerMutation, ":status", strconv.Itoa(statusCode))
+				denied, recordRequestCompletionErr = true, true
+			}
+		}
+		if denied {
+			u.responseCacheKey = ""
+		}
+		bodyMutation = &extprocv3.BodyMutation{Mutation: &extprocv3.BodyMutation_Body{Body: chunk}}
+	}
+
+	if u.piiTokens != nil {
+		// This follows the stream failover so that the continuation is requested with the tokens as well.
+		chunk = u.restorePII(chunk, endOfStream)
+		bodyMutation = &extprocv3.BodyMutation{Mutation: &extprocv3.BodyMutation_Body{Body: chunk}}
+	}
+
+	if u.responseCacheKey != "" {
+		u.bufferResponseCache(ctx, chunk, endOfStream)
+	}
+
+	u.responseModel = cmp.Or(responseModel, u.responseModel)
+	if u.auditLogger() != nil {
+		u.bufferAuditResponse(headerMutation, chunk)
+	}
+
 	// Remove content-encoding header if original body encoded but was mutated in the processor.
-	headerMutation = removeContentEncodingIfNeeded(headerMutation, bodyMutation, decodingResult.isEncoded)
+	headerMutation = removeContentEncodingIfNeeded(headerMutation, bodyMutation, isEncoded)
 
-	resp := &extprocv3.ProcessingResponse{
+	resp = &extprocv3.ProcessingResponse{
 		Response: &extprocv3.ProcessingResponse_ResponseBody{
 			ResponseBody: &extprocv3.BodyResponse{
 				Response: &extprocv3.CommonResponse{
@@ -553,9 +1205,6 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessRespo
 		},
 	}
 
-	// Translator reports the latest cumulative token usage which we use to override existing costs.
-	u.costs.Override(tokenUsage)
-
 	// Set the response model for metrics
 	u.metrics.SetResponseModel(responseModel)
 
@@ -564,19 +1213,33 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessRespo
 		// Token latency is only recorded for streaming responses, otherwise it doesn't make sense since
 		// these metrics are defined as a difference between the two output events.
 		out, _ := u.costs.OutputTokens()
-		u.metrics.RecordTokenLatency(ctx, out, body.EndOfStream, u.requestHeaders)
+		u.metrics.RecordTokenLatency(ctx, out, endOfStream, u.requestHeaders)
+		if u.circuitBreaker != nil {
+			// The time to first token is known once the first chunk has been recorded above.
+			objective := u.circuitBreaker.Config().TimeToFirstToken
+			ttft := time.Duration(u.metrics.GetTimeToFirstTokenMs() * float64(time.Millisecond))
+			u.recordCircuitBreakerResult(objective > 0 && ttft > objective)
+		}
 		// Emit usage once at end-of-stream using final totals.
-		if body.EndOfStream {
+		if endOfStream {
 			u.metrics.RecordTokenUsage(ctx, u.costs, u.requestHeaders)
+			if u.latency != nil {
+				u.latency.Record(u.metrics.GetTimeToFirstTokenMs(), u.metrics.GetInterTokenLatencyMs())
+			}
 		}
 	} else {
 		u.metrics.RecordTokenUsage(ctx, u.costs, u.requestHeaders)
+		u.recordCircuitBreakerResult(false)
+		if u.latency != nil && endOfStream {
+			out, _ := u.costs.OutputTokens()
+			u.latency.RecordResponse(float64(time.Since(u.requestStart))/float64(time.Millisecond), out)
+		}
 	}
 
-	if body.EndOfStream && (len(u.parent.config.GlobalRequestCosts) > 0 || len(u.parent.config.RequestCosts) > 0) {
+	if endOfStream && (len(u.parent.config.GlobalRequestCosts) > 0 || len(u.parent.config.RequestCosts) > 0) {
 		metadata, err := buildDynamicMetadata(u.parent.config.GlobalRequestCosts, u.parent.config.RequestCosts, &u.costs, u.requestHeaders, u.backendName, u.routeName, responseModel)
 		if err != nil {
-			return nil, fmt.Errorf("failed to build dynamic metadata: %w", err)
+			return nil, recordRequestCompletionErr, fmt.Errorf("failed to build dynamic metadata: %w", err)
 		}
 		if u.parent.stream {
 			// Adding token latency information to metadata.
@@ -585,12 +1248,138 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessRespo
 		resp.DynamicMetadata = metadata
 	}
 
-	if body.EndOfStream && u.parent.span != nil {
+	if endOfStream && u.parent.span != nil {
 		u.parent.span.EndSpan()
 	}
+	return resp, recordRequestCompletionErr, nil
+}
+
+// ProcessUpstreamResponseHeaders implements [upstreamResponseProcessor.ProcessUpstreamResponseHeaders].
+//
+// This is called for each upstream attempt when the route rule has a fallback policy or a hedge policy. The body of
+// an error response is buffered so that ProcessUpstreamResponseBody can classify it before the headers reach the
+// router, where Envoy decides whether to retry the request. The response of a hedged attempt is tagged with the
+// index of the attempt so that the router filter can tell which of the concurrent attempts won.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessUpstreamResponseHeaders(_ context.Context, headers *corev3.HeaderMap) (*extprocv3.ProcessingResponse, error) {
+	u.responseHeaders = headersToMap(headers)
+	u.responseEncoding = u.responseHeaders["content-encoding"]
+	var mode *extprocv3http.ProcessingMode
+	if code, _ := strconv.Atoi(u.responseHeaders[":status"]); !isGoodStatusCode(code) && len(u.retriableErrorClasses) > 0 {
+		mode = &extprocv3http.ProcessingMode{ResponseBodyMode: extprocv3http.ProcessingMode_BUFFERED}
+	}
+	resp := &extprocv3.HeadersResponse{}
+	if u.hedgeAttempt > 0 {
+		u.parent.mu.Lock()
+		u.responded = true
+		u.parent.mu.Unlock()
+		resp.Response = &extprocv3.CommonResponse{HeaderMutation: &extprocv3.HeaderMutation{
+			SetHeaders: []*corev3.HeaderValueOption{{
+				AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+				Header:       &corev3.HeaderValue{Key: internalapi.HedgeAttemptHeader, RawValue: []byte(strconv.Itoa(u.hedgeAttempt))},
+			}},
+		}}
+	}
+	return &extprocv3.ProcessingResponse{Response: &extprocv3.ProcessingResponse_ResponseHeaders{
+		ResponseHeaders: resp,
+	}, ModeOverride: mode}, nil
+}
+
+// ProcessUpstreamResponseBody implements [upstreamResponseProcessor.ProcessUpstreamResponseBody].
+//
+// This sets the fallback header to the class of the error response when the fallback policy retries it, so that the
+// retry policy of the route retries the request. The body is left as is, and it is translated at the router filter
+// level when this turns out to be the final response.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) ProcessUpstreamResponseBody(_ context.Context, body *extprocv3.HttpBody) (*extprocv3.ProcessingResponse, error) {
+	resp := &extprocv3.ProcessingResponse{Response: &extprocv3.ProcessingResponse_ResponseBody{
+		ResponseBody: &extprocv3.BodyResponse{},
+	}}
+	code, _ := strconv.Atoi(u.responseHeaders[":status"])
+	if isGoodStatusCode(code) {
+		return resp, nil
+	}
+	decodingResult, err := decodeContentIfNeeded(body.Body, u.responseEncoding)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := io.ReadAll(decodingResult.reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response error: %w", err)
+	}
+	// Classify the translated body so that the classification does not depend on the provider, and fall back to
+	// the original one when the translator keeps it as is or fails to translate it.
+	_, translated, err := u.translator.ResponseError(u.responseHeaders, bytes.NewReader(decoded))
+	if err != nil {
+		u.logger.Debug("failed to transform response error for classification", slog.String("error", err.Error()))
+	}
+	if err != nil || translated == nil {
+		translated = decoded
+	}
+	class := errorclass.Classify(code, translated)
+	u.recordCircuitBreakerResult(isOverloadErrorClass(class))
+	u.recordLatencyFailure(code, class)
+	if !slices.Contains(u.retriableErrorClasses, string(class)) {
+		return resp, nil
+	}
+	u.recordFailover(class)
+	u.logger.Debug("retrying the error response per the fallback policy",
+		slog.Int("status_code", code), slog.String("error_class", string(class)), slog.String("backend", u.backendName))
+	resp.GetResponseBody().Response = &extprocv3.CommonResponse{HeaderMutation: &extprocv3.HeaderMutation{
+		SetHeaders: []*corev3.HeaderValueOption{{
+			AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+			Header:       &corev3.HeaderValue{Key: internalapi.FallbackErrorClassHeader, RawValue: []byte(class)},
+		}},
+	}}
 	return resp, nil
 }
 
+// clientBoundChunk returns the chunk of the response body sent to the client, which is the one mutated by the
+// translator, or the decoded one teed from the reader if the translator does not mutate it.
+func clientBoundChunk(bodyMutation *extprocv3.BodyMutation, reader io.Reader, decoded *bytes.Buffer) []byte {
+	if bodyMutation != nil {
+		return bodyMutation.GetBody()
+	}
+	_, _ = io.Copy(io.Discard, reader)
+	return decoded.Bytes()
+}
+
+// recordCircuitBreakerResult records the result of the response of the backend on its circuit breaker, if any. This
+// records at most once per upstream attempt since the response may be seen both per attempt with a fallback policy
+// and as the final response.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) recordCircuitBreakerResult(failure bool) {
+	if u.circuitBreaker == nil || u.circuitBreakerRecorded {
+		return
+	}
+	u.circuitBreakerRecorded = true
+	if failure {
+		u.circuitBreaker.RecordFailure()
+		return
+	}
+	u.circuitBreaker.RecordSuccess()
+}
+
+// recordLatencyFailure records the error response of the backend on its latency of the backend selection, if any.
+// Only the failures of the backend count, not the errors caused by the request such as a too long prompt. This records
+// at most once per upstream attempt like recordCircuitBreakerResult.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) recordLatencyFailure(code int, class errorclass.Class) {
+	if u.latency == nil || u.latencyFailureRecorded {
+		return
+	}
+	if code < 500 && !isOverloadErrorClass(class) && class != errorclass.Auth {
+		return
+	}
+	u.latencyFailureRecorded = true
+	u.latency.RecordFailure()
+}
+
+// circuitBreakerOpenErrorType is the error.type of the requests rejected due to the open circuit breaker.
+const circuitBreakerOpenErrorType = "circuit_breaker_open"
+
+// isOverloadErrorClass returns true if the error class indicates that the backend is overloaded, which counts as a
+// failure of the circuit breaker.
+func isOverloadErrorClass(class errorclass.Class) bool {
+	return class == errorclass.RateLimit || class == errorclass.Overloaded
+}
+
 // decodeStreamingContent handles decompression for streaming responses with content-encoding.
 // It accumulates raw compressed bytes across chunks and re-decompresses from the beginning each time,
 // returning only the newly decompressed data. This is necessary because gzip streams are stateful
@@ -614,6 +1403,36 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) decodeStream
 	return contentDecodingResult{reader: bytes.NewReader(newData), isEncoded: true}, nil
 }
 
+// SetEndpointPriority implements [endpointPrioritySetter.SetEndpointPriority].
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) SetEndpointPriority(priority uint32) {
+	u.priority, u.hasPriority = priority, true
+}
+
+// failedOverClass returns the error class on which an attempt on the priority of this attempt failed the request
+// over to the next priority, or empty if none did or the priority is not known.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) failedOverClass() errorclass.Class {
+	if !u.hasPriority || len(u.failoverErrorClasses) == 0 {
+		return ""
+	}
+	u.parent.mu.Lock()
+	defer u.parent.mu.Unlock()
+	return u.parent.failedOverPriorities[u.priority]
+}
+
+// recordFailover records that the attempt failed the request over to the next priority if the fallback policy fails
+// over on the error class, so that the next attempts on its priority are rejected.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) recordFailover(class errorclass.Class) {
+	if !u.hasPriority || !slices.Contains(u.failoverErrorClasses, string(class)) {
+		return
+	}
+	u.parent.mu.Lock()
+	defer u.parent.mu.Unlock()
+	if u.parent.failedOverPriorities == nil {
+		u.parent.failedOverPriorities = make(map[uint32]errorclass.Class)
+	}
+	u.parent.failedOverPriorities[u.priority] = class
+}
+
 // SetBackend implements [Processor.SetBackend].
 func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) SetBackend(ctx context.Context, backend *filterapi.RuntimeBackend, routeName string, routeProcessor Processor) (err error) {
 	defer func() {
@@ -625,9 +1444,42 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) SetBackend(c
 	if !ok {
 		panic(fmt.Sprintf("BUG: expected routeProcessor to be of type *routerProcessor[%T], got %T", rp, routeProcessor))
 	}
+	rp.mu.Lock()
 	rp.upstreamFilterCount++
+	rp.mu.Unlock()
 	u.metrics.SetBackend(backend.Backend)
-	u.modelNameOverride = backend.Backend.ModelNameOverride
+	u.modelNameOverride = cmp.Or(backend.Backend.ModelNameOverride, rp.resolvedModel)
+	u.contextWindow = backend.Backend.ContextWindow
+	u.retriableErrorClasses = backend.Backend.RetriableErrorClasses
+	u.failoverErrorClasses = backend.Backend.FailoverErrorClasses
+	u.circuitBreaker = backend.CircuitBreaker
+	u.latency = backend.Latency
+	u.shadows = backend.Backend.Shadows
+	u.streamFailoverBackend = backend.Backend.StreamFailoverBackend
+	u.responseCache = backend.ResponseCache
+	if rc := backend.Backend.ResponseCache; rc != nil {
+		u.responseCacheTTL = rc.TTL
+		u.semanticCache = rc.Semantic
+		u.responseCacheVectorStore = backend.ResponseCacheVectorStore
+	}
+	u.promptPolicy, u.promptTemplates = backend.Backend.PromptPolicy, backend.PromptTemplates
+	u.toolPolicy, u.toolMatcher = backend.Backend.ToolPolicy, backend.ToolMatcher
+	u.parameterRules = backend.ParameterRules
+	if pp := backend.Backend.ParameterPolicy; pp != nil {
+		u.blockedFields = pp.BlockedFields
+	}
+	if g := backend.Backend.Guardrails; g != nil && g.PromptInjection != nil && backend.PromptInjectionDetector != nil {
+		u.promptInjectionGuardrail, u.promptInjectionDetector = g.PromptInjection, backend.PromptInjectionDetector
+	}
+	if g := backend.Backend.Guardrails; g != nil && g.PII != nil && backend.PIIDetector != nil {
+		u.piiGuardrail, u.piiDetector = g.PII, backend.PIIDetector
+	}
+	if g := backend.Backend.Guardrails; g != nil && g.External != nil && backend.ExternalChecker != nil {
+		u.externalGuardrail, u.externalChecker = g.External, backend.ExternalChecker
+	}
+	if g := backend.Backend.Guardrails; g != nil && g.Output != nil && backend.OutputFilter != nil && outputGuardrailSupported(rp.eh) {
+		u.outputGuardrail, u.outputFilter = g.Output, backend.OutputFilter
+	}
 	u.backendName = backend.Backend.Name
 	u.routeName = routeName
 	u.handler = backend.Handler
@@ -646,11 +1498,22 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) SetBackend(c
 	if setter, ok := u.translator.(translator.ContentTypeSetter); ok {
 		setter.SetContentType(rp.requestHeaders["content-type"])
 	}
-	rp.upstreamFilter = u // Only assign after translator is confirmed valid
+	// Only assign after translator is confirmed valid.
+	rp.mu.Lock()
+	if !rp.responseStarted {
+		rp.upstreamFilter = u
+	}
+	if backend.Backend.Hedging {
+		u.addHedgeAttempt()
+	}
+	rp.mu.Unlock()
 
 	if headerSetter, ok := u.translator.(translator.RequestHeadersSetter); ok {
 		headerSetter.SetRequestHeaders(u.requestHeaders)
 	}
+	if cachingSetter, ok := u.translator.(translator.PromptCachingSetter); ok {
+		cachingSetter.SetPromptCaching(backend.Backend.PromptCaching)
+	}
 
 	switch redactor := u.translator.(type) {
 	case translator.ResponseRedactor:
@@ -666,6 +1529,26 @@ func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) SetBackend(c
 	return
 }
 
+// addHedgeAttempt adds this upstream filter to the attempts of the hedged request, and records it on the span if it is
+// a hedged request, i.e., an earlier attempt has not started responding yet, rather than a retry.
+//
+// This must be called with the mutex of the parent held.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) addHedgeAttempt() {
+	rp := u.parent
+	hedged := slices.ContainsFunc(rp.hedgeAttempts, func(a *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) bool {
+		return !a.responded
+	})
+	rp.hedgeAttempts = append(rp.hedgeAttempts, u)
+	u.hedgeAttempt = len(rp.hedgeAttempts)
+	if !hedged {
+		return
+	}
+	rp.hedged = true
+	if recorder, ok := rp.span.(tracingapi.HedgeRecorder); ok {
+		recorder.RecordHedgedRequest(u.backendName, u.hedgeAttempt)
+	}
+}
+
 func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) mergeWithTokenLatencyMetadata(metadata *structpb.Struct) {
 	timeToFirstTokenMs := u.metrics.GetTimeToFirstTokenMs()
 	interTokenLatencyMs := u.metrics.GetInterTokenLatencyMs()
@@ -815,14 +1698,11 @@ func evalRuntimeRequestCost(rc *filterapi.RuntimeRequestCost, costs *metrics.Tok
 	return evalCost(rc.Type, rc.CELProg, costs, requestHeaders, backendName, routeName)
 }
 
-// buildDynamicMetadata creates metadata for rate limiting and cost tracking.
-// This function is called by the upstream filter only at the end of the stream (body.EndOfStream=true)
-// when the response is successfully completed. It is not called for failed requests or partial responses.
-// The metadata includes token usage costs and model information for downstream processing.
+// evalRequestCosts evaluates the request costs of the request, and returns them by their metadata keys.
 // Two-tier precedence: for each metadataKey, check route-scoped requestCosts first (matching RouteName == routeName).
-// If found, use it. Otherwise, fall back to globalRequestCosts. If neither exists, the key is not emitted.
-func buildDynamicMetadata(globalRequestCosts []filterapi.RuntimeGlobalRequestCost, requestCosts []filterapi.RuntimeRequestCost, costs *metrics.TokenUsage, requestHeaders map[string]string, backendName, routeName, responseModel string) (*structpb.Struct, error) {
-	metadata := make(map[string]*structpb.Value, len(requestCosts)+len(globalRequestCosts)+3)
+// If found, use it. Otherwise, fall back to globalRequestCosts. If neither exists, the key is not returned.
+func evalRequestCosts(globalRequestCosts []filterapi.RuntimeGlobalRequestCost, requestCosts []filterapi.RuntimeRequestCost, costs *metrics.TokenUsage, requestHeaders map[string]string, backendName, routeName string) (map[string]uint64, error) {
+	ret := make(map[string]uint64, len(requestCosts)+len(globalRequestCosts))
 
 	// Track which metadata keys have been populated by route-scoped costs.
 	populatedKeys := make(map[string]struct{})
@@ -851,7 +1731,7 @@ func buildDynamicMetadata(globalRequestCosts []filterapi.RuntimeGlobalRequestCos
 		if err != nil {
 			return nil, err
 		}
-		metadata[rc.MetadataKey] = &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: float64(cost)}}
+		ret[rc.MetadataKey] = cost
 		populatedKeys[rc.MetadataKey] = struct{}{}
 	}
 
@@ -865,9 +1745,27 @@ func buildDynamicMetadata(globalRequestCosts []filterapi.RuntimeGlobalRequestCos
 		if err != nil {
 			return nil, err
 		}
-		metadata[rc.MetadataKey] = &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: float64(cost)}}
+		ret[rc.MetadataKey] = cost
+	}
+	return ret, nil
+}
+
+// buildDynamicMetadata creates metadata for rate limiting and cost tracking.
+// This function is called by the upstream filter only at the end of the stream (body.EndOfStream=true)
+// when the response is successfully completed. It is not called for failed requests or partial responses.
+// The metadata includes token usage costs and model information for downstream processing.
+// See evalRequestCosts for the precedence of the costs.
+func buildDynamicMetadata(globalRequestCosts []filterapi.RuntimeGlobalRequestCost, requestCosts []filterapi.RuntimeRequestCost, costs *metrics.TokenUsage, requestHeaders map[string]string, backendName, routeName, responseModel string) (*structpb.Struct, error) {
+	requestCostValues, err := evalRequestCosts(globalRequestCosts, requestCosts, costs, requestHeaders, backendName, routeName)
+	if err != nil {
+		return nil, err
+	}
+	metadata := make(map[string]*structpb.Value, len(requestCostValues)+3)
+	for key, cost := range requestCostValues {
+		metadata[key] = &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: float64(cost)}}
 	}
 
+	actualModel := requestHeaders[internalapi.ModelNameHeaderKeyDefault]
 	metadata["model_name_override"] = &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: actualModel}}
 
 	if backendName != "" {
@@ -13,7 +13,11 @@ import (
 	"io"
 	"log/slog"
 	"mime/multipart"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	extprocv3http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_proc/v3"
@@ -24,10 +28,13 @@ import (
 	"go.opentelemetry.io/otel/propagation"
 	"google.golang.org/protobuf/types/known/structpb"
 
+	"github.com/envoyproxy/ai-gateway/internal/affinity"
 	anthropicschema "github.com/envoyproxy/ai-gateway/internal/apischema/anthropic"
 	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
 	"github.com/envoyproxy/ai-gateway/internal/backendauth"
+	"github.com/envoyproxy/ai-gateway/internal/backendselection"
 	"github.com/envoyproxy/ai-gateway/internal/bodymutator"
+	"github.com/envoyproxy/ai-gateway/internal/circuitbreaker"
 	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
 	"github.com/envoyproxy/ai-gateway/internal/filterapi"
 	"github.com/envoyproxy/ai-gateway/internal/headermutator"
@@ -35,6 +42,7 @@ import (
 	"github.com/envoyproxy/ai-gateway/internal/json"
 	"github.com/envoyproxy/ai-gateway/internal/llmcostcel"
 	"github.com/envoyproxy/ai-gateway/internal/metrics"
+	"github.com/envoyproxy/ai-gateway/internal/requestcel"
 	"github.com/envoyproxy/ai-gateway/internal/testing/testotel"
 	"github.com/envoyproxy/ai-gateway/internal/tracing/tracingapi"
 )
@@ -96,6 +104,28 @@ func (m *mockTracer) StartSpanAndInjectHeaders(_ context.Context, _ map[string]s
 	return nil
 }
 
+func Test_chatCompletionProcessorRouterFilter_requestAttributes(t *testing.T) {
+	p := &chatCompletionProcessorRouterFilter{
+		originalModel:          "some-model",
+		originalRequestBodyRaw: []byte(`{"model":"some-model","messages":[{"role":"user","content":"hello"}]}`),
+	}
+	// The upstream filters of the retried and the hedged requests read the attributes concurrently.
+	var wg sync.WaitGroup
+	attrs := make([]*requestcel.Attributes, 8)
+	for i := range attrs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attrs[i] = p.requestAttributes()
+		}()
+	}
+	wg.Wait()
+	for _, a := range attrs {
+		require.Same(t, attrs[0], a)
+	}
+	require.Equal(t, "some-model", attrs[0].Model)
+}
+
 func Test_chatCompletionProcessorRouterFilter_ProcessRequestBody(t *testing.T) {
 	t.Run("body parser error", func(t *testing.T) {
 		p := &chatCompletionProcessorRouterFilter{
@@ -142,6 +172,218 @@ func Test_chatCompletionProcessorRouterFilter_ProcessRequestBody(t *testing.T) {
 		require.Equal(t, "/foo", string(setHeaders[2].Header.RawValue))
 	})
 
+	t.Run("stream header", func(t *testing.T) {
+		newProcessor := func(headers map[string]string) *chatCompletionProcessorRouterFilter {
+			return &chatCompletionProcessorRouterFilter{
+				config:         &filterapi.RuntimeConfig{},
+				requestHeaders: headers,
+				logger:         slog.Default(),
+				tracer:         tracingapi.NoopTracer[openai.ChatCompletionRequest, openai.ChatCompletionResponse, openai.ChatCompletionResponseChunk]{},
+			}
+		}
+		headers := map[string]string{":path": "/foo"}
+		resp, err := newProcessor(headers).ProcessRequestBody(t.Context(), &extprocv3.HttpBody{Body: bodyFromModel(t, "some-model", true, nil)})
+		require.NoError(t, err)
+		got := map[string]string{}
+		for _, h := range resp.GetRequestBody().GetResponse().GetHeaderMutation().SetHeaders {
+			got[h.Header.Key] = string(h.Header.RawValue)
+		}
+		require.Equal(t, "true", got[internalapi.StreamHeader])
+		require.Equal(t, "true", headers[internalapi.StreamHeader])
+
+		// The header sent by the client on a non-streaming request is removed.
+		headers = map[string]string{":path": "/foo", internalapi.StreamHeader: "true"}
+		resp, err = newProcessor(headers).ProcessRequestBody(t.Context(), &extprocv3.HttpBody{Body: bodyFromModel(t, "some-model", false, nil)})
+		require.NoError(t, err)
+		require.Equal(t, []string{internalapi.StreamHeader}, resp.GetRequestBody().GetResponse().GetHeaderMutation().RemoveHeaders)
+		require.NotContains(t, headers, internalapi.StreamHeader)
+	})
+
+	t.Run("request body matches", func(t *testing.T) {
+		newMatch := func(headerName, expr string) filterapi.RuntimeRequestBodyMatch {
+			prog, err := requestcel.NewProgram(expr)
+			require.NoError(t, err)
+			return filterapi.RuntimeRequestBodyMatch{
+				RequestBodyMatch: &filterapi.RequestBodyMatch{HeaderName: headerName, CEL: expr},
+				CELProg:          prog,
+			}
+		}
+		headers := map[string]string{":path": "/foo", "x-ai-eg-body-match-stream": "true"}
+		p := &chatCompletionProcessorRouterFilter{
+			config: &filterapi.RuntimeConfig{RequestBodyMatches: []filterapi.RuntimeRequestBodyMatch{
+				newMatch("x-ai-eg-body-match-stream", "stream"),
+				newMatch("x-ai-eg-body-match-model", "model == 'some-model' && !has_tools"),
+				newMatch("x-ai-eg-body-match-effort", "request.reasoning_effort == 'high'"),
+			}},
+			requestHeaders: headers,
+			logger:         slog.Default(),
+			tracer:         tracingapi.NoopTracer[openai.ChatCompletionRequest, openai.ChatCompletionResponse, openai.ChatCompletionResponseChunk]{},
+		}
+		resp, err := p.ProcessRequestBody(t.Context(), &extprocv3.HttpBody{Body: bodyFromModel(t, "some-model", false, nil)})
+		require.NoError(t, err)
+		setHeaders := resp.GetRequestBody().GetResponse().GetHeaderMutation().SetHeaders
+		got := map[string]string{}
+		for _, h := range setHeaders {
+			got[h.Header.Key] = string(h.Header.RawValue)
+		}
+		// The header sent by the client is overwritten, and the failed evaluation does not match.
+		require.Equal(t, "false", got["x-ai-eg-body-match-stream"])
+		require.Equal(t, "true", got["x-ai-eg-body-match-model"])
+		require.Equal(t, "false", got["x-ai-eg-body-match-effort"])
+		require.Equal(t, "false", headers["x-ai-eg-body-match-stream"])
+	})
+
+	t.Run("context window exceeded", func(t *testing.T) {
+		body := []byte(`{"model":"some-model","messages":[{"role":"user","content":"` + strings.Repeat("a", 400) + `"}]}`)
+		for _, tc := range []struct {
+			window int32
+			expErr bool
+		}{{window: 100, expErr: true}, {window: 110}} {
+			p := &chatCompletionProcessorRouterFilter{
+				config:         &filterapi.RuntimeConfig{ModelContextWindows: map[string]int32{"some-model": tc.window}},
+				requestHeaders: map[string]string{":path": "/foo"},
+				logger:         slog.Default(),
+				tracer:         tracingapi.NoopTracer[openai.ChatCompletionRequest, openai.ChatCompletionResponse, openai.ChatCompletionResponseChunk]{},
+			}
+			resp, err := p.ProcessRequestBody(t.Context(), &extprocv3.HttpBody{Body: body})
+			require.NoError(t, err)
+			if !tc.expErr {
+				require.NotNil(t, resp.GetRequestBody())
+				continue
+			}
+			immediateResp := resp.GetImmediateResponse()
+			require.NotNil(t, immediateResp)
+			require.Equal(t, typev3.StatusCode(400), immediateResp.Status.Code)
+			require.JSONEq(t, `{"type":"error","error":{"type":"BadRequest","code":"400","message":"context length exceeded: the request has about 101 prompt tokens, which exceeds the context window of 100 tokens of model some-model"}}`,
+				string(immediateResp.Body))
+		}
+	})
+
+	t.Run("backend selection", func(t *testing.T) {
+		fast, slow := &backendselection.Latency{}, &backendselection.Latency{}
+		fast.Record(100, 10)
+		slow.Record(1000, 50)
+		headers := map[string]string{":path": "/foo", "x-ai-eg-backend-selection-none": "1"}
+		p := &chatCompletionProcessorRouterFilter{
+			config: &filterapi.RuntimeConfig{
+				Backends: map[string]*filterapi.RuntimeBackend{
+					"slow": {Backend: &filterapi.Backend{Name: "slow"}, Latency: slow},
+					"fast": {Backend: &filterapi.Backend{Name: "fast"}, Latency: fast},
+				},
+				BackendSelections: []filterapi.BackendSelection{
+					{
+						HeaderName:    "x-ai-eg-backend-selection-fastest",
+						LatencyWeight: 1,
+						Candidates:    []filterapi.BackendSelectionCandidate{{Backend: "slow", RefIndex: 0}, {Backend: "fast", RefIndex: 2}},
+					},
+					{
+						HeaderName: "x-ai-eg-backend-selection-cheapest",
+						CostWeight: 1,
+						Candidates: []filterapi.BackendSelectionCandidate{
+							{Backend: "slow", RefIndex: 0, InputTokenPrice: 0.15, OutputTokenPrice: 0.6},
+							{Backend: "fast", RefIndex: 1, InputTokenPrice: 3, OutputTokenPrice: 15},
+						},
+					},
+					{
+						// None of the candidates has an observed latency.
+						HeaderName:    "x-ai-eg-backend-selection-none",
+						LatencyWeight: 1,
+						Candidates:    []filterapi.BackendSelectionCandidate{{Backend: "unknown", RefIndex: 0}},
+					},
+				},
+			},
+			requestHeaders: headers,
+			logger:         slog.Default(),
+			tracer:         tracingapi.NoopTracer[openai.ChatCompletionRequest, openai.ChatCompletionResponse, openai.ChatCompletionResponseChunk]{},
+		}
+		resp, err := p.ProcessRequestBody(t.Context(), &extprocv3.HttpBody{Body: bodyFromModel(t, "some-model", false, nil)})
+		require.NoError(t, err)
+		got := map[string]string{}
+		for _, h := range resp.GetRequestBody().GetResponse().GetHeaderMutation().SetHeaders {
+			got[h.Header.Key] = string(h.Header.RawValue)
+		}
+		require.Equal(t, "2", got["x-ai-eg-backend-selection-fastest"])
+		require.Equal(t, "0", got["x-ai-eg-backend-selection-cheapest"])
+		// The header sent by the client is overwritten.
+		require.Equal(t, "none", got["x-ai-eg-backend-selection-none"])
+		require.Equal(t, "none", headers["x-ai-eg-backend-selection-none"])
+	})
+
+	t.Run("prompt affinity", func(t *testing.T) {
+		newProcessor := func(headers map[string]string) *chatCompletionProcessorRouterFilter {
+			return &chatCompletionProcessorRouterFilter{
+				config: &filterapi.RuntimeConfig{
+					PromptAffinities: []filterapi.PromptAffinity{
+						{HeaderName: "x-ai-eg-affinity-prompt-0", Messages: 0},
+						{HeaderName: "x-ai-eg-affinity-prompt-1", Messages: 1},
+					},
+				},
+				requestHeaders: headers,
+				logger:         slog.Default(),
+				tracer:         tracingapi.NoopTracer[openai.ChatCompletionRequest, openai.ChatCompletionResponse, openai.ChatCompletionResponseChunk]{},
+			}
+		}
+
+		headers := map[string]string{":path": "/foo", "x-ai-eg-affinity-prompt-1": "client"}
+		body := []byte(`{"model":"some-model","messages":[{"role":"system","content":"be nice"},{"role":"user","content":"hi"}]}`)
+		resp, err := newProcessor(headers).ProcessRequestBody(t.Context(), &extprocv3.HttpBody{Body: body})
+		require.NoError(t, err)
+		got := map[string]string{}
+		for _, h := range resp.GetRequestBody().GetResponse().GetHeaderMutation().SetHeaders {
+			got[h.Header.Key] = string(h.Header.RawValue)
+		}
+		var request map[string]any
+		require.NoError(t, json.Unmarshal(body, &request))
+		for _, messages := range []int{0, 1} {
+			expected, ok := affinity.PromptKey(request, messages)
+			require.True(t, ok)
+			name := internalapi.PromptAffinityHeaderName(messages)
+			require.Equal(t, expected, got[name])
+			// The header sent by the client is overwritten.
+			require.Equal(t, expected, headers[name])
+		}
+
+		// The headers are removed when the request has no prompt.
+		headers = map[string]string{":path": "/foo", "x-ai-eg-affinity-prompt-1": "client"}
+		resp, err = newProcessor(headers).ProcessRequestBody(t.Context(), &extprocv3.HttpBody{Body: []byte(`{"model":"some-model"}`)})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"x-ai-eg-affinity-prompt-0", "x-ai-eg-affinity-prompt-1"},
+			resp.GetRequestBody().GetResponse().GetHeaderMutation().RemoveHeaders)
+		require.NotContains(t, headers, "x-ai-eg-affinity-prompt-1")
+	})
+
+	t.Run("model alias", func(t *testing.T) {
+		headers := map[string]string{":path": "/foo", "x-user-id": "alice"}
+		p := &chatCompletionProcessorRouterFilter{
+			config: &filterapi.RuntimeConfig{
+				ModelAliases: map[string]*filterapi.ModelAlias{
+					"chat-default": {Name: "chat-default", Rollout: &filterapi.ModelAliasRollout{Model: "new-model", Percent: 100}},
+				},
+				// The context window of the concrete model applies.
+				ModelContextWindows: map[string]int32{"new-model": 1},
+			},
+			requestHeaders: headers,
+			logger:         slog.Default(),
+			tracer:         tracingapi.NoopTracer[openai.ChatCompletionRequest, openai.ChatCompletionResponse, openai.ChatCompletionResponseChunk]{},
+		}
+		body := []byte(`{"model":"chat-default","messages":[{"role":"user","content":"` + strings.Repeat("a", 40) + `"}]}`)
+		resp, err := p.ProcessRequestBody(t.Context(), &extprocv3.HttpBody{Body: body})
+		require.NoError(t, err)
+		require.Contains(t, string(resp.GetImmediateResponse().GetBody()), "of model new-model")
+
+		p.config.ModelContextWindows = nil
+		resp, err = p.ProcessRequestBody(t.Context(), &extprocv3.HttpBody{Body: body})
+		require.NoError(t, err)
+		got := map[string]string{}
+		for _, h := range resp.GetRequestBody().GetResponse().GetHeaderMutation().SetHeaders {
+			got[h.Header.Key] = string(h.Header.RawValue)
+		}
+		require.Equal(t, "new-model", got[internalapi.ModelNameHeaderKeyDefault])
+		require.Equal(t, "new-model", headers[internalapi.ModelNameHeaderKeyDefault])
+		require.Equal(t, "chat-default", p.originalModel)
+		require.Equal(t, "new-model", p.resolvedModel)
+	})
+
 	t.Run("span creation", func(t *testing.T) {
 		headers := map[string]string{":path": "/v1/chat/completions"}
 		span := &testotel.MockSpan{}
@@ -220,6 +462,69 @@ func Test_chatCompletionProcessorRouterFilter_ProcessRequestBody(t *testing.T) {
 	})
 }
 
+func Test_chatCompletionProcessorRouterFilter_resolveModelAlias(t *testing.T) {
+	newProcessor := func(headers map[string]string) *chatCompletionProcessorRouterFilter {
+		return &chatCompletionProcessorRouterFilter{requestHeaders: headers}
+	}
+	alias := &filterapi.ModelAlias{
+		Name:         "chat-default",
+		Models:       []filterapi.ModelAliasModel{{Name: "a", Weight: 3}, {Name: "b", Weight: 1}},
+		StickyHeader: "x-user-id",
+	}
+
+	t.Run("weights", func(t *testing.T) {
+		counts := map[string]int{}
+		for i := range 4000 {
+			counts[newProcessor(map[string]string{"x-user-id": strconv.Itoa(i)}).resolveModelAlias(alias)]++
+		}
+		require.Len(t, counts, 2)
+		require.InDelta(t, 3000, counts["a"], 200)
+		require.InDelta(t, 1000, counts["b"], 200)
+	})
+
+	t.Run("sticky", func(t *testing.T) {
+		for i := range 100 {
+			headers := map[string]string{"x-user-id": strconv.Itoa(i)}
+			model := newProcessor(headers).resolveModelAlias(alias)
+			for range 3 {
+				require.Equal(t, model, newProcessor(headers).resolveModelAlias(alias))
+			}
+		}
+	})
+
+	t.Run("rollout", func(t *testing.T) {
+		withRollout := func(percent int32) *filterapi.ModelAlias {
+			a := *alias
+			a.Rollout = &filterapi.ModelAliasRollout{Model: "c", Percent: percent}
+			return &a
+		}
+		moved := 0
+		for i := range 1000 {
+			headers := map[string]string{"x-user-id": strconv.Itoa(i)}
+			before := newProcessor(headers).resolveModelAlias(withRollout(10))
+			after := newProcessor(headers).resolveModelAlias(withRollout(50))
+			// Increasing the percentage only moves the requests to the rolled out model.
+			if before != after {
+				require.Equal(t, "c", after)
+				moved++
+			} else if before == "c" {
+				require.Equal(t, "c", after)
+			}
+			require.Equal(t, "c", newProcessor(headers).resolveModelAlias(withRollout(100)))
+		}
+		require.InDelta(t, 400, moved, 80)
+	})
+
+	t.Run("random without sticky header", func(t *testing.T) {
+		counts := map[string]int{}
+		for range 4000 {
+			counts[newProcessor(map[string]string{}).resolveModelAlias(alias)]++
+		}
+		require.InDelta(t, 3000, counts["a"], 200)
+		require.InDelta(t, 1000, counts["b"], 200)
+	})
+}
+
 func Test_chatCompletionProcessorUpstreamFilter_ProcessResponseHeaders(t *testing.T) {
 	t.Run("error translation", func(t *testing.T) {
 		mm := &mockMetrics{}
@@ -280,6 +585,95 @@ func Test_chatCompletionProcessorUpstreamFilter_ProcessResponseHeaders(t *testin
 		require.Empty(t, commonRes.HeaderMutation)
 		require.Nil(t, res.ModeOverride)
 	})
+	t.Run("fallback header removed", func(t *testing.T) {
+		inHeaders := &corev3.HeaderMap{
+			Headers: []*corev3.HeaderValue{{Key: ":status", Value: "200"}, {Key: internalapi.FallbackErrorClassHeader, Value: "rate_limit"}},
+		}
+		expHeaders := map[string]string{":status": "200", internalapi.FallbackErrorClassHeader: "rate_limit"}
+		mm := &mockMetrics{}
+		mt := &mockTranslator{t: t, expHeaders: expHeaders}
+		p := &chatCompletionProcessorUpstreamFilter{translator: mt, metrics: mm, parent: &chatCompletionProcessorRouterFilter{}}
+		res, err := p.ProcessResponseHeaders(t.Context(), inHeaders)
+		require.NoError(t, err)
+		commonRes := res.Response.(*extprocv3.ProcessingResponse_ResponseHeaders).ResponseHeaders.Response
+		require.Equal(t, []string{internalapi.FallbackErrorClassHeader}, commonRes.HeaderMutation.RemoveHeaders)
+	})
+}
+
+func Test_chatCompletionProcessorUpstreamFilter_ProcessUpstreamResponseHeaders(t *testing.T) {
+	for _, tc := range []struct {
+		name                  string
+		status                string
+		retriableErrorClasses []string
+		expMode               *extprocv3http.ProcessingMode
+	}{
+		{name: "success", status: "200", retriableErrorClasses: []string{"rate_limit"}},
+		{name: "error without fallback policy", status: "429"},
+		{
+			name:                  "error with fallback policy",
+			status:                "429",
+			retriableErrorClasses: []string{"rate_limit"},
+			expMode:               &extprocv3http.ProcessingMode{ResponseBodyMode: extprocv3http.ProcessingMode_BUFFERED},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &chatCompletionProcessorUpstreamFilter{retriableErrorClasses: tc.retriableErrorClasses}
+			res, err := p.ProcessUpstreamResponseHeaders(t.Context(), &corev3.HeaderMap{
+				Headers: []*corev3.HeaderValue{{Key: ":status", Value: tc.status}, {Key: "content-encoding", Value: "gzip"}},
+			})
+			require.NoError(t, err)
+			require.NotNil(t, res.GetResponseHeaders())
+			require.Equal(t, tc.expMode, res.ModeOverride)
+			require.Equal(t, "gzip", p.responseEncoding)
+		})
+	}
+}
+
+func Test_chatCompletionProcessorUpstreamFilter_ProcessUpstreamResponseBody(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		status         string
+		body           string
+		translatedBody string
+		expHeader      string
+	}{
+		{name: "success", status: "200", body: `{}`},
+		{name: "retriable class", status: "429", body: `{"error":{"message":"slow down"}}`, expHeader: "rate_limit"},
+		{
+			name:           "retriable class of translated body",
+			status:         "400",
+			body:           `{"message":"Input is too long for requested model."}`,
+			translatedBody: `{"error":{"type":"invalid_request_error","message":"Input is too long for requested model."}}`,
+			expHeader:      "context_length_exceeded",
+		},
+		{name: "non-retriable class", status: "401", body: `{"error":{"message":"invalid api key"}}`},
+		{name: "unclassified", status: "500", body: `internal error`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inBody := &extprocv3.HttpBody{Body: []byte(tc.body), EndOfStream: true}
+			mt := &mockTranslator{t: t, expResponseBody: inBody}
+			if tc.translatedBody != "" {
+				mt.retBodyMutation = []byte(tc.translatedBody)
+			}
+			p := &chatCompletionProcessorUpstreamFilter{
+				logger:                slog.Default(),
+				translator:            mt,
+				responseHeaders:       map[string]string{":status": tc.status},
+				retriableErrorClasses: []string{"rate_limit", "context_length_exceeded"},
+			}
+			res, err := p.ProcessUpstreamResponseBody(t.Context(), inBody)
+			require.NoError(t, err)
+			commonRes := res.GetResponseBody().GetResponse()
+			if tc.expHeader == "" {
+				require.Nil(t, commonRes)
+				return
+			}
+			require.Len(t, commonRes.HeaderMutation.SetHeaders, 1)
+			require.Equal(t, internalapi.FallbackErrorClassHeader, commonRes.HeaderMutation.SetHeaders[0].Header.Key)
+			require.Equal(t, tc.expHeader, string(commonRes.HeaderMutation.SetHeaders[0].Header.RawValue))
+			require.Nil(t, commonRes.BodyMutation)
+		})
+	}
 }
 
 func Test_chatCompletionProcessorUpstreamFilter_ProcessResponseBody(t *testing.T) {
@@ -397,6 +791,23 @@ func Test_chatCompletionProcessorUpstreamFilter_ProcessResponseBody(t *testing.T
 		require.Equal(t, "foo", commonRes.HeaderMutation.SetHeaders[0].Header.Key)
 		require.Equal(t, []byte("bar"), commonRes.HeaderMutation.SetHeaders[0].Header.RawValue)
 		mm.RequireRequestFailure(t)
+		require.Equal(t, "_OTHER", mm.errorType)
+	})
+
+	t.Run("non-2xx status records error type", func(t *testing.T) {
+		inBody := &extprocv3.HttpBody{Body: []byte(`{"error":{"message":"slow down"}}`), EndOfStream: true}
+		mm := &mockMetrics{}
+		mt := &mockTranslator{t: t, expResponseBody: inBody, retBodyMutation: inBody.Body}
+		p := &chatCompletionProcessorUpstreamFilter{
+			translator:      mt,
+			metrics:         mm,
+			responseHeaders: map[string]string{":status": "429"},
+			parent:          &chatCompletionProcessorRouterFilter{},
+		}
+		_, err := p.ProcessResponseBody(t.Context(), inBody)
+		require.NoError(t, err)
+		mm.RequireRequestFailure(t)
+		require.Equal(t, "rate_limit", mm.errorType)
 	})
 
 	// Verify streaming only records completion on EndOfStream.
@@ -441,6 +852,168 @@ func Test_chatCompletionProcessorUpstreamFilter_ProcessResponseBody(t *testing.T
 	})
 }
 
+func Test_chatCompletionProcessorUpstreamFilter_CircuitBreaker(t *testing.T) {
+	newBreaker := func(config circuitbreaker.Config) *circuitbreaker.Breaker {
+		return circuitbreaker.NewRegistry().Update(map[string]circuitbreaker.Config{"backend": config})["backend"]
+	}
+
+	t.Run("overload error records failure once", func(t *testing.T) {
+		breaker := newBreaker(circuitbreaker.Config{ConsecutiveFailures: 2, OpenDuration: time.Hour})
+		inBody := &extprocv3.HttpBody{Body: []byte(`{"error":{"message":"slow down"}}`), EndOfStream: true}
+		p := &chatCompletionProcessorUpstreamFilter{
+			logger:          slog.Default(),
+			translator:      &mockTranslator{t: t, expResponseBody: inBody, retBodyMutation: inBody.Body},
+			metrics:         &mockMetrics{},
+			responseHeaders: map[string]string{":status": "429"},
+			parent:          &chatCompletionProcessorRouterFilter{},
+			circuitBreaker:  breaker,
+		}
+		// The response is seen both per attempt and as the final response.
+		_, err := p.ProcessUpstreamResponseBody(t.Context(), inBody)
+		require.NoError(t, err)
+		_, err = p.ProcessResponseBody(t.Context(), inBody)
+		require.NoError(t, err)
+		require.Equal(t, 1, breaker.Status().ConsecutiveFailures)
+	})
+
+	t.Run("other error records success", func(t *testing.T) {
+		breaker := newBreaker(circuitbreaker.Config{ConsecutiveFailures: 2, OpenDuration: time.Hour})
+		breaker.RecordFailure()
+		inBody := &extprocv3.HttpBody{Body: []byte(`{"error":{"message":"invalid api key"}}`), EndOfStream: true}
+		p := &chatCompletionProcessorUpstreamFilter{
+			translator:      &mockTranslator{t: t, expResponseBody: inBody, retBodyMutation: inBody.Body},
+			metrics:         &mockMetrics{},
+			responseHeaders: map[string]string{":status": "401"},
+			parent:          &chatCompletionProcessorRouterFilter{},
+			circuitBreaker:  breaker,
+		}
+		_, err := p.ProcessResponseBody(t.Context(), inBody)
+		require.NoError(t, err)
+		require.Zero(t, breaker.Status().ConsecutiveFailures)
+	})
+
+	for _, tc := range []struct {
+		name       string
+		ttftMs     float64
+		expFailure bool
+	}{
+		{name: "streaming within time to first token objective", ttftMs: 500},
+		{name: "streaming exceeding time to first token objective", ttftMs: 2000, expFailure: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			breaker := newBreaker(circuitbreaker.Config{ConsecutiveFailures: 1, TimeToFirstToken: time.Second, OpenDuration: time.Hour})
+			chunk := &extprocv3.HttpBody{Body: []byte("chunk-1")}
+			p := &chatCompletionProcessorUpstreamFilter{
+				translator:      &mockTranslator{t: t, expResponseBody: chunk},
+				metrics:         &mockMetrics{timeToFirstTokenMs: tc.ttftMs},
+				responseHeaders: map[string]string{":status": "200"},
+				parent: &chatCompletionProcessorRouterFilter{
+					stream: true,
+					config: &filterapi.RuntimeConfig{},
+				},
+				circuitBreaker: breaker,
+			}
+			_, err := p.ProcessResponseBody(t.Context(), chunk)
+			require.NoError(t, err)
+			if tc.expFailure {
+				require.Equal(t, circuitbreaker.StateOpen, breaker.Status().State)
+			} else {
+				require.Equal(t, circuitbreaker.StateClosed, breaker.Status().State)
+			}
+		})
+	}
+}
+
+func Test_chatCompletionProcessorUpstreamFilter_BackendSelectionLatency(t *testing.T) {
+	latency := &backendselection.Latency{}
+	mm := &mockMetrics{timeToFirstTokenMs: 200}
+	mt := &mockTranslator{t: t}
+	p := &chatCompletionProcessorUpstreamFilter{
+		translator:      mt,
+		metrics:         mm,
+		responseHeaders: map[string]string{":status": "200"},
+		parent: &chatCompletionProcessorRouterFilter{
+			stream: true,
+			config: &filterapi.RuntimeConfig{},
+		},
+		latency: latency,
+	}
+	chunk := &extprocv3.HttpBody{Body: []byte("chunk-1")}
+	mt.expResponseBody = chunk
+	_, err := p.ProcessResponseBody(t.Context(), chunk)
+	require.NoError(t, err)
+	// The latency is recorded only at the end of the stream.
+	_, ok := latency.Expected()
+	require.False(t, ok)
+
+	final := &extprocv3.HttpBody{Body: []byte("chunk-final"), EndOfStream: true}
+	mt.expResponseBody = final
+	_, err = p.ProcessResponseBody(t.Context(), final)
+	require.NoError(t, err)
+	expected, ok := latency.Expected()
+	require.True(t, ok)
+	require.InDelta(t, 200+mm.GetInterTokenLatencyMs()*backendselection.ExpectedOutputTokens, expected, 1e-9)
+}
+
+func Test_chatCompletionProcessorUpstreamFilter_BackendSelectionLatencyNonStream(t *testing.T) {
+	latency := &backendselection.Latency{}
+	mt := &mockTranslator{t: t}
+	mt.retUsedToken.SetOutputTokens(100)
+	p := &chatCompletionProcessorUpstreamFilter{
+		translator:      mt,
+		metrics:         &mockMetrics{},
+		responseHeaders: map[string]string{":status": "200"},
+		parent:          &chatCompletionProcessorRouterFilter{config: &filterapi.RuntimeConfig{}},
+		latency:         latency,
+		requestStart:    time.Now().Add(-time.Second),
+	}
+	final := &extprocv3.HttpBody{Body: []byte("response"), EndOfStream: true}
+	mt.expResponseBody = final
+	_, err := p.ProcessResponseBody(t.Context(), final)
+	require.NoError(t, err)
+	// The time to first token is unknown, so the latency of the response is spread over its output tokens.
+	expected, ok := latency.Expected()
+	require.True(t, ok)
+	require.InDelta(t, 10*backendselection.ExpectedOutputTokens, expected, 100)
+}
+
+func Test_chatCompletionProcessorUpstreamFilter_BackendSelectionFailure(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		status     string
+		expFailure bool
+	}{
+		{name: "server error", status: "500", expFailure: true},
+		{name: "rate limit", status: "429", expFailure: true},
+		{name: "bad request", status: "400", expFailure: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			latency := &backendselection.Latency{}
+			latency.Record(100, 10)
+			before, _ := latency.Expected()
+			p := &chatCompletionProcessorUpstreamFilter{
+				translator:      &mockTranslator{t: t},
+				metrics:         &mockMetrics{},
+				responseHeaders: map[string]string{":status": tc.status},
+				parent:          &chatCompletionProcessorRouterFilter{config: &filterapi.RuntimeConfig{}},
+				latency:         latency,
+				logger:          slog.Default(),
+			}
+			_, err := p.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{Body: []byte(`{"error":{}}`), EndOfStream: true})
+			require.NoError(t, err)
+			// The error response is seen again as the final response, which is recorded once.
+			_, err = p.ProcessUpstreamResponseBody(t.Context(), &extprocv3.HttpBody{Body: []byte(`{"error":{}}`), EndOfStream: true})
+			require.NoError(t, err)
+			after, _ := latency.Expected()
+			if tc.expFailure {
+				require.InDelta(t, before/0.8, after, 1e-9)
+			} else {
+				require.InDelta(t, before, after, 1e-9)
+			}
+		})
+	}
+}
+
 func bodyFromModel(t *testing.T, model string, stream bool, streamOptions *openai.StreamOptions) []byte {
 	openAIReq := &openai.ChatCompletionRequest{}
 	openAIReq.Model = model
@@ -478,6 +1051,233 @@ func Test_chatCompletionProcessorUpstreamFilter_SetBackend(t *testing.T) {
 	require.Nil(t, r.upstreamFilter, "upstreamFilter must remain nil when SetBackend fails")
 }
 
+func Test_chatCompletionProcessorUpstreamFilter_SetBackend_ModelAlias(t *testing.T) {
+	r := &chatCompletionProcessorRouterFilter{
+		eh:             endpointspec.ChatCompletionsEndpointSpec{},
+		requestHeaders: map[string]string{":path": "/v1/chat/completions"},
+		resolvedModel:  "gpt-4o",
+	}
+	for _, tc := range []struct {
+		modelNameOverride, exp string
+	}{
+		{exp: "gpt-4o"},
+		// The model name override of the backend takes precedence over the resolved model.
+		{modelNameOverride: "gpt-4o-2024-08-06", exp: "gpt-4o-2024-08-06"},
+	} {
+		p := &chatCompletionProcessorUpstreamFilter{requestHeaders: map[string]string{}, metrics: &mockMetrics{}}
+		require.NoError(t, p.SetBackend(t.Context(), &filterapi.RuntimeBackend{Backend: &filterapi.Backend{
+			Name:              "some-backend",
+			Schema:            filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+			ModelNameOverride: tc.modelNameOverride,
+		}}, "test-route", r))
+		require.Equal(t, tc.exp, p.modelNameOverride)
+		require.Equal(t, tc.exp, p.requestHeaders[internalapi.ModelNameHeaderKeyDefault])
+	}
+}
+
+func Test_chatCompletionProcessorUpstreamFilter_FailoverErrorClasses(t *testing.T) {
+	newRouter := func() *chatCompletionProcessorRouterFilter {
+		return &chatCompletionProcessorRouterFilter{
+			eh:             endpointspec.ChatCompletionsEndpointSpec{},
+			requestHeaders: map[string]string{":path": "/v1/chat/completions"},
+		}
+	}
+	newAttempt := func(t *testing.T, r *chatCompletionProcessorRouterFilter, priority uint32, breaker *circuitbreaker.Breaker) *chatCompletionProcessorUpstreamFilter {
+		p := &chatCompletionProcessorUpstreamFilter{requestHeaders: map[string]string{}, metrics: &mockMetrics{}, logger: slog.Default()}
+		// The priority of the endpoint selected by Envoy is set before the backend.
+		p.SetEndpointPriority(priority)
+		require.NoError(t, p.SetBackend(t.Context(), &filterapi.RuntimeBackend{
+			Backend: &filterapi.Backend{
+				Name:                  "some-backend",
+				Schema:                filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+				RetriableErrorClasses: []string{"overloaded", "rate_limit"},
+				FailoverErrorClasses:  []string{"rate_limit"},
+			},
+			CircuitBreaker: breaker,
+		}, "test-route", r))
+		return p
+	}
+	fail := func(t *testing.T, p *chatCompletionProcessorUpstreamFilter, status, body string) {
+		inBody := &extprocv3.HttpBody{Body: []byte(body), EndOfStream: true}
+		p.translator = &mockTranslator{t: t, expResponseBody: inBody, retBodyMutation: inBody.Body}
+		p.responseHeaders = map[string]string{":status": status}
+		_, err := p.ProcessUpstreamResponseBody(t.Context(), inBody)
+		require.NoError(t, err)
+	}
+	requireRejected := func(t *testing.T, p *chatCompletionProcessorUpstreamFilter, expErrorClass, expErrorType string) {
+		res, err := p.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		immediateResp := res.GetImmediateResponse()
+		require.NotNil(t, immediateResp)
+		require.Equal(t, typev3.StatusCode(503), immediateResp.Status.Code)
+		var fallbackHeader string
+		for _, h := range immediateResp.Headers.GetSetHeaders() {
+			if h.Header.Key == internalapi.FallbackErrorClassHeader {
+				fallbackHeader = string(h.Header.RawValue)
+			}
+		}
+		require.Equal(t, expErrorClass, fallbackHeader)
+		mm := p.metrics.(*mockMetrics)
+		mm.RequireRequestFailure(t)
+		require.Equal(t, expErrorType, mm.errorType)
+	}
+
+	t.Run("failover", func(t *testing.T) {
+		r := newRouter()
+		// The first attempt on the priority fails with a class to fail over on, so the next one on it is rejected.
+		fail(t, newAttempt(t, r, 0, nil), "429", `{"error":{"message":"slow down"}}`)
+		requireRejected(t, newAttempt(t, r, 0, nil), "rate_limit", "rate_limit")
+		// The attempts on the next priority are not rejected.
+		require.Empty(t, newAttempt(t, r, 1, nil).failedOverClass())
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		r := newRouter()
+		// The class to retry on keeps the next attempt on the priority.
+		fail(t, newAttempt(t, r, 0, nil), "503", `{"error":{"message":"overloaded"}}`)
+		require.Empty(t, newAttempt(t, r, 0, nil).failedOverClass())
+		// The second attempt on the priority fails over on its own.
+		fail(t, newAttempt(t, r, 0, nil), "429", `{"error":{"message":"slow down"}}`)
+		require.Equal(t, "rate_limit", string(newAttempt(t, r, 0, nil).failedOverClass()))
+	})
+
+	t.Run("circuit breaker", func(t *testing.T) {
+		r := newRouter()
+		breaker := circuitbreaker.NewRegistry().Update(map[string]circuitbreaker.Config{
+			"backend": {ConsecutiveFailures: 1, OpenDuration: time.Hour},
+		})["backend"]
+		breaker.RecordFailure()
+		// The attempt rejected by the open circuit breaker is not a failover, even though it advances the attempts
+		// of the priority.
+		requireRejected(t, newAttempt(t, r, 0, breaker), "overloaded", circuitBreakerOpenErrorType)
+		require.Empty(t, newAttempt(t, r, 0, nil).failedOverClass())
+		// The next attempt on the priority fails over, so the one after it on the same priority is rejected
+		// regardless of the number of the attempts so far.
+		fail(t, newAttempt(t, r, 0, nil), "429", `{"error":{"message":"slow down"}}`)
+		requireRejected(t, newAttempt(t, r, 0, nil), "rate_limit", "rate_limit")
+		// The retry of the rejection is sent to the next priority, which calls the backend.
+		require.Empty(t, newAttempt(t, r, 1, nil).failedOverClass())
+		require.Equal(t, 5, r.upstreamFilterCount)
+	})
+
+	t.Run("circuit breaker failover", func(t *testing.T) {
+		r := newRouter()
+		breaker := circuitbreaker.NewRegistry().Update(map[string]circuitbreaker.Config{
+			"backend": {ConsecutiveFailures: 1, OpenDuration: time.Hour},
+		})["backend"]
+		breaker.RecordFailure()
+		p := newAttempt(t, r, 0, breaker)
+		p.failoverErrorClasses = []string{"overloaded"}
+		// The open circuit breaker fails over when the policy fails over on the overloaded backends.
+		requireRejected(t, p, "overloaded", circuitBreakerOpenErrorType)
+		p = newAttempt(t, r, 0, nil)
+		p.failoverErrorClasses = []string{"overloaded"}
+		require.Equal(t, "overloaded", string(p.failedOverClass()))
+	})
+
+	t.Run("unknown priority", func(t *testing.T) {
+		r := newRouter()
+		p := &chatCompletionProcessorUpstreamFilter{requestHeaders: map[string]string{}, metrics: &mockMetrics{}, logger: slog.Default()}
+		require.NoError(t, p.SetBackend(t.Context(), &filterapi.RuntimeBackend{Backend: &filterapi.Backend{
+			Name:                  "some-backend",
+			Schema:                filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+			RetriableErrorClasses: []string{"rate_limit"},
+			FailoverErrorClasses:  []string{"rate_limit"},
+		}}, "test-route", r))
+		// Without the priority of the endpoint, the failover is left to Envoy.
+		fail(t, p, "429", `{"error":{"message":"slow down"}}`)
+		require.Empty(t, r.failedOverPriorities)
+	})
+}
+
+// hedgeRecordingSpan is a span recording the hedged requests as "<backend>/<attempt>".
+type hedgeRecordingSpan struct {
+	testotel.MockSpan
+	hedged, winners []string
+}
+
+func (s *hedgeRecordingSpan) RecordHedgedRequest(backend string, attempt int) {
+	s.hedged = append(s.hedged, fmt.Sprintf("%s/%d", backend, attempt))
+}
+
+func (s *hedgeRecordingSpan) RecordHedgeWinner(backend string, attempt int) {
+	s.winners = append(s.winners, fmt.Sprintf("%s/%d", backend, attempt))
+}
+
+func Test_chatCompletionProcessorUpstreamFilter_Hedging(t *testing.T) {
+	hedgingBackend := func(name string) *filterapi.RuntimeBackend {
+		return &filterapi.RuntimeBackend{Backend: &filterapi.Backend{
+			Name:    name,
+			Schema:  filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+			Hedging: true,
+		}}
+	}
+	newRouter := func(span *hedgeRecordingSpan) *chatCompletionProcessorRouterFilter {
+		return &chatCompletionProcessorRouterFilter{
+			eh:     endpointspec.ChatCompletionsEndpointSpec{},
+			config: &filterapi.RuntimeConfig{},
+			logger: slog.Default(),
+			span:   span,
+		}
+	}
+	newAttempt := func(t *testing.T, r *chatCompletionProcessorRouterFilter, backend string) *chatCompletionProcessorUpstreamFilter {
+		u := &chatCompletionProcessorUpstreamFilter{requestHeaders: map[string]string{}, metrics: &mockMetrics{}, logger: slog.Default()}
+		require.NoError(t, u.SetBackend(t.Context(), hedgingBackend(backend), "test-route", r))
+		return u
+	}
+
+	t.Run("first response wins", func(t *testing.T) {
+		span := &hedgeRecordingSpan{}
+		r := newRouter(span)
+		first := newAttempt(t, r, "aaa")
+		second := newAttempt(t, r, "bbb")
+		require.Equal(t, []string{"bbb/2"}, span.hedged)
+		require.Equal(t, second, r.upstreamFilter)
+
+		// The response of the first attempt is tagged with its index.
+		res, err := first.ProcessUpstreamResponseHeaders(t.Context(), &corev3.HeaderMap{
+			Headers: []*corev3.HeaderValue{{Key: ":status", Value: "200"}},
+		})
+		require.NoError(t, err)
+		setHeaders := res.GetResponseHeaders().GetResponse().GetHeaderMutation().GetSetHeaders()
+		require.Len(t, setHeaders, 1)
+		require.Equal(t, internalapi.HedgeAttemptHeader, setHeaders[0].Header.Key)
+		require.Equal(t, "1", string(setHeaders[0].Header.RawValue))
+
+		// The router filter processes the response with the first attempt, and removes the tag.
+		res, err = r.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{
+			Headers: []*corev3.HeaderValue{{Key: ":status", Value: "200"}, {Key: internalapi.HedgeAttemptHeader, RawValue: []byte("1")}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, first, r.upstreamFilter)
+		require.Equal(t, []string{"aaa/1"}, span.winners)
+		require.Contains(t, res.GetResponseHeaders().GetResponse().GetHeaderMutation().GetRemoveHeaders(), internalapi.HedgeAttemptHeader)
+
+		// An attempt that started before the response does not replace the winner.
+		_ = newAttempt(t, r, "ccc")
+		require.Equal(t, first, r.upstreamFilter)
+	})
+
+	t.Run("retry is not a hedged request", func(t *testing.T) {
+		span := &hedgeRecordingSpan{}
+		r := newRouter(span)
+		first := newAttempt(t, r, "aaa")
+		_, err := first.ProcessUpstreamResponseHeaders(t.Context(), &corev3.HeaderMap{
+			Headers: []*corev3.HeaderValue{{Key: ":status", Value: "503"}},
+		})
+		require.NoError(t, err)
+		second := newAttempt(t, r, "bbb")
+		require.Empty(t, span.hedged)
+
+		_, err = r.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{
+			Headers: []*corev3.HeaderValue{{Key: ":status", Value: "200"}, {Key: internalapi.HedgeAttemptHeader, RawValue: []byte("2")}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, second, r.upstreamFilter)
+		require.Empty(t, span.winners)
+	})
+}
+
 // Test_chatCompletionProcessorUpstreamFilter_SetBackend_unsupportedSchema_noResponsePanic
 // verifies that when SetBackend fails due to an unsupported schema, subsequent
 // response processing does not panic. Before the fix for #1941, upstreamFilter
@@ -590,6 +1390,94 @@ func Test_chatCompletionProcessorUpstreamFilter_ProcessRequestHeaders(t *testing
 				require.Equal(t, "some-model", mm.originalModel)
 				require.Equal(t, "some-model", mm.requestModel)
 			})
+			t.Run("context window exceeded", func(t *testing.T) {
+				headers := map[string]string{":path": "/foo", internalapi.ModelNameHeaderKeyDefault: "some-model"}
+				someBody := []byte(`{"model":"some-model","messages":[{"role":"user","content":"` + strings.Repeat("a", 400) + `"}]}`)
+				var body openai.ChatCompletionRequest
+				require.NoError(t, json.Unmarshal(someBody, &body))
+				mm := &mockMetrics{}
+				p := &chatCompletionProcessorUpstreamFilter{
+					parent: &chatCompletionProcessorRouterFilter{
+						config:                 &filterapi.RuntimeConfig{},
+						logger:                 slog.Default(),
+						originalRequestBodyRaw: someBody,
+						originalRequestBody:    &body,
+						originalModel:          "some-model",
+						stream:                 tc.stream,
+					},
+					requestHeaders: headers,
+					metrics:        mm,
+					translator:     &mockTranslator{t: t},
+					logger:         slog.Default(),
+					contextWindow:  100,
+				}
+				resp, err := p.ProcessRequestHeaders(t.Context(), nil)
+				require.NoError(t, err)
+				immediateResp := resp.GetImmediateResponse()
+				require.NotNil(t, immediateResp)
+				require.Equal(t, typev3.StatusCode(400), immediateResp.Status.Code)
+				require.Contains(t, string(immediateResp.Body), "context length exceeded")
+				// The route retries the request on the other backends of the rule.
+				var fallbackHeader string
+				for _, h := range immediateResp.Headers.GetSetHeaders() {
+					if h.Header.Key == internalapi.FallbackErrorClassHeader {
+						fallbackHeader = string(h.Header.RawValue)
+					}
+				}
+				require.Equal(t, "context_length_exceeded", fallbackHeader)
+				mm.RequireRequestFailure(t)
+				require.Equal(t, "context_length_exceeded", mm.errorType)
+			})
+			t.Run("circuit breaker open", func(t *testing.T) {
+				for _, retriable := range []bool{false, true} {
+					headers := map[string]string{":path": "/foo", internalapi.ModelNameHeaderKeyDefault: "some-model"}
+					someBody := bodyFromModel(t, "some-model", tc.stream, nil)
+					var body openai.ChatCompletionRequest
+					require.NoError(t, json.Unmarshal(someBody, &body))
+					breaker := circuitbreaker.NewRegistry().Update(map[string]circuitbreaker.Config{
+						"backend": {ConsecutiveFailures: 1, OpenDuration: time.Hour},
+					})["backend"]
+					breaker.RecordFailure()
+					mm := &mockMetrics{}
+					p := &chatCompletionProcessorUpstreamFilter{
+						parent: &chatCompletionProcessorRouterFilter{
+							config:                 &filterapi.RuntimeConfig{},
+							logger:                 slog.Default(),
+							originalRequestBodyRaw: someBody,
+							originalRequestBody:    &body,
+							originalModel:          "some-model",
+							stream:                 tc.stream,
+						},
+						requestHeaders: headers,
+						metrics:        mm,
+						translator:     &mockTranslator{t: t},
+						logger:         slog.Default(),
+						circuitBreaker: breaker,
+					}
+					if retriable {
+						p.retriableErrorClasses = []string{"overloaded"}
+					}
+					resp, err := p.ProcessRequestHeaders(t.Context(), nil)
+					require.NoError(t, err)
+					immediateResp := resp.GetImmediateResponse()
+					require.NotNil(t, immediateResp)
+					require.Equal(t, typev3.StatusCode(503), immediateResp.Status.Code)
+					require.Contains(t, string(immediateResp.Body), "backend is temporarily unavailable")
+					var fallbackHeader string
+					for _, h := range immediateResp.Headers.GetSetHeaders() {
+						if h.Header.Key == internalapi.FallbackErrorClassHeader {
+							fallbackHeader = string(h.Header.RawValue)
+						}
+					}
+					// The request is retried on the other backends regardless of the fallback policy.
+					require.Equal(t, "overloaded", fallbackHeader)
+					mm.RequireRequestFailure(t)
+					require.Equal(t, "circuit_breaker_open", mm.errorType)
+					// The rejection does not count as a response of the backend.
+					require.Nil(t, p.circuitBreaker)
+					require.Equal(t, circuitbreaker.StateOpen, breaker.Status().State)
+				}
+			})
 			t.Run("auth handler error", func(t *testing.T) {
 				headers := map[string]string{":path": "/foo", internalapi.ModelNameHeaderKeyDefault: "some-model"}
 				someBody := bodyFromModel(t, "some-model", tc.stream, nil)
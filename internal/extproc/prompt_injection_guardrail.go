@@ -0,0 +1,58 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"context"
+	"log/slog"
+
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+	"github.com/envoyproxy/ai-gateway/internal/tracing/tracingapi"
+)
+
+// applyPromptInjectionGuardrail detects the prompt injection in the untrusted texts of the request, i.e., the inputs of
+// the end users and the results of the tools. The detection of the highest score among the texts is recorded. This
+// returns the immediate response rejecting the request if the injection is detected in the Block mode, or nil
+// otherwise.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) applyPromptInjectionGuardrail(ctx context.Context) *extprocv3.ProcessingResponse {
+	rp := u.parent
+	var detection *guardrail.PromptInjectionDetection
+	var source endpointspec.UntrustedTextSource
+	for _, t := range rp.eh.UntrustedTexts(u.requestBody) {
+		if d := u.promptInjectionDetector.Detect(t.Text); d != nil && (detection == nil || d.Score > detection.Score) {
+			detection, source = d, t.Source
+		}
+	}
+	if detection == nil {
+		return nil
+	}
+
+	action := "block"
+	if u.promptInjectionGuardrail.Shadow {
+		action = "shadow"
+	}
+	if m, ok := u.metrics.(metrics.GuardrailMetrics); ok {
+		for _, rule := range detection.Rules {
+			m.RecordPromptInjectionDetection(ctx, rule, action, u.requestHeaders)
+		}
+	}
+	if recorder, ok := rp.span.(tracingapi.GuardrailRecorder); ok {
+		recorder.RecordPromptInjectionDetection(action, string(source), detection.Score, detection.Rules)
+	}
+	if u.promptInjectionGuardrail.Shadow {
+		u.logger.Info("detected prompt injection in shadow mode", slog.String("backend", u.backendName),
+			slog.String("source", string(source)), slog.Int("score", detection.Score), slog.Any("rules", detection.Rules))
+		return nil
+	}
+	u.logger.Info("rejecting request with prompt injection", slog.String("backend", u.backendName),
+		slog.String("source", string(source)), slog.Int("score", detection.Score), slog.Any("rules", detection.Rules))
+	u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
+	return createUserFacingErrorResponse(400, "BadRequest", "request contains a potential prompt injection")
+}
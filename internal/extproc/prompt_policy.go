@@ -0,0 +1,172 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/promptpolicy"
+)
+
+// applyPromptPolicy adds the messages of the prompt policy of the backend to the request body sent to the backend
+// according to the API schema of the request. This returns the immediate response rejecting the request if the policy
+// locks the system prompt and the request has its own, or nil otherwise.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) applyPromptPolicy(ctx context.Context) (*extprocv3.ProcessingResponse, error) {
+	rp := u.parent
+	if u.promptPolicy.Lock && hasClientSystemPrompt(rp.eh, u.requestBodyRaw) {
+		u.logger.Info("rejecting request with system prompt locked by the prompt policy", slog.String("backend", u.backendName))
+		u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
+		return createUserFacingErrorResponse(400, "BadRequest", "system prompt is not allowed on this route"), nil
+	}
+	if len(u.promptTemplates) == 0 {
+		return nil, nil
+	}
+
+	data := &promptpolicy.Data{Headers: u.requestHeaders, Model: rp.originalModel}
+	contents := make([]string, len(u.promptTemplates))
+	for i, tmpl := range u.promptTemplates {
+		var err error
+		if contents[i], err = tmpl.Render(data); err != nil {
+			return nil, fmt.Errorf("failed to render the prompt policy message %d: %w", i, err)
+		}
+	}
+	raw, err := addPromptPolicyMessages(rp.eh, u.requestBodyRaw, u.promptPolicy.Messages, contents)
+	if err != nil || raw == nil {
+		return nil, err
+	}
+	_, parsed, _, _, err := rp.eh.ParseBody(raw, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the request with the prompt policy messages: %w", err)
+	}
+	u.requestBodyRaw, u.requestBody = raw, parsed
+	u.requestBodyMasked = true
+	return nil, nil
+}
+
+// hasClientSystemPrompt returns true if the chat completions, the messages or the responses request has the system or
+// developer messages of the client.
+func hasClientSystemPrompt(eh any, raw []byte) bool {
+	switch eh.(type) {
+	case endpointspec.ChatCompletionsEndpointSpec:
+		for _, m := range gjson.GetBytes(raw, "messages").Array() {
+			if isSystemRole(m.Get("role").String()) {
+				return true
+			}
+		}
+	case endpointspec.MessagesEndpointSpec:
+		system := gjson.GetBytes(raw, "system")
+		if system.IsArray() {
+			return len(system.Array()) > 0
+		}
+		return system.String() != ""
+	case endpointspec.ResponsesEndpointSpec:
+		if gjson.GetBytes(raw, "instructions").String() != "" {
+			return true
+		}
+		for _, item := range gjson.GetBytes(raw, "input").Array() {
+			if isSystemRole(item.Get("role").String()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addPromptPolicyMessages returns the request body with the messages of the prompt policy of the given contents, or
+// nil if the request of the endpoint has no system prompt. The prepended messages are added before the system and
+// developer messages of the client, and the appended ones after them:
+//
+//   - Chat completions: the messages are added to the "messages" array with their role.
+//   - Messages: the contents are added to the "system" field, as the text blocks if it is an array of them.
+//   - Responses: the contents are added to the "instructions" field.
+//
+// The contents added to a string field are separated from the rest of the field by the empty lines. There is no
+// Gemini endpoint, so the "systemInstruction" of the Gemini requests is translated from the chat completions.
+func addPromptPolicyMessages(eh any, raw []byte, messages []filterapi.PromptPolicyMessage, contents []string) ([]byte, error) {
+	// split returns the raw JSON values of the prepended and the appended messages made by the given function.
+	split := func(toJSON func(m filterapi.PromptPolicyMessage, content string) string) (prepend, appended []string) {
+		for i, m := range messages {
+			if m.Append {
+				appended = append(appended, toJSON(m, contents[i]))
+			} else {
+				prepend = append(prepend, toJSON(m, contents[i]))
+			}
+		}
+		return
+	}
+	// joined returns the string field with the contents added around its current value.
+	joined := func(current string) string {
+		prepend, appended := split(func(_ filterapi.PromptPolicyMessage, content string) string { return content })
+		if current != "" {
+			prepend = append(prepend, current)
+		}
+		return strings.Join(append(prepend, appended...), "\n\n")
+	}
+
+	switch eh.(type) {
+	case endpointspec.ChatCompletionsEndpointSpec:
+		prepend, appended := split(func(m filterapi.PromptPolicyMessage, content string) string {
+			role := "system"
+			if m.Developer {
+				role = "developer"
+			}
+			return `{"role":"` + role + `","content":` + jsonString(content) + `}`
+		})
+		existing := gjson.GetBytes(raw, "messages").Array()
+		n := 0
+		for n < len(existing) && isSystemRole(existing[n].Get("role").String()) {
+			n++
+		}
+		values := prepend
+		for _, m := range existing[:n] {
+			values = append(values, m.Raw)
+		}
+		values = append(values, appended...)
+		for _, m := range existing[n:] {
+			values = append(values, m.Raw)
+		}
+		return sjson.SetRawBytes(raw, "messages", []byte("["+strings.Join(values, ",")+"]"))
+	case endpointspec.MessagesEndpointSpec:
+		system := gjson.GetBytes(raw, "system")
+		if !system.IsArray() {
+			return sjson.SetBytes(raw, "system", joined(system.String()))
+		}
+		prepend, appended := split(func(_ filterapi.PromptPolicyMessage, content string) string {
+			return `{"type":"text","text":` + jsonString(content) + `}`
+		})
+		for _, block := range system.Array() {
+			prepend = append(prepend, block.Raw)
+		}
+		return sjson.SetRawBytes(raw, "system", []byte("["+strings.Join(append(prepend, appended...), ",")+"]"))
+	case endpointspec.ResponsesEndpointSpec:
+		return sjson.SetBytes(raw, "instructions", joined(gjson.GetBytes(raw, "instructions").String()))
+	}
+	return nil, nil
+}
+
+// isSystemRole returns true if the role is of the system or developer messages.
+func isSystemRole(role string) bool {
+	return role == "system" || role == "developer"
+}
+
+// jsonString returns the string as a JSON string value.
+func jsonString(s string) string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return `""`
+	}
+	return string(b)
+}
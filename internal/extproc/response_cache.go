@@ -0,0 +1,173 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strconv"
+
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/tidwall/gjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+	"github.com/envoyproxy/ai-gateway/internal/responsecache"
+)
+
+// lookupResponseCache looks up the response of the request with the given body sent to the backend in the response
+// cache, and returns the immediate response replaying it on a hit, or nil otherwise. On a miss, the key is kept so
+// that the response is stored once it completes. In the semantic mode, the cached response of the most similar request
+// is looked up when there is no identical one.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) lookupResponseCache(ctx context.Context, body []byte) *extprocv3.ProcessingResponse {
+	u.responseCacheKey, u.responseCacheBody = "", nil
+	u.responseCacheScope, u.responseCacheEmbedding = "", nil
+	noCache, noStore := responsecache.ParseCacheControl(u.requestHeaders["cache-control"])
+	if noStore {
+		u.setResponseCacheStatus(metrics.ResponseCacheBypass)
+		return nil
+	}
+	key := responsecache.Key(u.backendName, u.requestHeaders[":path"], body)
+	u.responseCacheKey = key
+	if noCache {
+		u.setResponseCacheStatus(metrics.ResponseCacheBypass)
+		if u.semanticCache != nil {
+			// The embedding is needed to add the refreshed response to the vector store.
+			u.embedLastUserMessage(ctx)
+		}
+		return nil
+	}
+
+	entry, err := responsecache.Get(ctx, u.responseCache, key)
+	if err != nil {
+		u.logger.Info("failed to look up the response cache", slog.String("backend", u.backendName), slog.String("error", err.Error()))
+	}
+	if entry == nil && u.semanticCache != nil {
+		entry = u.lookupSemanticCache(ctx)
+	}
+	if entry == nil {
+		u.setResponseCacheStatus(metrics.ResponseCacheMiss)
+		return nil
+	}
+
+	u.setResponseCacheStatus(metrics.ResponseCacheHit)
+	u.responseCacheKey = ""
+	u.responseCacheScope, u.responseCacheEmbedding = "", nil
+	u.responseCacheHit = true
+	if model := gjson.GetBytes(entry.Body, "model").String(); model != "" {
+		u.responseModel = model
+		u.metrics.SetResponseModel(model)
+	}
+	// No tokens are consumed on the backend.
+	var usage metrics.TokenUsage
+	usage.SetInputTokens(0)
+	usage.SetOutputTokens(0)
+	usage.SetTotalTokens(0)
+	u.metrics.RecordTokenUsage(ctx, usage, u.requestHeaders)
+	u.metrics.RecordRequestCompletion(ctx, true, u.requestHeaders)
+	u.recordResponseCacheSavedCosts(ctx, entry.Usage)
+
+	headerMutation := &extprocv3.HeaderMutation{}
+	setHeader(headerMutation, "content-type", entry.ContentType)
+	setHeader(headerMutation, "content-length", strconv.Itoa(len(entry.Body)))
+	return &extprocv3.ProcessingResponse{
+		Response: &extprocv3.ProcessingResponse_ImmediateResponse{
+			ImmediateResponse: &extprocv3.ImmediateResponse{
+				Status:  &typev3.HttpStatus{Code: typev3.StatusCode_OK},
+				Headers: headerMutation,
+				Body:    entry.Body,
+			},
+		},
+	}
+}
+
+// setResponseCacheStatus sets the outcome of the lookup of the response cache on the metrics.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) setResponseCacheStatus(status metrics.ResponseCacheStatus) {
+	if m, ok := u.metrics.(metrics.ResponseCacheMetrics); ok {
+		m.SetResponseCacheStatus(status)
+	}
+}
+
+// recordResponseCacheSavedCosts records the costs of the cached response served on a cache hit, calculated by the
+// costs of the route from the token usage of the response.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) recordResponseCacheSavedCosts(ctx context.Context, cached *responsecache.Usage) {
+	m, ok := u.metrics.(metrics.ResponseCacheMetrics)
+	config := u.parent.config
+	if !ok || cached == nil || (len(config.GlobalRequestCosts) == 0 && len(config.RequestCosts) == 0) {
+		return
+	}
+	var usage metrics.TokenUsage
+	usage.SetInputTokens(cached.InputTokens)
+	usage.SetCachedInputTokens(cached.CachedInputTokens)
+	usage.SetCacheCreationInputTokens(cached.CacheCreationInputTokens)
+	usage.SetOutputTokens(cached.OutputTokens)
+	usage.SetReasoningTokens(cached.ReasoningTokens)
+	usage.SetTotalTokens(cached.TotalTokens)
+	costs, err := evalRequestCosts(config.GlobalRequestCosts, config.RequestCosts, &usage, u.requestHeaders, u.backendName, u.routeName)
+	if err != nil {
+		u.logger.Info("failed to calculate the costs saved by the response cache", slog.String("backend", u.backendName), slog.String("error", err.Error()))
+		return
+	}
+	for key, cost := range costs {
+		m.RecordResponseCacheSavedCost(ctx, key, float64(cost), u.requestHeaders)
+	}
+}
+
+// bufferResponseCache buffers the chunk of the response returned to the client, and stores the response in the
+// response cache at the end of the stream if it is complete.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) bufferResponseCache(ctx context.Context, chunk []byte, endOfStream bool) {
+	u.responseCacheBody = append(u.responseCacheBody, chunk...)
+	if !endOfStream {
+		return
+	}
+	key, body := u.responseCacheKey, u.responseCacheBody
+	scope, embedding := u.responseCacheScope, u.responseCacheEmbedding
+	u.responseCacheKey, u.responseCacheBody = "", nil
+	u.responseCacheScope, u.responseCacheEmbedding = "", nil
+	contentType := "application/json"
+	if u.parent.stream {
+		if !streamCompleted(u.parent.eh, body) {
+			return
+		}
+		contentType = "text/event-stream"
+	}
+	entry := &responsecache.Entry{ContentType: contentType, Body: body, Usage: cacheUsage(&u.costs)}
+	if err := responsecache.Set(ctx, u.responseCache, key, entry, u.responseCacheTTL); err != nil {
+		u.logger.Info("failed to store the response in the response cache", slog.String("backend", u.backendName), slog.String("error", err.Error()))
+		return
+	}
+	if embedding != nil {
+		if err := u.responseCacheVectorStore.Add(ctx, scope, embedding, key, u.responseCacheTTL); err != nil {
+			u.logger.Info("failed to add the request to the response cache vector store", slog.String("backend", u.backendName), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// cacheUsage returns the token usage of the response stored in the response cache.
+func cacheUsage(costs *metrics.TokenUsage) *responsecache.Usage {
+	var usage responsecache.Usage
+	usage.InputTokens, _ = costs.InputTokens()
+	usage.CachedInputTokens, _ = costs.CachedInputTokens()
+	usage.CacheCreationInputTokens, _ = costs.CacheCreationInputTokens()
+	usage.OutputTokens, _ = costs.OutputTokens()
+	usage.ReasoningTokens, _ = costs.ReasoningTokens()
+	usage.TotalTokens, _ = costs.TotalTokens()
+	return &usage
+}
+
+// streamCompleted returns true if the streaming response of the endpoint is complete, i.e., it is not truncated,
+// e.g., by an error event. The streaming responses of the endpoints other than the chat completions and the
+// messages are never considered complete, so that they are not cached.
+func streamCompleted(eh any, body []byte) bool {
+	switch streamFailoverFormatOf(eh) {
+	case streamFailoverChatCompletions:
+		return bytes.Contains(body, []byte("data: [DONE]")) && !bytes.Contains(body, []byte(`data: {"error"`))
+	case streamFailoverMessages:
+		return bytes.Contains(body, []byte("event: message_stop"))
+	}
+	return false
+}
@@ -0,0 +1,311 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+	"github.com/envoyproxy/ai-gateway/internal/responsecache"
+)
+
+// mockResponseCacheMetrics implements [metrics.ResponseCacheMetrics] for testing.
+type mockResponseCacheMetrics struct {
+	mockMetrics
+	status       metrics.ResponseCacheStatus
+	similarities []float64
+	savedCosts   map[string]float64
+}
+
+// SetResponseCacheStatus implements [metrics.ResponseCacheMetrics].
+func (m *mockResponseCacheMetrics) SetResponseCacheStatus(status metrics.ResponseCacheStatus) {
+	m.status = status
+}
+
+// RecordResponseCacheSimilarity implements [metrics.ResponseCacheMetrics].
+func (m *mockResponseCacheMetrics) RecordResponseCacheSimilarity(_ context.Context, similarity float64, _ map[string]string) {
+	m.similarities = append(m.similarities, similarity)
+}
+
+// RecordResponseCacheSavedCost implements [metrics.ResponseCacheMetrics].
+func (m *mockResponseCacheMetrics) RecordResponseCacheSavedCost(_ context.Context, costKey string, cost float64, _ map[string]string) {
+	if m.savedCosts == nil {
+		m.savedCosts = make(map[string]float64)
+	}
+	m.savedCosts[costKey] += cost
+}
+
+func Test_chatCompletionProcessorUpstreamFilter_ResponseCache(t *testing.T) {
+	const (
+		requestBody  = `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`
+		responseBody = `{"model":"gpt-4o-2024-08-06","choices":[{"message":{"role":"assistant","content":"hello"}}],` +
+			`"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`
+	)
+	store := responsecache.NewMemoryStore(10)
+	backend := &filterapi.RuntimeBackend{
+		Backend: &filterapi.Backend{
+			Name:          "openai",
+			Schema:        filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+			ResponseCache: &filterapi.ResponseCache{TTL: time.Hour},
+		},
+		ResponseCache: store,
+	}
+	newFilters := func(t *testing.T, cacheControl string) (*chatCompletionProcessorRouterFilter, *chatCompletionProcessorUpstreamFilter, *mockResponseCacheMetrics) {
+		var parsed openai.ChatCompletionRequest
+		require.NoError(t, json.Unmarshal([]byte(requestBody), &parsed))
+		headers := map[string]string{":path": "/v1/chat/completions", ":method": "POST", "content-type": "application/json"}
+		if cacheControl != "" {
+			headers["cache-control"] = cacheControl
+		}
+		r := &chatCompletionProcessorRouterFilter{
+			eh:                     endpointspec.ChatCompletionsEndpointSpec{},
+			config:                 &filterapi.RuntimeConfig{},
+			logger:                 slog.Default(),
+			requestHeaders:         headers,
+			originalRequestBodyRaw: []byte(requestBody),
+			originalRequestBody:    &parsed,
+			originalModel:          "gpt-4o",
+		}
+		m := &mockResponseCacheMetrics{}
+		u := &chatCompletionProcessorUpstreamFilter{requestHeaders: maps.Clone(headers), metrics: m, logger: slog.Default()}
+		require.NoError(t, u.SetBackend(t.Context(), backend, "test-route", r))
+		return r, u, m
+	}
+	respond := func(t *testing.T, r *chatCompletionProcessorRouterFilter, status, body string) {
+		_, err := r.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{Headers: []*corev3.HeaderValue{
+			{Key: ":status", Value: status}, {Key: "content-type", Value: "application/json"},
+		}})
+		require.NoError(t, err)
+		_, err = r.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{Body: []byte(body), EndOfStream: true})
+		require.NoError(t, err)
+	}
+
+	// The error responses are not cached.
+	r, u, m := newFilters(t, "")
+	resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+	require.Nil(t, resp.GetImmediateResponse())
+	require.Equal(t, metrics.ResponseCacheMiss, m.status)
+	respond(t, r, "429", `{"error":{"message":"rate limited"}}`)
+
+	// The successful response is stored at the end of the stream.
+	r, u, m = newFilters(t, "")
+	resp, err = u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+	require.Nil(t, resp.GetImmediateResponse())
+	require.Equal(t, metrics.ResponseCacheMiss, m.status)
+	respond(t, r, "200", responseBody)
+	m.RequireTokensRecorded(t, 3, 0, 0, 2)
+
+	// The identical request is served from the cache without the tokens.
+	r, u, m = newFilters(t, "")
+	resp, err = u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+	ir := resp.GetImmediateResponse()
+	require.NotNil(t, ir)
+	require.Equal(t, typev3.StatusCode_OK, ir.Status.Code)
+	require.JSONEq(t, responseBody, string(ir.Body))
+	require.Equal(t, "application/json", headerValue(ir.Headers, "content-type"))
+	require.Equal(t, metrics.ResponseCacheHit, m.status)
+	m.RequireTokensRecorded(t, 0, 0, 0, 0)
+	m.RequireRequestSuccess(t)
+	require.Equal(t, "gpt-4o-2024-08-06", m.responseModel)
+	// The response is not processed again.
+	respond(t, r, "200", responseBody)
+	require.Equal(t, 1, m.requestSuccessCount)
+
+	// The request with no-cache is sent to the backend.
+	_, u, m = newFilters(t, "no-cache")
+	resp, err = u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+	require.Nil(t, resp.GetImmediateResponse())
+	require.Equal(t, metrics.ResponseCacheBypass, m.status)
+	require.NotEmpty(t, u.responseCacheKey)
+
+	// The response of the request with no-store is not stored.
+	_, u, m = newFilters(t, "no-store")
+	resp, err = u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+	require.Nil(t, resp.GetImmediateResponse())
+	require.Equal(t, metrics.ResponseCacheBypass, m.status)
+	require.Empty(t, u.responseCacheKey)
+}
+
+func Test_chatCompletionProcessorUpstreamFilter_SemanticResponseCache(t *testing.T) {
+	const responseBody = `{"model":"gpt-4o-2024-08-06","choices":[{"message":{"role":"assistant","content":"Paris"}}],` +
+		`"usage":{"prompt_tokens":7,"completion_tokens":1,"total_tokens":8}}`
+	embeddings := map[string][]float32{
+		"What is the capital of France?":   {1, 0},
+		"What's the capital of France?":    {0.99, 0.1},
+		"Tell me a joke about the capital": {0, 1},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "embeddings", r.Header.Get(internalapi.ShadowBackendHeader))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "text-embedding-3-small", gjson.GetBytes(body, "model").String())
+		embedding, ok := embeddings[gjson.GetBytes(body, "input").String()]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		resp, _ := json.Marshal(map[string]any{"object": "list", "data": []any{map[string]any{"object": "embedding", "index": 0, "embedding": embedding}}})
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write(resp)
+	}))
+	defer srv.Close()
+
+	backend := &filterapi.RuntimeBackend{
+		Backend: &filterapi.Backend{
+			Name:   "openai",
+			Schema: filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+			ResponseCache: &filterapi.ResponseCache{
+				TTL:      time.Hour,
+				Semantic: &filterapi.SemanticCache{EmbeddingsBackend: "embeddings", SimilarityThreshold: 0.95},
+			},
+		},
+		ResponseCache:            responsecache.NewMemoryStore(10),
+		ResponseCacheVectorStore: responsecache.NewMemoryVectorStore(10),
+	}
+	config := &filterapi.RuntimeConfig{
+		Backends: map[string]*filterapi.RuntimeBackend{"embeddings": {Backend: &filterapi.Backend{
+			Name:              "embeddings",
+			Schema:            filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Prefix: "v1"},
+			ModelNameOverride: "text-embedding-3-small",
+		}}},
+		RequestCosts: []filterapi.RuntimeRequestCost{
+			{LLMRequestCost: &filterapi.LLMRequestCost{MetadataKey: "total", Type: filterapi.LLMRequestCostTypeTotalToken, RouteName: "test-route"}},
+		},
+	}
+	newFilters := func(t *testing.T, requestBody string) (*chatCompletionProcessorRouterFilter, *chatCompletionProcessorUpstreamFilter, *mockResponseCacheMetrics) {
+		var parsed openai.ChatCompletionRequest
+		require.NoError(t, json.Unmarshal([]byte(requestBody), &parsed))
+		headers := map[string]string{":path": "/v1/chat/completions", ":method": "POST", "content-type": "application/json"}
+		r := &chatCompletionProcessorRouterFilter{
+			eh:                     endpointspec.ChatCompletionsEndpointSpec{},
+			config:                 config,
+			logger:                 slog.Default(),
+			requestHeaders:         headers,
+			originalRequestBodyRaw: []byte(requestBody),
+			originalRequestBody:    &parsed,
+			originalModel:          "gpt-4o",
+			shadower:               &shadower{client: srv.Client(), address: srv.URL, metrics: mockShadowMetricsFactory{&mockShadowMetrics{}}},
+		}
+		m := &mockResponseCacheMetrics{}
+		u := &chatCompletionProcessorUpstreamFilter{requestHeaders: maps.Clone(headers), metrics: m, logger: slog.Default()}
+		require.NoError(t, u.SetBackend(t.Context(), backend, "test-route", r))
+		return r, u, m
+	}
+	request := func(content string) string {
+		return `{"model":"gpt-4o","messages":[{"role":"user","content":"` + content + `"}]}`
+	}
+
+	// The first request is stored together with its embedding.
+	r, u, m := newFilters(t, request("What is the capital of France?"))
+	resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+	require.Nil(t, resp.GetImmediateResponse())
+	require.Equal(t, metrics.ResponseCacheMiss, m.status)
+	require.Empty(t, m.similarities)
+	_, err = r.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{Headers: []*corev3.HeaderValue{
+		{Key: ":status", Value: "200"}, {Key: "content-type", Value: "application/json"},
+	}})
+	require.NoError(t, err)
+	_, err = r.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{Body: []byte(responseBody), EndOfStream: true})
+	require.NoError(t, err)
+
+	// The similar request is served the cached response, saving its costs.
+	_, u, m = newFilters(t, request("What's the capital of France?"))
+	resp, err = u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+	ir := resp.GetImmediateResponse()
+	require.NotNil(t, ir)
+	require.JSONEq(t, responseBody, string(ir.Body))
+	require.Equal(t, metrics.ResponseCacheHit, m.status)
+	require.Len(t, m.similarities, 1)
+	require.InDelta(t, 0.995, m.similarities[0], 0.001)
+	require.Equal(t, map[string]float64{"total": 8}, m.savedCosts)
+
+	// The dissimilar request is sent to the backend.
+	_, u, m = newFilters(t, request("Tell me a joke about the capital"))
+	resp, err = u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+	require.Nil(t, resp.GetImmediateResponse())
+	require.Equal(t, metrics.ResponseCacheMiss, m.status)
+	require.Len(t, m.similarities, 1)
+	require.InDelta(t, 0, m.similarities[0], 0.001)
+	require.NotEmpty(t, u.responseCacheEmbedding)
+
+	// The request with a different history is in another scope.
+	_, u, m = newFilters(t, `{"model":"gpt-4o","messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"What's the capital of France?"}]}`)
+	resp, err = u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+	require.Nil(t, resp.GetImmediateResponse())
+	require.Equal(t, metrics.ResponseCacheMiss, m.status)
+	require.Empty(t, m.similarities)
+
+	// The request failing to be embedded falls back to the exact match.
+	_, u, m = newFilters(t, request("unknown"))
+	resp, err = u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+	require.Nil(t, resp.GetImmediateResponse())
+	require.Equal(t, metrics.ResponseCacheMiss, m.status)
+	require.Nil(t, u.responseCacheEmbedding)
+	require.NotEmpty(t, u.responseCacheKey)
+}
+
+func TestLastUserMessage(t *testing.T) {
+	index, text := lastUserMessage([]byte(`{"messages":[{"role":"user","content":"first"},{"role":"assistant","content":"ok"},` +
+		`{"role":"user","content":[{"type":"text","text":"second"},{"type":"image_url","image_url":{"url":"x"}},{"type":"text","text":"third"}]},` +
+		`{"role":"assistant","content":"ok"}]}`))
+	require.Equal(t, 2, index)
+	require.Equal(t, "second\nthird", text)
+	index, _ = lastUserMessage([]byte(`{"messages":[{"role":"system","content":"hi"}]}`))
+	require.Equal(t, -1, index)
+	index, _ = lastUserMessage([]byte(`{"input":"hi"}`))
+	require.Equal(t, -1, index)
+}
+
+func TestStreamCompleted(t *testing.T) {
+	chat := endpointspec.ChatCompletionsEndpointSpec{}
+	messages := endpointspec.MessagesEndpointSpec{}
+	require.True(t, streamCompleted(chat, []byte("data: {\"choices\":[]}\n\ndata: [DONE]\n\n")))
+	require.False(t, streamCompleted(chat, []byte("data: {\"choices\":[]}\n\n")))
+	require.False(t, streamCompleted(chat, []byte("data: {\"error\":{\"message\":\"overloaded\"}}\n\ndata: [DONE]\n\n")))
+	require.True(t, streamCompleted(messages, []byte("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")))
+	require.False(t, streamCompleted(messages, []byte("event: error\ndata: {\"type\":\"error\"}\n\n")))
+	require.False(t, streamCompleted(endpointspec.CompletionsEndpointSpec{}, []byte("data: [DONE]\n\n")))
+}
+
+// headerValue returns the value of the header set by the mutation.
+func headerValue(m *extprocv3.HeaderMutation, key string) string {
+	for _, h := range m.GetSetHeaders() {
+		if h.GetHeader().GetKey() == key {
+			return string(h.GetHeader().GetRawValue())
+		}
+	}
+	return ""
+}
@@ -0,0 +1,157 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+	"github.com/envoyproxy/ai-gateway/internal/responsecache"
+)
+
+// semanticCacheEmbeddingTimeout bounds the time of the embeddings request of the semantic mode of the response cache,
+// which delays the request on a cache miss.
+const semanticCacheEmbeddingTimeout = 5 * time.Second
+
+// lookupSemanticCache embeds the last user message of the request, and returns the cached response of the most
+// similar request of the same scope if their similarity reaches the threshold, or nil otherwise. The scope and the
+// embedding are kept so that they are added to the vector store once the response is stored.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) lookupSemanticCache(ctx context.Context) *responsecache.Entry {
+	if !u.embedLastUserMessage(ctx) {
+		return nil
+	}
+	key, similarity, ok, err := u.responseCacheVectorStore.Search(ctx, u.responseCacheScope, u.responseCacheEmbedding)
+	if err != nil {
+		u.logger.Info("failed to search the response cache vector store", slog.String("backend", u.backendName), slog.String("error", err.Error()))
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	if m, ok := u.metrics.(metrics.ResponseCacheMetrics); ok {
+		m.RecordResponseCacheSimilarity(ctx, similarity, u.requestHeaders)
+	}
+	if similarity < u.semanticCache.SimilarityThreshold {
+		return nil
+	}
+	entry, err := responsecache.Get(ctx, u.responseCache, key)
+	if err != nil {
+		u.logger.Info("failed to look up the response cache", slog.String("backend", u.backendName), slog.String("error", err.Error()))
+	}
+	return entry
+}
+
+// embedLastUserMessage sets the scope and the embedding of the last user message of the request, and returns false
+// if the request has no user message or it fails to be embedded.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) embedLastUserMessage(ctx context.Context) bool {
+	index, text := lastUserMessage(u.requestBodyRaw)
+	if index < 0 {
+		return false
+	}
+	embedding, err := u.embed(ctx, text)
+	if err != nil {
+		u.logger.Info("failed to embed the request for the response cache", slog.String("backend", u.backendName),
+			slog.String("embeddings_backend", u.semanticCache.EmbeddingsBackend), slog.String("error", err.Error()))
+		return false
+	}
+	u.responseCacheScope = semanticCacheScope(u.backendName, u.requestHeaders[":path"], u.requestBodyRaw, index)
+	u.responseCacheEmbedding = embedding
+	return true
+}
+
+// embed returns the embedding of the text computed by the embeddings backend of the semantic cache, to which the
+// request is sent through the shadow listener.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) embed(ctx context.Context, text string) ([]float32, error) {
+	rp := u.parent
+	if rp.shadower == nil {
+		return nil, errors.New("shadow listener is not configured")
+	}
+	backendName := u.semanticCache.EmbeddingsBackend
+	backend, ok := rp.config.Backends[backendName]
+	if !ok {
+		return nil, errors.New("backend not found")
+	}
+	model := backend.Backend.ModelNameOverride
+	raw, err := json.Marshal(map[string]string{"model": model, "input": text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embeddings request: %w", err)
+	}
+	eh := endpointspec.EmbeddingsEndpointSpec{}
+	_, parsed, _, _, err := eh.ParseBody(raw, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse embeddings request: %w", err)
+	}
+	headers := map[string]string{":method": "POST", ":path": "/v1/embeddings", "content-type": "application/json"}
+	req, err := translateListenerRequest[openai.EmbeddingRequest, openai.EmbeddingResponse, struct{}](eh, backend, backendName,
+		headers, rp.requestHeaders, model, raw, parsed, u.logger)
+	if err != nil {
+		return nil, err
+	}
+	// The embeddings request is not recorded in the metrics.
+	req.metrics = rp.shadower.metrics.NewMetrics()
+
+	ctx, cancel := context.WithTimeout(ctx, semanticCacheEmbeddingTimeout)
+	defer cancel()
+	body, _, err := req.do(ctx, rp.shadower, false)
+	if err != nil {
+		return nil, err
+	}
+	values := gjson.GetBytes(body, "data.0.embedding").Array()
+	if len(values) == 0 {
+		return nil, errors.New("embeddings response has no embedding")
+	}
+	embedding := make([]float32, len(values))
+	for i, v := range values {
+		embedding[i] = float32(v.Float())
+	}
+	return embedding, nil
+}
+
+// lastUserMessage returns the index and the text of the last user message of the chat completions or the messages
+// request, or -1 if it has none. The text parts of the message content are joined with newlines.
+func lastUserMessage(raw []byte) (int, string) {
+	messages := gjson.GetBytes(raw, "messages").Array()
+	for i := len(messages) - 1; i >= 0; i-- {
+		m := messages[i]
+		if m.Get("role").String() != "user" {
+			continue
+		}
+		content := m.Get("content")
+		if !content.IsArray() {
+			return i, content.String()
+		}
+		var parts []string
+		for _, p := range content.Array() {
+			if p.Get("type").String() == "text" {
+				parts = append(parts, p.Get("text").String())
+			}
+		}
+		return i, strings.Join(parts, "\n")
+	}
+	return -1, ""
+}
+
+// semanticCacheScope returns the scope of the request in the vector store, which is the key of the request without
+// the embedded message, so that only the requests to the same backend with the same parameters and history share it.
+func semanticCacheScope(backendName, path string, raw []byte, index int) string {
+	body, err := sjson.DeleteBytes(raw, "messages."+strconv.Itoa(index))
+	if err != nil {
+		body = raw
+	}
+	return responsecache.Key(backendName, path, body)
+}
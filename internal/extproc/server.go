@@ -22,13 +22,18 @@ import (
 	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/structpb"
 
+	"github.com/envoyproxy/ai-gateway/internal/audit"
 	"github.com/envoyproxy/ai-gateway/internal/backendauth"
+	"github.com/envoyproxy/ai-gateway/internal/backendselection"
+	"github.com/envoyproxy/ai-gateway/internal/circuitbreaker"
 	"github.com/envoyproxy/ai-gateway/internal/filterapi"
 	"github.com/envoyproxy/ai-gateway/internal/internalapi"
 	"github.com/envoyproxy/ai-gateway/internal/redaction"
+	"github.com/envoyproxy/ai-gateway/internal/responsecache"
 )
 
 var (
@@ -51,6 +56,20 @@ func loggerFromContext(ctx context.Context) *slog.Logger {
 	return nil
 }
 
+// responseSenderContextKey is the context key for the function sending a response on the stream of the message
+// being processed. See sendResponse.
+const responseSenderContextKey contextKey = "responseSender"
+
+// sendResponse sends the response on the stream of the message being processed ahead of the one returned for the
+// message, e.g., a chunk of the response body streamed in the FULL_DUPLEX_STREAMED mode. This is a no-op if the
+// context has no sender, e.g., in tests.
+func sendResponse(ctx context.Context, resp *extprocv3.ProcessingResponse) error {
+	if send, ok := ctx.Value(responseSenderContextKey).(func(*extprocv3.ProcessingResponse) error); ok {
+		return send(resp)
+	}
+	return nil
+}
+
 // Server implements the external processor server.
 type Server struct {
 	logger                        *slog.Logger
@@ -61,6 +80,11 @@ type Server struct {
 	routerProcessorsPerReqID      map[string]Processor
 	routerProcessorsPerReqIDMutex sync.RWMutex
 	uuidFn                        func() string
+	circuitBreakers               *circuitbreaker.Registry
+	latencies                     *backendselection.Registry
+	responseCache                 responsecache.Store
+	responseCacheVectorStore      responsecache.VectorStore
+	auditLogger                   *audit.Logger
 }
 
 // NewServer creates a new external processor server.
@@ -73,6 +97,10 @@ func NewServer(logger *slog.Logger, enableRedaction bool) (*Server, error) {
 		processorFactories:       make(map[string]ProcessorFactory),
 		routerProcessorsPerReqID: make(map[string]Processor),
 		uuidFn:                   uuid.NewString,
+		circuitBreakers:          circuitbreaker.NewRegistry(),
+		latencies:                backendselection.NewRegistry(),
+		responseCache:            responsecache.NewMemoryStore(responsecache.DefaultMaxEntries),
+		responseCacheVectorStore: responsecache.NewMemoryVectorStore(responsecache.DefaultMaxEntries),
 	}
 	return srv, nil
 }
@@ -83,10 +111,70 @@ func (s *Server) LoadConfig(ctx context.Context, config *filterapi.Config) error
 	if err != nil {
 		return fmt.Errorf("cannot create runtime filter config: %w", err)
 	}
+	// The circuit breakers are kept across the configuration updates so that the state of the backends is not lost.
+	configs := make(map[string]circuitbreaker.Config)
+	for name, b := range newConfig.Backends {
+		if cb := b.Backend.CircuitBreaker; cb != nil {
+			configs[name] = circuitbreaker.Config{
+				ConsecutiveFailures: cb.ConsecutiveFailures,
+				TimeToFirstToken:    cb.TimeToFirstToken,
+				OpenDuration:        cb.OpenDuration,
+			}
+		}
+	}
+	for name, breaker := range s.circuitBreakers.Update(configs) {
+		newConfig.Backends[name].CircuitBreaker = breaker
+	}
+	// Likewise, the latencies of the candidates of the backend selections are kept across the configuration updates.
+	var candidates []string
+	for i := range newConfig.BackendSelections {
+		for _, c := range newConfig.BackendSelections[i].Candidates {
+			if _, ok := newConfig.Backends[c.Backend]; ok {
+				candidates = append(candidates, c.Backend)
+			}
+		}
+	}
+	for name, latency := range s.latencies.Update(candidates) {
+		newConfig.Backends[name].Latency = latency
+	}
+	// The store of the response cache is shared by all the backends whose route rule has the response cache. The
+	// entries are keyed on the backend, so that they do not conflict.
+	for _, b := range newConfig.Backends {
+		if rc := b.Backend.ResponseCache; rc != nil {
+			b.ResponseCache = s.responseCache
+			if rc.Semantic != nil {
+				b.ResponseCacheVectorStore = s.responseCacheVectorStore
+			}
+		}
+	}
+	newConfig.AuditLogger = s.auditLogger
 	s.config = newConfig // This is racey, but we don't care.
 	return nil
 }
 
+// CircuitBreakers returns the registry of the circuit breakers of the backends.
+func (s *Server) CircuitBreakers() *circuitbreaker.Registry {
+	return s.circuitBreakers
+}
+
+// SetResponseCacheStore sets the store of the response cache, which defaults to the in-memory store. This must be
+// called before the configuration is loaded.
+func (s *Server) SetResponseCacheStore(store responsecache.Store) {
+	s.responseCache = store
+}
+
+// SetResponseCacheVectorStore sets the vector store of the semantic mode of the response cache, which defaults to the
+// in-memory store. This must be called before the configuration is loaded.
+func (s *Server) SetResponseCacheVectorStore(store responsecache.VectorStore) {
+	s.responseCacheVectorStore = store
+}
+
+// SetAuditLogger sets the logger of the audit records of the requests, which are not audited by default. This must be
+// called before the configuration is loaded.
+func (s *Server) SetAuditLogger(logger *audit.Logger) {
+	s.auditLogger = logger
+}
+
 // Register a new processor for the given request path.
 func (s *Server) Register(path string, newProcessor ProcessorFactory) {
 	s.logger.Info("Registering processor", slog.String("path", path))
@@ -145,6 +233,11 @@ func (s *Server) Process(stream extprocv3.ExternalProcessor_ProcessServer) error
 	var logger *slog.Logger
 	// Seed the context with the server-level logger as a fallback so that loggerFromContext never returns nil in processMsg.
 	ctx = context.WithValue(ctx, loggerContextKey, s.logger)
+	// The messages are processed one at a time, so the stream is not sent concurrently while a message is processed.
+	ctx = context.WithValue(ctx, responseSenderContextKey, stream.Send)
+	// The stream failover filter is an upstream filter without the request attributes, which sets the gRPC metadata.
+	md, _ := metadata.FromIncomingContext(ctx)
+	isStreamFailoverFilter := len(md.Get(internalapi.StreamFailoverFilterMetadataKey)) > 0
 	defer func() {
 		if !isUpstreamFilter {
 			s.routerProcessorsPerReqIDMutex.Lock()
@@ -177,7 +270,7 @@ func (s *Server) Process(stream extprocv3.ExternalProcessor_ProcessServer) error
 			headersMap := headersToMap(headers)
 			originalReqID = headersMap["x-request-id"]
 			// Assume that when attributes are set, this stream is for the upstream filter level.
-			isUpstreamFilter = req.GetAttributes() != nil
+			isUpstreamFilter = req.GetAttributes() != nil || isStreamFailoverFilter
 
 			if isUpstreamFilter {
 				// For upstream filter, use the internal request ID passed from the router filter
@@ -199,7 +292,11 @@ func (s *Server) Process(stream extprocv3.ExternalProcessor_ProcessServer) error
 			// Add logger to context so processMsg can access it
 			ctx = context.WithValue(ctx, loggerContextKey, logger)
 
-			p, err = s.processorForPath(headersMap, isUpstreamFilter, logger)
+			if isStreamFailoverFilter {
+				p = s.newStreamFailoverFilter(internalReqID, logger)
+			} else {
+				p, err = s.processorForPath(headersMap, isUpstreamFilter, logger)
+			}
 			if err != nil {
 				if errors.Is(err, errNoProcessor) {
 					path := headersMap[":path"]
@@ -218,12 +315,15 @@ func (s *Server) Process(stream extprocv3.ExternalProcessor_ProcessServer) error
 				return status.Error(codes.NotFound, err.Error())
 			}
 			_, isEndpoinPicker := headersMap[internalapi.EndpointPickerHeaderKey]
-			if isUpstreamFilter {
+			switch {
+			case isStreamFailoverFilter:
+				// The stream failover filter is not bound to a backend.
+			case isUpstreamFilter:
 				if err = s.setBackend(ctx, p, internalReqID, isEndpoinPicker, req); err != nil {
 					s.logger.Error("error processing request message", slog.String("error", err.Error()))
 					return status.Errorf(codes.Unknown, "error processing request message: %v", err)
 				}
-			} else {
+			default:
 				s.routerProcessorsPerReqIDMutex.Lock()
 				s.routerProcessorsPerReqID[internalReqID] = p
 				s.routerProcessorsPerReqIDMutex.Unlock()
@@ -328,7 +428,13 @@ func (s *Server) processMsg(ctx context.Context, p Processor, req *extprocv3.Pro
 		if s.debugLogEnabled {
 			l.Debug("response headers processing", slog.Any("response_headers", responseHdrs))
 		}
-		resp, err := p.ProcessResponseHeaders(ctx, responseHdrs)
+		var resp *extprocv3.ProcessingResponse
+		var err error
+		if up, ok := p.(upstreamResponseProcessor); ok && isUpstreamFilter {
+			resp, err = up.ProcessUpstreamResponseHeaders(ctx, responseHdrs)
+		} else {
+			resp, err = p.ProcessResponseHeaders(ctx, responseHdrs)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("cannot process response headers: %w", err)
 		}
@@ -340,7 +446,13 @@ func (s *Server) processMsg(ctx context.Context, p Processor, req *extprocv3.Pro
 		if s.debugLogEnabled && !s.enableRedaction {
 			l.Debug("response body processing", slog.Any("request", req))
 		}
-		resp, err := p.ProcessResponseBody(ctx, value.ResponseBody)
+		var resp *extprocv3.ProcessingResponse
+		var err error
+		if up, ok := p.(upstreamResponseProcessor); ok && isUpstreamFilter {
+			resp, err = up.ProcessUpstreamResponseBody(ctx, value.ResponseBody)
+		} else {
+			resp, err = p.ProcessResponseBody(ctx, value.ResponseBody)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("cannot process response body: %w", err)
 		}
@@ -362,12 +474,33 @@ func (s *Server) processMsg(ctx context.Context, p Processor, req *extprocv3.Pro
 		}
 
 		return resp, nil
+	case *extprocv3.ProcessingRequest_ResponseTrailers:
+		// The response trailers are only sent to the stream failover filter.
+		if tp, ok := p.(responseTrailersProcessor); ok {
+			resp, err := tp.ProcessResponseTrailers(ctx, value.ResponseTrailers.Trailers)
+			if err != nil {
+				return nil, fmt.Errorf("cannot process response trailers: %w", err)
+			}
+			return resp, nil
+		}
+		return &extprocv3.ProcessingResponse{Response: &extprocv3.ProcessingResponse_ResponseTrailers{
+			ResponseTrailers: &extprocv3.TrailersResponse{},
+		}}, nil
 	default:
 		l.Error("unknown request type", slog.Any("request", value))
 		return nil, fmt.Errorf("unknown request type: %T", value)
 	}
 }
 
+// newStreamFailoverFilter creates the processor of the stream failover filter of the request with the given internal
+// request ID, which passes the response through if the router filter level processor of the request is not found.
+func (s *Server) newStreamFailoverFilter(internalReqID string, logger *slog.Logger) Processor {
+	s.routerProcessorsPerReqIDMutex.RLock()
+	defer s.routerProcessorsPerReqIDMutex.RUnlock()
+	router, _ := s.routerProcessorsPerReqID[internalReqID].(streamContinuer)
+	return &streamFailoverFilter{router: router, logger: logger}
+}
+
 // setBackend retrieves the backend from the request attributes and sets it in the processor. This is only called
 // if the processor is an upstream filter.
 func (s *Server) setBackend(ctx context.Context, p Processor, internalReqID string, isEndpointPicker bool, req *extprocv3.ProcessingRequest) error {
@@ -395,6 +528,11 @@ func (s *Server) setBackend(ctx context.Context, p Processor, internalReqID stri
 			internalReqID, backendName)
 	}
 
+	if ps, ok := p.(endpointPrioritySetter); ok {
+		if priority, ok := attributes.Fields[internalapi.XDSUpstreamHostMetadataPriorityPath]; ok {
+			ps.SetEndpointPriority(uint32(priority.GetNumberValue()))
+		}
+	}
 	if err := p.SetBackend(ctx, backend, routeName, routerProcessor); err != nil {
 		return status.Errorf(codes.Internal, "cannot set backend: %v", err)
 	}
@@ -23,6 +23,7 @@ import (
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/structpb"
 
+	"github.com/envoyproxy/ai-gateway/internal/circuitbreaker"
 	"github.com/envoyproxy/ai-gateway/internal/filterapi"
 	"github.com/envoyproxy/ai-gateway/internal/internalapi"
 	internaltesting "github.com/envoyproxy/ai-gateway/internal/testing"
@@ -46,12 +47,55 @@ func requireNewServerWithMockProcessor(t *testing.T) (*Server, *mockProcessor) {
 
 func TestServer_LoadConfig(t *testing.T) {
 	config := &filterapi.Config{}
-	s := &Server{}
-	err := s.LoadConfig(t.Context(), config)
+	s, err := NewServer(slog.Default(), false)
+	require.NoError(t, err)
+	err = s.LoadConfig(t.Context(), config)
 	require.NoError(t, err)
 	require.NotNil(t, s.config)
 }
 
+func TestServer_LoadConfig_CircuitBreakers(t *testing.T) {
+	s, err := NewServer(slog.Default(), false)
+	require.NoError(t, err)
+	config := &filterapi.Config{Backends: []filterapi.Backend{
+		{Name: "a", CircuitBreaker: &filterapi.CircuitBreaker{ConsecutiveFailures: 1, OpenDuration: time.Minute}},
+		{Name: "b"},
+	}}
+	require.NoError(t, s.LoadConfig(t.Context(), config))
+	breaker := s.config.Backends["a"].CircuitBreaker
+	require.NotNil(t, breaker)
+	require.Nil(t, s.config.Backends["b"].CircuitBreaker)
+	breaker.RecordFailure()
+
+	// The state is kept across the configuration updates.
+	require.NoError(t, s.LoadConfig(t.Context(), config))
+	require.Same(t, breaker, s.config.Backends["a"].CircuitBreaker)
+	require.Equal(t, []circuitbreaker.Status{
+		{Backend: "a", State: circuitbreaker.StateOpen, OpenedAt: breaker.Status().OpenedAt},
+	}, s.CircuitBreakers().Statuses())
+}
+
+func TestServer_LoadConfig_BackendSelectionLatencies(t *testing.T) {
+	s, err := NewServer(slog.Default(), false)
+	require.NoError(t, err)
+	config := &filterapi.Config{
+		Backends: []filterapi.Backend{{Name: "a"}, {Name: "b"}},
+		BackendSelections: []filterapi.BackendSelection{{
+			HeaderName:    "x-ai-eg-backend-selection-1",
+			LatencyWeight: 1,
+			Candidates:    []filterapi.BackendSelectionCandidate{{Backend: "a"}},
+		}},
+	}
+	require.NoError(t, s.LoadConfig(t.Context(), config))
+	latency := s.config.Backends["a"].Latency
+	require.NotNil(t, latency)
+	require.Nil(t, s.config.Backends["b"].Latency)
+
+	// The latency is kept across the configuration updates.
+	require.NoError(t, s.LoadConfig(t.Context(), config))
+	require.Same(t, latency, s.config.Backends["a"].Latency)
+}
+
 func TestServer_Check(t *testing.T) {
 	s, _ := requireNewServerWithMockProcessor(t)
 
@@ -195,6 +239,31 @@ func TestServer_processMsg(t *testing.T) {
 		require.NotNil(t, resp)
 		require.Equal(t, expResponse, resp)
 	})
+	t.Run("upstream response headers and body", func(t *testing.T) {
+		s, _ := requireNewServerWithMockProcessor(t)
+		ctx := context.WithValue(t.Context(), loggerContextKey, slog.Default())
+
+		hm := &corev3.HeaderMap{Headers: []*corev3.HeaderValue{{Key: ":status", Value: "429"}}}
+		body := &extprocv3.HttpBody{Body: []byte("rate limited"), EndOfStream: true}
+		upstreamResponse := &extprocv3.ProcessingResponse{Response: &extprocv3.ProcessingResponse_ResponseBody{}}
+		routerResponse := &extprocv3.ProcessingResponse{Response: &extprocv3.ProcessingResponse_ResponseHeaders{}}
+		p := &mockUpstreamResponseProcessor{
+			mockProcessor:                 mockProcessor{t: t, expHeaderMap: hm, expBody: body, retProcessingResponse: routerResponse},
+			retUpstreamProcessingResponse: upstreamResponse,
+		}
+		for _, req := range []*extprocv3.ProcessingRequest{
+			{Request: &extprocv3.ProcessingRequest_ResponseHeaders{ResponseHeaders: &extprocv3.HttpHeaders{Headers: hm}}},
+			{Request: &extprocv3.ProcessingRequest_ResponseBody{ResponseBody: body}},
+		} {
+			resp, err := s.processMsg(ctx, p, req, "test-req-id", true)
+			require.NoError(t, err)
+			require.Equal(t, upstreamResponse, resp)
+			// The router filter level processor still processes the final response as usual.
+			resp, err = s.processMsg(ctx, p, req, "test-req-id", false)
+			require.NoError(t, err)
+			require.Equal(t, routerResponse, resp)
+		}
+	})
 	t.Run("error response headers", func(t *testing.T) {
 		s, p := requireNewServerWithMockProcessor(t)
 		ctx := context.WithValue(t.Context(), loggerContextKey, slog.Default())
@@ -229,6 +298,29 @@ func TestServer_processMsg(t *testing.T) {
 		require.NotNil(t, resp)
 		require.Equal(t, expResponse, resp)
 	})
+	t.Run("response trailers", func(t *testing.T) {
+		s, p := requireNewServerWithMockProcessor(t)
+		ctx := context.WithValue(t.Context(), loggerContextKey, slog.Default())
+		req := &extprocv3.ProcessingRequest{
+			Request: &extprocv3.ProcessingRequest_ResponseTrailers{ResponseTrailers: &extprocv3.HttpTrailers{}},
+		}
+		resp, err := s.processMsg(ctx, p, req, "test-req-id", true)
+		require.NoError(t, err)
+		require.NotNil(t, resp.GetResponseTrailers())
+
+		resp, err = s.processMsg(ctx, &streamFailoverFilter{logger: slog.Default()}, req, "test-req-id", true)
+		require.NoError(t, err)
+		require.NotNil(t, resp.GetResponseTrailers())
+	})
+}
+
+func TestServer_newStreamFailoverFilter(t *testing.T) {
+	s, _ := requireNewServerWithMockProcessor(t)
+	r := &chatCompletionProcessorRouterFilter{}
+	s.routerProcessorsPerReqID["req-id"] = r
+	require.Equal(t, streamContinuer(r), s.newStreamFailoverFilter("req-id", slog.Default()).(*streamFailoverFilter).router)
+	// The response passes through if the router filter level processor is not found.
+	require.Nil(t, s.newStreamFailoverFilter("other", slog.Default()).(*streamFailoverFilter).router)
 }
 
 func TestServer_Process(t *testing.T) {
@@ -340,6 +432,34 @@ func TestServer_setBackend(t *testing.T) {
 			require.ErrorContains(t, err, `no router processor found, request_id=aaaaaaaaaaaa, backend=openai`)
 		})
 	}
+
+	t.Run("priority", func(t *testing.T) {
+		s.routerProcessorsPerReqID["bbbbbbbbbbbb"] = &mockProcessor{}
+		t.Cleanup(func() { delete(s.routerProcessorsPerReqID, "bbbbbbbbbbbb") })
+		proc := &priorityRecordingProcessor{}
+		err := s.setBackend(t.Context(), proc, "bbbbbbbbbbbb", false, &extprocv3.ProcessingRequest{
+			Attributes: map[string]*structpb.Struct{
+				"envoy.filters.http.ext_proc": {Fields: map[string]*structpb.Value{
+					internalapi.XDSUpstreamHostMetadataBackendNamePath: structpb.NewStringValue("openai"),
+					internalapi.XDSUpstreamHostMetadataPriorityPath:    structpb.NewNumberValue(2),
+				}},
+			},
+			Request: &extprocv3.ProcessingRequest_RequestHeaders{RequestHeaders: &extprocv3.HttpHeaders{}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []uint32{2}, proc.priorities)
+	})
+}
+
+// priorityRecordingProcessor is a mockProcessor recording the priorities of the endpoints set by the server.
+type priorityRecordingProcessor struct {
+	mockProcessor
+	priorities []uint32
+}
+
+// SetEndpointPriority implements [endpointPrioritySetter.SetEndpointPriority].
+func (p *priorityRecordingProcessor) SetEndpointPriority(priority uint32) {
+	p.priorities = append(p.priorities, priority)
 }
 
 func TestResolveBackendName(t *testing.T) {
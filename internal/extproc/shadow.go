@@ -0,0 +1,484 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+
+	"github.com/envoyproxy/ai-gateway/internal/bodymutator"
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/errorclass"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/headermutator"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+	"github.com/envoyproxy/ai-gateway/internal/tracing/tracingapi"
+	"github.com/envoyproxy/ai-gateway/internal/translator"
+)
+
+const (
+	// shadowRequestTimeout bounds the time of a copy of the request sent to a shadow backend including its response.
+	shadowRequestTimeout = 5 * time.Minute
+	// shadowCompareTimeout bounds the time a shadow waits for the response returned to the client to compare them.
+	shadowCompareTimeout = 5 * time.Minute
+	// maxInFlightShadowRequests bounds the number of the copies of the requests in flight to the shadow backends, above
+	// which the copies are dropped so that a slow shadow backend does not pile up the goroutines and the memory.
+	maxInFlightShadowRequests = 256
+)
+
+// shadower sends the copies of the requests to the shadow backends through the shadow listener of Envoy, which
+// routes them to the backends without any other filter since they are already translated and authenticated.
+type shadower struct {
+	client *http.Client
+	// address is the base URL of the shadow listener.
+	address string
+	metrics metrics.Factory
+	// inFlight holds a token per copy of the request in flight to the shadow backends.
+	inFlight chan struct{}
+}
+
+// newShadower creates a new shadower sending the copies of the requests to the shadow listener on localhost.
+func newShadower(f metrics.Factory) *shadower {
+	return &shadower{
+		client:   &http.Client{},
+		address:  fmt.Sprintf("http://127.0.0.1:%d", internalapi.ShadowListenerPort),
+		metrics:  f,
+		inFlight: make(chan struct{}, maxInFlightShadowRequests),
+	}
+}
+
+// tryAcquire reserves a slot for a copy of the request, returning false if too many copies are in flight.
+func (s *shadower) tryAcquire() bool {
+	select {
+	case s.inFlight <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees the slot reserved by tryAcquire.
+func (s *shadower) release() {
+	<-s.inFlight
+}
+
+// primaryResponse is the response body returned to the client, which is published by the router filter for the
+// shadows comparing their responses to it.
+type primaryResponse struct {
+	buf bytes.Buffer
+	// body is the response body, or nil if the response failed. This is set before done is closed.
+	body []byte
+	done chan struct{}
+}
+
+// shadowRequest is a request translated for a backend to which it is sent through the shadow listener, i.e., either a
+// copy of the request for a shadow backend or the continuation of a truncated stream for the stream failover backend.
+type shadowRequest[ReqT, RespT, RespChunkT any] struct {
+	// shadow is the shadow that the request is sent to, or zero for a continuation.
+	shadow     filterapi.Shadow
+	backend    *filterapi.RuntimeBackend
+	translator translator.Translator[ReqT, tracingapi.Span[RespT, RespChunkT]]
+	// headers are the headers of the request seen by the translator and the auth handler.
+	headers map[string]string
+	// sendHeaders are the headers sent to the backend.
+	sendHeaders map[string]string
+	body        []byte
+	metrics     metrics.Metrics
+}
+
+// maybeStartShadows sends the copies of the request to the shadow backends of the backend if any, which is done only
+// once per request regardless of the retries and the hedged requests. The copies are translated here and sent
+// asynchronously so that they do not delay the request to the backend.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) maybeStartShadows(ctx context.Context) {
+	rp := u.parent
+	if len(u.shadows) == 0 || rp.shadower == nil {
+		return
+	}
+	rp.mu.Lock()
+	if rp.shadowsStarted {
+		rp.mu.Unlock()
+		return
+	}
+	rp.shadowsStarted = true
+	rp.mu.Unlock()
+
+	for _, shadow := range u.shadows {
+		if shadow.Percent < 100 && rand.IntN(100) >= shadow.Percent { // #nosec G404
+			continue
+		}
+		if !rp.shadower.tryAcquire() {
+			u.logger.Debug("dropping the request to the shadow backend since too many are in flight",
+				slog.String("shadow", shadow.Backend))
+			u.recordShadowDropped(ctx, shadow)
+			continue
+		}
+		req, err := u.newShadowRequest(shadow)
+		if err != nil {
+			rp.shadower.release()
+			u.logger.Info("failed to prepare the request to the shadow backend",
+				slog.String("shadow", shadow.Backend), slog.String("error", err.Error()))
+			continue
+		}
+		var primary *primaryResponse
+		if shadow.CompareResponses && !rp.stream {
+			primary = rp.comparedResponse()
+		}
+		go func() {
+			defer rp.shadower.release()
+			req.send(context.WithoutCancel(ctx), rp.shadower, rp.stream, primary, u.logger)
+		}()
+	}
+}
+
+// recordShadowDropped records the copy of the request that is not sent to the shadow backend.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) recordShadowDropped(ctx context.Context, shadow filterapi.Shadow) {
+	rp := u.parent
+	m := rp.shadower.metrics.NewMetrics()
+	sm, ok := m.(metrics.ShadowMetrics)
+	if !ok {
+		return
+	}
+	sm.SetShadow(shadow.Backend)
+	m.SetOriginalModel(rp.originalModel)
+	if backend, ok := rp.config.Backends[shadow.Backend]; ok {
+		m.SetBackend(backend.Backend)
+	}
+	sm.RecordShadowDropped(ctx, rp.requestHeaders)
+}
+
+// newShadowRequest translates the original request for the shadow backend.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) newShadowRequest(shadow filterapi.Shadow) (*shadowRequest[ReqT, RespT, RespChunkT], error) {
+	req, err := u.newListenerRequest(shadow.Backend, u.requestBodyRaw, u.requestBody)
+	if err != nil {
+		return nil, err
+	}
+	req.shadow = shadow
+	if sm, ok := req.metrics.(metrics.ShadowMetrics); ok {
+		sm.SetShadow(shadow.Backend)
+	}
+	return req, nil
+}
+
+// newListenerRequest translates the given request for the backend of the given name, to which it is sent through the
+// shadow listener.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) newListenerRequest(backendName string, raw []byte, parsed *ReqT) (*shadowRequest[ReqT, RespT, RespChunkT], error) {
+	rp := u.parent
+	backend, ok := rp.config.Backends[backendName]
+	if !ok {
+		return nil, errors.New("backend not found")
+	}
+	modelNameOverride := cmp.Or(backend.Backend.ModelNameOverride, rp.resolvedModel)
+	req, err := translateListenerRequest[ReqT, RespT, RespChunkT](rp.eh, backend, backendName, maps.Clone(rp.requestHeaders),
+		rp.requestHeaders, modelNameOverride, raw, parsed, u.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	m := rp.shadower.metrics.NewMetrics()
+	m.SetOriginalModel(rp.originalModel)
+	m.SetRequestModel(cmp.Or(modelNameOverride, rp.originalModel))
+	m.SetBackend(backend.Backend)
+	req.metrics = m
+	return req, nil
+}
+
+// translateListenerRequest translates the given request of the endpoint with the given headers for the backend of the
+// given name, to which it is sent through the shadow listener. The header mutation of the backend is applied based on
+// the original headers of the client. The metrics of the returned request are not set.
+func translateListenerRequest[ReqT, RespT, RespChunkT any](eh endpointspec.Spec[ReqT, RespT, RespChunkT], backend *filterapi.RuntimeBackend, backendName string,
+	headers, originalHeaders map[string]string, modelNameOverride string, raw []byte, parsed *ReqT, logger *slog.Logger,
+) (*shadowRequest[ReqT, RespT, RespChunkT], error) {
+	if modelNameOverride != "" {
+		headers[internalapi.ModelNameHeaderKeyDefault] = modelNameOverride
+	}
+	tr, err := eh.GetTranslator(backend.Backend.Schema, modelNameOverride)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create translator: %w", err)
+	}
+	if setter, ok := tr.(translator.ContentTypeSetter); ok {
+		setter.SetContentType(headers["content-type"])
+	}
+	if setter, ok := tr.(translator.RequestHeadersSetter); ok {
+		setter.SetRequestHeaders(headers)
+	}
+	if setter, ok := tr.(translator.PromptCachingSetter); ok {
+		setter.SetPromptCaching(backend.Backend.PromptCaching)
+	}
+	// The body is always set on the request, so the body mutation is forced.
+	newHeaders, body, err := tr.RequestBody(raw, parsed, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform request: %w", err)
+	}
+	var bodyMutation *extprocv3.BodyMutation
+	if body != nil {
+		bodyMutation = &extprocv3.BodyMutation{Mutation: &extprocv3.BodyMutation_Body{Body: body}}
+	}
+	bodyMutator := bodymutator.NewBodyMutator(backend.Backend.BodyMutation, raw)
+	if body = applyBodyMutation(bodyMutator, bodyMutation, raw, logger).GetBody(); body == nil {
+		body = raw
+	}
+
+	sendHeaders := map[string]string{"content-type": headers["content-type"]}
+	for _, h := range newHeaders {
+		headers[h.Key()] = h.Value()
+		sendHeaders[h.Key()] = h.Value()
+	}
+	sets, _ := headermutator.NewHeaderMutator(backend.Backend.HeaderMutation, originalHeaders).Mutate(headers, false)
+	for _, h := range sets {
+		headers[h.Key()] = h.Value()
+		sendHeaders[h.Key()] = h.Value()
+	}
+	sendHeaders[":path"] = headers[":path"]
+	sendHeaders[internalapi.ShadowBackendHeader] = backendName
+
+	return &shadowRequest[ReqT, RespT, RespChunkT]{
+		backend:     backend,
+		translator:  tr,
+		headers:     headers,
+		sendHeaders: sendHeaders,
+		body:        body,
+	}, nil
+}
+
+// send sends the copy of the request to the shadow backend, and records the metrics of its response. When primary is
+// not nil, the similarity of the response to the primary one is recorded as well.
+func (s *shadowRequest[ReqT, RespT, RespChunkT]) send(ctx context.Context, sh *shadower, stream bool, primary *primaryResponse, logger *slog.Logger) {
+	logger = logger.With(slog.String("shadow", s.shadow.Backend))
+	ctx, cancel := context.WithTimeout(ctx, shadowRequestTimeout)
+	defer cancel()
+
+	s.metrics.StartRequest(s.headers)
+	responseBody, costs, err := s.do(ctx, sh, stream)
+	if err != nil {
+		logger.Info("failed to send the request to the shadow backend", slog.String("error", err.Error()))
+		s.metrics.RecordRequestCompletion(ctx, false, s.headers)
+		return
+	}
+	s.metrics.RecordTokenUsage(ctx, costs, s.headers)
+	s.metrics.RecordRequestCompletion(ctx, true, s.headers)
+
+	if primary == nil {
+		return
+	}
+	select {
+	case <-primary.done:
+	case <-time.After(shadowCompareTimeout):
+		return
+	}
+	if primary.body == nil {
+		return
+	}
+	if sm, ok := s.metrics.(metrics.ShadowMetrics); ok {
+		sm.RecordShadowSimilarity(ctx, responseSimilarity(primary.body, responseBody), s.headers)
+	}
+}
+
+// do sends the request and returns the response body in the schema of the client together with its token usage.
+func (s *shadowRequest[ReqT, RespT, RespChunkT]) do(ctx context.Context, sh *shadower, stream bool) ([]byte, metrics.TokenUsage, error) {
+	var out []byte
+	costs, err := s.doChunked(ctx, sh, stream, func(chunk []byte, _ metrics.TokenUsage) error {
+		out = append(out, chunk...)
+		return nil
+	})
+	if err != nil {
+		return nil, metrics.TokenUsage{}, err
+	}
+	return out, costs, nil
+}
+
+// doChunked sends the request and passes each chunk of the response body in the schema of the client to yield as it
+// arrives, together with the token usage so far. This returns the token usage of the whole response.
+func (s *shadowRequest[ReqT, RespT, RespChunkT]) doChunked(ctx context.Context, sh *shadower, stream bool,
+	yield func(chunk []byte, costs metrics.TokenUsage) error,
+) (metrics.TokenUsage, error) {
+	if h := s.backend.Handler; h != nil {
+		hdrs, err := h.Do(ctx, s.headers, s.body)
+		if err != nil {
+			return metrics.TokenUsage{}, fmt.Errorf("failed to do auth request: %w", err)
+		}
+		for _, h := range hdrs {
+			s.sendHeaders[h.Key()] = h.Value()
+		}
+	}
+
+	method := cmp.Or(s.headers[":method"], http.MethodPost)
+	req, err := http.NewRequestWithContext(ctx, method, sh.address+s.sendHeaders[":path"], bytes.NewReader(s.body))
+	if err != nil {
+		return metrics.TokenUsage{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range s.sendHeaders {
+		if strings.HasPrefix(k, ":") || k == "content-length" || v == "" {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	resp, err := sh.client.Do(req)
+	if err != nil {
+		return metrics.TokenUsage{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respHeaders := map[string]string{":status": strconv.Itoa(resp.StatusCode)}
+	for k := range resp.Header {
+		respHeaders[strings.ToLower(k)] = resp.Header.Get(k)
+	}
+	if !isGoodStatusCode(resp.StatusCode) {
+		body, _ := io.ReadAll(resp.Body)
+		s.metrics.SetErrorType(string(errorclass.Classify(resp.StatusCode, body)))
+		return metrics.TokenUsage{}, fmt.Errorf("shadow backend responded with status %d", resp.StatusCode)
+	}
+	if _, err = s.translator.ResponseHeaders(respHeaders); err != nil {
+		return metrics.TokenUsage{}, fmt.Errorf("failed to transform response headers: %w", err)
+	}
+
+	var (
+		span  tracingapi.Span[RespT, RespChunkT]
+		costs metrics.TokenUsage
+	)
+	translate := func(chunk []byte, endOfStream bool) error {
+		_, newBody, tokenUsage, responseModel, err := s.translator.ResponseBody(respHeaders, bytes.NewReader(chunk), endOfStream, span)
+		if err != nil {
+			return fmt.Errorf("failed to transform response: %w", err)
+		}
+		costs.Override(tokenUsage)
+		s.metrics.SetResponseModel(responseModel)
+		if newBody == nil {
+			newBody = chunk
+		}
+		if stream {
+			o, _ := costs.OutputTokens()
+			s.metrics.RecordTokenLatency(ctx, o, endOfStream, s.headers)
+		}
+		return yield(newBody, costs)
+	}
+
+	if !stream {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return metrics.TokenUsage{}, fmt.Errorf("failed to read response: %w", err)
+		}
+		if err = translate(body, true); err != nil {
+			return metrics.TokenUsage{}, err
+		}
+	} else {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := resp.Body.Read(buf)
+			if n > 0 {
+				if err := translate(buf[:n], false); err != nil {
+					return metrics.TokenUsage{}, err
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return metrics.TokenUsage{}, fmt.Errorf("failed to read response: %w", err)
+			}
+		}
+		if err := translate(nil, true); err != nil {
+			return metrics.TokenUsage{}, err
+		}
+	}
+	return costs, nil
+}
+
+// comparedResponse returns the response body returned to the client, which is published once the response ends.
+func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) comparedResponse() *primaryResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.primaryResponse == nil {
+		r.primaryResponse = &primaryResponse{done: make(chan struct{})}
+	}
+	return r.primaryResponse
+}
+
+// maybePublishPrimaryResponse accumulates the chunk of the response body returned to the client, and publishes the
+// whole body at the end of the stream when a shadow compares its response to it.
+func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) maybePublishPrimaryResponse(chunk []byte, endOfStream bool, responseEncoding string, succeeded bool) {
+	r.mu.Lock()
+	primary := r.primaryResponse
+	r.mu.Unlock()
+	if primary == nil {
+		return
+	}
+	primary.buf.Write(chunk)
+	if !endOfStream {
+		return
+	}
+	defer close(primary.done)
+	if !succeeded {
+		return
+	}
+	decoded, err := decodeContentIfNeeded(primary.buf.Bytes(), responseEncoding)
+	if err != nil {
+		return
+	}
+	if primary.body, err = io.ReadAll(decoded.reader); err != nil {
+		primary.body = nil
+	}
+}
+
+// responseSimilarity returns the similarity of the texts of the two JSON response bodies from 0 to 1, which is the
+// Jaccard index of the sets of their lowercase words.
+func responseSimilarity(a, b []byte) float64 {
+	wordsA, wordsB := responseWords(a), responseWords(b)
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 1
+	}
+	var intersection int
+	for w := range wordsA {
+		if _, ok := wordsB[w]; ok {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(wordsA)+len(wordsB)-intersection)
+}
+
+// responseWords returns the set of the lowercase words in the texts of the JSON response body, which are the string
+// values of the "content" and "text" fields at any depth.
+func responseWords(body []byte) map[string]struct{} {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil
+	}
+	words := make(map[string]struct{})
+	var walk func(v any, key string)
+	walk = func(v any, key string) {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, child := range v {
+				walk(child, k)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child, key)
+			}
+		case string:
+			if key == "content" || key == "text" {
+				for _, w := range strings.Fields(strings.ToLower(v)) {
+					words[w] = struct{}{}
+				}
+			}
+		}
+	}
+	walk(v, "")
+	return words
+}
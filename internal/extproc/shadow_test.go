@@ -0,0 +1,219 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+)
+
+// mockShadowMetrics implements [metrics.ShadowMetrics] for testing.
+type mockShadowMetrics struct {
+	mockMetrics
+	shadow     string
+	similarity float64
+	dropped    int
+	// done is closed once the similarity is recorded, which is the last step of a shadow comparing the responses.
+	done chan struct{}
+}
+
+// SetShadow implements [metrics.ShadowMetrics].
+func (m *mockShadowMetrics) SetShadow(shadow string) { m.shadow = shadow }
+
+// RecordShadowSimilarity implements [metrics.ShadowMetrics].
+func (m *mockShadowMetrics) RecordShadowSimilarity(_ context.Context, similarity float64, _ map[string]string) {
+	m.similarity = similarity
+	close(m.done)
+}
+
+// RecordShadowDropped implements [metrics.ShadowMetrics].
+func (m *mockShadowMetrics) RecordShadowDropped(context.Context, map[string]string) { m.dropped++ }
+
+// mockShadowMetricsFactory implements [metrics.Factory] returning the given metrics.
+type mockShadowMetricsFactory struct{ m *mockShadowMetrics }
+
+// NewMetrics implements [metrics.Factory.NewMetrics].
+func (f mockShadowMetricsFactory) NewMetrics() metrics.Metrics { return f.m }
+
+func Test_chatCompletionProcessorUpstreamFilter_Shadows(t *testing.T) {
+	type received struct {
+		path, authorization, shadowBackend, body string
+	}
+	requests := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{
+			path:          r.URL.Path,
+			authorization: r.Header.Get("authorization"),
+			shadowBackend: r.Header.Get(internalapi.ShadowBackendHeader),
+			body:          string(body),
+		}
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"shadow-model","choices":[{"message":{"role":"assistant","content":"Hello world"}}],` +
+			`"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`))
+	}))
+	defer srv.Close()
+
+	sm := &mockShadowMetrics{done: make(chan struct{})}
+	const requestBody = `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`
+	var parsed openai.ChatCompletionRequest
+	require.NoError(t, json.Unmarshal([]byte(requestBody), &parsed))
+	headers := map[string]string{
+		":path": "/v1/chat/completions", ":method": "POST", "content-type": "application/json",
+		// The headers of the client are not sent to the shadow backend.
+		"authorization": "Bearer client",
+	}
+	r := &chatCompletionProcessorRouterFilter{
+		eh: endpointspec.ChatCompletionsEndpointSpec{},
+		config: &filterapi.RuntimeConfig{Backends: map[string]*filterapi.RuntimeBackend{
+			"shadow": {Backend: &filterapi.Backend{
+				Name:              "shadow",
+				Schema:            filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+				ModelNameOverride: "shadow-model",
+			}},
+		}},
+		logger:                 slog.Default(),
+		requestHeaders:         headers,
+		originalRequestBodyRaw: []byte(requestBody),
+		originalRequestBody:    &parsed,
+		originalModel:          "gpt-4o",
+		shadower: &shadower{
+			client: srv.Client(), address: srv.URL, metrics: mockShadowMetricsFactory{sm}, inFlight: make(chan struct{}, 1),
+		},
+	}
+	u := &chatCompletionProcessorUpstreamFilter{requestHeaders: maps.Clone(headers), metrics: &mockMetrics{}, logger: slog.Default()}
+	require.NoError(t, u.SetBackend(t.Context(), &filterapi.RuntimeBackend{Backend: &filterapi.Backend{
+		Name:    "primary",
+		Schema:  filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+		Shadows: []filterapi.Shadow{{Backend: "shadow", Percent: 100, CompareResponses: true}},
+	}}, "test-route", r))
+
+	_, err := u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+	select {
+	case req := <-requests:
+		require.Equal(t, "/chat/completions", req.path)
+		require.Empty(t, req.authorization)
+		require.Equal(t, "shadow", req.shadowBackend)
+		require.JSONEq(t, `{"model":"shadow-model","messages":[{"role":"user","content":"hi"}]}`, req.body)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the shadow backend did not receive the request")
+	}
+
+	// The copy is sent only once even when the request is retried.
+	r.upstreamFilterCount++
+	_, err = u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+
+	// The primary response is published to the shadow at the end of the stream.
+	_, err = r.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{Headers: []*corev3.HeaderValue{
+		{Key: ":status", Value: "200"}, {Key: "content-type", Value: "application/json"},
+	}})
+	require.NoError(t, err)
+	_, err = r.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{
+		Body:        []byte(`{"model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"hello there"}}]}`),
+		EndOfStream: true,
+	})
+	require.NoError(t, err)
+
+	select {
+	case <-sm.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the similarity was not recorded")
+	}
+	require.Empty(t, requests)
+	require.Equal(t, "shadow", sm.shadow)
+	require.Equal(t, "shadow", sm.backend)
+	require.Equal(t, 1, sm.requestSuccessCount)
+	require.Equal(t, 3, sm.inputTokenCount)
+	require.Equal(t, 2, sm.outputTokenCount)
+	require.Equal(t, "shadow-model", sm.responseModel)
+	// {hello, world} and {hello, there} share one of the three words.
+	require.InDelta(t, 1.0/3, sm.similarity, 1e-9)
+}
+
+func Test_chatCompletionProcessorUpstreamFilter_ShadowsDropped(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("the shadow backend must not receive the request")
+	}))
+	defer srv.Close()
+
+	sm := &mockShadowMetrics{}
+	const requestBody = `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`
+	var parsed openai.ChatCompletionRequest
+	require.NoError(t, json.Unmarshal([]byte(requestBody), &parsed))
+	headers := map[string]string{":path": "/v1/chat/completions", ":method": "POST", "content-type": "application/json"}
+	sh := &shadower{client: srv.Client(), address: srv.URL, metrics: mockShadowMetricsFactory{sm}, inFlight: make(chan struct{}, 1)}
+	// Another copy is already in flight.
+	require.True(t, sh.tryAcquire())
+	r := &chatCompletionProcessorRouterFilter{
+		eh: endpointspec.ChatCompletionsEndpointSpec{},
+		config: &filterapi.RuntimeConfig{Backends: map[string]*filterapi.RuntimeBackend{
+			"shadow": {Backend: &filterapi.Backend{
+				Name:   "shadow",
+				Schema: filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+			}},
+		}},
+		logger:                 slog.Default(),
+		requestHeaders:         headers,
+		originalRequestBodyRaw: []byte(requestBody),
+		originalRequestBody:    &parsed,
+		originalModel:          "gpt-4o",
+		shadower:               sh,
+	}
+	u := &chatCompletionProcessorUpstreamFilter{requestHeaders: maps.Clone(headers), metrics: &mockMetrics{}, logger: slog.Default()}
+	require.NoError(t, u.SetBackend(t.Context(), &filterapi.RuntimeBackend{Backend: &filterapi.Backend{
+		Name:    "primary",
+		Schema:  filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+		Shadows: []filterapi.Shadow{{Backend: "shadow", Percent: 100}},
+	}}, "test-route", r))
+
+	_, err := u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, sm.dropped)
+	require.Equal(t, "shadow", sm.shadow)
+	require.Equal(t, "shadow", sm.backend)
+
+	// The slot is freed once the copy in flight completes.
+	sh.release()
+	require.True(t, sh.tryAcquire())
+}
+
+func TestResponseSimilarity(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		a, b string
+		exp  float64
+	}{
+		{name: "identical", a: `{"choices":[{"message":{"content":"Hello World"}}]}`, b: `{"content":[{"text":"hello world"}]}`, exp: 1},
+		{name: "disjoint", a: `{"choices":[{"message":{"content":"foo"}}]}`, b: `{"choices":[{"message":{"content":"bar"}}]}`, exp: 0},
+		{name: "half", a: `{"choices":[{"message":{"content":"a b"}}]}`, b: `{"choices":[{"message":{"content":"a b c d"}}]}`, exp: 0.5},
+		{name: "other fields are ignored", a: `{"id":"x","content":"a"}`, b: `{"id":"y","content":"a"}`, exp: 1},
+		{name: "both empty", a: `{}`, b: `not json`, exp: 1},
+		{name: "one empty", a: `{"content":"a"}`, b: `{}`, exp: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.InDelta(t, tc.exp, responseSimilarity([]byte(tc.a), []byte(tc.b)), 1e-9)
+		})
+	}
+}
@@ -0,0 +1,573 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+)
+
+const (
+	// streamFailoverTimeout bounds the time of the continuation of a truncated stream including its response.
+	streamFailoverTimeout = 5 * time.Minute
+	// streamFailoverAwaitTimeout bounds the time the stream failover filter waits for the router filter to process
+	// the response of the backend before continuing it.
+	streamFailoverAwaitTimeout = 30 * time.Second
+)
+
+// streamFailoverFormat is the format of the streaming responses that can be continued on the stream failover backend.
+type streamFailoverFormat int
+
+const (
+	// streamFailoverChatCompletions is the format of the chat completion chunks.
+	streamFailoverChatCompletions streamFailoverFormat = iota + 1
+	// streamFailoverMessages is the format of the events of the messages endpoint.
+	streamFailoverMessages
+)
+
+// streamFailoverFormatOf returns the format of the streaming responses of the endpoint, or zero if the streaming
+// responses of the endpoint cannot be continued.
+func streamFailoverFormatOf(eh any) streamFailoverFormat {
+	switch eh.(type) {
+	case endpointspec.ChatCompletionsEndpointSpec:
+		return streamFailoverChatCompletions
+	case endpointspec.MessagesEndpointSpec:
+		return streamFailoverMessages
+	}
+	return 0
+}
+
+// streamFailover tracks the streaming response returned to the client, so that the response can be continued on the
+// stream failover backend when the stream terminates before it completes.
+//
+// The events of the response are returned to the client once they are complete. The partial text output is buffered
+// to be sent as the assistant message to continue from, and the error event terminating the stream is held back so
+// that it is replaced by the continuation.
+type streamFailover struct {
+	format streamFailoverFormat
+	// pending is the incomplete event at the end of the response so far.
+	pending []byte
+	// continuationPending is the incomplete event at the end of the continuation so far.
+	continuationPending []byte
+	// errorEvent is the error event held back from the client, which is returned if the stream is not continued.
+	errorEvent []byte
+	// text is the text output returned to the client so far.
+	text strings.Builder
+	// completed is true once the response is complete, after which it is not continued.
+	completed bool
+	// unsupported is true if the output cannot be continued, e.g., it has a tool call.
+	unsupported bool
+	// trimmedSpace is true if the trailing whitespace of the text is trimmed from the assistant message of the
+	// continuation, in which case the leading whitespace of the continuation is trimmed as well since the client has
+	// already received it.
+	trimmedSpace bool
+
+	// id is the ID of the chat completion chunks, which is set on the chunks of the continuation.
+	id string
+
+	// started is true once the message_start event of the messages stream has been returned.
+	started bool
+	// openBlock is the index of the content block of the messages stream not stopped yet, or -1 if none.
+	openBlock int64
+	// nextBlock is the index of the next content block of the messages stream.
+	nextBlock int64
+	// blockOffset is the offset added to the indexes of the content blocks of the continuation, or -1 until the
+	// first content block of the continuation.
+	blockOffset int64
+}
+
+// newStreamFailover creates a new streamFailover for the response of the given format.
+func newStreamFailover(format streamFailoverFormat) *streamFailover {
+	return &streamFailover{format: format, openBlock: -1, blockOffset: -1}
+}
+
+// sseEvent is a server-sent event.
+type sseEvent struct {
+	name string
+	data []byte
+}
+
+// parseSSEEvent parses the event and the data fields of the server-sent event.
+func parseSSEEvent(raw []byte) sseEvent {
+	var e sseEvent
+	for line := range bytes.SplitSeq(bytes.TrimRight(raw, "\r\n"), []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if v, ok := bytes.CutPrefix(line, []byte("event:")); ok {
+			e.name = string(bytes.TrimSpace(v))
+		} else if v, ok = bytes.CutPrefix(line, []byte("data:")); ok {
+			if e.data != nil {
+				e.data = append(e.data, '\n')
+			}
+			e.data = append(e.data, bytes.TrimPrefix(v, []byte(" "))...)
+		}
+	}
+	return e
+}
+
+// bytes returns the wire format of the event.
+func (e sseEvent) bytes() []byte {
+	var b []byte
+	if e.name != "" {
+		b = fmt.Appendf(b, "event: %s\n", e.name)
+	}
+	return fmt.Appendf(b, "data: %s\n\n", e.data)
+}
+
+// splitSSEEvents splits the complete events off the head of the buffer, and returns them with the incomplete rest.
+func splitSSEEvents(buf []byte) (events [][]byte, rest []byte) {
+	for {
+		i := bytes.Index(buf, []byte("\n\n"))
+		if i < 0 {
+			return events, buf
+		}
+		events = append(events, buf[:i+2])
+		buf = buf[i+2:]
+	}
+}
+
+// process observes the chunk of the response returned to the client, and returns the complete events of the
+// response so far except the error event held back.
+func (f *streamFailover) process(chunk []byte) []byte {
+	events, rest := splitSSEEvents(append(f.pending, chunk...))
+	var out []byte
+	for _, raw := range events {
+		if f.observe(parseSSEEvent(raw)) {
+			out = append(out, raw...)
+		} else {
+			f.errorEvent = append(f.errorEvent, raw...)
+		}
+	}
+	f.pending = bytes.Clone(rest)
+	return out
+}
+
+// observe updates the state with the event of the response, and returns false if the event is an error event held
+// back from the client.
+func (f *streamFailover) observe(e sseEvent) bool {
+	if f.format == streamFailoverChatCompletions && string(e.data) == "[DONE]" {
+		f.completed = true
+		return true
+	}
+	data := gjson.ParseBytes(e.data)
+	if f.format == streamFailoverChatCompletions {
+		if data.Get("error").Exists() {
+			return !f.continuable()
+		}
+		if f.id == "" {
+			f.id = data.Get("id").String()
+		}
+		for _, choice := range data.Get("choices").Array() {
+			delta := choice.Get("delta")
+			if choice.Get("index").Int() > 0 || delta.Get("tool_calls").Exists() || delta.Get("function_call").Exists() ||
+				delta.Get("reasoning_content").String() != "" {
+				f.unsupported = true
+			}
+			f.text.WriteString(delta.Get("content").String())
+			if r := choice.Get("finish_reason"); r.Exists() && r.Type != gjson.Null {
+				f.completed = true
+			}
+		}
+		return true
+	}
+
+	switch data.Get("type").String() {
+	case "error":
+		return !f.continuable()
+	case "message_start":
+		f.started = true
+	case "content_block_start":
+		if data.Get("content_block.type").String() != "text" {
+			f.unsupported = true
+		}
+		f.openBlock = data.Get("index").Int()
+	case "content_block_delta":
+		if delta := data.Get("delta"); delta.Get("type").String() == "text_delta" {
+			f.text.WriteString(delta.Get("text").String())
+		} else {
+			f.unsupported = true
+		}
+	case "content_block_stop":
+		f.openBlock = -1
+		f.nextBlock = data.Get("index").Int() + 1
+	case "message_delta":
+		if r := data.Get("delta.stop_reason"); r.Exists() && r.Type != gjson.Null {
+			f.completed = true
+		}
+	case "message_stop":
+		f.completed = true
+	}
+	return true
+}
+
+// continuable returns true if the response so far can be continued.
+func (f *streamFailover) continuable() bool {
+	return !f.completed && !f.unsupported
+}
+
+// finish returns the rest of the response, i.e., the incomplete event at the end and the error event held back, when
+// the response is not continued.
+func (f *streamFailover) finish() []byte {
+	return append(f.pending, f.errorEvent...)
+}
+
+// continuationBody returns the request body of the continuation, which is the original request body with the text
+// output so far appended as the assistant message to continue from.
+func (f *streamFailover) continuationBody(raw []byte) ([]byte, error) {
+	text := f.text.String()
+	// Some providers reject the assistant message ending with whitespace.
+	prefill := strings.TrimRightFunc(text, unicode.IsSpace)
+	f.trimmedSpace = len(prefill) < len(text)
+	if prefill == "" {
+		return raw, nil
+	}
+	last := "messages." + strconv.FormatInt(gjson.GetBytes(raw, "messages.#").Int()-1, 10)
+	if gjson.GetBytes(raw, last+".role").String() != "assistant" {
+		return sjson.SetBytes(raw, "messages.-1", map[string]string{"role": "assistant", "content": prefill})
+	}
+	// The request already ends with the assistant message, e.g., the prefill of the messages endpoint, which the
+	// text output continues from.
+	switch content := gjson.GetBytes(raw, last+".content"); {
+	case content.IsArray():
+		return sjson.SetBytes(raw, last+".content.-1", map[string]string{"type": "text", "text": prefill})
+	default:
+		return sjson.SetBytes(raw, last+".content", content.String()+prefill)
+	}
+}
+
+// stitch returns the complete events of the chunk of the continuation rewritten to follow the response returned to
+// the client so far as a single response, whose usage is the merged one. The incomplete event at the end is held back
+// until it completes, or returned as is at the end of the continuation.
+func (f *streamFailover) stitch(chunk []byte, usage metrics.TokenUsage, end bool) []byte {
+	events, rest := splitSSEEvents(append(f.continuationPending, chunk...))
+	if end && len(rest) > 0 {
+		events, rest = append(events, rest), nil
+	}
+	f.continuationPending = bytes.Clone(rest)
+	var out []byte
+	for _, raw := range events {
+		e := parseSSEEvent(raw)
+		if f.format == streamFailoverChatCompletions {
+			out = append(out, f.stitchChatCompletionChunk(e, usage).bytes()...)
+			continue
+		}
+		for _, e := range f.stitchMessagesEvent(e, usage) {
+			out = append(out, e.bytes()...)
+		}
+	}
+	return out
+}
+
+// stitchChatCompletionChunk rewrites the chat completion chunk of the continuation.
+func (f *streamFailover) stitchChatCompletionChunk(e sseEvent, usage metrics.TokenUsage) sseEvent {
+	if string(e.data) == "[DONE]" {
+		return e
+	}
+	if f.id != "" && gjson.GetBytes(e.data, "id").Exists() {
+		e.data, _ = sjson.SetBytes(e.data, "id", f.id)
+	}
+	if content := gjson.GetBytes(e.data, "choices.0.delta.content"); content.Type == gjson.String {
+		e.data, _ = sjson.SetBytes(e.data, "choices.0.delta.content", f.trimLeadingSpace(content.String()))
+	}
+	if gjson.GetBytes(e.data, "usage").IsObject() {
+		if in, ok := usage.InputTokens(); ok {
+			e.data, _ = sjson.SetBytes(e.data, "usage.prompt_tokens", in)
+		}
+		if out, ok := usage.OutputTokens(); ok {
+			e.data, _ = sjson.SetBytes(e.data, "usage.completion_tokens", out)
+		}
+		if total, ok := usage.TotalTokens(); ok {
+			e.data, _ = sjson.SetBytes(e.data, "usage.total_tokens", total)
+		}
+	}
+	return e
+}
+
+// stitchMessagesEvent rewrites the event of the messages stream of the continuation, which is dropped when the
+// client has already received its counterpart, and preceded by the stop of the open content block when the
+// continuation does not continue it.
+func (f *streamFailover) stitchMessagesEvent(e sseEvent, usage metrics.TokenUsage) []sseEvent {
+	var ret []sseEvent
+	switch gjson.GetBytes(e.data, "type").String() {
+	case "message_start":
+		if f.started {
+			return nil
+		}
+		f.started = true
+	case "content_block_start":
+		if f.blockOffset < 0 {
+			switch {
+			case f.openBlock >= 0 && gjson.GetBytes(e.data, "content_block.type").String() == "text":
+				// The first text block continues the open one.
+				f.blockOffset = f.openBlock - gjson.GetBytes(e.data, "index").Int()
+				return nil
+			case f.openBlock >= 0:
+				ret = append(ret, sseEvent{
+					name: "content_block_stop",
+					data: fmt.Appendf(nil, `{"type":"content_block_stop","index":%d}`, f.openBlock),
+				})
+				f.blockOffset = f.openBlock + 1
+			default:
+				f.blockOffset = f.nextBlock
+			}
+		}
+		e.data, _ = sjson.SetBytes(e.data, "index", gjson.GetBytes(e.data, "index").Int()+f.blockOffset)
+	case "content_block_delta", "content_block_stop":
+		e.data, _ = sjson.SetBytes(e.data, "index", gjson.GetBytes(e.data, "index").Int()+max(f.blockOffset, 0))
+		if text := gjson.GetBytes(e.data, "delta.text"); text.Type == gjson.String {
+			e.data, _ = sjson.SetBytes(e.data, "delta.text", f.trimLeadingSpace(text.String()))
+		}
+	case "message_delta":
+		if gjson.GetBytes(e.data, "usage").IsObject() {
+			if in, ok := usage.InputTokens(); ok {
+				e.data, _ = sjson.SetBytes(e.data, "usage.input_tokens", in)
+			}
+			if out, ok := usage.OutputTokens(); ok {
+				e.data, _ = sjson.SetBytes(e.data, "usage.output_tokens", out)
+			}
+		}
+	}
+	return append(ret, e)
+}
+
+// trimLeadingSpace trims the leading whitespace of the text of the continuation until the first non-whitespace
+// text if the trailing whitespace of the text output so far was trimmed from the assistant message.
+func (f *streamFailover) trimLeadingSpace(text string) string {
+	if !f.trimmedSpace {
+		return text
+	}
+	text = strings.TrimLeftFunc(text, unicode.IsSpace)
+	if text != "" {
+		f.trimmedSpace = false
+	}
+	return text
+}
+
+// streamContinuation is the continuation of the truncated stream of an upstream filter, which is streamed to the
+// client through the stream failover filter while the router filter processes its chunks. See streamFailoverFilter.
+type streamContinuation struct {
+	// costs is the token usage of the continuation, which is set before done is closed.
+	costs metrics.TokenUsage
+	// done is closed once the continuation ends.
+	done chan struct{}
+}
+
+// continueStream re-issues the request to the stream failover backend with the text output so far, and passes the
+// events of the continuation stitched onto the response returned to the client to send as they arrive. If nothing is
+// sent, the response ends as it is. When the continuation fails halfway, it is terminated by the error event held
+// back from the client.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) continueStream(ctx context.Context, send func([]byte) error) {
+	c := &streamContinuation{done: make(chan struct{})}
+	defer close(c.done)
+	started := false
+	err := u.doContinuation(ctx, c, func(chunk []byte) error {
+		if !started {
+			// The router filter processes the chunks as the ones of the continuation from now on.
+			u.parent.mu.Lock()
+			u.continuation = c
+			u.parent.mu.Unlock()
+			started = true
+		}
+		return send(chunk)
+	})
+	if err == nil {
+		u.logger.Info("continued the truncated stream on the stream failover backend",
+			slog.String("backend", u.backendName), slog.String("stream_failover_backend", u.streamFailoverBackend))
+		return
+	}
+	u.logger.Info("failed to continue the truncated stream on the stream failover backend",
+		slog.String("backend", u.backendName), slog.String("error", err.Error()))
+	if started && len(u.failover.errorEvent) > 0 {
+		_ = send(u.failover.errorEvent)
+	}
+}
+
+// doContinuation sends the continuation request to the stream failover backend, and passes the events of its
+// response stitched onto the response returned to the client to send. The token usage of the continuation is set on c.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) doContinuation(ctx context.Context, c *streamContinuation, send func([]byte) error) error {
+	rp := u.parent
+	if rp.shadower == nil {
+		return errors.New("shadow listener is not available")
+	}
+	raw, err := u.failover.continuationBody(u.requestBodyRaw)
+	if err != nil {
+		return fmt.Errorf("failed to append the partial output to the request: %w", err)
+	}
+	_, parsed, _, _, err := rp.eh.ParseBody(raw, false)
+	if err != nil {
+		return fmt.Errorf("failed to parse the continuation request: %w", err)
+	}
+	req, err := u.newListenerRequest(u.streamFailoverBackend, raw, parsed)
+	if err != nil {
+		return fmt.Errorf("failed to prepare the continuation request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, streamFailoverTimeout)
+	defer cancel()
+	// The usage reported to the client is the one of the response so far merged with the one of the continuation.
+	usage := u.costs
+	merged := func(costs metrics.TokenUsage) metrics.TokenUsage {
+		m := usage
+		m.Merge(costs)
+		return m
+	}
+	req.metrics.StartRequest(req.headers)
+	c.costs, err = req.doChunked(ctx, rp.shadower, true, func(chunk []byte, costs metrics.TokenUsage) error {
+		if out := u.failover.stitch(chunk, merged(costs), false); len(out) > 0 {
+			return send(out)
+		}
+		return nil
+	})
+	if err == nil {
+		if out := u.failover.stitch(nil, merged(c.costs), true); len(out) > 0 {
+			err = send(out)
+		}
+	}
+	req.metrics.RecordRequestCompletion(ctx, err == nil, req.headers)
+	return err
+}
+
+// streamContinuer is implemented by the router filter level processor, which the stream failover filter asks to
+// continue the streaming response of the request once the backend ends it.
+type streamContinuer interface {
+	// streamed returns true if the request is a streaming request.
+	streamed() bool
+	// continueStream waits until the router filter processes the response body of the given size, i.e., the whole
+	// response of the backend, and then continues the response on the stream failover backend if it is truncated,
+	// passing the chunks of the continuation to send.
+	continueStream(ctx context.Context, size int, send func([]byte) error)
+}
+
+// streamed implements [streamContinuer.streamed].
+func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) streamed() bool { return r.stream }
+
+// continueStream implements [streamContinuer.continueStream].
+func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) continueStream(ctx context.Context, size int, send func([]byte) error) {
+	awaited := make(chan struct{})
+	r.mu.Lock()
+	if r.responseBodySize >= size {
+		close(awaited)
+	} else {
+		r.responseBodyAwaited, r.responseBodyAwaitedSize = awaited, size
+	}
+	r.mu.Unlock()
+	timer := time.NewTimer(streamFailoverAwaitTimeout)
+	defer timer.Stop()
+	select {
+	case <-awaited:
+	case <-timer.C:
+		r.logger.Info("timed out waiting for the router filter to process the response to continue")
+		return
+	case <-ctx.Done():
+		return
+	}
+
+	r.mu.Lock()
+	u := r.upstreamFilter
+	r.mu.Unlock()
+	if u == nil || u.failover == nil || !u.failover.continuable() {
+		return
+	}
+	u.continueStream(ctx, send)
+}
+
+// responseBodyProcessed records the size of the chunk of the response body processed at the router filter, and
+// notifies the stream failover filter waiting for it. See continueStream.
+func (r *routerProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) responseBodyProcessed(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responseBodySize += n
+	if r.responseBodyAwaited != nil && r.responseBodySize >= r.responseBodyAwaitedSize {
+		close(r.responseBodyAwaited)
+		r.responseBodyAwaited = nil
+	}
+}
+
+// streamFailoverFilter implements [Processor] for the stream failover filter, which is the upstream filter of the
+// backends of the route rules with the stream failover configured next to the main upstream filter. This processes
+// the response body in the FULL_DUPLEX_STREAMED mode, so that it can hold back the end of the stream of the backend
+// and send the continuation on the stream failover backend chunk by chunk as it arrives. The chunks are then
+// processed by the router filter as the rest of the response. See [streamContinuer].
+type streamFailoverFilter struct {
+	passThroughProcessor
+	// router is the router filter level processor of the request, or nil if it is not found.
+	router streamContinuer
+	logger *slog.Logger
+	// streaming is true if the response is a successful streaming response, which may be continued.
+	streaming bool
+	// size is the size of the response body of the backend so far.
+	size int
+}
+
+// ProcessResponseHeaders implements [Processor.ProcessResponseHeaders].
+func (f *streamFailoverFilter) ProcessResponseHeaders(_ context.Context, headers *corev3.HeaderMap) (*extprocv3.ProcessingResponse, error) {
+	f.streaming = f.router != nil && f.router.streamed() && headersToMap(headers)[":status"] == "200"
+	return &extprocv3.ProcessingResponse{Response: &extprocv3.ProcessingResponse_ResponseHeaders{
+		ResponseHeaders: &extprocv3.HeadersResponse{},
+	}}, nil
+}
+
+// ProcessResponseBody implements [Processor.ProcessResponseBody].
+func (f *streamFailoverFilter) ProcessResponseBody(ctx context.Context, body *extprocv3.HttpBody) (*extprocv3.ProcessingResponse, error) {
+	f.size += len(body.Body)
+	if !f.streaming || !body.EndOfStream {
+		return streamedBodyResponse(body.Body, body.EndOfStream), nil
+	}
+	// The end of the stream is held back until the router filter processes the rest of the response, which is sent
+	// first, so that the continuation follows it.
+	if err := sendResponse(ctx, streamedBodyResponse(body.Body, false)); err != nil {
+		return nil, fmt.Errorf("failed to send the response body: %w", err)
+	}
+	f.continueStream(ctx)
+	return streamedBodyResponse(nil, true), nil
+}
+
+// ProcessResponseTrailers implements [responseTrailersProcessor.ProcessResponseTrailers].
+func (f *streamFailoverFilter) ProcessResponseTrailers(ctx context.Context, _ *corev3.HeaderMap) (*extprocv3.ProcessingResponse, error) {
+	if f.streaming {
+		// The stream ends with the trailers, so the continuation is sent ahead of them.
+		f.continueStream(ctx)
+	}
+	return &extprocv3.ProcessingResponse{Response: &extprocv3.ProcessingResponse_ResponseTrailers{
+		ResponseTrailers: &extprocv3.TrailersResponse{},
+	}}, nil
+}
+
+// continueStream sends the continuation of the response if it is truncated.
+func (f *streamFailoverFilter) continueStream(ctx context.Context) {
+	if f.size == 0 {
+		// Nothing has been returned, so there is nothing to continue.
+		return
+	}
+	f.router.continueStream(ctx, f.size, func(chunk []byte) error {
+		return sendResponse(ctx, streamedBodyResponse(chunk, false))
+	})
+}
+
+// streamedBodyResponse returns the response to a chunk of the response body in the FULL_DUPLEX_STREAMED mode.
+func streamedBodyResponse(chunk []byte, endOfStream bool) *extprocv3.ProcessingResponse {
+	return &extprocv3.ProcessingResponse{Response: &extprocv3.ProcessingResponse_ResponseBody{
+		ResponseBody: &extprocv3.BodyResponse{Response: &extprocv3.CommonResponse{
+			BodyMutation: &extprocv3.BodyMutation{Mutation: &extprocv3.BodyMutation_StreamedResponse{
+				StreamedResponse: &extprocv3.StreamedBodyResponse{Body: chunk, EndOfStream: endOfStream},
+			}},
+		}},
+	}}
+}
@@ -0,0 +1,324 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+)
+
+func Test_chatCompletionProcessorUpstreamFilter_StreamFailover(t *testing.T) {
+	type received struct {
+		backend, body string
+	}
+	requests := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{backend: r.Header.Get(internalapi.ShadowBackendHeader), body: string(body)}
+		w.Header().Set("content-type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"id":"other","choices":[{"index":0,"delta":{"content":" world"}}]}` + "\n\n" +
+			`data: {"id":"other","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}` + "\n\n" +
+			`data: {"id":"other","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}` + "\n\n" +
+			"data: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	const requestBody = `{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"}]}`
+	var parsed openai.ChatCompletionRequest
+	require.NoError(t, json.Unmarshal([]byte(requestBody), &parsed))
+	headers := map[string]string{":path": "/v1/chat/completions", ":method": "POST", "content-type": "application/json"}
+	sm := &mockShadowMetrics{done: make(chan struct{})}
+	r := &chatCompletionProcessorRouterFilter{
+		eh: endpointspec.ChatCompletionsEndpointSpec{},
+		config: &filterapi.RuntimeConfig{Backends: map[string]*filterapi.RuntimeBackend{
+			"failover": {Backend: &filterapi.Backend{
+				Name:   "failover",
+				Schema: filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+			}},
+		}},
+		logger:                 slog.Default(),
+		requestHeaders:         headers,
+		originalRequestBodyRaw: []byte(requestBody),
+		originalRequestBody:    &parsed,
+		originalModel:          "gpt-4o",
+		stream:                 true,
+		shadower:               &shadower{client: srv.Client(), address: srv.URL, metrics: mockShadowMetricsFactory{sm}},
+	}
+	u := &chatCompletionProcessorUpstreamFilter{requestHeaders: maps.Clone(headers), metrics: &mockMetrics{}, logger: slog.Default()}
+	require.NoError(t, u.SetBackend(t.Context(), &filterapi.RuntimeBackend{Backend: &filterapi.Backend{
+		Name:                  "primary",
+		Schema:                filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+		StreamFailoverBackend: "failover",
+	}}, "test-route", r))
+	_, err := u.ProcessRequestHeaders(t.Context(), nil)
+	require.NoError(t, err)
+	_, err = u.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{Headers: []*corev3.HeaderValue{
+		{Key: ":status", Value: "200"}, {Key: "content-type", Value: "text/event-stream"},
+	}})
+	require.NoError(t, err)
+
+	// The stream failover filter passes the chunks through until the end of the stream, and then sends the rest of
+	// the response followed by the continuation as they are processed at the router filter.
+	sent := make(chan *extprocv3.ProcessingResponse, 16)
+	ctx := context.WithValue(t.Context(), responseSenderContextKey, func(resp *extprocv3.ProcessingResponse) error {
+		sent <- resp
+		return nil
+	})
+	sf := &streamFailoverFilter{router: r, logger: slog.Default()}
+	_, err = sf.ProcessResponseHeaders(ctx, &corev3.HeaderMap{Headers: []*corev3.HeaderValue{{Key: ":status", Value: "200"}}})
+	require.NoError(t, err)
+	require.True(t, sf.streaming)
+	first := []byte(`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"Hello "}}]}` + "\n\n" + `data: {"id":"c1",`)
+	res, err := sf.ProcessResponseBody(ctx, &extprocv3.HttpBody{Body: first})
+	require.NoError(t, err)
+	require.Equal(t, first, res.GetResponseBody().GetResponse().GetBodyMutation().GetStreamedResponse().GetBody())
+
+	// The incomplete event is held back until it completes.
+	res, err = r.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{Body: first})
+	require.NoError(t, err)
+	require.Equal(t, `data: {"id":"c1","choices":[{"index":0,"delta":{"content":"Hello "}}]}`+"\n\n",
+		string(res.GetResponseBody().GetResponse().GetBodyMutation().GetBody()))
+	require.Empty(t, requests)
+
+	last := []byte(`"choices":[{"index":0,"delta":{"content":"there, "}}]}` + "\n\n" + `data: {"error":{"message":"overloaded"}}` + "\n\n")
+	go func() {
+		res, err := sf.ProcessResponseBody(ctx, &extprocv3.HttpBody{Body: last, EndOfStream: true})
+		assert.NoError(t, err)
+		sent <- res
+	}()
+	var out strings.Builder
+	for {
+		streamed := (<-sent).GetResponseBody().GetResponse().GetBodyMutation().GetStreamedResponse()
+		res, err = r.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{Body: streamed.Body, EndOfStream: streamed.EndOfStream})
+		require.NoError(t, err)
+		out.Write(res.GetResponseBody().GetResponse().GetBodyMutation().GetBody())
+		if streamed.EndOfStream {
+			break
+		}
+	}
+
+	// The error event terminating the stream is replaced by the continuation.
+	req := <-requests
+	require.Equal(t, "failover", req.backend)
+	require.JSONEq(t, `{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"},`+
+		`{"role":"assistant","content":"Hello there,"}]}`, req.body)
+	require.Equal(t, `data: {"id":"c1","choices":[{"index":0,"delta":{"content":"there, "}}]}`+"\n\n"+
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"world"}}]}`+"\n\n"+
+		`data: {"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`+"\n\n"+
+		`data: {"id":"c1","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`+"\n\n"+
+		"data: [DONE]\n\n", out.String())
+	require.Equal(t, "failover", sm.backend)
+	require.Equal(t, 1, sm.requestSuccessCount)
+	in, _ := u.costs.InputTokens()
+	require.Equal(t, uint32(5), in)
+}
+
+func TestStreamFailoverFilter(t *testing.T) {
+	t.Run("not streaming", func(t *testing.T) {
+		sf := &streamFailoverFilter{router: &chatCompletionProcessorRouterFilter{}, logger: slog.Default()}
+		_, err := sf.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{Headers: []*corev3.HeaderValue{{Key: ":status", Value: "200"}}})
+		require.NoError(t, err)
+		require.False(t, sf.streaming)
+		res, err := sf.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{Body: []byte("body"), EndOfStream: true})
+		require.NoError(t, err)
+		streamed := res.GetResponseBody().GetResponse().GetBodyMutation().GetStreamedResponse()
+		require.Equal(t, "body", string(streamed.Body))
+		require.True(t, streamed.EndOfStream)
+	})
+	t.Run("error response", func(t *testing.T) {
+		sf := &streamFailoverFilter{router: &chatCompletionProcessorRouterFilter{stream: true}, logger: slog.Default()}
+		_, err := sf.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{Headers: []*corev3.HeaderValue{{Key: ":status", Value: "503"}}})
+		require.NoError(t, err)
+		require.False(t, sf.streaming)
+	})
+	t.Run("router not found", func(t *testing.T) {
+		sf := &streamFailoverFilter{logger: slog.Default()}
+		_, err := sf.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{Headers: []*corev3.HeaderValue{{Key: ":status", Value: "200"}}})
+		require.NoError(t, err)
+		require.False(t, sf.streaming)
+		res, err := sf.ProcessResponseTrailers(t.Context(), &corev3.HeaderMap{})
+		require.NoError(t, err)
+		require.NotNil(t, res.GetResponseTrailers())
+	})
+	t.Run("truncated stream without continuation", func(t *testing.T) {
+		r := &chatCompletionProcessorRouterFilter{stream: true, logger: slog.Default()}
+		var sent []*extprocv3.ProcessingResponse
+		ctx := context.WithValue(t.Context(), responseSenderContextKey, func(resp *extprocv3.ProcessingResponse) error {
+			sent = append(sent, resp)
+			// The router filter processes the chunk once it is sent.
+			r.responseBodyProcessed(len(resp.GetResponseBody().GetResponse().GetBodyMutation().GetStreamedResponse().GetBody()))
+			return nil
+		})
+		sf := &streamFailoverFilter{router: r, logger: slog.Default()}
+		_, err := sf.ProcessResponseHeaders(ctx, &corev3.HeaderMap{Headers: []*corev3.HeaderValue{{Key: ":status", Value: "200"}}})
+		require.NoError(t, err)
+		res, err := sf.ProcessResponseBody(ctx, &extprocv3.HttpBody{Body: []byte("data: {}\n\n"), EndOfStream: true})
+		require.NoError(t, err)
+		// The rest of the response is sent ahead, and the stream ends as it is since there is no upstream filter.
+		require.Len(t, sent, 1)
+		require.False(t, sent[0].GetResponseBody().GetResponse().GetBodyMutation().GetStreamedResponse().GetEndOfStream())
+		streamed := res.GetResponseBody().GetResponse().GetBodyMutation().GetStreamedResponse()
+		require.Empty(t, streamed.Body)
+		require.True(t, streamed.EndOfStream)
+	})
+}
+
+func TestStreamFailover_stitchChunks(t *testing.T) {
+	var usage metrics.TokenUsage
+	f := newStreamFailover(streamFailoverChatCompletions)
+	f.id = "c1"
+	// The incomplete event is held back until it completes, or the continuation ends.
+	require.Empty(t, f.stitch([]byte(`data: {"id":"other","choices":[{"index":0,`), usage, false))
+	require.Equal(t, `data: {"id":"c1","choices":[{"index":0,"delta":{"content":"a"}}]}`+"\n\n",
+		string(f.stitch([]byte(`"delta":{"content":"a"}}]}`+"\n\n"+"data: [DO"), usage, false)))
+	require.Equal(t, "data: [DONE]\n\n", string(f.stitch([]byte("NE]"), usage, true)))
+	require.Empty(t, f.continuationPending)
+}
+
+func TestStreamFailover_process(t *testing.T) {
+	t.Run("completed chat completion", func(t *testing.T) {
+		f := newStreamFailover(streamFailoverChatCompletions)
+		out := f.process([]byte(`data: {"choices":[{"index":0,"delta":{"content":"a"},"finish_reason":"stop"}]}` + "\n\n" +
+			`data: {"error":{"message":"late"}}` + "\n\n"))
+		// The error event after the completion is returned as is.
+		require.Contains(t, string(out), "late")
+		require.False(t, f.continuable())
+	})
+	t.Run("chat completion tool call", func(t *testing.T) {
+		f := newStreamFailover(streamFailoverChatCompletions)
+		f.process([]byte(`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0}]}}]}` + "\n\n"))
+		require.False(t, f.continuable())
+	})
+	t.Run("chat completion multiple choices", func(t *testing.T) {
+		f := newStreamFailover(streamFailoverChatCompletions)
+		f.process([]byte(`data: {"choices":[{"index":1,"delta":{"content":"a"}}]}` + "\n\n"))
+		require.False(t, f.continuable())
+	})
+	t.Run("messages", func(t *testing.T) {
+		f := newStreamFailover(streamFailoverMessages)
+		out := f.process([]byte("event: message_start\ndata: {\"type\":\"message_start\"}\n\n" +
+			"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n" +
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n" +
+			"event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\"}}\n\nevent: ping"))
+		require.NotContains(t, string(out), "overloaded_error")
+		require.True(t, f.continuable())
+		require.Equal(t, "Hi", f.text.String())
+		require.Equal(t, int64(0), f.openBlock)
+		require.Equal(t, "event: ping"+"event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\"}}\n\n", string(f.finish()))
+	})
+	t.Run("messages thinking", func(t *testing.T) {
+		f := newStreamFailover(streamFailoverMessages)
+		f.process([]byte("data: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"thinking\"}}\n\n"))
+		require.False(t, f.continuable())
+	})
+	t.Run("messages stopped", func(t *testing.T) {
+		f := newStreamFailover(streamFailoverMessages)
+		f.process([]byte("data: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"}}\n\n"))
+		require.False(t, f.continuable())
+	})
+}
+
+func TestStreamFailover_continuationBody(t *testing.T) {
+	for _, tc := range []struct {
+		name, text, raw, exp string
+		trimmedSpace         bool
+	}{
+		{
+			name: "appended",
+			text: "Hello \n",
+			raw:  `{"messages":[{"role":"user","content":"hi"}]}`,
+			exp:  `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"Hello"}]}`,
+
+			trimmedSpace: true,
+		},
+		{
+			name: "string prefill",
+			text: "lo",
+			raw:  `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"Hel"}]}`,
+			exp:  `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"Hello"}]}`,
+		},
+		{
+			name: "array prefill",
+			text: "lo",
+			raw:  `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":[{"type":"text","text":"Hel"}]}]}`,
+			exp: `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":[{"type":"text","text":"Hel"},` +
+				`{"type":"text","text":"lo"}]}]}`,
+		},
+		{
+			name:         "no text",
+			text:         " ",
+			raw:          `{"messages":[{"role":"user","content":"hi"}]}`,
+			exp:          `{"messages":[{"role":"user","content":"hi"}]}`,
+			trimmedSpace: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newStreamFailover(streamFailoverChatCompletions)
+			f.text.WriteString(tc.text)
+			body, err := f.continuationBody([]byte(tc.raw))
+			require.NoError(t, err)
+			require.JSONEq(t, tc.exp, string(body))
+			require.Equal(t, tc.trimmedSpace, f.trimmedSpace)
+		})
+	}
+}
+
+func TestStreamFailover_stitchMessages(t *testing.T) {
+	var usage metrics.TokenUsage
+	usage.SetInputTokens(10)
+	usage.SetOutputTokens(7)
+	continuation := "event: message_start\ndata: {\"type\":\"message_start\"}\n\n" +
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\" there\"}}\n\n" +
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n" +
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":2}}\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+
+	t.Run("open text block", func(t *testing.T) {
+		f := newStreamFailover(streamFailoverMessages)
+		f.started, f.openBlock, f.trimmedSpace = true, 1, true
+		require.Equal(t,
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"text_delta\",\"text\":\"there\"}}\n\n"+
+				"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":1}\n\n"+
+				"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":7,\"input_tokens\":10}}\n\n"+
+				"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+			string(f.stitch([]byte(continuation), usage, true)))
+	})
+	t.Run("stopped block", func(t *testing.T) {
+		f := newStreamFailover(streamFailoverMessages)
+		f.started, f.nextBlock = true, 2
+		out := string(f.stitch([]byte(continuation), usage, true))
+		require.Contains(t, out, `{"type":"content_block_start","index":2,`)
+		require.Contains(t, out, `{"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":" there"}}`)
+		require.NotContains(t, out, "message_start")
+	})
+	t.Run("open non-text block", func(t *testing.T) {
+		f := newStreamFailover(streamFailoverMessages)
+		f.openBlock = 0
+		out := string(f.stitch([]byte(strings.Replace(continuation, `"type":"text","text":""`, `"type":"tool_use"`, 1)), usage, true))
+		require.Contains(t, out, "message_start")
+		require.Contains(t, out, "event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n"+
+			"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,")
+	})
+}
@@ -0,0 +1,507 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/toolpolicy"
+)
+
+// applyToolPolicy removes the built-in tools and the disallowed tools from the request body sent to the backend
+// according to the tool policy of the route rule, and prepares the inspection of the tool calls of the response. This
+// returns the immediate response rejecting the request if it declares a disallowed tool while the action is Block, or
+// exceeds the limits of the policy, or nil otherwise.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) applyToolPolicy(ctx context.Context) (*extprocv3.ProcessingResponse, error) {
+	rp := u.parent
+	switch any(rp.eh).(type) {
+	case endpointspec.ChatCompletionsEndpointSpec, endpointspec.MessagesEndpointSpec, endpointspec.ResponsesEndpointSpec:
+	default:
+		// The requests of the other endpoints have no tools.
+		return nil, nil
+	}
+
+	raw, disallowed, err := filterRequestTools(rp.eh, u.requestBodyRaw, u.toolPolicy, u.toolMatcher)
+	if err != nil {
+		return nil, err
+	}
+	if len(disallowed) > 0 && !u.toolPolicy.Strip {
+		u.logger.Info("rejecting request with tools disallowed by the tool policy", slog.String("backend", u.backendName),
+			slog.Any("tools", disallowed))
+		u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
+		return createUserFacingErrorResponse(400, "BadRequest",
+			jsonEscaped("tools not allowed on this route: "+strings.Join(disallowed, ", "))), nil
+	}
+	body := raw
+	if body == nil {
+		body = u.requestBodyRaw
+	}
+	if message := checkToolLimits(rp.eh, body, u.toolPolicy); message != "" {
+		u.logger.Info("rejecting request exceeding the limits of the tool policy", slog.String("backend", u.backendName),
+			slog.String("error", message))
+		u.metrics.RecordRequestCompletion(ctx, false, u.requestHeaders)
+		return createUserFacingErrorResponse(400, "BadRequest", jsonEscaped(message)), nil
+	}
+	if raw != nil {
+		_, parsed, _, _, err := rp.eh.ParseBody(raw, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the request with the tools removed: %w", err)
+		}
+		u.requestBodyRaw, u.requestBody = raw, parsed
+		u.requestBodyMasked = true
+	}
+
+	if u.toolMatcher != nil {
+		u.toolCalls = &toolCallFilter{format: streamFailoverFormatOf(rp.eh), matcher: u.toolMatcher, strip: u.toolPolicy.Strip}
+	}
+	return nil, nil
+}
+
+// checkToolResponse applies the tool policy to the chunk of the response returned to the client. If the response
+// calls a disallowed tool while the action is Block, the non-streaming response is replaced by the error of the
+// returned statusCode, and the streaming response is terminated with an error event, after which denied is true.
+func (u *upstreamProcessor[ReqT, RespT, RespChunkT, EndpointSpecT]) checkToolResponse(chunk []byte, endOfStream bool) (out []byte, statusCode int, denied bool) {
+	f := u.toolCalls
+	if u.parent.stream {
+		out, denied = f.processStream(chunk, endOfStream)
+		if denied && f.deniedTool != "" {
+			u.logger.Info("terminating stream calling a tool disallowed by the tool policy", slog.String("backend", u.backendName),
+				slog.String("tool", f.deniedTool))
+			f.deniedTool = ""
+		}
+		return out, 0, denied
+	}
+	out, disallowed, err := filterResponseToolCalls(u.parent.eh, chunk, f.matcher)
+	if err != nil {
+		u.logger.Error("failed to filter the tool calls of the response", slog.String("error", err.Error()))
+		return chunk, 0, false
+	}
+	if len(disallowed) == 0 {
+		return chunk, 0, false
+	}
+	if f.strip {
+		return out, 0, false
+	}
+	u.logger.Info("replacing response calling tools disallowed by the tool policy", slog.String("backend", u.backendName),
+		slog.Any("tools", disallowed))
+	return formatUserFacingErrorJSON("BadRequest", 400, jsonEscaped(toolCallNotAllowedMessage(disallowed...))), 400, true
+}
+
+// toolCallNotAllowedMessage returns the message of the error returned to the client when the response calls the
+// disallowed tools.
+func toolCallNotAllowedMessage(names ...string) string {
+	return "tool call not allowed on this route: " + strings.Join(names, ", ")
+}
+
+// filterRequestTools returns the request body with the built-in tools removed if the policy says so, and with the
+// disallowed tools removed, or nil if no tool is removed. disallowed are the names of the disallowed tools declared in
+// the request, which are removed regardless of the action of the policy.
+//
+// The tool choice naming a removed tool is removed as well, as are the tool choice and the parallel tool calls option
+// if no tool is left.
+func filterRequestTools(eh any, raw []byte, policy *filterapi.ToolPolicy, matcher *toolpolicy.Matcher) (out []byte, disallowed []string, err error) {
+	var kept []string
+	removed := make(map[string]struct{})
+	for _, tool := range gjson.GetBytes(raw, "tools").Array() {
+		name := toolName(eh, tool)
+		if policy.RemoveBuiltInTools && isBuiltInTool(tool) {
+			removed[name] = struct{}{}
+			continue
+		}
+		if matcher != nil && !matcher.Allows(name) {
+			removed[name] = struct{}{}
+			disallowed = append(disallowed, name)
+			continue
+		}
+		kept = append(kept, tool.Raw)
+	}
+	_, isChat := eh.(endpointspec.ChatCompletionsEndpointSpec)
+	webSearch := isChat && policy.RemoveBuiltInTools && gjson.GetBytes(raw, "web_search_options").Exists()
+	if len(removed) == 0 && !webSearch {
+		return nil, nil, nil
+	}
+
+	out = raw
+	if webSearch {
+		if out, err = sjson.DeleteBytes(out, "web_search_options"); err != nil {
+			return nil, nil, fmt.Errorf("failed to remove the web search options: %w", err)
+		}
+	}
+	if len(removed) == 0 {
+		return out, nil, nil
+	}
+	if len(kept) == 0 {
+		for _, path := range []string{"tools", "tool_choice", "parallel_tool_calls"} {
+			if out, err = sjson.DeleteBytes(out, path); err != nil {
+				return nil, nil, fmt.Errorf("failed to remove the %s: %w", path, err)
+			}
+		}
+		return out, disallowed, nil
+	}
+	if out, err = sjson.SetRawBytes(out, "tools", joinJSONArray(kept)); err != nil {
+		return nil, nil, fmt.Errorf("failed to remove the tools: %w", err)
+	}
+	if _, ok := removed[toolChoiceName(gjson.GetBytes(out, "tool_choice"))]; ok {
+		if out, err = sjson.DeleteBytes(out, "tool_choice"); err != nil {
+			return nil, nil, fmt.Errorf("failed to remove the tool choice: %w", err)
+		}
+	}
+	return out, disallowed, nil
+}
+
+// checkToolLimits returns the message of the error rejecting the request if the tools declared in the request exceed
+// the limits of the policy, or empty otherwise.
+func checkToolLimits(eh any, raw []byte, policy *filterapi.ToolPolicy) string {
+	if policy.MaxTools == 0 && policy.MaxSchemaBytes == 0 {
+		return ""
+	}
+	tools := gjson.GetBytes(raw, "tools").Array()
+	if policy.MaxTools > 0 && len(tools) > policy.MaxTools {
+		return fmt.Sprintf("request declares %d tools, exceeding the maximum of %d", len(tools), policy.MaxTools)
+	}
+	if policy.MaxSchemaBytes > 0 {
+		for _, tool := range tools {
+			if n := len(toolSchema(eh, tool).Raw); n > policy.MaxSchemaBytes {
+				return fmt.Sprintf("schema of tool %q has %d bytes, exceeding the maximum of %d",
+					toolName(eh, tool), n, policy.MaxSchemaBytes)
+			}
+		}
+	}
+	return ""
+}
+
+// toolName returns the name of the tool declared in the chat completions, the messages or the responses request. The
+// built-in tools without a name are named after their type, e.g., "web_search_preview".
+func toolName(eh any, tool gjson.Result) string {
+	if _, ok := eh.(endpointspec.ChatCompletionsEndpointSpec); ok {
+		if name := cmp.Or(tool.Get("function.name").String(), tool.Get("custom.name").String()); name != "" {
+			return name
+		}
+	}
+	return cmp.Or(tool.Get("name").String(), tool.Get("type").String())
+}
+
+// isBuiltInTool returns true if the tool is a built-in tool of the provider rather than a tool executed by the client,
+// i.e., of a type other than "function" and "custom". The tools of the messages endpoint may have no type.
+func isBuiltInTool(tool gjson.Result) bool {
+	switch tool.Get("type").String() {
+	case "", "function", "custom":
+		return false
+	}
+	return true
+}
+
+// toolSchema returns the JSON schema of the parameters of the tool declared in the chat completions, the messages or
+// the responses request.
+func toolSchema(eh any, tool gjson.Result) gjson.Result {
+	switch eh.(type) {
+	case endpointspec.ChatCompletionsEndpointSpec:
+		return tool.Get("function.parameters")
+	case endpointspec.MessagesEndpointSpec:
+		return tool.Get("input_schema")
+	}
+	return tool.Get("parameters")
+}
+
+// toolChoiceName returns the name of the tool forced by the tool choice of the request, or empty if it forces none,
+// e.g., "auto". The built-in tools forced without a name are named after their type.
+func toolChoiceName(choice gjson.Result) string {
+	if !choice.IsObject() {
+		return ""
+	}
+	if name := cmp.Or(choice.Get("function.name").String(), choice.Get("custom.name").String(), choice.Get("name").String()); name != "" {
+		return name
+	}
+	switch t := choice.Get("type").String(); t {
+	case "auto", "any", "none", "required", "tool", "function", "custom", "allowed_tools":
+		return ""
+	default:
+		return t
+	}
+}
+
+// filterResponseToolCalls returns the non-streaming response body of the chat completions, the messages or the
+// responses endpoint with the calls of the disallowed tools removed, and the names of the disallowed tools called. The
+// finish reason of the response only calling the disallowed tools becomes the one of the completed response.
+func filterResponseToolCalls(eh any, body []byte, matcher *toolpolicy.Matcher) (out []byte, disallowed []string, err error) {
+	out = body
+	switch eh.(type) {
+	case endpointspec.ChatCompletionsEndpointSpec:
+		for i, choice := range gjson.GetBytes(body, "choices").Array() {
+			path := "choices." + strconv.Itoa(i)
+			kept, names := filterToolCallArray(choice.Get("message.tool_calls"), matcher, func(c gjson.Result) string {
+				return cmp.Or(c.Get("function.name").String(), c.Get("custom.name").String())
+			})
+			if len(names) == 0 {
+				continue
+			}
+			disallowed = append(disallowed, names...)
+			if len(kept) > 0 {
+				out, err = sjson.SetRawBytes(out, path+".message.tool_calls", joinJSONArray(kept))
+			} else if out, err = sjson.DeleteBytes(out, path+".message.tool_calls"); err == nil && choice.Get("finish_reason").String() == "tool_calls" {
+				out, err = sjson.SetBytes(out, path+".finish_reason", "stop")
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to remove the tool calls: %w", err)
+			}
+		}
+	case endpointspec.MessagesEndpointSpec:
+		kept, names := filterToolCallArray(gjson.GetBytes(body, "content"), matcher, func(block gjson.Result) string {
+			if block.Get("type").String() != "tool_use" {
+				return ""
+			}
+			return block.Get("name").String()
+		})
+		if len(names) == 0 {
+			return out, nil, nil
+		}
+		disallowed = names
+		if out, err = sjson.SetRawBytes(out, "content", joinJSONArray(kept)); err != nil {
+			return nil, nil, fmt.Errorf("failed to remove the tool calls: %w", err)
+		}
+		if !slices.ContainsFunc(kept, func(raw string) bool { return gjson.Get(raw, "type").String() == "tool_use" }) &&
+			gjson.GetBytes(body, "stop_reason").String() == "tool_use" {
+			if out, err = sjson.SetBytes(out, "stop_reason", "end_turn"); err != nil {
+				return nil, nil, fmt.Errorf("failed to set the stop reason: %w", err)
+			}
+		}
+	case endpointspec.ResponsesEndpointSpec:
+		kept, names := filterToolCallArray(gjson.GetBytes(body, "output"), matcher, responsesToolCallName)
+		if len(names) == 0 {
+			return out, nil, nil
+		}
+		disallowed = names
+		if out, err = sjson.SetRawBytes(out, "output", joinJSONArray(kept)); err != nil {
+			return nil, nil, fmt.Errorf("failed to remove the tool calls: %w", err)
+		}
+	}
+	return out, disallowed, nil
+}
+
+// filterToolCallArray returns the raw elements of the array that are not the calls of the disallowed tools, and the
+// names of the disallowed tools called. nameOf returns the name of the tool called by the element, or empty if the
+// element is not a tool call.
+func filterToolCallArray(array gjson.Result, matcher *toolpolicy.Matcher, nameOf func(gjson.Result) string) (kept, disallowed []string) {
+	for _, e := range array.Array() {
+		if name := nameOf(e); name != "" && !matcher.Allows(name) {
+			disallowed = append(disallowed, name)
+			continue
+		}
+		kept = append(kept, e.Raw)
+	}
+	return
+}
+
+// responsesToolCallName returns the name of the tool called by the output item of the responses endpoint, or empty if
+// it is not the call of a tool executed by the client.
+func responsesToolCallName(item gjson.Result) string {
+	switch item.Get("type").String() {
+	case "function_call", "custom_tool_call":
+		return item.Get("name").String()
+	}
+	return ""
+}
+
+// joinJSONArray returns the JSON array of the raw elements.
+func joinJSONArray(elements []string) []byte {
+	return []byte("[" + strings.Join(elements, ",") + "]")
+}
+
+// toolCallFilter inspects the tool calls of the response of the chat completions, the messages or the responses
+// endpoint for the tool policy.
+//
+// The name of the tool is only known from the first delta of a streaming tool call, so the following deltas of a
+// removed call are recognized by the index of the call. The indexes of the calls and the content blocks following a
+// removed one are shifted so that the client sees no gap.
+type toolCallFilter struct {
+	format  streamFailoverFormat
+	matcher *toolpolicy.Matcher
+	strip   bool
+	// pending is the incomplete event at the end of the streaming response so far.
+	pending []byte
+	// indexes maps the index of a tool call of the chat completion chunks, keyed by the choice and the call, or of a
+	// content block or an output item of the other endpoints, to the index returned to the client, or -1 if removed.
+	indexes map[string]int64
+	// next is the next index returned to the client by the choice, or by the empty key for the other endpoints.
+	next map[string]int64
+	// removed is true once a tool call is removed, and kept is true once a tool call is returned, by the same keys as
+	// next.
+	removed, kept map[string]bool
+	// denied is true once the stream is terminated, after which the rest of the response is dropped.
+	denied bool
+	// deniedTool is the name of the disallowed tool terminating the stream, which is cleared once logged.
+	deniedTool string
+}
+
+// processStream filters the chunk of the streaming response, and returns the complete events of the response so far.
+// This returns the error event terminating the stream if the response calls a disallowed tool while the action is
+// Block, after which denied is true.
+func (f *toolCallFilter) processStream(chunk []byte, endOfStream bool) (out []byte, denied bool) {
+	if f.denied {
+		return nil, true
+	}
+	events, rest := splitSSEEvents(append(f.pending, chunk...))
+	f.pending = rest
+	for _, raw := range events {
+		e := parseSSEEvent(raw)
+		data := e.data
+		var keep bool
+		var err error
+		switch f.format {
+		case streamFailoverChatCompletions:
+			keep, err = f.filterChatCompletionChunk(&e)
+		default:
+			keep, err = f.filterIndexedEvent(&e)
+		}
+		if f.denied {
+			return append(out, externalGuardrailErrorEvents(f.format, 400, "BadRequest", toolCallNotAllowedMessage(f.deniedTool))...), true
+		}
+		switch {
+		case err != nil || bytes.Equal(e.data, data):
+			// The event is returned as is unless it is modified.
+			if keep || err != nil {
+				out = append(out, raw...)
+			}
+		case keep:
+			out = append(out, e.bytes()...)
+		}
+	}
+	if endOfStream {
+		out = append(out, f.pending...)
+		f.pending = nil
+	}
+	return out, false
+}
+
+// filterChatCompletionChunk removes the deltas of the calls of the disallowed tools from the chat completion chunk,
+// and shifts the indexes of the following calls of the choice. The finish reason of the choice only calling the
+// disallowed tools becomes "stop".
+func (f *toolCallFilter) filterChatCompletionChunk(e *sseEvent) (keep bool, err error) {
+	choices := gjson.GetBytes(e.data, "choices").Array()
+	data := e.data
+	for i, choice := range choices {
+		calls := choice.Get("delta.tool_calls")
+		finishReason := choice.Get("finish_reason").String()
+		if !calls.Exists() && finishReason != "tool_calls" {
+			continue
+		}
+		choiceKey := choice.Get("index").String()
+		var kept []string
+		for _, call := range calls.Array() {
+			index, ok := f.index(choiceKey+":"+call.Get("index").String(), choiceKey,
+				cmp.Or(call.Get("function.name").String(), call.Get("custom.name").String()))
+			if f.denied {
+				return false, nil
+			}
+			if !ok {
+				continue
+			}
+			raw, err := sjson.Set(call.Raw, "index", index)
+			if err != nil {
+				return false, err
+			}
+			kept = append(kept, raw)
+		}
+		path := "choices." + strconv.Itoa(i)
+		if calls.Exists() {
+			if len(kept) > 0 {
+				data, err = sjson.SetRawBytes(data, path+".delta.tool_calls", joinJSONArray(kept))
+			} else {
+				data, err = sjson.DeleteBytes(data, path+".delta.tool_calls")
+			}
+			if err != nil {
+				return false, err
+			}
+		}
+		if finishReason == "tool_calls" && f.removed[choiceKey] && !f.kept[choiceKey] {
+			if data, err = sjson.SetBytes(data, path+".finish_reason", "stop"); err != nil {
+				return false, err
+			}
+		}
+	}
+	e.data = data
+	return true, nil
+}
+
+// filterIndexedEvent drops the events of the content blocks of the messages endpoint and the output items of the
+// responses endpoint calling the disallowed tools, and shifts the indexes of the following ones. The stop reason of
+// the message only calling the disallowed tools becomes "end_turn", and the calls are removed from the output of the
+// response events of the responses endpoint.
+func (f *toolCallFilter) filterIndexedEvent(e *sseEvent) (keep bool, err error) {
+	indexPath, name := "output_index", ""
+	switch typ := gjson.GetBytes(e.data, "type").String(); typ {
+	case "content_block_start":
+		indexPath = "index"
+		if block := gjson.GetBytes(e.data, "content_block"); block.Get("type").String() == "tool_use" {
+			name = block.Get("name").String()
+		}
+	case "content_block_delta", "content_block_stop":
+		indexPath = "index"
+	case "message_delta":
+		if gjson.GetBytes(e.data, "delta.stop_reason").String() == "tool_use" && f.removed[""] && !f.kept[""] {
+			e.data, err = sjson.SetBytes(e.data, "delta.stop_reason", "end_turn")
+		}
+		return true, err
+	case "response.output_item.added":
+		name = responsesToolCallName(gjson.GetBytes(e.data, "item"))
+	}
+
+	index := gjson.GetBytes(e.data, indexPath)
+	if !index.Exists() {
+		if output := gjson.GetBytes(e.data, "response.output"); output.IsArray() {
+			kept, _ := filterToolCallArray(output, f.matcher, responsesToolCallName)
+			e.data, err = sjson.SetRawBytes(e.data, "response.output", joinJSONArray(kept))
+		}
+		return true, err
+	}
+	newIndex, ok := f.index(index.String(), "", name)
+	if !ok || f.denied {
+		return false, nil
+	}
+	if newIndex != index.Int() {
+		e.data, err = sjson.SetBytes(e.data, indexPath, newIndex)
+	}
+	return true, err
+}
+
+// index returns the index returned to the client for the tool call, the content block or the output item of the given
+// key, or false if it is removed. name is the name of the tool called if this is the first event of a tool call.
+func (f *toolCallFilter) index(key, counterKey, name string) (int64, bool) {
+	if f.indexes == nil {
+		f.indexes, f.next = make(map[string]int64), make(map[string]int64)
+		f.removed, f.kept = make(map[string]bool), make(map[string]bool)
+	}
+	if index, ok := f.indexes[key]; ok {
+		return index, index >= 0
+	}
+	if name != "" && !f.matcher.Allows(name) {
+		if !f.strip {
+			f.denied, f.deniedTool = true, name
+			return 0, false
+		}
+		f.indexes[key], f.removed[counterKey] = -1, true
+		return 0, false
+	}
+	if name != "" {
+		f.kept[counterKey] = true
+	}
+	index := f.next[counterKey]
+	f.indexes[key], f.next[counterKey] = index, index+1
+	return index, true
+}
@@ -0,0 +1,236 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package extproc
+
+import (
+	"log/slog"
+	"maps"
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/endpointspec"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/toolpolicy"
+)
+
+func Test_chatCompletionProcessorUpstreamFilter_ToolPolicy(t *testing.T) {
+	const requestBody = `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}],"tools":[` +
+		`{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}},` +
+		`{"type":"function","function":{"name":"delete_user","parameters":{"type":"object"}}}],` +
+		`"tool_choice":{"type":"function","function":{"name":"delete_user"}},"web_search_options":{}}`
+	newFilters := func(t *testing.T, policy *filterapi.ToolPolicy) (*chatCompletionProcessorUpstreamFilter, *mockMetrics) {
+		var parsed openai.ChatCompletionRequest
+		require.NoError(t, json.Unmarshal([]byte(requestBody), &parsed))
+		headers := map[string]string{":path": "/v1/chat/completions", ":method": "POST", "content-type": "application/json"}
+		r := &chatCompletionProcessorRouterFilter{
+			eh:                     endpointspec.ChatCompletionsEndpointSpec{},
+			config:                 &filterapi.RuntimeConfig{},
+			logger:                 slog.Default(),
+			requestHeaders:         headers,
+			originalRequestBodyRaw: []byte(requestBody),
+			originalRequestBody:    &parsed,
+			originalModel:          "gpt-4o",
+		}
+		matcher, err := toolpolicy.NewMatcher(policy.Allow, policy.AllowRegex, policy.Deny, policy.DenyRegex)
+		require.NoError(t, err)
+		m := &mockMetrics{}
+		u := &chatCompletionProcessorUpstreamFilter{requestHeaders: maps.Clone(headers), metrics: m, logger: slog.Default()}
+		require.NoError(t, u.SetBackend(t.Context(), &filterapi.RuntimeBackend{
+			Backend: &filterapi.Backend{
+				Name:       "openai",
+				Schema:     filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI, Version: "v1"},
+				ToolPolicy: policy,
+			},
+			ToolMatcher: matcher,
+		}, "test-route", r))
+		return u, m
+	}
+	processResponse := func(t *testing.T, u *chatCompletionProcessorUpstreamFilter, body string) *extprocv3.CommonResponse {
+		_, err := u.ProcessResponseHeaders(t.Context(), &corev3.HeaderMap{Headers: []*corev3.HeaderValue{
+			{Key: ":status", Value: "200"}, {Key: "content-type", Value: "application/json"},
+		}})
+		require.NoError(t, err)
+		res, err := u.ProcessResponseBody(t.Context(), &extprocv3.HttpBody{EndOfStream: true, Body: []byte(body)})
+		require.NoError(t, err)
+		return res.GetResponseBody().Response
+	}
+	const responseBody = `{"choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","tool_calls":[` +
+		`{"id":"call_1","type":"function","function":{"name":"delete_user","arguments":"{}"}}]}}],` +
+		`"usage":{"prompt_tokens":10,"completion_tokens":8,"total_tokens":18}}`
+
+	t.Run("strip", func(t *testing.T) {
+		u, _ := newFilters(t, &filterapi.ToolPolicy{Strip: true, Deny: []string{"delete_user"}, RemoveBuiltInTools: true})
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		body := resp.GetRequestHeaders().Response.BodyMutation.GetBody()
+		require.JSONEq(t, `[{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}]`,
+			gjson.GetBytes(body, "tools").Raw)
+		require.False(t, gjson.GetBytes(body, "tool_choice").Exists())
+		require.False(t, gjson.GetBytes(body, "web_search_options").Exists())
+
+		out := processResponse(t, u, responseBody).BodyMutation.GetBody()
+		require.False(t, gjson.GetBytes(out, "choices.0.message.tool_calls").Exists())
+		require.Equal(t, "stop", gjson.GetBytes(out, "choices.0.finish_reason").String())
+	})
+
+	t.Run("block", func(t *testing.T) {
+		u, m := newFilters(t, &filterapi.ToolPolicy{Deny: []string{"delete_user"}})
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		ir := resp.GetImmediateResponse()
+		require.NotNil(t, ir)
+		require.Equal(t, typev3.StatusCode_BadRequest, ir.Status.Code)
+		require.Contains(t, string(ir.Body), "tools not allowed on this route: delete_user")
+		m.RequireRequestFailure(t)
+	})
+
+	t.Run("block response", func(t *testing.T) {
+		u, m := newFilters(t, &filterapi.ToolPolicy{Allow: []string{"get_weather", "delete_user"}, MaxTools: 2})
+		_, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		// The model calls a tool not allowed anymore, e.g., one from the history of the conversation.
+		u.toolMatcher, err = toolpolicy.NewMatcher([]string{"get_weather"}, nil, nil, nil)
+		require.NoError(t, err)
+		u.toolCalls.matcher = u.toolMatcher
+
+		res := processResponse(t, u, responseBody)
+		require.Contains(t, string(res.BodyMutation.GetBody()), "tool call not allowed on this route: delete_user")
+		require.Equal(t, ":status", res.HeaderMutation.SetHeaders[0].Header.Key)
+		require.Equal(t, "400", string(res.HeaderMutation.SetHeaders[0].Header.RawValue))
+		m.RequireRequestFailure(t)
+	})
+
+	t.Run("max tools", func(t *testing.T) {
+		u, _ := newFilters(t, &filterapi.ToolPolicy{MaxTools: 1})
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		require.Contains(t, string(resp.GetImmediateResponse().Body), "request declares 2 tools, exceeding the maximum of 1")
+	})
+
+	t.Run("max schema bytes", func(t *testing.T) {
+		u, _ := newFilters(t, &filterapi.ToolPolicy{MaxSchemaBytes: 10})
+		resp, err := u.ProcessRequestHeaders(t.Context(), nil)
+		require.NoError(t, err)
+		require.Contains(t, string(resp.GetImmediateResponse().Body),
+			`schema of tool \"get_weather\" has 17 bytes, exceeding the maximum of 10`)
+	})
+}
+
+func Test_filterRequestTools(t *testing.T) {
+	policy := &filterapi.ToolPolicy{Strip: true, AllowRegex: []string{"^crm_"}, RemoveBuiltInTools: true}
+	matcher, err := toolpolicy.NewMatcher(nil, policy.AllowRegex, nil, nil)
+	require.NoError(t, err)
+
+	t.Run("messages", func(t *testing.T) {
+		out, disallowed, err := filterRequestTools(endpointspec.MessagesEndpointSpec{}, []byte(`{"tools":[`+
+			`{"name":"crm_lookup","input_schema":{}},{"type":"web_search_20250305","name":"web_search"},`+
+			`{"name":"send_email","input_schema":{}}],"tool_choice":{"type":"tool","name":"send_email"}}`), policy, matcher)
+		require.NoError(t, err)
+		require.Equal(t, []string{"send_email"}, disallowed)
+		require.JSONEq(t, `{"tools":[{"name":"crm_lookup","input_schema":{}}]}`, string(out))
+	})
+
+	t.Run("responses", func(t *testing.T) {
+		out, disallowed, err := filterRequestTools(endpointspec.ResponsesEndpointSpec{}, []byte(`{"tools":[`+
+			`{"type":"code_interpreter","container":{"type":"auto"}}],"tool_choice":"auto","parallel_tool_calls":true}`), policy, matcher)
+		require.NoError(t, err)
+		require.Empty(t, disallowed)
+		require.JSONEq(t, `{}`, string(out))
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		out, disallowed, err := filterRequestTools(endpointspec.ResponsesEndpointSpec{}, []byte(`{"tools":[`+
+			`{"type":"function","name":"crm_lookup"}]}`), policy, matcher)
+		require.NoError(t, err)
+		require.Empty(t, disallowed)
+		require.Nil(t, out)
+	})
+}
+
+func Test_filterResponseToolCalls(t *testing.T) {
+	matcher, err := toolpolicy.NewMatcher(nil, nil, []string{"delete_user"}, nil)
+	require.NoError(t, err)
+
+	t.Run("messages", func(t *testing.T) {
+		out, disallowed, err := filterResponseToolCalls(endpointspec.MessagesEndpointSpec{}, []byte(`{"content":[`+
+			`{"type":"text","text":"Deleting."},{"type":"tool_use","id":"t1","name":"delete_user","input":{}}],`+
+			`"stop_reason":"tool_use"}`), matcher)
+		require.NoError(t, err)
+		require.Equal(t, []string{"delete_user"}, disallowed)
+		require.JSONEq(t, `{"content":[{"type":"text","text":"Deleting."}],"stop_reason":"end_turn"}`, string(out))
+	})
+
+	t.Run("responses", func(t *testing.T) {
+		out, disallowed, err := filterResponseToolCalls(endpointspec.ResponsesEndpointSpec{}, []byte(`{"output":[`+
+			`{"type":"function_call","name":"delete_user","arguments":"{}"},{"type":"function_call","name":"get_weather","arguments":"{}"}]}`), matcher)
+		require.NoError(t, err)
+		require.Equal(t, []string{"delete_user"}, disallowed)
+		require.JSONEq(t, `{"output":[{"type":"function_call","name":"get_weather","arguments":"{}"}]}`, string(out))
+	})
+}
+
+func TestToolCallFilter_processStream(t *testing.T) {
+	matcher, err := toolpolicy.NewMatcher(nil, nil, []string{"delete_user"}, nil)
+	require.NoError(t, err)
+
+	t.Run("chat completions strip", func(t *testing.T) {
+		f := &toolCallFilter{format: streamFailoverChatCompletions, matcher: matcher, strip: true}
+		out, denied := f.processStream([]byte(
+			`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"c1","function":{"name":"delete_user","arguments":""}}]}}]}`+"\n\n"+
+				`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"c2","function":{"name":"get_weather","arguments":""}}]}}]}`+"\n\n"+
+				`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{}"}}]}}]}`+"\n\n"+
+				`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"function":{"arguments":"{}"}}]}}]}`+"\n\n"+
+				`data: {"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`+"\n\n"+
+				"data: [DONE]\n\n"), true)
+		require.False(t, denied)
+		require.Equal(t,
+			`data: {"choices":[{"index":0,"delta":{}}]}`+"\n\n"+
+				`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"c2","function":{"name":"get_weather","arguments":""}}]}}]}`+"\n\n"+
+				`data: {"choices":[{"index":0,"delta":{}}]}`+"\n\n"+
+				`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{}"}}]}}]}`+"\n\n"+
+				`data: {"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`+"\n\n"+
+				"data: [DONE]\n\n", string(out))
+	})
+
+	t.Run("messages strip", func(t *testing.T) {
+		f := &toolCallFilter{format: streamFailoverMessages, matcher: matcher, strip: true}
+		out, denied := f.processStream([]byte("event: content_block_start\n"+
+			`data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"t1","name":"delete_user","input":{}}}`+"\n\n"+
+			"event: content_block_delta\n"+
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{}"}}`+"\n\n"+
+			"event: content_block_stop\n"+
+			`data: {"type":"content_block_stop","index":0}`+"\n\n"+
+			"event: content_block_start\n"+
+			`data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`+"\n\n"+
+			"event: message_delta\n"+
+			`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"}}`+"\n\n"), true)
+		require.False(t, denied)
+		require.Equal(t, "event: content_block_start\n"+
+			`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`+"\n\n"+
+			"event: message_delta\n"+
+			`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"}}`+"\n\n", string(out))
+	})
+
+	t.Run("responses block", func(t *testing.T) {
+		f := &toolCallFilter{matcher: matcher}
+		out, denied := f.processStream([]byte("event: response.output_item.added\n"+
+			`data: {"type":"response.output_item.added","output_index":0,"item":{"type":"function_call","name":"delete_user"}}`+"\n\n"), false)
+		require.True(t, denied)
+		require.Contains(t, string(out), "event: error\n")
+		require.Contains(t, string(out), "tool call not allowed on this route: delete_user")
+		// The rest of the stream is dropped.
+		out, denied = f.processStream([]byte(`data: {"type":"response.completed"}`+"\n\n"), true)
+		require.True(t, denied)
+		require.Nil(t, out)
+	})
+}
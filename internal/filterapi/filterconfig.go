@@ -38,6 +38,22 @@ type Config struct {
 	// LLMRequestCost configures the cost of each LLM-related request. Optional. If this is provided, the filter will populate
 	// the "calculated" cost in the filter metadata at the end of the response body processing.
 	LLMRequestCosts []LLMRequestCost `json:"llmRequestCosts,omitempty"`
+	// RequestBodyMatches is the list of the request body matches of the AIGatewayRoute rules. The router filter
+	// evaluates each of them on the parsed request body, and sets the result to the corresponding header.
+	RequestBodyMatches []RequestBodyMatch `json:"requestBodyMatches,omitempty"`
+	// ModelContextWindows maps a model name to the largest context window among the AIGatewayRoute rules matching it,
+	// when all of them have a context window. The router filter rejects a request for the model whose estimated
+	// prompt does not fit in it, since no rule can serve it.
+	ModelContextWindows map[string]int32 `json:"modelContextWindows,omitempty"`
+	// BackendSelections is the list of the backend selections of the AIGatewayRoute rules. The router filter picks
+	// the preferred backend of each of them, and sets its index to the corresponding header.
+	BackendSelections []BackendSelection `json:"backendSelections,omitempty"`
+	// PromptAffinities is the list of the prompt affinities of the AIGatewayRoute rules. The router filter computes
+	// the affinity key of the request for each of them, and sets it to the corresponding header.
+	PromptAffinities []PromptAffinity `json:"promptAffinities,omitempty"`
+	// ModelAliases is the list of the model aliases of the namespaces of the AIGatewayRoutes. The router filter resolves
+	// the model of a request matching an alias to one of its concrete models before the routing.
+	ModelAliases []ModelAlias `json:"modelAliases,omitempty"`
 	// Backends is the list of backends that this listener can route to.
 	Backends []Backend `json:"backends,omitempty"`
 	// Models is the list of models that this route is aware of. Used to populate the "/models" endpoint in OpenAI-compatible APIs.
@@ -54,6 +70,79 @@ type Config struct {
 	MCPConfig *MCPConfig `json:"mcpConfig,omitempty"`
 }
 
+// RequestBodyMatch is a CEL expression matching the request body, whose result is set to a request header
+// so that the HTTPRoute generated from the AIGatewayRoute can match on it.
+type RequestBodyMatch struct {
+	// HeaderName is the name of the header set to "true" or "false" depending on the result of the expression.
+	HeaderName string `json:"headerName"`
+	// CEL is the CEL expression evaluated against the request. See the requestcel package for the variables.
+	CEL string `json:"cel"`
+}
+
+// BackendSelection corresponds to AIGatewayRouteRuleBackendSelection in api/v1beta1/ai_gateway_route.go.
+type BackendSelection struct {
+	// HeaderName is the name of the header set to the index of the preferred backend ref of the rule, which the
+	// HTTPRoute generated from the AIGatewayRoute matches on.
+	HeaderName string `json:"headerName"`
+	// LatencyWeight is the weight of the latency of the backends. Zero means the latency is not considered.
+	LatencyWeight float64 `json:"latencyWeight,omitempty"`
+	// CostWeight is the weight of the cost of the request on the backends. Zero means the cost is not considered.
+	CostWeight float64 `json:"costWeight,omitempty"`
+	// Candidates is the list of the backends which can be preferred.
+	Candidates []BackendSelectionCandidate `json:"candidates,omitempty"`
+}
+
+// BackendSelectionCandidate is a backend which can be preferred by a BackendSelection.
+type BackendSelectionCandidate struct {
+	// Backend is the name of the backend, i.e., the name of one of the Config.Backends.
+	Backend string `json:"backend"`
+	// RefIndex is the index of the backend ref in the rule, which is the value of the header when it is preferred.
+	RefIndex int `json:"refIndex"`
+	// InputTokenPrice is the price of a million input tokens.
+	InputTokenPrice float64 `json:"inputTokenPrice,omitempty"`
+	// OutputTokenPrice is the price of a million output tokens.
+	OutputTokenPrice float64 `json:"outputTokenPrice,omitempty"`
+}
+
+// PromptAffinity corresponds to AIGatewayRouteRuleAffinity in api/v1beta1/ai_gateway_route.go with the Prompt type.
+type PromptAffinity struct {
+	// HeaderName is the name of the header set to the affinity key, which the routes of the rules hash on.
+	HeaderName string `json:"headerName"`
+	// Messages is the number of the messages following the system prompt that are part of the key.
+	Messages int `json:"messages,omitempty"`
+}
+
+// ModelAlias corresponds to ModelAlias in api/v1alpha1/model_alias.go.
+type ModelAlias struct {
+	// Name is the alias, i.e., the public model name sent by the clients.
+	Name string `json:"name"`
+	// Models is the list of the concrete models that the alias is resolved to, in proportion to their weights.
+	// The models with zero weight are omitted.
+	Models []ModelAliasModel `json:"models,omitempty"`
+	// Rollout is the rollout of a new concrete model, or nil if none.
+	Rollout *ModelAliasRollout `json:"rollout,omitempty"`
+	// StickyHeader is the lowercase name of the request header whose value the concrete model is assigned by. When
+	// empty, or when a request does not have the header, the model is picked at random.
+	StickyHeader string `json:"stickyHeader,omitempty"`
+}
+
+// ModelAliasModel is a concrete model that a ModelAlias is resolved to.
+type ModelAliasModel struct {
+	// Name is the name of the concrete model.
+	Name string `json:"name"`
+	// Weight is the proportion of the requests resolved to the model, relative to the other models of the alias.
+	Weight int32 `json:"weight"`
+}
+
+// ModelAliasRollout is the rollout of a new concrete model for a ModelAlias.
+type ModelAliasRollout struct {
+	// Model is the name of the concrete model being rolled out.
+	Model string `json:"model"`
+	// Percent is the percentage of the requests for the alias resolved to the model before the weights of the
+	// models apply.
+	Percent int32 `json:"percent"`
+}
+
 // Model corresponds to the OpenAI model object in the OpenAI-compatible APIs
 // and is used to populate the "/models" endpoint in OpenAI-compatible APIs.
 type Model struct {
@@ -178,6 +267,10 @@ const (
 	// Used for Claude models hosted on AWS Bedrock. Supports both OpenAI and Anthropic input formats
 	// depending on the endpoint path, similar to APISchemaGCPAnthropic.
 	APISchemaAWSAnthropic APISchemaName = "AWSAnthropic"
+	// APISchemaOllama represents the native Ollama API schema.
+	APISchemaOllama APISchemaName = "Ollama"
+	// APISchemaAzureAIInference represents the Azure AI Model Inference API schema used by Azure AI Foundry.
+	APISchemaAzureAIInference APISchemaName = "AzureAIInference"
 )
 
 // RouteRuleName is the name of the route rule.
@@ -197,6 +290,253 @@ type Backend struct {
 	HeaderMutation *HTTPHeaderMutation `json:"httpHeaderMutation,omitempty"`
 	// Body mutations to be applied to the request before sending to the backend. Optional.
 	BodyMutation *HTTPBodyMutation `json:"httpBodyMutation,omitempty"`
+	// PromptCaching configures the automatic placement of prompt cache breakpoints. Optional.
+	PromptCaching *PromptCaching `json:"promptCaching,omitempty"`
+	// CircuitBreaker configures the ejection of the backend based on the AI specific signals. Optional.
+	CircuitBreaker *CircuitBreaker `json:"circuitBreaker,omitempty"`
+	// ContextWindow is the maximum number of tokens accepted by the model of the backend. Zero means unknown. Optional.
+	ContextWindow int32 `json:"contextWindow,omitempty"`
+	// RetriableErrorClasses is the list of the error classes, e.g. "rate_limit", for which the fallback policy of the
+	// route rule retries the request on the error responses of the backend. Optional.
+	RetriableErrorClasses []string `json:"retriableErrorClasses,omitempty"`
+	// FailoverErrorClasses is the subset of RetriableErrorClasses for which the request fails over to the next
+	// priority when the fallback policy also retries the other classes on the same priority. Optional.
+	FailoverErrorClasses []string `json:"failoverErrorClasses,omitempty"`
+	// Hedging is true if the route rule hedges the requests, in which case the upstream attempts of a request may run
+	// concurrently. Optional.
+	Hedging bool `json:"hedging,omitempty"`
+	// Shadows is the list of the shadow backends of the route rule to which the requests are mirrored. Optional.
+	Shadows []Shadow `json:"shadows,omitempty"`
+	// StreamFailoverBackend is the name of the backend, i.e., the name of one of the Config.Backends, on which the
+	// truncated streaming responses of the route rule are continued. Optional.
+	StreamFailoverBackend string `json:"streamFailoverBackend,omitempty"`
+	// ResponseCache configures the cache of the responses of the route rule. Optional.
+	ResponseCache *ResponseCache `json:"responseCache,omitempty"`
+	// Guardrails configures the guardrails of the route rule. Optional.
+	Guardrails *Guardrails `json:"guardrails,omitempty"`
+	// PromptPolicy configures the messages added to the requests by the route rule and the backend. Optional.
+	PromptPolicy *PromptPolicy `json:"promptPolicy,omitempty"`
+	// ToolPolicy configures the tools allowed in the requests and the responses of the route rule. Optional.
+	ToolPolicy *ToolPolicy `json:"toolPolicy,omitempty"`
+	// ParameterPolicy configures the rules applied to the generation parameters of the requests by the route rule and
+	// the backend. Optional.
+	ParameterPolicy *ParameterPolicy `json:"parameterPolicy,omitempty"`
+}
+
+// ParameterPolicy corresponds to ParameterPolicy in api/v1beta1/shared_types.go, merging the ones of the route rule
+// and the AIServiceBackend.
+type ParameterPolicy struct {
+	// Rules is the list of the rules applied to the requests in their order.
+	Rules []ParameterPolicyRule `json:"rules,omitempty"`
+	// BlockedFields is the list of the paths of the fields of the request bodies, e.g., "metadata.user_id", with
+	// which the requests are rejected. Optional.
+	BlockedFields []string `json:"blockedFields,omitempty"`
+}
+
+// ParameterPolicyRule corresponds to ParameterPolicyRule in api/v1beta1/shared_types.go.
+type ParameterPolicyRule struct {
+	// Name is the name of the rule.
+	Name string `json:"name"`
+	// Parameter is the parameter to which the rule applies, e.g., "MaxTokens".
+	Parameter string `json:"parameter"`
+	// Action is the action taken on the parameter, i.e., "Clamp", "Reject" or "Remove".
+	Action string `json:"action"`
+	// Default is the value set on the requests not setting the parameter. Optional.
+	Default string `json:"default,omitempty"`
+	// Min is the minimum value of the parameter. Optional.
+	Min string `json:"min,omitempty"`
+	// Max is the maximum value of the parameter. Optional.
+	Max string `json:"max,omitempty"`
+}
+
+// ToolPolicy corresponds to AIGatewayRouteRuleToolPolicy in api/v1beta1/ai_gateway_route.go.
+type ToolPolicy struct {
+	// Strip is true if the disallowed tools are removed from the requests and the responses rather than rejected.
+	Strip bool `json:"strip,omitempty"`
+	// Allow is the list of the names of the allowed tools.
+	Allow []string `json:"allow,omitempty"`
+	// AllowRegex is the list of the RE2 regular expressions matching the names of the allowed tools.
+	AllowRegex []string `json:"allowRegex,omitempty"`
+	// Deny is the list of the names of the disallowed tools.
+	Deny []string `json:"deny,omitempty"`
+	// DenyRegex is the list of the RE2 regular expressions matching the names of the disallowed tools.
+	DenyRegex []string `json:"denyRegex,omitempty"`
+	// MaxTools is the maximum number of the tools declared in a request, or zero if unlimited.
+	MaxTools int `json:"maxTools,omitempty"`
+	// MaxSchemaBytes is the maximum size of the JSON schema of the parameters of each tool, or zero if unlimited.
+	MaxSchemaBytes int `json:"maxSchemaBytes,omitempty"`
+	// RemoveBuiltInTools is true if the built-in tools of the providers are removed from the requests.
+	RemoveBuiltInTools bool `json:"removeBuiltInTools,omitempty"`
+}
+
+// PromptPolicy corresponds to PromptPolicy in api/v1beta1/shared_types.go, merging the ones of the route rule and
+// the AIServiceBackend.
+type PromptPolicy struct {
+	// Lock is true if the requests with the system and developer messages of the client are rejected.
+	Lock bool `json:"lock,omitempty"`
+	// Messages is the list of the messages added to the requests in their order.
+	Messages []PromptPolicyMessage `json:"messages,omitempty"`
+}
+
+// PromptPolicyMessage corresponds to PromptPolicyMessage in api/v1beta1/shared_types.go.
+type PromptPolicyMessage struct {
+	// Developer is true if the message is a developer message rather than a system message.
+	Developer bool `json:"developer,omitempty"`
+	// Append is true if the message is added after the system and developer messages of the client rather than
+	// before them.
+	Append bool `json:"append,omitempty"`
+	// Template is the Go text/template of the content of the message.
+	Template string `json:"template"`
+}
+
+// Guardrails corresponds to AIGatewayRouteRuleGuardrails in api/v1beta1/ai_gateway_route.go.
+type Guardrails struct {
+	// PII configures the detection of the personally identifiable information in the requests. Optional.
+	PII *PIIGuardrail `json:"pii,omitempty"`
+	// External configures the external guardrail service checking the requests and the responses. Optional.
+	External *ExternalGuardrail `json:"external,omitempty"`
+	// PromptInjection configures the detection of the prompt injection in the requests. Optional.
+	PromptInjection *PromptInjectionGuardrail `json:"promptInjection,omitempty"`
+	// Output configures the filter of the text output of the responses. Optional.
+	Output *OutputGuardrail `json:"output,omitempty"`
+}
+
+// ExternalGuardrail corresponds to AIGatewayRouteRuleExternalGuardrail in api/v1beta1/ai_gateway_route.go.
+type ExternalGuardrail struct {
+	// Protocol is the protocol of the service, i.e., "HTTP" or "GRPC".
+	Protocol string `json:"protocol"`
+	// Endpoint is the URL of the HTTP service or the target of the gRPC service.
+	Endpoint string `json:"endpoint"`
+	// Timeout is the maximum time of each call to the service.
+	Timeout time.Duration `json:"timeout"`
+	// FailOpen is true if the requests and the responses are let through when the service fails.
+	FailOpen bool `json:"failOpen,omitempty"`
+	// ResponseWindowSize is the number of the characters of the text output of a streaming response held back
+	// between the checks of the response, or zero if the responses are not checked.
+	ResponseWindowSize int `json:"responseWindowSize,omitempty"`
+}
+
+// PIIGuardrail corresponds to AIGatewayRouteRulePIIGuardrail in api/v1beta1/ai_gateway_route.go.
+type PIIGuardrail struct {
+	// Action is the action taken on the detected information.
+	Action PIIGuardrailAction `json:"action"`
+	// Entities is the list of the built-in types of the information to detect, e.g., "Email".
+	Entities []string `json:"entities,omitempty"`
+	// Patterns is the list of the custom types of the information to detect.
+	Patterns []PIIPattern `json:"patterns,omitempty"`
+}
+
+// PIIGuardrailAction corresponds to PIIGuardrailAction in api/v1beta1/ai_gateway_route.go.
+type PIIGuardrailAction string
+
+const (
+	// PIIGuardrailActionMask replaces the detected information with a placeholder of its type.
+	PIIGuardrailActionMask PIIGuardrailAction = "Mask"
+	// PIIGuardrailActionBlock rejects the request.
+	PIIGuardrailActionBlock PIIGuardrailAction = "Block"
+	// PIIGuardrailActionTokenize replaces the detected information with a token restored in the response.
+	PIIGuardrailActionTokenize PIIGuardrailAction = "Tokenize"
+)
+
+// PIIPattern corresponds to AIGatewayRouteRulePIIPattern in api/v1beta1/ai_gateway_route.go.
+type PIIPattern struct {
+	// Name is the name of the custom type.
+	Name string `json:"name"`
+	// Regex is the RE2 regular expression matching the information.
+	Regex string `json:"regex"`
+}
+
+// PromptInjectionGuardrail corresponds to AIGatewayRouteRulePromptInjectionGuardrail in api/v1beta1/ai_gateway_route.go.
+type PromptInjectionGuardrail struct {
+	// Shadow is true if the requests with the detected injection are only logged and sent to the backend.
+	Shadow bool `json:"shadow,omitempty"`
+	// Rules is the list of the built-in rules of the detection, e.g., "IgnoreInstructions".
+	Rules []string `json:"rules,omitempty"`
+	// Patterns is the list of the custom rules of the detection.
+	Patterns []PromptInjectionPattern `json:"patterns,omitempty"`
+	// Threshold is the score of a text at or above which the injection is detected.
+	Threshold int `json:"threshold"`
+}
+
+// PromptInjectionPattern corresponds to AIGatewayRouteRulePromptInjectionPattern in api/v1beta1/ai_gateway_route.go.
+type PromptInjectionPattern struct {
+	// Name is the name of the custom rule.
+	Name string `json:"name"`
+	// Regex is the RE2 regular expression matching the injection.
+	Regex string `json:"regex"`
+	// Score is added to the score of the text matching the rule.
+	Score int `json:"score"`
+}
+
+// OutputGuardrail corresponds to AIGatewayRouteRuleOutputGuardrail in api/v1beta1/ai_gateway_route.go.
+type OutputGuardrail struct {
+	// Shadow is true if the output matching a rule is only logged and returned to the client as is.
+	Shadow bool `json:"shadow,omitempty"`
+	// Rules is the list of the rules of the filter.
+	Rules []OutputFilterRule `json:"rules"`
+	// WindowSize is the number of the characters of the streamed text before each delta matched together with it.
+	WindowSize int `json:"windowSize"`
+}
+
+// OutputFilterRule corresponds to AIGatewayRouteRuleOutputFilterRule in api/v1beta1/ai_gateway_route.go.
+type OutputFilterRule struct {
+	// Name is the name of the rule.
+	Name string `json:"name"`
+	// Regex is the RE2 regular expression matching the output. Either this or Keywords is set.
+	Regex string `json:"regex,omitempty"`
+	// Keywords are the keywords matching the output regardless of the case.
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// ResponseCache corresponds to AIGatewayRouteRuleResponseCache in api/v1beta1/ai_gateway_route.go.
+type ResponseCache struct {
+	// TTL is the time for which a cached response is served.
+	TTL time.Duration `json:"ttl"`
+
+	// Semantic configures the semantic mode of the cache. Optional.
+	Semantic *SemanticCache `json:"semantic,omitempty"`
+}
+
+// SemanticCache corresponds to AIGatewayRouteRuleSemanticCache in api/v1beta1/ai_gateway_route.go.
+type SemanticCache struct {
+	// EmbeddingsBackend is the name of the embeddings backend, i.e., the name of one of the Config.Backends, whose
+	// ModelNameOverride is the embedding model.
+	EmbeddingsBackend string `json:"embeddingsBackend"`
+	// SimilarityThreshold is the minimum cosine similarity, from 0 to 1, at or above which the cached response is
+	// served.
+	SimilarityThreshold float64 `json:"similarityThreshold"`
+}
+
+// Shadow corresponds to AIGatewayRouteRuleShadow in api/v1beta1/ai_gateway_route.go.
+type Shadow struct {
+	// Backend is the name of the shadow backend, i.e., the name of one of the Config.Backends.
+	Backend string `json:"backend"`
+	// Percent is the percentage of the requests mirrored to the shadow backend.
+	Percent int `json:"percent"`
+	// CompareResponses records the similarity of the response of the shadow backend to the one returned to the client.
+	CompareResponses bool `json:"compareResponses,omitempty"`
+}
+
+// PromptCaching corresponds to PromptCaching in api/v1beta1/ai_service_backend.go.
+type PromptCaching struct {
+	// System places a cache breakpoint at the end of the system prompt.
+	System bool `json:"system,omitempty"`
+	// Tools places a cache breakpoint at the end of the tool definitions.
+	Tools bool `json:"tools,omitempty"`
+	// LastTurns is the number of the most recent conversation messages on which a cache breakpoint is placed.
+	LastTurns int `json:"lastTurns,omitempty"`
+	// TTL is the time-to-live of the placed cache breakpoints, e.g. "5m" or "1h". Empty means the provider default.
+	TTL string `json:"ttl,omitempty"`
+}
+
+// CircuitBreaker corresponds to CircuitBreaker in api/v1beta1/ai_service_backend.go.
+type CircuitBreaker struct {
+	// ConsecutiveFailures is the number of failures in a row after which the circuit breaker opens.
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+	// TimeToFirstToken is the objective of the time to first token of the streaming responses. Zero means disabled.
+	TimeToFirstToken time.Duration `json:"timeToFirstToken,omitempty"`
+	// OpenDuration is the time during which the circuit breaker stays open before letting a probe request through.
+	OpenDuration time.Duration `json:"openDuration"`
 }
 
 // BackendAuth corresponds partially to BackendSecurityPolicy in api/v1alpha1/api.go.
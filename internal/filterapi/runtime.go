@@ -11,8 +11,17 @@ import (
 
 	"github.com/google/cel-go/cel"
 
+	"github.com/envoyproxy/ai-gateway/internal/audit"
+	"github.com/envoyproxy/ai-gateway/internal/backendselection"
+	"github.com/envoyproxy/ai-gateway/internal/circuitbreaker"
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
 	"github.com/envoyproxy/ai-gateway/internal/internalapi"
 	"github.com/envoyproxy/ai-gateway/internal/llmcostcel"
+	"github.com/envoyproxy/ai-gateway/internal/parameterpolicy"
+	"github.com/envoyproxy/ai-gateway/internal/promptpolicy"
+	"github.com/envoyproxy/ai-gateway/internal/requestcel"
+	"github.com/envoyproxy/ai-gateway/internal/responsecache"
+	"github.com/envoyproxy/ai-gateway/internal/toolpolicy"
 )
 
 // BackendAuthHandler is the interface that deals with the backend auth for a specific backend.
@@ -35,6 +44,16 @@ type RuntimeConfig struct {
 	// RequestCosts is the list of route-scoped request costs.
 	// Each entry has a RouteName identifying the route it applies to.
 	RequestCosts []RuntimeRequestCost
+	// RequestBodyMatches is the list of request body matches of the AIGatewayRoute rules.
+	RequestBodyMatches []RuntimeRequestBodyMatch
+	// ModelContextWindows maps a model name to the largest context window of the rules matching it.
+	ModelContextWindows map[string]int32
+	// BackendSelections is the list of the backend selections of the AIGatewayRoute rules.
+	BackendSelections []BackendSelection
+	// PromptAffinities is the list of the prompt affinities of the AIGatewayRoute rules.
+	PromptAffinities []PromptAffinity
+	// ModelAliases maps an alias to the model alias resolving it.
+	ModelAliases map[string]*ModelAlias
 	// DeclaredModels is the list of declared models.
 	DeclaredModels []Model
 	// ModelsByHost maps hostnames to their specific model lists for per-host filtering. Each entry already includes
@@ -45,6 +64,10 @@ type RuntimeConfig struct {
 	UnscopedModels []Model
 	// Backends is the map of backends by name.
 	Backends map[string]*RuntimeBackend
+	// AuditLogger is the logger of the audit records of the requests, or nil if the requests are not audited. This is
+	// shared across the configuration updates, and set by the external processor server after the creation of the
+	// RuntimeConfig.
+	AuditLogger *audit.Logger
 }
 
 // RuntimeBackend is a filter backend with its auth handler that is derived from the filterapi.Backend configuration.
@@ -53,6 +76,39 @@ type RuntimeBackend struct {
 	Backend *Backend
 	// Handler is the backend auth handler.
 	Handler BackendAuthHandler
+	// CircuitBreaker is the circuit breaker of the backend, or nil if not configured. This is shared across the
+	// configuration updates, and set by the external processor server after the creation of the RuntimeConfig.
+	CircuitBreaker *circuitbreaker.Breaker
+	// Latency is the latency of the backend if it is a candidate of a backend selection, or nil otherwise. This is
+	// shared across the configuration updates, and set by the external processor server like CircuitBreaker.
+	Latency *backendselection.Latency
+	// ResponseCache is the store of the cached responses if the route rule of the backend has the response cache, or
+	// nil otherwise. This is set by the external processor server like CircuitBreaker.
+	ResponseCache responsecache.Store
+	// ResponseCacheVectorStore is the store of the embeddings of the cached requests if the response cache of the
+	// route rule of the backend is in the semantic mode, or nil otherwise. This is set like ResponseCache.
+	ResponseCacheVectorStore responsecache.VectorStore
+	// PIIDetector detects the personally identifiable information in the requests if the route rule of the backend has
+	// the PII guardrail, or nil otherwise.
+	PIIDetector *guardrail.PIIDetector
+	// ExternalChecker calls the external guardrail service if the route rule of the backend has the external
+	// guardrail, or nil otherwise.
+	ExternalChecker guardrail.ExternalChecker
+	// PromptInjectionDetector detects the prompt injection in the requests if the route rule of the backend has the
+	// prompt injection guardrail, or nil otherwise.
+	PromptInjectionDetector *guardrail.PromptInjectionDetector
+	// OutputFilter filters the text output of the responses if the route rule of the backend has the output
+	// guardrail, or nil otherwise.
+	OutputFilter *guardrail.OutputFilter
+	// PromptTemplates are the templates of the messages of the prompt policy of the backend in the same order as
+	// Backend.PromptPolicy.Messages, or nil if the backend has no prompt policy.
+	PromptTemplates []*promptpolicy.Template
+	// ToolMatcher matches the names of the tools if the tool policy of the route rule of the backend has the allow or
+	// the deny rules, or nil otherwise.
+	ToolMatcher *toolpolicy.Matcher
+	// ParameterRules are the rules of the parameter policy of the backend in the same order as
+	// Backend.ParameterPolicy.Rules, or nil if the backend has no parameter policy.
+	ParameterRules []*parameterpolicy.Rule
 }
 
 // RuntimeGlobalRequestCost is the configuration for gateway-level default request costs.
@@ -69,6 +125,13 @@ type RuntimeRequestCost struct {
 	CELProg cel.Program
 }
 
+// RuntimeRequestBodyMatch is the configuration for a request body match with its compiled CEL program.
+// This is derived from the filterapi.RequestBodyMatch configuration.
+type RuntimeRequestBodyMatch struct {
+	*RequestBodyMatch
+	CELProg cel.Program
+}
+
 // NewRuntimeConfig creates a new runtime filter configuration from the given filterapi.Config and a function to create backend auth handlers.
 func NewRuntimeConfig(ctx context.Context, config *Config, fn NewBackendAuthHandlerFunc) (*RuntimeConfig, error) {
 	backends := make(map[string]*RuntimeBackend, len(config.Backends))
@@ -83,7 +146,95 @@ func NewRuntimeConfig(ctx context.Context, config *Config, fn NewBackendAuthHand
 			}
 		}
 
-		backends[b.Name] = &RuntimeBackend{Backend: b, Handler: h}
+		var piiDetector *guardrail.PIIDetector
+		if b.Guardrails != nil && b.Guardrails.PII != nil {
+			pii := b.Guardrails.PII
+			patterns := make([]guardrail.PIIPattern, len(pii.Patterns))
+			for j, p := range pii.Patterns {
+				patterns[j] = guardrail.PIIPattern{Name: p.Name, Regex: p.Regex}
+			}
+			var err error
+			piiDetector, err = guardrail.NewPIIDetector(pii.Entities, patterns)
+			if err != nil {
+				return nil, fmt.Errorf("cannot create PII detector for backend %q: %w", b.Name, err)
+			}
+		}
+
+		var externalChecker guardrail.ExternalChecker
+		if b.Guardrails != nil && b.Guardrails.External != nil {
+			ext := b.Guardrails.External
+			var err error
+			externalChecker, err = guardrail.NewExternalChecker(ext.Protocol, ext.Endpoint, ext.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("cannot create external guardrail checker for backend %q: %w", b.Name, err)
+			}
+		}
+
+		var promptInjectionDetector *guardrail.PromptInjectionDetector
+		if b.Guardrails != nil && b.Guardrails.PromptInjection != nil {
+			pi := b.Guardrails.PromptInjection
+			patterns := make([]guardrail.PromptInjectionPattern, len(pi.Patterns))
+			for j, p := range pi.Patterns {
+				patterns[j] = guardrail.PromptInjectionPattern{Name: p.Name, Regex: p.Regex, Score: p.Score}
+			}
+			var err error
+			promptInjectionDetector, err = guardrail.NewPromptInjectionDetector(pi.Rules, patterns, pi.Threshold)
+			if err != nil {
+				return nil, fmt.Errorf("cannot create prompt injection detector for backend %q: %w", b.Name, err)
+			}
+		}
+
+		var outputFilter *guardrail.OutputFilter
+		if b.Guardrails != nil && b.Guardrails.Output != nil {
+			out := b.Guardrails.Output
+			rules := make([]guardrail.OutputRule, len(out.Rules))
+			for j, r := range out.Rules {
+				rules[j] = guardrail.OutputRule{Name: r.Name, Regex: r.Regex, Keywords: r.Keywords}
+			}
+			var err error
+			outputFilter, err = guardrail.NewOutputFilter(rules, out.WindowSize)
+			if err != nil {
+				return nil, fmt.Errorf("cannot create output filter for backend %q: %w", b.Name, err)
+			}
+		}
+
+		var promptTemplates []*promptpolicy.Template
+		if b.PromptPolicy != nil {
+			for j, m := range b.PromptPolicy.Messages {
+				tmpl, err := promptpolicy.NewTemplate(m.Template)
+				if err != nil {
+					return nil, fmt.Errorf("cannot create template of prompt policy message %d for backend %q: %w", j, b.Name, err)
+				}
+				promptTemplates = append(promptTemplates, tmpl)
+			}
+		}
+
+		var toolMatcher *toolpolicy.Matcher
+		if tp := b.ToolPolicy; tp != nil && len(tp.Allow)+len(tp.AllowRegex)+len(tp.Deny)+len(tp.DenyRegex) > 0 {
+			var err error
+			toolMatcher, err = toolpolicy.NewMatcher(tp.Allow, tp.AllowRegex, tp.Deny, tp.DenyRegex)
+			if err != nil {
+				return nil, fmt.Errorf("cannot create tool matcher for backend %q: %w", b.Name, err)
+			}
+		}
+
+		var parameterRules []*parameterpolicy.Rule
+		if b.ParameterPolicy != nil {
+			for _, r := range b.ParameterPolicy.Rules {
+				rule, err := parameterpolicy.NewRule(r.Name, parameterpolicy.Parameter(r.Parameter),
+					parameterpolicy.Action(r.Action), r.Default, r.Min, r.Max)
+				if err != nil {
+					return nil, fmt.Errorf("cannot create parameter policy rule %q for backend %q: %w", r.Name, b.Name, err)
+				}
+				parameterRules = append(parameterRules, rule)
+			}
+		}
+
+		backends[b.Name] = &RuntimeBackend{
+			Backend: b, Handler: h, PIIDetector: piiDetector, ExternalChecker: externalChecker,
+			PromptInjectionDetector: promptInjectionDetector, PromptTemplates: promptTemplates, ToolMatcher: toolMatcher,
+			ParameterRules: parameterRules, OutputFilter: outputFilter,
+		}
 	}
 
 	// Compile CEL programs for GlobalLLMRequestCosts (gateway-level defaults).
@@ -123,13 +274,37 @@ func NewRuntimeConfig(ctx context.Context, config *Config, fn NewBackendAuthHand
 		costs = append(costs, RuntimeRequestCost{LLMRequestCost: c, CELProg: prog})
 	}
 
+	var modelAliases map[string]*ModelAlias
+	if len(config.ModelAliases) > 0 {
+		modelAliases = make(map[string]*ModelAlias, len(config.ModelAliases))
+		for i := range config.ModelAliases {
+			a := &config.ModelAliases[i]
+			modelAliases[a.Name] = a
+		}
+	}
+
+	bodyMatches := make([]RuntimeRequestBodyMatch, 0, len(config.RequestBodyMatches))
+	for i := range config.RequestBodyMatches {
+		m := &config.RequestBodyMatches[i]
+		prog, err := requestcel.NewProgram(m.CEL)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create CEL program for request body match %q: %w", m.HeaderName, err)
+		}
+		bodyMatches = append(bodyMatches, RuntimeRequestBodyMatch{RequestBodyMatch: m, CELProg: prog})
+	}
+
 	return &RuntimeConfig{
-		UUID:               config.UUID,
-		Backends:           backends,
-		GlobalRequestCosts: globalCosts,
-		RequestCosts:       costs,
-		DeclaredModels:     config.Models,
-		ModelsByHost:       config.ModelsByHost,
-		UnscopedModels:     config.UnscopedModels,
+		UUID:                config.UUID,
+		Backends:            backends,
+		GlobalRequestCosts:  globalCosts,
+		RequestCosts:        costs,
+		RequestBodyMatches:  bodyMatches,
+		ModelContextWindows: config.ModelContextWindows,
+		BackendSelections:   config.BackendSelections,
+		PromptAffinities:    config.PromptAffinities,
+		ModelAliases:        modelAliases,
+		DeclaredModels:      config.Models,
+		ModelsByHost:        config.ModelsByHost,
+		UnscopedModels:      config.UnscopedModels,
 	}, nil
 }
@@ -41,6 +41,9 @@ func TestServer_LoadConfig(t *testing.T) {
 					CreatedAt: now,
 				},
 			},
+			ModelAliases: []ModelAlias{
+				{Name: "chat-default", Models: []ModelAliasModel{{Name: "gpt4.4444", Weight: 1}}},
+			},
 		}
 		rc, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, b *BackendAuth) (BackendAuthHandler, error) {
 			require.NotNil(t, b)
@@ -63,6 +66,7 @@ func TestServer_LoadConfig(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, uint64(2), val)
 		require.Equal(t, config.Models, rc.DeclaredModels)
+		require.Equal(t, map[string]*ModelAlias{"chat-default": &config.ModelAliases[0]}, rc.ModelAliases)
 	})
 
 	t.Run("with global costs", func(t *testing.T) {
@@ -118,6 +122,212 @@ func TestServer_LoadConfig(t *testing.T) {
 		require.Contains(t, err.Error(), "cannot create CEL program for cost")
 	})
 
+	t.Run("request body matches", func(t *testing.T) {
+		config := &Config{
+			RequestBodyMatches:  []RequestBodyMatch{{HeaderName: "x-ai-eg-body-match-1", CEL: "has_images"}},
+			ModelContextWindows: map[string]int32{"gpt-4o-mini": 128000},
+			BackendSelections:   []BackendSelection{{HeaderName: "x-ai-eg-backend-selection-1", LatencyWeight: 1}},
+			PromptAffinities:    []PromptAffinity{{HeaderName: "x-ai-eg-affinity-prompt-1", Messages: 1}},
+		}
+		rc, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.NoError(t, err)
+		require.Len(t, rc.RequestBodyMatches, 1)
+		require.Equal(t, "x-ai-eg-body-match-1", rc.RequestBodyMatches[0].HeaderName)
+		require.NotNil(t, rc.RequestBodyMatches[0].CELProg)
+		require.Equal(t, map[string]int32{"gpt-4o-mini": 128000}, rc.ModelContextWindows)
+		require.Equal(t, []BackendSelection{{HeaderName: "x-ai-eg-backend-selection-1", LatencyWeight: 1}}, rc.BackendSelections)
+		require.Equal(t, []PromptAffinity{{HeaderName: "x-ai-eg-affinity-prompt-1", Messages: 1}}, rc.PromptAffinities)
+	})
+
+	t.Run("error - invalid CEL in request body match", func(t *testing.T) {
+		config := &Config{
+			RequestBodyMatches: []RequestBodyMatch{{HeaderName: "x-ai-eg-body-match-1", CEL: "estimated_prompt_tokens"}},
+		}
+		_, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.ErrorContains(t, err, `cannot create CEL program for request body match "x-ai-eg-body-match-1"`)
+	})
+
+	t.Run("pii guardrail", func(t *testing.T) {
+		config := &Config{Backends: []Backend{
+			{Name: "with-pii", Guardrails: &Guardrails{PII: &PIIGuardrail{
+				Action: PIIGuardrailActionMask, Entities: []string{"Email"},
+				Patterns: []PIIPattern{{Name: "employee_id", Regex: `EMP-\d{6}`}},
+			}}},
+			{Name: "without-pii"},
+		}}
+		rc, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.NoError(t, err)
+		require.NotNil(t, rc.Backends["with-pii"].PIIDetector)
+		require.Nil(t, rc.Backends["without-pii"].PIIDetector)
+	})
+
+	t.Run("error - invalid PII pattern", func(t *testing.T) {
+		config := &Config{Backends: []Backend{
+			{Name: "bad", Guardrails: &Guardrails{PII: &PIIGuardrail{Patterns: []PIIPattern{{Name: "x", Regex: "("}}}}},
+		}}
+		_, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.ErrorContains(t, err, `cannot create PII detector for backend "bad"`)
+	})
+
+	t.Run("external guardrail", func(t *testing.T) {
+		config := &Config{Backends: []Backend{
+			{Name: "with-external", Guardrails: &Guardrails{External: &ExternalGuardrail{
+				Protocol: "GRPC", Endpoint: "classifier.safety:9090", Timeout: time.Second,
+			}}},
+			{Name: "without-external"},
+		}}
+		rc, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.NoError(t, err)
+		require.NotNil(t, rc.Backends["with-external"].ExternalChecker)
+		require.Nil(t, rc.Backends["without-external"].ExternalChecker)
+	})
+
+	t.Run("error - invalid external guardrail", func(t *testing.T) {
+		config := &Config{Backends: []Backend{
+			{Name: "bad", Guardrails: &Guardrails{External: &ExternalGuardrail{Protocol: "HTTP", Endpoint: "classifier:8080"}}},
+		}}
+		_, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.ErrorContains(t, err, `cannot create external guardrail checker for backend "bad"`)
+	})
+
+	t.Run("prompt injection guardrail", func(t *testing.T) {
+		config := &Config{Backends: []Backend{
+			{Name: "with-prompt-injection", Guardrails: &Guardrails{PromptInjection: &PromptInjectionGuardrail{
+				Rules: []string{"IgnoreInstructions"}, Threshold: 50,
+				Patterns: []PromptInjectionPattern{{Name: "exfiltration", Regex: `send .* to https?://`, Score: 50}},
+			}}},
+			{Name: "without-prompt-injection"},
+		}}
+		rc, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.NoError(t, err)
+		require.NotNil(t, rc.Backends["with-prompt-injection"].PromptInjectionDetector)
+		require.Nil(t, rc.Backends["without-prompt-injection"].PromptInjectionDetector)
+	})
+
+	t.Run("error - invalid prompt injection guardrail", func(t *testing.T) {
+		config := &Config{Backends: []Backend{
+			{Name: "bad", Guardrails: &Guardrails{PromptInjection: &PromptInjectionGuardrail{Rules: []string{"Telepathy"}, Threshold: 50}}},
+		}}
+		_, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.ErrorContains(t, err, `cannot create prompt injection detector for backend "bad"`)
+	})
+
+	t.Run("prompt policy", func(t *testing.T) {
+		config := &Config{Backends: []Backend{
+			{Name: "with-prompt-policy", PromptPolicy: &PromptPolicy{Messages: []PromptPolicyMessage{
+				{Template: "You are a helpful assistant."},
+				{Template: `Tenant: {{ index .Headers "x-tenant-id" }}`, Append: true},
+			}}},
+			{Name: "without-prompt-policy"},
+		}}
+		rc, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.NoError(t, err)
+		require.Len(t, rc.Backends["with-prompt-policy"].PromptTemplates, 2)
+		require.Nil(t, rc.Backends["without-prompt-policy"].PromptTemplates)
+	})
+
+	t.Run("error - invalid prompt policy template", func(t *testing.T) {
+		config := &Config{Backends: []Backend{
+			{Name: "bad", PromptPolicy: &PromptPolicy{Messages: []PromptPolicyMessage{{Template: "{{ .Headers"}}}},
+		}}
+		_, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.ErrorContains(t, err, `cannot create template of prompt policy message 0 for backend "bad"`)
+	})
+
+	t.Run("tool policy", func(t *testing.T) {
+		config := &Config{Backends: []Backend{
+			{Name: "with-rules", ToolPolicy: &ToolPolicy{Allow: []string{"get_weather"}, DenyRegex: []string{"^delete_"}}},
+			{Name: "with-limits", ToolPolicy: &ToolPolicy{MaxTools: 8, RemoveBuiltInTools: true}},
+		}}
+		rc, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.NoError(t, err)
+		require.NotNil(t, rc.Backends["with-rules"].ToolMatcher)
+		require.True(t, rc.Backends["with-rules"].ToolMatcher.Allows("get_weather"))
+		require.Nil(t, rc.Backends["with-limits"].ToolMatcher)
+	})
+
+	t.Run("error - invalid tool policy regex", func(t *testing.T) {
+		config := &Config{Backends: []Backend{{Name: "bad", ToolPolicy: &ToolPolicy{AllowRegex: []string{"("}}}}}
+		_, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.ErrorContains(t, err, `cannot create tool matcher for backend "bad"`)
+	})
+
+	t.Run("parameter policy", func(t *testing.T) {
+		config := &Config{Backends: []Backend{
+			{Name: "with-parameter-policy", ParameterPolicy: &ParameterPolicy{Rules: []ParameterPolicyRule{
+				{Name: "cap-max-tokens", Parameter: "MaxTokens", Action: "Clamp", Default: "1024", Max: "4096"},
+				{Name: "block-n", Parameter: "N", Action: "Reject"},
+			}}},
+			{Name: "without-parameter-policy"},
+		}}
+		rc, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.NoError(t, err)
+		require.Len(t, rc.Backends["with-parameter-policy"].ParameterRules, 2)
+		require.Equal(t, "block-n", rc.Backends["with-parameter-policy"].ParameterRules[1].Name)
+		require.Nil(t, rc.Backends["without-parameter-policy"].ParameterRules)
+	})
+
+	t.Run("error - invalid parameter policy rule", func(t *testing.T) {
+		config := &Config{Backends: []Backend{{Name: "bad", ParameterPolicy: &ParameterPolicy{Rules: []ParameterPolicyRule{
+			{Name: "cap-temperature", Parameter: "Temperature", Action: "Clamp", Max: "hot"},
+		}}}}}
+		_, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.ErrorContains(t, err, `cannot create parameter policy rule "cap-temperature" for backend "bad"`)
+	})
+
+	t.Run("output guardrail", func(t *testing.T) {
+		config := &Config{Backends: []Backend{
+			{Name: "with-output", Guardrails: &Guardrails{Output: &OutputGuardrail{
+				Rules: []OutputFilterRule{{Name: "codename", Keywords: []string{"Project Orion"}}, {Name: "card", Regex: `\d{16}`}}, WindowSize: 256,
+			}}},
+			{Name: "without-output"},
+		}}
+		rc, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.NoError(t, err)
+		require.NotNil(t, rc.Backends["with-output"].OutputFilter)
+		require.Nil(t, rc.Backends["without-output"].OutputFilter)
+	})
+
+	t.Run("error - invalid output guardrail", func(t *testing.T) {
+		config := &Config{Backends: []Backend{
+			{Name: "bad", Guardrails: &Guardrails{Output: &OutputGuardrail{Rules: []OutputFilterRule{{Name: "bad", Regex: "("}}, WindowSize: 256}}},
+		}}
+		_, err := NewRuntimeConfig(t.Context(), config, func(_ context.Context, _ *BackendAuth) (BackendAuthHandler, error) {
+			return nil, nil
+		})
+		require.ErrorContains(t, err, `cannot create output filter for backend "bad"`)
+	})
+
 	t.Run("error - route cost with empty RouteName", func(t *testing.T) {
 		config := &Config{
 			LLMRequestCosts: []LLMRequestCost{
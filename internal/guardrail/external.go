@@ -0,0 +1,297 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package guardrail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+// The protocols of the external guardrail services, which correspond to ExternalGuardrailProtocol in
+// api/v1beta1/ai_gateway_route.go.
+const (
+	ExternalProtocolHTTP = "HTTP"
+	ExternalProtocolGRPC = "GRPC"
+)
+
+// ExternalGRPCService and ExternalGRPCMethod are the names of the gRPC service and its method called on the external
+// guardrail services. The request and the response of the method are the google.protobuf.Struct messages holding the
+// ExternalCheckRequest and the ExternalCheckResponse in their JSON format, so that the services need no generated code
+// of the gateway.
+const (
+	ExternalGRPCService = "envoy.ai_gateway.guardrail.v1.ExternalGuardrail"
+	ExternalGRPCMethod  = "/" + ExternalGRPCService + "/Check"
+)
+
+// The phases of the exchange checked by the external guardrail services.
+const (
+	// ExternalPhaseRequest is the check of the request before it is sent to the backend.
+	ExternalPhaseRequest = "request"
+	// ExternalPhaseResponse is the check of the output of the backend before it is returned to the client.
+	ExternalPhaseResponse = "response"
+)
+
+// ExternalDecision is the decision of the external guardrail service on a check.
+type ExternalDecision string
+
+const (
+	// ExternalDecisionAllow lets the request or the response through unchanged.
+	ExternalDecisionAllow ExternalDecision = "allow"
+	// ExternalDecisionDeny rejects the request, or terminates the response.
+	ExternalDecisionDeny ExternalDecision = "deny"
+	// ExternalDecisionRewrite replaces the content of the messages of the request with the ones of the response. This
+	// is only valid for the requests.
+	ExternalDecisionRewrite ExternalDecision = "rewrite"
+)
+
+// ExternalMessage is a text of the request in the normalized view, e.g., the content of a message or the system
+// prompt.
+type ExternalMessage struct {
+	// Role is the role of the text, i.e., "system", "user", "assistant" or "tool".
+	Role string `json:"role"`
+	// Content is the text.
+	Content string `json:"content"`
+}
+
+// ExternalTool is a tool of the request in the normalized view.
+type ExternalTool struct {
+	// Name is the name of the function, or the type of the built-in tool, e.g., "web_search".
+	Name string `json:"name"`
+	// Description is the description of the function, if any.
+	Description string `json:"description,omitempty"`
+}
+
+// ExternalCheckRequest is the normalized view of the request sent to the external guardrail service, which is the
+// same regardless of the API schema of the request.
+type ExternalCheckRequest struct {
+	// Phase is the checked part of the exchange, i.e., ExternalPhaseRequest or ExternalPhaseResponse.
+	Phase string `json:"phase"`
+	// Model is the model of the request sent to the backend.
+	Model string `json:"model,omitempty"`
+	// Messages are the texts of the request in their order, one for each text of the messages, e.g., each text part of
+	// a message with multiple parts.
+	Messages []ExternalMessage `json:"messages"`
+	// Tools are the tools available to the model.
+	Tools []ExternalTool `json:"tools,omitempty"`
+	// Output is the text output of the backend so far on the response checks.
+	Output string `json:"output,omitempty"`
+	// Complete is true if Output is the whole text output of the backend, and false if more of a streaming response
+	// follows.
+	Complete bool `json:"complete,omitempty"`
+}
+
+// ExternalCheckResponse is the decision of the external guardrail service.
+type ExternalCheckResponse struct {
+	// Decision is the decision on the check.
+	Decision ExternalDecision `json:"decision"`
+	// Reason is the reason for the decision, which is returned to the client on denials.
+	Reason string `json:"reason,omitempty"`
+	// Messages are the rewritten texts of the request when Decision is ExternalDecisionRewrite, which correspond one to
+	// one to the Messages of the ExternalCheckRequest.
+	Messages []ExternalMessage `json:"messages,omitempty"`
+}
+
+// ExternalChecker checks the requests and the responses with an external guardrail service.
+type ExternalChecker interface {
+	// Check sends the normalized view of the request to the service, and returns its decision.
+	Check(ctx context.Context, req *ExternalCheckRequest) (*ExternalCheckResponse, error)
+}
+
+// NewExternalChecker creates a new ExternalChecker calling the service at the endpoint with the protocol, i.e., the
+// URL of the HTTP service or the target of the gRPC service. Each check is bounded by the timeout.
+func NewExternalChecker(protocol, endpoint string, timeout time.Duration) (ExternalChecker, error) {
+	switch protocol {
+	case ExternalProtocolHTTP:
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL of the external guardrail: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("unsupported scheme of the external guardrail URL %q", endpoint)
+		}
+		return &httpExternalChecker{url: endpoint, timeout: timeout}, nil
+	case ExternalProtocolGRPC:
+		conn, err := externalGRPCConn(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return &grpcExternalChecker{conn: conn, timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("unknown protocol of the external guardrail %q", protocol)
+	}
+}
+
+// httpExternalChecker calls the external guardrail service with the JSON POST requests.
+type httpExternalChecker struct {
+	url     string
+	timeout time.Duration
+}
+
+// Check implements [ExternalChecker.Check].
+func (c *httpExternalChecker) Check(ctx context.Context, req *ExternalCheckRequest) (*ExternalCheckResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the check request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the check request: %w", err)
+	}
+	httpReq.Header.Set("content-type", "application/json")
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call the external guardrail: %w", err)
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the check response: %w", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("external guardrail returned status %d: %s", httpResp.StatusCode, respBody)
+	}
+	return parseExternalCheckResponse(respBody)
+}
+
+// grpcExternalChecker calls the external guardrail service with the ExternalGRPCMethod.
+type grpcExternalChecker struct {
+	conn    *grpc.ClientConn
+	timeout time.Duration
+}
+
+// Check implements [ExternalChecker.Check].
+func (c *grpcExternalChecker) Check(ctx context.Context, req *ExternalCheckRequest) (*ExternalCheckResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	in, err := externalStruct(req)
+	if err != nil {
+		return nil, err
+	}
+	out := &structpb.Struct{}
+	if err = c.conn.Invoke(ctx, ExternalGRPCMethod, in, out); err != nil {
+		return nil, fmt.Errorf("failed to call the external guardrail: %w", err)
+	}
+	respBody, err := protojson.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the check response: %w", err)
+	}
+	return parseExternalCheckResponse(respBody)
+}
+
+// externalGRPCConns are the connections to the gRPC external guardrail services by their targets, which are shared
+// by the configurations of the external processor since it does not close the ones replaced on the updates.
+var externalGRPCConns sync.Map
+
+// externalGRPCConn returns the connection to the gRPC service at the target.
+func externalGRPCConn(target string) (*grpc.ClientConn, error) {
+	if conn, ok := externalGRPCConns.Load(target); ok {
+		return conn.(*grpc.ClientConn), nil
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid target of the external guardrail: %w", err)
+	}
+	if existing, loaded := externalGRPCConns.LoadOrStore(target, conn); loaded {
+		_ = conn.Close()
+		return existing.(*grpc.ClientConn), nil
+	}
+	return conn, nil
+}
+
+// parseExternalCheckResponse parses and validates the decision of the external guardrail service.
+func parseExternalCheckResponse(body []byte) (*ExternalCheckResponse, error) {
+	var resp ExternalCheckResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse the check response: %w", err)
+	}
+	switch resp.Decision {
+	case ExternalDecisionAllow, ExternalDecisionDeny, ExternalDecisionRewrite:
+		return &resp, nil
+	default:
+		return nil, fmt.Errorf("unknown decision of the external guardrail %q", resp.Decision)
+	}
+}
+
+// externalStruct converts the value to the google.protobuf.Struct message of its JSON format.
+func externalStruct(v any) (*structpb.Struct, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the check message: %w", err)
+	}
+	s := &structpb.Struct{}
+	if err = protojson.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("failed to convert the check message: %w", err)
+	}
+	return s, nil
+}
+
+// ExternalCheckFunc checks the request in the external guardrail service.
+type ExternalCheckFunc func(ctx context.Context, req *ExternalCheckRequest) (*ExternalCheckResponse, error)
+
+// RegisterExternalGRPCServer registers the gRPC service of the external guardrail calling the check, which is useful
+// to implement the services in Go, e.g., the test ones.
+func RegisterExternalGRPCServer(s grpc.ServiceRegistrar, check ExternalCheckFunc) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: ExternalGRPCService,
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Check",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				in := &structpb.Struct{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				b, err := protojson.Marshal(in)
+				if err != nil {
+					return nil, err
+				}
+				var req ExternalCheckRequest
+				if err = json.Unmarshal(b, &req); err != nil {
+					return nil, err
+				}
+				resp, err := check(ctx, &req)
+				if err != nil {
+					return nil, err
+				}
+				return externalStruct(resp)
+			},
+		}},
+	}, struct{}{})
+}
+
+// NewExternalHTTPHandler returns the handler of the HTTP service of the external guardrail calling the check, which
+// is useful to implement the services in Go, e.g., the test ones.
+func NewExternalHTTPHandler(check ExternalCheckFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ExternalCheckRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp, err := check(r.Context(), &req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -0,0 +1,145 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package guardrail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// testExternalCheck denies the requests mentioning "weapons", and rewrites "secret" to "[REDACTED]".
+func testExternalCheck(_ context.Context, req *ExternalCheckRequest) (*ExternalCheckResponse, error) {
+	if strings.Contains(req.Output, "weapons") {
+		return &ExternalCheckResponse{Decision: ExternalDecisionDeny, Reason: "weapons in " + req.Phase}, nil
+	}
+	resp := &ExternalCheckResponse{Decision: ExternalDecisionAllow}
+	for _, m := range req.Messages {
+		if strings.Contains(m.Content, "weapons") {
+			return &ExternalCheckResponse{Decision: ExternalDecisionDeny, Reason: "weapons"}, nil
+		}
+		if strings.Contains(m.Content, "secret") {
+			resp.Decision = ExternalDecisionRewrite
+		}
+		resp.Messages = append(resp.Messages, ExternalMessage{Role: m.Role, Content: strings.ReplaceAll(m.Content, "secret", "[REDACTED]")})
+	}
+	if resp.Decision == ExternalDecisionAllow {
+		resp.Messages = nil
+	}
+	return resp, nil
+}
+
+func TestExternalChecker(t *testing.T) {
+	httpServer := httptest.NewServer(NewExternalHTTPHandler(testExternalCheck))
+	t.Cleanup(httpServer.Close)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	grpcServer := grpc.NewServer()
+	RegisterExternalGRPCServer(grpcServer, testExternalCheck)
+	go func() { _ = grpcServer.Serve(lis) }()
+	t.Cleanup(grpcServer.Stop)
+
+	for _, tc := range []struct {
+		protocol, endpoint string
+	}{
+		{protocol: ExternalProtocolHTTP, endpoint: httpServer.URL},
+		{protocol: ExternalProtocolGRPC, endpoint: lis.Addr().String()},
+	} {
+		t.Run(tc.protocol, func(t *testing.T) {
+			c, err := NewExternalChecker(tc.protocol, tc.endpoint, time.Second)
+			require.NoError(t, err)
+
+			resp, err := c.Check(t.Context(), &ExternalCheckRequest{
+				Phase: ExternalPhaseRequest, Model: "gpt-4o",
+				Messages: []ExternalMessage{{Role: "system", Content: "Be nice."}, {Role: "user", Content: "Hi"}},
+				Tools:    []ExternalTool{{Name: "get_weather", Description: "Gets the weather."}},
+			})
+			require.NoError(t, err)
+			require.Equal(t, &ExternalCheckResponse{Decision: ExternalDecisionAllow}, resp)
+
+			resp, err = c.Check(t.Context(), &ExternalCheckRequest{
+				Phase:    ExternalPhaseRequest,
+				Messages: []ExternalMessage{{Role: "user", Content: "My secret is 42"}},
+			})
+			require.NoError(t, err)
+			require.Equal(t, &ExternalCheckResponse{
+				Decision: ExternalDecisionRewrite,
+				Messages: []ExternalMessage{{Role: "user", Content: "My [REDACTED] is 42"}},
+			}, resp)
+
+			resp, err = c.Check(t.Context(), &ExternalCheckRequest{
+				Phase: ExternalPhaseResponse, Messages: []ExternalMessage{}, Output: "Here are the weapons", Complete: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, &ExternalCheckResponse{Decision: ExternalDecisionDeny, Reason: "weapons in response"}, resp)
+		})
+	}
+}
+
+func TestExternalChecker_Errors(t *testing.T) {
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := NewExternalChecker(ExternalProtocolHTTP, "classifier:8080", time.Second)
+		require.ErrorContains(t, err, `unsupported scheme of the external guardrail URL "classifier:8080"`)
+		_, err = NewExternalChecker("SOAP", "http://classifier:8080", time.Second)
+		require.ErrorContains(t, err, `unknown protocol of the external guardrail "SOAP"`)
+	})
+
+	for _, tc := range []struct {
+		name   string
+		check  ExternalCheckFunc
+		expErr string
+	}{
+		{
+			name: "error status",
+			check: func(context.Context, *ExternalCheckRequest) (*ExternalCheckResponse, error) {
+				return nil, errors.New("classifier is down")
+			},
+			expErr: "external guardrail returned status 500: classifier is down",
+		},
+		{
+			name: "unknown decision",
+			check: func(context.Context, *ExternalCheckRequest) (*ExternalCheckResponse, error) {
+				return &ExternalCheckResponse{Decision: "maybe"}, nil
+			},
+			expErr: `unknown decision of the external guardrail "maybe"`,
+		},
+		{
+			name: "timeout",
+			check: func(ctx context.Context, _ *ExternalCheckRequest) (*ExternalCheckResponse, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			expErr: "context deadline exceeded",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := httptest.NewServer(NewExternalHTTPHandler(tc.check))
+			t.Cleanup(s.Close)
+			c, err := NewExternalChecker(ExternalProtocolHTTP, s.URL, 100*time.Millisecond)
+			require.NoError(t, err)
+			_, err = c.Check(t.Context(), &ExternalCheckRequest{Phase: ExternalPhaseRequest})
+			require.ErrorContains(t, err, tc.expErr)
+		})
+	}
+
+	t.Run("bad request", func(t *testing.T) {
+		s := httptest.NewServer(NewExternalHTTPHandler(testExternalCheck))
+		t.Cleanup(s.Close)
+		resp, err := http.Post(s.URL, "application/json", strings.NewReader("{"))
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
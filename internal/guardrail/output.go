@@ -0,0 +1,111 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package guardrail
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// OutputRule is a rule of the output filter, which matches either the regular expression or any of the keywords.
+type OutputRule struct {
+	// Name is the name of the rule, which is reported on the matches.
+	Name string
+	// Regex is the RE2 regular expression matching the output.
+	Regex string
+	// Keywords are the keywords matching the output anywhere in the text regardless of the case.
+	Keywords []string
+}
+
+// OutputFilter matches the text output of the responses with the rules of the output guardrail.
+type OutputFilter struct {
+	rules      []outputRule
+	windowSize int
+}
+
+// outputRule is a compiled rule of the output filter.
+type outputRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// NewOutputFilter creates a new OutputFilter of the given rules, which keeps the last windowSize characters of the
+// streamed text to match together with the following deltas.
+func NewOutputFilter(rules []OutputRule, windowSize int) (*OutputFilter, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no output filter rules")
+	}
+	if windowSize <= 0 {
+		return nil, fmt.Errorf("invalid output filter window size %d", windowSize)
+	}
+	f := &OutputFilter{windowSize: windowSize}
+	for _, r := range rules {
+		expr := r.Regex
+		switch {
+		case expr != "" && len(r.Keywords) > 0:
+			return nil, fmt.Errorf("output filter rule %q has both regex and keywords", r.Name)
+		case len(r.Keywords) > 0:
+			quoted := make([]string, len(r.Keywords))
+			for i, k := range r.Keywords {
+				if k == "" {
+					return nil, fmt.Errorf("output filter rule %q has an empty keyword", r.Name)
+				}
+				quoted[i] = regexp.QuoteMeta(k)
+			}
+			expr = "(?i)(?:" + strings.Join(quoted, "|") + ")"
+		case expr == "":
+			return nil, fmt.Errorf("output filter rule %q has neither regex nor keywords", r.Name)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex of the output filter rule %q: %w", r.Name, err)
+		}
+		f.rules = append(f.rules, outputRule{name: r.Name, re: re})
+	}
+	return f, nil
+}
+
+// Match returns the name of the first rule matching the text, or the empty string if none matches.
+func (f *OutputFilter) Match(text string) string {
+	for _, r := range f.rules {
+		if r.re.MatchString(text) {
+			return r.name
+		}
+	}
+	return ""
+}
+
+// NewWindow returns a new OutputWindow of the filter for a text streamed in deltas.
+func (f *OutputFilter) NewWindow() *OutputWindow {
+	return &OutputWindow{filter: f}
+}
+
+// OutputWindow matches a text streamed in deltas with the rules of the OutputFilter. Each delta is matched together
+// with the last characters of the text before it up to the window size of the filter, so that the matches spanning
+// several deltas are found as long as they are not longer than the window.
+type OutputWindow struct {
+	filter *OutputFilter
+	// tail is the end of the text so far, which is at most the window size of the filter in characters.
+	tail string
+}
+
+// Append matches the delta following the text so far, and returns the name of the first rule matching, or the empty
+// string if none matches.
+func (w *OutputWindow) Append(delta string) string {
+	text := w.tail + delta
+	rule := w.filter.Match(text)
+	if n := utf8.RuneCountInString(text); n > w.filter.windowSize {
+		// Drop the characters before the window, which is at a rune boundary since the text is valid UTF-8.
+		for range n - w.filter.windowSize {
+			_, size := utf8.DecodeRuneInString(text)
+			text = text[size:]
+		}
+	}
+	w.tail = text
+	return rule
+}
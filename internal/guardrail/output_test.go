@@ -0,0 +1,84 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package guardrail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOutputFilter(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		rules      []OutputRule
+		windowSize int
+		expErr     string
+	}{
+		{name: "no rules", windowSize: 10, expErr: "no output filter rules"},
+		{name: "invalid window", rules: []OutputRule{{Name: "a", Regex: "a"}}, expErr: "invalid output filter window size 0"},
+		{
+			name: "both", rules: []OutputRule{{Name: "a", Regex: "a", Keywords: []string{"b"}}}, windowSize: 10,
+			expErr: `output filter rule "a" has both regex and keywords`,
+		},
+		{name: "neither", rules: []OutputRule{{Name: "a"}}, windowSize: 10, expErr: `output filter rule "a" has neither regex nor keywords`},
+		{
+			name: "empty keyword", rules: []OutputRule{{Name: "a", Keywords: []string{""}}}, windowSize: 10,
+			expErr: `output filter rule "a" has an empty keyword`,
+		},
+		{
+			name: "invalid regex", rules: []OutputRule{{Name: "a", Regex: "("}}, windowSize: 10,
+			expErr: `invalid regex of the output filter rule "a"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewOutputFilter(tc.rules, tc.windowSize)
+			require.ErrorContains(t, err, tc.expErr)
+		})
+	}
+}
+
+func TestOutputFilter_Match(t *testing.T) {
+	f, err := NewOutputFilter([]OutputRule{
+		{Name: "codename", Keywords: []string{"Project Orion", "a.b"}},
+		{Name: "card", Regex: `\b\d{4}-\d{4}-\d{4}-\d{4}\b`},
+	}, 16)
+	require.NoError(t, err)
+
+	require.Equal(t, "codename", f.Match("the project orion launch"))
+	require.Equal(t, "codename", f.Match("see a.b"))
+	// The keywords are matched literally.
+	require.Empty(t, f.Match("see axb"))
+	require.Equal(t, "card", f.Match("card 1234-5678-9012-3456"))
+	require.Empty(t, f.Match("nothing to see here"))
+}
+
+func TestOutputWindow_Append(t *testing.T) {
+	f, err := NewOutputFilter([]OutputRule{{Name: "codename", Keywords: []string{"Project Orion"}}}, 16)
+	require.NoError(t, err)
+
+	t.Run("match across deltas", func(t *testing.T) {
+		w := f.NewWindow()
+		for _, delta := range []string{"The ", "Proj", "ect O"} {
+			require.Empty(t, w.Append(delta))
+		}
+		require.Equal(t, "codename", w.Append("rion is"))
+	})
+
+	t.Run("match longer than the window", func(t *testing.T) {
+		w := f.NewWindow()
+		require.Empty(t, w.Append("Project"))
+		require.Empty(t, w.Append(" ------------------"))
+		require.Empty(t, w.Append("Orion"))
+	})
+
+	t.Run("multi-byte characters", func(t *testing.T) {
+		w := f.NewWindow()
+		require.Empty(t, w.Append("日本語のテキストが続きます。Proj"))
+		require.Equal(t, "語のテキストが続きます。Proj", w.tail)
+		require.Equal(t, "codename", w.Append("ect Orion"))
+	})
+}
@@ -0,0 +1,218 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+// Package guardrail implements the guardrails of the AIGatewayRoute rules applied by the external processor to the
+// requests and the responses of the backends.
+package guardrail
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+// The built-in types of the personally identifiable information, which correspond to PIIEntity in
+// api/v1beta1/ai_gateway_route.go. PhoneNumber and NationalID only detect the formats of the United States by default,
+// which can be replaced with a PIIPattern of the same name.
+const (
+	PIIEntityEmail       = "Email"
+	PIIEntityPhoneNumber = "PhoneNumber"
+	PIIEntityCreditCard  = "CreditCard"
+	PIIEntityNationalID  = "NationalID"
+)
+
+// piiEntities are the detectors of the built-in types, in the order in which they are applied. The more specific
+// ones come first, so that, e.g., a credit card number is not detected as a phone number.
+var piiEntities = []piiEntity{
+	{name: PIIEntityCreditCard, label: "CREDIT_CARD", re: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), valid: luhn},
+	{name: PIIEntityNationalID, label: "NATIONAL_ID", re: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{name: PIIEntityEmail, label: "EMAIL", re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{
+		name: PIIEntityPhoneNumber, label: "PHONE_NUMBER",
+		re: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)[ .-]?|\b\d{2,4}[ .-])\d{3,4}[ .-]\d{4}\b|\+\d{8,15}\b`),
+	},
+}
+
+// piiEntity is the detector of a built-in type of the personally identifiable information.
+type piiEntity struct {
+	name, label string
+	re          *regexp.Regexp
+	valid       func(string) bool
+}
+
+// PIIPattern is a custom type of the personally identifiable information.
+type PIIPattern struct {
+	// Name is the name of the type, which is used in upper case as its label. The name of a built-in type replaces its
+	// detection instead.
+	Name string
+	// Regex is the RE2 regular expression matching the information.
+	Regex string
+}
+
+// PIIDetector detects the personally identifiable information in the text.
+type PIIDetector struct {
+	detectors []piiDetector
+}
+
+// piiDetector detects a type of the personally identifiable information.
+type piiDetector struct {
+	label string
+	re    *regexp.Regexp
+	// valid returns false if the match is not the information, e.g., a number failing the checksum. Optional.
+	valid func(string) bool
+}
+
+// NewPIIDetector creates a new PIIDetector of the given built-in types and custom patterns. The custom patterns are
+// applied before the built-in types, except the ones named after a built-in type, which replace its regular expression
+// and its validation, keeping its label and its order, whether or not the type is given.
+func NewPIIDetector(entities []string, patterns []PIIPattern) (*PIIDetector, error) {
+	for _, name := range entities {
+		if !knownPIIEntity(name) {
+			return nil, fmt.Errorf("unknown PII entity %q", name)
+		}
+	}
+	d := &PIIDetector{}
+	overrides := make(map[string]*regexp.Regexp)
+	for _, p := range patterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex of the PII pattern %q: %w", p.Name, err)
+		}
+		if knownPIIEntity(p.Name) {
+			overrides[p.Name] = re
+			continue
+		}
+		d.detectors = append(d.detectors, piiDetector{label: strings.ToUpper(p.Name), re: re})
+	}
+	for _, e := range piiEntities {
+		if re, ok := overrides[e.name]; ok {
+			d.detectors = append(d.detectors, piiDetector{label: e.label, re: re})
+		} else if slices.Contains(entities, e.name) {
+			d.detectors = append(d.detectors, piiDetector{label: e.label, re: e.re, valid: e.valid})
+		}
+	}
+	return d, nil
+}
+
+// knownPIIEntity returns true if the name is one of the built-in types.
+func knownPIIEntity(name string) bool {
+	return slices.ContainsFunc(piiEntities, func(e piiEntity) bool { return e.name == name })
+}
+
+// Replace replaces each detection in the text with the return value of replace called with the label of its type,
+// e.g., "EMAIL", and the detected value.
+func (d *PIIDetector) Replace(text string, replace func(label, value string) string) string {
+	for _, det := range d.detectors {
+		text = det.re.ReplaceAllStringFunc(text, func(value string) string {
+			if det.valid != nil && !det.valid(value) {
+				return value
+			}
+			return replace(det.label, value)
+		})
+	}
+	return text
+}
+
+// PIIPlaceholder returns the placeholder replacing the masked information of the type of the label.
+func PIIPlaceholder(label string) string {
+	return "[" + label + "]"
+}
+
+// PIITokens are the tokens replacing the tokenized information of a request, which are restored in the response.
+type PIITokens struct {
+	tokens map[string]string // by the value.
+	values map[string]string // by the token.
+	counts map[string]int    // by the label.
+
+	replacer, jsonReplacer *strings.Replacer
+}
+
+// NewPIITokens creates a new empty PIITokens.
+func NewPIITokens() *PIITokens {
+	return &PIITokens{tokens: make(map[string]string), values: make(map[string]string), counts: make(map[string]int)}
+}
+
+// Token returns the token of the value of the type of the label, e.g., "[EMAIL_1]". The same value is always replaced
+// with the same token.
+func (t *PIITokens) Token(label, value string) string {
+	if token, ok := t.tokens[value]; ok {
+		return token
+	}
+	t.counts[label]++
+	token := "[" + label + "_" + strconv.Itoa(t.counts[label]) + "]"
+	t.tokens[value], t.values[token] = token, value
+	t.replacer, t.jsonReplacer = nil, nil
+	return token
+}
+
+// Len returns the number of the tokens.
+func (t *PIITokens) Len() int {
+	return len(t.values)
+}
+
+// Restore replaces the tokens in the text with their values.
+func (t *PIITokens) Restore(text string) string {
+	if t.replacer == nil {
+		oldnew := make([]string, 0, 2*len(t.values))
+		for token, value := range t.values {
+			oldnew = append(oldnew, token, value)
+		}
+		t.replacer = strings.NewReplacer(oldnew...)
+	}
+	return t.replacer.Replace(text)
+}
+
+// RestoreJSON replaces the tokens in the JSON document with their values escaped as the content of JSON strings.
+func (t *PIITokens) RestoreJSON(doc []byte) []byte {
+	if t.jsonReplacer == nil {
+		oldnew := make([]string, 0, 2*len(t.values))
+		for token, value := range t.values {
+			escaped, _ := json.Marshal(value)
+			oldnew = append(oldnew, token, string(escaped[1:len(escaped)-1]))
+		}
+		t.jsonReplacer = strings.NewReplacer(oldnew...)
+	}
+	return []byte(t.jsonReplacer.Replace(string(doc)))
+}
+
+// PartialSuffix returns the length of the longest suffix of the text that is the beginning of a token but not a
+// whole one, which may be completed by the text that follows, e.g., in the next chunk of a stream.
+func (t *PIITokens) PartialSuffix(text string) int {
+	i := strings.LastIndexByte(text, '[')
+	if i < 0 {
+		return 0
+	}
+	suffix := text[i:]
+	for token := range t.values {
+		if len(suffix) < len(token) && strings.HasPrefix(token, suffix) {
+			return len(suffix)
+		}
+	}
+	return 0
+}
+
+// luhn returns true if the digits of the number pass the Luhn checksum.
+func luhn(number string) bool {
+	sum, n := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if n%2 == 1 {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,79 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package guardrail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPIIDetector_Replace(t *testing.T) {
+	d, err := NewPIIDetector([]string{PIIEntityEmail, PIIEntityPhoneNumber, PIIEntityCreditCard, PIIEntityNationalID},
+		[]PIIPattern{{Name: "employee_id", Regex: `EMP-[0-9]{6}`}})
+	require.NoError(t, err)
+	mask := func(label, _ string) string { return PIIPlaceholder(label) }
+
+	for _, tc := range []struct {
+		name, text, exp string
+	}{
+		{name: "email", text: "Contact jane.doe+ai@example.co.uk today", exp: "Contact [EMAIL] today"},
+		{name: "phone with country code", text: "Call +1 415-555-2671.", exp: "Call [PHONE_NUMBER]."},
+		{name: "phone with area code", text: "Call (415) 555-2671.", exp: "Call [PHONE_NUMBER]."},
+		{name: "phone with dots", text: "Call 415.555.2671", exp: "Call [PHONE_NUMBER]"},
+		{name: "e164 phone", text: "Call +14155552671", exp: "Call [PHONE_NUMBER]"},
+		{name: "credit card", text: "Card 4111 1111 1111 1111 expires", exp: "Card [CREDIT_CARD] expires"},
+		{name: "invalid credit card", text: "Order 1234567812345678", exp: "Order 1234567812345678"},
+		{name: "national id", text: "SSN 123-45-6789", exp: "SSN [NATIONAL_ID]"},
+		{name: "custom pattern", text: "I am EMP-123456", exp: "I am [EMPLOYEE_ID]"},
+		{name: "no pii", text: "Released on 2024-01-15 as v1.2.3 at 192.168.1.100", exp: "Released on 2024-01-15 as v1.2.3 at 192.168.1.100"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, d.Replace(tc.text, mask))
+		})
+	}
+
+	// Only the given types are detected.
+	d, err = NewPIIDetector([]string{PIIEntityEmail}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "[EMAIL] 415-555-2671", d.Replace("a@b.io 415-555-2671", mask))
+
+	// A pattern named after a built-in type replaces its detection, e.g., with the formats of another country, keeping
+	// its label.
+	d, err = NewPIIDetector([]string{PIIEntityEmail, PIIEntityNationalID}, []PIIPattern{
+		{Name: PIIEntityNationalID, Regex: `\b\d{2}\.\d{2}\.\d{2}-\d{3}\.\d{2}\b`},
+		{Name: PIIEntityPhoneNumber, Regex: `\b0\d{2,4} ?\d{6,8}\b`},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "[EMAIL] [NATIONAL_ID] [PHONE_NUMBER] 123-45-6789",
+		d.Replace("a@b.io 85.07.30-033.61 030 1234567 123-45-6789", mask))
+
+	_, err = NewPIIDetector([]string{"Passport"}, nil)
+	require.ErrorContains(t, err, `unknown PII entity "Passport"`)
+	_, err = NewPIIDetector(nil, []PIIPattern{{Name: "bad", Regex: "("}})
+	require.ErrorContains(t, err, `invalid regex of the PII pattern "bad"`)
+}
+
+func TestPIITokens(t *testing.T) {
+	d, err := NewPIIDetector([]string{PIIEntityEmail}, nil)
+	require.NoError(t, err)
+	tokens := NewPIITokens()
+	masked := d.Replace("Mail a@b.io, c@d.io and a@b.io", tokens.Token)
+	require.Equal(t, "Mail [EMAIL_1], [EMAIL_2] and [EMAIL_1]", masked)
+	require.Equal(t, 2, tokens.Len())
+	require.Equal(t, "Sent to a@b.io and c@d.io", tokens.Restore("Sent to [EMAIL_1] and [EMAIL_2]"))
+
+	// The tokens added later are restored as well, and the values are escaped in JSON.
+	require.Equal(t, "[NAME_1]", tokens.Token("NAME", `Jane "JD" Doe`))
+	require.Equal(t, `{"content":"Hi Jane \"JD\" Doe at a@b.io"}`,
+		string(tokens.RestoreJSON([]byte(`{"content":"Hi [NAME_1] at [EMAIL_1]"}`))))
+
+	require.Equal(t, 3, tokens.PartialSuffix("Sent to [EM"))
+	require.Equal(t, 1, tokens.PartialSuffix("Sent to ["))
+	require.Zero(t, tokens.PartialSuffix("Sent to [EMAIL_1]"))
+	require.Zero(t, tokens.PartialSuffix("Sent to [link"))
+	require.Zero(t, tokens.PartialSuffix("Sent to"))
+}
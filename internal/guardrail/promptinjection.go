@@ -0,0 +1,214 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package guardrail
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The built-in rules of the prompt injection detection, which correspond to PromptInjectionRule in
+// api/v1beta1/ai_gateway_route.go.
+const (
+	PromptInjectionRuleIgnoreInstructions     = "IgnoreInstructions"
+	PromptInjectionRuleRoleOverride           = "RoleOverride"
+	PromptInjectionRuleSystemPromptExtraction = "SystemPromptExtraction"
+	PromptInjectionRuleDelimiterInjection     = "DelimiterInjection"
+	PromptInjectionRuleHiddenUnicode          = "HiddenUnicode"
+	PromptInjectionRuleEncodedPayload         = "EncodedPayload"
+)
+
+// promptInjectionRules are the built-in rules other than PromptInjectionRuleEncodedPayload, which is applied by
+// decoding the payloads and matching the other rules on them.
+var promptInjectionRules = []promptInjectionBuiltIn{
+	{
+		name: PromptInjectionRuleIgnoreInstructions, score: 60,
+		re: regexp.MustCompile(`(?i)\b(?:ignore|disregard|forget|override|bypass)\b[\w\s,]{0,30}?\b(?:previous|prior|above|earlier|preceding|all|any|your|the|system)\b[\w\s,]{0,20}?\b(?:instructions?|prompts?|rules|directives|guidelines)\b|\b(?:ignore|disregard|forget)\s+(?:everything|all)\s+(?:above|before|previously)\b`),
+	},
+	{
+		name: PromptInjectionRuleRoleOverride, score: 40,
+		re: regexp.MustCompile(`(?i)\b(?:you are now|from now on,? you (?:are|will)|act as|pretend (?:to be|you are)|roleplay as)\b.{0,40}?\b(?:DAN|unrestricted|unfiltered|uncensored|jailbroken|without (?:any )?(?:restrictions|limits|filters|rules))\b|\b(?:developer|god|jailbreak|DAN) mode\b|\bdo anything now\b`),
+	},
+	{
+		name: PromptInjectionRuleSystemPromptExtraction, score: 40,
+		re: regexp.MustCompile(`(?i)\b(?:reveal|print|show|repeat|output|display|tell me|leak|disclose)\b.{0,30}?\b(?:system (?:prompt|message)|initial (?:prompt|instructions)|hidden (?:prompt|instructions)|developer (?:message|instructions))\b`),
+	},
+	{
+		name: PromptInjectionRuleDelimiterInjection, score: 30,
+		re: regexp.MustCompile(`(?im)<\|im_(?:start|end)\|>|<\|(?:system|assistant|user)\|>|\[/?INST\]|<</?SYS>>|</?(?:system|assistant)>|^\s*#{2,}\s*(?:system|assistant)\s*:`),
+	},
+	{name: PromptInjectionRuleHiddenUnicode, score: 50, match: hasHiddenUnicode},
+}
+
+// promptInjectionEncodedPayloadScore is the score of PromptInjectionRuleEncodedPayload.
+const promptInjectionEncodedPayloadScore = 30
+
+// promptInjectionBase64 matches the candidates of the base64 payloads, which are long enough not to be ordinary words.
+var promptInjectionBase64 = regexp.MustCompile(`[A-Za-z0-9+/_-]{32,}={0,2}`)
+
+// promptInjectionBuiltIn is a built-in rule of the prompt injection detection.
+type promptInjectionBuiltIn struct {
+	name  string
+	score int
+	re    *regexp.Regexp
+	match func(string) bool
+}
+
+// PromptInjectionPattern is a custom rule of the prompt injection detection.
+type PromptInjectionPattern struct {
+	// Name is the name of the rule, which is reported on the detections.
+	Name string
+	// Regex is the RE2 regular expression matching the injection.
+	Regex string
+	// Score is added to the score of the text matching the rule.
+	Score int
+}
+
+// PromptInjectionDetector scores the texts of the requests with the rules of the prompt injection detection, and
+// detects the injection in the ones scoring at or above the threshold.
+type PromptInjectionDetector struct {
+	rules     []promptInjectionRule
+	threshold int
+	// decode is true if the base64 payloads are decoded and matched with the rules.
+	decode bool
+}
+
+// promptInjectionRule is a rule of the prompt injection detection.
+type promptInjectionRule struct {
+	name  string
+	score int
+	match func(string) bool
+}
+
+// PromptInjectionDetection is the result of the prompt injection detection on a text.
+type PromptInjectionDetection struct {
+	// Score is the sum of the scores of the Rules.
+	Score int
+	// Rules are the names of the rules matching the text in the order in which they are applied.
+	Rules []string
+}
+
+// NewPromptInjectionDetector creates a new PromptInjectionDetector of the given built-in rules and custom patterns,
+// which detects the injection in the texts scoring at or above the threshold. The custom patterns are applied after
+// the built-in rules.
+func NewPromptInjectionDetector(rules []string, patterns []PromptInjectionPattern, threshold int) (*PromptInjectionDetector, error) {
+	for _, name := range rules {
+		if name != PromptInjectionRuleEncodedPayload &&
+			!slices.ContainsFunc(promptInjectionRules, func(r promptInjectionBuiltIn) bool { return r.name == name }) {
+			return nil, fmt.Errorf("unknown prompt injection rule %q", name)
+		}
+	}
+	if threshold <= 0 {
+		return nil, fmt.Errorf("invalid prompt injection threshold %d", threshold)
+	}
+	d := &PromptInjectionDetector{threshold: threshold, decode: slices.Contains(rules, PromptInjectionRuleEncodedPayload)}
+	for _, r := range promptInjectionRules {
+		if !slices.Contains(rules, r.name) {
+			continue
+		}
+		match := r.match
+		if match == nil {
+			match = r.re.MatchString
+		}
+		d.rules = append(d.rules, promptInjectionRule{name: r.name, score: r.score, match: match})
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex of the prompt injection pattern %q: %w", p.Name, err)
+		}
+		d.rules = append(d.rules, promptInjectionRule{name: p.Name, score: p.Score, match: re.MatchString})
+	}
+	return d, nil
+}
+
+// Detect scores the text, and returns the detection if the score is at or above the threshold, or nil otherwise.
+// Each rule adds its score once, whether it matches the text itself or a base64 payload decoded from it.
+func (d *PromptInjectionDetector) Detect(text string) *PromptInjectionDetection {
+	matched := make([]bool, len(d.rules))
+	for i, r := range d.rules {
+		matched[i] = r.match(text)
+	}
+	var decoded bool
+	if d.decode {
+		for _, payload := range promptInjectionBase64.FindAllString(text, -1) {
+			plain, ok := decodeBase64Text(payload)
+			if !ok {
+				continue
+			}
+			decoded = true
+			for i, r := range d.rules {
+				matched[i] = matched[i] || r.match(plain)
+			}
+		}
+	}
+
+	det := &PromptInjectionDetection{}
+	for i, r := range d.rules {
+		if matched[i] {
+			det.Score += r.score
+			det.Rules = append(det.Rules, r.name)
+		}
+	}
+	if decoded {
+		det.Score += promptInjectionEncodedPayloadScore
+		det.Rules = append(det.Rules, PromptInjectionRuleEncodedPayload)
+	}
+	if det.Score < d.threshold {
+		return nil
+	}
+	return det
+}
+
+// decodeBase64Text decodes the base64 payload in either the standard or the URL encoding, and returns the decoded
+// text if it is mostly printable, or false if the payload is not a text, e.g., a hash or an identifier.
+func decodeBase64Text(payload string) (string, bool) {
+	trimmed := strings.TrimRight(payload, "=")
+	var b []byte
+	var err error
+	if strings.ContainsAny(trimmed, "-_") {
+		b, err = base64.RawURLEncoding.DecodeString(trimmed)
+	} else {
+		b, err = base64.RawStdEncoding.DecodeString(trimmed)
+	}
+	if err != nil || !utf8.Valid(b) {
+		return "", false
+	}
+	text := string(b)
+	var printable, total int
+	for _, r := range text {
+		total++
+		if unicode.IsPrint(r) || unicode.IsSpace(r) {
+			printable++
+		}
+	}
+	// Require the decoded text to contain words, so that the random bytes that happen to be printable are ignored.
+	return text, total > 0 && printable*10 >= total*9 && strings.Contains(text, " ")
+}
+
+// hasHiddenUnicode returns true if the text contains the invisible characters used to hide the instructions from the
+// humans reading the text, i.e., the zero width characters, the bidirectional controls and the Unicode tags. The zero
+// width joiner and non-joiner are allowed since they are used in the emoji sequences and some scripts, as well as the
+// byte order mark at the beginning of the text.
+func hasHiddenUnicode(text string) bool {
+	for i, r := range text {
+		switch {
+		case r == '\u200B', r == '\u200E', r == '\u200F',
+			r >= '\u2060' && r <= '\u2064',
+			r >= '\u202A' && r <= '\u202E',
+			r >= '\u2066' && r <= '\u2069',
+			r >= '\U000E0000' && r <= '\U000E007F',
+			r == '\uFEFF' && i > 0:
+			return true
+		}
+	}
+	return false
+}
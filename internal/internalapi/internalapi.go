@@ -8,9 +8,12 @@
 package internalapi
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"maps"
 	"slices"
+	"strconv"
 	"strings"
 
 	aigv1b1 "github.com/envoyproxy/ai-gateway/api/v1beta1"
@@ -29,6 +32,8 @@ const (
 	InternalMetadataBackendNameKey = "per_route_rule_backend_name"
 	// InternalMetadataRouteNameKey is the key used to store the route name.
 	InternalMetadataRouteNameKey = "aigw_route_name"
+	// InternalMetadataPriorityKey is the key used to store the priority of the endpoints of a backend.
+	InternalMetadataPriorityKey = "priority"
 	// MCPBackendHeader is the special header key used to specify the target backend name.
 	MCPBackendHeader = EnvoyAIGatewayHeaderPrefix + "mcp-backend"
 	// MCPRouteHeader is the special header key used to identify the mcp route.
@@ -37,6 +42,9 @@ const (
 	MCPBackendListenerPort = 10088
 	// MCPProxyPort is the port where the MCP proxy listens.
 	MCPProxyPort = 9856
+	// ShadowListenerPort is the port of the listener through which the router filter sends the copies of the
+	// requests to the shadow backends of the AIGatewayRoute rules.
+	ShadowListenerPort = 10089
 	// MCPGeneratedResourceCommonPrefix is the common prefix for all MCP-related generated resources.
 	MCPGeneratedResourceCommonPrefix = "ai-eg-mcp-"
 	// MCPMainHTTPRoutePrefix is the prefix for the main HTTPRoute resources generated for MCP.
@@ -81,10 +89,70 @@ const (
 	XDSClusterMetadataBackendNamePath = "xds.cluster_metadata.filter_metadata['aigateway.envoy.io']['per_route_rule_backend_name']"
 	// XDSUpstreamHostMetadataBackendNamePath is the full attribute path to access the backend name in upstream host metadata in xDS attributes.
 	XDSUpstreamHostMetadataBackendNamePath = "xds.upstream_host_metadata.filter_metadata['aigateway.envoy.io']['per_route_rule_backend_name']"
+	// XDSUpstreamHostMetadataPriorityPath is the full attribute path to access the priority of the endpoint in upstream host metadata in xDS attributes.
+	XDSUpstreamHostMetadataPriorityPath = "xds.upstream_host_metadata.filter_metadata['aigateway.envoy.io']['priority']"
 	// XDSRouteMetadataRouteNamePath is the full attribute path to access the route name in route metadata in xDS attributes.
 	XDSRouteMetadataRouteNamePath = "xds.route_metadata.filter_metadata['aigateway.envoy.io']['aigw_route_name']"
 )
 
+// BodyMatchHeaderPrefix is the prefix of the headers set by the router filter to the result of the
+// request body matches of AIGatewayRoute rules, which the generated HTTPRoute matches on.
+const BodyMatchHeaderPrefix = EnvoyAIGatewayHeaderPrefix + "body-match-"
+
+// BodyMatchHeaderName returns the name of the header carrying the result of the given body match CEL expression.
+// The name is derived from the expression so that identical expressions across routes are evaluated only once.
+func BodyMatchHeaderName(cel string) string {
+	sum := sha256.Sum256([]byte(cel))
+	return BodyMatchHeaderPrefix + hex.EncodeToString(sum[:8])
+}
+
+// BackendSelectionHeaderPrefix is the prefix of the headers set by the router filter to the index of the preferred
+// backend ref of the AIGatewayRoute rules with a backend selection, which the generated HTTPRoute matches on.
+const BackendSelectionHeaderPrefix = EnvoyAIGatewayHeaderPrefix + "backend-selection-"
+
+// BackendSelectionHeaderName returns the name of the header carrying the index of the preferred backend ref of the
+// given AIGatewayRoute rule.
+func BackendSelectionHeaderName(namespace, routeName string, ruleIndex int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d", namespace, routeName, ruleIndex)))
+	return BackendSelectionHeaderPrefix + hex.EncodeToString(sum[:8])
+}
+
+// PromptAffinityHeaderPrefix is the prefix of the headers set by the router filter to the prompt affinity key of
+// the request, which the routes of the AIGatewayRoute rules with a prompt affinity hash on.
+const PromptAffinityHeaderPrefix = EnvoyAIGatewayHeaderPrefix + "affinity-prompt-"
+
+// PromptAffinityHeaderName returns the name of the header carrying the prompt affinity key computed from the system
+// prompt and the given number of the following messages.
+func PromptAffinityHeaderName(messages int) string {
+	return PromptAffinityHeaderPrefix + strconv.Itoa(messages)
+}
+
+// FallbackErrorClassHeader is the response header set by the upstream filter to the class of an error response of a
+// backend when the fallback policy of the AIGatewayRoute rule retries it. The retry policy of the generated routes
+// retries on the presence of this header, and the router filter removes it from the final response.
+const FallbackErrorClassHeader = EnvoyAIGatewayHeaderPrefix + "fallback-error-class"
+
+// HedgeAttemptHeader is the response header set by the upstream filter to the 1-based index of the upstream attempt
+// of a hedged request. Since the attempts run concurrently, the router filter uses it to find the attempt whose
+// response Envoy returns to the client, and removes it from the final response.
+const HedgeAttemptHeader = EnvoyAIGatewayHeaderPrefix + "hedge-attempt"
+
+// StreamHeader is the request header set by the router filter to "true" on the streaming requests, and removed from
+// the other requests, so that the routes can match on whether the request is streamed, e.g., the routes hedging the
+// streaming requests of the rules with a HedgePolicy.
+const StreamHeader = EnvoyAIGatewayHeaderPrefix + "stream"
+
+// StreamFailoverFilterMetadataKey is the gRPC metadata key set by the stream failover filter, i.e., the upstream
+// filter of the backends of the AIGatewayRoute rules with StreamFailover, on its streams to the external processor so
+// that they are told apart from the ones of the other filters.
+const StreamFailoverFilterMetadataKey = EnvoyAIGatewayHeaderPrefix + "stream-failover-filter"
+
+// ShadowBackendHeader is the request header set by the router filter to the name of the shadow backend on the copies
+// of the requests sent to the shadow listener, whose routes match on it. This is also set to the name of the stream
+// failover backend on the continuations of the truncated streaming responses, and to the name of the embeddings
+// backend on the embeddings requests of the semantic response cache, which are sent the same way.
+const ShadowBackendHeader = EnvoyAIGatewayHeaderPrefix + "shadow-backend"
+
 // PerRouteRuleRefBackendName generates a unique backend name for a per-route rule,
 // i.e., the unique identifier for a backend that is associated with a specific
 // route rule in a specific AIGatewayRoute.
@@ -92,6 +160,24 @@ func PerRouteRuleRefBackendName(namespace, name, routeName string, routeRuleInde
 	return fmt.Sprintf("%s/%s/route/%s/rule/%d/ref/%d", namespace, name, routeName, routeRuleIndex, refIndex)
 }
 
+// PerRouteRuleShadowBackendName generates a unique backend name for a shadow backend of a per-route rule, similarly
+// to PerRouteRuleRefBackendName.
+func PerRouteRuleShadowBackendName(namespace, name, routeName string, routeRuleIndex, shadowIndex int) string {
+	return fmt.Sprintf("%s/%s/route/%s/rule/%d/shadow/%d", namespace, name, routeName, routeRuleIndex, shadowIndex)
+}
+
+// PerRouteRuleStreamFailoverBackendName generates a unique backend name for the stream failover backend of a
+// per-route rule, similarly to PerRouteRuleRefBackendName.
+func PerRouteRuleStreamFailoverBackendName(namespace, name, routeName string, routeRuleIndex int) string {
+	return fmt.Sprintf("%s/%s/route/%s/rule/%d/stream-failover", namespace, name, routeName, routeRuleIndex)
+}
+
+// PerRouteRuleSemanticCacheBackendName generates a unique backend name for the embeddings backend of the semantic
+// response cache of a per-route rule, similarly to PerRouteRuleRefBackendName.
+func PerRouteRuleSemanticCacheBackendName(namespace, name, routeName string, routeRuleIndex int) string {
+	return fmt.Sprintf("%s/%s/route/%s/rule/%d/semantic-cache", namespace, name, routeName, routeRuleIndex)
+}
+
 const (
 	// AIGatewayGeneratedHTTPRouteAnnotation is the annotation key used to mark
 	// HTTPRoute resources that are generated by the AI Gateway controller.
@@ -6,6 +6,7 @@
 package internalapi
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -100,11 +101,48 @@ func TestPerRouteRuleRefBackendName(t *testing.T) {
 	}
 }
 
+func TestPerRouteRuleShadowBackendName(t *testing.T) {
+	require.Equal(t, "test-ns/my-backend/route/my-route/rule/2/shadow/1",
+		PerRouteRuleShadowBackendName("test-ns", "my-backend", "my-route", 2, 1))
+}
+
+func TestPerRouteRuleStreamFailoverBackendName(t *testing.T) {
+	require.Equal(t, "test-ns/my-backend/route/my-route/rule/2/stream-failover",
+		PerRouteRuleStreamFailoverBackendName("test-ns", "my-backend", "my-route", 2))
+}
+
+func TestPerRouteRuleSemanticCacheBackendName(t *testing.T) {
+	require.Equal(t, "test-ns/my-embeddings/route/my-route/rule/2/semantic-cache",
+		PerRouteRuleSemanticCacheBackendName("test-ns", "my-embeddings", "my-route", 2))
+}
+
+func TestBodyMatchHeaderName(t *testing.T) {
+	name := BodyMatchHeaderName("has_images")
+	require.Equal(t, "x-ai-eg-body-match-1f0b3202a7f0ed83", name)
+	require.Equal(t, name, BodyMatchHeaderName("has_images"))
+	require.NotEqual(t, name, BodyMatchHeaderName("has_tools"))
+}
+
+func TestBackendSelectionHeaderName(t *testing.T) {
+	name := BackendSelectionHeaderName("ns", "route", 0)
+	require.True(t, strings.HasPrefix(name, "x-ai-eg-backend-selection-"))
+	require.Len(t, name, len("x-ai-eg-backend-selection-")+16)
+	require.Equal(t, name, BackendSelectionHeaderName("ns", "route", 0))
+	require.NotEqual(t, name, BackendSelectionHeaderName("ns", "route", 1))
+	require.NotEqual(t, name, BackendSelectionHeaderName("ns", "other", 0))
+}
+
+func TestPromptAffinityHeaderName(t *testing.T) {
+	require.Equal(t, "x-ai-eg-affinity-prompt-0", PromptAffinityHeaderName(0))
+	require.Equal(t, "x-ai-eg-affinity-prompt-2", PromptAffinityHeaderName(2))
+}
+
 func TestConstants(t *testing.T) {
 	// Test that constants have expected values
 	require.Equal(t, "aigateway.envoy.io", InternalEndpointMetadataNamespace)
 	require.Equal(t, "per_route_rule_backend_name", InternalMetadataBackendNameKey)
 	require.Equal(t, "aigw_route_name", InternalMetadataRouteNameKey)
+	require.Equal(t, "priority", InternalMetadataPriorityKey)
 	require.Equal(t, "x-gateway-destination-endpoint", EndpointPickerHeaderKey)
 	require.Equal(t, "xds.route_metadata.filter_metadata['aigateway.envoy.io']['aigw_route_name']", XDSRouteMetadataRouteNamePath)
 }
@@ -24,6 +24,10 @@ var (
 	// unsupported features, or doesn't match the expected schema for the target API.
 	// Should return HTTP 422 Unprocessable Entity.
 	ErrInvalidRequestBody = errors.New("invalid request body")
+
+	// ErrContextLengthExceeded indicates the estimated prompt of the request does not fit in the context window
+	// of the model it is routed to. Should return HTTP 400 Bad Request.
+	ErrContextLengthExceeded = errors.New("context length exceeded")
 )
 
 // GetUserFacingError checks if an error is a known user-facing error that's safe to expose.
@@ -36,5 +40,8 @@ func GetUserFacingError(err error) error {
 	if errors.Is(err, ErrInvalidRequestBody) {
 		return err
 	}
+	if errors.Is(err, ErrContextLengthExceeded) {
+		return err
+	}
 	return nil
 }
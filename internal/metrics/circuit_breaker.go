@@ -0,0 +1,56 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package metrics
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/envoyproxy/ai-gateway/internal/circuitbreaker"
+)
+
+// nolint: godot
+const (
+	// Circuit Breaker State is a gauge metric that reports 1 for the current state of the circuit breaker of each
+	// backend, and 0 for the other states.
+	//
+	// Dimensions:
+	// - backend
+	// - state
+	circuitBreakerState = "aigw.circuit_breaker.state"
+	// Backend attribute, which is the name of the backend including the route name and the route rule index.
+	circuitBreakerAttributeBackend = "backend"
+	// State attribute, which is one of "closed", "open" and "half_open".
+	circuitBreakerAttributeState = "state"
+)
+
+var circuitBreakerStates = []circuitbreaker.State{circuitbreaker.StateClosed, circuitbreaker.StateOpen, circuitbreaker.StateHalfOpen}
+
+// RegisterCircuitBreakerState registers the gauge reporting the state of the circuit breakers returned by the
+// statuses function, which is called on each collection.
+func RegisterCircuitBreakerState(meter metric.Meter, statuses func() []circuitbreaker.Status) error {
+	_, err := meter.Int64ObservableGauge(circuitBreakerState,
+		metric.WithDescription("The state of the circuit breaker of the backend."),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			for _, s := range statuses() {
+				for _, state := range circuitBreakerStates {
+					var v int64
+					if s.State == state {
+						v = 1
+					}
+					o.Observe(v, metric.WithAttributes(
+						attribute.Key(circuitBreakerAttributeBackend).String(s.Backend),
+						attribute.Key(circuitBreakerAttributeState).String(string(state)),
+					))
+				}
+			}
+			return nil
+		}),
+	)
+	return err
+}
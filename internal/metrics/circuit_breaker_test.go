@@ -0,0 +1,47 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/envoyproxy/ai-gateway/internal/circuitbreaker"
+)
+
+func TestRegisterCircuitBreakerState(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	err := RegisterCircuitBreakerState(meter, func() []circuitbreaker.Status {
+		return []circuitbreaker.Status{
+			{Backend: "a", State: circuitbreaker.StateClosed},
+			{Backend: "b", State: circuitbreaker.StateOpen},
+		}
+	})
+	require.NoError(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(t.Context(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+	require.Equal(t, circuitBreakerState, rm.ScopeMetrics[0].Metrics[0].Name)
+	gauge, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Gauge[int64])
+	require.True(t, ok)
+
+	actual := map[[2]string]int64{}
+	for _, dp := range gauge.DataPoints {
+		backend, _ := dp.Attributes.Value(circuitBreakerAttributeBackend)
+		state, _ := dp.Attributes.Value(circuitBreakerAttributeState)
+		actual[[2]string{backend.AsString(), state.AsString()}] = dp.Value
+	}
+	require.Equal(t, map[[2]string]int64{
+		{"a", "closed"}: 1, {"a", "open"}: 0, {"a", "half_open"}: 0,
+		{"b", "closed"}: 0, {"b", "open"}: 1, {"b", "half_open"}: 0,
+	}, actual)
+}
@@ -40,12 +40,14 @@ const (
 	// See: https://opentelemetry.io/docs/specs/semconv/attributes-registry/gen-ai/
 	genaiProviderOpenAI       = "openai"
 	genaiProviderAzureOpenAI  = "azure.openai"
+	genaiProviderAzureAI      = "azure.ai.inference"
 	genaiProviderAWSBedrock   = "aws.bedrock"
 	genaiProviderAWSAnthropic = "aws.anthropic"
 	genaiProviderGCPVertexAI  = "gcp.vertex_ai"
 	genaiProviderGCPAnthropic = "gcp.anthropic"
 	genaiProviderAnthropic    = "anthropic"
 	genaiProviderCohere       = "cohere"
+	genaiProviderOllama       = "ollama"
 
 	genaiTokenTypeInput  = "input"
 	genaiTokenTypeOutput = "output"
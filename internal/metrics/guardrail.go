@@ -0,0 +1,155 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package metrics
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// nolint: godot
+const (
+	// Guardrail PII Detections is a counter metric that records the number of the personally identifiable information
+	// detected in the requests by the PII guardrail of the route rules.
+	//
+	// Dimensions:
+	// - the base attributes of the gen_ai metrics
+	// - entity: the type of the information, e.g., "email" or the name of a custom pattern
+	// - action: the action taken on the information, i.e., "mask", "block" or "tokenize"
+	guardrailPIIDetections = "aigw.guardrail.pii.detections"
+	// Guardrail entity attribute, which is the type of the detected information.
+	guardrailAttributeEntity = "entity"
+	// Guardrail action attribute, which is the action taken on the detected information.
+	guardrailAttributeAction = "action"
+
+	// Guardrail External Checks is a counter metric that records the number of the checks of the requests and the
+	// responses by the external guardrail services of the route rules.
+	//
+	// Dimensions:
+	// - the base attributes of the gen_ai metrics
+	// - phase: the checked part of the exchange, i.e., "request" or "response"
+	// - decision: the decision of the service, i.e., "allow", "deny" or "rewrite", or "error" if the check failed
+	guardrailExternalChecks = "aigw.guardrail.external.checks"
+	// Guardrail phase attribute, which is the checked part of the exchange.
+	guardrailAttributePhase = "phase"
+	// Guardrail decision attribute, which is the decision of the external guardrail service.
+	guardrailAttributeDecision = "decision"
+
+	// Guardrail Prompt Injection Detections is a counter metric that records the number of the requests in which the
+	// prompt injection guardrail of the route rules detected the injection, per rule matching the request.
+	//
+	// Dimensions:
+	// - the base attributes of the gen_ai metrics
+	// - rule: the rule matching the request, e.g., "IgnoreInstructions" or the name of a custom pattern
+	// - action: the action taken on the request, i.e., "block" or "shadow"
+	guardrailPromptInjectionDetections = "aigw.guardrail.prompt_injection.detections"
+	// Guardrail rule attribute, which is the rule of the prompt injection detection matching the request, or the rule
+	// of the output filter matching the response.
+	guardrailAttributeRule = "rule"
+
+	// Guardrail Output Matches is a counter metric that records the number of the responses whose text output matched
+	// a rule of the output guardrail of the route rules.
+	//
+	// Dimensions:
+	// - the base attributes of the gen_ai metrics
+	// - rule: the name of the rule matching the response
+	// - action: the action taken on the response, i.e., "block" or "shadow"
+	guardrailOutputMatches = "aigw.guardrail.output.matches"
+)
+
+// GuardrailMetrics is implemented by the Metrics recording the detections of the guardrails of the route rules.
+type GuardrailMetrics interface {
+	// RecordPIIDetections records the number of the detections of the entity in the request, and the action taken on
+	// them.
+	RecordPIIDetections(ctx context.Context, entity, action string, count int, requestHeaders map[string]string)
+	// RecordExternalGuardrailCheck records a check of the request or the response by the external guardrail service,
+	// and its decision.
+	RecordExternalGuardrailCheck(ctx context.Context, phase, decision string, requestHeaders map[string]string)
+	// RecordPromptInjectionDetection records the detection of the prompt injection in the request by the rule, and the
+	// action taken on the request.
+	RecordPromptInjectionDetection(ctx context.Context, rule, action string, requestHeaders map[string]string)
+	// RecordOutputGuardrailMatch records the match of the text output of the response by the rule of the output
+	// guardrail, and the action taken on the response.
+	RecordOutputGuardrailMatch(ctx context.Context, rule, action string, requestHeaders map[string]string)
+}
+
+// newGuardrailPIIDetections registers the counter of the detections of the PII guardrail.
+func newGuardrailPIIDetections(meter metric.Meter) metric.Float64Counter {
+	return mustRegisterCounter(meter,
+		guardrailPIIDetections,
+		metric.WithDescription("Number of the personally identifiable information detected in the requests by the PII guardrail."),
+	)
+}
+
+// newGuardrailExternalChecks registers the counter of the checks of the external guardrail.
+func newGuardrailExternalChecks(meter metric.Meter) metric.Float64Counter {
+	return mustRegisterCounter(meter,
+		guardrailExternalChecks,
+		metric.WithDescription("Number of the checks of the requests and the responses by the external guardrail services."),
+	)
+}
+
+// newGuardrailPromptInjectionDetections registers the counter of the detections of the prompt injection guardrail.
+func newGuardrailPromptInjectionDetections(meter metric.Meter) metric.Float64Counter {
+	return mustRegisterCounter(meter,
+		guardrailPromptInjectionDetections,
+		metric.WithDescription("Number of the requests with the prompt injection detected by the prompt injection guardrail, per rule."),
+	)
+}
+
+// newGuardrailOutputMatches registers the counter of the matches of the output guardrail.
+func newGuardrailOutputMatches(meter metric.Meter) metric.Float64Counter {
+	return mustRegisterCounter(meter,
+		guardrailOutputMatches,
+		metric.WithDescription("Number of the responses whose text output matched a rule of the output guardrail."),
+	)
+}
+
+// RecordPIIDetections implements [GuardrailMetrics.RecordPIIDetections].
+func (b *metricsImpl) RecordPIIDetections(ctx context.Context, entity, action string, count int, requestHeaders map[string]string) {
+	b.guardrailPIIDetections.Add(ctx, float64(count),
+		metric.WithAttributeSet(b.buildBaseAttributes(requestHeaders)),
+		metric.WithAttributes(
+			attribute.Key(guardrailAttributeEntity).String(entity),
+			attribute.Key(guardrailAttributeAction).String(action),
+		),
+	)
+}
+
+// RecordExternalGuardrailCheck implements [GuardrailMetrics.RecordExternalGuardrailCheck].
+func (b *metricsImpl) RecordExternalGuardrailCheck(ctx context.Context, phase, decision string, requestHeaders map[string]string) {
+	b.guardrailExternalChecks.Add(ctx, 1,
+		metric.WithAttributeSet(b.buildBaseAttributes(requestHeaders)),
+		metric.WithAttributes(
+			attribute.Key(guardrailAttributePhase).String(phase),
+			attribute.Key(guardrailAttributeDecision).String(decision),
+		),
+	)
+}
+
+// RecordPromptInjectionDetection implements [GuardrailMetrics.RecordPromptInjectionDetection].
+func (b *metricsImpl) RecordPromptInjectionDetection(ctx context.Context, rule, action string, requestHeaders map[string]string) {
+	b.guardrailPromptInjectionDetections.Add(ctx, 1,
+		metric.WithAttributeSet(b.buildBaseAttributes(requestHeaders)),
+		metric.WithAttributes(
+			attribute.Key(guardrailAttributeRule).String(rule),
+			attribute.Key(guardrailAttributeAction).String(action),
+		),
+	)
+}
+
+// RecordOutputGuardrailMatch implements [GuardrailMetrics.RecordOutputGuardrailMatch].
+func (b *metricsImpl) RecordOutputGuardrailMatch(ctx context.Context, rule, action string, requestHeaders map[string]string) {
+	b.guardrailOutputMatches.Add(ctx, 1,
+		metric.WithAttributeSet(b.buildBaseAttributes(requestHeaders)),
+		metric.WithAttributes(
+			attribute.Key(guardrailAttributeRule).String(rule),
+			attribute.Key(guardrailAttributeAction).String(action),
+		),
+	)
+}
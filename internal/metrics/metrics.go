@@ -109,6 +109,9 @@ type Metrics interface {
 	// SetBackend sets the selected backend when the routing decision has been made. This is usually called
 	// after parsing the request body to determine the model and invoke the routing logic.
 	SetBackend(backend *filterapi.Backend)
+	// SetErrorType sets the class of the error response, e.g. "rate_limit", to be reported as the error.type
+	// attribute when the request fails. The placeholder "_OTHER" is reported when this is not set.
+	SetErrorType(errorType string)
 	// RecordRequestCompletion records the completion of the request, including success status.
 	RecordRequestCompletion(ctx context.Context, success bool, requestHeaders map[string]string)
 	// RecordTokenUsage records token usage metrics.
@@ -134,7 +137,21 @@ type Factory interface {
 
 // NewMetricsFactory returns a Factory to create a new Metrics instance.
 func NewMetricsFactory(meter metric.Meter, requestHeaderLabelMapping map[string]string, operation GenAIOperation) Factory {
-	return &metricsImplFactory{metrics: newGenAI(meter), requestHeaderAttributeMapping: requestHeaderLabelMapping, operation: string(operation)}
+	return &metricsImplFactory{
+		metrics:                            newGenAI(meter),
+		promptCacheHitRatio:                newPromptCacheHitRatio(meter),
+		shadowSimilarity:                   newShadowSimilarity(meter),
+		shadowDropped:                      newShadowDropped(meter),
+		responseCacheSimilarity:            newResponseCacheSimilarity(meter),
+		responseCacheSavedCost:             newResponseCacheSavedCost(meter),
+		guardrailPIIDetections:             newGuardrailPIIDetections(meter),
+		guardrailExternalChecks:            newGuardrailExternalChecks(meter),
+		guardrailPromptInjectionDetections: newGuardrailPromptInjectionDetections(meter),
+		guardrailOutputMatches:             newGuardrailOutputMatches(meter),
+		parameterPolicyRules:               newParameterPolicyRules(meter),
+		requestHeaderAttributeMapping:      requestHeaderLabelMapping,
+		operation:                          string(operation),
+	}
 }
 
 // TokenUsage represents the token usage reported usually by the backend API in the response body.
@@ -282,6 +299,30 @@ func (u *TokenUsage) Override(other TokenUsage) {
 	}
 }
 
+// Merge adds the fields of another TokenUsage instance to the current values, e.g., to report the usage of a response
+// continued on another backend as a whole. Only fields that are marked as set in the other instance are added.
+func (u *TokenUsage) Merge(other TokenUsage) {
+	if other.inputTokenSet {
+		u.AddInputTokens(other.inputTokens)
+	}
+	if other.outputTokenSet {
+		u.AddOutputTokens(other.outputTokens)
+	}
+	if other.totalTokenSet {
+		u.totalTokens += other.totalTokens
+		u.totalTokenSet = true
+	}
+	if other.cachedInputTokenSet {
+		u.AddCachedInputTokens(other.cachedInputTokens)
+	}
+	if other.cacheCreationInputTokenSet {
+		u.AddCacheCreationInputTokens(other.cacheCreationInputTokens)
+	}
+	if other.reasoningTokenSet {
+		u.AddReasoningTokens(other.reasoningTokens)
+	}
+}
+
 // ExtractTokenUsageFromExplicitCaching extracts the correct token usage from upstream Anthropic or AWS Bedrock token usage response.
 // The total input tokens is the summation of:
 // input_tokens + cache_creation_input_tokens + cache_read_input_tokens
@@ -18,21 +18,41 @@ import (
 
 // metricsImplFactory implements the Factory interface for creating metricsImpl instances.
 type metricsImplFactory struct {
-	metrics                       *genAI
-	requestHeaderAttributeMapping map[string]string // maps HTTP headers to metric attribute names.
-	operation                     string
+	metrics                            *genAI
+	promptCacheHitRatio                metric.Float64Histogram
+	shadowSimilarity                   metric.Float64Histogram
+	shadowDropped                      metric.Float64Counter
+	responseCacheSimilarity            metric.Float64Histogram
+	responseCacheSavedCost             metric.Float64Counter
+	guardrailPIIDetections             metric.Float64Counter
+	guardrailExternalChecks            metric.Float64Counter
+	guardrailPromptInjectionDetections metric.Float64Counter
+	guardrailOutputMatches             metric.Float64Counter
+	parameterPolicyRules               metric.Float64Counter
+	requestHeaderAttributeMapping      map[string]string // maps HTTP headers to metric attribute names.
+	operation                          string
 }
 
 // NewMetrics implements [Factory.NewMetrics].
 func (f *metricsImplFactory) NewMetrics() Metrics {
 	return &metricsImpl{
-		metrics:                       f.metrics,
-		operation:                     f.operation,
-		originalModel:                 "unknown",
-		requestModel:                  "unknown",
-		responseModel:                 "unknown",
-		backend:                       "unknown",
-		requestHeaderAttributeMapping: f.requestHeaderAttributeMapping,
+		metrics:                            f.metrics,
+		promptCacheHitRatio:                f.promptCacheHitRatio,
+		shadowSimilarity:                   f.shadowSimilarity,
+		shadowDropped:                      f.shadowDropped,
+		responseCacheSimilarity:            f.responseCacheSimilarity,
+		responseCacheSavedCost:             f.responseCacheSavedCost,
+		guardrailPIIDetections:             f.guardrailPIIDetections,
+		guardrailExternalChecks:            f.guardrailExternalChecks,
+		guardrailPromptInjectionDetections: f.guardrailPromptInjectionDetections,
+		guardrailOutputMatches:             f.guardrailOutputMatches,
+		parameterPolicyRules:               f.parameterPolicyRules,
+		operation:                          f.operation,
+		originalModel:                      "unknown",
+		requestModel:                       "unknown",
+		responseModel:                      "unknown",
+		backend:                            "unknown",
+		requestHeaderAttributeMapping:      f.requestHeaderAttributeMapping,
 	}
 }
 
@@ -40,9 +60,23 @@ func (f *metricsImplFactory) NewMetrics() Metrics {
 //
 // This implements the Metrics interface.
 type metricsImpl struct {
-	metrics      *genAI
-	operation    string
-	requestStart time.Time
+	metrics             *genAI
+	promptCacheHitRatio metric.Float64Histogram
+	shadowSimilarity    metric.Float64Histogram
+	shadowDropped       metric.Float64Counter
+	// responseCacheSimilarity and responseCacheSavedCost are the metrics of the response cache.
+	responseCacheSimilarity metric.Float64Histogram
+	responseCacheSavedCost  metric.Float64Counter
+	// parameterPolicyRules is the metric of the rules of the parameter policies.
+	parameterPolicyRules metric.Float64Counter
+	// guardrailPIIDetections, guardrailExternalChecks, guardrailPromptInjectionDetections and guardrailOutputMatches
+	// are the metrics of the guardrails.
+	guardrailPIIDetections             metric.Float64Counter
+	guardrailExternalChecks            metric.Float64Counter
+	guardrailPromptInjectionDetections metric.Float64Counter
+	guardrailOutputMatches             metric.Float64Counter
+	operation                          string
+	requestStart                       time.Time
 	// originalModel is the model name extracted from the incoming request body before any virtualization applies.
 	originalModel string
 	// requestModel is the original model from the request body.
@@ -50,6 +84,10 @@ type metricsImpl struct {
 	// responseModel is the model that ultimately generated the response (may differ due to backend override).
 	responseModel                 string
 	backend                       string
+	backendName                   string // the name of the backend including the route name and the route rule index.
+	errorType                     string
+	shadow                        string
+	responseCache                 ResponseCacheStatus
 	requestHeaderAttributeMapping map[string]string // maps HTTP headers to metric attribute names.
 
 	// Fields for streaming token latency calculation, not used for non-streaming requests.
@@ -81,14 +119,22 @@ func (b *metricsImpl) SetResponseModel(responseModel internalapi.ResponseModel)
 	b.responseModel = responseModel
 }
 
+// SetErrorType sets the class of the error response to be reported as the error.type attribute. e.g. rate_limit
+func (b *metricsImpl) SetErrorType(errorType string) {
+	b.errorType = errorType
+}
+
 // SetBackend sets the name of the backend to be reported in the metrics according to:
 // https://opentelemetry.io/docs/specs/semconv/gen-ai/gen-ai-metrics/
 func (b *metricsImpl) SetBackend(backend *filterapi.Backend) {
+	b.backendName = backend.Name
 	switch backend.Schema.Name {
 	case filterapi.APISchemaOpenAI:
 		b.backend = genaiProviderOpenAI
 	case filterapi.APISchemaAzureOpenAI:
 		b.backend = genaiProviderAzureOpenAI
+	case filterapi.APISchemaAzureAIInference:
+		b.backend = genaiProviderAzureAI
 	case filterapi.APISchemaAWSBedrock:
 		b.backend = genaiProviderAWSBedrock
 	case filterapi.APISchemaAWSAnthropic:
@@ -101,6 +147,8 @@ func (b *metricsImpl) SetBackend(backend *filterapi.Backend) {
 		b.backend = genaiProviderAnthropic
 	case filterapi.APISchemaCohere:
 		b.backend = genaiProviderCohere
+	case filterapi.APISchemaOllama:
+		b.backend = genaiProviderOllama
 	default:
 		b.backend = backend.Name
 	}
@@ -113,12 +161,20 @@ func (b *metricsImpl) buildBaseAttributes(headers map[string]string) attribute.S
 	origModel := attribute.Key(genaiAttributeOriginalModel).String(b.originalModel)
 	reqModel := attribute.Key(genaiAttributeRequestModel).String(b.requestModel)
 	respModel := attribute.Key(genaiAttributeResponseModel).String(b.responseModel)
-	if len(b.requestHeaderAttributeMapping) == 0 {
+	shadow, isShadow := b.shadowAttribute()
+	cache, isCached := b.responseCacheAttribute()
+	if len(b.requestHeaderAttributeMapping) == 0 && !isShadow && !isCached {
 		return attribute.NewSet(opt, provider, origModel, reqModel, respModel)
 	}
 
 	// Add header values as attributes based on the header mapping if headers are provided.
 	attrs := []attribute.KeyValue{opt, provider, origModel, reqModel, respModel}
+	if isShadow {
+		attrs = append(attrs, shadow)
+	}
+	if isCached {
+		attrs = append(attrs, cache)
+	}
 	for headerName, labelName := range b.requestHeaderAttributeMapping {
 		if headerValue, exists := headers[headerName]; exists {
 			attrs = append(attrs, attribute.Key(labelName).String(headerValue))
@@ -135,11 +191,15 @@ func (b *metricsImpl) RecordRequestCompletion(ctx context.Context, success bool,
 		// According to the semantic conventions, the error attribute should not be added for successful operations.
 		b.metrics.requestLatency.Record(ctx, time.Since(b.requestStart).Seconds(), metric.WithAttributeSet(attrs))
 	} else {
-		// We don't have a set of typed errors yet, or a set of low-cardinality values, so we can just set the value to the
-		// placeholder one. See: https://opentelemetry.io/docs/specs/semconv/attributes-registry/error/#error-type
+		// The error type is the low-cardinality class of the error response when it is known, and the placeholder
+		// one otherwise. See: https://opentelemetry.io/docs/specs/semconv/attributes-registry/error/#error-type
+		errorType := b.errorType
+		if errorType == "" {
+			errorType = genaiErrorTypeFallback
+		}
 		b.metrics.requestLatency.Record(ctx, time.Since(b.requestStart).Seconds(),
 			metric.WithAttributeSet(attrs),
-			metric.WithAttributes(attribute.Key(genaiAttributeErrorType).String(genaiErrorTypeFallback)),
+			metric.WithAttributes(attribute.Key(genaiAttributeErrorType).String(errorType)),
 		)
 	}
 }
@@ -159,6 +219,12 @@ func (b *metricsImpl) RecordTokenUsage(ctx context.Context, usage TokenUsage, re
 			metric.WithAttributeSet(attrs),
 			metric.WithAttributes(attribute.Key(genaiAttributeTokenType).String(genaiTokenTypeCachedInput)),
 		)
+		if inputTokens, ok := usage.InputTokens(); ok && inputTokens > 0 {
+			b.promptCacheHitRatio.Record(ctx, float64(cachedInputTokens)/float64(inputTokens),
+				metric.WithAttributeSet(attrs),
+				metric.WithAttributes(attribute.Key(promptCacheAttributeBackend).String(b.backendName)),
+			)
+		}
 	}
 	if cacheCreationInputTokens, ok := usage.CacheCreationInputTokens(); ok {
 		b.metrics.tokenUsage.Record(ctx, float64(cacheCreationInputTokens),
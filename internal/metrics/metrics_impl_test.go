@@ -6,6 +6,7 @@
 package metrics
 
 import (
+	"slices"
 	"testing"
 	"testing/synctest"
 	"time"
@@ -103,6 +104,198 @@ func TestRecordTokenUsage(t *testing.T) {
 	assert.Equal(t, 5.0, sum)
 }
 
+func TestRecordTokenUsage_PromptCacheHitRatio(t *testing.T) {
+	t.Parallel()
+	var (
+		mr    = metric.NewManualReader()
+		meter = metric.NewMeterProvider(metric.WithReader(mr)).Meter("test")
+		pm    = NewMetricsFactory(meter, nil, GenAIOperationChat).NewMetrics().(*metricsImpl)
+
+		attrs = attribute.NewSet(
+			attribute.Key(genaiAttributeOperationName).String(string(GenAIOperationChat)),
+			attribute.Key(genaiAttributeProviderName).String(genaiProviderOpenAI),
+			attribute.Key(genaiAttributeOriginalModel).String("unknown"),
+			attribute.Key(genaiAttributeRequestModel).String("unknown"),
+			attribute.Key(genaiAttributeResponseModel).String("unknown"),
+			attribute.Key(promptCacheAttributeBackend).String("ns/backend/route/route1/rule/0/ref/0"),
+		)
+	)
+
+	pm.SetBackend(&filterapi.Backend{
+		Name:   "ns/backend/route/route1/rule/0/ref/0",
+		Schema: filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI},
+	})
+	pm.RecordTokenUsage(t.Context(), TokenUsage{
+		inputTokens: 100, cachedInputTokens: 75, inputTokenSet: true, cachedInputTokenSet: true,
+	}, nil)
+	// The ratio is not recorded without the cached input tokens or the input tokens.
+	pm.RecordTokenUsage(t.Context(), TokenUsage{inputTokens: 100, inputTokenSet: true}, nil)
+	pm.RecordTokenUsage(t.Context(), TokenUsage{inputTokens: 0, cachedInputTokens: 0, inputTokenSet: true, cachedInputTokenSet: true}, nil)
+
+	count, sum := testotel.GetHistogramValues(t, mr, promptCacheHitRatio, attrs)
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, 0.75, sum)
+}
+
+func TestShadowMetrics(t *testing.T) {
+	t.Parallel()
+	var (
+		mr    = metric.NewManualReader()
+		meter = metric.NewMeterProvider(metric.WithReader(mr)).Meter("test")
+		pm    = NewMetricsFactory(meter, nil, GenAIOperationChat).NewMetrics()
+
+		attrs = attribute.NewSet(
+			attribute.Key(genaiAttributeOperationName).String(string(GenAIOperationChat)),
+			attribute.Key(genaiAttributeProviderName).String(genaiProviderOpenAI),
+			attribute.Key(genaiAttributeOriginalModel).String("unknown"),
+			attribute.Key(genaiAttributeRequestModel).String("unknown"),
+			attribute.Key(genaiAttributeResponseModel).String("unknown"),
+			attribute.Key(shadowAttributeShadow).String("ns/shadow/route/route1/rule/0/shadow/0"),
+		)
+	)
+
+	sm, ok := pm.(ShadowMetrics)
+	require.True(t, ok)
+	sm.SetShadow("ns/shadow/route/route1/rule/0/shadow/0")
+	pm.SetBackend(&filterapi.Backend{
+		Name:   "ns/shadow/route/route1/rule/0/shadow/0",
+		Schema: filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI},
+	})
+	pm.StartRequest(nil)
+	pm.RecordRequestCompletion(t.Context(), true, nil)
+	sm.RecordShadowSimilarity(t.Context(), 0.25, nil)
+	sm.RecordShadowDropped(t.Context(), nil)
+
+	count, _ := testotel.GetHistogramValues(t, mr, genaiMetricServerRequestDuration, attrs)
+	assert.Equal(t, uint64(1), count)
+	count, sum := testotel.GetHistogramValues(t, mr, shadowSimilarity, attrs)
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, 0.25, sum)
+	assert.Equal(t, float64(1), testotel.GetCounterValue(t, mr, shadowDropped, attrs))
+}
+
+func TestResponseCacheMetrics(t *testing.T) {
+	t.Parallel()
+	var (
+		mr    = metric.NewManualReader()
+		meter = metric.NewMeterProvider(metric.WithReader(mr)).Meter("test")
+		pm    = NewMetricsFactory(meter, nil, GenAIOperationChat).NewMetrics()
+
+		attrs = attribute.NewSet(
+			attribute.Key(genaiAttributeOperationName).String(string(GenAIOperationChat)),
+			attribute.Key(genaiAttributeProviderName).String(genaiProviderOpenAI),
+			attribute.Key(genaiAttributeOriginalModel).String("unknown"),
+			attribute.Key(genaiAttributeRequestModel).String("unknown"),
+			attribute.Key(genaiAttributeResponseModel).String("unknown"),
+			attribute.Key(responseCacheAttributeCache).String("hit"),
+		)
+	)
+
+	cm, ok := pm.(ResponseCacheMetrics)
+	require.True(t, ok)
+	cm.SetResponseCacheStatus(ResponseCacheHit)
+	pm.SetBackend(&filterapi.Backend{Name: "openai", Schema: filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI}})
+	pm.StartRequest(nil)
+	var usage TokenUsage
+	usage.SetInputTokens(0)
+	usage.SetOutputTokens(0)
+	pm.RecordTokenUsage(t.Context(), usage, nil)
+	pm.RecordRequestCompletion(t.Context(), true, nil)
+
+	count, _ := testotel.GetHistogramValues(t, mr, genaiMetricServerRequestDuration, attrs)
+	assert.Equal(t, uint64(1), count)
+	inputAttrs := attribute.NewSet(append(attrs.ToSlice(), attribute.Key(genaiAttributeTokenType).String(genaiTokenTypeInput))...)
+	count, sum := testotel.GetHistogramValues(t, mr, genaiMetricClientTokenUsage, inputAttrs)
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, 0.0, sum)
+
+	cm.RecordResponseCacheSimilarity(t.Context(), 0.97, nil)
+	count, sum = testotel.GetHistogramValues(t, mr, responseCacheSimilarity, attrs)
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, 0.97, sum)
+
+	cm.RecordResponseCacheSavedCost(t.Context(), "llm_total_cost", 30, nil)
+	cm.RecordResponseCacheSavedCost(t.Context(), "llm_total_cost", 12, nil)
+	costAttrs := attribute.NewSet(append(attrs.ToSlice(), attribute.Key(responseCacheAttributeCost).String("llm_total_cost"))...)
+	assert.Equal(t, 42.0, testotel.GetCounterValue(t, mr, responseCacheSavedCost, costAttrs))
+}
+
+func TestGuardrailMetrics(t *testing.T) {
+	t.Parallel()
+	var (
+		mr    = metric.NewManualReader()
+		meter = metric.NewMeterProvider(metric.WithReader(mr)).Meter("test")
+		pm    = NewMetricsFactory(meter, nil, GenAIOperationChat).NewMetrics()
+
+		baseAttrs = []attribute.KeyValue{
+			attribute.Key(genaiAttributeOperationName).String(string(GenAIOperationChat)),
+			attribute.Key(genaiAttributeProviderName).String(genaiProviderOpenAI),
+			attribute.Key(genaiAttributeOriginalModel).String("unknown"),
+			attribute.Key(genaiAttributeRequestModel).String("unknown"),
+			attribute.Key(genaiAttributeResponseModel).String("unknown"),
+		}
+		attrs = attribute.NewSet(append(slices.Clone(baseAttrs),
+			attribute.Key(guardrailAttributeEntity).String("email"),
+			attribute.Key(guardrailAttributeAction).String("mask"),
+		)...)
+		checkAttrs = attribute.NewSet(append(slices.Clone(baseAttrs),
+			attribute.Key(guardrailAttributePhase).String("response"),
+			attribute.Key(guardrailAttributeDecision).String("deny"),
+		)...)
+		injectionAttrs = attribute.NewSet(append(slices.Clone(baseAttrs),
+			attribute.Key(guardrailAttributeRule).String("IgnoreInstructions"),
+			attribute.Key(guardrailAttributeAction).String("shadow"),
+		)...)
+		outputAttrs = attribute.NewSet(append(slices.Clone(baseAttrs),
+			attribute.Key(guardrailAttributeRule).String("codename"),
+			attribute.Key(guardrailAttributeAction).String("block"),
+		)...)
+	)
+
+	gm, ok := pm.(GuardrailMetrics)
+	require.True(t, ok)
+	pm.SetBackend(&filterapi.Backend{Name: "openai", Schema: filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI}})
+	gm.RecordPIIDetections(t.Context(), "email", "mask", 2, nil)
+	gm.RecordPIIDetections(t.Context(), "email", "mask", 1, nil)
+	assert.Equal(t, 3.0, testotel.GetCounterValue(t, mr, guardrailPIIDetections, attrs))
+
+	gm.RecordExternalGuardrailCheck(t.Context(), "response", "deny", nil)
+	assert.Equal(t, 1.0, testotel.GetCounterValue(t, mr, guardrailExternalChecks, checkAttrs))
+
+	gm.RecordPromptInjectionDetection(t.Context(), "IgnoreInstructions", "shadow", nil)
+	assert.Equal(t, 1.0, testotel.GetCounterValue(t, mr, guardrailPromptInjectionDetections, injectionAttrs))
+
+	gm.RecordOutputGuardrailMatch(t.Context(), "codename", "block", nil)
+	assert.Equal(t, 1.0, testotel.GetCounterValue(t, mr, guardrailOutputMatches, outputAttrs))
+}
+
+func TestParameterPolicyMetrics(t *testing.T) {
+	t.Parallel()
+	var (
+		mr    = metric.NewManualReader()
+		meter = metric.NewMeterProvider(metric.WithReader(mr)).Meter("test")
+		pm    = NewMetricsFactory(meter, nil, GenAIOperationChat).NewMetrics()
+
+		attrs = attribute.NewSet(
+			attribute.Key(genaiAttributeOperationName).String(string(GenAIOperationChat)),
+			attribute.Key(genaiAttributeProviderName).String(genaiProviderOpenAI),
+			attribute.Key(genaiAttributeOriginalModel).String("unknown"),
+			attribute.Key(genaiAttributeRequestModel).String("unknown"),
+			attribute.Key(genaiAttributeResponseModel).String("unknown"),
+			attribute.Key(parameterPolicyAttributeRule).String("cap-max-tokens"),
+			attribute.Key(parameterPolicyAttributeParameter).String("MaxTokens"),
+			attribute.Key(parameterPolicyAttributeAction).String("clamp"),
+		)
+	)
+
+	ppm, ok := pm.(ParameterPolicyMetrics)
+	require.True(t, ok)
+	pm.SetBackend(&filterapi.Backend{Name: "openai", Schema: filterapi.VersionedAPISchema{Name: filterapi.APISchemaOpenAI}})
+	ppm.RecordParameterPolicyRule(t.Context(), "cap-max-tokens", "MaxTokens", "clamp", nil)
+	ppm.RecordParameterPolicyRule(t.Context(), "cap-max-tokens", "MaxTokens", "clamp", nil)
+	assert.Equal(t, 2.0, testotel.GetCounterValue(t, mr, parameterPolicyRules, attrs))
+}
+
 func TestRecordTokenLatency(t *testing.T) {
 	synctest.Test(t, testRecordTokenLatency)
 }
@@ -188,6 +381,14 @@ func testRecordRequestCompletion(t *testing.T) {
 	count, sum = testotel.GetHistogramValues(t, mr, genaiMetricServerRequestDuration, attrsFailure)
 	assert.Equal(t, uint64(2), count)
 	assert.Equal(t, 2*10*time.Millisecond.Seconds(), sum)
+
+	// The class of the error response replaces the placeholder error type.
+	pm.SetErrorType("rate_limit")
+	pm.RecordRequestCompletion(t.Context(), false, nil)
+	attrsRateLimit := attribute.NewSet(append(attrs, attribute.Key(genaiAttributeErrorType).String("rate_limit"))...)
+	count, sum = testotel.GetHistogramValues(t, mr, genaiMetricServerRequestDuration, attrsRateLimit)
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, 10*time.Millisecond.Seconds(), sum)
 }
 
 func TestGetTimeToFirstTokenMsAndGetInterTokenLatencyMs(t *testing.T) {
@@ -646,6 +847,11 @@ func TestSetBackendProviderName(t *testing.T) {
 			schema:           filterapi.APISchemaAzureOpenAI,
 			expectedProvider: "azure.openai",
 		},
+		{
+			name:             "Azure AI Inference schema",
+			schema:           filterapi.APISchemaAzureAIInference,
+			expectedProvider: "azure.ai.inference",
+		},
 		{
 			name:             "AWS Bedrock schema",
 			schema:           filterapi.APISchemaAWSBedrock,
@@ -676,6 +882,11 @@ func TestSetBackendProviderName(t *testing.T) {
 			schema:           filterapi.APISchemaCohere,
 			expectedProvider: "cohere",
 		},
+		{
+			name:             "Ollama schema",
+			schema:           filterapi.APISchemaOllama,
+			expectedProvider: "ollama",
+		},
 		{
 			name:             "Unknown schema falls back to backend name",
 			schema:           "UnknownSchema",
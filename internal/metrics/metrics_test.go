@@ -469,3 +469,32 @@ func TestNewMeterFromEnv_OTLPHeaders(t *testing.T) {
 
 	require.Equal(t, expectedAuthorization, <-actualAuthorization)
 }
+
+func TestTokenUsage_Merge(t *testing.T) {
+	var u TokenUsage
+	u.SetInputTokens(10)
+	u.SetOutputTokens(5)
+	u.SetTotalTokens(15)
+
+	var other TokenUsage
+	other.SetInputTokens(12)
+	other.SetOutputTokens(20)
+	other.SetTotalTokens(32)
+	other.SetCachedInputTokens(8)
+	u.Merge(other)
+
+	in, ok := u.InputTokens()
+	require.True(t, ok)
+	require.Equal(t, uint32(22), in)
+	out, ok := u.OutputTokens()
+	require.True(t, ok)
+	require.Equal(t, uint32(25), out)
+	total, ok := u.TotalTokens()
+	require.True(t, ok)
+	require.Equal(t, uint32(47), total)
+	cached, ok := u.CachedInputTokens()
+	require.True(t, ok)
+	require.Equal(t, uint32(8), cached)
+	_, ok = u.ReasoningTokens()
+	require.False(t, ok)
+}
@@ -0,0 +1,59 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package metrics
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// nolint: godot
+const (
+	// Parameter Policy Rules is a counter metric that records the number of the requests changed or rejected by the
+	// rules of the parameter policies, per rule.
+	//
+	// Dimensions:
+	// - the base attributes of the gen_ai metrics
+	// - rule: the name of the rule, e.g., "cap-max-tokens", or "blocked-fields" or "thinking-budget"
+	// - parameter: the parameter of the rule, e.g., "MaxTokens", or the path of the blocked field
+	// - action: the change made by the rule, i.e., "default", "clamp", "remove" or "reject"
+	parameterPolicyRules = "aigw.parameter_policy.rules"
+	// Parameter policy rule attribute, which is the name of the rule.
+	parameterPolicyAttributeRule = "rule"
+	// Parameter policy parameter attribute, which is the parameter of the rule.
+	parameterPolicyAttributeParameter = "parameter"
+	// Parameter policy action attribute, which is the change made by the rule.
+	parameterPolicyAttributeAction = "action"
+)
+
+// ParameterPolicyMetrics is implemented by the Metrics recording the rules of the parameter policies applied to the
+// requests.
+type ParameterPolicyMetrics interface {
+	// RecordParameterPolicyRule records the change made to the request by the rule, or its rejection.
+	RecordParameterPolicyRule(ctx context.Context, rule, parameter, action string, requestHeaders map[string]string)
+}
+
+// newParameterPolicyRules registers the counter of the rules of the parameter policies.
+func newParameterPolicyRules(meter metric.Meter) metric.Float64Counter {
+	return mustRegisterCounter(meter,
+		parameterPolicyRules,
+		metric.WithDescription("Number of the requests changed or rejected by the rules of the parameter policies, per rule."),
+	)
+}
+
+// RecordParameterPolicyRule implements [ParameterPolicyMetrics.RecordParameterPolicyRule].
+func (b *metricsImpl) RecordParameterPolicyRule(ctx context.Context, rule, parameter, action string, requestHeaders map[string]string) {
+	b.parameterPolicyRules.Add(ctx, 1,
+		metric.WithAttributeSet(b.buildBaseAttributes(requestHeaders)),
+		metric.WithAttributes(
+			attribute.Key(parameterPolicyAttributeRule).String(rule),
+			attribute.Key(parameterPolicyAttributeParameter).String(parameter),
+			attribute.Key(parameterPolicyAttributeAction).String(action),
+		),
+	)
+}
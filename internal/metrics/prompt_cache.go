@@ -0,0 +1,31 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package metrics
+
+import "go.opentelemetry.io/otel/metric"
+
+// nolint: godot
+const (
+	// Prompt Cache Hit Ratio is a histogram metric that records the ratio of the cached input tokens to the input
+	// tokens of each response reporting both, which shows the effect of the affinity of the backends.
+	//
+	// Dimensions:
+	// - the base attributes of the gen_ai metrics
+	// - backend
+	promptCacheHitRatio = "aigw.prompt_cache.hit_ratio"
+	// Backend attribute, which is the name of the backend including the route name and the route rule index.
+	promptCacheAttributeBackend = "backend"
+)
+
+// newPromptCacheHitRatio registers the histogram of the prompt cache hit ratio.
+func newPromptCacheHitRatio(meter metric.Meter) metric.Float64Histogram {
+	return mustRegisterHistogram(meter,
+		promptCacheHitRatio,
+		metric.WithDescription("Ratio of the cached input tokens to the input tokens of the response."),
+		metric.WithUnit("1"),
+		metric.WithExplicitBucketBoundaries(0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1),
+	)
+}
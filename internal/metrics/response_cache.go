@@ -0,0 +1,108 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package metrics
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// nolint: godot
+const (
+	// Response Cache Similarity is a histogram metric that records the cosine similarity, from -1 to 1, of the most
+	// similar cached request found by the semantic lookup of the response cache, regardless of whether it is a hit.
+	//
+	// Dimensions:
+	// - the base attributes of the gen_ai metrics
+	// - cache
+	responseCacheSimilarity = "aigw.response_cache.similarity"
+	// Response Cache Saved Cost is a counter metric that records the costs of the cached responses served on the cache
+	// hits, calculated by the CEL expressions of the LLMRequestCosts from the token usage of the cached responses.
+	//
+	// Dimensions:
+	// - the base attributes of the gen_ai metrics
+	// - cache
+	// - cost: the metadata key of the LLMRequestCost
+	responseCacheSavedCost = "aigw.response_cache.saved_cost"
+	// Response cache cost attribute, which is the metadata key of the LLMRequestCost of the saved cost.
+	responseCacheAttributeCost = "cost"
+	// Response cache attribute, which is the outcome of the lookup of the response cache. This is added to all the
+	// metrics of the requests to the route rules with the response cache.
+	responseCacheAttributeCache = "cache"
+)
+
+// ResponseCacheStatus is the outcome of the lookup of the response cache for a request.
+type ResponseCacheStatus string
+
+const (
+	// ResponseCacheHit is the status of the requests served from the response cache.
+	ResponseCacheHit ResponseCacheStatus = "hit"
+	// ResponseCacheMiss is the status of the requests whose response is not cached yet.
+	ResponseCacheMiss ResponseCacheStatus = "miss"
+	// ResponseCacheBypass is the status of the requests that bypass the response cache, e.g., with the
+	// "Cache-Control: no-cache" header.
+	ResponseCacheBypass ResponseCacheStatus = "bypass"
+)
+
+// ResponseCacheMetrics is implemented by the Metrics recording the requests to the route rules with the response
+// cache.
+type ResponseCacheMetrics interface {
+	// SetResponseCacheStatus sets the outcome of the lookup of the response cache, which is added as the cache
+	// attribute to all the metrics.
+	SetResponseCacheStatus(status ResponseCacheStatus)
+	// RecordResponseCacheSimilarity records the similarity of the most similar cached request found by the semantic
+	// lookup.
+	RecordResponseCacheSimilarity(ctx context.Context, similarity float64, requestHeaders map[string]string)
+	// RecordResponseCacheSavedCost records the cost of the cached response served on a cache hit, which is calculated
+	// by the LLMRequestCost of the given metadata key.
+	RecordResponseCacheSavedCost(ctx context.Context, costKey string, cost float64, requestHeaders map[string]string)
+}
+
+// newResponseCacheSimilarity registers the histogram of the similarity of the semantic lookups of the response cache.
+func newResponseCacheSimilarity(meter metric.Meter) metric.Float64Histogram {
+	return mustRegisterHistogram(meter,
+		responseCacheSimilarity,
+		metric.WithDescription("Similarity of the most similar cached request found by the semantic lookup of the response cache."),
+		metric.WithUnit("1"),
+		metric.WithExplicitBucketBoundaries(0, 0.5, 0.6, 0.7, 0.8, 0.85, 0.9, 0.95, 0.98, 0.99, 1),
+	)
+}
+
+// newResponseCacheSavedCost registers the counter of the costs saved by the response cache.
+func newResponseCacheSavedCost(meter metric.Meter) metric.Float64Counter {
+	return mustRegisterCounter(meter,
+		responseCacheSavedCost,
+		metric.WithDescription("Cost of the cached responses served by the response cache, as calculated by the LLMRequestCosts."),
+	)
+}
+
+// SetResponseCacheStatus implements [ResponseCacheMetrics.SetResponseCacheStatus].
+func (b *metricsImpl) SetResponseCacheStatus(status ResponseCacheStatus) {
+	b.responseCache = status
+}
+
+// RecordResponseCacheSimilarity implements [ResponseCacheMetrics.RecordResponseCacheSimilarity].
+func (b *metricsImpl) RecordResponseCacheSimilarity(ctx context.Context, similarity float64, requestHeaders map[string]string) {
+	b.responseCacheSimilarity.Record(ctx, similarity, metric.WithAttributeSet(b.buildBaseAttributes(requestHeaders)))
+}
+
+// RecordResponseCacheSavedCost implements [ResponseCacheMetrics.RecordResponseCacheSavedCost].
+func (b *metricsImpl) RecordResponseCacheSavedCost(ctx context.Context, costKey string, cost float64, requestHeaders map[string]string) {
+	b.responseCacheSavedCost.Add(ctx, cost,
+		metric.WithAttributeSet(b.buildBaseAttributes(requestHeaders)),
+		metric.WithAttributes(attribute.Key(responseCacheAttributeCost).String(costKey)),
+	)
+}
+
+// responseCacheAttribute returns the response cache attribute to add to the base attributes, if any.
+func (b *metricsImpl) responseCacheAttribute() (attribute.KeyValue, bool) {
+	if b.responseCache == "" {
+		return attribute.KeyValue{}, false
+	}
+	return attribute.Key(responseCacheAttributeCache).String(string(b.responseCache)), true
+}
@@ -0,0 +1,85 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package metrics
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// nolint: godot
+const (
+	// Shadow Similarity is a histogram metric that records the similarity, from 0 to 1, of the response of each
+	// shadow backend to the one returned to the client when the shadow compares the responses.
+	//
+	// Dimensions:
+	// - the base attributes of the gen_ai metrics
+	// - shadow
+	shadowSimilarity = "aigw.shadow.similarity"
+	// Shadow Dropped is a counter metric that records the number of the copies of the requests that are not sent to
+	// the shadow backends since too many copies are already in flight.
+	//
+	// Dimensions:
+	// - the base attributes of the gen_ai metrics
+	// - shadow
+	shadowDropped = "aigw.shadow.dropped"
+	// Shadow attribute, which is the name of the shadow backend including the route name and the route rule index.
+	// This is added to all the metrics of the copies of the requests sent to the shadow backends.
+	shadowAttributeShadow = "shadow"
+)
+
+// ShadowMetrics is implemented by the Metrics recording the copies of the requests sent to the shadow backends.
+type ShadowMetrics interface {
+	// SetShadow sets the name of the shadow backend, which is added as the shadow attribute to all the metrics.
+	SetShadow(shadow string)
+	// RecordShadowSimilarity records the similarity of the response of the shadow backend to the primary one.
+	RecordShadowSimilarity(ctx context.Context, similarity float64, requestHeaders map[string]string)
+	// RecordShadowDropped records a copy of the request that is not sent to the shadow backend.
+	RecordShadowDropped(ctx context.Context, requestHeaders map[string]string)
+}
+
+// newShadowSimilarity registers the histogram of the similarity of the responses of the shadow backends.
+func newShadowSimilarity(meter metric.Meter) metric.Float64Histogram {
+	return mustRegisterHistogram(meter,
+		shadowSimilarity,
+		metric.WithDescription("Similarity of the response of the shadow backend to the one returned to the client."),
+		metric.WithUnit("1"),
+		metric.WithExplicitBucketBoundaries(0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1),
+	)
+}
+
+// newShadowDropped registers the counter of the copies of the requests not sent to the shadow backends.
+func newShadowDropped(meter metric.Meter) metric.Float64Counter {
+	return mustRegisterCounter(meter,
+		shadowDropped,
+		metric.WithDescription("Number of the copies of the requests not sent to the shadow backends since too many are in flight."),
+	)
+}
+
+// SetShadow implements [ShadowMetrics.SetShadow].
+func (b *metricsImpl) SetShadow(shadow string) {
+	b.shadow = shadow
+}
+
+// RecordShadowSimilarity implements [ShadowMetrics.RecordShadowSimilarity].
+func (b *metricsImpl) RecordShadowSimilarity(ctx context.Context, similarity float64, requestHeaders map[string]string) {
+	b.shadowSimilarity.Record(ctx, similarity, metric.WithAttributeSet(b.buildBaseAttributes(requestHeaders)))
+}
+
+// RecordShadowDropped implements [ShadowMetrics.RecordShadowDropped].
+func (b *metricsImpl) RecordShadowDropped(ctx context.Context, requestHeaders map[string]string) {
+	b.shadowDropped.Add(ctx, 1, metric.WithAttributeSet(b.buildBaseAttributes(requestHeaders)))
+}
+
+// shadowAttribute returns the shadow attribute to add to the base attributes, if any.
+func (b *metricsImpl) shadowAttribute() (attribute.KeyValue, bool) {
+	if b.shadow == "" {
+		return attribute.KeyValue{}, false
+	}
+	return attribute.Key(shadowAttributeShadow).String(b.shadow), true
+}
@@ -0,0 +1,235 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+// Package parameterpolicy provides the rules of the parameter policies applied to the generation parameters of the
+// requests, e.g., the maximum number of the output tokens.
+//
+// This exists as a separate package to be used both in the controller to validate the values of the rules
+// and in the external processor to apply them to the requests.
+package parameterpolicy
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// Parameter is a generation parameter governed by the rules.
+type Parameter string
+
+const (
+	// ParameterMaxTokens is the maximum number of the output tokens.
+	ParameterMaxTokens Parameter = "MaxTokens"
+	// ParameterTemperature is the sampling temperature.
+	ParameterTemperature Parameter = "Temperature"
+	// ParameterTopP is the probability mass of the nucleus sampling.
+	ParameterTopP Parameter = "TopP"
+	// ParameterN is the number of the choices generated.
+	ParameterN Parameter = "N"
+	// ParameterThinkingBudgetTokens is the maximum number of the tokens of the extended thinking.
+	ParameterThinkingBudgetTokens Parameter = "ThinkingBudgetTokens"
+	// ParameterReasoningEffort is the effort of the reasoning models.
+	ParameterReasoningEffort Parameter = "ReasoningEffort"
+)
+
+// Action is the action taken by a rule on the parameter.
+type Action string
+
+const (
+	// ActionClamp replaces the values out of the range with its bounds.
+	ActionClamp Action = "Clamp"
+	// ActionReject rejects the requests with the values out of the range.
+	ActionReject Action = "Reject"
+	// ActionRemove removes the parameter from the requests.
+	ActionRemove Action = "Remove"
+)
+
+// Outcome is the change made by a rule to a value, which is reported in the logs and the metrics.
+type Outcome string
+
+const (
+	// OutcomeNone is returned when the rule does not change the value.
+	OutcomeNone Outcome = ""
+	// OutcomeDefault is returned when the default is set on the unset parameter.
+	OutcomeDefault Outcome = "default"
+	// OutcomeClamp is returned when the value is replaced with a bound of the range.
+	OutcomeClamp Outcome = "clamp"
+	// OutcomeRemove is returned when the parameter is removed.
+	OutcomeRemove Outcome = "remove"
+	// OutcomeReject is returned when the request must be rejected.
+	OutcomeReject Outcome = "reject"
+)
+
+const (
+	// RuleBlockedFields is the name reported for the rejections of the requests setting the blocked fields.
+	RuleBlockedFields = "blocked-fields"
+	// RuleThinkingBudget is the name reported for the changes of the ThinkingBudgetTokens lowered below the
+	// MaxTokens, and the rejections of the requests whose budget would then be below MinThinkingBudgetTokens.
+	RuleThinkingBudget = "thinking-budget"
+	// MinThinkingBudgetTokens is the minimum of the ThinkingBudgetTokens accepted by the providers.
+	MinThinkingBudgetTokens = 1024
+)
+
+// ReasoningEffortLevels are the levels of the ReasoningEffort from the lowest to the highest.
+var ReasoningEffortLevels = []string{"none", "minimal", "low", "medium", "high", "xhigh", "max"}
+
+// Rule is a compiled rule of a parameter policy.
+type Rule struct {
+	// Name is the name of the rule.
+	Name string
+	// Parameter is the parameter to which the rule applies.
+	Parameter Parameter
+	// Action is the action taken on the parameter.
+	Action Action
+
+	// def is the default, which is a float64 or a string for the ReasoningEffort, or nil if not set.
+	def any
+	// min and max are the bounds of the range. The levels of the ReasoningEffort are their indexes in
+	// ReasoningEffortLevels.
+	min, max *float64
+}
+
+// NewRule creates a new Rule. The default, the minimum and the maximum are the decimal numbers, or the levels of
+// the ReasoningEffort, and the empty strings are treated as unset.
+func NewRule(name string, parameter Parameter, action Action, def, minimum, maximum string) (*Rule, error) {
+	switch action {
+	case "":
+		action = ActionClamp
+	case ActionClamp, ActionReject, ActionRemove:
+	default:
+		return nil, fmt.Errorf("unknown action %q", action)
+	}
+	r := &Rule{Name: name, Parameter: parameter, Action: action}
+	if action == ActionRemove && (def != "" || minimum != "" || maximum != "") {
+		return nil, fmt.Errorf("default, min and max cannot be set with the %s action", action)
+	}
+	if action == ActionClamp && def == "" && minimum == "" && maximum == "" {
+		return nil, fmt.Errorf("at least one of default, min or max must be set with the %s action", action)
+	}
+	var err error
+	if def != "" {
+		if parameter == ParameterReasoningEffort {
+			if !slices.Contains(ReasoningEffortLevels, def) {
+				return nil, fmt.Errorf("invalid default %q: unknown reasoning effort", def)
+			}
+			r.def = def
+		} else {
+			var v *float64
+			if v, err = parseValue(parameter, def); err != nil {
+				return nil, fmt.Errorf("invalid default %q: %w", def, err)
+			}
+			r.def = *v
+		}
+	}
+	if minimum != "" {
+		if r.min, err = parseValue(parameter, minimum); err != nil {
+			return nil, fmt.Errorf("invalid min %q: %w", minimum, err)
+		}
+	}
+	if maximum != "" {
+		if r.max, err = parseValue(parameter, maximum); err != nil {
+			return nil, fmt.Errorf("invalid max %q: %w", maximum, err)
+		}
+	}
+	if r.min != nil && r.max != nil && *r.min > *r.max {
+		return nil, fmt.Errorf("min %q is greater than max %q", minimum, maximum)
+	}
+	return r, nil
+}
+
+// parseValue parses a bound of the given parameter into a number.
+func parseValue(parameter Parameter, s string) (*float64, error) {
+	switch parameter {
+	case ParameterReasoningEffort:
+		i := slices.Index(ReasoningEffortLevels, s)
+		if i < 0 {
+			return nil, fmt.Errorf("unknown reasoning effort")
+		}
+		v := float64(i)
+		return &v, nil
+	case ParameterMaxTokens, ParameterN, ParameterThinkingBudgetTokens:
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("must be a non-negative integer")
+		}
+		f := float64(v)
+		return &f, nil
+	case ParameterTemperature, ParameterTopP:
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+			return nil, fmt.Errorf("must be a non-negative number")
+		}
+		return &v, nil
+	default:
+		return nil, fmt.Errorf("unknown parameter %q", parameter)
+	}
+}
+
+// Apply applies the rule to the value of the parameter, which is a float64, a string for the ReasoningEffort, or nil
+// if the parameter is not set. This returns the new value, which is nil if the parameter must be removed, and the
+// outcome. The value is returned as is when the outcome is OutcomeNone or OutcomeReject.
+//
+// The levels of the ReasoningEffort not in ReasoningEffortLevels are treated as out of the range, so they are
+// replaced with the maximum, or the minimum if the maximum is not set, by the Clamp action.
+func (r *Rule) Apply(value any) (any, Outcome) {
+	if value == nil {
+		if r.def != nil {
+			return r.def, OutcomeDefault
+		}
+		return nil, OutcomeNone
+	}
+	switch r.Action {
+	case ActionRemove:
+		return nil, OutcomeRemove
+	case ActionReject:
+		if r.min == nil && r.max == nil {
+			return value, OutcomeReject
+		}
+		if _, ok := r.clamp(value); !ok {
+			return value, OutcomeReject
+		}
+		return value, OutcomeNone
+	default:
+		if bound, ok := r.clamp(value); !ok {
+			return bound, OutcomeClamp
+		}
+		return value, OutcomeNone
+	}
+}
+
+// clamp returns true if the value is in the range. Otherwise, this returns the bound replacing it.
+func (r *Rule) clamp(value any) (any, bool) {
+	var v float64
+	known := true
+	switch value := value.(type) {
+	case float64:
+		v = value
+	case string:
+		i := slices.Index(ReasoningEffortLevels, value)
+		v, known = float64(i), i >= 0
+	default:
+		known = false
+	}
+	switch {
+	case known && (r.min == nil || v >= *r.min) && (r.max == nil || v <= *r.max):
+		return nil, true
+	case r.max != nil && (!known || v > *r.max):
+		return r.boundValue(*r.max), false
+	case r.min != nil:
+		return r.boundValue(*r.min), false
+	default:
+		// Unknown level without the bounds.
+		return nil, true
+	}
+}
+
+// boundValue converts a bound to the value of the parameter.
+func (r *Rule) boundValue(bound float64) any {
+	if r.Parameter == ParameterReasoningEffort {
+		return ReasoningEffortLevels[int(bound)]
+	}
+	return bound
+}
@@ -0,0 +1,83 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package parameterpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRule(t *testing.T) {
+	for _, tc := range []struct {
+		name                  string
+		parameter             Parameter
+		action                Action
+		def, minimum, maximum string
+		expectedErr           string
+	}{
+		{name: "max tokens", parameter: ParameterMaxTokens, def: "1024", maximum: "4096"},
+		{name: "default action", parameter: ParameterTemperature, minimum: "0.2", maximum: "1.5"},
+		{name: "reasoning effort", parameter: ParameterReasoningEffort, def: "low", maximum: "medium"},
+		{name: "block", parameter: ParameterN, action: ActionReject},
+		{name: "remove", parameter: ParameterTopP, action: ActionRemove},
+		{name: "unknown action", parameter: ParameterN, action: "Drop", expectedErr: `unknown action "Drop"`},
+		{name: "unknown parameter", parameter: "Seed", maximum: "1", expectedErr: `invalid max "1": unknown parameter "Seed"`},
+		{name: "remove with bounds", parameter: ParameterTopP, action: ActionRemove, maximum: "1", expectedErr: "default, min and max cannot be set with the Remove action"},
+		{name: "clamp without bounds", parameter: ParameterTopP, expectedErr: "at least one of default, min or max must be set with the Clamp action"},
+		{name: "fractional tokens", parameter: ParameterMaxTokens, maximum: "10.5", expectedErr: `invalid max "10.5": must be a non-negative integer`},
+		{name: "negative temperature", parameter: ParameterTemperature, minimum: "-1", expectedErr: `invalid min "-1": must be a non-negative number`},
+		{name: "unknown level", parameter: ParameterReasoningEffort, def: "extreme", expectedErr: `invalid default "extreme": unknown reasoning effort`},
+		{name: "min above max", parameter: ParameterReasoningEffort, minimum: "high", maximum: "low", expectedErr: `min "high" is greater than max "low"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewRule("rule", tc.parameter, tc.action, tc.def, tc.minimum, tc.maximum)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, r)
+		})
+	}
+}
+
+func TestRule_Apply(t *testing.T) {
+	for _, tc := range []struct {
+		name                  string
+		parameter             Parameter
+		action                Action
+		def, minimum, maximum string
+		value                 any
+		expected              any
+		expectedOutcome       Outcome
+	}{
+		{name: "default", parameter: ParameterMaxTokens, def: "1024", maximum: "4096", expected: 1024.0, expectedOutcome: OutcomeDefault},
+		{name: "unset without default", parameter: ParameterMaxTokens, maximum: "4096"},
+		{name: "in range", parameter: ParameterMaxTokens, def: "1024", maximum: "4096", value: 2048.0, expected: 2048.0},
+		{name: "clamp max", parameter: ParameterMaxTokens, maximum: "4096", value: 100000.0, expected: 4096.0, expectedOutcome: OutcomeClamp},
+		{name: "clamp min", parameter: ParameterTemperature, minimum: "0.2", maximum: "1", value: 0.0, expected: 0.2, expectedOutcome: OutcomeClamp},
+		{name: "reject", parameter: ParameterN, action: ActionReject, maximum: "1", value: 4.0, expected: 4.0, expectedOutcome: OutcomeReject},
+		{name: "reject in range", parameter: ParameterN, action: ActionReject, maximum: "1", value: 1.0, expected: 1.0},
+		{name: "block", parameter: ParameterN, action: ActionReject, value: 1.0, expected: 1.0, expectedOutcome: OutcomeReject},
+		{name: "block unset", parameter: ParameterN, action: ActionReject},
+		{name: "remove", parameter: ParameterTopP, action: ActionRemove, value: 0.9, expectedOutcome: OutcomeRemove},
+		{name: "remove unset", parameter: ParameterTopP, action: ActionRemove},
+		{name: "clamp level", parameter: ParameterReasoningEffort, maximum: "medium", value: "high", expected: "medium", expectedOutcome: OutcomeClamp},
+		{name: "level in range", parameter: ParameterReasoningEffort, maximum: "medium", value: "low", expected: "low"},
+		{name: "clamp unknown level", parameter: ParameterReasoningEffort, minimum: "low", value: "ultra", expected: "low", expectedOutcome: OutcomeClamp},
+		{name: "reject unknown level", parameter: ParameterReasoningEffort, action: ActionReject, maximum: "high", value: "ultra", expected: "ultra", expectedOutcome: OutcomeReject},
+		{name: "unknown level without bounds", parameter: ParameterReasoningEffort, def: "low", value: "ultra", expected: "ultra"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewRule("rule", tc.parameter, tc.action, tc.def, tc.minimum, tc.maximum)
+			require.NoError(t, err)
+			out, outcome := r.Apply(tc.value)
+			require.Equal(t, tc.expected, out)
+			require.Equal(t, tc.expectedOutcome, outcome)
+		})
+	}
+}
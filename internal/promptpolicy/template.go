@@ -0,0 +1,48 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+// Package promptpolicy provides functions to create and render the templates of the messages added to the requests
+// by the prompt policies of the route rules and the backends.
+//
+// This exists as a separate package to be used both in the controller to validate the templates
+// and in the external processor to render them.
+package promptpolicy
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Data is the data with which the templates are rendered.
+type Data struct {
+	// Headers are the request headers by their lower-cased names.
+	Headers map[string]string
+	// Model is the model of the request sent by the client.
+	Model string
+}
+
+// Template is the compiled template of the content of a message.
+type Template struct {
+	t *template.Template
+}
+
+// NewTemplate parses the given Go text/template.
+func NewTemplate(text string) (*Template, error) {
+	t, err := template.New("message").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse template: %w", err)
+	}
+	return &Template{t: t}, nil
+}
+
+// Render renders the template with the given data.
+func (t *Template) Render(data *Data) (string, error) {
+	var b strings.Builder
+	if err := t.t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,46 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package promptpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplate_Render(t *testing.T) {
+	data := &Data{Headers: map[string]string{"x-tenant-id": "acme"}, Model: "gpt-4o-mini"}
+	for _, tc := range []struct {
+		name, text, exp string
+	}{
+		{name: "plain", text: "You are a helpful assistant.", exp: "You are a helpful assistant."},
+		{name: "header", text: `You are the assistant of {{ index .Headers "x-tenant-id" }}.`, exp: "You are the assistant of acme."},
+		{name: "missing header", text: `Region: {{ index .Headers "x-region" }}.`, exp: "Region: ."},
+		{
+			name: "condition",
+			text: `{{ if eq (index .Headers "x-tenant-id") "acme" }}Answer in English.{{ else }}Answer briefly.{{ end }}`,
+			exp:  "Answer in English.",
+		},
+		{name: "model", text: "You are running on {{ .Model }}.", exp: "You are running on gpt-4o-mini."},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := NewTemplate(tc.text)
+			require.NoError(t, err)
+			out, err := tmpl.Render(data)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, out)
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := NewTemplate("{{ .Headers")
+		require.ErrorContains(t, err, "cannot parse template")
+		tmpl, err := NewTemplate("{{ .Tenant }}")
+		require.NoError(t, err)
+		_, err = tmpl.Render(data)
+		require.ErrorContains(t, err, "failed to render template")
+	})
+}
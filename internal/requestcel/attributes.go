@@ -0,0 +1,107 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package requestcel
+
+import (
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+// charsPerToken is the average number of characters per token used to estimate the number of prompt tokens.
+// This is the commonly used approximation for English text with the BPE tokenizers of the major providers.
+const charsPerToken = 4
+
+// promptKeys are the top-level request body fields that contribute to the prompt across the supported APIs,
+// e.g., "messages" for the OpenAI Chat Completions and Anthropic Messages APIs, "input" and "instructions" for
+// the OpenAI Responses and Embeddings APIs, and "prompt" for the OpenAI Completions API.
+var promptKeys = []string{"messages", "input", "instructions", "prompt", "system", "tools"}
+
+// imagePartTypes are the content part types carrying an image across the supported APIs.
+var imagePartTypes = map[string]struct{}{
+	"image_url":   {}, // OpenAI Chat Completions.
+	"input_image": {}, // OpenAI Responses.
+	"image":       {}, // Anthropic Messages.
+}
+
+// Attributes are the attributes of a request that can be used in the CEL expressions.
+type Attributes struct {
+	// Model is the model name in the request body.
+	Model string
+	// Stream is true when the request is a streaming request.
+	Stream bool
+	// HasTools is true when the request declares any tools.
+	HasTools bool
+	// HasImages is true when any of the input messages contains an image.
+	HasImages bool
+	// EstimatedPromptTokens is the estimated number of prompt tokens. See EstimatePromptTokens.
+	EstimatedPromptTokens uint64
+	// Request is the request body as a JSON object. This is empty when the body is not a JSON object,
+	// e.g., for multipart requests.
+	Request map[string]any
+}
+
+// NewAttributes creates the Attributes of the request from its parsed model and stream flag, and its raw body.
+func NewAttributes(model string, stream bool, body []byte) *Attributes {
+	a := &Attributes{Model: model, Stream: stream}
+	if err := json.Unmarshal(body, &a.Request); err != nil || a.Request == nil {
+		a.Request = map[string]any{}
+	}
+	tools, _ := a.Request["tools"].([]any)
+	a.HasTools = len(tools) > 0
+	a.EstimatedPromptTokens, a.HasImages = estimate(a.Request)
+	return a
+}
+
+// EstimatePromptTokens returns a fast local estimate of the number of prompt tokens of the request body.
+// This counts the characters of all the text in the prompt fields including the tool definitions, and
+// divides it by the average number of characters per token. Images are not counted.
+//
+// This is meant for routing decisions where calling the tokenizer of the backend is too expensive, so
+// the result can be off by a few tens of percent depending on the language and the content.
+func EstimatePromptTokens(body []byte) uint64 {
+	var request map[string]any
+	if err := json.Unmarshal(body, &request); err != nil {
+		return 0
+	}
+	tokens, _ := estimate(request)
+	return tokens
+}
+
+// estimate returns the estimated number of prompt tokens, and whether the prompt contains any image.
+func estimate(request map[string]any) (tokens uint64, hasImages bool) {
+	var chars int
+	for _, key := range promptKeys {
+		if v, ok := request[key]; ok {
+			walk(v, &chars, &hasImages)
+		}
+	}
+	return uint64((chars + charsPerToken - 1) / charsPerToken), hasImages //nolint:gosec
+}
+
+// walk accumulates the number of characters of the strings in v, skipping the image parts.
+func walk(v any, chars *int, hasImages *bool) {
+	switch v := v.(type) {
+	case string:
+		*chars += len([]rune(v))
+	case []any:
+		for _, e := range v {
+			walk(e, chars, hasImages)
+		}
+	case map[string]any:
+		if typ, _ := v["type"].(string); typ != "" {
+			if _, ok := imagePartTypes[typ]; ok {
+				// Skip the image data which would otherwise dominate the estimate.
+				*hasImages = true
+				return
+			}
+		}
+		for k, e := range v {
+			if k == "type" {
+				continue
+			}
+			walk(e, chars, hasImages)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package requestcel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAttributes(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		body      string
+		expTools  bool
+		expImages bool
+		expTokens uint64
+	}{
+		{
+			name:      "chat completions",
+			body:      `{"model":"m","messages":[{"role":"system","content":"12345678"},{"role":"user","content":"1234"}]}`,
+			expTokens: 6, // "system", "12345678", "user", "1234" = 22 characters.
+		},
+		{
+			name:      "images are not counted",
+			body:      `{"model":"m","messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,` + strings.Repeat("A", 4000) + `"}}]}]}`,
+			expImages: true,
+			expTokens: 1,
+		},
+		{
+			name:      "anthropic messages",
+			body:      `{"model":"m","system":"1234","messages":[{"role":"user","content":[{"type":"image","source":{"type":"base64","data":"aGVsbG8="}}]}],"tools":[{"name":"f"}]}`,
+			expTools:  true,
+			expImages: true,
+			expTokens: 3, // "1234", "user", "f" = 9 characters.
+		},
+		{
+			name:      "responses",
+			body:      `{"model":"m","instructions":"1234","input":[{"role":"user","content":[{"type":"input_image","image_url":"https://example.com/a.png"}]}],"tools":[]}`,
+			expImages: true,
+			expTokens: 2,
+		},
+		{
+			name: "not JSON",
+			body: `--boundary`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			attrs := NewAttributes("m", false, []byte(tc.body))
+			require.Equal(t, tc.expTools, attrs.HasTools)
+			require.Equal(t, tc.expImages, attrs.HasImages)
+			require.Equal(t, tc.expTokens, attrs.EstimatedPromptTokens)
+			require.NotNil(t, attrs.Request)
+			require.Equal(t, tc.expTokens, EstimatePromptTokens([]byte(tc.body)))
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+// Package requestcel provides functions to create and evaluate CEL programs matching requests based on their body.
+//
+// This exists as a separate package to be used both in the controller to validate the expression
+// and in the external processor to evaluate the expression.
+package requestcel
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+const (
+	celModelNameKey             = "model"
+	celStreamKey                = "stream"
+	celHasToolsKey              = "has_tools"
+	celHasImagesKey             = "has_images"
+	celEstimatedPromptTokensKey = "estimated_prompt_tokens" // #nosec G101
+	celRequestKey               = "request"
+)
+
+var env *cel.Env
+
+func init() {
+	var err error
+	env, err = cel.NewEnv(
+		cel.Variable(celModelNameKey, cel.StringType),
+		cel.Variable(celStreamKey, cel.BoolType),
+		cel.Variable(celHasToolsKey, cel.BoolType),
+		cel.Variable(celHasImagesKey, cel.BoolType),
+		cel.Variable(celEstimatedPromptTokensKey, cel.UintType),
+		cel.Variable(celRequestKey, cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("cannot create CEL environment: %v", err))
+	}
+}
+
+// NewProgram creates a new CEL program from the given expression, which must evaluate to a boolean.
+func NewProgram(expr string) (prog cel.Program, err error) {
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		err = issues.Err()
+		return nil, fmt.Errorf("cannot compile CEL expression: %w", err)
+	}
+	if t := ast.OutputType(); t != cel.BoolType && t != cel.DynType {
+		return nil, fmt.Errorf("CEL expression must evaluate to a bool, got %v", t)
+	}
+	prog, err = env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create CEL program: %w", err)
+	}
+	return prog, nil
+}
+
+// EvaluateProgram evaluates the given CEL program with the given request attributes, and returns whether it matches.
+// Evaluation errors, e.g., accessing a field missing from the request, are returned as errors.
+func EvaluateProgram(prog cel.Program, attrs *Attributes) (bool, error) {
+	out, _, err := prog.Eval(map[string]any{
+		celModelNameKey:             attrs.Model,
+		celStreamKey:                attrs.Stream,
+		celHasToolsKey:              attrs.HasTools,
+		celHasImagesKey:             attrs.HasImages,
+		celEstimatedPromptTokensKey: attrs.EstimatedPromptTokens,
+		celRequestKey:               attrs.Request,
+	})
+	if err != nil || out == nil {
+		return false, fmt.Errorf("failed to evaluate CEL expression: %w", err)
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("CEL expression result is not a bool, got %v", out.Type())
+	}
+	return matched, nil
+}
@@ -0,0 +1,65 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package requestcel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProgram(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewProgram("has_tools &&")
+		require.ErrorContains(t, err, "cannot compile CEL expression")
+	})
+	t.Run("not a bool", func(t *testing.T) {
+		_, err := NewProgram("estimated_prompt_tokens + 1u")
+		require.ErrorContains(t, err, "CEL expression must evaluate to a bool")
+	})
+	t.Run("unknown variable", func(t *testing.T) {
+		_, err := NewProgram("input_tokens > 1u")
+		require.Error(t, err)
+	})
+	t.Run("dyn", func(t *testing.T) {
+		_, err := NewProgram("request.stream")
+		require.NoError(t, err)
+	})
+}
+
+func TestEvaluateProgram(t *testing.T) {
+	attrs := NewAttributes("gpt-4o", true, []byte(`{
+"model":"gpt-4o","stream":true,"reasoning_effort":"high",
+"messages":[{"role":"user","content":[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,aGVsbG8="}}]}],
+"tools":[{"type":"function","function":{"name":"f"}}]}`))
+
+	for _, tc := range []struct {
+		expr       string
+		expMatched bool
+		expErr     string
+	}{
+		{expr: "has_tools && has_images", expMatched: true},
+		{expr: "stream && model == 'gpt-4o'", expMatched: true},
+		{expr: "estimated_prompt_tokens > 32000u", expMatched: false},
+		{expr: "request.reasoning_effort == 'high'", expMatched: true},
+		{expr: "has(request.service_tier) && request.service_tier == 'flex'", expMatched: false},
+		{expr: "request.service_tier == 'flex'", expErr: "no such key: service_tier"},
+		{expr: "request.stream", expMatched: true},
+		{expr: "request.model", expErr: "CEL expression result is not a bool"},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			prog, err := NewProgram(tc.expr)
+			require.NoError(t, err)
+			matched, err := EvaluateProgram(prog, attrs)
+			if tc.expErr != "" {
+				require.ErrorContains(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expMatched, matched)
+		})
+	}
+}
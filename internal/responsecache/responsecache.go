@@ -0,0 +1,493 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+// Package responsecache implements the cache of the responses of the AIGatewayRoute rules with the response cache,
+// which serves the repeated identical requests without sending them to the backend.
+package responsecache
+
+import (
+	"cmp"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+// DefaultMaxEntries is the default maximum number of the entries of the in-memory store.
+const DefaultMaxEntries = 10000
+
+// Store stores the cached responses. Implementations must be safe for concurrent use.
+//
+// The built-in stores are the in-memory LRU store and the local disk store. A shared store, e.g., backed by Redis,
+// can be plugged in with RegisterStore by the users building their own external processor.
+type Store interface {
+	// Get returns the value stored under the key, or false if there is none or it has expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value under the key for the given TTL.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// StoreFactory creates a Store from its configuration, which is the value of the -responseCacheStoreConfig flag of
+// the external processor.
+type StoreFactory func(config string) (Store, error)
+
+var (
+	storeFactoriesMu sync.RWMutex
+	storeFactories   = map[string]StoreFactory{
+		"memory": func(config string) (Store, error) {
+			maxEntries := DefaultMaxEntries
+			if config != "" {
+				n, err := strconv.Atoi(config)
+				if err != nil || n <= 0 {
+					return nil, fmt.Errorf("invalid maximum number of entries %q", config)
+				}
+				maxEntries = n
+			}
+			return NewMemoryStore(maxEntries), nil
+		},
+		"disk": func(config string) (Store, error) {
+			dir, options, _ := strings.Cut(config, ",")
+			if dir == "" {
+				return nil, errors.New("the directory of the disk store must be set")
+			}
+			maxEntries, maxSize := DefaultMaxEntries, int64(DefaultMaxDiskSize)
+			for option := range strings.SplitSeq(options, ",") {
+				if option == "" {
+					continue
+				}
+				name, value, _ := strings.Cut(option, "=")
+				n, err := strconv.ParseInt(value, 10, 64)
+				if err != nil || n <= 0 {
+					return nil, fmt.Errorf("invalid option %q of the disk store", option)
+				}
+				switch name {
+				case "maxEntries":
+					maxEntries = int(n)
+				case "maxSize":
+					maxSize = n
+				default:
+					return nil, fmt.Errorf("unknown option %q of the disk store", option)
+				}
+			}
+			return NewDiskStore(dir, maxEntries, maxSize)
+		},
+	}
+)
+
+// RegisterStore registers the factory of the store with the given name, which can then be selected with the
+// -responseCacheStore flag of the external processor. This replaces the factory already registered with the name.
+func RegisterStore(name string, f StoreFactory) {
+	storeFactoriesMu.Lock()
+	defer storeFactoriesMu.Unlock()
+	storeFactories[name] = f
+}
+
+// NewStore creates the store registered with the given name from its configuration.
+func NewStore(name, config string) (Store, error) {
+	storeFactoriesMu.RLock()
+	f, ok := storeFactories[name]
+	storeFactoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown response cache store %q", name)
+	}
+	return f(config)
+}
+
+// Entry is a cached response.
+type Entry struct {
+	// ContentType is the content type of the response, e.g., "text/event-stream" for the streaming responses.
+	ContentType string `json:"contentType,omitempty"`
+	// Body is the response body in the API schema of the client.
+	Body []byte `json:"body"`
+	// Usage is the token usage of the response, from which the costs saved by the cache hits are calculated.
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// Usage is the token usage of a cached response.
+type Usage struct {
+	InputTokens              uint32 `json:"inputTokens,omitempty"`
+	CachedInputTokens        uint32 `json:"cachedInputTokens,omitempty"`
+	CacheCreationInputTokens uint32 `json:"cacheCreationInputTokens,omitempty"`
+	OutputTokens             uint32 `json:"outputTokens,omitempty"`
+	ReasoningTokens          uint32 `json:"reasoningTokens,omitempty"`
+	TotalTokens              uint32 `json:"totalTokens,omitempty"`
+}
+
+// Get returns the entry stored under the key in the store, or nil if there is none.
+func Get(ctx context.Context, s Store, key string) (*Entry, error) {
+	value, ok, err := s.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var e Entry
+	if err = json.Unmarshal(value, &e); err != nil {
+		return nil, fmt.Errorf("failed to decode the cached response: %w", err)
+	}
+	return &e, nil
+}
+
+// Set stores the entry under the key in the store for the given TTL.
+func Set(ctx context.Context, s Store, key string, e *Entry, ttl time.Duration) error {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode the response: %w", err)
+	}
+	return s.Set(ctx, key, value, ttl)
+}
+
+// Key returns the cache key of the request with the given body sent to the backend at the path. The body is
+// canonicalized, i.e., the keys of the JSON objects are sorted and the insignificant whitespace is removed, so that
+// the requests differing only in their formatting share the key.
+func Key(backend, path string, body []byte) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\n%s\n", backend, path)
+	if gjson.ValidBytes(body) {
+		_, _ = h.Write(canonicalize(nil, gjson.ParseBytes(body)))
+	} else {
+		_, _ = h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalize appends the canonical form of the JSON value to b.
+func canonicalize(b []byte, v gjson.Result) []byte {
+	switch {
+	case v.IsObject():
+		type field struct {
+			key   string
+			value gjson.Result
+		}
+		var fields []field
+		v.ForEach(func(k, v gjson.Result) bool {
+			fields = append(fields, field{key: k.String(), value: v})
+			return true
+		})
+		slices.SortStableFunc(fields, func(a, b field) int { return cmp.Compare(a.key, b.key) })
+		b = append(b, '{')
+		for i, f := range fields {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = strconv.AppendQuote(b, f.key)
+			b = append(b, ':')
+			b = canonicalize(b, f.value)
+		}
+		return append(b, '}')
+	case v.IsArray():
+		b = append(b, '[')
+		for i, e := range v.Array() {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = canonicalize(b, e)
+		}
+		return append(b, ']')
+	case v.Type == gjson.String:
+		return strconv.AppendQuote(b, v.String())
+	default:
+		return append(b, v.Raw...)
+	}
+}
+
+// memoryStore is the in-memory Store evicting the least recently used entries.
+type memoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+	now        func() time.Time
+}
+
+// memoryEntry is the element of the LRU list of memoryStore.
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates a new in-memory Store holding up to maxEntries entries, evicting the least recently used
+// ones.
+func NewMemoryStore(maxEntries int) Store {
+	return &memoryStore{maxEntries: maxEntries, entries: make(map[string]*list.Element), lru: list.New(), now: time.Now}
+}
+
+// Get implements [Store.Get].
+func (m *memoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*memoryEntry)
+	if !m.now().Before(e.expiresAt) {
+		m.lru.Remove(el)
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+	m.lru.MoveToFront(el)
+	return e.value, true, nil
+}
+
+// Set implements [Store.Set].
+func (m *memoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := &memoryEntry{key: key, value: value, expiresAt: m.now().Add(ttl)}
+	if el, ok := m.entries[key]; ok {
+		el.Value = e
+		m.lru.MoveToFront(el)
+		return nil
+	}
+	m.entries[key] = m.lru.PushFront(e)
+	for m.lru.Len() > m.maxEntries {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
+
+// diskStore is the Store keeping each entry in a file of a local directory, which survives the restarts of the
+// external processor. Each file starts with the expiration time of the entry in Unix nanoseconds, followed by the
+// value.
+//
+// The entries are indexed in memory to bound the number and the total size of the files, evicting the least recently
+// used ones, and the expired ones are removed periodically as well as when they are read.
+type diskStore struct {
+	dir        string
+	maxEntries int
+	maxSize    int64
+	now        func() time.Time
+
+	// mu guards the index of the entries, as well as the files so that the index matches them.
+	mu sync.Mutex
+	// entries are the elements of lru by the names of the files.
+	entries map[string]*list.Element
+	lru     *list.List
+	// size is the total size of the files.
+	size int64
+}
+
+// diskEntry is the element of the LRU list of diskStore.
+type diskEntry struct {
+	name      string
+	size      int64
+	expiresAt int64
+}
+
+const (
+	// DefaultMaxDiskSize is the default maximum total size in bytes of the entries of the disk store.
+	DefaultMaxDiskSize = 1 << 30
+	// diskSweepInterval is the interval at which the disk store removes the expired entries.
+	diskSweepInterval = time.Minute
+	// diskTempPrefix is the prefix of the temporary files of the entries being written.
+	diskTempPrefix = ".tmp-"
+)
+
+// NewDiskStore creates a new Store keeping up to maxEntries entries of up to maxSize bytes in total in the given
+// directory, which is created if it does not exist. The entries already in the directory are kept.
+func NewDiskStore(dir string, maxEntries int, maxSize int64) (Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create the directory of the disk store: %w", err)
+	}
+	d := &diskStore{
+		dir: dir, maxEntries: maxEntries, maxSize: maxSize, now: time.Now,
+		entries: make(map[string]*list.Element), lru: list.New(),
+	}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	// The store lives as long as the external processor, so the sweeper is never stopped.
+	go func() {
+		for range time.Tick(diskSweepInterval) {
+			d.sweep()
+		}
+	}()
+	return d, nil
+}
+
+// load indexes the entries already in the directory in the order of their modification times, removing the
+// temporary files left behind by a previous process.
+func (d *diskStore) load() error {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read the directory of the disk store: %w", err)
+	}
+	type loaded struct {
+		entry   *diskEntry
+		modTime time.Time
+	}
+	var entries []loaded
+	for _, f := range files {
+		path := filepath.Join(d.dir, f.Name())
+		if strings.HasPrefix(f.Name(), diskTempPrefix) {
+			_ = os.Remove(path)
+			continue
+		}
+		info, err := f.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		var header [8]byte
+		if err = readHeader(path, header[:]); err != nil {
+			_ = os.Remove(path)
+			continue
+		}
+		entries = append(entries, loaded{
+			entry:   &diskEntry{name: f.Name(), size: info.Size(), expiresAt: int64(binary.BigEndian.Uint64(header[:]))}, //nolint:gosec
+			modTime: info.ModTime(),
+		})
+	}
+	slices.SortFunc(entries, func(a, b loaded) int { return a.modTime.Compare(b.modTime) })
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, e := range entries {
+		d.entries[e.entry.name] = d.lru.PushFront(e.entry)
+		d.size += e.entry.size
+	}
+	d.evict()
+	return nil
+}
+
+// readHeader reads the header of the file at the path, i.e., the expiration time of the entry.
+func readHeader(path string, header []byte) error {
+	f, err := os.Open(path) //nolint:gosec // The path is in the directory of the store.
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	_, err = io.ReadFull(f, header)
+	return err
+}
+
+// name returns the name of the file of the key, which is hashed so that any key is a valid file name.
+func (d *diskStore) name(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get implements [Store.Get].
+func (d *diskStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	name := d.name(key)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	el, ok := d.entries[name]
+	if !ok {
+		return nil, false, nil
+	}
+	raw, err := os.ReadFile(filepath.Join(d.dir, name)) //nolint:gosec // The name is the hash of the key.
+	if errors.Is(err, fs.ErrNotExist) {
+		d.remove(el)
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("failed to read the cached response: %w", err)
+	}
+	if len(raw) < 8 || d.now().UnixNano() >= int64(binary.BigEndian.Uint64(raw)) { //nolint:gosec
+		d.remove(el)
+		return nil, false, nil
+	}
+	d.lru.MoveToFront(el)
+	return raw[8:], true, nil
+}
+
+// Set implements [Store.Set].
+func (d *diskStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	expiresAt := d.now().Add(ttl).UnixNano()
+	raw := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(value)), uint64(expiresAt)) //nolint:gosec
+	raw = append(raw, value...)
+	// The entry is written to a temporary file first so that the concurrent readers never see a partial entry.
+	f, err := os.CreateTemp(d.dir, diskTempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to create the cached response: %w", err)
+	}
+	_, err = f.Write(raw)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	name := d.name(key)
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(d.dir, name))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("failed to write the cached response: %w", err)
+	}
+	e := &diskEntry{name: name, size: int64(len(raw)), expiresAt: expiresAt}
+	if el, ok := d.entries[name]; ok {
+		d.size -= el.Value.(*diskEntry).size
+		el.Value = e
+		d.lru.MoveToFront(el)
+	} else {
+		d.entries[name] = d.lru.PushFront(e)
+	}
+	d.size += e.size
+	d.evict()
+	return nil
+}
+
+// sweep removes the expired entries.
+func (d *diskStore) sweep() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now().UnixNano()
+	for el := d.lru.Front(); el != nil; {
+		next := el.Next()
+		if now >= el.Value.(*diskEntry).expiresAt {
+			d.remove(el)
+		}
+		el = next
+	}
+}
+
+// evict removes the least recently used entries until the store is within its limits. This must be called with mu
+// held.
+func (d *diskStore) evict() {
+	for d.lru.Len() > 0 && (d.lru.Len() > d.maxEntries || d.size > d.maxSize) {
+		d.remove(d.lru.Back())
+	}
+}
+
+// remove removes the entry and its file. This must be called with mu held.
+func (d *diskStore) remove(el *list.Element) {
+	e := d.lru.Remove(el).(*diskEntry)
+	delete(d.entries, e.name)
+	d.size -= e.size
+	_ = os.Remove(filepath.Join(d.dir, e.name))
+}
+
+// ParseCacheControl returns the directives of the Cache-Control request header relevant to the cache: no-cache
+// requires the response not to be served from the cache, and no-store requires it not to be stored either.
+func ParseCacheControl(cacheControl string) (noCache, noStore bool) {
+	for directive := range strings.SplitSeq(cacheControl, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "no-cache":
+			noCache = true
+		case "no-store":
+			noCache, noStore = true, true
+		}
+	}
+	return
+}
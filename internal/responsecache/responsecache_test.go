@@ -0,0 +1,270 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package responsecache
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey(t *testing.T) {
+	key := Key("backend", "/v1/chat/completions", []byte(`{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"hi"}]}`))
+	require.Len(t, key, 64)
+	// The formatting and the order of the keys do not matter.
+	require.Equal(t, key, Key("backend", "/v1/chat/completions",
+		[]byte("{\n  \"messages\": [{\"content\": \"hi\", \"role\": \"user\"}],\n  \"temperature\": 0, \"model\": \"gpt-4o\"\n}")))
+	// The order of the arrays, the values, the backend and the path do.
+	require.NotEqual(t, key, Key("backend", "/v1/chat/completions", []byte(`{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"hello"}]}`)))
+	require.NotEqual(t, key, Key("other", "/v1/chat/completions", []byte(`{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"hi"}]}`)))
+	require.NotEqual(t, key, Key("backend", "/chat/completions", []byte(`{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"hi"}]}`)))
+	// The body which is not JSON is hashed as is.
+	require.Equal(t, Key("backend", "/", []byte("not json")), Key("backend", "/", []byte("not json")))
+	require.NotEqual(t, Key("backend", "/", []byte("not json")), Key("backend", "/", []byte("not  json")))
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := t.Context()
+	now := time.Unix(0, 0)
+	s := NewMemoryStore(2).(*memoryStore)
+	s.now = func() time.Time { return now }
+
+	require.NoError(t, s.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, s.Set(ctx, "b", []byte("2"), time.Hour))
+	requireStored(ctx, t, s, "a", "1")
+	// "b" is the least recently used one, so it is evicted.
+	require.NoError(t, s.Set(ctx, "c", []byte("3"), time.Hour))
+	requireStored(ctx, t, s, "b", "")
+	requireStored(ctx, t, s, "c", "3")
+
+	now = now.Add(time.Minute)
+	requireStored(ctx, t, s, "a", "")
+	require.Equal(t, 1, s.lru.Len())
+}
+
+func TestDiskStore(t *testing.T) {
+	ctx := t.Context()
+	now := time.Unix(0, 0)
+	st, err := NewDiskStore(t.TempDir()+"/cache", 10, DefaultMaxDiskSize)
+	require.NoError(t, err)
+	s := st.(*diskStore)
+	s.now = func() time.Time { return now }
+
+	requireStored(ctx, t, s, "a", "")
+	require.NoError(t, s.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, s.Set(ctx, "a/../b", []byte("2"), time.Hour))
+	requireStored(ctx, t, s, "a", "1")
+	requireStored(ctx, t, s, "a/../b", "2")
+
+	now = now.Add(time.Minute)
+	requireStored(ctx, t, s, "a", "")
+	files, err := os.ReadDir(s.dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, int64(9), s.size)
+}
+
+func TestDiskStore_evict(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	st, err := NewDiskStore(dir, 2, 30)
+	require.NoError(t, err)
+	s := st.(*diskStore)
+
+	require.NoError(t, s.Set(ctx, "a", []byte("1"), time.Hour))
+	require.NoError(t, s.Set(ctx, "b", []byte("2"), time.Hour))
+	requireStored(ctx, t, s, "a", "1")
+	// "b" is the least recently used one, so it is evicted over the maximum number of entries.
+	require.NoError(t, s.Set(ctx, "c", []byte("3"), time.Hour))
+	requireStored(ctx, t, s, "b", "")
+	requireStored(ctx, t, s, "a", "1")
+	requireStored(ctx, t, s, "c", "3")
+
+	// "a" is evicted over the maximum size, i.e., 9 bytes for "c" and 21 bytes for "d".
+	require.NoError(t, s.Set(ctx, "d", []byte("0123456789abc"), time.Hour))
+	requireStored(ctx, t, s, "a", "")
+	requireStored(ctx, t, s, "d", "0123456789abc")
+	require.Equal(t, int64(30), s.size)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	// The entries survive the restart, and the stale temporary files are removed.
+	require.NoError(t, os.WriteFile(dir+"/"+diskTempPrefix+"1", []byte("partial"), 0o600))
+	st, err = NewDiskStore(dir, 1, DefaultMaxDiskSize)
+	require.NoError(t, err)
+	s = st.(*diskStore)
+	require.Equal(t, 1, s.lru.Len())
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
+func TestDiskStore_sweep(t *testing.T) {
+	ctx := t.Context()
+	now := time.Unix(0, 0)
+	dir := t.TempDir()
+	st, err := NewDiskStore(dir, 10, DefaultMaxDiskSize)
+	require.NoError(t, err)
+	s := st.(*diskStore)
+	s.now = func() time.Time { return now }
+
+	require.NoError(t, s.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, s.Set(ctx, "b", []byte("2"), time.Hour))
+	now = now.Add(time.Minute)
+	s.sweep()
+	// The expired entry is removed without being read.
+	require.Equal(t, 1, s.lru.Len())
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	requireStored(ctx, t, s, "b", "2")
+}
+
+func TestNewStore(t *testing.T) {
+	s, err := NewStore("memory", "")
+	require.NoError(t, err)
+	require.Equal(t, DefaultMaxEntries, s.(*memoryStore).maxEntries)
+	s, err = NewStore("memory", "5")
+	require.NoError(t, err)
+	require.Equal(t, 5, s.(*memoryStore).maxEntries)
+	_, err = NewStore("memory", "0")
+	require.ErrorContains(t, err, `invalid maximum number of entries "0"`)
+
+	dir := t.TempDir()
+	s, err = NewStore("disk", dir)
+	require.NoError(t, err)
+	require.Equal(t, dir, s.(*diskStore).dir)
+	require.Equal(t, DefaultMaxEntries, s.(*diskStore).maxEntries)
+	require.Equal(t, int64(DefaultMaxDiskSize), s.(*diskStore).maxSize)
+	s, err = NewStore("disk", dir+",maxEntries=5,maxSize=1024")
+	require.NoError(t, err)
+	require.Equal(t, 5, s.(*diskStore).maxEntries)
+	require.Equal(t, int64(1024), s.(*diskStore).maxSize)
+	_, err = NewStore("disk", "")
+	require.ErrorContains(t, err, "the directory of the disk store must be set")
+	_, err = NewStore("disk", dir+",maxSize=0")
+	require.ErrorContains(t, err, `invalid option "maxSize=0" of the disk store`)
+	_, err = NewStore("disk", dir+",ttl=1")
+	require.ErrorContains(t, err, `unknown option "ttl=1" of the disk store`)
+
+	_, err = NewStore("shared", "")
+	require.ErrorContains(t, err, `unknown response cache store "shared"`)
+	shared := NewMemoryStore(1)
+	RegisterStore("shared", func(string) (Store, error) { return shared, nil })
+	s, err = NewStore("shared", "")
+	require.NoError(t, err)
+	require.Same(t, shared, s)
+}
+
+func TestGetSet(t *testing.T) {
+	ctx := t.Context()
+	s := NewMemoryStore(1)
+	e, err := Get(ctx, s, "key")
+	require.NoError(t, err)
+	require.Nil(t, e)
+
+	require.NoError(t, Set(ctx, s, "key", &Entry{ContentType: "text/event-stream", Body: []byte("data: {}\n\n")}, time.Hour))
+	e, err = Get(ctx, s, "key")
+	require.NoError(t, err)
+	require.Equal(t, &Entry{ContentType: "text/event-stream", Body: []byte("data: {}\n\n")}, e)
+
+	require.NoError(t, s.Set(ctx, "key", []byte("not json"), time.Hour))
+	_, err = Get(ctx, s, "key")
+	require.ErrorContains(t, err, "failed to decode the cached response")
+}
+
+func TestParseCacheControl(t *testing.T) {
+	for _, tc := range []struct {
+		header           string
+		noCache, noStore bool
+	}{
+		{header: ""},
+		{header: "max-age=0"},
+		{header: "no-cache", noCache: true},
+		{header: "max-age=0, No-Cache", noCache: true},
+		{header: "no-store", noCache: true, noStore: true},
+	} {
+		t.Run(tc.header, func(t *testing.T) {
+			noCache, noStore := ParseCacheControl(tc.header)
+			require.Equal(t, tc.noCache, noCache)
+			require.Equal(t, tc.noStore, noStore)
+		})
+	}
+}
+
+func requireStored(ctx context.Context, t *testing.T, s Store, key, exp string) {
+	t.Helper()
+	value, ok, err := s.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, exp != "", ok)
+	require.Equal(t, exp, string(value))
+}
+
+func TestMemoryVectorStore(t *testing.T) {
+	ctx := t.Context()
+	now := time.Unix(0, 0)
+	s := NewMemoryVectorStore(3).(*memoryVectorStore)
+	s.now = func() time.Time { return now }
+
+	_, _, ok, err := s.Search(ctx, "scope", []float32{1, 0})
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, s.Add(ctx, "scope", []float32{1, 0}, "a", time.Minute))
+	require.NoError(t, s.Add(ctx, "scope", []float32{0, 1}, "b", time.Hour))
+	require.NoError(t, s.Add(ctx, "other", []float32{1, 1}, "c", time.Hour))
+	key, similarity, ok, err := s.Search(ctx, "scope", []float32{1, 0.1})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "a", key)
+	require.InDelta(t, 0.995, similarity, 0.001)
+
+	// The entry re-added under the same key replaces the old one.
+	require.NoError(t, s.Add(ctx, "scope", []float32{1, 0}, "b", time.Hour))
+	require.Len(t, s.scopes["scope"], 2)
+
+	// The expired entries are not returned.
+	now = now.Add(time.Minute)
+	key, _, ok, err = s.Search(ctx, "scope", []float32{0, 1})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "b", key)
+	require.Len(t, s.scopes["scope"], 1)
+
+	// The oldest entry is evicted.
+	require.NoError(t, s.Add(ctx, "scope", []float32{0, 1}, "d", time.Hour))
+	require.NoError(t, s.Add(ctx, "scope", []float32{0, 1}, "e", time.Hour))
+	require.Equal(t, 3, s.order.Len())
+	require.NotContains(t, s.scopes, "other")
+}
+
+func TestNewVectorStore(t *testing.T) {
+	s, err := NewVectorStore("memory", "")
+	require.NoError(t, err)
+	require.Equal(t, DefaultMaxEntries, s.(*memoryVectorStore).maxEntries)
+	_, err = NewVectorStore("memory", "-1")
+	require.ErrorContains(t, err, `invalid maximum number of entries "-1"`)
+
+	_, err = NewVectorStore("vectordb", "")
+	require.ErrorContains(t, err, `unknown response cache vector store "vectordb"`)
+	shared := NewMemoryVectorStore(1)
+	RegisterVectorStore("vectordb", func(string) (VectorStore, error) { return shared, nil })
+	s, err = NewVectorStore("vectordb", "")
+	require.NoError(t, err)
+	require.Same(t, shared, s)
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	require.InDelta(t, 1, CosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	require.InDelta(t, 0, CosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	require.InDelta(t, -1, CosineSimilarity([]float32{1, 0}, []float32{-1, 0}), 1e-9)
+	require.Zero(t, CosineSimilarity([]float32{1, 0}, []float32{1, 0, 0}))
+	require.Zero(t, CosineSimilarity([]float32{0, 0}, []float32{1, 0}))
+}
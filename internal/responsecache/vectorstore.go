@@ -0,0 +1,164 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package responsecache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// VectorStore stores the embeddings of the cached requests for the semantic mode of the cache, in which a request is
+// served the cached response of the most similar request. Implementations must be safe for concurrent use.
+//
+// The embeddings are partitioned into scopes, e.g., the backend and the request except the embedded message, and
+// only the embeddings of the same scope are compared. The built-in store is the in-memory store comparing all the
+// embeddings of the scope. A vector database can be plugged in with RegisterVectorStore by the users building their
+// own external processor.
+type VectorStore interface {
+	// Search returns the key of the entry of the scope whose embedding is the most similar to the given one, together
+	// with their cosine similarity, or false if the scope has no entry.
+	Search(ctx context.Context, scope string, embedding []float32) (key string, similarity float64, ok bool, err error)
+	// Add adds the embedding of the entry stored under the key to the scope for the given TTL.
+	Add(ctx context.Context, scope string, embedding []float32, key string, ttl time.Duration) error
+}
+
+// VectorStoreFactory creates a VectorStore from its configuration, which is the value of the
+// -responseCacheVectorStoreConfig flag of the external processor.
+type VectorStoreFactory func(config string) (VectorStore, error)
+
+var (
+	vectorStoreFactoriesMu sync.RWMutex
+	vectorStoreFactories   = map[string]VectorStoreFactory{
+		"memory": func(config string) (VectorStore, error) {
+			maxEntries := DefaultMaxEntries
+			if config != "" {
+				n, err := strconv.Atoi(config)
+				if err != nil || n <= 0 {
+					return nil, fmt.Errorf("invalid maximum number of entries %q", config)
+				}
+				maxEntries = n
+			}
+			return NewMemoryVectorStore(maxEntries), nil
+		},
+	}
+)
+
+// RegisterVectorStore registers the factory of the vector store with the given name, which can then be selected with
+// the -responseCacheVectorStore flag of the external processor. This replaces the factory already registered with
+// the name.
+func RegisterVectorStore(name string, f VectorStoreFactory) {
+	vectorStoreFactoriesMu.Lock()
+	defer vectorStoreFactoriesMu.Unlock()
+	vectorStoreFactories[name] = f
+}
+
+// NewVectorStore creates the vector store registered with the given name from its configuration.
+func NewVectorStore(name, config string) (VectorStore, error) {
+	vectorStoreFactoriesMu.RLock()
+	f, ok := vectorStoreFactories[name]
+	vectorStoreFactoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown response cache vector store %q", name)
+	}
+	return f(config)
+}
+
+// memoryVectorStore is the in-memory VectorStore comparing the embedding with all the embeddings of the scope,
+// evicting the oldest entries.
+type memoryVectorStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	scopes     map[string][]*vectorEntry
+	// order is the list of the entries from the newest to the oldest.
+	order *list.List
+	now   func() time.Time
+}
+
+// vectorEntry is an entry of memoryVectorStore.
+type vectorEntry struct {
+	scope, key string
+	embedding  []float32
+	expiresAt  time.Time
+	element    *list.Element
+}
+
+// NewMemoryVectorStore creates a new in-memory VectorStore holding up to maxEntries entries, evicting the oldest
+// ones.
+func NewMemoryVectorStore(maxEntries int) VectorStore {
+	return &memoryVectorStore{maxEntries: maxEntries, scopes: make(map[string][]*vectorEntry), order: list.New(), now: time.Now}
+}
+
+// Search implements [VectorStore.Search].
+func (m *memoryVectorStore) Search(_ context.Context, scope string, embedding []float32) (key string, similarity float64, ok bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for _, e := range m.scopes[scope] {
+		if !now.Before(e.expiresAt) {
+			continue
+		}
+		if s := CosineSimilarity(embedding, e.embedding); !ok || s > similarity {
+			key, similarity, ok = e.key, s, true
+		}
+	}
+	m.removeLocked(scope, func(e *vectorEntry) bool { return !now.Before(e.expiresAt) })
+	return
+}
+
+// Add implements [VectorStore.Add].
+func (m *memoryVectorStore) Add(_ context.Context, scope string, embedding []float32, key string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeLocked(scope, func(e *vectorEntry) bool { return e.key == key })
+	e := &vectorEntry{scope: scope, key: key, embedding: embedding, expiresAt: m.now().Add(ttl)}
+	e.element = m.order.PushFront(e)
+	m.scopes[scope] = append(m.scopes[scope], e)
+	for m.order.Len() > m.maxEntries {
+		oldest := m.order.Back().Value.(*vectorEntry)
+		m.removeLocked(oldest.scope, func(e *vectorEntry) bool { return e == oldest })
+	}
+	return nil
+}
+
+// removeLocked removes the entries of the scope matching the predicate. This must be called with the lock held.
+func (m *memoryVectorStore) removeLocked(scope string, remove func(*vectorEntry) bool) {
+	entries := slices.DeleteFunc(m.scopes[scope], func(e *vectorEntry) bool {
+		if remove(e) {
+			m.order.Remove(e.element)
+			return true
+		}
+		return false
+	})
+	if len(entries) == 0 {
+		delete(m.scopes, scope)
+	} else {
+		m.scopes[scope] = entries
+	}
+}
+
+// CosineSimilarity returns the cosine similarity of the two embeddings, from -1 to 1, or zero if they have different
+// dimensions or either of them is zero.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
@@ -16,6 +16,7 @@ func ClearTestEnv(t testing.TB) {
 		"AZURE_OPENAI_API_KEY",
 		"ANTHROPIC_API_KEY",
 		"ANTHROPIC_BASE_URL",
+		"OLLAMA_HOST",
 		"OTEL_EXPORTER_OTLP_ENDPOINT",
 		"OTEL_EXPORTER_OTLP_PROTOCOL",
 		"OTEL_EXPORTER_OTLP_HEADERS",
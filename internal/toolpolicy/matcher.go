@@ -0,0 +1,81 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+// Package toolpolicy provides the matcher of the names of the tools allowed by the tool policies of the route rules.
+//
+// This exists as a separate package to be used both in the controller to validate the regular expressions
+// and in the external processor to match the tools of the requests and the responses.
+package toolpolicy
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Matcher decides whether a tool is allowed by its name. The deny rules take precedence over the allow rules, and
+// all the tools not denied are allowed if there are no allow rules.
+type Matcher struct {
+	allow        map[string]struct{}
+	allowRegexps []*regexp.Regexp
+	deny         map[string]struct{}
+	denyRegexps  []*regexp.Regexp
+}
+
+// NewMatcher creates a new Matcher from the names and the RE2 regular expressions of the allowed and the denied tools.
+func NewMatcher(allow, allowRegex, deny, denyRegex []string) (*Matcher, error) {
+	m := &Matcher{allow: toSet(allow), deny: toSet(deny)}
+	var err error
+	if m.allowRegexps, err = compileRegexps(allowRegex); err != nil {
+		return nil, err
+	}
+	if m.denyRegexps, err = compileRegexps(denyRegex); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Allows returns true if the tool of the given name is allowed.
+func (m *Matcher) Allows(name string) bool {
+	if _, ok := m.deny[name]; ok {
+		return false
+	}
+	for _, re := range m.denyRegexps {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(m.allow) == 0 && len(m.allowRegexps) == 0 {
+		return true
+	}
+	if _, ok := m.allow[name]; ok {
+		return true
+	}
+	for _, re := range m.allowRegexps {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func toSet(names []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		set[n] = struct{}{}
+	}
+	return set
+}
+
+func compileRegexps(exprs []string) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", expr, err)
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps, nil
+}
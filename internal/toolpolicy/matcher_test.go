@@ -0,0 +1,61 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package toolpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher_Allows(t *testing.T) {
+	for _, tc := range []struct {
+		name                               string
+		allow, allowRegex, deny, denyRegex []string
+		allowed, disallowed                []string
+	}{
+		{
+			name:       "deny only",
+			deny:       []string{"delete_user"},
+			denyRegex:  []string{"(?i)^drop_"},
+			allowed:    []string{"get_weather", "delete_users"},
+			disallowed: []string{"delete_user", "DROP_table"},
+		},
+		{
+			name:       "allow only",
+			allow:      []string{"get_weather"},
+			allowRegex: []string{"^crm_"},
+			allowed:    []string{"get_weather", "crm_lookup"},
+			disallowed: []string{"get_weather2", "google_search", "lookup_crm_"},
+		},
+		{
+			name:       "deny wins",
+			allowRegex: []string{"^crm_"},
+			deny:       []string{"crm_delete"},
+			allowed:    []string{"crm_lookup"},
+			disallowed: []string{"crm_delete"},
+		},
+		{name: "empty", allowed: []string{"anything"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewMatcher(tc.allow, tc.allowRegex, tc.deny, tc.denyRegex)
+			require.NoError(t, err)
+			for _, name := range tc.allowed {
+				require.True(t, m.Allows(name), name)
+			}
+			for _, name := range tc.disallowed {
+				require.False(t, m.Allows(name), name)
+			}
+		})
+	}
+}
+
+func TestNewMatcher_InvalidRegex(t *testing.T) {
+	_, err := NewMatcher(nil, []string{"("}, nil, nil)
+	require.ErrorContains(t, err, `invalid regex "("`)
+	_, err = NewMatcher(nil, nil, nil, []string{"[a-"})
+	require.ErrorContains(t, err, `invalid regex "[a-"`)
+}
@@ -6,6 +6,10 @@
 package tracing
 
 import (
+	"maps"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 
 	anthropicschema "github.com/envoyproxy/ai-gateway/internal/apischema/anthropic"
@@ -39,6 +43,70 @@ func (s *span[RespT, ChunkT]) EndSpan() {
 	s.span.End()
 }
 
+// RecordErrorType implements [tracingapi.ErrorTypeRecorder.RecordErrorType]
+func (s *span[RespT, ChunkT]) RecordErrorType(errorType string) {
+	s.span.SetAttributes(attribute.String("error.type", errorType))
+}
+
+// RecordHedgedRequest implements [tracingapi.HedgeRecorder.RecordHedgedRequest]
+func (s *span[RespT, ChunkT]) RecordHedgedRequest(backend string, attempt int) {
+	s.span.AddEvent("hedged request", trace.WithAttributes(
+		attribute.String("hedge.backend.name", backend),
+		attribute.Int("hedge.attempt", attempt),
+	))
+}
+
+// RecordHedgeWinner implements [tracingapi.HedgeRecorder.RecordHedgeWinner]
+func (s *span[RespT, ChunkT]) RecordHedgeWinner(backend string, attempt int) {
+	s.span.AddEvent("hedge winner", trace.WithAttributes(
+		attribute.String("hedge.backend.name", backend),
+		attribute.Int("hedge.attempt", attempt),
+	))
+}
+
+// RecordPIIDetections implements [tracingapi.GuardrailRecorder.RecordPIIDetections]
+func (s *span[RespT, ChunkT]) RecordPIIDetections(action string, detections map[string]int) {
+	total := 0
+	for _, n := range detections {
+		total += n
+	}
+	s.span.SetAttributes(
+		attribute.String("guardrail.pii.action", action),
+		attribute.StringSlice("guardrail.pii.entities", slices.Sorted(maps.Keys(detections))),
+		attribute.Int("guardrail.pii.detections", total),
+	)
+}
+
+// RecordExternalGuardrailCheck implements [tracingapi.GuardrailRecorder.RecordExternalGuardrailCheck]
+func (s *span[RespT, ChunkT]) RecordExternalGuardrailCheck(phase, decision, reason string) {
+	attrs := []attribute.KeyValue{
+		attribute.String("guardrail.external.phase", phase),
+		attribute.String("guardrail.external.decision", decision),
+	}
+	if reason != "" {
+		attrs = append(attrs, attribute.String("guardrail.external.reason", reason))
+	}
+	s.span.AddEvent("external guardrail check", trace.WithAttributes(attrs...))
+}
+
+// RecordPromptInjectionDetection implements [tracingapi.GuardrailRecorder.RecordPromptInjectionDetection]
+func (s *span[RespT, ChunkT]) RecordPromptInjectionDetection(action, source string, score int, rules []string) {
+	s.span.SetAttributes(
+		attribute.String("guardrail.prompt_injection.action", action),
+		attribute.String("guardrail.prompt_injection.source", source),
+		attribute.Int("guardrail.prompt_injection.score", score),
+		attribute.StringSlice("guardrail.prompt_injection.rules", rules),
+	)
+}
+
+// RecordOutputGuardrailMatch implements [tracingapi.GuardrailRecorder.RecordOutputGuardrailMatch]
+func (s *span[RespT, ChunkT]) RecordOutputGuardrailMatch(action, rule string) {
+	s.span.AddEvent("output guardrail match", trace.WithAttributes(
+		attribute.String("guardrail.output.action", action),
+		attribute.String("guardrail.output.rule", rule),
+	))
+}
+
 // EndSpanOnError implements [tracingapi.Span.EndSpanOnError]
 func (s *span[RespT, ChunkT]) EndSpanOnError(statusCode int, body []byte) {
 	s.recorder.RecordResponseOnError(s.span, statusCode, body)
@@ -61,6 +61,112 @@ func TestChatCompletionSpan_EndSpanOnError(t *testing.T) {
 	}, actualSpan.Attributes)
 }
 
+func TestChatCompletionSpan_RecordErrorType(t *testing.T) {
+	actualSpan := testotel.RecordWithSpan(t, func(span oteltrace.Span) bool {
+		s := &chatCompletionSpan{span: span, recorder: testChatCompletionRecorder{}}
+		s.RecordErrorType("rate_limit")
+		s.EndSpanOnError(429, []byte("slow down"))
+		return true
+	})
+
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("error.type", "rate_limit"),
+		attribute.Int("statusCode", 429),
+		attribute.String("errorBody", "slow down"),
+	}, actualSpan.Attributes)
+}
+
+func TestChatCompletionSpan_RecordHedge(t *testing.T) {
+	actualSpan := testotel.RecordWithSpan(t, func(span oteltrace.Span) bool {
+		s := &chatCompletionSpan{span: span, recorder: testChatCompletionRecorder{}}
+		s.RecordHedgedRequest("backend-b", 2)
+		s.RecordHedgeWinner("backend-a", 1)
+		s.EndSpan()
+		return true
+	})
+
+	require.Len(t, actualSpan.Events, 2)
+	require.Equal(t, "hedged request", actualSpan.Events[0].Name)
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("hedge.backend.name", "backend-b"),
+		attribute.Int("hedge.attempt", 2),
+	}, actualSpan.Events[0].Attributes)
+	require.Equal(t, "hedge winner", actualSpan.Events[1].Name)
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("hedge.backend.name", "backend-a"),
+		attribute.Int("hedge.attempt", 1),
+	}, actualSpan.Events[1].Attributes)
+}
+
+func TestChatCompletionSpan_RecordPIIDetections(t *testing.T) {
+	actualSpan := testotel.RecordWithSpan(t, func(span oteltrace.Span) bool {
+		s := &chatCompletionSpan{span: span, recorder: testChatCompletionRecorder{}}
+		s.RecordPIIDetections("mask", map[string]int{"phone_number": 1, "email": 2})
+		s.EndSpan()
+		return true
+	})
+
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("guardrail.pii.action", "mask"),
+		attribute.StringSlice("guardrail.pii.entities", []string{"email", "phone_number"}),
+		attribute.Int("guardrail.pii.detections", 3),
+	}, actualSpan.Attributes)
+}
+
+func TestChatCompletionSpan_RecordExternalGuardrailCheck(t *testing.T) {
+	actualSpan := testotel.RecordWithSpan(t, func(span oteltrace.Span) bool {
+		s := &chatCompletionSpan{span: span, recorder: testChatCompletionRecorder{}}
+		s.RecordExternalGuardrailCheck("request", "allow", "")
+		s.RecordExternalGuardrailCheck("response", "deny", "self-harm")
+		s.EndSpan()
+		return true
+	})
+
+	require.Len(t, actualSpan.Events, 2)
+	require.Equal(t, "external guardrail check", actualSpan.Events[0].Name)
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("guardrail.external.phase", "request"),
+		attribute.String("guardrail.external.decision", "allow"),
+	}, actualSpan.Events[0].Attributes)
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("guardrail.external.phase", "response"),
+		attribute.String("guardrail.external.decision", "deny"),
+		attribute.String("guardrail.external.reason", "self-harm"),
+	}, actualSpan.Events[1].Attributes)
+}
+
+func TestChatCompletionSpan_RecordPromptInjectionDetection(t *testing.T) {
+	actualSpan := testotel.RecordWithSpan(t, func(span oteltrace.Span) bool {
+		s := &chatCompletionSpan{span: span, recorder: testChatCompletionRecorder{}}
+		s.RecordPromptInjectionDetection("block", "tool", 90, []string{"IgnoreInstructions", "SystemPromptExtraction"})
+		s.EndSpan()
+		return true
+	})
+
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("guardrail.prompt_injection.action", "block"),
+		attribute.String("guardrail.prompt_injection.source", "tool"),
+		attribute.Int("guardrail.prompt_injection.score", 90),
+		attribute.StringSlice("guardrail.prompt_injection.rules", []string{"IgnoreInstructions", "SystemPromptExtraction"}),
+	}, actualSpan.Attributes)
+}
+
+func TestChatCompletionSpan_RecordOutputGuardrailMatch(t *testing.T) {
+	actualSpan := testotel.RecordWithSpan(t, func(span oteltrace.Span) bool {
+		s := &chatCompletionSpan{span: span, recorder: testChatCompletionRecorder{}}
+		s.RecordOutputGuardrailMatch("block", "codename")
+		s.EndSpan()
+		return true
+	})
+
+	require.Len(t, actualSpan.Events, 1)
+	require.Equal(t, "output guardrail match", actualSpan.Events[0].Name)
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("guardrail.output.action", "block"),
+		attribute.String("guardrail.output.rule", "codename"),
+	}, actualSpan.Events[0].Attributes)
+}
+
 func TestChatCompletionSpan_EndSpan(t *testing.T) {
 	s := &chatCompletionSpan{recorder: testChatCompletionRecorder{}, chunks: []*openai.ChatCompletionResponseChunk{{}, {}}}
 	actualSpan := testotel.RecordWithSpan(t, func(span oteltrace.Span) bool {
@@ -105,6 +105,37 @@ type (
 		// EndSpan finalizes and ends the span.
 		EndSpan()
 	}
+	// ErrorTypeRecorder is optionally implemented by a Span to record the class of the error response, e.g.
+	// "rate_limit", as the "error.type" attribute. This is called before EndSpanOnError.
+	ErrorTypeRecorder interface {
+		// RecordErrorType records the class of the error response to the span.
+		RecordErrorType(errorType string)
+	}
+	// HedgeRecorder is optionally implemented by a Span to record the upstream attempts of a hedged request as the
+	// span events.
+	HedgeRecorder interface {
+		// RecordHedgedRequest records that a copy of the request was sent to the backend while the earlier attempts
+		// had not started responding. attempt is the 1-based index of the upstream attempt.
+		RecordHedgedRequest(backend string, attempt int)
+		// RecordHedgeWinner records the upstream attempt whose response is returned to the client.
+		RecordHedgeWinner(backend string, attempt int)
+	}
+	// GuardrailRecorder is optionally implemented by a Span to record the detections of the guardrails of the route
+	// rule as the span attributes.
+	GuardrailRecorder interface {
+		// RecordPIIDetections records the number of the detections of each type of the personally identifiable
+		// information in the request, and the action taken on them, e.g., "mask".
+		RecordPIIDetections(action string, detections map[string]int)
+		// RecordExternalGuardrailCheck records a check of the request or the response by the external guardrail
+		// service, e.g., "request", and its decision, e.g., "deny", with the reason given by the service if any.
+		RecordExternalGuardrailCheck(phase, decision, reason string)
+		// RecordPromptInjectionDetection records the prompt injection detected in the request, the action taken on
+		// the request, e.g., "block", the source of the text, e.g., "tool", its score and the rules matching it.
+		RecordPromptInjectionDetection(action, source string, score int, rules []string)
+		// RecordOutputGuardrailMatch records the match of the text output of the response by the rule of the output
+		// guardrail, and the action taken on the response, e.g., "block".
+		RecordOutputGuardrailMatch(action, rule string)
+	}
 	// ChatCompletionSpan represents an OpenAI chat completion.
 	ChatCompletionSpan = Span[openai.ChatCompletionResponse, openai.ChatCompletionResponseChunk]
 	// CompletionSpan represents an OpenAI completion request.
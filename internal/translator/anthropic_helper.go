@@ -675,6 +675,47 @@ func mapReasoningEffortToOutputConfigEffort(reasonEffort openai.ReasoningEffort)
 	}
 }
 
+// anthropicStructuredOutputEmulated returns true if the json_schema response format of the request
+// must be emulated with a forced tool call, i.e. the backend or the model does not support OutputConfig.
+// Currently, GCP Vertex AI does not support structured output.
+func anthropicStructuredOutputEmulated(openAIReq *openai.ChatCompletionRequest, apiSchema string, modelNameOverride internalapi.ModelNameOverride) bool {
+	if !isStructuredOutputRequested(openAIReq) {
+		return false
+	}
+	featureCheckModel := cmp.Or(modelNameOverride, openAIReq.Model)
+	return strings.HasPrefix(apiSchema, "GCP") || !outputConfigAvailable(featureCheckModel)
+}
+
+// addAnthropicStructuredOutputTool appends the structured output emulation tool to the params and
+// forces the model to call it.
+func addAnthropicStructuredOutputTool(params *anthropic.MessageNewParams, openAIReq *openai.ChatCompletionRequest) error {
+	tool, err := newStructuredOutputTool(openAIReq)
+	if err != nil {
+		return err
+	}
+	inputSchema, err := openAIToolParamsToAnthropicInputSchema(tool.schema)
+	if err != nil {
+		return err
+	}
+	params.Tools = append(params.Tools, anthropic.ToolUnionParam{OfTool: &anthropic.ToolParam{
+		Name:        structuredOutputToolName,
+		Description: anthropic.String(tool.description),
+		InputSchema: inputSchema,
+	}})
+
+	switch {
+	case openAIReq.Thinking != nil && openAIReq.Thinking.OfDisabled == nil:
+		// Forced tool use is not compatible with extended thinking, so let the model decide.
+		params.ToolChoice = anthropic.ToolChoiceUnionParam{OfAuto: &anthropic.ToolChoiceAutoParam{}}
+	case len(params.Tools) == 1:
+		params.ToolChoice = anthropic.ToolChoiceUnionParam{OfTool: &anthropic.ToolChoiceToolParam{Name: structuredOutputToolName}}
+	default:
+		// The client's own tools stay callable, but the model must end up calling one of the tools.
+		params.ToolChoice = anthropic.ToolChoiceUnionParam{OfAny: &anthropic.ToolChoiceAnyParam{}}
+	}
+	return nil
+}
+
 // buildAnthropicParams is a helper function that translates an OpenAI request
 // into the parameter struct required by the Anthropic SDK.
 // The apiSchema parameter indicates the backend API schema (e.g., "AWSAnthropic", "GCPAnthropic").
@@ -718,8 +759,9 @@ func buildAnthropicParams(openAIReq *openai.ChatCompletionRequest, apiSchema str
 	if modelNameOverride != "" {
 		featureCheckModel = modelNameOverride
 	}
-	isGCPBackend := strings.HasPrefix(apiSchema, "GCP")
-	if !isGCPBackend && openAIReq.ResponseFormat != nil && openAIReq.ResponseFormat.OfJSONSchema != nil && outputConfigAvailable(featureCheckModel) {
+	// Backends or models without OutputConfig support get the schema as a forced tool call instead.
+	emulateStructuredOutput := anthropicStructuredOutputEmulated(openAIReq, apiSchema, modelNameOverride)
+	if !emulateStructuredOutput && isStructuredOutputRequested(openAIReq) {
 		// Convert OpenAI JSON schema to Anthropic OutputConfig format
 		var schemaMap map[string]any
 		if err = json.Unmarshal(openAIReq.ResponseFormat.OfJSONSchema.JSONSchema.Schema, &schemaMap); err != nil {
@@ -732,6 +774,11 @@ func buildAnthropicParams(openAIReq *openai.ChatCompletionRequest, apiSchema str
 			},
 		}
 	}
+	if emulateStructuredOutput {
+		if err = addAnthropicStructuredOutputTool(params, openAIReq); err != nil {
+			return nil, err
+		}
+	}
 
 	// Map OpenAI reasoning_effort to Anthropic output_config.effort.
 	if openAIReq.ReasoningEffort != "" && effortAvailable(featureCheckModel) {
@@ -814,6 +861,11 @@ type anthropicStreamParser struct {
 	requestModel    internalapi.RequestModel
 	sentFirstChunk  bool
 	created         openai.JSONUNIXTime
+	// structuredOutput is true when the json_schema response format is emulated with a forced tool call,
+	// in which case the arguments of that tool call are streamed back as the message content.
+	structuredOutput bool
+	// inStructuredOutput is true while the content block of the structured output tool call is streamed.
+	inStructuredOutput bool
 }
 
 // newAnthropicStreamParser creates a new parser for a streaming request.
@@ -1063,6 +1115,10 @@ func (p *anthropicStreamParser) handleAnthropicStreamEvent(eventType []byte, dat
 		if err := json.Unmarshal(data, &event); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal content_block_start: %w", err)
 		}
+		if p.structuredOutput && event.ContentBlock.Type == string(constant.ValueOf[constant.ToolUse]()) && event.ContentBlock.Name == structuredOutputToolName {
+			p.inStructuredOutput = true
+			return nil, nil
+		}
 		if event.ContentBlock.Type == string(constant.ValueOf[constant.ToolUse]()) || event.ContentBlock.Type == string(constant.ValueOf[constant.ServerToolUse]()) {
 			p.toolIndex++
 			var argsJSON string
@@ -1167,6 +1223,10 @@ func (p *anthropicStreamParser) handleAnthropicStreamEvent(eventType []byte, dat
 			delta := openai.ChatCompletionResponseChunkChoiceDelta{Content: &event.Delta.Text}
 			return p.constructOpenAIChatCompletionChunk(delta, ""), nil
 		case string(constant.ValueOf[constant.InputJSONDelta]()):
+			if p.inStructuredOutput {
+				delta := openai.ChatCompletionResponseChunkChoiceDelta{Content: &event.Delta.PartialJSON}
+				return p.constructOpenAIChatCompletionChunk(delta, ""), nil
+			}
 			tool, ok := p.activeToolCalls[p.toolIndex]
 			if !ok {
 				return nil, fmt.Errorf("received input_json_delta for unknown tool at index %d", p.toolIndex)
@@ -1191,6 +1251,10 @@ func (p *anthropicStreamParser) handleAnthropicStreamEvent(eventType []byte, dat
 		if err := json.Unmarshal(data, &event); err != nil {
 			return nil, fmt.Errorf("unmarshal content_block_stop: %w", err)
 		}
+		if p.inStructuredOutput {
+			p.inStructuredOutput = false
+			return nil, nil
+		}
 		delete(p.activeToolCalls, p.toolIndex)
 		return nil, nil
 
@@ -1203,6 +1267,11 @@ func (p *anthropicStreamParser) handleAnthropicStreamEvent(eventType []byte, dat
 		if p.stopReason == "" {
 			p.stopReason = anthropic.StopReasonEndTurn
 		}
+		// The emulated structured output tool call is surfaced as content, so unless the model
+		// also called one of the client's tools, the response ends as a regular turn.
+		if p.structuredOutput && p.stopReason == anthropic.StopReasonToolUse && p.toolIndex < 0 {
+			p.stopReason = anthropic.StopReasonEndTurn
+		}
 
 		finishReason, err := anthropicToOpenAIFinishReason(p.stopReason)
 		if err != nil {
@@ -18,6 +18,7 @@ import (
 
 	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
 	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
 	"github.com/envoyproxy/ai-gateway/internal/metrics"
 )
 
@@ -1193,7 +1194,7 @@ func TestBuildAnthropicParamsWithStructuredOutput(t *testing.T) {
 			},
 		},
 		{
-			name: "structured output skipped on unsupported model",
+			name: "structured output emulated on unsupported model",
 			request: &openai.ChatCompletionRequest{
 				Model:               "claude-3-sonnet",
 				MaxCompletionTokens: ptr.To(int64(1024)),
@@ -1305,6 +1306,142 @@ func TestBuildAnthropicParamsWithStructuredOutput(t *testing.T) {
 	})
 }
 
+func TestBuildAnthropicParamsWithStructuredOutputEmulation(t *testing.T) {
+	weatherTool := openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:       "get_weather",
+			Parameters: map[string]any{"type": "object"},
+		},
+	}
+	tests := []struct {
+		name               string
+		apiSchema          string
+		model              string
+		tools              []openai.Tool
+		thinking           *openai.ThinkingUnion
+		expectEmulation    bool
+		expectedToolChoice anthropic.ToolChoiceUnionParam
+	}{
+		{
+			name:               "GCP backend forces the structured output tool",
+			apiSchema:          "GCPAnthropic",
+			model:              "claude-sonnet-4-5",
+			expectEmulation:    true,
+			expectedToolChoice: anthropic.ToolChoiceUnionParam{OfTool: &anthropic.ToolChoiceToolParam{Name: structuredOutputToolName}},
+		},
+		{
+			name:               "older model forces the structured output tool",
+			apiSchema:          "AWSAnthropic",
+			model:              "claude-3-sonnet",
+			expectEmulation:    true,
+			expectedToolChoice: anthropic.ToolChoiceUnionParam{OfTool: &anthropic.ToolChoiceToolParam{Name: structuredOutputToolName}},
+		},
+		{
+			name:               "client tools use any",
+			apiSchema:          "AWSAnthropic",
+			model:              "claude-3-sonnet",
+			tools:              []openai.Tool{weatherTool},
+			expectEmulation:    true,
+			expectedToolChoice: anthropic.ToolChoiceUnionParam{OfAny: &anthropic.ToolChoiceAnyParam{}},
+		},
+		{
+			name:      "thinking uses auto",
+			apiSchema: "GCPAnthropic",
+			model:     "claude-3-7-sonnet",
+			thinking: &openai.ThinkingUnion{OfEnabled: &openai.ThinkingEnabled{
+				Type: "enabled", BudgetTokens: 1024,
+			}},
+			expectEmulation:    true,
+			expectedToolChoice: anthropic.ToolChoiceUnionParam{OfAuto: &anthropic.ToolChoiceAutoParam{}},
+		},
+		{
+			name:            "native structured output is not emulated",
+			apiSchema:       "AWSAnthropic",
+			model:           "claude-sonnet-4-5",
+			expectEmulation: false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := structuredOutputRequest("", `{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}`)
+			req.Model = tc.model
+			req.MaxCompletionTokens = ptr.To(int64(1024))
+			req.Tools = tc.tools
+			req.Thinking = tc.thinking
+			require.Equal(t, tc.expectEmulation, anthropicStructuredOutputEmulated(req, tc.apiSchema, ""))
+
+			params, err := buildAnthropicParams(req, tc.apiSchema, "")
+			require.NoError(t, err)
+			if !tc.expectEmulation {
+				require.NotNil(t, params.OutputConfig.Format.Schema)
+				require.Empty(t, params.Tools)
+				return
+			}
+			require.Nil(t, params.OutputConfig.Format.Schema)
+			require.Len(t, params.Tools, len(tc.tools)+1)
+			emulated := params.Tools[len(params.Tools)-1].OfTool
+			require.NotNil(t, emulated)
+			require.Equal(t, structuredOutputToolName, emulated.Name)
+			require.Equal(t, structuredOutputToolDescription, emulated.Description.Value)
+			require.Equal(t, map[string]any{"name": map[string]any{"type": "string"}}, emulated.InputSchema.Properties)
+			require.Equal(t, []string{"name"}, emulated.InputSchema.Required)
+			require.Equal(t, tc.expectedToolChoice, params.ToolChoice)
+		})
+	}
+}
+
+func TestAnthropicStreamParser_StructuredOutputEmulation(t *testing.T) {
+	events := `event: message_start
+data: {"type": "message_start", "message": {"id": "msg_1", "type": "message", "role": "assistant", "content": [], "model": "claude-3-sonnet", "usage": {"input_tokens": 10, "output_tokens": 1}}}
+
+event: content_block_start
+data: {"type": "content_block_start", "index": 0, "content_block": {"type": "tool_use", "id": "toolu_1", "name": "json_response", "input": {}}}
+
+event: content_block_delta
+data: {"type": "content_block_delta", "index": 0, "delta": {"type": "input_json_delta", "partial_json": "{\"name\": "}}
+
+event: content_block_delta
+data: {"type": "content_block_delta", "index": 0, "delta": {"type": "input_json_delta", "partial_json": "\"Alice\"}"}}
+
+event: content_block_stop
+data: {"type": "content_block_stop", "index": 0}
+
+event: message_delta
+data: {"type": "message_delta", "delta": {"stop_reason": "tool_use"}, "usage": {"output_tokens": 8}}
+
+event: message_stop
+data: {"type": "message_stop"}
+
+`
+	parser := newAnthropicStreamParser("claude-3-sonnet")
+	parser.structuredOutput = true
+	_, body, _, _, err := parser.Process(strings.NewReader(events), true, nil)
+	require.NoError(t, err)
+
+	var content strings.Builder
+	var finishReason openai.ChatCompletionChoicesFinishReason
+	for line := range strings.SplitSeq(string(body), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk openai.ChatCompletionResponseChunk
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		for _, choice := range chunk.Choices {
+			require.Empty(t, choice.Delta.ToolCalls)
+			if choice.Delta.Content != nil {
+				content.WriteString(*choice.Delta.Content)
+			}
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+	}
+	require.JSONEq(t, `{"name":"Alice"}`, content.String())
+	require.Equal(t, openai.ChatCompletionChoicesFinishReasonStop, finishReason)
+}
+
 func TestBuildAnthropicParamsWithReasoningEffort(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -0,0 +1,85 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package translator
+
+import (
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+)
+
+// anthropicMaxCacheBreakpoints is the maximum number of cache_control breakpoints allowed per request.
+// See: https://docs.claude.com/en/docs/build-with-claude/prompt-caching
+const anthropicMaxCacheBreakpoints = 4
+
+// applyAnthropicPromptCaching places ephemeral cache breakpoints in the params according to the policy.
+//
+// Breakpoints are placed on the last tool, the last system block and then on the last content block of the
+// most recent messages, until the per-request limit is reached. Breakpoints already set by the client are
+// counted towards the limit and never modified.
+func applyAnthropicPromptCaching(params *anthropic.MessageNewParams, policy *filterapi.PromptCaching) {
+	if policy == nil {
+		return
+	}
+	remaining := anthropicMaxCacheBreakpoints - countAnthropicCacheBreakpoints(params)
+	place := func(cc *anthropic.CacheControlEphemeralParam) {
+		if remaining <= 0 || isCacheControlSet(cc) {
+			return
+		}
+		*cc = anthropic.NewCacheControlEphemeralParam()
+		cc.TTL = anthropic.CacheControlEphemeralTTL(policy.TTL)
+		remaining--
+	}
+
+	if policy.Tools && len(params.Tools) > 0 {
+		if cc := params.Tools[len(params.Tools)-1].GetCacheControl(); cc != nil {
+			place(cc)
+		}
+	}
+	if policy.System && len(params.System) > 0 {
+		place(&params.System[len(params.System)-1].CacheControl)
+	}
+	for i, turns := len(params.Messages)-1, 0; i >= 0 && turns < policy.LastTurns; i-- {
+		content := params.Messages[i].Content
+		if len(content) == 0 {
+			continue
+		}
+		// Thinking blocks cannot be cached directly, so the breakpoint goes on the last cacheable block.
+		for j := len(content) - 1; j >= 0; j-- {
+			if cc := content[j].GetCacheControl(); cc != nil {
+				place(cc)
+				break
+			}
+		}
+		turns++
+	}
+}
+
+// countAnthropicCacheBreakpoints returns the number of cache breakpoints already present in the params.
+func countAnthropicCacheBreakpoints(params *anthropic.MessageNewParams) (count int) {
+	for i := range params.Tools {
+		if isCacheControlSet(params.Tools[i].GetCacheControl()) {
+			count++
+		}
+	}
+	for i := range params.System {
+		if isCacheControlSet(&params.System[i].CacheControl) {
+			count++
+		}
+	}
+	for i := range params.Messages {
+		for j := range params.Messages[i].Content {
+			if isCacheControlSet(params.Messages[i].Content[j].GetCacheControl()) {
+				count++
+			}
+		}
+	}
+	return
+}
+
+func isCacheControlSet(cc *anthropic.CacheControlEphemeralParam) bool {
+	return cc != nil && cc.Type != ""
+}
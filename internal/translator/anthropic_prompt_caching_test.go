@@ -0,0 +1,117 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package translator
+
+import (
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"k8s.io/utils/ptr"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
+)
+
+func newPromptCachingTestParams() *anthropic.MessageNewParams {
+	return &anthropic.MessageNewParams{
+		System: []anthropic.TextBlockParam{{Text: "a"}, {Text: "b"}},
+		Tools: []anthropic.ToolUnionParam{
+			{OfTool: &anthropic.ToolParam{Name: "t1"}},
+			{OfTool: &anthropic.ToolParam{Name: "t2"}},
+		},
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock("u1")),
+			anthropic.NewAssistantMessage(anthropic.NewTextBlock("a1")),
+			anthropic.NewUserMessage(anthropic.NewTextBlock("u2"), anthropic.NewTextBlock("u3")),
+		},
+	}
+}
+
+func TestApplyAnthropicPromptCaching(t *testing.T) {
+	t.Run("nil policy", func(t *testing.T) {
+		params := newPromptCachingTestParams()
+		applyAnthropicPromptCaching(params, nil)
+		require.Zero(t, countAnthropicCacheBreakpoints(params))
+	})
+
+	t.Run("system, tools and last turns", func(t *testing.T) {
+		params := newPromptCachingTestParams()
+		applyAnthropicPromptCaching(params, &filterapi.PromptCaching{System: true, Tools: true, LastTurns: 2, TTL: "1h"})
+		require.Equal(t, 4, countAnthropicCacheBreakpoints(params))
+
+		require.False(t, isCacheControlSet(params.Tools[0].GetCacheControl()))
+		require.Equal(t, anthropic.CacheControlEphemeralTTLTTL1h, params.Tools[1].GetCacheControl().TTL)
+		require.False(t, isCacheControlSet(&params.System[0].CacheControl))
+		require.Equal(t, anthropic.CacheControlEphemeralTTLTTL1h, params.System[1].CacheControl.TTL)
+		require.False(t, isCacheControlSet(params.Messages[0].Content[0].GetCacheControl()))
+		require.True(t, isCacheControlSet(params.Messages[1].Content[0].GetCacheControl()))
+		require.False(t, isCacheControlSet(params.Messages[2].Content[0].GetCacheControl()))
+		require.True(t, isCacheControlSet(params.Messages[2].Content[1].GetCacheControl()))
+	})
+
+	t.Run("limit includes client breakpoints", func(t *testing.T) {
+		params := newPromptCachingTestParams()
+		params.System[0].CacheControl = anthropic.NewCacheControlEphemeralParam()
+		params.Messages[0].Content[0].OfText.CacheControl = anthropic.NewCacheControlEphemeralParam()
+		applyAnthropicPromptCaching(params, &filterapi.PromptCaching{System: true, Tools: true, LastTurns: 3})
+		require.Equal(t, 4, countAnthropicCacheBreakpoints(params))
+		// Tools and system are placed first, so no room is left for the conversation turns.
+		require.True(t, isCacheControlSet(params.Tools[1].GetCacheControl()))
+		require.True(t, isCacheControlSet(&params.System[1].CacheControl))
+		require.False(t, isCacheControlSet(params.Messages[2].Content[1].GetCacheControl()))
+		// The default TTL is left to the provider.
+		require.Empty(t, params.Tools[1].GetCacheControl().TTL)
+	})
+
+	t.Run("existing breakpoint is kept", func(t *testing.T) {
+		params := newPromptCachingTestParams()
+		params.Tools[1].OfTool.CacheControl = anthropic.CacheControlEphemeralParam{TTL: anthropic.CacheControlEphemeralTTLTTL5m, Type: "ephemeral"}
+		applyAnthropicPromptCaching(params, &filterapi.PromptCaching{Tools: true, TTL: "1h"})
+		require.Equal(t, 1, countAnthropicCacheBreakpoints(params))
+		require.Equal(t, anthropic.CacheControlEphemeralTTLTTL5m, params.Tools[1].GetCacheControl().TTL)
+	})
+}
+
+func TestOpenAIToGCPAnthropicTranslatorV1ChatCompletion_PromptCaching(t *testing.T) {
+	req := &openai.ChatCompletionRequest{
+		Model:     "claude-3-5-sonnet",
+		MaxTokens: ptr.To(int64(100)),
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			{OfSystem: &openai.ChatCompletionSystemMessageParam{Content: openai.ContentUnion{Value: "You are a helpful assistant."}}},
+			{OfUser: &openai.ChatCompletionUserMessageParam{Content: openai.StringOrUserRoleContentUnion{Value: "Hello"}}},
+		},
+	}
+	tr := NewChatCompletionOpenAIToGCPAnthropicTranslator("", "")
+	tr.(PromptCachingSetter).SetPromptCaching(&filterapi.PromptCaching{System: true, LastTurns: 1, TTL: "1h"})
+	_, body, err := tr.RequestBody(nil, req, false)
+	require.NoError(t, err)
+	require.Equal(t, "ephemeral", gjson.GetBytes(body, "system.0.cache_control.type").String())
+	require.Equal(t, "1h", gjson.GetBytes(body, "system.0.cache_control.ttl").String())
+	require.Equal(t, "ephemeral", gjson.GetBytes(body, "messages.0.content.0.cache_control.type").String())
+}
+
+func TestOpenAIToAWSAnthropicTranslatorV1ChatCompletion_PromptCaching(t *testing.T) {
+	req := &openai.ChatCompletionRequest{
+		Model:     "anthropic.claude-3-5-sonnet",
+		MaxTokens: ptr.To(int64(100)),
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			{OfUser: &openai.ChatCompletionUserMessageParam{Content: openai.StringOrUserRoleContentUnion{Value: "Hello"}}},
+		},
+		Tools: []openai.Tool{{
+			Type:     openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{Name: "get_weather", Parameters: map[string]any{"type": "object"}},
+		}},
+	}
+	tr := NewChatCompletionOpenAIToAWSAnthropicTranslator("", "")
+	tr.(PromptCachingSetter).SetPromptCaching(&filterapi.PromptCaching{Tools: true})
+	_, body, err := tr.RequestBody(nil, req, false)
+	require.NoError(t, err)
+	require.Equal(t, "ephemeral", gjson.GetBytes(body, "tools.0.cache_control.type").String())
+	require.False(t, gjson.GetBytes(body, "tools.0.cache_control.ttl").Exists())
+	require.False(t, gjson.GetBytes(body, "messages.0.content.0.cache_control").Exists())
+}
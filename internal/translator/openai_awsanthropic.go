@@ -21,6 +21,7 @@ import (
 
 	"github.com/envoyproxy/ai-gateway/internal/apischema/awsbedrock"
 	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
 	"github.com/envoyproxy/ai-gateway/internal/internalapi"
 	"github.com/envoyproxy/ai-gateway/internal/json"
 	"github.com/envoyproxy/ai-gateway/internal/metrics"
@@ -47,7 +48,11 @@ type openAIToAWSAnthropicTranslatorV1ChatCompletion struct {
 	modelNameOverride internalapi.ModelNameOverride
 	streamParser      *anthropicStreamParser
 	requestModel      internalapi.RequestModel
-	bufferedBody      []byte
+	// structuredOutput is true when the json_schema response format is emulated with a forced tool call.
+	structuredOutput bool
+	// promptCaching is the policy to automatically place prompt cache breakpoints.
+	promptCaching *filterapi.PromptCaching
+	bufferedBody  []byte
 }
 
 // RequestBody implements [OpenAIChatCompletionTranslator.RequestBody] for AWS Anthropic.
@@ -58,6 +63,7 @@ func (o *openAIToAWSAnthropicTranslatorV1ChatCompletion) RequestBody(_ []byte, o
 	if o.modelNameOverride != "" {
 		o.requestModel = o.modelNameOverride
 	}
+	o.structuredOutput = anthropicStructuredOutputEmulated(openAIReq, "AWSAnthropic", o.modelNameOverride)
 
 	// URL encode the model name for the path to handle special characters (e.g., ARNs)
 	encodedModelName := url.PathEscape(o.requestModel)
@@ -68,12 +74,14 @@ func (o *openAIToAWSAnthropicTranslatorV1ChatCompletion) RequestBody(_ []byte, o
 	if openAIReq.Stream {
 		pathTemplate = "/model/%s/invoke-with-response-stream"
 		o.streamParser = newAnthropicStreamParser(o.requestModel)
+		o.streamParser.structuredOutput = o.structuredOutput
 	}
 
 	params, err := buildAnthropicParams(openAIReq, "AWSAnthropic", o.modelNameOverride)
 	if err != nil {
 		return
 	}
+	applyAnthropicPromptCaching(params, o.promptCaching)
 
 	body, err := json.Marshal(params)
 	if err != nil {
@@ -99,6 +107,11 @@ func (o *openAIToAWSAnthropicTranslatorV1ChatCompletion) RequestBody(_ []byte, o
 	return
 }
 
+// SetPromptCaching implements [PromptCachingSetter.SetPromptCaching].
+func (o *openAIToAWSAnthropicTranslatorV1ChatCompletion) SetPromptCaching(policy *filterapi.PromptCaching) {
+	o.promptCaching = policy
+}
+
 // ResponseError implements [OpenAIChatCompletionTranslator.ResponseError].
 // Translate AWS Bedrock exceptions to OpenAI error type.
 // The error type is stored in the "x-amzn-errortype" HTTP header for AWS error responses.
@@ -197,6 +210,9 @@ func (o *openAIToAWSAnthropicTranslatorV1ChatCompletion) ResponseBody(_ map[stri
 	if err != nil {
 		return nil, nil, metrics.TokenUsage{}, "", err
 	}
+	if o.structuredOutput {
+		unwrapStructuredOutputToolCall(&openAIResp.Choices[0])
+	}
 
 	newBody, err = json.Marshal(openAIResp)
 	if err != nil {
@@ -48,6 +48,10 @@ type openAIToAWSBedrockTranslatorV1ChatCompletion struct {
 	responseID       string
 	toolIndex        int64
 	activeToolStream bool
+	// structuredOutput is true when the json_schema response format is emulated with a forced tool call.
+	structuredOutput bool
+	// structuredOutputStream is true while the structured output tool call is streamed as content.
+	structuredOutputStream bool
 	// Redaction configuration for debug logging
 	debugLogEnabled bool
 	enableRedaction bool
@@ -166,6 +170,13 @@ func (o *openAIToAWSBedrockTranslatorV1ChatCompletion) RequestBody(_ []byte, ope
 			return nil, nil, err
 		}
 	}
+	// Bedrock Converse does not support response_format, so json_schema is emulated with a forced tool call.
+	if isStructuredOutputRequested(openAIReq) {
+		if err = o.addStructuredOutputTool(openAIReq, &bedrockReq); err != nil {
+			return nil, nil, err
+		}
+		o.structuredOutput = true
+	}
 
 	newBody, err = json.Marshal(bedrockReq)
 	if err != nil {
@@ -245,6 +256,42 @@ func (o *openAIToAWSBedrockTranslatorV1ChatCompletion) openAIToolsToBedrockToolC
 	return nil
 }
 
+// addStructuredOutputTool appends the structured output emulation tool to the tool configuration
+// and forces the model to call it.
+func (o *openAIToAWSBedrockTranslatorV1ChatCompletion) addStructuredOutputTool(openAIReq *openai.ChatCompletionRequest,
+	bedrockReq *awsbedrock.ConverseInput,
+) error {
+	tool, err := newStructuredOutputTool(openAIReq)
+	if err != nil {
+		return err
+	}
+	if bedrockReq.ToolConfig == nil {
+		bedrockReq.ToolConfig = &awsbedrock.ToolConfiguration{}
+	}
+	toolName := structuredOutputToolName
+	bedrockReq.ToolConfig.Tools = append(bedrockReq.ToolConfig.Tools, &awsbedrock.Tool{
+		ToolSpec: &awsbedrock.ToolSpecification{
+			Name:        &toolName,
+			Description: &tool.description,
+			InputSchema: &awsbedrock.ToolInputSchema{JSON: tool.schema},
+		},
+	})
+
+	isClaude := strings.Contains(openAIReq.Model, "anthropic") && strings.Contains(openAIReq.Model, "claude")
+	switch {
+	case openAIReq.Thinking != nil && openAIReq.Thinking.OfDisabled == nil:
+		// Forced tool use is not compatible with extended thinking, so let the model decide.
+		bedrockReq.ToolConfig.ToolChoice = &awsbedrock.ToolChoice{Auto: &awsbedrock.AutoToolChoice{}}
+	case len(bedrockReq.ToolConfig.Tools) == 1 && isClaude:
+		bedrockReq.ToolConfig.ToolChoice = &awsbedrock.ToolChoice{Tool: &awsbedrock.SpecificToolChoice{Name: &toolName}}
+	default:
+		// With a single tool, any is equivalent to forcing it. Otherwise, the client's own tools
+		// stay callable, but the model must end up calling one of the tools.
+		bedrockReq.ToolConfig.ToolChoice = &awsbedrock.ToolChoice{Any: &awsbedrock.AnyToolChoice{}}
+	}
+	return nil
+}
+
 // openAIMessageToBedrockMessageRoleUser converts openai user role message.
 func (o *openAIToAWSBedrockTranslatorV1ChatCompletion) openAIMessageToBedrockMessageRoleUser(
 	openAiMessage *openai.ChatCompletionUserMessageParam, role string,
@@ -841,6 +888,9 @@ func (o *openAIToAWSBedrockTranslatorV1ChatCompletion) ResponseBody(_ map[string
 			}
 		}
 	}
+	if o.structuredOutput {
+		unwrapStructuredOutputToolCall(&choice)
+	}
 	openAIResp.Choices = append(openAIResp.Choices, choice)
 
 	// Redact and log response when enabled
@@ -949,6 +999,14 @@ func (o *openAIToAWSBedrockTranslatorV1ChatCompletion) convertEvent(event *awsbe
 			return chunk, false
 		}
 		switch {
+		case o.structuredOutputStream && event.Delta.ToolUse != nil:
+			chunk.Choices = append(chunk.Choices, openai.ChatCompletionResponseChunkChoice{
+				Index: 0,
+				Delta: &openai.ChatCompletionResponseChunkChoiceDelta{
+					Role:    o.role,
+					Content: &event.Delta.ToolUse.Input,
+				},
+			})
 		case event.Delta.Text != nil:
 			chunk.Choices = append(chunk.Choices, openai.ChatCompletionResponseChunkChoice{
 				Index: 0,
@@ -998,6 +1056,10 @@ func (o *openAIToAWSBedrockTranslatorV1ChatCompletion) convertEvent(event *awsbe
 		if event.Start == nil {
 			return chunk, false
 		}
+		if o.structuredOutput && event.Start.ToolUse != nil && event.Start.ToolUse.Name == structuredOutputToolName {
+			o.structuredOutputStream = true
+			return chunk, false
+		}
 		if event.Start.ToolUse != nil {
 			o.activeToolStream = true
 			chunk.Choices = append(chunk.Choices, openai.ChatCompletionResponseChunkChoice{
@@ -1022,16 +1084,26 @@ func (o *openAIToAWSBedrockTranslatorV1ChatCompletion) convertEvent(event *awsbe
 		if event.StopReason == nil {
 			return chunk, false
 		}
+		finishReason := o.bedrockStopReasonToOpenAIStopReason(event.StopReason)
+		// The emulated structured output tool call is surfaced as content, so unless the model
+		// also called one of the client's tools, the response ends as a regular turn.
+		if o.structuredOutput && finishReason == openai.ChatCompletionChoicesFinishReasonToolCalls && o.toolIndex == 0 {
+			finishReason = openai.ChatCompletionChoicesFinishReasonStop
+		}
 		chunk.Choices = append(chunk.Choices, openai.ChatCompletionResponseChunkChoice{
 			Index: 0,
 			Delta: &openai.ChatCompletionResponseChunkChoiceDelta{
 				Role:    o.role,
 				Content: ptr.To(emptyString),
 			},
-			FinishReason: o.bedrockStopReasonToOpenAIStopReason(event.StopReason),
+			FinishReason: finishReason,
 		})
 	case awsbedrock.ConverseStreamEventTypeContentBlockStop.String():
 		// this is the content stop event if none of the above is set.
+		if o.structuredOutputStream {
+			o.structuredOutputStream = false
+			return chunk, false
+		}
 		if o.activeToolStream {
 			o.toolIndex++
 			o.activeToolStream = false
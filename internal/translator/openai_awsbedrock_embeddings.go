@@ -26,9 +26,34 @@ func NewEmbeddingOpenAIToAWSBedrockTranslator(modelNameOverride internalapi.Mode
 }
 
 // openAIToAWSBedrockTranslatorV1Embedding translates OpenAI embedding requests to AWS Bedrock InvokeModel requests.
+// The request format is selected based on the model name:
+//   - Cohere Embed models (cohere.embed-*): Cohere format, with text and image inputs for Cohere Embed v4
+//   - Titan Multimodal Embeddings (amazon.titan-embed-image-*): Titan format with text and image inputs
+//   - Otherwise: Titan Text Embeddings format
 type openAIToAWSBedrockTranslatorV1Embedding struct {
 	modelNameOverride internalapi.ModelNameOverride
 	requestModel      internalapi.RequestModel
+	useCohere         bool
+	// cohereInputTokens is the number of input tokens reported in the response headers for the Cohere models.
+	cohereInputTokens int
+}
+
+const (
+	// awsBedrockInputTokenCountHeaderName is the response header with the number of input tokens.
+	awsBedrockInputTokenCountHeaderName = "x-amzn-bedrock-input-token-count"
+	// cohereEmbedDefaultInputType is the Cohere input_type used when no task type is specified.
+	cohereEmbedDefaultInputType = "search_document"
+)
+
+// isBedrockCohereEmbedModel returns true if the model is a Cohere Embed model, including cross-region
+// inference profiles such as us.cohere.embed-v4:0.
+func isBedrockCohereEmbedModel(model string) bool {
+	return strings.Contains(model, "cohere.embed")
+}
+
+// isBedrockTitanMultimodalEmbedModel returns true if the model is the Titan Multimodal Embeddings model.
+func isBedrockTitanMultimodalEmbedModel(model string) bool {
+	return strings.Contains(model, "titan-embed-image")
 }
 
 // RequestBody implements [OpenAIEmbeddingTranslator.RequestBody].
@@ -42,45 +67,161 @@ func (o *openAIToAWSBedrockTranslatorV1Embedding) RequestBody(_ []byte, req *ope
 	o.requestModel = model
 
 	if req.OfCompletion == nil {
-		return nil, nil, fmt.Errorf("%w: AWS Bedrock Titan requires an input-based embedding request (messages not supported)", internalapi.ErrInvalidRequestBody)
+		return nil, nil, fmt.Errorf("%w: AWS Bedrock requires an input-based embedding request (messages not supported)", internalapi.ErrInvalidRequestBody)
+	}
+
+	o.useCohere = isBedrockCohereEmbedModel(model)
+	switch {
+	case o.useCohere:
+		mutatedBody, err = openAIEmbeddingToCohereEmbedRequest(req)
+	case isBedrockTitanMultimodalEmbedModel(model):
+		mutatedBody, err = openAIEmbeddingToTitanMultimodalRequest(req)
+	default:
+		mutatedBody, err = openAIEmbeddingToTitanTextRequest(req)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encodedModel := url.PathEscape(model)
+	newHeaders = []internalapi.Header{
+		{pathHeaderName, fmt.Sprintf("/model/%s/invoke", encodedModel)},
+		{contentLengthHeaderName, strconv.Itoa(len(mutatedBody))},
 	}
+	return
+}
 
+// openAIEmbeddingToTitanTextRequest converts an OpenAI EmbeddingRequest to a Titan Text Embeddings request body.
+func openAIEmbeddingToTitanTextRequest(req *openai.EmbeddingRequest) ([]byte, error) {
 	var inputText string
 	switch v := req.OfCompletion.Input.Value.(type) {
 	case string:
 		inputText = v
 	case []string:
 		if len(v) != 1 {
-			return nil, nil, fmt.Errorf("%w: AWS Bedrock Titan does not support batch embeddings (got %d inputs)",
+			return nil, fmt.Errorf("%w: AWS Bedrock Titan does not support batch embeddings (got %d inputs)",
 				internalapi.ErrInvalidRequestBody, len(v))
 		}
 		inputText = v[0]
 	default:
-		return nil, nil, fmt.Errorf("%w: unsupported input type %T", internalapi.ErrInvalidRequestBody, req.OfCompletion.Input.Value)
+		return nil, fmt.Errorf("%w: unsupported input type %T", internalapi.ErrInvalidRequestBody, req.OfCompletion.Input.Value)
 	}
 
-	bedrockReq := awsbedrock.TitanEmbeddingRequest{
+	body, err := json.Marshal(awsbedrock.TitanEmbeddingRequest{
 		InputText:  inputText,
 		Dimensions: req.Dimensions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal body: %w", err)
 	}
+	return body, nil
+}
 
-	mutatedBody, err = json.Marshal(bedrockReq)
+// openAIEmbeddingToTitanMultimodalRequest converts an OpenAI EmbeddingRequest to a Titan Multimodal Embeddings
+// request body. The model embeds a single input, combining its text and image into one embedding.
+func openAIEmbeddingToTitanMultimodalRequest(req *openai.EmbeddingRequest) ([]byte, error) {
+	inputs, err := collectEmbeddingInputs(req.OfCompletion.Input)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to marshal body: %w", err)
+		return nil, err
+	}
+	if len(inputs) != 1 {
+		return nil, fmt.Errorf("%w: AWS Bedrock Titan does not support batch embeddings (got %d inputs)",
+			internalapi.ErrInvalidRequestBody, len(inputs))
 	}
 
-	encodedModel := url.PathEscape(model)
-	newHeaders = []internalapi.Header{
-		{pathHeaderName, fmt.Sprintf("/model/%s/invoke", encodedModel)},
-		{contentLengthHeaderName, strconv.Itoa(len(mutatedBody))},
+	bedrockReq := awsbedrock.TitanMultimodalEmbeddingRequest{
+		InputText:  inputs[0].text,
+		InputImage: inputs[0].image,
+	}
+	if req.Dimensions != nil && *req.Dimensions > 0 {
+		bedrockReq.EmbeddingConfig = &awsbedrock.TitanMultimodalEmbeddingConfig{OutputEmbeddingLength: *req.Dimensions}
+	}
+	body, err := json.Marshal(bedrockReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal body: %w", err)
+	}
+	return body, nil
+}
+
+// openAIEmbeddingToCohereEmbedRequest converts an OpenAI EmbeddingRequest to a Cohere Embed request body.
+// Text-only requests use the texts field, which all the Cohere Embed models support, while requests with
+// images use the inputs field of Cohere Embed v4 so that each input yields exactly one embedding.
+func openAIEmbeddingToCohereEmbedRequest(req *openai.EmbeddingRequest) ([]byte, error) {
+	inputs, err := collectEmbeddingInputs(req.OfCompletion.Input)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: embedding request has no input", internalapi.ErrInvalidRequestBody)
+	}
+
+	taskType := inputs[0].taskType
+	if req.GCPVertexAIEmbeddingVendorFields != nil && req.TaskType != "" {
+		taskType = req.TaskType
+	}
+	bedrockReq := awsbedrock.CohereEmbedRequest{
+		InputType:       cohereEmbedInputType(taskType),
+		EmbeddingTypes:  []string{"float"},
+		OutputDimension: req.Dimensions,
+	}
+
+	hasImage := false
+	for _, in := range inputs {
+		hasImage = hasImage || in.image != ""
+	}
+	if hasImage {
+		bedrockReq.Inputs = make([]awsbedrock.CohereEmbedInput, len(inputs))
+		for i, in := range inputs {
+			var content []awsbedrock.CohereEmbedContent
+			if in.text != "" {
+				content = append(content, awsbedrock.CohereEmbedContent{Type: "text", Text: in.text})
+			}
+			if in.image != "" {
+				content = append(content, awsbedrock.CohereEmbedContent{
+					Type:     "image_url",
+					ImageURL: fmt.Sprintf("data:%s;base64,%s", in.imageMIMEType, in.image),
+				})
+			}
+			bedrockReq.Inputs[i] = awsbedrock.CohereEmbedInput{Content: content}
+		}
+	} else {
+		bedrockReq.Texts = make([]string, len(inputs))
+		for i, in := range inputs {
+			bedrockReq.Texts[i] = in.text
+		}
+	}
+
+	body, err := json.Marshal(bedrockReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal body: %w", err)
+	}
+	return body, nil
+}
+
+// cohereEmbedInputType maps the embedding task type to the Cohere input_type.
+// https://docs.cohere.com/docs/embeddings#the-input_type-parameter
+func cohereEmbedInputType(taskType openai.EmbeddingTaskType) string {
+	switch taskType {
+	case openai.EmbeddingTaskTypeRetrievalQuery, openai.EmbeddingTaskTypeQuestionAnswering,
+		openai.EmbeddingTaskTypeCodeRetrievalQuery:
+		return "search_query"
+	case openai.EmbeddingTaskTypeClassification:
+		return "classification"
+	case openai.EmbeddingTaskTypeClustering:
+		return "clustering"
+	default:
+		return cohereEmbedDefaultInputType
 	}
-	return
 }
 
 // ResponseHeaders implements [OpenAIEmbeddingTranslator.ResponseHeaders].
-func (o *openAIToAWSBedrockTranslatorV1Embedding) ResponseHeaders(_ map[string]string) (
+// The Cohere models only report the number of input tokens in the response headers.
+func (o *openAIToAWSBedrockTranslatorV1Embedding) ResponseHeaders(headers map[string]string) (
 	newHeaders []internalapi.Header, err error,
 ) {
+	if o.useCohere {
+		o.cohereInputTokens, _ = strconv.Atoi(headers[awsBedrockInputTokenCountHeaderName])
+	}
 	return nil, nil
 }
 
@@ -89,26 +230,42 @@ func (o *openAIToAWSBedrockTranslatorV1Embedding) ResponseHeaders(_ map[string]s
 func (o *openAIToAWSBedrockTranslatorV1Embedding) ResponseBody(_ map[string]string, body io.Reader, _ bool, span tracingapi.EmbeddingsSpan) (
 	newHeaders []internalapi.Header, mutatedBody []byte, tokenUsage metrics.TokenUsage, responseModel internalapi.ResponseModel, err error,
 ) {
-	var titanResp awsbedrock.TitanEmbeddingResponse
-	if err = json.NewDecoder(body).Decode(&titanResp); err != nil {
-		return nil, nil, tokenUsage, "", fmt.Errorf("failed to unmarshal Titan embedding response: %w", err)
-	}
-
-	tokens := titanResp.InputTextTokenCount
 	openaiResp := openai.EmbeddingResponse{
 		Object: "list",
 		Model:  o.requestModel,
-		Data: []openai.Embedding{
+	}
+	var tokens int
+	if o.useCohere {
+		var cohereResp awsbedrock.CohereEmbedResponse
+		if err = json.NewDecoder(body).Decode(&cohereResp); err != nil {
+			return nil, nil, tokenUsage, "", fmt.Errorf("failed to unmarshal Cohere embedding response: %w", err)
+		}
+		openaiResp.Data = make([]openai.Embedding, len(cohereResp.Embeddings.Float))
+		for i, embedding := range cohereResp.Embeddings.Float {
+			openaiResp.Data[i] = openai.Embedding{
+				Object:    "embedding",
+				Index:     i,
+				Embedding: openai.EmbeddingUnion{Value: embedding},
+			}
+		}
+		tokens = o.cohereInputTokens
+	} else {
+		var titanResp awsbedrock.TitanEmbeddingResponse
+		if err = json.NewDecoder(body).Decode(&titanResp); err != nil {
+			return nil, nil, tokenUsage, "", fmt.Errorf("failed to unmarshal Titan embedding response: %w", err)
+		}
+		openaiResp.Data = []openai.Embedding{
 			{
 				Object:    "embedding",
 				Index:     0,
 				Embedding: openai.EmbeddingUnion{Value: titanResp.Embedding},
 			},
-		},
-		Usage: openai.EmbeddingUsage{
-			PromptTokens: tokens,
-			TotalTokens:  tokens,
-		},
+		}
+		tokens = titanResp.InputTextTokenCount
+	}
+	openaiResp.Usage = openai.EmbeddingUsage{
+		PromptTokens: tokens,
+		TotalTokens:  tokens,
 	}
 
 	mutatedBody, err = json.Marshal(openaiResp)
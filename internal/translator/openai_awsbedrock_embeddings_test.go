@@ -574,3 +574,94 @@ func TestEmbeddingOpenAIToAWSBedrockTranslator_ResponseError(t *testing.T) {
 		})
 	}
 }
+
+func TestEmbeddingOpenAIToAWSBedrockTranslator_Multimodal(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		expPath string
+		expBody string
+		expErr  string
+	}{
+		{
+			name:    "titan multimodal text and image",
+			input:   `{"model":"amazon.titan-embed-image-v1","dimensions":384,"input":{"content":"a red car","image":"data:image/png;base64,iVBORw0KGgo="}}`,
+			expPath: "/model/amazon.titan-embed-image-v1/invoke",
+			expBody: `{"inputText":"a red car","inputImage":"iVBORw0KGgo=","embeddingConfig":{"outputEmbeddingLength":384}}`,
+		},
+		{
+			name:    "titan multimodal image only",
+			input:   `{"model":"amazon.titan-embed-image-v1","input":[{"image":"iVBORw0KGgo="}]}`,
+			expPath: "/model/amazon.titan-embed-image-v1/invoke",
+			expBody: `{"inputImage":"iVBORw0KGgo="}`,
+		},
+		{
+			name:   "titan multimodal batch",
+			input:  `{"model":"amazon.titan-embed-image-v1","input":["a","b"]}`,
+			expErr: "does not support batch embeddings",
+		},
+		{
+			name:    "cohere texts",
+			input:   `{"model":"cohere.embed-v4:0","dimensions":256,"input":["a","b"]}`,
+			expPath: "/model/cohere.embed-v4:0/invoke",
+			expBody: `{"input_type":"search_document","texts":["a","b"],"embedding_types":["float"],"output_dimension":256}`,
+		},
+		{
+			name:    "cohere interleaved text and images",
+			input:   `{"model":"us.cohere.embed-v4:0","input":[{"content":"a red car","task_type":"RETRIEVAL_QUERY"},{"content":"photo","image":"iVBORw0KGgo="}]}`,
+			expPath: "/model/us.cohere.embed-v4:0/invoke",
+			expBody: `{"input_type":"search_query","inputs":[
+{"content":[{"type":"text","text":"a red car"}]},
+{"content":[{"type":"text","text":"photo"},{"type":"image_url","image_url":"data:image/png;base64,iVBORw0KGgo="}]}],
+"embedding_types":["float"]}`,
+		},
+		{
+			name:   "image with array content",
+			input:  `{"model":"cohere.embed-v4:0","input":{"content":["a","b"],"image":"iVBORw0KGgo="}}`,
+			expErr: "an input with an image must have a single string as content",
+		},
+		{
+			name:   "non-image base64",
+			input:  `{"model":"cohere.embed-v4:0","input":{"image":"aGVsbG8="}}`,
+			expErr: "image has unsupported media type text/plain",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var req openai.EmbeddingRequest
+			require.NoError(t, json.Unmarshal([]byte(tc.input), &req))
+			translator := NewEmbeddingOpenAIToAWSBedrockTranslator("")
+			headers, body, err := translator.RequestBody(nil, &req, false)
+			if tc.expErr != "" {
+				require.ErrorContains(t, err, tc.expErr)
+				require.ErrorIs(t, err, internalapi.ErrInvalidRequestBody)
+				return
+			}
+			require.NoError(t, err)
+			require.JSONEq(t, tc.expBody, string(body))
+			require.Equal(t, tc.expPath, headers[0].Value())
+		})
+	}
+}
+
+func TestEmbeddingOpenAIToAWSBedrockTranslator_CohereResponseBody(t *testing.T) {
+	var req openai.EmbeddingRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"model":"cohere.embed-v4:0","input":["a","b"]}`), &req))
+	translator := NewEmbeddingOpenAIToAWSBedrockTranslator("")
+	_, _, err := translator.RequestBody(nil, &req, false)
+	require.NoError(t, err)
+
+	headers, err := translator.ResponseHeaders(map[string]string{"x-amzn-bedrock-input-token-count": "4"})
+	require.NoError(t, err)
+	require.Nil(t, headers)
+
+	resp := `{"id":"1","embeddings":{"float":[[0.1,0.2],[0.3,0.4]]},"response_type":"embeddings_by_type","texts":["a","b"]}`
+	_, body, tokenUsage, responseModel, err := translator.ResponseBody(nil, strings.NewReader(resp), true, nil)
+	require.NoError(t, err)
+	require.Equal(t, "cohere.embed-v4:0", responseModel)
+	require.Equal(t, tokenUsageFrom(4, -1, -1, -1, 4, -1), tokenUsage)
+	require.JSONEq(t, `{"object":"list","model":"cohere.embed-v4:0","data":[
+{"object":"embedding","index":0,"embedding":[0.1,0.2]},
+{"object":"embedding","index":1,"embedding":[0.3,0.4]}],
+"usage":{"prompt_tokens":4,"total_tokens":4}}`, string(body))
+}
@@ -2818,3 +2818,135 @@ func TestOpenAIToAWSBedrockTranslatorV1ChatCompletion_Streaming_ResponseBody_Mar
 	require.ErrorContains(t, err, "failed to marshal streaming event")
 	require.ErrorContains(t, err, "injected marshal error")
 }
+
+func TestOpenAIToAWSBedrockTranslatorV1ChatCompletion_StructuredOutputEmulation(t *testing.T) {
+	schema := `{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}`
+
+	t.Run("request", func(t *testing.T) {
+		for _, tc := range []struct {
+			name               string
+			model              string
+			tools              []openai.Tool
+			expectedToolChoice *awsbedrock.ToolChoice
+		}{
+			{
+				name:               "claude forces the tool",
+				model:              "anthropic.claude-3-sonnet",
+				expectedToolChoice: &awsbedrock.ToolChoice{Tool: &awsbedrock.SpecificToolChoice{Name: ptr.To(structuredOutputToolName)}},
+			},
+			{
+				name:               "other models use any",
+				model:              "amazon.nova-pro-v1:0",
+				expectedToolChoice: &awsbedrock.ToolChoice{Any: &awsbedrock.AnyToolChoice{}},
+			},
+			{
+				name:  "client tools use any",
+				model: "anthropic.claude-3-sonnet",
+				tools: []openai.Tool{{
+					Type:     openai.ToolTypeFunction,
+					Function: &openai.FunctionDefinition{Name: "get_weather", Parameters: map[string]any{"type": "object"}},
+				}},
+				expectedToolChoice: &awsbedrock.ToolChoice{Any: &awsbedrock.AnyToolChoice{}},
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				req := structuredOutputRequest("", schema)
+				req.Model = tc.model
+				req.Tools = tc.tools
+				o := &openAIToAWSBedrockTranslatorV1ChatCompletion{}
+				_, body, err := o.RequestBody(nil, req, false)
+				require.NoError(t, err)
+				require.True(t, o.structuredOutput)
+
+				var bedrockReq awsbedrock.ConverseInput
+				require.NoError(t, json.Unmarshal(body, &bedrockReq))
+				require.Len(t, bedrockReq.ToolConfig.Tools, len(tc.tools)+1)
+				spec := bedrockReq.ToolConfig.Tools[len(bedrockReq.ToolConfig.Tools)-1].ToolSpec
+				require.Equal(t, structuredOutputToolName, *spec.Name)
+				require.Equal(t, map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"name": map[string]any{"type": "string"}},
+					"required":   []any{"name"},
+				}, spec.InputSchema.JSON)
+				require.Equal(t, tc.expectedToolChoice, bedrockReq.ToolConfig.ToolChoice)
+			})
+		}
+	})
+
+	t.Run("response", func(t *testing.T) {
+		o := &openAIToAWSBedrockTranslatorV1ChatCompletion{structuredOutput: true}
+		bedrockResp := awsbedrock.ConverseResponse{
+			Output: &awsbedrock.ConverseOutput{Message: awsbedrock.Message{
+				Role: awsbedrock.ConversationRoleAssistant,
+				Content: []*awsbedrock.ContentBlock{{ToolUse: &awsbedrock.ToolUseBlock{
+					Name: structuredOutputToolName, ToolUseID: "tooluse_1", Input: map[string]any{"name": "Alice"},
+				}}},
+			}},
+			StopReason: ptr.To(awsbedrock.StopReasonToolUse),
+		}
+		raw, err := json.Marshal(bedrockResp)
+		require.NoError(t, err)
+		_, body, _, _, err := o.ResponseBody(nil, bytes.NewReader(raw), true, nil)
+		require.NoError(t, err)
+
+		var resp openai.ChatCompletionResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.Len(t, resp.Choices, 1)
+		require.JSONEq(t, `{"name":"Alice"}`, *resp.Choices[0].Message.Content)
+		require.Empty(t, resp.Choices[0].Message.ToolCalls)
+		require.Equal(t, openai.ChatCompletionChoicesFinishReasonStop, resp.Choices[0].FinishReason)
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		inputEvents := []awsbedrock.ConverseStreamEvent{
+			{EventType: awsbedrock.ConverseStreamEventTypeMessageStart.String(), Role: ptr.To(awsbedrock.ConversationRoleAssistant)},
+			{
+				EventType: awsbedrock.ConverseStreamEventTypeContentBlockStart.String(),
+				Start: &awsbedrock.ContentBlockStart{ToolUse: &awsbedrock.ToolUseBlockStart{
+					Name: structuredOutputToolName, ToolUseID: "tooluse_1",
+				}},
+			},
+			{
+				EventType: awsbedrock.ConverseStreamEventTypeContentBlockDelta.String(),
+				Delta:     &awsbedrock.ConverseStreamEventContentBlockDelta{ToolUse: &awsbedrock.ToolUseBlockDelta{Input: `{"name":`}},
+			},
+			{
+				EventType: awsbedrock.ConverseStreamEventTypeContentBlockDelta.String(),
+				Delta:     &awsbedrock.ConverseStreamEventContentBlockDelta{ToolUse: &awsbedrock.ToolUseBlockDelta{Input: `"Alice"}`}},
+			},
+			{EventType: awsbedrock.ConverseStreamEventTypeContentBlockStop.String()},
+			{EventType: awsbedrock.ConverseStreamEventTypeMessageStop.String(), StopReason: ptr.To(awsbedrock.StopReasonToolUse)},
+		}
+		buf := bytes.NewBuffer(nil)
+		encoder := eventstream.NewEncoder()
+		for _, event := range inputEvents {
+			payload, err := json.Marshal(event)
+			require.NoError(t, err)
+			err = encoder.Encode(buf, eventstream.Message{
+				Headers: eventstream.Headers{{Name: ":event-type", Value: eventstream.StringValue(event.EventType)}},
+				Payload: payload,
+			})
+			require.NoError(t, err)
+		}
+
+		o := &openAIToAWSBedrockTranslatorV1ChatCompletion{stream: true, structuredOutput: true}
+		_, body, _, _, err := o.ResponseBody(nil, buf, true, nil)
+		require.NoError(t, err)
+
+		var content strings.Builder
+		var finishReason openai.ChatCompletionChoicesFinishReason
+		for _, chunk := range getChatCompletionResponseChunk(body) {
+			for _, choice := range chunk.Choices {
+				require.Empty(t, choice.Delta.ToolCalls)
+				if choice.Delta.Content != nil {
+					content.WriteString(*choice.Delta.Content)
+				}
+				if choice.FinishReason != "" {
+					finishReason = choice.FinishReason
+				}
+			}
+		}
+		require.JSONEq(t, `{"name":"Alice"}`, content.String())
+		require.Equal(t, openai.ChatCompletionChoicesFinishReasonStop, finishReason)
+	})
+}
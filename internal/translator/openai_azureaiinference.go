@@ -0,0 +1,99 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package translator
+
+import (
+	"cmp"
+	"path"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+)
+
+const (
+	// azureAIInferenceDefaultAPIVersion is the api-version used when the schema does not specify one.
+	azureAIInferenceDefaultAPIVersion = "2024-05-01-preview"
+	// azureAIInferenceExtraParametersHeaderName controls how the Azure AI Model Inference API handles
+	// the parameters that are not part of its specification.
+	// https://learn.microsoft.com/en-us/azure/ai-foundry/model-inference/reference/reference-model-inference-api#extensibility
+	azureAIInferenceExtraParametersHeaderName = "extra-parameters"
+	// azureAIInferenceExtraParametersPassThrough passes the unknown parameters to the underlying model,
+	// instead of rejecting the request, so that OpenAI-specific parameters reach the models supporting them.
+	azureAIInferenceExtraParametersPassThrough = "pass-through"
+)
+
+// NewChatCompletionOpenAIToAzureAIInferenceTranslator implements [Factory] for OpenAI to Azure AI Model Inference
+// translations. The request and response bodies follow the OpenAI format, so only the request path and headers
+// differ from NewChatCompletionOpenAIToOpenAITranslator.
+func NewChatCompletionOpenAIToAzureAIInferenceTranslator(apiVersion, prefix string, modelNameOverride internalapi.ModelNameOverride) OpenAIChatCompletionTranslator {
+	return &openAIToAzureAIInferenceTranslatorV1ChatCompletion{
+		apiVersion: cmp.Or(apiVersion, azureAIInferenceDefaultAPIVersion),
+		openAIToOpenAITranslatorV1ChatCompletion: openAIToOpenAITranslatorV1ChatCompletion{
+			modelNameOverride: modelNameOverride,
+			path:              path.Join("/", prefix, "chat/completions"),
+		},
+	}
+}
+
+// openAIToAzureAIInferenceTranslatorV1ChatCompletion adapts OpenAI requests for the Azure AI Model Inference API.
+// Unlike Azure OpenAI, the path does not contain the deployment, and the model is selected by the request body:
+// https://learn.microsoft.com/en-us/azure/ai-foundry/model-inference/reference/reference-model-inference-chat-completions
+type openAIToAzureAIInferenceTranslatorV1ChatCompletion struct {
+	apiVersion string
+	openAIToOpenAITranslatorV1ChatCompletion
+}
+
+// RequestBody implements [OpenAIChatCompletionTranslator.RequestBody].
+func (o *openAIToAzureAIInferenceTranslatorV1ChatCompletion) RequestBody(raw []byte, req *openai.ChatCompletionRequest, forceBodyMutation bool) (
+	newHeaders []internalapi.Header, newBody []byte, err error,
+) {
+	newHeaders, newBody, err = o.openAIToOpenAITranslatorV1ChatCompletion.RequestBody(raw, req, forceBodyMutation)
+	if err != nil {
+		return nil, nil, err
+	}
+	return azureAIInferenceHeaders(newHeaders, o.apiVersion), newBody, nil
+}
+
+// NewEmbeddingOpenAIToAzureAIInferenceTranslator implements [Factory] for OpenAI to Azure AI Model Inference
+// translation for embeddings.
+func NewEmbeddingOpenAIToAzureAIInferenceTranslator(apiVersion, prefix string, modelNameOverride internalapi.ModelNameOverride) OpenAIEmbeddingTranslator {
+	return &openAIToAzureAIInferenceTranslatorV1Embedding{
+		apiVersion: cmp.Or(apiVersion, azureAIInferenceDefaultAPIVersion),
+		openAIToOpenAITranslatorV1Embedding: openAIToOpenAITranslatorV1Embedding{
+			modelNameOverride: modelNameOverride,
+			path:              path.Join("/", prefix, "embeddings"),
+		},
+	}
+}
+
+// openAIToAzureAIInferenceTranslatorV1Embedding implements [OpenAIEmbeddingTranslator] for /embeddings.
+// https://learn.microsoft.com/en-us/azure/ai-foundry/model-inference/reference/reference-model-inference-embeddings
+type openAIToAzureAIInferenceTranslatorV1Embedding struct {
+	apiVersion string
+	openAIToOpenAITranslatorV1Embedding
+}
+
+// RequestBody implements [OpenAIEmbeddingTranslator.RequestBody].
+func (o *openAIToAzureAIInferenceTranslatorV1Embedding) RequestBody(original []byte, req *openai.EmbeddingRequest, onRetry bool) (
+	newHeaders []internalapi.Header, newBody []byte, err error,
+) {
+	newHeaders, newBody, err = o.openAIToOpenAITranslatorV1Embedding.RequestBody(original, req, onRetry)
+	if err != nil {
+		return nil, nil, err
+	}
+	return azureAIInferenceHeaders(newHeaders, o.apiVersion), newBody, nil
+}
+
+// azureAIInferenceHeaders appends the api-version to the path header set by the OpenAI translators,
+// and adds the extra-parameters header.
+func azureAIInferenceHeaders(headers []internalapi.Header, apiVersion string) []internalapi.Header {
+	for i, h := range headers {
+		if h.Key() == pathHeaderName {
+			headers[i] = internalapi.Header{pathHeaderName, appendAzureOpenAIAPIVersion(h.Value(), apiVersion)}
+		}
+	}
+	return append(headers, internalapi.Header{azureAIInferenceExtraParametersHeaderName, azureAIInferenceExtraParametersPassThrough})
+}
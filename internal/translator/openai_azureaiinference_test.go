@@ -0,0 +1,69 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package translator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+)
+
+func TestOpenAIToAzureAIInferenceTranslatorV1ChatCompletion_RequestBody(t *testing.T) {
+	original := []byte(`{"model":"Mistral-Large-2411","messages":[{"role":"user","content":"hi"}]}`)
+	req := &openai.ChatCompletionRequest{Model: "Mistral-Large-2411", Stream: true}
+
+	t.Run("default version and prefix", func(t *testing.T) {
+		tr := NewChatCompletionOpenAIToAzureAIInferenceTranslator("", "", "")
+		headers, body, err := tr.RequestBody(original, req, false)
+		require.NoError(t, err)
+		require.Nil(t, body)
+		require.Equal(t, []internalapi.Header{
+			{pathHeaderName, "/chat/completions?api-version=2024-05-01-preview"},
+			{"extra-parameters", "pass-through"},
+		}, headers)
+		require.True(t, tr.(*openAIToAzureAIInferenceTranslatorV1ChatCompletion).stream)
+	})
+
+	t.Run("foundry prefix and model override", func(t *testing.T) {
+		tr := NewChatCompletionOpenAIToAzureAIInferenceTranslator("2025-05-01", "models", "DeepSeek-R1")
+		headers, body, err := tr.RequestBody(original, req, false)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"model":"DeepSeek-R1","messages":[{"role":"user","content":"hi"}]}`, string(body))
+		require.Equal(t, []internalapi.Header{
+			{pathHeaderName, "/models/chat/completions?api-version=2025-05-01"},
+			{contentLengthHeaderName, "67"},
+			{"extra-parameters", "pass-through"},
+		}, headers)
+	})
+
+	t.Run("response model", func(t *testing.T) {
+		tr := NewChatCompletionOpenAIToAzureAIInferenceTranslator("", "", "")
+		_, _, err := tr.RequestBody(original, &openai.ChatCompletionRequest{Model: "Mistral-Large-2411"}, false)
+		require.NoError(t, err)
+		resp := []byte(`{"id":"1","object":"chat.completion","model":"mistral-large-2411","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`)
+		_, _, tokenUsage, responseModel, err := tr.ResponseBody(nil, bytes.NewReader(resp), true, nil)
+		require.NoError(t, err)
+		require.Equal(t, "mistral-large-2411", responseModel)
+		require.Equal(t, tokenUsageFrom(3, -1, -1, 2, 5, -1), tokenUsage)
+	})
+}
+
+func TestOpenAIToAzureAIInferenceTranslatorV1Embedding_RequestBody(t *testing.T) {
+	original := []byte(`{"model":"Cohere-embed-v3-english","input":"hello"}`)
+	tr := NewEmbeddingOpenAIToAzureAIInferenceTranslator("", "models", "")
+	headers, body, err := tr.RequestBody(original, &openai.EmbeddingRequest{}, true)
+	require.NoError(t, err)
+	require.Equal(t, original, body)
+	require.Equal(t, []internalapi.Header{
+		{pathHeaderName, "/models/embeddings?api-version=2024-05-01-preview"},
+		{contentLengthHeaderName, "51"},
+		{"extra-parameters", "pass-through"},
+	}, headers)
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/tidwall/sjson"
 
 	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/filterapi"
 	"github.com/envoyproxy/ai-gateway/internal/internalapi"
 	"github.com/envoyproxy/ai-gateway/internal/json"
 	"github.com/envoyproxy/ai-gateway/internal/metrics"
@@ -46,6 +47,10 @@ type openAIToGCPAnthropicTranslatorV1ChatCompletion struct {
 	modelNameOverride internalapi.ModelNameOverride
 	streamParser      *anthropicStreamParser
 	requestModel      internalapi.RequestModel
+	// structuredOutput is true when the json_schema response format is emulated with a forced tool call.
+	structuredOutput bool
+	// promptCaching is the policy to automatically place prompt cache breakpoints.
+	promptCaching *filterapi.PromptCaching
 	// Redaction configuration for debug logging
 	debugLogEnabled bool
 	enableRedaction bool
@@ -60,6 +65,8 @@ func (o *openAIToGCPAnthropicTranslatorV1ChatCompletion) RequestBody(_ []byte, o
 	if err != nil {
 		return
 	}
+	applyAnthropicPromptCaching(params, o.promptCaching)
+	o.structuredOutput = anthropicStructuredOutputEmulated(openAIReq, "GCPAnthropic", o.modelNameOverride)
 
 	body, err := json.Marshal(params)
 	if err != nil {
@@ -81,6 +88,7 @@ func (o *openAIToGCPAnthropicTranslatorV1ChatCompletion) RequestBody(_ []byte, o
 			return
 		}
 		o.streamParser = newAnthropicStreamParser(o.requestModel)
+		o.streamParser.structuredOutput = o.structuredOutput
 	}
 
 	path := buildGCPModelPathSuffix(gcpModelPublisherAnthropic, o.requestModel, specifier)
@@ -99,6 +107,11 @@ func (o *openAIToGCPAnthropicTranslatorV1ChatCompletion) RequestBody(_ []byte, o
 	return
 }
 
+// SetPromptCaching implements [PromptCachingSetter.SetPromptCaching].
+func (o *openAIToGCPAnthropicTranslatorV1ChatCompletion) SetPromptCaching(policy *filterapi.PromptCaching) {
+	o.promptCaching = policy
+}
+
 // ResponseError implements [OpenAIChatCompletionTranslator.ResponseError].
 func (o *openAIToGCPAnthropicTranslatorV1ChatCompletion) ResponseError(respHeaders map[string]string, body io.Reader) (
 	newHeaders []internalapi.Header, newBody []byte, err error,
@@ -257,6 +270,9 @@ func (o *openAIToGCPAnthropicTranslatorV1ChatCompletion) ResponseBody(_ map[stri
 	if err != nil {
 		return nil, nil, metrics.TokenUsage{}, "", err
 	}
+	if o.structuredOutput {
+		unwrapStructuredOutputToolCall(&openAIResp.Choices[0])
+	}
 
 	// Redact and log response when enabled
 	if o.debugLogEnabled && o.enableRedaction && o.logger != nil {
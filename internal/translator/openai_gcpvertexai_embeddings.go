@@ -39,19 +39,24 @@ func NewEmbeddingOpenAIToGCPVertexAITranslator(requestModel internalapi.RequestM
 // It auto-detects the endpoint based on model name:
 //   - Older models (text-embedding-004, gemini-embedding-001): predict endpoint
 //   - Newer models (gemini-embedding-2-*): embedContent endpoint
+//   - Multimodal models (multimodalembedding*): predict endpoint with text and image instances
 //
 // https://cloud.google.com/vertex-ai/generative-ai/docs/model-reference/text-embeddings-api
 type openAIToGCPVertexAITranslatorV1Embedding struct {
 	requestModel      internalapi.RequestModel
 	modelNameOverride internalapi.ModelNameOverride
 	useEmbedContent   bool
+	useMultimodal     bool
 }
 
 // createInstancesFromEmbeddingInputItem converts an EmbeddingInputItem to GCP Instance(s).
 // This handles the mapping of OpenAI's extended embedding input format to GCP's instance format,
 // including task_type and title metadata for optimized embedding generation.
 // When content is an array of strings, each string becomes a separate instance with the same task_type.
-func createInstancesFromEmbeddingInputItem(item openai.EmbeddingInputItem, instances []*gcp.Instance) []*gcp.Instance {
+func createInstancesFromEmbeddingInputItem(item openai.EmbeddingInputItem, instances []*gcp.Instance) ([]*gcp.Instance, error) {
+	if item.Image != "" {
+		return nil, fmt.Errorf("%w: image input requires a multimodal embedding model", internalapi.ErrInvalidRequestBody)
+	}
 	switch v := item.Content.Value.(type) {
 	case string:
 		instance := &gcp.Instance{Content: v}
@@ -78,7 +83,7 @@ func createInstancesFromEmbeddingInputItem(item openai.EmbeddingInputItem, insta
 			instances = append(instances, instance)
 		}
 	}
-	return instances
+	return instances, nil
 }
 
 // setInstances converts OpenAI embedding input to GCP instances.
@@ -98,12 +103,14 @@ func setInstances(input openai.EmbeddingRequestInput, instances []*gcp.Instance)
 	case openai.EmbeddingInputItem:
 		// Single EmbeddingInputItem with enhanced metadata.
 		// Content can be string or []string.
-		instances = createInstancesFromEmbeddingInputItem(v, instances)
-		return instances, nil
+		return createInstancesFromEmbeddingInputItem(v, instances)
 	case []openai.EmbeddingInputItem:
 		// Array of EmbeddingInputItem objects with metadata support.
+		var err error
 		for _, item := range v {
-			instances = createInstancesFromEmbeddingInputItem(item, instances)
+			if instances, err = createInstancesFromEmbeddingInputItem(item, instances); err != nil {
+				return nil, err
+			}
 		}
 		return instances, nil
 	default:
@@ -162,6 +169,44 @@ func openAIEmbeddingToGeminiMessage(openAIReq *openai.EmbeddingRequest) (*gcp.Pr
 	return gcr, nil
 }
 
+// openAIEmbeddingToMultimodalPredictRequest converts an OpenAI EmbeddingRequest to a GCP MultimodalPredictRequest.
+// Each input becomes a separate instance. Since the multimodalembedding models return separate text and image
+// embeddings for an instance, an input cannot have both content and an image, so that every input yields
+// exactly one embedding.
+func openAIEmbeddingToMultimodalPredictRequest(openAIReq *openai.EmbeddingRequest) (*gcp.MultimodalPredictRequest, error) {
+	if openAIReq.OfCompletion == nil {
+		return nil, fmt.Errorf("%w: model %s does not support multimodal embedding via messages; use input items with an image instead", internalapi.ErrInvalidRequestBody, openAIReq.Model)
+	}
+	inputs, err := collectEmbeddingInputs(openAIReq.OfCompletion.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]*gcp.MultimodalInstance, len(inputs))
+	for i, in := range inputs {
+		switch {
+		case in.image != "" && in.text != "":
+			return nil, fmt.Errorf("%w: input at index %d has both content and an image; send them as separate inputs", internalapi.ErrInvalidRequestBody, i)
+		case in.image != "":
+			instances[i] = &gcp.MultimodalInstance{Image: &gcp.MultimodalImage{BytesBase64Encoded: in.image, MimeType: in.imageMIMEType}}
+		default:
+			instances[i] = &gcp.MultimodalInstance{Text: in.text}
+		}
+	}
+
+	req := &gcp.MultimodalPredictRequest{Instances: instances}
+	if openAIReq.Dimensions != nil && *openAIReq.Dimensions > 0 {
+		req.Parameters = &gcp.MultimodalParameters{Dimension: *openAIReq.Dimensions}
+	}
+	return req, nil
+}
+
+// isMultimodalEmbeddingModel returns true if the model is one of the multimodalembedding models, which use
+// the predict endpoint with a different request and response format than the text embedding models.
+func isMultimodalEmbeddingModel(model string) bool {
+	return strings.HasPrefix(model, "multimodalembedding")
+}
+
 // isEmbedContentModel returns true if the model should use the embedContent endpoint
 // instead of the predict endpoint.
 // Reference: https://github.com/googleapis/go-genai/blob/v1.54.0/transformer.go#L565
@@ -293,7 +338,18 @@ func (o *openAIToGCPVertexAITranslatorV1Embedding) RequestBody(_ []byte, req *op
 
 	var path string
 
-	if isEmbedContentModel(o.requestModel) {
+	o.useMultimodal = isMultimodalEmbeddingModel(o.requestModel)
+	if o.useMultimodal {
+		o.useEmbedContent = false
+		path = buildGCPModelPathSuffix(gcpModelPublisherGoogle, o.requestModel, gcpMethodPredict)
+
+		var gcpReq *gcp.MultimodalPredictRequest
+		gcpReq, err = openAIEmbeddingToMultimodalPredictRequest(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error converting EmbeddingRequest: %w", err)
+		}
+		newBody, err = json.Marshal(gcpReq)
+	} else if isEmbedContentModel(o.requestModel) {
 		o.useEmbedContent = true
 		path = buildGCPModelPathSuffix(gcpModelPublisherGoogle, o.requestModel, gcpMethodEmbedContent)
 
@@ -352,9 +408,12 @@ func (o *openAIToGCPVertexAITranslatorV1Embedding) ResponseBody(_ map[string]str
 	var openaiResp openai.EmbeddingResponse
 	var promptTokens int
 
-	if o.useEmbedContent {
+	switch {
+	case o.useMultimodal:
+		openaiResp, err = o.parseMultimodalPredictResponse(respBody)
+	case o.useEmbedContent:
 		openaiResp, promptTokens, err = o.parseEmbedContentResponse(respBody)
-	} else {
+	default:
 		openaiResp, promptTokens, err = o.parsePredictResponse(respBody)
 	}
 	if err != nil {
@@ -424,6 +483,37 @@ func (o *openAIToGCPVertexAITranslatorV1Embedding) parsePredictResponse(respBody
 	return openaiResp, promptTokens, nil
 }
 
+// parseMultimodalPredictResponse parses a GCP MultimodalPredictResponse and converts it to the OpenAI format.
+// Each instance has either text or an image, so each prediction has exactly one of the embeddings.
+// The multimodalembedding models do not report the token usage.
+func (o *openAIToGCPVertexAITranslatorV1Embedding) parseMultimodalPredictResponse(respBody []byte) (openai.EmbeddingResponse, error) {
+	var gcpResp gcp.MultimodalPredictResponse
+	if err := json.Unmarshal(respBody, &gcpResp); err != nil {
+		return openai.EmbeddingResponse{}, fmt.Errorf("failed to unmarshal multimodal response: %w", err)
+	}
+
+	openaiResp := openai.EmbeddingResponse{
+		Object: "list",
+		Model:  o.requestModel,
+		Data:   make([]openai.Embedding, 0, len(gcpResp.Predictions)),
+	}
+	for i, prediction := range gcpResp.Predictions {
+		if prediction == nil {
+			continue
+		}
+		values := prediction.TextEmbedding
+		if len(prediction.ImageEmbedding) > 0 {
+			values = prediction.ImageEmbedding
+		}
+		openaiResp.Data = append(openaiResp.Data, openai.Embedding{
+			Object:    "embedding",
+			Index:     i,
+			Embedding: openai.EmbeddingUnion{Value: values},
+		})
+	}
+	return openaiResp, nil
+}
+
 // parseEmbedContentResponse parses a GCP EmbedContentResponse and converts it to the OpenAI format.
 func (o *openAIToGCPVertexAITranslatorV1Embedding) parseEmbedContentResponse(respBody []byte) (openai.EmbeddingResponse, int, error) {
 	var gcpResp gcp.EmbedContentResponse
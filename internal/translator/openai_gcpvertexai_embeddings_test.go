@@ -1403,3 +1403,77 @@ func TestCollectPartsFromMessages(t *testing.T) {
 		})
 	}
 }
+
+func TestOpenAIToGCPVertexAITranslatorV1Embedding_Multimodal(t *testing.T) {
+	t.Run("request body", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			input   string
+			expBody string
+			expErr  string
+		}{
+			{
+				name:    "text and images with dimensions",
+				input:   `{"model":"multimodalembedding@001","dimensions":512,"input":[{"content":"a red car"},{"image":"data:image/jpeg;base64,aGVsbG8="},{"image":"iVBORw0KGgo="}]}`,
+				expBody: `{"instances":[{"text":"a red car"},{"image":{"bytesBase64Encoded":"aGVsbG8=","mimeType":"image/jpeg"}},{"image":{"bytesBase64Encoded":"iVBORw0KGgo=","mimeType":"image/png"}}],"parameters":{"dimension":512}}`,
+			},
+			{
+				name:   "text and image in the same input",
+				input:  `{"model":"multimodalembedding@001","input":{"content":"a red car","image":"iVBORw0KGgo="}}`,
+				expErr: "input at index 0 has both content and an image",
+			},
+			{
+				name:   "remote image",
+				input:  `{"model":"multimodalembedding@001","input":{"image":"https://example.com/car.png"}}`,
+				expErr: "image must be a data URL or base64-encoded",
+			},
+			{
+				name:   "messages",
+				input:  `{"model":"multimodalembedding@001","messages":[{"role":"user","content":"a red car"}]}`,
+				expErr: "does not support multimodal embedding via messages",
+			},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				var req openai.EmbeddingRequest
+				require.NoError(t, json.Unmarshal([]byte(tc.input), &req))
+				tr := NewEmbeddingOpenAIToGCPVertexAITranslator(req.Model, "")
+				headers, body, err := tr.RequestBody(nil, &req, false)
+				if tc.expErr != "" {
+					require.ErrorContains(t, err, tc.expErr)
+					require.ErrorIs(t, err, internalapi.ErrInvalidRequestBody)
+					return
+				}
+				require.NoError(t, err)
+				require.JSONEq(t, tc.expBody, string(body))
+				require.Equal(t, "publishers/google/models/multimodalembedding@001:predict", headers[0].Value())
+			})
+		}
+	})
+
+	t.Run("image input rejected by text model", func(t *testing.T) {
+		var req openai.EmbeddingRequest
+		require.NoError(t, json.Unmarshal([]byte(`{"model":"text-embedding-004","input":[{"image":"iVBORw0KGgo="}]}`), &req))
+		tr := NewEmbeddingOpenAIToGCPVertexAITranslator(req.Model, "")
+		_, _, err := tr.RequestBody(nil, &req, false)
+		require.ErrorContains(t, err, "image input requires a multimodal embedding model")
+	})
+
+	t.Run("response body", func(t *testing.T) {
+		var req openai.EmbeddingRequest
+		require.NoError(t, json.Unmarshal([]byte(`{"model":"multimodalembedding@001","input":[{"content":"a red car"},{"image":"iVBORw0KGgo="}]}`), &req))
+		tr := NewEmbeddingOpenAIToGCPVertexAITranslator(req.Model, "")
+		_, _, err := tr.RequestBody(nil, &req, false)
+		require.NoError(t, err)
+
+		resp := `{"predictions":[{"textEmbedding":[0.1,0.2]},{"imageEmbedding":[0.3,0.4]}]}`
+		_, body, tokenUsage, responseModel, err := tr.ResponseBody(nil, strings.NewReader(resp), true, nil)
+		require.NoError(t, err)
+		require.Equal(t, "multimodalembedding@001", responseModel)
+		require.Equal(t, tokenUsageFrom(0, -1, -1, -1, 0, -1), tokenUsage)
+		require.JSONEq(t, `{"object":"list","model":"multimodalembedding@001","data":[
+{"object":"embedding","index":0,"embedding":[0.1,0.2]},
+{"object":"embedding","index":1,"embedding":[0.3,0.4]}],
+"usage":{"prompt_tokens":0,"total_tokens":0}}`, string(body))
+	})
+}
@@ -0,0 +1,541 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package translator
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/ollama"
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+	"github.com/envoyproxy/ai-gateway/internal/tracing/tracingapi"
+)
+
+const (
+	ollamaBackendError = "OllamaBackendError"
+	ollamaChatPath     = "/api/chat"
+	ollamaEmbedPath    = "/api/embed"
+)
+
+// NewChatCompletionOpenAIToOllamaTranslator implements [Factory] for OpenAI to Ollama native API translation.
+func NewChatCompletionOpenAIToOllamaTranslator(modelNameOverride internalapi.ModelNameOverride) OpenAIChatCompletionTranslator {
+	return &openAIToOllamaTranslatorV1ChatCompletion{modelNameOverride: modelNameOverride}
+}
+
+// openAIToOllamaTranslatorV1ChatCompletion translates OpenAI Chat Completions API to the Ollama /api/chat endpoint.
+// Streaming responses of Ollama are newline-delimited JSON objects which are converted to OpenAI SSE chunks.
+type openAIToOllamaTranslatorV1ChatCompletion struct {
+	modelNameOverride internalapi.ModelNameOverride
+	requestModel      internalapi.RequestModel
+	stream            bool
+	// bufferedBody holds an incomplete NDJSON line from the previous streaming call.
+	bufferedBody []byte
+	// streamID is the ID shared by all the chunks of a streaming response.
+	streamID string
+	// roleSent records whether the assistant role has been emitted in the streaming response.
+	roleSent      bool
+	toolCallIndex int64
+}
+
+// RequestBody implements [OpenAIChatCompletionTranslator.RequestBody].
+func (o *openAIToOllamaTranslatorV1ChatCompletion) RequestBody(_ []byte, openAIReq *openai.ChatCompletionRequest, _ bool) (
+	newHeaders []internalapi.Header, newBody []byte, err error,
+) {
+	o.requestModel = cmp.Or(o.modelNameOverride, openAIReq.Model)
+	o.stream = openAIReq.Stream
+
+	ollamaReq, err := openAIToOllamaChatRequest(openAIReq, o.requestModel)
+	if err != nil {
+		return nil, nil, err
+	}
+	newBody, err = json.Marshal(ollamaReq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal Ollama request: %w", err)
+	}
+	newHeaders = []internalapi.Header{
+		{pathHeaderName, ollamaChatPath},
+		{contentLengthHeaderName, strconv.Itoa(len(newBody))},
+	}
+	return
+}
+
+// ResponseHeaders implements [OpenAIChatCompletionTranslator.ResponseHeaders].
+func (o *openAIToOllamaTranslatorV1ChatCompletion) ResponseHeaders(_ map[string]string) (
+	newHeaders []internalapi.Header, err error,
+) {
+	if o.stream {
+		// Ollama streams application/x-ndjson, which is converted to SSE.
+		newHeaders = []internalapi.Header{{contentTypeHeaderName, eventStreamContentType}}
+	}
+	return
+}
+
+// ResponseBody implements [OpenAIChatCompletionTranslator.ResponseBody].
+func (o *openAIToOllamaTranslatorV1ChatCompletion) ResponseBody(_ map[string]string, body io.Reader, endOfStream bool, span tracingapi.ChatCompletionSpan) (
+	newHeaders []internalapi.Header, newBody []byte, tokenUsage metrics.TokenUsage, responseModel internalapi.ResponseModel, err error,
+) {
+	if o.stream {
+		return o.handleStreamingResponse(body, endOfStream, span)
+	}
+
+	var ollamaResp ollama.ChatResponse
+	if err = json.NewDecoder(body).Decode(&ollamaResp); err != nil {
+		return nil, nil, tokenUsage, "", fmt.Errorf("failed to decode Ollama response: %w", err)
+	}
+	responseModel = cmp.Or(ollamaResp.Model, o.requestModel)
+
+	message := openai.ChatCompletionResponseChoiceMessage{
+		Role:    openai.ChatMessageRoleAssistant,
+		Content: &ollamaResp.Message.Content,
+	}
+	if ollamaResp.Message.Thinking != "" {
+		message.ReasoningContent = &openai.ReasoningContentUnion{Value: ollamaResp.Message.Thinking}
+	}
+	for i := range ollamaResp.Message.ToolCalls {
+		toolCall, toolErr := ollamaToolCallToOpenAI(&ollamaResp.Message.ToolCalls[i])
+		if toolErr != nil {
+			return nil, nil, tokenUsage, "", toolErr
+		}
+		message.ToolCalls = append(message.ToolCalls, toolCall)
+	}
+
+	usage := ollamaUsageToOpenAI(&ollamaResp)
+	openAIResp := &openai.ChatCompletionResponse{
+		ID:      "chatcmpl-" + uuid.NewString(),
+		Object:  "chat.completion",
+		Created: ollamaCreatedAt(ollamaResp.CreatedAt),
+		Model:   responseModel,
+		Choices: []openai.ChatCompletionResponseChoice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: ollamaDoneReasonToOpenAI(ollamaResp.DoneReason, len(message.ToolCalls) > 0),
+		}},
+		Usage: usage,
+	}
+
+	newBody, err = json.Marshal(openAIResp)
+	if err != nil {
+		return nil, nil, tokenUsage, "", fmt.Errorf("failed to marshal OpenAI response: %w", err)
+	}
+	tokenUsage.SetInputTokens(uint32(usage.PromptTokens))      //nolint:gosec
+	tokenUsage.SetOutputTokens(uint32(usage.CompletionTokens)) //nolint:gosec
+	tokenUsage.SetTotalTokens(uint32(usage.TotalTokens))       //nolint:gosec
+	if span != nil {
+		span.RecordResponse(openAIResp)
+	}
+	newHeaders = []internalapi.Header{{contentLengthHeaderName, strconv.Itoa(len(newBody))}}
+	return
+}
+
+// handleStreamingResponse converts the NDJSON stream of Ollama to OpenAI SSE chunks.
+func (o *openAIToOllamaTranslatorV1ChatCompletion) handleStreamingResponse(body io.Reader, endOfStream bool, span tracingapi.ChatCompletionSpan) (
+	newHeaders []internalapi.Header, newBody []byte, tokenUsage metrics.TokenUsage, responseModel internalapi.ResponseModel, err error,
+) {
+	responseModel = o.requestModel
+	data, err := io.ReadAll(io.MultiReader(bytes.NewReader(o.bufferedBody), body))
+	if err != nil {
+		return nil, nil, tokenUsage, "", fmt.Errorf("failed to read streaming body: %w", err)
+	}
+	o.bufferedBody = nil
+
+	for len(data) > 0 {
+		line := data
+		i := bytes.IndexByte(data, '\n')
+		if i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else if !endOfStream {
+			// Incomplete line, wait for the rest of it.
+			o.bufferedBody = data
+			break
+		} else {
+			data = nil
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var ollamaChunk ollama.ChatResponse
+		if err = json.Unmarshal(line, &ollamaChunk); err != nil {
+			return nil, nil, tokenUsage, "", fmt.Errorf("failed to decode Ollama stream chunk: %w", err)
+		}
+		responseModel = cmp.Or(ollamaChunk.Model, responseModel)
+		chunks, chunkErr := o.convertOllamaChunkToOpenAI(&ollamaChunk, responseModel)
+		if chunkErr != nil {
+			return nil, nil, tokenUsage, "", chunkErr
+		}
+		for _, chunk := range chunks {
+			if err = serializeOpenAIChatCompletionChunk(chunk, &newBody); err != nil {
+				return nil, nil, tokenUsage, "", fmt.Errorf("failed to marshal OpenAI chunk: %w", err)
+			}
+			if span != nil {
+				span.RecordResponseChunk(chunk)
+			}
+			if chunk.Usage != nil {
+				tokenUsage.SetInputTokens(uint32(chunk.Usage.PromptTokens))      //nolint:gosec
+				tokenUsage.SetOutputTokens(uint32(chunk.Usage.CompletionTokens)) //nolint:gosec
+				tokenUsage.SetTotalTokens(uint32(chunk.Usage.TotalTokens))       //nolint:gosec
+			}
+		}
+	}
+
+	if endOfStream {
+		newBody = append(newBody, sseDoneFullLine...)
+	}
+	// Return an empty body rather than nil so that Envoy does not pass the original NDJSON through.
+	if newBody == nil {
+		newBody = []byte{}
+	}
+	return
+}
+
+// convertOllamaChunkToOpenAI converts a single Ollama stream object to OpenAI chunks. The final object
+// produces an additional usage-only chunk.
+func (o *openAIToOllamaTranslatorV1ChatCompletion) convertOllamaChunkToOpenAI(ollamaChunk *ollama.ChatResponse, model string) ([]*openai.ChatCompletionResponseChunk, error) {
+	if o.streamID == "" {
+		o.streamID = "chatcmpl-" + uuid.NewString()
+	}
+	created := ollamaCreatedAt(ollamaChunk.CreatedAt)
+	newChunk := func(choices []openai.ChatCompletionResponseChunkChoice) *openai.ChatCompletionResponseChunk {
+		return &openai.ChatCompletionResponseChunk{
+			ID:      o.streamID,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: choices,
+		}
+	}
+
+	delta := &openai.ChatCompletionResponseChunkChoiceDelta{}
+	if !o.roleSent {
+		delta.Role = openai.ChatMessageRoleAssistant
+		o.roleSent = true
+	}
+	if ollamaChunk.Message.Content != "" {
+		delta.Content = &ollamaChunk.Message.Content
+	}
+	if ollamaChunk.Message.Thinking != "" {
+		delta.ReasoningContent = &openai.StreamReasoningContent{Text: ollamaChunk.Message.Thinking}
+	}
+	for i := range ollamaChunk.Message.ToolCalls {
+		toolCall, err := ollamaToolCallToOpenAI(&ollamaChunk.Message.ToolCalls[i])
+		if err != nil {
+			return nil, err
+		}
+		delta.ToolCalls = append(delta.ToolCalls, openai.ChatCompletionChunkChoiceDeltaToolCall{
+			Index:    o.toolCallIndex,
+			ID:       toolCall.ID,
+			Type:     toolCall.Type,
+			Function: toolCall.Function,
+		})
+		o.toolCallIndex++
+	}
+
+	choice := openai.ChatCompletionResponseChunkChoice{Index: 0, Delta: delta}
+	if !ollamaChunk.Done {
+		return []*openai.ChatCompletionResponseChunk{newChunk([]openai.ChatCompletionResponseChunkChoice{choice})}, nil
+	}
+
+	choice.FinishReason = ollamaDoneReasonToOpenAI(ollamaChunk.DoneReason, o.toolCallIndex > 0)
+	usageChunk := newChunk([]openai.ChatCompletionResponseChunkChoice{})
+	usage := ollamaUsageToOpenAI(ollamaChunk)
+	usageChunk.Usage = &usage
+	return []*openai.ChatCompletionResponseChunk{newChunk([]openai.ChatCompletionResponseChunkChoice{choice}), usageChunk}, nil
+}
+
+// ResponseError implements [OpenAIChatCompletionTranslator.ResponseError].
+// Ollama errors are JSON objects with a single "error" string field.
+func (o *openAIToOllamaTranslatorV1ChatCompletion) ResponseError(respHeaders map[string]string, body io.Reader) (
+	newHeaders []internalapi.Header, newBody []byte, err error,
+) {
+	return convertOllamaErrorToOpenAI(respHeaders, body)
+}
+
+// openAIToOllamaChatRequest converts an OpenAI chat completion request to an Ollama chat request.
+func openAIToOllamaChatRequest(openAIReq *openai.ChatCompletionRequest, model string) (*ollama.ChatRequest, error) {
+	req := &ollama.ChatRequest{Model: model, Stream: openAIReq.Stream}
+
+	knownToolCalls := make(map[string]string)
+	for i := range openAIReq.Messages {
+		msg, err := openAIMessageToOllama(&openAIReq.Messages[i], knownToolCalls)
+		if err != nil {
+			return nil, err
+		}
+		req.Messages = append(req.Messages, msg)
+	}
+
+	toolChoiceNone := false
+	if openAIReq.ToolChoice != nil {
+		if s, ok := openAIReq.ToolChoice.Value.(string); ok && s == string(openai.ToolChoiceTypeNone) {
+			toolChoiceNone = true
+		}
+	}
+	// Ollama does not support tool_choice, so "none" is honored by not sending the tools at all.
+	if !toolChoiceNone {
+		for i := range openAIReq.Tools {
+			tool := &openAIReq.Tools[i]
+			if tool.Type != openai.ToolTypeFunction || tool.Function == nil {
+				return nil, fmt.Errorf("%w: Ollama only supports function tools", internalapi.ErrInvalidRequestBody)
+			}
+			req.Tools = append(req.Tools, ollama.Tool{
+				Type: string(openai.ToolTypeFunction),
+				Function: ollama.ToolFunction{
+					Name:        tool.Function.Name,
+					Description: tool.Function.Description,
+					Parameters:  tool.Function.Parameters,
+				},
+			})
+		}
+	}
+
+	if rf := openAIReq.ResponseFormat; rf != nil {
+		switch {
+		case rf.OfJSONObject != nil:
+			req.Format = json.RawMessage(`"json"`)
+		case rf.OfJSONSchema != nil:
+			req.Format = json.RawMessage(rf.OfJSONSchema.JSONSchema.Schema)
+		}
+	}
+
+	options := ollama.Options{
+		Temperature:      openAIReq.Temperature,
+		TopP:             openAIReq.TopP,
+		Seed:             openAIReq.Seed,
+		NumPredict:       cmp.Or(openAIReq.MaxCompletionTokens, openAIReq.MaxTokens),
+		FrequencyPenalty: openAIReq.FrequencyPenalty,
+		PresencePenalty:  openAIReq.PresencePenalty,
+	}
+	if openAIReq.Stop.OfString.Valid() {
+		options.Stop = []string{openAIReq.Stop.OfString.String()}
+	} else if openAIReq.Stop.OfStringArray != nil {
+		options.Stop = openAIReq.Stop.OfStringArray
+	}
+	if options.Temperature != nil || options.TopP != nil || options.Seed != nil || options.NumPredict != nil ||
+		options.FrequencyPenalty != nil || options.PresencePenalty != nil || len(options.Stop) > 0 {
+		req.Options = &options
+	}
+
+	switch openAIReq.ReasoningEffort {
+	case "":
+	case "none":
+		req.Think = false
+	default:
+		req.Think = string(openAIReq.ReasoningEffort)
+	}
+	return req, nil
+}
+
+// openAIMessageToOllama converts a single OpenAI message to an Ollama message. knownToolCalls maps the
+// tool call IDs of the preceding assistant messages to the function names, since Ollama identifies tool
+// results by the tool name rather than the tool call ID.
+func openAIMessageToOllama(msgUnion *openai.ChatCompletionMessageParamUnion, knownToolCalls map[string]string) (ollama.Message, error) {
+	switch {
+	case msgUnion.OfSystem != nil:
+		content, err := ollamaTextContent(msgUnion.OfSystem.Content.Value)
+		return ollama.Message{Role: openai.ChatMessageRoleSystem, Content: content}, err
+	case msgUnion.OfDeveloper != nil:
+		content, err := ollamaTextContent(msgUnion.OfDeveloper.Content.Value)
+		return ollama.Message{Role: openai.ChatMessageRoleSystem, Content: content}, err
+	case msgUnion.OfUser != nil:
+		return openAIUserMessageToOllama(msgUnion.OfUser)
+	case msgUnion.OfTool != nil:
+		content, err := ollamaTextContent(msgUnion.OfTool.Content.Value)
+		return ollama.Message{
+			Role:     openai.ChatMessageRoleTool,
+			Content:  content,
+			ToolName: knownToolCalls[msgUnion.OfTool.ToolCallID],
+		}, err
+	case msgUnion.OfAssistant != nil:
+		msg := msgUnion.OfAssistant
+		ret := ollama.Message{Role: openai.ChatMessageRoleAssistant}
+		switch v := msg.Content.Value.(type) {
+		case nil:
+		case string:
+			ret.Content = v
+		case []openai.ChatCompletionAssistantMessageParamContent:
+			var content, thinking strings.Builder
+			for _, part := range v {
+				if part.Text == nil {
+					continue
+				}
+				switch part.Type {
+				case openai.ChatCompletionAssistantMessageParamContentTypeText:
+					content.WriteString(*part.Text)
+				case openai.ChatCompletionAssistantMessageParamContentTypeThinking:
+					thinking.WriteString(*part.Text)
+				}
+			}
+			ret.Content, ret.Thinking = content.String(), thinking.String()
+		default:
+			return ollama.Message{}, fmt.Errorf("%w: unsupported assistant message content type %T", internalapi.ErrInvalidRequestBody, v)
+		}
+		for i, toolCall := range msg.ToolCalls {
+			var args map[string]any
+			if toolCall.Function.Arguments != "" {
+				if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+					return ollama.Message{}, fmt.Errorf("%w: tool_call at index %d has invalid JSON arguments", internalapi.ErrInvalidRequestBody, i)
+				}
+			}
+			if toolCall.ID != nil {
+				knownToolCalls[*toolCall.ID] = toolCall.Function.Name
+			}
+			ret.ToolCalls = append(ret.ToolCalls, ollama.ToolCall{
+				Function: ollama.ToolCallFunction{Name: toolCall.Function.Name, Arguments: args},
+			})
+		}
+		return ret, nil
+	default:
+		return ollama.Message{}, fmt.Errorf("%w: invalid role in message", internalapi.ErrInvalidRequestBody)
+	}
+}
+
+// openAIUserMessageToOllama converts an OpenAI user message. Ollama only accepts base64-encoded images,
+// so image URLs must be data URIs.
+func openAIUserMessageToOllama(msg *openai.ChatCompletionUserMessageParam) (ollama.Message, error) {
+	ret := ollama.Message{Role: openai.ChatMessageRoleUser}
+	switch v := msg.Content.Value.(type) {
+	case string:
+		ret.Content = v
+	case []openai.ChatCompletionContentPartUserUnionParam:
+		var content strings.Builder
+		for _, part := range v {
+			switch {
+			case part.OfText != nil:
+				content.WriteString(part.OfText.Text)
+			case part.OfImageURL != nil:
+				imgURL := part.OfImageURL.ImageURL.URL
+				parsed, err := url.Parse(imgURL)
+				if err != nil || parsed.Scheme != "data" {
+					return ollama.Message{}, fmt.Errorf("%w: Ollama only supports base64 data URI images", internalapi.ErrInvalidRequestBody)
+				}
+				_, img, err := parseDataURI(imgURL)
+				if err != nil {
+					return ollama.Message{}, fmt.Errorf("%w: invalid image data URI", internalapi.ErrInvalidRequestBody)
+				}
+				ret.Images = append(ret.Images, base64.StdEncoding.EncodeToString(img))
+			default:
+				return ollama.Message{}, fmt.Errorf("%w: only text and image content is supported by Ollama", internalapi.ErrInvalidRequestBody)
+			}
+		}
+		ret.Content = content.String()
+	default:
+		return ollama.Message{}, fmt.Errorf("%w: message 'content' must be a string or an array", internalapi.ErrInvalidRequestBody)
+	}
+	return ret, nil
+}
+
+// ollamaTextContent flattens the text content of system, developer and tool messages.
+func ollamaTextContent(content any) (string, error) {
+	switch v := content.(type) {
+	case string:
+		return v, nil
+	case []openai.ChatCompletionContentPartTextParam:
+		var b strings.Builder
+		for _, part := range v {
+			b.WriteString(part.Text)
+		}
+		return b.String(), nil
+	default:
+		return "", fmt.Errorf("%w: message 'content' must be a string or an array", internalapi.ErrInvalidRequestBody)
+	}
+}
+
+// ollamaToolCallToOpenAI converts an Ollama tool call to the OpenAI format. Ollama does not always
+// return tool call IDs, so one is generated when missing.
+func ollamaToolCallToOpenAI(toolCall *ollama.ToolCall) (openai.ChatCompletionMessageToolCallParam, error) {
+	arguments := toolCall.Function.Arguments
+	if arguments == nil {
+		arguments = map[string]any{}
+	}
+	args, err := json.Marshal(arguments)
+	if err != nil {
+		return openai.ChatCompletionMessageToolCallParam{}, fmt.Errorf("failed to marshal tool call arguments: %w", err)
+	}
+	id := cmp.Or(toolCall.ID, "call_"+uuid.NewString())
+	return openai.ChatCompletionMessageToolCallParam{
+		ID:   &id,
+		Type: openai.ChatCompletionMessageToolCallTypeFunction,
+		Function: openai.ChatCompletionMessageToolCallFunctionParam{
+			Name:      toolCall.Function.Name,
+			Arguments: string(args),
+		},
+	}, nil
+}
+
+// ollamaDoneReasonToOpenAI maps the Ollama done_reason to the OpenAI finish_reason.
+func ollamaDoneReasonToOpenAI(doneReason string, hasToolCalls bool) openai.ChatCompletionChoicesFinishReason {
+	switch {
+	case doneReason == "length":
+		return openai.ChatCompletionChoicesFinishReasonLength
+	case hasToolCalls:
+		return openai.ChatCompletionChoicesFinishReasonToolCalls
+	default:
+		return openai.ChatCompletionChoicesFinishReasonStop
+	}
+}
+
+func ollamaUsageToOpenAI(resp *ollama.ChatResponse) openai.Usage {
+	return openai.Usage{
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+		TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+	}
+}
+
+// ollamaCreatedAt parses the created_at timestamp of Ollama, falling back to the current time.
+func ollamaCreatedAt(createdAt string) openai.JSONUNIXTime {
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		t = time.Now()
+	}
+	return openai.JSONUNIXTime(t)
+}
+
+// convertOllamaErrorToOpenAI converts Ollama error responses to the OpenAI error format.
+// This is shared by the chat completion and embedding translators.
+func convertOllamaErrorToOpenAI(respHeaders map[string]string, body io.Reader) (
+	newHeaders []internalapi.Header, newBody []byte, err error,
+) {
+	buf, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read error body: %w", err)
+	}
+	statusCode := respHeaders[statusHeaderName]
+	openaiError := openai.Error{
+		Type: "error",
+		Error: openai.ErrorType{
+			Type: ollamaBackendError,
+			Code: &statusCode,
+		},
+	}
+	var ollamaErr ollama.Error
+	if json.Unmarshal(buf, &ollamaErr) == nil && ollamaErr.Error != "" {
+		openaiError.Error.Message = ollamaErr.Error
+	} else {
+		openaiError.Error.Message = string(buf)
+	}
+	newBody, err = json.Marshal(openaiError)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal error body: %w", err)
+	}
+	newHeaders = []internalapi.Header{
+		{contentTypeHeaderName, jsonContentType},
+		{contentLengthHeaderName, strconv.Itoa(len(newBody))},
+	}
+	return
+}
@@ -0,0 +1,111 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package translator
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/ollama"
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+	"github.com/envoyproxy/ai-gateway/internal/metrics"
+	"github.com/envoyproxy/ai-gateway/internal/tracing/tracingapi"
+)
+
+// NewEmbeddingOpenAIToOllamaTranslator implements [Factory] for OpenAI to Ollama native API embedding translation.
+func NewEmbeddingOpenAIToOllamaTranslator(modelNameOverride internalapi.ModelNameOverride) OpenAIEmbeddingTranslator {
+	return &openAIToOllamaTranslatorV1Embedding{modelNameOverride: modelNameOverride}
+}
+
+// openAIToOllamaTranslatorV1Embedding translates OpenAI embedding requests to the Ollama /api/embed endpoint.
+type openAIToOllamaTranslatorV1Embedding struct {
+	modelNameOverride internalapi.ModelNameOverride
+	requestModel      internalapi.RequestModel
+}
+
+// RequestBody implements [OpenAIEmbeddingTranslator.RequestBody].
+func (o *openAIToOllamaTranslatorV1Embedding) RequestBody(_ []byte, req *openai.EmbeddingRequest, _ bool) (
+	newHeaders []internalapi.Header, mutatedBody []byte, err error,
+) {
+	o.requestModel = cmp.Or(o.modelNameOverride, req.Model)
+
+	if req.OfCompletion == nil {
+		return nil, nil, fmt.Errorf("%w: Ollama requires an input-based embedding request (messages not supported)", internalapi.ErrInvalidRequestBody)
+	}
+	ollamaReq := ollama.EmbedRequest{Model: o.requestModel, Dimensions: req.Dimensions}
+	switch v := req.OfCompletion.Input.Value.(type) {
+	case string, []string:
+		ollamaReq.Input = v
+	default:
+		return nil, nil, fmt.Errorf("%w: Ollama only supports string inputs for embeddings (got %T)", internalapi.ErrInvalidRequestBody, v)
+	}
+
+	mutatedBody, err = json.Marshal(ollamaReq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal body: %w", err)
+	}
+	newHeaders = []internalapi.Header{
+		{pathHeaderName, ollamaEmbedPath},
+		{contentLengthHeaderName, strconv.Itoa(len(mutatedBody))},
+	}
+	return
+}
+
+// ResponseHeaders implements [OpenAIEmbeddingTranslator.ResponseHeaders].
+func (o *openAIToOllamaTranslatorV1Embedding) ResponseHeaders(map[string]string) (newHeaders []internalapi.Header, err error) {
+	return nil, nil
+}
+
+// ResponseBody implements [OpenAIEmbeddingTranslator.ResponseBody].
+func (o *openAIToOllamaTranslatorV1Embedding) ResponseBody(_ map[string]string, body io.Reader, _ bool, span tracingapi.EmbeddingsSpan) (
+	newHeaders []internalapi.Header, mutatedBody []byte, tokenUsage metrics.TokenUsage, responseModel internalapi.ResponseModel, err error,
+) {
+	var ollamaResp ollama.EmbedResponse
+	if err = json.NewDecoder(body).Decode(&ollamaResp); err != nil {
+		return nil, nil, tokenUsage, "", fmt.Errorf("failed to unmarshal Ollama embedding response: %w", err)
+	}
+
+	openaiResp := openai.EmbeddingResponse{
+		Object: "list",
+		Model:  cmp.Or(ollamaResp.Model, o.requestModel),
+		Data:   make([]openai.Embedding, 0, len(ollamaResp.Embeddings)),
+		Usage: openai.EmbeddingUsage{
+			PromptTokens: ollamaResp.PromptEvalCount,
+			TotalTokens:  ollamaResp.PromptEvalCount,
+		},
+	}
+	for i, embedding := range ollamaResp.Embeddings {
+		openaiResp.Data = append(openaiResp.Data, openai.Embedding{
+			Object:    "embedding",
+			Index:     i,
+			Embedding: openai.EmbeddingUnion{Value: embedding},
+		})
+	}
+
+	mutatedBody, err = json.Marshal(openaiResp)
+	if err != nil {
+		return nil, nil, tokenUsage, "", fmt.Errorf("failed to marshal OpenAI embedding response: %w", err)
+	}
+	tokenUsage.SetInputTokens(uint32(ollamaResp.PromptEvalCount)) //nolint:gosec
+	tokenUsage.SetTotalTokens(uint32(ollamaResp.PromptEvalCount)) //nolint:gosec
+	if span != nil {
+		span.RecordResponse(&openaiResp)
+	}
+	newHeaders = []internalapi.Header{{contentLengthHeaderName, strconv.Itoa(len(mutatedBody))}}
+	responseModel = openaiResp.Model
+	return
+}
+
+// ResponseError implements [OpenAIEmbeddingTranslator.ResponseError].
+func (o *openAIToOllamaTranslatorV1Embedding) ResponseError(respHeaders map[string]string, body io.Reader) (
+	newHeaders []internalapi.Header, mutatedBody []byte, err error,
+) {
+	return convertOllamaErrorToOpenAI(respHeaders, body)
+}
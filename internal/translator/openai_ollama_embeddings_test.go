@@ -0,0 +1,85 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package translator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+func TestEmbeddingOpenAIToOllamaTranslator_RequestBody(t *testing.T) {
+	tests := []struct {
+		name              string
+		modelNameOverride internalapi.ModelNameOverride
+		input             string
+		expBody           string
+		expErr            string
+	}{
+		{
+			name:    "string input",
+			input:   `{"model":"nomic-embed-text","input":"hello"}`,
+			expBody: `{"model":"nomic-embed-text","input":"hello"}`,
+		},
+		{
+			name:              "batch input with dimensions and override",
+			modelNameOverride: "all-minilm",
+			input:             `{"model":"nomic-embed-text","input":["a","b"],"dimensions":256}`,
+			expBody:           `{"model":"all-minilm","input":["a","b"],"dimensions":256}`,
+		},
+		{
+			name:   "token input rejected",
+			input:  `{"model":"nomic-embed-text","input":[1,2,3]}`,
+			expErr: "Ollama only supports string inputs for embeddings",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var req openai.EmbeddingRequest
+			require.NoError(t, json.Unmarshal([]byte(tc.input), &req))
+			tr := NewEmbeddingOpenAIToOllamaTranslator(tc.modelNameOverride)
+			headers, body, err := tr.RequestBody(nil, &req, false)
+			if tc.expErr != "" {
+				require.ErrorContains(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.JSONEq(t, tc.expBody, string(body))
+			require.Equal(t, "/api/embed", headers[0].Value())
+		})
+	}
+}
+
+func TestEmbeddingOpenAIToOllamaTranslator_ResponseBody(t *testing.T) {
+	tr := NewEmbeddingOpenAIToOllamaTranslator("")
+	var req openai.EmbeddingRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"model":"nomic-embed-text","input":["a","b"]}`), &req))
+	_, _, err := tr.RequestBody(nil, &req, false)
+	require.NoError(t, err)
+
+	ollamaResp := `{"model":"nomic-embed-text","embeddings":[[0.1,0.2],[0.3,0.4]],"prompt_eval_count":4}`
+	_, body, tokenUsage, responseModel, err := tr.ResponseBody(nil, strings.NewReader(ollamaResp), true, nil)
+	require.NoError(t, err)
+	require.Equal(t, "nomic-embed-text", responseModel)
+	require.Equal(t, tokenUsageFrom(4, -1, -1, -1, 4, -1), tokenUsage)
+	require.JSONEq(t, `{
+  "object": "list",
+  "model": "nomic-embed-text",
+  "data": [
+    {"object": "embedding", "index": 0, "embedding": [0.1, 0.2]},
+    {"object": "embedding", "index": 1, "embedding": [0.3, 0.4]}
+  ],
+  "usage": {"prompt_tokens": 4, "total_tokens": 4}
+}`, string(body))
+
+	_, _, _, _, err = tr.ResponseBody(nil, strings.NewReader("not json"), true, nil)
+	require.ErrorContains(t, err, "failed to unmarshal Ollama embedding response")
+}
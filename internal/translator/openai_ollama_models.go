@@ -0,0 +1,40 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package translator
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/ollama"
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+// OllamaModelListToOpenAI translates the response body of the Ollama /api/tags endpoint to the
+// OpenAI /v1/models response, attributing every model to ownedBy.
+func OllamaModelListToOpenAI(body io.Reader, ownedBy string) (*openai.ModelList, error) {
+	var ollamaResp ollama.ListModelsResponse
+	if err := json.NewDecoder(body).Decode(&ollamaResp); err != nil {
+		return nil, fmt.Errorf("failed to decode Ollama model list: %w", err)
+	}
+	ret := &openai.ModelList{Object: "list", Data: make([]openai.Model, 0, len(ollamaResp.Models))}
+	for _, m := range ollamaResp.Models {
+		var created time.Time
+		if t, err := time.Parse(time.RFC3339Nano, m.ModifiedAt); err == nil {
+			created = t
+		}
+		ret.Data = append(ret.Data, openai.Model{
+			ID:      cmp.Or(m.Model, m.Name),
+			Object:  "model",
+			Created: openai.JSONUNIXTime(created),
+			OwnedBy: ownedBy,
+		})
+	}
+	return ret, nil
+}
@@ -0,0 +1,39 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package translator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+)
+
+func TestOllamaModelListToOpenAI(t *testing.T) {
+	body := `{"models":[
+{"name":"llama3.2:latest","model":"llama3.2:latest","modified_at":"2025-01-02T03:04:05.123456789Z","size":2019393189,"digest":"a80c4f17acd5"},
+{"name":"nomic-embed-text:latest","modified_at":"invalid"}]}`
+	list, err := OllamaModelListToOpenAI(strings.NewReader(body), "Ollama")
+	require.NoError(t, err)
+	require.Equal(t, &openai.ModelList{
+		Object: "list",
+		Data: []openai.Model{
+			{
+				ID:      "llama3.2:latest",
+				Object:  "model",
+				Created: openai.JSONUNIXTime(time.Date(2025, 1, 2, 3, 4, 5, 123456789, time.UTC)),
+				OwnedBy: "Ollama",
+			},
+			{ID: "nomic-embed-text:latest", Object: "model", OwnedBy: "Ollama"},
+		},
+	}, list)
+
+	_, err = OllamaModelListToOpenAI(strings.NewReader("{"), "Ollama")
+	require.ErrorContains(t, err, "failed to decode Ollama model list")
+}
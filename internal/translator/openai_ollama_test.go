@@ -0,0 +1,213 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package translator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+func TestOpenAIToOllamaTranslatorV1ChatCompletion_RequestBody(t *testing.T) {
+	tests := []struct {
+		name              string
+		modelNameOverride internalapi.ModelNameOverride
+		input             string
+		expBody           string
+		expErr            string
+	}{
+		{
+			name:    "simple",
+			input:   `{"model":"llama3.2","messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hi"}]}`,
+			expBody: `{"model":"llama3.2","messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hi"}],"stream":false}`,
+		},
+		{
+			name:              "model override and streaming",
+			modelNameOverride: "qwen3:8b",
+			input:             `{"model":"llama3.2","stream":true,"messages":[{"role":"user","content":"hi"}]}`,
+			expBody:           `{"model":"qwen3:8b","messages":[{"role":"user","content":"hi"}],"stream":true}`,
+		},
+		{
+			name:    "generation options",
+			input:   `{"model":"llama3.2","messages":[{"role":"user","content":"hi"}],"temperature":0.5,"top_p":0.9,"seed":42,"max_completion_tokens":128,"stop":"END"}`,
+			expBody: `{"model":"llama3.2","messages":[{"role":"user","content":"hi"}],"options":{"temperature":0.5,"top_p":0.9,"seed":42,"num_predict":128,"stop":["END"]},"stream":false}`,
+		},
+		{
+			name:    "json_schema response format",
+			input:   `{"model":"llama3.2","messages":[{"role":"user","content":"hi"}],"response_format":{"type":"json_schema","json_schema":{"name":"x","schema":{"type":"object"}}}}`,
+			expBody: `{"model":"llama3.2","messages":[{"role":"user","content":"hi"}],"format":{"type":"object"},"stream":false}`,
+		},
+		{
+			name:    "json_object response format",
+			input:   `{"model":"llama3.2","messages":[{"role":"user","content":"hi"}],"response_format":{"type":"json_object"}}`,
+			expBody: `{"model":"llama3.2","messages":[{"role":"user","content":"hi"}],"format":"json","stream":false}`,
+		},
+		{
+			name:    "reasoning effort",
+			input:   `{"model":"gpt-oss","messages":[{"role":"user","content":"hi"}],"reasoning_effort":"low"}`,
+			expBody: `{"model":"gpt-oss","messages":[{"role":"user","content":"hi"}],"stream":false,"think":"low"}`,
+		},
+		{
+			name: "tools and tool results",
+			input: `{"model":"llama3.2","messages":[
+{"role":"user","content":"weather?"},
+{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},
+{"role":"tool","tool_call_id":"call_1","content":"sunny"}],
+"tools":[{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}]}`,
+			expBody: `{"model":"llama3.2","messages":[{"role":"user","content":"weather?"},{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"Paris"}}}]},{"role":"tool","content":"sunny","tool_name":"get_weather"}],"tools":[{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}],"stream":false}`,
+		},
+		{
+			name:    "tool_choice none drops tools",
+			input:   `{"model":"llama3.2","messages":[{"role":"user","content":"hi"}],"tool_choice":"none","tools":[{"type":"function","function":{"name":"f"}}]}`,
+			expBody: `{"model":"llama3.2","messages":[{"role":"user","content":"hi"}],"stream":false}`,
+		},
+		{
+			name:    "data URI image",
+			input:   `{"model":"llava","messages":[{"role":"user","content":[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,aGVsbG8="}}]}]}`,
+			expBody: `{"model":"llava","messages":[{"role":"user","content":"what is this?","images":["aGVsbG8="]}],"stream":false}`,
+		},
+		{
+			name:   "remote image rejected",
+			input:  `{"model":"llava","messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://example.com/cat.png"}}]}]}`,
+			expErr: "Ollama only supports base64 data URI images",
+		},
+		{
+			name:   "invalid tool call arguments",
+			input:  `{"model":"llama3.2","messages":[{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"f","arguments":"{"}}]}]}`,
+			expErr: "tool_call at index 0 has invalid JSON arguments",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var req openai.ChatCompletionRequest
+			require.NoError(t, json.Unmarshal([]byte(tc.input), &req))
+			tr := NewChatCompletionOpenAIToOllamaTranslator(tc.modelNameOverride)
+			headers, body, err := tr.RequestBody(nil, &req, false)
+			if tc.expErr != "" {
+				require.ErrorContains(t, err, tc.expErr)
+				require.ErrorIs(t, err, internalapi.ErrInvalidRequestBody)
+				return
+			}
+			require.NoError(t, err)
+			require.JSONEq(t, tc.expBody, string(body))
+			require.Len(t, headers, 2)
+			require.Equal(t, pathHeaderName, headers[0].Key())
+			require.Equal(t, "/api/chat", headers[0].Value())
+		})
+	}
+}
+
+func TestOpenAIToOllamaTranslatorV1ChatCompletion_ResponseBody(t *testing.T) {
+	tr := NewChatCompletionOpenAIToOllamaTranslator("")
+	_, _, err := tr.RequestBody(nil, &openai.ChatCompletionRequest{Model: "llama3.2"}, false)
+	require.NoError(t, err)
+
+	headers, err := tr.ResponseHeaders(nil)
+	require.NoError(t, err)
+	require.Empty(t, headers)
+
+	ollamaResp := `{"model":"llama3.2","created_at":"2025-01-01T00:00:00Z","message":{"role":"assistant","content":"","thinking":"hmm","tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"Paris"}}}]},"done":true,"done_reason":"stop","prompt_eval_count":10,"eval_count":5}`
+	_, body, tokenUsage, responseModel, err := tr.ResponseBody(nil, strings.NewReader(ollamaResp), true, nil)
+	require.NoError(t, err)
+	require.Equal(t, "llama3.2", responseModel)
+	require.Equal(t, tokenUsageFrom(10, -1, -1, 5, 15, -1), tokenUsage)
+
+	var resp openai.ChatCompletionResponse
+	require.NoError(t, json.Unmarshal(body, &resp))
+	require.Equal(t, "chat.completion", resp.Object)
+	require.Len(t, resp.Choices, 1)
+	choice := resp.Choices[0]
+	require.Equal(t, openai.ChatCompletionChoicesFinishReasonToolCalls, choice.FinishReason)
+	require.Equal(t, "hmm", choice.Message.ReasoningContent.Value)
+	require.Len(t, choice.Message.ToolCalls, 1)
+	require.Equal(t, "get_weather", choice.Message.ToolCalls[0].Function.Name)
+	require.JSONEq(t, `{"city":"Paris"}`, choice.Message.ToolCalls[0].Function.Arguments)
+	require.NotEmpty(t, *choice.Message.ToolCalls[0].ID)
+	require.Equal(t, 15, resp.Usage.TotalTokens)
+}
+
+func TestOpenAIToOllamaTranslatorV1ChatCompletion_StreamingResponseBody(t *testing.T) {
+	tr := NewChatCompletionOpenAIToOllamaTranslator("")
+	_, _, err := tr.RequestBody(nil, &openai.ChatCompletionRequest{Model: "llama3.2", Stream: true}, false)
+	require.NoError(t, err)
+
+	headers, err := tr.ResponseHeaders(nil)
+	require.NoError(t, err)
+	require.Equal(t, []internalapi.Header{{contentTypeHeaderName, eventStreamContentType}}, headers)
+
+	// The first call ends in the middle of a line, which must be buffered until the next call.
+	first := `{"model":"llama3.2","created_at":"2025-01-01T00:00:00Z","message":{"role":"assistant","content":"Hel"},"done":false}
+{"model":"llama3.2","created_at":"2025-01-01T00:00:00Z","message":{"role":"assistant","content":"lo"},`
+	second := `"done":false}
+{"model":"llama3.2","created_at":"2025-01-01T00:00:00Z","message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":3,"eval_count":2}
+`
+
+	_, body1, tokenUsage, responseModel, err := tr.ResponseBody(nil, strings.NewReader(first), false, nil)
+	require.NoError(t, err)
+	require.Equal(t, "llama3.2", responseModel)
+	require.Equal(t, tokenUsageFrom(-1, -1, -1, -1, -1, -1), tokenUsage)
+	require.Equal(t, 1, bytes.Count(body1, []byte("data: ")))
+
+	_, body2, tokenUsage, _, err := tr.ResponseBody(nil, strings.NewReader(second), true, nil)
+	require.NoError(t, err)
+	require.Equal(t, tokenUsageFrom(3, -1, -1, 2, 5, -1), tokenUsage)
+
+	var chunks []openai.ChatCompletionResponseChunk
+	for _, line := range strings.Split(string(body1)+string(body2), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk openai.ChatCompletionResponseChunk
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		chunks = append(chunks, chunk)
+	}
+	require.True(t, bytes.HasSuffix(body2, sseDoneFullLine))
+	require.Len(t, chunks, 4)
+	require.Equal(t, openai.ChatMessageRoleAssistant, chunks[0].Choices[0].Delta.Role)
+	require.Equal(t, "Hel", *chunks[0].Choices[0].Delta.Content)
+	require.Equal(t, "lo", *chunks[1].Choices[0].Delta.Content)
+	require.Equal(t, openai.ChatCompletionChoicesFinishReasonLength, chunks[2].Choices[0].FinishReason)
+	require.Empty(t, chunks[3].Choices)
+	require.Equal(t, 5, chunks[3].Usage.TotalTokens)
+	for _, chunk := range chunks {
+		require.Equal(t, chunks[0].ID, chunk.ID)
+	}
+}
+
+func TestOpenAIToOllamaTranslatorV1ChatCompletion_ResponseError(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		expBody string
+	}{
+		{
+			name:    "ollama error",
+			body:    `{"error":"model 'foo' not found"}`,
+			expBody: `{"type":"error","error":{"type":"OllamaBackendError","code":"404","message":"model 'foo' not found"}}`,
+		},
+		{
+			name:    "plain text",
+			body:    `404 page not found`,
+			expBody: `{"type":"error","error":{"type":"OllamaBackendError","code":"404","message":"404 page not found"}}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := NewChatCompletionOpenAIToOllamaTranslator("")
+			headers, body, err := tr.ResponseError(map[string]string{statusHeaderName: "404"}, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			require.JSONEq(t, tc.expBody, string(body))
+			require.Len(t, headers, 2)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package translator
+
+import (
+	"fmt"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+	"github.com/envoyproxy/ai-gateway/internal/json"
+)
+
+// structuredOutputToolName is the name of the synthetic tool used to emulate OpenAI's
+// `response_format: json_schema` on providers that do not support structured outputs natively.
+//
+// The JSON schema becomes the input schema of this tool, the model is forced to call it,
+// and the tool call arguments are unwrapped back into the assistant message content.
+const structuredOutputToolName = "json_response"
+
+// structuredOutputToolDescription is used when the response format does not carry its own description.
+const structuredOutputToolDescription = "Respond to the user with a JSON object that conforms to this tool's input schema."
+
+// structuredOutputTool holds the normalized tool definition derived from a json_schema response format.
+type structuredOutputTool struct {
+	description string
+	schema      map[string]any
+}
+
+// isStructuredOutputRequested returns true if the request asks for a json_schema structured output.
+func isStructuredOutputRequested(openAIReq *openai.ChatCompletionRequest) bool {
+	return openAIReq.ResponseFormat != nil && openAIReq.ResponseFormat.OfJSONSchema != nil
+}
+
+// newStructuredOutputTool builds the synthetic tool definition from the request's json_schema response format.
+// The schema is dereferenced so that providers without $ref/$defs support receive a self-contained schema.
+func newStructuredOutputTool(openAIReq *openai.ChatCompletionRequest) (*structuredOutputTool, error) {
+	jsonSchema := &openAIReq.ResponseFormat.OfJSONSchema.JSONSchema
+	var schemaMap map[string]any
+	if err := json.Unmarshal(jsonSchema.Schema, &schemaMap); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse response_format JSON schema: %w", internalapi.ErrInvalidRequestBody, err)
+	}
+	if schemaMap == nil {
+		return nil, fmt.Errorf("%w: response_format JSON schema must be an object", internalapi.ErrInvalidRequestBody)
+	}
+	dereferenced, err := jsonSchemaDereference(schemaMap)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to normalize response_format JSON schema: %w", internalapi.ErrInvalidRequestBody, err)
+	}
+	schema, ok := dereferenced.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: response_format JSON schema must be an object", internalapi.ErrInvalidRequestBody)
+	}
+	// Definitions are inlined by the dereference above, so they are no longer needed.
+	delete(schema, "$defs")
+	delete(schema, "definitions")
+	delete(schema, "$schema")
+	if _, ok := schema["type"]; !ok {
+		schema["type"] = "object"
+	}
+
+	description := jsonSchema.Description
+	if description == "" {
+		description = structuredOutputToolDescription
+	}
+	return &structuredOutputTool{description: description, schema: schema}, nil
+}
+
+// unwrapStructuredOutputToolCall moves the arguments of the emulated structured output tool call
+// into the message content of the given choice. The remaining tool calls, if any, are kept as is.
+func unwrapStructuredOutputToolCall(choice *openai.ChatCompletionResponseChoice) {
+	for i := range choice.Message.ToolCalls {
+		toolCall := &choice.Message.ToolCalls[i]
+		if toolCall.Function.Name != structuredOutputToolName {
+			continue
+		}
+		arguments := toolCall.Function.Arguments
+		choice.Message.Content = &arguments
+		choice.Message.ToolCalls = append(choice.Message.ToolCalls[:i], choice.Message.ToolCalls[i+1:]...)
+		if len(choice.Message.ToolCalls) == 0 {
+			choice.Message.ToolCalls = nil
+			if choice.FinishReason == openai.ChatCompletionChoicesFinishReasonToolCalls {
+				choice.FinishReason = openai.ChatCompletionChoicesFinishReasonStop
+			}
+		}
+		return
+	}
+}
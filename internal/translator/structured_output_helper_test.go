@@ -0,0 +1,161 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package translator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/ptr"
+
+	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
+)
+
+func structuredOutputRequest(description, schema string) *openai.ChatCompletionRequest {
+	return &openai.ChatCompletionRequest{
+		Model: "claude-3-sonnet",
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			{OfUser: &openai.ChatCompletionUserMessageParam{
+				Role:    "user",
+				Content: openai.StringOrUserRoleContentUnion{Value: "test"},
+			}},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormatUnion{
+			OfJSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+				JSONSchema: openai.ChatCompletionResponseFormatJSONSchemaJSONSchema{
+					Name:        "test_schema",
+					Description: description,
+					Schema:      []byte(schema),
+				},
+			},
+		},
+	}
+}
+
+func TestIsStructuredOutputRequested(t *testing.T) {
+	require.True(t, isStructuredOutputRequested(structuredOutputRequest("", `{"type":"object"}`)))
+	require.False(t, isStructuredOutputRequested(&openai.ChatCompletionRequest{}))
+	require.False(t, isStructuredOutputRequested(&openai.ChatCompletionRequest{
+		ResponseFormat: &openai.ChatCompletionResponseFormatUnion{
+			OfJSONObject: &openai.ChatCompletionResponseFormatJSONObjectParam{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+		},
+	}))
+}
+
+func TestNewStructuredOutputTool(t *testing.T) {
+	tests := []struct {
+		name                string
+		description         string
+		schema              string
+		expectedDescription string
+		expectedSchema      map[string]any
+		expectedErr         string
+	}{
+		{
+			name:                "default description",
+			schema:              `{"type":"object","properties":{"name":{"type":"string"}}}`,
+			expectedDescription: structuredOutputToolDescription,
+			expectedSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"name": map[string]any{"type": "string"}},
+			},
+		},
+		{
+			name:                "refs are inlined",
+			description:         "A person.",
+			schema:              `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","properties":{"address":{"$ref":"#/$defs/address"}},"$defs":{"address":{"type":"object","properties":{"city":{"type":"string"}}}}}`,
+			expectedDescription: "A person.",
+			expectedSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"address": map[string]any{
+						"type":       "object",
+						"properties": map[string]any{"city": map[string]any{"type": "string"}},
+					},
+				},
+			},
+		},
+		{
+			name:                "missing type defaults to object",
+			schema:              `{"properties":{"n":{"type":"integer"}}}`,
+			expectedDescription: structuredOutputToolDescription,
+			expectedSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"n": map[string]any{"type": "integer"}},
+			},
+		},
+		{
+			name:        "invalid json",
+			schema:      `{invalid`,
+			expectedErr: "failed to parse response_format JSON schema",
+		},
+		{
+			name:        "null schema",
+			schema:      `null`,
+			expectedErr: "response_format JSON schema must be an object",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tool, err := newStructuredOutputTool(structuredOutputRequest(tc.description, tc.schema))
+			if tc.expectedErr != "" {
+				require.ErrorIs(t, err, internalapi.ErrInvalidRequestBody)
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedDescription, tool.description)
+			require.Equal(t, tc.expectedSchema, tool.schema)
+		})
+	}
+}
+
+func TestUnwrapStructuredOutputToolCall(t *testing.T) {
+	structuredCall := openai.ChatCompletionMessageToolCallParam{
+		ID:       ptr.To("call_1"),
+		Type:     openai.ChatCompletionMessageToolCallTypeFunction,
+		Function: openai.ChatCompletionMessageToolCallFunctionParam{Name: structuredOutputToolName, Arguments: `{"name":"Alice"}`},
+	}
+	clientCall := openai.ChatCompletionMessageToolCallParam{
+		ID:       ptr.To("call_2"),
+		Type:     openai.ChatCompletionMessageToolCallTypeFunction,
+		Function: openai.ChatCompletionMessageToolCallFunctionParam{Name: "get_weather", Arguments: `{}`},
+	}
+
+	t.Run("only structured output", func(t *testing.T) {
+		choice := openai.ChatCompletionResponseChoice{
+			Message:      openai.ChatCompletionResponseChoiceMessage{Role: "assistant", ToolCalls: []openai.ChatCompletionMessageToolCallParam{structuredCall}},
+			FinishReason: openai.ChatCompletionChoicesFinishReasonToolCalls,
+		}
+		unwrapStructuredOutputToolCall(&choice)
+		require.Equal(t, `{"name":"Alice"}`, *choice.Message.Content)
+		require.Nil(t, choice.Message.ToolCalls)
+		require.Equal(t, openai.ChatCompletionChoicesFinishReasonStop, choice.FinishReason)
+	})
+
+	t.Run("with client tool call", func(t *testing.T) {
+		choice := openai.ChatCompletionResponseChoice{
+			Message:      openai.ChatCompletionResponseChoiceMessage{Role: "assistant", ToolCalls: []openai.ChatCompletionMessageToolCallParam{clientCall, structuredCall}},
+			FinishReason: openai.ChatCompletionChoicesFinishReasonToolCalls,
+		}
+		unwrapStructuredOutputToolCall(&choice)
+		require.Equal(t, `{"name":"Alice"}`, *choice.Message.Content)
+		require.Equal(t, []openai.ChatCompletionMessageToolCallParam{clientCall}, choice.Message.ToolCalls)
+		require.Equal(t, openai.ChatCompletionChoicesFinishReasonToolCalls, choice.FinishReason)
+	})
+
+	t.Run("no structured output", func(t *testing.T) {
+		choice := openai.ChatCompletionResponseChoice{
+			Message:      openai.ChatCompletionResponseChoiceMessage{Role: "assistant", Content: ptr.To("plain text")},
+			FinishReason: openai.ChatCompletionChoicesFinishReasonStop,
+		}
+		unwrapStructuredOutputToolCall(&choice)
+		require.Equal(t, "plain text", *choice.Message.Content)
+		require.Equal(t, openai.ChatCompletionChoicesFinishReasonStop, choice.FinishReason)
+	})
+}
@@ -8,9 +8,12 @@ package translator
 import (
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"regexp"
+	"strings"
 
 	"github.com/envoyproxy/ai-gateway/internal/apischema/openai"
+	"github.com/envoyproxy/ai-gateway/internal/internalapi"
 	"github.com/envoyproxy/ai-gateway/internal/json"
 )
 
@@ -49,6 +52,87 @@ func parseDataURI(uri string) (string, []byte, error) {
 	return contentType, bin, nil
 }
 
+// embeddingInput is a single input of a multimodal embedding request, which yields one embedding.
+type embeddingInput struct {
+	text string
+	// image is the base64-encoded image, and imageMIMEType is its media type.
+	image, imageMIMEType string
+	taskType             openai.EmbeddingTaskType
+}
+
+// collectEmbeddingInputs flattens the input of an embedding request for the multimodal embedding models.
+// Every string becomes a text input, and every EmbeddingInputItem becomes a single input combining its
+// content and image. An item with an array of strings as content yields one text input per string,
+// and therefore cannot have an image.
+func collectEmbeddingInputs(input openai.EmbeddingRequestInput) ([]embeddingInput, error) {
+	switch v := input.Value.(type) {
+	case string:
+		return []embeddingInput{{text: v}}, nil
+	case []string:
+		inputs := make([]embeddingInput, len(v))
+		for i, text := range v {
+			inputs[i] = embeddingInput{text: text}
+		}
+		return inputs, nil
+	case openai.EmbeddingInputItem:
+		return appendEmbeddingInputItem(nil, v)
+	case []openai.EmbeddingInputItem:
+		var inputs []embeddingInput
+		for _, item := range v {
+			var err error
+			if inputs, err = appendEmbeddingInputItem(inputs, item); err != nil {
+				return nil, err
+			}
+		}
+		return inputs, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported input type for embedding: %T (supported: string, []string, EmbeddingInputItem, []EmbeddingInputItem)", internalapi.ErrInvalidRequestBody, v)
+	}
+}
+
+func appendEmbeddingInputItem(inputs []embeddingInput, item openai.EmbeddingInputItem) ([]embeddingInput, error) {
+	if texts, ok := item.Content.Value.([]string); ok {
+		if item.Image != "" {
+			return nil, fmt.Errorf("%w: an input with an image must have a single string as content", internalapi.ErrInvalidRequestBody)
+		}
+		for _, text := range texts {
+			inputs = append(inputs, embeddingInput{text: text, taskType: item.TaskType})
+		}
+		return inputs, nil
+	}
+	in := embeddingInput{taskType: item.TaskType}
+	in.text, _ = item.Content.Value.(string)
+	if item.Image != "" {
+		var err error
+		if in.imageMIMEType, in.image, err = parseEmbeddingImage(item.Image); err != nil {
+			return nil, err
+		}
+	}
+	return append(inputs, in), nil
+}
+
+// parseEmbeddingImage returns the media type and the base64-encoded data of an embedding input image,
+// given as either a data URL or base64-encoded bytes. Remote URLs are not supported since the backends
+// only accept inline images.
+func parseEmbeddingImage(image string) (mimeType, data string, err error) {
+	if strings.HasPrefix(image, "data:") {
+		var bin []byte
+		if mimeType, bin, err = parseDataURI(image); err != nil {
+			return "", "", fmt.Errorf("%w: invalid image data URL: %w", internalapi.ErrInvalidRequestBody, err)
+		}
+		return mimeType, base64.StdEncoding.EncodeToString(bin), nil
+	}
+	bin, err := base64.StdEncoding.DecodeString(image)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: image must be a data URL or base64-encoded: %w", internalapi.ErrInvalidRequestBody, err)
+	}
+	mimeType = http.DetectContentType(bin)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", "", fmt.Errorf("%w: image has unsupported media type %s", internalapi.ErrInvalidRequestBody, mimeType)
+	}
+	return mimeType, image, nil
+}
+
 // systemMsgToDeveloperMsg converts OpenAI system message to developer message.
 // Since systemMsg is deprecated, this function is provided to maintain backward compatibility.
 func systemMsgToDeveloperMsg(msg openai.ChatCompletionSystemMessageParam) openai.ChatCompletionDeveloperMessageParam {
@@ -66,6 +66,10 @@ func TestAIGatewayRoutes(t *testing.T) {
 			name:   "too_many_rules.yaml",
 			expErr: "spec.rules: Too many: 16: must have at most 15 items",
 		},
+		{
+			name:   "too_many_generated_rules.yaml",
+			expErr: `spec.rules: Invalid value: "array": the rules generate more than 16 HTTPRoute rules including the ones of the backend selections, shadows, stream failovers and semantic caches; split the rules across multiple AIGatewayRoute resources`,
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			data, err := testdata.ReadFile(path.Join("testdata/aigatewayroutes", tc.name))
@@ -0,0 +1,57 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package testguardrail
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
+)
+
+const (
+	// DenyKeyword is the keyword for which the requests and the responses containing it are denied.
+	DenyKeyword = "forbidden-topic"
+	// RewriteKeyword is the keyword replaced with RewriteReplacement in the requests.
+	RewriteKeyword = "classified"
+	// RewriteReplacement is the replacement of RewriteKeyword in the rewritten requests.
+	RewriteReplacement = "[REDACTED]"
+	// FailKeyword is the keyword for which the checks of the requests and the responses containing it fail, so
+	// that the failure mode of the guardrail can be tested.
+	FailKeyword = "guardrail-failure"
+)
+
+// Check is the check of the test external guardrail service. The requests and the responses containing DenyKeyword
+// are denied, the ones containing FailKeyword fail, and RewriteKeyword is rewritten in the requests.
+func Check(_ context.Context, req *guardrail.ExternalCheckRequest) (*guardrail.ExternalCheckResponse, error) {
+	texts := []string{req.Output}
+	for _, m := range req.Messages {
+		texts = append(texts, m.Content)
+	}
+	for _, text := range texts {
+		if strings.Contains(text, FailKeyword) {
+			return nil, errors.New("check failed")
+		}
+		if strings.Contains(text, DenyKeyword) {
+			return &guardrail.ExternalCheckResponse{Decision: guardrail.ExternalDecisionDeny, Reason: DenyKeyword}, nil
+		}
+	}
+
+	if req.Phase == guardrail.ExternalPhaseRequest {
+		resp := &guardrail.ExternalCheckResponse{Decision: guardrail.ExternalDecisionRewrite}
+		rewritten := false
+		for _, m := range req.Messages {
+			rewritten = rewritten || strings.Contains(m.Content, RewriteKeyword)
+			m.Content = strings.ReplaceAll(m.Content, RewriteKeyword, RewriteReplacement)
+			resp.Messages = append(resp.Messages, m)
+		}
+		if rewritten {
+			return resp, nil
+		}
+	}
+	return &guardrail.ExternalCheckResponse{Decision: guardrail.ExternalDecisionAllow}, nil
+}
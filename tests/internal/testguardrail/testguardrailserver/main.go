@@ -0,0 +1,94 @@
+// Copyright Envoy AI Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+// The full text of the Apache license is available in the LICENSE file at
+// the root of the repo.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/envoyproxy/ai-gateway/internal/guardrail"
+	"github.com/envoyproxy/ai-gateway/tests/internal/testguardrail"
+)
+
+var logger = log.New(os.Stdout, "[testguardrail] ", 0)
+
+func main() {
+	httpServer, grpcServer := doMain()
+	defer grpcServer.Stop()
+	defer func() { _ = httpServer.Close() }()
+
+	// Block until a terminate signal is received (SIGINT or SIGTERM).
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	s := <-sigCh
+	logger.Printf("received signal %v, shutting down", s)
+}
+
+// doMain starts the HTTP service of the test external guardrail on the LISTENER_PORT at the "/check" path, and its
+// gRPC service on the GRPC_LISTENER_PORT.
+func doMain() (*http.Server, *grpc.Server) {
+	httpLis := listen("LISTENER_PORT", "1074")
+	grpcLis := listen("GRPC_LISTENER_PORT", "1075")
+
+	mux := http.NewServeMux()
+	mux.Handle("/check", guardrail.NewExternalHTTPHandler(check))
+	httpServer := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		logger.Printf("starting HTTP guardrail server on %s", httpLis.Addr())
+		if err := httpServer.Serve(httpLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatalf("failed to serve: %v", err)
+		}
+	}()
+
+	grpcServer := grpc.NewServer()
+	guardrail.RegisterExternalGRPCServer(grpcServer, check)
+	go func() {
+		logger.Printf("starting gRPC guardrail server on %s", grpcLis.Addr())
+		if err := grpcServer.Serve(grpcLis); err != nil {
+			logger.Fatalf("failed to serve: %v", err)
+		}
+	}()
+	return httpServer, grpcServer
+}
+
+// listen listens on the port of the environment variable, or on the default port if it is not set.
+func listen(envVar, defaultPort string) net.Listener {
+	portStr := os.Getenv(envVar)
+	if portStr == "" {
+		portStr = defaultPort
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		logger.Fatalf("invalid port: %v", err)
+	}
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		logger.Fatalf("failed to listen: %v", err)
+	}
+	return lis
+}
+
+// check logs the check and its decision.
+func check(ctx context.Context, req *guardrail.ExternalCheckRequest) (*guardrail.ExternalCheckResponse, error) {
+	resp, err := testguardrail.Check(ctx, req)
+	if err != nil {
+		logger.Printf("%s check of model %q with %d messages failed: %v", req.Phase, req.Model, len(req.Messages), err)
+		return nil, err
+	}
+	logger.Printf("%s check of model %q with %d messages: %s", req.Phase, req.Model, len(req.Messages), resp.Decision)
+	return resp, nil
+}